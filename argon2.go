@@ -0,0 +1,46 @@
+package powork
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// argon2Salt is the fixed salt of Argon2id proofs. The nonce already makes every input unique.
+var argon2Salt = []byte("powork.argon2id")
+
+// Argon2Params are the cost parameters of an Argon2id Worker
+type Argon2Params struct {
+	// Time is the number of passes over the memory
+	Time uint32
+	// Memory is the memory used by one hash, in KiB
+	Memory uint32
+	// Threads is the number of threads one hash uses
+	Threads uint8
+	// KeyLen is the length of the hash, in bytes
+	KeyLen uint32
+}
+
+// DefaultArgon2Params use a single pass over 16 MiB on one thread for a 32 byte hash
+var DefaultArgon2Params = Argon2Params{Time: 1, Memory: 16 * 1024, Threads: 1, KeyLen: 32}
+
+// NewArgon2idWorker creates a Worker that hashes with the memory-hard Argon2id instead of a
+// plain hash, which makes proofs expensive to compute on GPUs and ASICs. Each hash costs as
+// much as the parameters say, so the Worker starts at a difficulty of 4 rather than 10.
+// Proofs declare the parameters and are only valid for Workers with the same ones.
+func NewArgon2idWorker(params Argon2Params) (*Worker, error) {
+	if params.Time < 1 || params.Threads < 1 || params.KeyLen < 1 {
+		return nil, fmt.Errorf("%w: argon2id time, threads and key length must be at least 1", ErrInvalidSetting)
+	}
+
+	if params.Memory < 8*uint32(params.Threads) {
+		return nil, fmt.Errorf("%w: argon2id needs at least 8 KiB of memory per thread", ErrInvalidSetting)
+	}
+
+	w := NewWorkerWithHashFunc(newKDFHashFunc(int(params.KeyLen), func(in []byte) []byte {
+		return argon2.IDKey(in, argon2Salt, params.Time, params.Memory, params.Threads, params.KeyLen)
+	}))
+	w.difficulty = 4
+	w.algorithm = fmt.Sprintf("argon2id-t%d-m%d-p%d-l%d", params.Time, params.Memory, params.Threads, params.KeyLen)
+	return w, nil
+}
@@ -0,0 +1,58 @@
+package powork
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// cheap parameters so that the tests run quickly
+var testArgon2Params = Argon2Params{Time: 1, Memory: 64, Threads: 1, KeyLen: 32}
+
+func TestArgon2idWorker(t *testing.T) {
+	worker, err := NewArgon2idWorker(testArgon2Params)
+	if err != nil {
+		t.Fatalf("Could not create worker: %v\n", err)
+	}
+
+	pow, err := worker.DoProofForString("A memory-hard message")
+	if err != nil {
+		t.Fatalf("Could not compute proof: %v\n", err)
+	}
+
+	ok, err := worker.ValidatePoWork(pow)
+	if err != nil || !ok {
+		t.Fatalf("Could not validate proof: %v, %v\n", ok, err)
+	}
+
+	// the hash is plain Argon2id over the preimage
+	var preimage bytes.Buffer
+	pow.writePreimage(&preimage)
+	expected := argon2.IDKey(preimage.Bytes(), argon2Salt, 1, 64, 1, 32)
+	if sum, _ := pow.sum(worker.validationHasher()); !bytes.Equal(sum, expected) {
+		t.Fatalf("Hash %x is not Argon2id %x\n", sum, expected)
+	}
+
+	// other parameters are another algorithm
+	params := testArgon2Params
+	params.Time = 2
+	other, _ := NewArgon2idWorker(params)
+	if ok, _ := other.ValidatePoWork(pow); ok {
+		t.Fatalf("Proof was valid with other parameters\n")
+	}
+}
+
+func TestArgon2idParams(t *testing.T) {
+	for _, params := range []Argon2Params{
+		{Time: 0, Memory: 64, Threads: 1, KeyLen: 32},
+		{Time: 1, Memory: 64, Threads: 0, KeyLen: 32},
+		{Time: 1, Memory: 15, Threads: 2, KeyLen: 32},
+		{Time: 1, Memory: 64, Threads: 1, KeyLen: 0},
+	} {
+		if _, err := NewArgon2idWorker(params); !errors.Is(err, ErrInvalidSetting) {
+			t.Fatalf("Expected ErrInvalidSetting for %+v, got %v\n", params, err)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package powork
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// A Bank keeps an account of work for each client, identified by a key such as an API token,
+// so that the time work is done is decoupled from the time it is spent. A client deposits
+// proofs while it is idle, or in the background, and each request it makes is charged
+// against its balance instead of waiting on a proof of its own.
+//
+// A proof at the Worker's difficulty is worth one credit, and each bit of difficulty above it
+// doubles its worth. Under load, a pricing policy can make requests cost more credits, so that
+// clients have to deposit more work for the same requests. Balances are capped, so that a
+// client can't stockpile work to spend in a burst, and accounts unused for the Bank's TTL are
+// closed along with their credits.
+//
+// A Bank is safe for concurrent use.
+type Bank struct {
+	worker *Worker
+	limit  float64
+	ttl    time.Duration
+
+	mu       sync.Mutex
+	pricing  func() float64
+	accounts map[string]*bankAccount
+	swept    time.Time
+}
+
+// bankAccount is the balance of one client
+type bankAccount struct {
+	balance float64
+	used    time.Time
+}
+
+// NewBank creates a Bank that checks deposits with w, holds at most limit credits per client
+// and closes accounts unused for ttl. Deposits are recorded in the Worker's SpentStore so that
+// a proof can't be deposited twice; the Worker must have one.
+func NewBank(w *Worker, limit float64, ttl time.Duration) (*Bank, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("%w: limit must be greater than 0", ErrInvalidSetting)
+	}
+
+	if ttl <= 0 {
+		return nil, fmt.Errorf("%w: TTL must be greater than 0", ErrInvalidSetting)
+	}
+
+	return &Bank{
+		worker:   w,
+		limit:    limit,
+		ttl:      ttl,
+		accounts: make(map[string]*bankAccount),
+		swept:    time.Now(),
+	}, nil
+}
+
+// SetPricing sets the policy that scales the cost of requests, returning the number of
+// credits a request of cost 1 takes at the moment, such as 1 plus the share of the server's
+// workers that are busy. Prices that aren't greater than 0 are taken as 1, as is every price
+// without a policy.
+func (b *Bank) SetPricing(price func() float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pricing = price
+}
+
+// Price returns the number of credits a request of cost 1 takes at the moment
+func (b *Bank) Price() float64 {
+	b.mu.Lock()
+	pricing := b.pricing
+	b.mu.Unlock()
+
+	if pricing == nil {
+		return 1
+	}
+
+	if p := pricing(); p > 0 {
+		return p
+	}
+	return 1
+}
+
+// Value returns the credits a valid proof is worth: 1 at the Worker's difficulty, doubling
+// with every bit the proof declares above it
+func (b *Bank) Value(pow *PoWork) float64 {
+	return math.Ldexp(1, pow.difficulty-b.worker.difficulty)
+}
+
+// Deposit checks a proof bound to the subject key, and credits its Value to key's account,
+// up to the Bank's limit. It returns the credits deposited, which are 0 if the proof is
+// invalid or was deposited before, or if the account is full. The proof is held to the
+// difficulty it declares, which must be at least the Worker's.
+func (b *Bank) Deposit(ctx context.Context, key string, pow *PoWork) (float64, error) {
+	start := time.Now()
+	ok, replayed, err := b.worker.validateDeposit(pow, key)
+	b.worker.validated(ctx, "Deposit", start, pow, ok, replayed, err)
+	if err != nil || !ok {
+		return 0, err
+	}
+
+	now := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.sweep(now)
+
+	a := b.account(key, now)
+	credit := min(b.Value(pow), b.limit-a.balance)
+	a.balance += credit
+	a.used = now
+	return credit, nil
+}
+
+// Charge takes cost times the current Price from key's account, and reports whether the
+// balance covered it. A balance that doesn't cover the charge is left as it is.
+func (b *Bank) Charge(key string, cost float64) bool {
+	price := b.Price() * cost
+	now := time.Now()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	a, ok := b.live(key, now)
+	if !ok || a.balance < price {
+		return false
+	}
+
+	a.balance -= price
+	a.used = now
+	return true
+}
+
+// Balance returns the credits in key's account
+func (b *Bank) Balance(key string) float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	a, ok := b.live(key, time.Now())
+	if !ok {
+		return 0
+	}
+	return a.balance
+}
+
+// Difficulty returns the difficulty of a single proof that would cover a charge of cost
+// to key's account at the current Price, or 0 if the balance already covers it. Servers
+// challenge clients at this difficulty when a charge fails. Charges above the Bank's limit
+// can never be covered; their difficulty is capped at the Worker's MaxDifficulty.
+func (b *Bank) Difficulty(key string, cost float64) int {
+	shortfall := b.Price()*cost - b.Balance(key)
+	if shortfall <= 0 {
+		return 0
+	}
+
+	d := b.worker.difficulty
+	if shortfall > 1 {
+		d += int(math.Ceil(math.Log2(shortfall)))
+	}
+	return min(d, b.worker.MaxDifficulty())
+}
+
+// Close closes key's account, forfeiting its credits
+func (b *Bank) Close(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.accounts, key)
+}
+
+// account returns key's account, opening it if need be. The caller must hold b.mu.
+func (b *Bank) account(key string, now time.Time) *bankAccount {
+	if a, ok := b.live(key, now); ok {
+		return a
+	}
+
+	a := &bankAccount{used: now}
+	b.accounts[key] = a
+	return a
+}
+
+// live returns key's account unless it has gone unused for the TTL, in which case it is
+// closed. The caller must hold b.mu.
+func (b *Bank) live(key string, now time.Time) (*bankAccount, bool) {
+	a, ok := b.accounts[key]
+	if !ok {
+		return nil, false
+	}
+
+	if now.Sub(a.used) >= b.ttl {
+		delete(b.accounts, key)
+		return nil, false
+	}
+	return a, true
+}
+
+// sweep closes accounts unused for the TTL, at most once per TTL. The caller must hold b.mu.
+func (b *Bank) sweep(now time.Time) {
+	if now.Sub(b.swept) < b.ttl {
+		return
+	}
+
+	for key, a := range b.accounts {
+		if now.Sub(a.used) >= b.ttl {
+			delete(b.accounts, key)
+		}
+	}
+	b.swept = now
+}
+
+// validateDeposit checks a proof deposited to key's account, and records it as spent. It also
+// reports whether the proof was rejected because it was spent before.
+func (p *Worker) validateDeposit(pow *PoWork, key string) (bool, bool, error) {
+	if p.spent == nil {
+		return false, false, ErrNoSpentStore
+	}
+
+	if !pow.hasSubject(key) || pow.difficulty < p.difficulty {
+		return false, false, nil
+	}
+
+	if !p.acceptsAlgorithm(pow) || !p.acceptsAge(pow, time.Now()) {
+		return false, false, nil
+	}
+
+	seen, err := p.spent.Seen(pow)
+	if err != nil || seen {
+		return false, seen, err
+	}
+
+	ok, err := p.validateAt(p.hasherFor(pow), pow, pow.difficulty)
+	if err != nil || !ok {
+		return false, false, err
+	}
+
+	seen, err = p.spent.Add(pow, p.spentTTLFor(pow, time.Now()))
+	if err != nil {
+		return false, false, err
+	}
+
+	return !seen, seen, nil
+}
@@ -0,0 +1,129 @@
+package powork
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newBankWorker() *Worker {
+	worker := NewWorker()
+	worker.SetDifficulty(8)
+	worker.SetSpentStore(NewMemorySpentStore(100), time.Minute)
+	return worker
+}
+
+func TestBankDepositAndCharge(t *testing.T) {
+	worker := newBankWorker()
+	bank, err := NewBank(worker, 10, time.Hour)
+	if err != nil {
+		t.Fatalf("Error creating bank: %v\n", err)
+	}
+
+	pow, _ := worker.DoProofForSubject([]byte("deposit"), "alice")
+	if credit, err := bank.Deposit(context.Background(), "alice", pow); credit != 1 || err != nil {
+		t.Fatalf("Deposit credited %v, expected 1: %v\n", credit, err)
+	}
+
+	// a proof can't be deposited twice, or into someone else's account
+	if credit, _ := bank.Deposit(context.Background(), "alice", pow); credit != 0 {
+		t.Fatalf("Replayed deposit credited %v\n", credit)
+	}
+	other, _ := worker.DoProofForSubject([]byte("deposit"), "bob")
+	if credit, _ := bank.Deposit(context.Background(), "alice", other); credit != 0 {
+		t.Fatalf("Deposit of a proof for another subject credited %v\n", credit)
+	}
+
+	// two bits more work is worth four times as much
+	worker.SetDifficulty(10)
+	big, _ := worker.DoProofForSubject([]byte("big deposit"), "alice")
+	worker.SetDifficulty(8)
+	if credit, _ := bank.Deposit(context.Background(), "alice", big); credit != 4 {
+		t.Fatalf("Deposit at difficulty 10 credited %v, expected 4\n", credit)
+	}
+
+	if !bank.Charge("alice", 3) || bank.Balance("alice") != 2 {
+		t.Fatalf("Charge of 3 left a balance of %v, expected 2\n", bank.Balance("alice"))
+	}
+	if bank.Charge("alice", 3) || bank.Balance("alice") != 2 {
+		t.Fatalf("Charge over the balance went through\n")
+	}
+	if bank.Charge("bob", 1) {
+		t.Fatalf("Charge to an account without deposits went through\n")
+	}
+}
+
+func TestBankLimit(t *testing.T) {
+	worker := newBankWorker()
+	bank, _ := NewBank(worker, 3, time.Hour)
+
+	worker.SetDifficulty(10)
+	pow, _ := worker.DoProofForSubject([]byte("deposit"), "alice")
+	worker.SetDifficulty(8)
+	if credit, _ := bank.Deposit(context.Background(), "alice", pow); credit != 3 {
+		t.Fatalf("Deposit credited %v, expected the limit of 3\n", credit)
+	}
+
+	bank.Close("alice")
+	if bank.Balance("alice") != 0 {
+		t.Fatalf("Closed account has a balance of %v\n", bank.Balance("alice"))
+	}
+}
+
+func TestBankPricing(t *testing.T) {
+	worker := newBankWorker()
+	bank, _ := NewBank(worker, 100, time.Hour)
+
+	if d := bank.Difficulty("alice", 1); d != 8 {
+		t.Fatalf("Empty account asked for difficulty %d, expected 8\n", d)
+	}
+
+	load := 1.0
+	bank.SetPricing(func() float64 { return 1 + load })
+	if d := bank.Difficulty("alice", 2); d != 10 {
+		t.Fatalf("Charge of 4 credits asked for difficulty %d, expected 10\n", d)
+	}
+
+	worker.SetDifficulty(10)
+	pow, _ := worker.DoProofForSubject([]byte("deposit"), "alice")
+	worker.SetDifficulty(8)
+	bank.Deposit(context.Background(), "alice", pow)
+	if d := bank.Difficulty("alice", 2); d != 0 {
+		t.Fatalf("Covered charge asked for difficulty %d, expected 0\n", d)
+	}
+
+	// under heavier load the same request costs more
+	load = 3
+	if bank.Charge("alice", 2) {
+		t.Fatalf("Charge of 8 credits went through on a balance of 4\n")
+	}
+	load = 0
+	if !bank.Charge("alice", 2) || bank.Balance("alice") != 2 {
+		t.Fatalf("Charge at price 1 left a balance of %v, expected 2\n", bank.Balance("alice"))
+	}
+}
+
+func TestBankExpiry(t *testing.T) {
+	worker := newBankWorker()
+	bank, _ := NewBank(worker, 10, 20*time.Millisecond)
+
+	pow, _ := worker.DoProofForSubject([]byte("deposit"), "alice")
+	bank.Deposit(context.Background(), "alice", pow)
+
+	time.Sleep(40 * time.Millisecond)
+	if bank.Balance("alice") != 0 || bank.Charge("alice", 1) {
+		t.Fatalf("Unused account kept its credits past the TTL\n")
+	}
+}
+
+func TestBankSettings(t *testing.T) {
+	if _, err := NewBank(NewWorker(), 0, time.Hour); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Limit of 0 did not fail with ErrInvalidSetting: %v\n", err)
+	}
+
+	bank, _ := NewBank(NewWorker(), 10, time.Hour)
+	if _, err := bank.Deposit(context.Background(), "alice", &PoWork{}); !errors.Is(err, ErrNoSpentStore) {
+		t.Fatalf("Deposit without a spent store did not fail with ErrNoSpentStore: %v\n", err)
+	}
+}
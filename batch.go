@@ -0,0 +1,147 @@
+package powork
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DoProofForBatch calculates proofs of work for many messages at once. The messages are
+// spread over as many goroutines as the Worker's concurrency allows, and each proof is
+// searched for on a single goroutine. The Worker's timeout applies to each proof separately.
+// The results are in the order of msgs.
+func (p *Worker) DoProofForBatch(msgs [][]byte) []Result {
+	return p.DoProofForBatchContext(context.Background(), msgs)
+}
+
+// DoProofForBatchContext does the same thing as DoProofForBatch except carrying a context.
+// Proofs not found by the time ctx is done fail with its error.
+func (p *Worker) DoProofForBatchContext(ctx context.Context, msgs [][]byte) []Result {
+	results := make([]Result, len(msgs))
+
+	n := p.searchers()
+	if n > len(msgs) {
+		n = len(msgs)
+	}
+
+	// progress callbacks of concurrent searches must not run concurrently
+	var progressMu sync.Mutex
+	progress := p.progress
+	if progress != nil && n > 1 {
+		progress = func(iterations uint64, elapsed time.Duration) {
+			progressMu.Lock()
+			defer progressMu.Unlock()
+			p.progress(iterations, elapsed)
+		}
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for g := 0; g < n; g++ {
+		h := p.hasher
+		if n > 1 {
+			h = p.getHash()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				st := p.newSearchState()
+				st.progress = progress
+				pow, _, err := p.searchOne(ctx, st, h, p.newBase(msgs[i]))
+				results[i] = Result{pow, err}
+			}
+		}()
+	}
+
+	for i := range msgs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// A Validation is the outcome of validating one proof of a batch or stream
+type Validation struct {
+	Proof *PoWork
+	Valid bool
+	Err   error
+}
+
+// ValidateBatch validates many proofs at once, as ValidatePoWork does, on as many goroutines
+// as the Worker's concurrency allows. The validations are in the order of pows.
+func (p *Worker) ValidateBatch(pows []*PoWork) []Validation {
+	results := make([]Validation, len(pows))
+
+	n := p.searchers()
+	if n > len(pows) {
+		n = len(pows)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for g := 0; g < n; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				ok, err := p.ValidatePoWork(pows[i])
+				results[i] = Validation{pows[i], ok, err}
+			}
+		}()
+	}
+
+	for i := range pows {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// ValidateStream validates the proofs received from in, as ValidatePoWork does, on as many
+// goroutines as the Worker's concurrency allows, and sends each validation to the returned
+// channel, not necessarily in the order the proofs arrived. The channel is closed once in is
+// closed and drained, or ctx is done.
+func (p *Worker) ValidateStream(ctx context.Context, in <-chan *PoWork) <-chan Validation {
+	n := p.searchers()
+	out := make(chan Validation, n)
+
+	var wg sync.WaitGroup
+	for g := 0; g < n; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var pow *PoWork
+				select {
+				case <-ctx.Done():
+					return
+				case received, ok := <-in:
+					if !ok {
+						return
+					}
+					pow = received
+				}
+
+				ok, err := p.ValidatePoWork(pow)
+				select {
+				case out <- Validation{pow, ok, err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
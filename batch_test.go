@@ -0,0 +1,133 @@
+package powork
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoProofForBatch(t *testing.T) {
+	msgs := make([][]byte, 20)
+	for i := range msgs {
+		msgs[i] = []byte(fmt.Sprintf("Message number %d in the queue", i))
+	}
+
+	for _, n := range []int{1, 4} {
+		worker := NewWorker()
+		worker.SetConcurrency(n)
+
+		results := worker.DoProofForBatch(msgs)
+		if len(results) != len(msgs) {
+			t.Fatalf("Got %d results for %d messages\n", len(results), len(msgs))
+		}
+
+		for i, r := range results {
+			if r.Err != nil {
+				t.Fatalf("Proof %d failed: %v\n", i, r.Err)
+			}
+			if r.Proof.GetMessageString() != string(msgs[i]) {
+				t.Fatalf("Result %d is for %q\n", i, r.Proof.GetMessageString())
+			}
+			if ok, _ := worker.ValidatePoWork(r.Proof); !ok {
+				t.Fatalf("Proof %d is not valid\n", i)
+			}
+		}
+
+		if stats := worker.Stats(); stats.Proofs != uint64(len(msgs)) {
+			t.Fatalf("Stats counted %d proofs, expected %d\n", stats.Proofs, len(msgs))
+		}
+	}
+}
+
+func TestDoProofForBatchContext(t *testing.T) {
+	worker := NewWorker()
+	worker.SetConcurrency(2)
+	worker.SetDifficulty(100)
+
+	var concurrent, calls int32
+	worker.SetProgressInterval(1000)
+	worker.SetProgressCallback(func(iterations uint64, elapsed time.Duration) {
+		if atomic.AddInt32(&concurrent, 1) > 1 {
+			t.Errorf("Progress callbacks ran concurrently\n")
+		}
+		atomic.AddInt32(&calls, 1)
+		atomic.AddInt32(&concurrent, -1)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	for i, r := range worker.DoProofForBatchContext(ctx, [][]byte{[]byte("one"), []byte("two"), []byte("three")}) {
+		if r.Err != context.DeadlineExceeded {
+			t.Fatalf("Expected result %d to fail with the context's deadline, got %v\n", i, r.Err)
+		}
+	}
+
+	if atomic.LoadInt32(&calls) == 0 {
+		t.Fatalf("No progress was reported\n")
+	}
+
+	if results := worker.DoProofForBatch(nil); len(results) != 0 {
+		t.Fatalf("Expected no results for no messages\n")
+	}
+}
+
+func TestValidateBatch(t *testing.T) {
+	worker := NewWorker()
+	worker.SetConcurrency(4)
+
+	msgs := make([][]byte, 10)
+	for i := range msgs {
+		msgs[i] = []byte(fmt.Sprintf("Incoming message %d", i))
+	}
+
+	var pows []*PoWork
+	for _, r := range worker.DoProofForBatch(msgs) {
+		pows = append(pows, r.Proof)
+	}
+
+	// break every other proof
+	for i := 1; i < len(pows); i += 2 {
+		broken := *pows[i]
+		broken.msg = []byte("Tampered message")
+		pows[i] = &broken
+	}
+
+	for i, v := range worker.ValidateBatch(pows) {
+		if v.Proof != pows[i] || v.Err != nil {
+			t.Fatalf("Validation %d is for the wrong proof or failed: %v\n", i, v.Err)
+		}
+
+		if expected, _ := worker.ValidatePoWork(pows[i]); v.Valid != expected {
+			t.Fatalf("Validation %d is %v, expected %v\n", i, v.Valid, expected)
+		}
+	}
+
+	in := make(chan *PoWork)
+	go func() {
+		for _, pow := range pows {
+			in <- pow
+		}
+		close(in)
+	}()
+
+	seen := 0
+	for v := range worker.ValidateStream(context.Background(), in) {
+		if expected, _ := worker.ValidatePoWork(v.Proof); v.Valid != expected {
+			t.Fatalf("Streamed validation is %v, expected %v\n", v.Valid, expected)
+		}
+		seen++
+	}
+	if seen != len(pows) {
+		t.Fatalf("Stream validated %d proofs, expected %d\n", seen, len(pows))
+	}
+
+	// the output closes when the context is done, even if the input stays open
+	ctx, cancel := context.WithCancel(context.Background())
+	out := worker.ValidateStream(ctx, make(chan *PoWork))
+	cancel()
+	for range out {
+	}
+}
@@ -0,0 +1,51 @@
+package powork
+
+import "context"
+
+// SetBestEffort sets whether a search that runs out of time or iterations returns the best
+// proof it came across instead of an error. The best proof is the one whose hash has the most
+// leading zero bits, and it declares that many bits as its difficulty, which is below the
+// Worker's. Verifiers can rank such proofs with MeasureDifficulty or accept them with
+// ValidateWithMinimum, for schemes where partial work still earns something. A search that
+// is canceled through its context still fails.
+func (p *Worker) SetBestEffort(enabled bool) {
+	p.bestEffort = enabled
+}
+
+// offer records pow as the best proof of the search if its hash has more leading zero bits
+// than any before. pow is copied, since the search goes on changing it.
+func (st *searchState) offer(pow *PoWork, bits int) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.best != nil && bits <= st.bestBits {
+		return
+	}
+
+	if st.best == nil {
+		st.best = new(PoWork)
+	}
+	*st.best = *pow
+	st.bestBits = bits
+}
+
+// bestEffortProof returns the best proof of a search that ended without a proof after the
+// given number of iterations, or nil if the search is not best effort, found nothing, or was
+// canceled through ctx
+func (st *searchState) bestEffortProof(ctx context.Context, iterations int) *PoWork {
+	if !st.bestEffort || ctx.Err() == context.Canceled {
+		return nil
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.best == nil {
+		return nil
+	}
+
+	best := st.best
+	best.difficulty = st.bestBits
+	best.requiredIterations = iterations
+	return best
+}
@@ -0,0 +1,74 @@
+package powork
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBestEffortTimeout(t *testing.T) {
+	for _, n := range []int{1, 4} {
+		worker := NewWorker()
+		worker.SetConcurrency(n)
+		worker.SetDifficulty(60)
+		worker.SetTimeoutDuration(20 * time.Millisecond)
+		worker.SetBestEffort(true)
+
+		pow, err := worker.DoProofForString("pay what you can")
+		if err != nil {
+			t.Fatalf("Best effort search with %d goroutines failed: %v\n", n, err)
+		}
+
+		// a few thousand hashes find far more than a couple of zero bits
+		if pow.GetDifficulty() < 4 || pow.GetDifficulty() >= 60 {
+			t.Fatalf("Best effort proof declares difficulty %d\n", pow.GetDifficulty())
+		}
+
+		if got := worker.MeasureDifficulty(pow); got != pow.GetDifficulty() {
+			t.Fatalf("Best effort proof measures %d bits, declares %d\n", got, pow.GetDifficulty())
+		}
+
+		if ok, err := worker.ValidateWithMinimum(pow, pow.GetDifficulty()); !ok || err != nil {
+			t.Fatalf("Best effort proof does not validate at its own difficulty: %v\n", err)
+		}
+
+		if ok, _ := worker.ValidatePoWork(pow); ok {
+			t.Fatalf("Best effort proof validated at the Worker's difficulty\n")
+		}
+	}
+}
+
+func TestBestEffortMaxIterations(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(60)
+	worker.SetMaxIterations(1000)
+	worker.SetBestEffort(true)
+
+	pow, err := worker.DoProofForString("pay what you can")
+	if err != nil {
+		t.Fatalf("Best effort search failed: %v\n", err)
+	}
+	if pow.GetProof() >= 1000 || pow.GetIterations() != 1000 {
+		t.Fatalf("Best effort proof has nonce %d after %d iterations\n", pow.GetProof(), pow.GetIterations())
+	}
+}
+
+func TestBestEffortCanceled(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(60)
+	worker.SetBestEffort(true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if _, err := worker.DoProofForContext(ctx, []byte("pay what you can")); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Canceled best effort search returned %v\n", err)
+	}
+
+	// a proof that is found is returned as usual
+	worker.SetDifficulty(8)
+	pow, err := worker.DoProofForString("pay what you can")
+	if err != nil || pow.GetDifficulty() != 8 {
+		t.Fatalf("Best effort search for an easy proof returned %v at difficulty %d\n", err, pow.GetDifficulty())
+	}
+}
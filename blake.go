@@ -0,0 +1,31 @@
+package powork
+
+import (
+	"hash"
+
+	"golang.org/x/crypto/blake2b"
+	"lukechampine.com/blake3"
+)
+
+// NewWorkerBLAKE3 creates a Worker with the defaults of NewWorker, but hashing with BLAKE3
+// with a 256 bit output, which is several times faster than SHA3-512 and makes validation
+// cheap for busy servers
+func NewWorkerBLAKE3() *Worker {
+	w := NewWorkerWithHashFunc(func() hash.Hash {
+		return blake3.New(32, nil)
+	})
+	w.algorithm = "blake3-256"
+	return w
+}
+
+// NewWorkerBLAKE2b creates a Worker with the defaults of NewWorker, but hashing with
+// BLAKE2b-512
+func NewWorkerBLAKE2b() *Worker {
+	w := NewWorkerWithHashFunc(func() hash.Hash {
+		// only fails for keys longer than 64 bytes
+		h, _ := blake2b.New512(nil)
+		return h
+	})
+	w.algorithm = "blake2b-512"
+	return w
+}
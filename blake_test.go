@@ -0,0 +1,44 @@
+package powork
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+	"lukechampine.com/blake3"
+)
+
+func TestBLAKEWorkers(t *testing.T) {
+	for _, c := range []struct {
+		worker    *Worker
+		algorithm string
+		sum       func([]byte) []byte
+	}{
+		{NewWorkerBLAKE3(), "blake3-256", func(b []byte) []byte { s := blake3.Sum256(b); return s[:] }},
+		{NewWorkerBLAKE2b(), "blake2b-512", func(b []byte) []byte { s := blake2b.Sum512(b); return s[:] }},
+	} {
+		pow, err := c.worker.DoProofForString("A message hashed with BLAKE")
+		if err != nil {
+			t.Fatalf("%s: could not compute proof: %v\n", c.algorithm, err)
+		}
+
+		if pow.algorithm != c.algorithm {
+			t.Fatalf("Proof declares %s, expected %s\n", pow.algorithm, c.algorithm)
+		}
+
+		ok, err := c.worker.ValidatePoWork(pow)
+		if err != nil || !ok {
+			t.Fatalf("%s: could not validate proof: %v, %v\n", c.algorithm, ok, err)
+		}
+
+		var preimage bytes.Buffer
+		pow.writePreimage(&preimage)
+		if sum, _ := pow.sum(c.worker.validationHasher()); !bytes.Equal(sum, c.sum(preimage.Bytes())) {
+			t.Fatalf("%s: hash is not the expected algorithm\n", c.algorithm)
+		}
+
+		if ok, _ := NewWorker().ValidatePoWork(pow); ok {
+			t.Fatalf("%s: proof was valid for SHA3-512\n", c.algorithm)
+		}
+	}
+}
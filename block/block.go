@@ -0,0 +1,205 @@
+// Package block implements a toy blockchain on top of powork: blocks linked by the hash of
+// their parent, mined with a Worker against a Bitcoin-style compact target, and chains
+// compared by their cumulative work.
+//
+// It is meant for teaching and prototyping. There are no transactions, no consensus rules
+// besides work and linkage, and no network.
+package block
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+// HashSize is the size of a block hash
+const HashSize = sha256.Size
+
+// HeaderSize is the size of an encoded header: the parent hash, the timestamp, the payload
+// hash, the target bits and the nonce
+const HeaderSize = HashSize + 8 + HashSize + 4 + 8
+
+// DefaultBits is a target that takes about 2^16 hashes to meet, which a laptop mines in a
+// fraction of a second
+const DefaultBits = 0x1f00ffff
+
+// A Block commits to its parent's hash, a timestamp and a payload. Its hash is the double
+// SHA-256 of its header, read as a big-endian number, and must not exceed the target
+// encoded in Bits.
+type Block struct {
+	Prev      [HashSize]byte
+	Timestamp time.Time
+	Payload   []byte
+	Nonce     uint64
+	Bits      uint32
+}
+
+// New creates an unmined block for payload that follows parent, or a genesis block if
+// parent is nil, timestamped with the current time
+func New(parent *Block, payload []byte, bits uint32) *Block {
+	b := &Block{Timestamp: time.Now().Truncate(time.Second), Payload: payload, Bits: bits}
+	if parent != nil {
+		b.Prev = parent.Hash()
+	}
+	return b
+}
+
+// Header returns the encoded header of the block, in network byte order except for the
+// nonce, which is little-endian as powork appends it:
+//
+//	prev      [32]byte
+//	timestamp int64, Unix seconds
+//	payload   [32]byte, SHA-256 of the payload
+//	bits      uint32
+//	nonce     uint64
+func (b *Block) Header() []byte {
+	header := b.unsolvedHeader()
+	return binary.LittleEndian.AppendUint64(header, b.Nonce)
+}
+
+// unsolvedHeader is the header without its nonce, the message the Worker searches a nonce for
+func (b *Block) unsolvedHeader() []byte {
+	header := make([]byte, 0, HeaderSize)
+	header = append(header, b.Prev[:]...)
+	header = binary.BigEndian.AppendUint64(header, uint64(b.Timestamp.Unix()))
+	payload := sha256.Sum256(b.Payload)
+	header = append(header, payload[:]...)
+	return binary.BigEndian.AppendUint32(header, b.Bits)
+}
+
+// Hash returns the double SHA-256 of the block's header
+func (b *Block) Hash() [HashSize]byte {
+	first := sha256.Sum256(b.Header())
+	return sha256.Sum256(first[:])
+}
+
+// Target decodes the block's Bits
+func (b *Block) Target() (*big.Int, error) {
+	return powork.CompactToTarget(b.Bits)
+}
+
+// Work returns the expected number of hashes it took to mine the block
+func (b *Block) Work() (*big.Int, error) {
+	target, err := b.Target()
+	if err != nil {
+		return nil, err
+	}
+
+	return powork.WorkForTarget(target), nil
+}
+
+// NewWorker creates a Worker that mines blocks at bits, searching on every CPU and without
+// a timeout
+func NewWorker(bits uint32) (*powork.Worker, error) {
+	target, err := powork.CompactToTarget(bits)
+	if err != nil {
+		return nil, err
+	}
+
+	w := powork.NewWorkerWithHashFunc(powork.DoubleHash(sha256.New))
+	if err := w.SetTarget(target); err != nil {
+		return nil, err
+	}
+	w.SetConcurrency(0)
+	w.SetTimeoutDuration(0)
+	return w, nil
+}
+
+// Mine searches for a nonce that meets the block's target and sets it, giving up as soon as
+// ctx is canceled or its deadline passes
+func (b *Block) Mine(ctx context.Context) error {
+	w, err := NewWorker(b.Bits)
+	if err != nil {
+		return err
+	}
+
+	return b.MineWith(ctx, w)
+}
+
+// MineWith does the same thing as Mine with a Worker from NewWorker, which may have been
+// configured further, for example with a progress callback or an iteration limit
+func (b *Block) MineWith(ctx context.Context, w *powork.Worker) error {
+	pow, err := w.DoProofForContext(ctx, b.unsolvedHeader())
+	if err != nil {
+		return err
+	}
+
+	b.Nonce = pow.GetProof()
+	return nil
+}
+
+// CheckWork reports whether the block's hash meets its target
+func (b *Block) CheckWork() (bool, error) {
+	target, err := b.Target()
+	if err != nil {
+		return false, err
+	}
+
+	hash := b.Hash()
+	return new(big.Int).SetBytes(hash[:]).Cmp(target) <= 0, nil
+}
+
+// Validate checks a chain of blocks, genesis first: the genesis block has no parent, every
+// later block names the hash of the one before it and is not timestamped before it, and every
+// block meets its target. It does not check what target each block should have; compare
+// chains with CompareWork to pick the one that took the most work. An empty chain is valid.
+func Validate(blocks []*Block) (bool, error) {
+	var prev [HashSize]byte
+	var last time.Time
+	for i, b := range blocks {
+		if b.Prev != prev || (i > 0 && b.Timestamp.Before(last)) {
+			return false, nil
+		}
+
+		ok, err := b.CheckWork()
+		if !ok || err != nil {
+			return false, err
+		}
+
+		prev, last = b.Hash(), b.Timestamp
+	}
+
+	return true, nil
+}
+
+// TotalWork returns the cumulative work of blocks
+func TotalWork(blocks []*Block) (*big.Int, error) {
+	total := new(big.Int)
+	for _, b := range blocks {
+		work, err := b.Work()
+		if err != nil {
+			return nil, err
+		}
+		total.Add(total, work)
+	}
+	return total, nil
+}
+
+// CompareWork compares the cumulative work of two chains, returning -1 if a took less work
+// than b, 0 if as much, and +1 if more. On a tie a node usually keeps the chain it saw first.
+// The chains are assumed to be valid.
+func CompareWork(a, b []*Block) (int, error) {
+	wa, err := TotalWork(a)
+	if err != nil {
+		return 0, err
+	}
+
+	wb, err := TotalWork(b)
+	if err != nil {
+		return 0, err
+	}
+
+	return wa.Cmp(wb), nil
+}
+
+// String summarizes the block for logging and debugging
+func (b *Block) String() string {
+	hash := b.Hash()
+	return fmt.Sprintf("Block{hash: %x, prev: %x, timestamp: %s, payload: %d bytes, nonce: %d, bits: %08x}",
+		hash, b.Prev, b.Timestamp.UTC().Format(time.RFC3339), len(b.Payload), b.Nonce, b.Bits)
+}
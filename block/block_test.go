@@ -0,0 +1,129 @@
+package block
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// testBits is an easy target so that tests mine quickly
+const testBits = 0x2000ffff
+
+func mineChain(t *testing.T, n int, bits uint32) []*Block {
+	var blocks []*Block
+	var parent *Block
+	for i := 0; i < n; i++ {
+		b := New(parent, []byte(fmt.Sprintf("block %d", i)), bits)
+		if err := b.Mine(context.Background()); err != nil {
+			t.Fatalf("Error mining block %d: %v\n", i, err)
+		}
+		blocks = append(blocks, b)
+		parent = b
+	}
+	return blocks
+}
+
+func TestMine(t *testing.T) {
+	b := New(nil, []byte("genesis"), DefaultBits)
+	if err := b.Mine(context.Background()); err != nil {
+		t.Fatalf("Error mining block: %v\n", err)
+	}
+
+	ok, err := b.CheckWork()
+	if !ok || err != nil {
+		t.Fatalf("Mined block does not meet its target: %v\n", err)
+	}
+
+	if len(b.Header()) != HeaderSize {
+		t.Fatalf("Header is %d bytes, expected %d\n", len(b.Header()), HeaderSize)
+	}
+
+	b.Payload = []byte("another payload")
+	if ok, _ := b.CheckWork(); ok {
+		t.Fatalf("Block still meets its target after changing the payload\n")
+	}
+}
+
+func TestMineCanceled(t *testing.T) {
+	// a target no block will meet before the context is canceled
+	b := New(nil, []byte("genesis"), 0x03000001)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.Mine(ctx); err == nil {
+		t.Fatalf("Mining an impossible block succeeded\n")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	blocks := mineChain(t, 4, testBits)
+
+	ok, err := Validate(blocks)
+	if !ok || err != nil {
+		t.Fatalf("Chain did not validate: %v\n", err)
+	}
+
+	if ok, _ := Validate(blocks[1:]); ok {
+		t.Fatalf("Chain without its genesis block validated\n")
+	}
+
+	if ok, _ := Validate([]*Block{blocks[0], blocks[2]}); ok {
+		t.Fatalf("Chain with a missing block validated\n")
+	}
+
+	early := *blocks[1]
+	early.Timestamp = blocks[0].Timestamp.Add(-time.Hour)
+	if err := early.Mine(context.Background()); err != nil {
+		t.Fatalf("Error mining block: %v\n", err)
+	}
+	if ok, _ := Validate([]*Block{blocks[0], &early}); ok {
+		t.Fatalf("Chain with a block timestamped before its parent validated\n")
+	}
+
+	unmined := New(blocks[3], []byte("unmined"), DefaultBits)
+	for unmined.Nonce = 0; ; unmined.Nonce++ {
+		if ok, _ := unmined.CheckWork(); !ok {
+			break
+		}
+	}
+	if ok, _ := Validate(append(blocks, unmined)); ok {
+		t.Fatalf("Chain with an unmined block validated\n")
+	}
+
+	if ok, err := Validate(nil); !ok || err != nil {
+		t.Fatalf("Empty chain did not validate: %v\n", err)
+	}
+}
+
+func TestCompareWork(t *testing.T) {
+	short := mineChain(t, 2, testBits)
+	long := mineChain(t, 3, testBits)
+	hard := mineChain(t, 2, DefaultBits)
+
+	cases := []struct {
+		a, b     []*Block
+		expected int
+	}{
+		{long, short, 1},
+		{short, long, -1},
+		{short, short, 0},
+		// fewer blocks at a harder target take more work
+		{hard, long, 1},
+	}
+
+	for i, c := range cases {
+		cmp, err := CompareWork(c.a, c.b)
+		if err != nil {
+			t.Fatalf("Error comparing case %d: %v\n", i, err)
+		}
+		if cmp != c.expected {
+			t.Fatalf("Case %d compared as %d, expected %d\n", i, cmp, c.expected)
+		}
+	}
+
+	bad := []*Block{{Bits: 0x04923456}}
+	if _, err := CompareWork(bad, short); err == nil {
+		t.Fatalf("Chain with a negative target compared without error\n")
+	}
+}
@@ -0,0 +1,197 @@
+package powork
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// bloomGenerations is how many Bloom filters a BloomSpentStore spreads its capacity over.
+// Each is dropped whole once every proof in it has expired.
+const bloomGenerations = 4
+
+// BloomSpentStore is an in-memory SpentStore for verifiers that accept more proofs per TTL
+// than they can afford to remember exactly. The most recently used proofs are kept in an
+// LRU, like MemorySpentStore; proofs pushed out of it before they expire are added to a
+// Bloom filter, which takes a couple of bytes per proof whatever its size.
+//
+// The filter can mistake a fresh proof for a spent one, at about the false positive rate
+// the store is created with as long as it holds no more than its capacity, and causes the
+// proof to be rejected as a replay. It never mistakes a spent proof for a fresh one. The
+// filter is kept as a few generations that are dropped as the proofs in them expire; when
+// more unexpired proofs are pushed out of the LRU than the filter has capacity for, the
+// oldest generation is dropped early and its proofs forgotten.
+type BloomSpentStore struct {
+	mu sync.Mutex
+	// recent is guarded by mu rather than its own lock, so that the LRU and the filter
+	// change together
+	recent      *MemorySpentStore
+	size        int
+	bits        int
+	hashes      int
+	generations []*bloomGeneration
+}
+
+// bloomGeneration is one Bloom filter of a BloomSpentStore
+type bloomGeneration struct {
+	bits  []uint64
+	count int
+	// expires is when the last proof added to the filter expires
+	expires time.Time
+}
+
+// NewBloomSpentStore creates a BloomSpentStore that remembers recent proofs exactly and up to
+// capacity more in its filter, mistaking fresh proofs for spent ones at a rate of about
+// falsePositive
+func NewBloomSpentStore(recent, capacity int, falsePositive float64) (*BloomSpentStore, error) {
+	if recent < 1 || capacity < 1 {
+		return nil, fmt.Errorf("%w: capacities must be at least 1", ErrInvalidSetting)
+	}
+
+	if !(falsePositive > 0 && falsePositive < 1) {
+		return nil, fmt.Errorf("%w: false positive rate must be between 0 and 1", ErrInvalidSetting)
+	}
+
+	size := (capacity + bloomGenerations - 1) / bloomGenerations
+	// a proof is looked up in every generation, so each gets a share of the rate
+	rate := falsePositive / bloomGenerations
+	bits := int(math.Ceil(-float64(size) * math.Log(rate) / (math.Ln2 * math.Ln2)))
+	hashes := max(1, int(math.Round(float64(bits)/float64(size)*math.Ln2)))
+
+	s := &BloomSpentStore{
+		recent: NewMemorySpentStore(recent),
+		size:   size,
+		bits:   bits,
+		hashes: hashes,
+	}
+	s.recent.evicted = s.retire
+	return s, nil
+}
+
+// Seen implements SpentStore
+func (s *BloomSpentStore) Seen(pow *PoWork) (bool, error) {
+	key := SpentKey(pow)
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.recent.live(key, now) || s.filtered(key, now), nil
+}
+
+// Add implements SpentStore
+func (s *BloomSpentStore) Add(pow *PoWork, ttl time.Duration) (bool, error) {
+	key := SpentKey(pow)
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.recent.live(key, now) || s.filtered(key, now) {
+		return true, nil
+	}
+
+	s.recent.record(key, now.Add(ttl))
+	return false, nil
+}
+
+// Len returns the number of proofs the store remembers exactly and the number in its filter,
+// including expired ones not yet dropped
+func (s *BloomSpentStore) Len() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var filtered int
+	for _, g := range s.generations {
+		filtered += g.count
+	}
+	return s.recent.order.Len(), filtered
+}
+
+// Collect implements Collector, dropping the proofs whose records have expired from the
+// LRU, and the generations of the filter whose proofs have all expired
+func (s *BloomSpentStore) Collect(ctx context.Context) (int64, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.recent.collect(now) + s.expire(now), nil
+}
+
+// retire adds a proof pushed out of the LRU to the filter, unless it has expired. The caller
+// must hold s.mu.
+func (s *BloomSpentStore) retire(e *spentEntry) {
+	if !time.Now().Before(e.expires) {
+		return
+	}
+
+	n := len(s.generations)
+	if n == 0 || s.generations[n-1].count >= s.size {
+		if n == bloomGenerations {
+			s.generations = s.generations[1:]
+		}
+		s.generations = append(s.generations, &bloomGeneration{bits: make([]uint64, (s.bits+63)/64)})
+	}
+
+	g := s.generations[len(s.generations)-1]
+	h1, h2 := bloomHashes(e.key)
+	for i := 0; i < s.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % uint64(s.bits)
+		g.bits[bit/64] |= 1 << (bit % 64)
+	}
+	g.count++
+	if e.expires.After(g.expires) {
+		g.expires = e.expires
+	}
+}
+
+// filtered reports whether key may be in the filter, first dropping the generations whose
+// proofs have all expired at now. The caller must hold s.mu.
+func (s *BloomSpentStore) filtered(key string, now time.Time) bool {
+	s.expire(now)
+	if len(s.generations) == 0 {
+		return false
+	}
+
+	h1, h2 := bloomHashes(key)
+	for _, g := range s.generations {
+		found := true
+		for i := 0; i < s.hashes && found; i++ {
+			bit := (h1 + uint64(i)*h2) % uint64(s.bits)
+			found = g.bits[bit/64]&(1<<(bit%64)) != 0
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// expire drops the generations whose proofs have all expired at now, and returns how many
+// proofs they held. The caller must hold s.mu.
+func (s *BloomSpentStore) expire(now time.Time) int64 {
+	var n int64
+	live := s.generations[:0]
+	for _, g := range s.generations {
+		if now.Before(g.expires) {
+			live = append(live, g)
+		} else {
+			n += int64(g.count)
+		}
+	}
+	clear(s.generations[len(live):])
+	s.generations = live
+	return n
+}
+
+// bloomHashes derives the two hashes the filter's bit positions are computed from, from a
+// key returned by SpentKey. Keys are hex encoded digests, so their bytes are already
+// uniformly distributed.
+func bloomHashes(key string) (uint64, uint64) {
+	var b [16]byte
+	hex.Decode(b[:], []byte(key[:32]))
+	// the step must not be 0, or every hash would set the same bit
+	return binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:]) | 1
+}
@@ -0,0 +1,91 @@
+package powork
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBloomSpentStore(t *testing.T) {
+	store, err := NewBloomSpentStore(2, 100, 0.01)
+	if err != nil {
+		t.Fatalf("Error creating store: %v\n", err)
+	}
+
+	worker := NewWorker()
+	worker.SetDifficulty(8)
+	worker.SetSpentStore(store, time.Minute)
+
+	proof, _ := worker.DoProofForString("A message")
+	if ok, err := worker.ValidateOnce(proof); !ok || err != nil {
+		t.Fatalf("First use of a proof was rejected: %v\n", err)
+	}
+
+	// pushed out of the LRU, the proof is still remembered by the filter
+	for i := 0; i < 5; i++ {
+		store.Add(&PoWork{msg: []byte(fmt.Sprintf("filler %d", i))}, time.Minute)
+	}
+	if recent, filtered := store.Len(); recent != 2 || filtered != 4 {
+		t.Fatalf("Store holds %d recent and %d filtered proofs, expected 2 and 4\n", recent, filtered)
+	}
+
+	if ok, _ := worker.ValidateOnce(proof); ok {
+		t.Fatalf("Proof pushed into the filter was accepted again\n")
+	}
+}
+
+func TestBloomSpentStoreFalsePositives(t *testing.T) {
+	store, _ := NewBloomSpentStore(1, 2000, 0.01)
+	for i := 0; i < 2000; i++ {
+		store.Add(&PoWork{msg: []byte(fmt.Sprintf("spent %d", i))}, time.Minute)
+	}
+
+	var mistaken int
+	for i := 0; i < 10000; i++ {
+		if seen, _ := store.Seen(&PoWork{msg: []byte(fmt.Sprintf("fresh %d", i))}); seen {
+			mistaken++
+		}
+	}
+
+	// about 100 expected; leave room for chance
+	if mistaken > 200 {
+		t.Fatalf("Store mistook %d of 10000 fresh proofs for spent ones\n", mistaken)
+	}
+}
+
+func TestBloomSpentStoreExpiry(t *testing.T) {
+	store, _ := NewBloomSpentStore(1, 8, 0.01)
+	a := &PoWork{msg: []byte("a")}
+	b := &PoWork{msg: []byte("b")}
+
+	store.Add(a, 50*time.Millisecond)
+	store.Add(b, 50*time.Millisecond)
+	if seen, _ := store.Seen(a); !seen {
+		t.Fatalf("Filtered proof was not seen\n")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if seen, _ := store.Seen(a); seen {
+		t.Fatalf("Expired filtered proof was still seen\n")
+	}
+	if _, filtered := store.Len(); filtered != 0 {
+		t.Fatalf("Store kept %d expired proofs in its filter\n", filtered)
+	}
+
+	// proofs that expire in the LRU never reach the filter
+	store.Add(a, time.Minute)
+	if _, filtered := store.Len(); filtered != 0 {
+		t.Fatalf("Expired proof was pushed into the filter\n")
+	}
+}
+
+func TestBloomSpentStoreSettings(t *testing.T) {
+	if _, err := NewBloomSpentStore(0, 100, 0.01); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Empty LRU did not fail with ErrInvalidSetting: %v\n", err)
+	}
+
+	if _, err := NewBloomSpentStore(10, 100, 1); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("False positive rate of 1 did not fail with ErrInvalidSetting: %v\n", err)
+	}
+}
@@ -0,0 +1,110 @@
+package powork
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// benchmarkDuration is how long Calibrate measures the hash rate for
+	benchmarkDuration = 200 * time.Millisecond
+	// estimateDuration is how long EstimateDuration measures the hash rate for
+	estimateDuration = 50 * time.Millisecond
+)
+
+// EstimateIterations returns the expected number of hashes needed to find a proof of the
+// given difficulty, which is 2^difficulty
+func EstimateIterations(difficulty int) float64 {
+	if difficulty <= 0 {
+		return 1
+	}
+
+	return math.Exp2(float64(difficulty))
+}
+
+// EstimateDuration returns the expected time this machine needs to find a proof of the given
+// difficulty with the Worker's hash and concurrency. It measures the hash rate for a short
+// moment each time it is called.
+func (p *Worker) EstimateDuration(difficulty int) (time.Duration, error) {
+	if difficulty <= 0 {
+		return 0, fmt.Errorf("%w: must be at least 1", ErrInvalidDifficulty)
+	}
+
+	rate, err := p.measureHashRate(estimateDuration)
+	if err != nil {
+		return 0, err
+	}
+
+	return durationForRate(rate, difficulty), nil
+}
+
+// durationForRate returns the expected time to find a proof of the given difficulty at rate
+// hashes per second, saturating at the largest time.Duration
+func durationForRate(rate float64, difficulty int) time.Duration {
+	ns := EstimateIterations(difficulty) / rate * float64(time.Second)
+	if ns >= math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+
+	return time.Duration(ns)
+}
+
+// Calibrate measures how fast this machine computes the Worker's hash and returns the
+// difficulty whose expected solve time is closest to target. The Worker's difficulty is
+// left unchanged. The measurement takes a fraction of a second.
+func (p *Worker) Calibrate(target time.Duration) (int, error) {
+	if target <= 0 {
+		return 0, fmt.Errorf("%w: calibration target must be greater than 0", ErrInvalidSetting)
+	}
+
+	rate, err := p.measureHashRate(benchmarkDuration)
+	if err != nil {
+		return 0, err
+	}
+
+	return difficultyForTime(rate, target, p.hasher.Size()*8), nil
+}
+
+// difficultyForTime returns the difficulty, between 1 and maxBits, whose expected solve time
+// at rate hashes per second is closest to target
+func difficultyForTime(rate float64, target time.Duration, maxBits int) int {
+	expected := func(d int) float64 {
+		return EstimateIterations(d) / rate
+	}
+
+	want := target.Seconds()
+	best := 1
+	for d := 2; d <= maxBits; d++ {
+		if math.Abs(expected(d)-want) < math.Abs(expected(best)-want) {
+			best = d
+		}
+		if expected(d) > want {
+			break
+		}
+	}
+	return best
+}
+
+// measureHashRate computes hashes the way a proof search does for d and returns the number
+// of hashes per second, scaled by the number of goroutines a search would use
+func (p *Worker) measureHashRate(d time.Duration) (float64, error) {
+	h := p.validationHasher()
+	pow := p.newBase([]byte("powork calibration message"))
+
+	start := time.Now()
+	var hashes uint64
+	for {
+		for i := 0; i < 1024; i++ {
+			if _, err := p.validateAt(h, pow, 1); err != nil {
+				return 0, err
+			}
+			pow.proof++
+		}
+		hashes += 1024
+
+		if elapsed := time.Since(start); elapsed >= d {
+			return float64(hashes) / elapsed.Seconds() * float64(p.searchers()), nil
+		}
+	}
+}
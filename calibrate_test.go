@@ -0,0 +1,85 @@
+package powork
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDifficultyForTime(t *testing.T) {
+	// at 1024 hashes per second a 10 bit proof takes a second on average
+	cases := []struct {
+		target time.Duration
+		want   int
+	}{
+		{time.Second, 10},
+		{2 * time.Second, 11},
+		{1100 * time.Millisecond, 10},
+		{1900 * time.Millisecond, 11},
+		{time.Nanosecond, 1},
+		{24 * time.Hour, 26},
+		{1000000 * time.Hour, 32},
+	}
+
+	for _, c := range cases {
+		if got := difficultyForTime(1024, c.target, 32); got != c.want {
+			t.Fatalf("Difficulty for %v is %v, expected %v\n", c.target, got, c.want)
+		}
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	worker := NewWorker()
+
+	d, err := worker.Calibrate(100 * time.Millisecond)
+	if err != nil {
+		t.Fatalf("Calibration failed: %v\n", err)
+	}
+
+	if d < 1 || d > 512 {
+		t.Fatalf("Calibrated difficulty %v is out of range\n", d)
+	}
+
+	if worker.GetDifficulty() != 10 {
+		t.Fatalf("Calibration changed the worker's difficulty\n")
+	}
+
+	longer, _ := worker.Calibrate(100 * time.Second)
+	if longer <= d {
+		t.Fatalf("A longer target did not increase the difficulty: %v <= %v\n", longer, d)
+	}
+
+	if _, err := worker.Calibrate(0); err == nil {
+		t.Fatalf("Calibration target of 0 was accepted\n")
+	}
+}
+
+func TestEstimateIterations(t *testing.T) {
+	if EstimateIterations(10) != 1024 || EstimateIterations(0) != 1 {
+		t.Fatalf("Wrong iteration estimates\n")
+	}
+}
+
+func TestEstimateDuration(t *testing.T) {
+	if d := durationForRate(1024, 10); d != time.Second {
+		t.Fatalf("10 bits at 1024 hashes per second is estimated at %v\n", d)
+	}
+
+	if d := durationForRate(1, 400); d != time.Duration(1<<63-1) {
+		t.Fatalf("Huge estimate did not saturate: %v\n", d)
+	}
+
+	worker := NewWorker()
+	short, err := worker.EstimateDuration(8)
+	if err != nil || short <= 0 {
+		t.Fatalf("Could not estimate duration: %v\n", err)
+	}
+
+	long, _ := worker.EstimateDuration(30)
+	if long <= short {
+		t.Fatalf("Harder proof is not estimated to take longer: %v <= %v\n", long, short)
+	}
+
+	if _, err := worker.EstimateDuration(0); err == nil {
+		t.Fatalf("Difficulty of 0 was accepted\n")
+	}
+}
@@ -0,0 +1,121 @@
+package powork
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"sync"
+)
+
+// Hash returns the SHA-256 digest of everything the proof's work covers. Each link of a Chain
+// commits to the Hash of the link before it.
+func (p *PoWork) Hash() []byte {
+	h := sha256.New()
+	p.writePreimage(h)
+	return h.Sum(nil)
+}
+
+// GetPrevious gets the Hash of the proof this one follows in a Chain, or nil if it is the
+// first link or not part of a chain
+func (p *PoWork) GetPrevious() []byte {
+	return p.previous
+}
+
+// A Chain is a sequence of proofs of work in which every proof is bound to the Hash of the one
+// before it, so that no link can be changed or removed without redoing the work of every link
+// after it. A Chain is safe for concurrent use; appends take place one after the other.
+type Chain struct {
+	worker *Worker
+
+	mu    sync.Mutex
+	links []*PoWork
+}
+
+// NewChain creates an empty Chain whose links are computed by w
+func NewChain(w *Worker) *Chain {
+	return &Chain{worker: w}
+}
+
+// Append computes a proof of work for msg bound to the head of the chain and adds it as the
+// new head
+func (c *Chain) Append(msg []byte) (*PoWork, error) {
+	return c.AppendContext(context.Background(), msg)
+}
+
+// AppendContext does the same thing as Append, giving up as soon as ctx is canceled or its
+// deadline passes. The chain is unchanged if no proof is found.
+func (c *Chain) AppendContext(ctx context.Context, msg []byte) (*PoWork, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	base := c.worker.newBase(msg)
+	if n := len(c.links); n > 0 {
+		base.previous = c.links[n-1].Hash()
+	}
+
+	pow, err := c.worker.doProof(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+
+	c.links = append(c.links, pow)
+	return pow, nil
+}
+
+// Links returns the proofs in the chain, first to last
+func (c *Chain) Links() []*PoWork {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]*PoWork(nil), c.links...)
+}
+
+// Len returns the number of proofs in the chain
+func (c *Chain) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.links)
+}
+
+// Head returns the last proof in the chain, or nil if it is empty
+func (c *Chain) Head() *PoWork {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.links) == 0 {
+		return nil
+	}
+	return c.links[len(c.links)-1]
+}
+
+// Verify checks the whole chain with its Worker, as by VerifyChain
+func (c *Chain) Verify() (bool, error) {
+	return VerifyChain(c.worker, c.Links())
+}
+
+// VerifyChain checks that links form a chain as built by Chain: the first link follows no
+// other proof, each later link is bound to the Hash of the one before it, and every link is
+// a valid proof of work for w. The Worker's maximum proof age is not applied, since the early
+// links of a long chain are expected to be old. An empty chain is valid.
+func VerifyChain(w *Worker, links []*PoWork) (bool, error) {
+	var prev []byte
+	for _, pow := range links {
+		if !bytes.Equal(pow.previous, prev) {
+			return false, nil
+		}
+
+		if !w.acceptsAlgorithm(pow) {
+			return false, nil
+		}
+
+		ok, err := w.validate(w.hasherFor(pow), pow)
+		if !ok || err != nil {
+			return false, err
+		}
+
+		prev = pow.Hash()
+	}
+
+	return true, nil
+}
@@ -0,0 +1,121 @@
+package powork
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	worker := NewWorker()
+	chain := NewChain(worker)
+
+	if chain.Head() != nil {
+		t.Fatalf("Empty chain has a head\n")
+	}
+
+	for i := 0; i < 3; i++ {
+		pow, err := chain.Append([]byte(fmt.Sprintf("link %d", i)))
+		if err != nil {
+			t.Fatalf("Error appending link %d: %v\n", i, err)
+		}
+
+		if chain.Head() != pow {
+			t.Fatalf("Appended link %d is not the head\n", i)
+		}
+	}
+
+	links := chain.Links()
+	if len(links) != 3 || chain.Len() != 3 {
+		t.Fatalf("Chain has %d links, expected 3\n", len(links))
+	}
+
+	if links[0].GetPrevious() != nil {
+		t.Fatalf("First link follows another proof\n")
+	}
+
+	for i := 1; i < len(links); i++ {
+		if !bytes.Equal(links[i].GetPrevious(), links[i-1].Hash()) {
+			t.Fatalf("Link %d is not bound to link %d\n", i, i-1)
+		}
+	}
+
+	ok, err := chain.Verify()
+	if !ok || err != nil {
+		t.Fatalf("Chain did not verify: %v\n", err)
+	}
+}
+
+func TestVerifyChainRejectsTampering(t *testing.T) {
+	worker := NewWorker()
+	chain := NewChain(worker)
+	for i := 0; i < 3; i++ {
+		if _, err := chain.Append([]byte(fmt.Sprintf("link %d", i))); err != nil {
+			t.Fatalf("Error appending link %d: %v\n", i, err)
+		}
+	}
+	links := chain.Links()
+
+	if ok, _ := VerifyChain(worker, []*PoWork{links[0], links[2]}); ok {
+		t.Fatalf("Chain with a removed link verified\n")
+	}
+
+	if ok, _ := VerifyChain(worker, links[1:]); ok {
+		t.Fatalf("Chain starting at a later link verified\n")
+	}
+
+	// a valid proof for another message does not fit into the chain
+	replaced, err := worker.DoProofFor([]byte("another link"))
+	if err != nil {
+		t.Fatalf("Error doing proof: %v\n", err)
+	}
+	if ok, _ := VerifyChain(worker, []*PoWork{links[0], replaced, links[2]}); ok {
+		t.Fatalf("Chain with a replaced link verified\n")
+	}
+
+	// rebinding the last link breaks its work
+	rebound := *links[2]
+	rebound.previous = links[0].Hash()
+	if ok, _ := VerifyChain(worker, []*PoWork{links[0], &rebound}); ok {
+		t.Fatalf("Rebound link verified\n")
+	}
+
+	if ok, err := VerifyChain(worker, nil); !ok || err != nil {
+		t.Fatalf("Empty chain did not verify: %v\n", err)
+	}
+}
+
+func TestChainLinkRoundTrip(t *testing.T) {
+	chain := NewChain(NewWorker())
+	chain.Append([]byte("first"))
+	pow, err := chain.Append([]byte("second"))
+	if err != nil {
+		t.Fatalf("Error appending link: %v\n", err)
+	}
+
+	data, err := pow.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Error encoding link: %v\n", err)
+	}
+
+	var decoded PoWork
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Error decoding link: %v\n", err)
+	}
+	if !bytes.Equal(decoded.GetPrevious(), pow.GetPrevious()) {
+		t.Fatalf("Decoded link follows %x, expected %x\n", decoded.GetPrevious(), pow.GetPrevious())
+	}
+
+	js, err := pow.MarshalJSON()
+	if err != nil {
+		t.Fatalf("Error encoding link as JSON: %v\n", err)
+	}
+
+	var fromJSON PoWork
+	if err := fromJSON.UnmarshalJSON(js); err != nil {
+		t.Fatalf("Error decoding link from JSON: %v\n", err)
+	}
+	if !bytes.Equal(fromJSON.Hash(), pow.Hash()) {
+		t.Fatalf("Link decoded from JSON has a different hash\n")
+	}
+}
@@ -0,0 +1,338 @@
+package powork
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// challengeSize is the number of random bytes in a challenge issued by NewChallenge
+const challengeSize = 16
+
+// A signed challenge is laid out as
+//
+//	version    uint8
+//	expires    int64 (Unix milliseconds)
+//	difficulty uint16
+//	random     [challengeSize]byte
+//	mac        [sha256.Size]byte
+//
+// where mac is the HMAC-SHA256 of everything before it under the Worker's challenge key.
+const (
+	signedChallengeVersion = 1
+	signedChallengeBodyLen = 1 + 8 + 2 + challengeSize
+	signedChallengeLen     = signedChallengeBodyLen + sha256.Size
+)
+
+// A Challenge is a random value issued by a server. Proofs bound to a challenge can't be
+// computed before the server hands the challenge out.
+type Challenge struct {
+	value      []byte
+	expires    time.Time
+	difficulty int
+}
+
+// ChallengeFromBytes rebuilds a challenge received from a server, so that a prover can
+// compute a proof for it with DoProofForChallenge
+func ChallengeFromBytes(b []byte) *Challenge {
+	return &Challenge{value: append([]byte(nil), b...)}
+}
+
+// GetBytes gets the random value of the challenge, which is what a server sends to provers
+func (c *Challenge) GetBytes() []byte {
+	return c.value
+}
+
+// GetExpiry gets the time after which the issuing Worker stops accepting proofs for the
+// challenge. It is the zero time for challenges built with ChallengeFromBytes.
+func (c *Challenge) GetExpiry() time.Time {
+	return c.expires
+}
+
+// GetDifficulty gets the difficulty the issuing Worker demands for the challenge. It is 0
+// for challenges built with ChallengeFromBytes.
+func (c *Challenge) GetDifficulty() int {
+	return c.difficulty
+}
+
+// SetChallengeKey makes the Worker issue signed challenges. A signed challenge carries its
+// own expiry and difficulty, authenticated with HMAC-SHA256 under key, so any Worker sharing
+// the key can validate proofs for it without having issued it. Signed challenges are not
+// remembered, so they are only single use if the Worker has a SpentStore, which records each
+// answered challenge until it expires; Workers sharing the key should share the store too.
+// Passing a nil key switches back to remembered challenges.
+func (p *Worker) SetChallengeKey(key []byte) error {
+	if key == nil {
+		p.challengeKey = nil
+		return nil
+	}
+
+	if len(key) < 16 {
+		return fmt.Errorf("%w: challenge key must be at least 16 bytes", ErrInvalidSetting)
+	}
+
+	p.challengeKey = append([]byte(nil), key...)
+	return nil
+}
+
+// SignsChallenges reports whether the Worker has a challenge key
+func (p *Worker) SignsChallenges() bool {
+	return p.challengeKey != nil
+}
+
+// SetChallengeTTL sets how long challenges issued by NewChallenge can be answered. The default is one minute.
+func (p *Worker) SetChallengeTTL(d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("%w: challenge TTL must be greater than 0", ErrInvalidSetting)
+	}
+
+	p.challengeTTL = d
+	return nil
+}
+
+// NewChallenge issues a new random challenge that expires after the Worker's challenge TTL.
+// Unless the Worker has a challenge key, it remembers the challenge until it is answered or
+// expires.
+func (p *Worker) NewChallenge() (*Challenge, error) {
+	return p.NewChallengeWithDifficulty(p.difficulty)
+}
+
+// NewChallengeWithDifficulty issues a challenge as NewChallenge does that demands the given
+// difficulty instead of the Worker's, for example to make a suspicious client work harder.
+// Proofs for it are held to the higher of the two, unless the Worker signs its challenges:
+// a signed challenge carries its difficulty, which proofs are held to alone, so that it can
+// demand less than the Worker's.
+func (p *Worker) NewChallengeWithDifficulty(difficulty int) (*Challenge, error) {
+	if difficulty <= 0 {
+		return nil, fmt.Errorf("%w: must be at least 1", ErrInvalidDifficulty)
+	}
+
+	if bits := p.MaxDifficulty(); difficulty > bits {
+		return nil, fmt.Errorf("%w: %v bits requested, hash has %v", ErrDifficultyExceedsHash, difficulty, bits)
+	}
+
+	b := make([]byte, challengeSize)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	c := &Challenge{value: b, expires: time.Now().Add(p.challengeTTL), difficulty: difficulty}
+	if p.challengeKey != nil {
+		if difficulty > math.MaxUint16 {
+			return nil, fmt.Errorf("%w: does not fit in a signed challenge", ErrInvalidDifficulty)
+		}
+
+		c.value = p.signChallenge(c.expires, c.difficulty, b)
+		return c, nil
+	}
+
+	p.challenges.add(c)
+	return c, nil
+}
+
+// signChallenge lays out and authenticates a signed challenge
+func (p *Worker) signChallenge(expires time.Time, difficulty int, random []byte) []byte {
+	v := make([]byte, signedChallengeBodyLen, signedChallengeLen)
+	v[0] = signedChallengeVersion
+	binary.BigEndian.PutUint64(v[1:], uint64(expires.UnixMilli()))
+	binary.BigEndian.PutUint16(v[9:], uint16(difficulty))
+	copy(v[11:], random)
+
+	mac := hmac.New(sha256.New, p.challengeKey)
+	mac.Write(v)
+	return mac.Sum(v)
+}
+
+// openChallenge authenticates a signed challenge and returns its expiry and difficulty
+func (p *Worker) openChallenge(v []byte) (time.Time, int, bool) {
+	if len(v) != signedChallengeLen || v[0] != signedChallengeVersion {
+		return time.Time{}, 0, false
+	}
+
+	mac := hmac.New(sha256.New, p.challengeKey)
+	mac.Write(v[:signedChallengeBodyLen])
+	if !hmac.Equal(mac.Sum(nil), v[signedChallengeBodyLen:]) {
+		return time.Time{}, 0, false
+	}
+
+	expires := time.UnixMilli(int64(binary.BigEndian.Uint64(v[1:])))
+	return expires, int(binary.BigEndian.Uint16(v[9:])), true
+}
+
+// DoProofForChallenge calculates a proof of work for msg bound to challenge c
+func (p *Worker) DoProofForChallenge(c *Challenge, msg []byte) (*PoWork, error) {
+	return p.DoProofForChallengeContext(context.Background(), c, msg)
+}
+
+// DoProofForChallengeContext does the same thing as DoProofForChallenge except carrying a context
+func (p *Worker) DoProofForChallengeContext(ctx context.Context, c *Challenge, msg []byte) (*PoWork, error) {
+	if len(c.value) == 0 {
+		return nil, fmt.Errorf("%w: challenge must not be empty", ErrInvalidEncoding)
+	}
+
+	base := p.newBase(msg)
+	base.challenge = c.value
+	return p.doProof(ctx, base)
+}
+
+// ValidateChallenge checks a proof bound to a challenge. The proof is valid if the work
+// checks out and its challenge was issued by this Worker, has not expired and has not been
+// answered before. A challenge issued with a higher difficulty than the Worker's holds the
+// work to that difficulty. A valid proof uses its challenge up.
+//
+// If the Worker has a challenge key, the challenge instead has to carry a valid signature
+// under that key and the work is checked against the difficulty in the challenge. The
+// challenge is used up in the Worker's SpentStore if it has one, and not at all otherwise.
+func (p *Worker) ValidateChallenge(pow *PoWork) (bool, error) {
+	return p.ValidateChallengeContext(context.Background(), pow)
+}
+
+// ValidateChallengeContext does the same thing as ValidateChallenge, passing ctx to the
+// Worker's hooks so that the validation can be traced as part of a request
+func (p *Worker) ValidateChallengeContext(ctx context.Context, pow *PoWork) (bool, error) {
+	start := time.Now()
+	ok, replayed, err := p.validateChallenge(pow)
+	p.validated(ctx, "ValidateChallenge", start, pow, ok, replayed, err)
+	return ok, err
+}
+
+// validateChallenge does the work of ValidateChallenge, also reporting whether the proof was
+// rejected because its challenge was not outstanding
+func (p *Worker) validateChallenge(pow *PoWork) (bool, bool, error) {
+	if len(pow.challenge) == 0 {
+		return false, false, nil
+	}
+
+	if p.challengeKey != nil {
+		return p.validateSignedChallenge(pow)
+	}
+
+	ok, err := p.validatePoWork(pow)
+	if err != nil || !ok {
+		return false, false, err
+	}
+
+	difficulty, ok := p.challenges.consume(pow.challenge, time.Now())
+	if !ok {
+		return false, true, nil
+	}
+
+	if difficulty > p.difficulty {
+		ok, err := p.validateAt(p.hasherFor(pow), pow, difficulty)
+		return ok, false, err
+	}
+	return true, false, nil
+}
+
+// validateSignedChallenge does the work of validateChallenge for a Worker with a challenge key
+func (p *Worker) validateSignedChallenge(pow *PoWork) (bool, bool, error) {
+	now := time.Now()
+	expires, difficulty, ok := p.openChallenge(pow.challenge)
+	if !ok || !now.Before(expires) || difficulty <= 0 {
+		return false, false, nil
+	}
+
+	if !p.acceptsAlgorithm(pow) || !p.acceptsAge(pow, now) {
+		return false, false, nil
+	}
+
+	// the challenge itself is recorded, so that a second proof for it is a replay too
+	answered := &PoWork{challenge: pow.challenge}
+	if p.spent != nil {
+		seen, err := p.spent.Seen(answered)
+		if err != nil || seen {
+			return false, seen, err
+		}
+	}
+
+	ok, err := p.validateAt(p.hasherFor(pow), pow, difficulty)
+	if err != nil || !ok || p.spent == nil {
+		return ok, false, err
+	}
+
+	seen, err := p.spent.Add(answered, expires.Sub(now))
+	if err != nil {
+		return false, false, err
+	}
+	return !seen, seen, nil
+}
+
+// GetChallenge gets the challenge the proof is bound to, or nil if there is none
+func (p *PoWork) GetChallenge() []byte {
+	return p.challenge
+}
+
+// challengeSet remembers the challenges issued by a Worker until they expire
+type challengeSet struct {
+	mu      sync.Mutex
+	issued  map[string]issuedChallenge
+	swept   time.Time
+	longest time.Duration
+}
+
+// issuedChallenge is what a challengeSet remembers about a challenge
+type issuedChallenge struct {
+	expires    time.Time
+	difficulty int
+}
+
+func newChallengeSet() *challengeSet {
+	return &challengeSet{issued: make(map[string]issuedChallenge), swept: time.Now()}
+}
+
+func (s *challengeSet) add(c *Challenge) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ttl := c.expires.Sub(now); ttl > s.longest {
+		s.longest = ttl
+	}
+
+	// drop expired challenges at most once per TTL
+	if now.Sub(s.swept) > s.longest {
+		for k, issued := range s.issued {
+			if now.After(issued.expires) {
+				delete(s.issued, k)
+			}
+		}
+		s.swept = now
+	}
+
+	s.issued[string(c.value)] = issuedChallenge{expires: c.expires, difficulty: c.difficulty}
+}
+
+// consume reports whether challenge was issued and is unexpired at now, and the difficulty
+// it was issued with, and forgets it
+func (s *challengeSet) consume(challenge []byte, now time.Time) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issued, ok := s.issued[string(challenge)]
+	if !ok {
+		return 0, false
+	}
+
+	delete(s.issued, string(challenge))
+	return issued.difficulty, now.Before(issued.expires)
+}
+
+// peek reports whether challenge was issued and is unexpired at now, and the difficulty it
+// was issued with, without forgetting it
+func (s *challengeSet) peek(challenge []byte, now time.Time) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issued, ok := s.issued[string(challenge)]
+	if !ok {
+		return 0, false
+	}
+
+	return issued.difficulty, now.Before(issued.expires)
+}
@@ -0,0 +1,280 @@
+package powork
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/sha3"
+)
+
+func TestChallengeProof(t *testing.T) {
+	server := NewWorker()
+	challenge, err := server.NewChallenge()
+	if err != nil {
+		t.Fatalf("Could not issue challenge: %v\n", err)
+	}
+
+	// the client only gets to see the challenge bytes
+	client := NewWorker()
+	proof, err := client.DoProofForChallenge(ChallengeFromBytes(challenge.GetBytes()), []byte("A message bound to a challenge"))
+	if err != nil {
+		t.Fatalf("Could not prove challenge: %v\n", err)
+	}
+
+	ok, err := server.ValidateChallenge(proof)
+	if err != nil || !ok {
+		t.Fatalf("Challenge proof did not validate: %v\n", err)
+	}
+
+	// challenges are single use
+	ok, _ = server.ValidateChallenge(proof)
+	if ok {
+		t.Fatalf("Challenge was accepted twice\n")
+	}
+}
+
+func TestChallengeNotIssued(t *testing.T) {
+	server := NewWorker()
+	other := NewWorker()
+	challenge, _ := other.NewChallenge()
+
+	proof, _ := NewWorker().DoProofForChallenge(challenge, []byte("A message bound to a challenge"))
+	ok, err := server.ValidateChallenge(proof)
+	if err != nil || ok {
+		t.Fatalf("Challenge issued by another worker was accepted\n")
+	}
+
+	// a plain proof has no challenge at all
+	plain, _ := NewWorker().DoProofForString("A message bound to nothing")
+	ok, _ = server.ValidateChallenge(plain)
+	if ok {
+		t.Fatalf("Proof without a challenge was accepted\n")
+	}
+}
+
+func TestChallengeExpired(t *testing.T) {
+	server := NewWorker()
+	server.SetChallengeTTL(50 * time.Millisecond)
+	challenge, _ := server.NewChallenge()
+
+	proof, _ := NewWorker().DoProofForChallenge(challenge, []byte("A message bound to a challenge"))
+	time.Sleep(100 * time.Millisecond)
+
+	ok, err := server.ValidateChallenge(proof)
+	if err != nil || ok {
+		t.Fatalf("Expired challenge was accepted\n")
+	}
+}
+
+func TestChallengeIsBound(t *testing.T) {
+	server := NewWorker()
+	challenge, _ := server.NewChallenge()
+	proof, _ := NewWorker().DoProofForChallenge(challenge, []byte("A message bound to a challenge"))
+
+	// swapping in a different challenge breaks the proof
+	other, _ := server.NewChallenge()
+	proof.challenge = other.GetBytes()
+	ok, _ := server.ValidateChallenge(proof)
+	if ok {
+		t.Fatalf("Proof was accepted for a challenge it was not computed for\n")
+	}
+}
+
+func TestChallengeEncoding(t *testing.T) {
+	server := NewWorker()
+	challenge, _ := server.NewChallenge()
+	proof, _ := NewWorker().DoProofForChallenge(challenge, []byte("A message bound to a challenge"))
+
+	data, err := proof.MarshalBinary()
+	if err != nil || data[0] != binaryVersionFields {
+		t.Fatalf("Could not marshal challenge proof: %v\n", err)
+	}
+
+	decoded := new(PoWork)
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Could not unmarshal challenge proof: %v\n", err)
+	}
+
+	if !bytes.Equal(decoded.GetChallenge(), challenge.GetBytes()) {
+		t.Fatalf("Decoded proof lost its challenge\n")
+	}
+
+	var fromJSON PoWork
+	j, _ := json.Marshal(proof)
+	if err := json.Unmarshal(j, &fromJSON); err != nil || !bytes.Equal(fromJSON.GetChallenge(), challenge.GetBytes()) {
+		t.Fatalf("JSON round trip lost the challenge: %s\n", j)
+	}
+
+	ok, err := server.ValidateChallenge(decoded)
+	if err != nil || !ok {
+		t.Fatalf("Decoded challenge proof did not validate: %v\n", err)
+	}
+}
+
+func TestSignedChallenge(t *testing.T) {
+	key := []byte("a secret shared by every server")
+
+	issuer := NewWorker()
+	issuer.SetDifficulty(12)
+	if err := issuer.SetChallengeKey(key); err != nil {
+		t.Fatalf("Could not set challenge key: %v\n", err)
+	}
+
+	challenge, err := issuer.NewChallenge()
+	if err != nil {
+		t.Fatalf("Could not issue challenge: %v\n", err)
+	}
+
+	client := NewWorker()
+	client.SetDifficulty(challenge.GetDifficulty())
+	proof, _ := client.DoProofForChallenge(ChallengeFromBytes(challenge.GetBytes()), []byte("A message bound to a signed challenge"))
+
+	// a different server with the same key, and a lower configured difficulty, validates
+	// the proof against the difficulty carried in the challenge
+	verifier := NewWorker()
+	verifier.SetDifficulty(1)
+	verifier.SetChallengeKey(key)
+	ok, err := verifier.ValidateChallenge(proof)
+	if err != nil || !ok {
+		t.Fatalf("Signed challenge proof did not validate on another server: %v\n", err)
+	}
+
+	// a server with a different key rejects it
+	stranger := NewWorker()
+	stranger.SetChallengeKey([]byte("a different secret altogether"))
+	ok, _ = stranger.ValidateChallenge(proof)
+	if ok {
+		t.Fatalf("Signed challenge was accepted under the wrong key\n")
+	}
+}
+
+func TestSignedChallengeTampered(t *testing.T) {
+	key := []byte("a secret shared by every server")
+
+	issuer := NewWorker()
+	issuer.SetDifficulty(12)
+	issuer.SetChallengeKey(key)
+	challenge, _ := issuer.NewChallenge()
+
+	// lowering the difficulty inside the challenge breaks its signature
+	forged := append([]byte(nil), challenge.GetBytes()...)
+	forged[10] = 1
+
+	client := NewWorker()
+	client.SetDifficulty(1)
+	proof, _ := client.DoProofForChallenge(ChallengeFromBytes(forged), []byte("A message bound to a forged challenge"))
+	ok, _ := issuer.ValidateChallenge(proof)
+	if ok {
+		t.Fatalf("Tampered signed challenge was accepted\n")
+	}
+}
+
+func TestSignedChallengeExpired(t *testing.T) {
+	issuer := NewWorker()
+	issuer.SetChallengeKey([]byte("a secret shared by every server"))
+	issuer.SetChallengeTTL(50 * time.Millisecond)
+	challenge, _ := issuer.NewChallenge()
+
+	proof, _ := NewWorker().DoProofForChallenge(challenge, []byte("A message bound to a signed challenge"))
+	time.Sleep(100 * time.Millisecond)
+
+	ok, _ := issuer.ValidateChallenge(proof)
+	if ok {
+		t.Fatalf("Expired signed challenge was accepted\n")
+	}
+
+	if err := issuer.SetChallengeKey([]byte("short")); err == nil {
+		t.Fatalf("Short challenge key was accepted\n")
+	}
+}
+
+func TestSignedChallengeSpent(t *testing.T) {
+	key := []byte("a secret shared by every server")
+	store := NewMemorySpentStore(100)
+
+	issuer := NewWorker()
+	issuer.SetChallengeKey(key)
+	issuer.SetSpentStore(store, time.Minute)
+	challenge, _ := issuer.NewChallenge()
+
+	client := NewWorker()
+	proof, _ := client.DoProofForChallenge(challenge, []byte("A message bound to a signed challenge"))
+	if ok, err := issuer.ValidateChallenge(proof); !ok || err != nil {
+		t.Fatalf("Signed challenge proof did not validate: %v\n", err)
+	}
+
+	// neither the same proof nor another one for the challenge gets in again, on any server
+	// sharing the store
+	verifier := NewWorker()
+	verifier.SetChallengeKey(key)
+	verifier.SetSpentStore(store, time.Minute)
+	if ok, _ := verifier.ValidateChallenge(proof); ok {
+		t.Fatalf("Replayed signed challenge proof was accepted\n")
+	}
+	other, _ := client.DoProofForChallenge(challenge, []byte("Another message"))
+	if ok, _ := issuer.ValidateChallenge(other); ok {
+		t.Fatalf("Second proof for an answered signed challenge was accepted\n")
+	}
+}
+
+func TestChallengeWithDifficulty(t *testing.T) {
+	for _, key := range [][]byte{nil, []byte("a challenge key of 32 bytes......")} {
+		server := NewWorker()
+		server.SetDifficulty(4)
+		server.SetChallengeKey(key)
+
+		if _, err := server.NewChallengeWithDifficulty(0); err == nil {
+			t.Fatalf("Challenge with difficulty 0 was issued\n")
+		}
+
+		// a proof that only meets the Worker's difficulty
+		weak := NewWorker()
+		weak.SetDifficulty(4)
+		challenge, _ := server.NewChallengeWithDifficulty(14)
+		if challenge.GetDifficulty() != 14 {
+			t.Fatalf("Challenge demands difficulty %d, expected 14\n", challenge.GetDifficulty())
+		}
+
+		var proof *PoWork
+		for i := 0; proof == nil || proof.LeadingZeroBits(sha3.New512) >= 14; i++ {
+			proof, _ = weak.DoProofForChallenge(challenge, []byte(fmt.Sprintf("message %d", i)))
+		}
+		if ok, _ := server.ValidateChallenge(proof); ok {
+			t.Fatalf("Proof below the challenge's difficulty was accepted\n")
+		}
+
+		strong := NewWorker()
+		strong.SetDifficulty(14)
+		challenge, _ = server.NewChallengeWithDifficulty(14)
+		proof, _ = strong.DoProofForChallenge(challenge, nil)
+		if ok, err := server.ValidateChallenge(proof); !ok || err != nil {
+			t.Fatalf("Proof at the challenge's difficulty was rejected: %v\n", err)
+		}
+	}
+}
+
+func TestChallengeBelowDifficulty(t *testing.T) {
+	for _, key := range [][]byte{nil, []byte("a challenge key of 32 bytes......")} {
+		server := NewWorker()
+		server.SetDifficulty(14)
+		server.SetChallengeKey(key)
+
+		// a proof that meets a challenge's lower difficulty, but not the Worker's
+		weak := NewWorker()
+		weak.SetDifficulty(4)
+		var proof *PoWork
+		for i := 0; proof == nil || proof.LeadingZeroBits(sha3.New512) >= 14; i++ {
+			challenge, _ := server.NewChallengeWithDifficulty(4)
+			proof, _ = weak.DoProofForChallenge(challenge, []byte(fmt.Sprintf("message %d", i)))
+		}
+
+		// a remembered challenge is held to the Worker's difficulty, a signed one to its own
+		ok, err := server.ValidateChallenge(proof)
+		if err != nil || ok != (key != nil) {
+			t.Fatalf("Proof below the Worker's difficulty accepted: %v, with a challenge key: %v: %v\n", ok, key != nil, err)
+		}
+	}
+}
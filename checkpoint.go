@@ -0,0 +1,141 @@
+package powork
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// checkpointVersion is the version byte of encoded checkpoints
+const checkpointVersion = 1
+
+// checkpointHeaderLen is the size of the fixed part of an encoded checkpoint: version,
+// iterations and elapsed time
+const checkpointHeaderLen = 1 + 8 + 8
+
+// A Checkpoint records how far a search for a proof of work got before it stopped, so that
+// it can be resumed with ResumeProof instead of starting over. It carries everything the
+// proof is bound to and the lowest nonce not yet tried.
+//
+// When a search runs on several goroutines, each leaves off at a slightly different nonce.
+// The Checkpoint keeps the lowest, so a resumed search repeats a few iterations.
+type Checkpoint struct {
+	// base holds the next nonce to try as its proof and the nonces tried so far as its
+	// iterations
+	base    *PoWork
+	elapsed time.Duration
+}
+
+// GetMessage gets the message the search is for
+func (c *Checkpoint) GetMessage() []byte {
+	return c.base.msg
+}
+
+// GetNonce gets the lowest nonce the search has not tried yet
+func (c *Checkpoint) GetNonce() uint64 {
+	return c.base.proof
+}
+
+// GetIterations gets the number of nonces tried so far, summed across every run of the search
+func (c *Checkpoint) GetIterations() int {
+	return c.base.requiredIterations
+}
+
+// GetElapsed gets the time spent searching so far, summed across every run of the search
+func (c *Checkpoint) GetElapsed() time.Duration {
+	return c.elapsed
+}
+
+// DoProofForResumable calculates a proof of work for msg like DoProofFor. If the search stops
+// without a proof because of the Worker's timeout or iteration limit, a Checkpoint to resume
+// it from is returned along with the error.
+func (p *Worker) DoProofForResumable(msg []byte) (*PoWork, *Checkpoint, error) {
+	return p.DoProofForResumableContext(context.Background(), msg)
+}
+
+// DoProofForResumableContext does the same thing as DoProofForResumable, also returning a
+// Checkpoint if ctx is canceled or its deadline passes. Cancel ctx to pause a search, for
+// example when the process is shutting down, and save the Checkpoint to resume it later.
+func (p *Worker) DoProofForResumableContext(ctx context.Context, msg []byte) (*PoWork, *Checkpoint, error) {
+	return p.resume(ctx, p.newBase(msg), 0, 0)
+}
+
+// ResumeProof continues the search a Checkpoint was taken from. The Worker must search at
+// the difficulty, with the hash algorithm and in the nonce byte order the Checkpoint was
+// taken at, or the nonces already tried might have been solutions. A search that stops
+// again returns a new Checkpoint.
+func (p *Worker) ResumeProof(cp *Checkpoint) (*PoWork, *Checkpoint, error) {
+	return p.ResumeProofContext(context.Background(), cp)
+}
+
+// ResumeProofContext does the same thing as ResumeProof except carrying a context
+func (p *Worker) ResumeProofContext(ctx context.Context, cp *Checkpoint) (*PoWork, *Checkpoint, error) {
+	if cp.base.difficulty != p.difficulty || cp.base.algorithm != p.algorithm || cp.base.bigEndian != p.bigEndian {
+		return nil, nil, fmt.Errorf("%w: checkpoint was taken at another difficulty, algorithm or nonce byte order", ErrInvalidSetting)
+	}
+
+	base := new(PoWork)
+	*base = *cp.base
+	base.requiredIterations = 0
+	return p.resume(ctx, base, cp.base.requiredIterations, cp.elapsed)
+}
+
+// resume searches for a proof for base, adding the iterations and time of earlier runs to
+// the result
+func (p *Worker) resume(ctx context.Context, base *PoWork, done int, elapsed time.Duration) (*PoWork, *Checkpoint, error) {
+	start := time.Now()
+
+	pow, partial, err := p.doSearch(ctx, p.newSearchState(), base)
+	if pow != nil {
+		pow.requiredIterations += done
+		return pow, nil, nil
+	}
+
+	if partial == nil {
+		return nil, nil, err
+	}
+
+	partial.requiredIterations += done
+	return nil, &Checkpoint{base: partial, elapsed: elapsed + time.Since(start)}, err
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. The encoding is, in network byte order:
+//
+//	version    uint8
+//	iterations uint64
+//	elapsed    int64 (nanoseconds)
+//	proof      the search as encoded by PoWork.MarshalBinary, with the next nonce to try
+func (c *Checkpoint) MarshalBinary() ([]byte, error) {
+	pow, err := c.base.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, checkpointHeaderLen, checkpointHeaderLen+len(pow))
+	data[0] = checkpointVersion
+	binary.BigEndian.PutUint64(data[1:], uint64(c.base.requiredIterations))
+	binary.BigEndian.PutUint64(data[9:], uint64(c.elapsed))
+	return append(data, pow...), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, decoding data produced by MarshalBinary
+func (c *Checkpoint) UnmarshalBinary(data []byte) error {
+	if len(data) < checkpointHeaderLen {
+		return fmt.Errorf("%w: encoded checkpoint is too short", ErrInvalidEncoding)
+	}
+
+	if data[0] != checkpointVersion {
+		return fmt.Errorf("%w: unsupported encoded checkpoint version", ErrInvalidEncoding)
+	}
+
+	base := new(PoWork)
+	if err := base.UnmarshalBinary(data[checkpointHeaderLen:]); err != nil {
+		return err
+	}
+	base.requiredIterations = int(binary.BigEndian.Uint64(data[1:]))
+
+	c.base = base
+	c.elapsed = time.Duration(binary.BigEndian.Uint64(data[9:]))
+	return nil
+}
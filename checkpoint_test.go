@@ -0,0 +1,115 @@
+package powork
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestResumeProof(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(14)
+	worker.SetTimeoutDuration(0)
+	worker.SetMaxIterations(1000)
+
+	// find a message without a solution among the first 1000 nonces
+	var msg []byte
+	var cp *Checkpoint
+	for i := 0; cp == nil; i++ {
+		msg = []byte(fmt.Sprintf("message %d", i))
+		pow, c, err := worker.DoProofForResumable(msg)
+		if pow == nil && !errors.Is(err, ErrMaxIterations) {
+			t.Fatalf("Search stopped with %v, expected ErrMaxIterations\n", err)
+		}
+		cp = c
+	}
+
+	if cp.GetNonce() != 1000 || cp.GetIterations() != 1000 || string(cp.GetMessage()) != string(msg) {
+		t.Fatalf("Checkpoint at nonce %d after %d iterations, expected 1000\n", cp.GetNonce(), cp.GetIterations())
+	}
+
+	worker.SetMaxIterations(0)
+	resumed, cp, err := worker.ResumeProof(cp)
+	if err != nil || cp != nil {
+		t.Fatalf("Error resuming proof: %v\n", err)
+	}
+
+	// the same proof as a search that was never interrupted
+	direct, _ := worker.DoProofFor(msg)
+	if resumed.GetProof() != direct.GetProof() || resumed.GetIterations() != direct.GetIterations() {
+		t.Fatalf("Resumed search found nonce %d after %d iterations, expected %d after %d\n",
+			resumed.GetProof(), resumed.GetIterations(), direct.GetProof(), direct.GetIterations())
+	}
+
+	if ok, err := worker.ValidatePoWork(resumed); !ok || err != nil {
+		t.Fatalf("Resumed proof is not valid: %v\n", err)
+	}
+}
+
+func TestCheckpointParallel(t *testing.T) {
+	worker := NewWorker()
+	worker.SetConcurrency(4)
+	worker.SetDifficulty(40)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, cp, err := worker.DoProofForResumableContext(ctx, []byte("a hard proof"))
+	if !errors.Is(err, context.DeadlineExceeded) || cp == nil {
+		t.Fatalf("Canceled search returned %v and checkpoint %v\n", err, cp)
+	}
+
+	// every lane has tried at least the nonces below the checkpoint
+	if cp.GetIterations() < int(cp.GetNonce()) || cp.GetElapsed() <= 0 {
+		t.Fatalf("Checkpoint at nonce %d after %d iterations\n", cp.GetNonce(), cp.GetIterations())
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, next, _ := worker.ResumeProofContext(ctx, cp)
+	if next == nil || next.GetNonce() <= cp.GetNonce() || next.GetIterations() <= cp.GetIterations() {
+		t.Fatalf("Resumed search did not get further than its checkpoint\n")
+	}
+
+	worker.SetDifficulty(20)
+	if _, _, err := worker.ResumeProof(cp); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Resuming at another difficulty did not fail with ErrInvalidSetting: %v\n", err)
+	}
+}
+
+func TestCheckpointEncoding(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(40)
+	worker.SetTimeoutDuration(10 * time.Millisecond)
+
+	_, cp, err := worker.DoProofForResumable([]byte("a hard proof"))
+	if !errors.Is(err, ErrTimeout) || cp == nil {
+		t.Fatalf("Timed out search returned %v and checkpoint %v\n", err, cp)
+	}
+
+	data, err := cp.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Error encoding checkpoint: %v\n", err)
+	}
+
+	decoded := new(Checkpoint)
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Error decoding checkpoint: %v\n", err)
+	}
+
+	if decoded.GetNonce() != cp.GetNonce() || decoded.GetIterations() != cp.GetIterations() ||
+		decoded.GetElapsed() != cp.GetElapsed() || string(decoded.GetMessage()) != string(cp.GetMessage()) {
+		t.Fatalf("Decoded checkpoint differs from the original\n")
+	}
+
+	// the resumed search is bound to the same things as the original
+	worker.SetDifficulty(40)
+	if _, next, _ := worker.ResumeProof(decoded); next == nil || next.GetNonce() <= cp.GetNonce() {
+		t.Fatalf("Decoded checkpoint did not resume\n")
+	}
+
+	if err := decoded.UnmarshalBinary(data[:5]); !errors.Is(err, ErrInvalidEncoding) {
+		t.Fatalf("Truncated checkpoint did not fail with ErrInvalidEncoding: %v\n", err)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+/*
+#include <stdlib.h>
+
+#define POWORK_OK 0
+#define POWORK_ERR_ARGUMENT -1
+#define POWORK_ERR_TIMEOUT -2
+#define POWORK_ERR_ENCODING -3
+#define POWORK_ERR_INTERNAL -4
+*/
+import "C"
+
+import (
+	"time"
+	"unsafe"
+)
+
+//export powork_solve
+func powork_solve(algorithm *C.char, msg unsafe.Pointer, msgLen C.size_t, difficulty C.int, timeoutMs C.int, proof *unsafe.Pointer, proofLen *C.size_t) C.int {
+	if (msg == nil && msgLen > 0) || proof == nil || proofLen == nil || timeoutMs < 0 {
+		return codeArgument
+	}
+
+	data, code := solve(goString(algorithm), C.GoBytes(msg, C.int(msgLen)), int(difficulty), time.Duration(timeoutMs)*time.Millisecond)
+	if code != codeOK {
+		return C.int(code)
+	}
+
+	*proof = C.CBytes(data)
+	*proofLen = C.size_t(len(data))
+	return codeOK
+}
+
+//export powork_verify
+func powork_verify(algorithm *C.char, proof unsafe.Pointer, proofLen C.size_t, difficulty C.int) C.int {
+	if proof == nil {
+		return codeArgument
+	}
+
+	return C.int(verify(goString(algorithm), C.GoBytes(proof, C.int(proofLen)), int(difficulty)))
+}
+
+//export powork_free
+func powork_free(p unsafe.Pointer) {
+	C.free(p)
+}
+
+// goString converts a C string that may be NULL
+func goString(s *C.char) string {
+	if s == nil {
+		return ""
+	}
+	return C.GoString(s)
+}
@@ -0,0 +1,114 @@
+// Command libpowork builds powork as a C shared library, so that services not written in Go,
+// such as nginx modules or Python backends, compute and check proofs exactly as Go services
+// do:
+//
+//	go build -buildmode=c-shared -o libpowork.so ./cmd/libpowork
+//
+// The build also writes libpowork.h, which declares:
+//
+//	int powork_solve(char *algorithm, void *msg, size_t msgLen, int difficulty, int timeoutMs,
+//	                 void **proof, size_t *proofLen);
+//	int powork_verify(char *algorithm, void *proof, size_t proofLen, int difficulty);
+//	void powork_free(void *p);
+//
+// Proofs are in the binary encoding of powork.PoWork; base64 encode them with the URL
+// alphabet and no padding for an X-PoWork header. algorithm names a registered hash
+// algorithm, or is NULL or empty for SHA-256. A timeoutMs of 0 searches without a time
+// limit.
+//
+// powork_solve returns POWORK_OK and stores a proof, to be released with powork_free, or
+// returns a negative POWORK_ERR_ code. powork_verify returns 1 for a valid proof, 0 for an invalid
+// one, and a negative error code when the arguments are bad. The functions are safe to call
+// from several threads.
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+// Return codes, also defined in libpowork.h
+const (
+	codeOK       = 0
+	codeArgument = -1
+	codeTimeout  = -2
+	codeEncoding = -3
+	codeInternal = -4
+)
+
+func main() {}
+
+// newWorker returns a Worker for the named algorithm at difficulty
+func newWorker(algorithm string, difficulty int) (*powork.Worker, int) {
+	w := powork.NewWorker()
+	if algorithm != "" {
+		var err error
+		if w, err = powork.NewWorkerForAlgorithm(algorithm); err != nil {
+			return nil, codeArgument
+		}
+	}
+
+	if err := w.SetDifficulty(difficulty); err != nil {
+		return nil, codeArgument
+	}
+	return w, codeOK
+}
+
+// solve computes the binary encoding of a proof for msg
+func solve(algorithm string, msg []byte, difficulty int, timeout time.Duration) ([]byte, int) {
+	w, code := newWorker(algorithm, difficulty)
+	if code != codeOK {
+		return nil, code
+	}
+	if err := w.SetTimeoutDuration(timeout); err != nil {
+		return nil, codeArgument
+	}
+
+	pow, err := w.DoProofFor(msg)
+	if err != nil {
+		return nil, errorCode(err)
+	}
+
+	data, err := pow.MarshalBinary()
+	if err != nil {
+		return nil, codeInternal
+	}
+	return data, codeOK
+}
+
+// verify checks the binary encoding of a proof, returning 1 if it is valid and 0 if not
+func verify(algorithm string, data []byte, difficulty int) int {
+	w, code := newWorker(algorithm, difficulty)
+	if code != codeOK {
+		return code
+	}
+
+	pow := new(powork.PoWork)
+	if err := pow.UnmarshalBinary(data); err != nil {
+		return codeEncoding
+	}
+
+	ok, err := w.ValidatePoWork(pow)
+	if err != nil {
+		return errorCode(err)
+	}
+	if ok {
+		return 1
+	}
+	return 0
+}
+
+// errorCode maps an error to a return code
+func errorCode(err error) int {
+	switch {
+	case errors.Is(err, powork.ErrTimeout), errors.Is(err, powork.ErrMaxIterations):
+		return codeTimeout
+	case errors.Is(err, powork.ErrInvalidDifficulty), errors.Is(err, powork.ErrDifficultyExceedsHash), errors.Is(err, powork.ErrInvalidSetting):
+		return codeArgument
+	case errors.Is(err, powork.ErrInvalidEncoding):
+		return codeEncoding
+	}
+	return codeInternal
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+func TestSolveVerify(t *testing.T) {
+	data, code := solve("", []byte("from C"), 12, 0)
+	if code != codeOK {
+		t.Fatalf("Solve returned %d\n", code)
+	}
+
+	// the same proof a Go service would accept
+	pow := new(powork.PoWork)
+	if err := pow.UnmarshalBinary(data); err != nil || pow.GetMessageString() != "from C" {
+		t.Fatalf("Error decoding proof: %v\n", err)
+	}
+	if code := verify("", data, 12); code != 1 {
+		t.Fatalf("Verify returned %d for a valid proof\n", code)
+	}
+	if code := verify("", data, 40); code != 0 {
+		t.Fatalf("Verify returned %d for a proof below the difficulty\n", code)
+	}
+	if code := verify("", data[:3], 12); code != codeEncoding {
+		t.Fatalf("Verify returned %d for a truncated proof\n", code)
+	}
+}
+
+func TestSolveErrors(t *testing.T) {
+	if _, code := solve("", []byte("msg"), 0, 0); code != codeArgument {
+		t.Fatalf("Solve returned %d for difficulty 0\n", code)
+	}
+	if _, code := solve("no such hash", []byte("msg"), 8, 0); code != codeArgument {
+		t.Fatalf("Solve returned %d for an unknown algorithm\n", code)
+	}
+	if _, code := solve("", []byte("msg"), 60, 10*time.Millisecond); code != codeTimeout {
+		t.Fatalf("Solve returned %d for a search that timed out\n", code)
+	}
+}
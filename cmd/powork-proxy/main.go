@@ -0,0 +1,209 @@
+// Command powork-proxy is a reverse proxy that puts a proof of work in front of any HTTP
+// upstream, without changes to the upstream app.
+//
+// Usage:
+//
+//	powork-proxy [-config file] [flags]
+//
+// Clients without a valid proof get the challenge of the powhttp middleware, with a JSON
+// body for API clients and an HTML interstitial for browsers. Clients with one are forwarded
+// to the upstream, and earn a session cookie that spares them proofs for a while. The
+// interstitial solves the challenge with the cmd/powork-wasm module; put powork.wasm and the
+// wasm_exec.js of the Go distribution in a directory and pass it as assets. Without it,
+// browsers are shown the challenge but can't answer it.
+//
+// Settings are read from a YAML file, and flags given on the command line override it:
+//
+//	listen: ":8080"
+//	upstream: "http://localhost:3000"
+//	difficulty: 16
+//	challenge_key: "<hex>"   # sign challenges, so that any replica accepts them once
+//	challenge_ttl: 1m
+//	session:
+//	  key: "<hex>"           # share between replicas; random if empty
+//	  ttl: 10m
+//	  requests: 100
+//	  renewal_difficulty: 0  # the difficulty to renew a session at, 0 for difficulty
+//	assets: /srv/powork
+//	exempt: ["/healthz"]     # paths forwarded without a proof
+//
+// It exits with status 1 when it fails and with status 2 for bad usage.
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Exit statuses
+const (
+	exitOK    = 0
+	exitFail  = 1
+	exitUsage = 2
+)
+
+// errUsage reports bad flags, arguments or settings
+var errUsage = errors.New("bad usage")
+
+// config are the settings of the proxy
+type config struct {
+	Listen       string        `yaml:"listen"`
+	Upstream     string        `yaml:"upstream"`
+	Difficulty   int           `yaml:"difficulty"`
+	ChallengeKey string        `yaml:"challenge_key"`
+	ChallengeTTL time.Duration `yaml:"challenge_ttl"`
+	Session      struct {
+		Key               string        `yaml:"key"`
+		TTL               time.Duration `yaml:"ttl"`
+		Requests          int           `yaml:"requests"`
+		RenewalDifficulty int           `yaml:"renewal_difficulty"`
+	} `yaml:"session"`
+	Assets string   `yaml:"assets"`
+	Exempt []string `yaml:"exempt"`
+}
+
+// defaultConfig returns the settings used where neither the file nor the flags set any
+func defaultConfig() *config {
+	c := &config{
+		Listen:       "localhost:8080",
+		Difficulty:   16,
+		ChallengeTTL: time.Minute,
+	}
+	c.Session.TTL = 10 * time.Minute
+	c.Session.Requests = 100
+	return c
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stderr))
+}
+
+// run runs the proxy with the command line args until interrupted and returns the exit status
+func run(args []string, stderr io.Writer) int {
+	c, err := parse(args, stderr)
+	if err == nil {
+		err = serve(c, stderr)
+	}
+
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp):
+		return exitUsage
+	}
+
+	fmt.Fprintf(stderr, "powork-proxy: %v\n", err)
+	if errors.Is(err, errUsage) {
+		return exitUsage
+	}
+	return exitFail
+}
+
+// parse reads the settings of the command line args and of the config file they name
+func parse(args []string, stderr io.Writer) (*config, error) {
+	c := defaultConfig()
+	path, err := parseFlags(c, args, stderr)
+	if err != nil || path == "" {
+		return c, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// the flags are parsed again so that they override the file
+	c = defaultConfig()
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", errUsage, path, err)
+	}
+	_, err = parseFlags(c, args, io.Discard)
+	return c, err
+}
+
+// parseFlags sets the settings the flags in args give and returns the path of the config file
+func parseFlags(c *config, args []string, stderr io.Writer) (string, error) {
+	fs := flag.NewFlagSet("powork-proxy", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var path, exempt string
+	fs.StringVar(&path, "config", "", "YAML file to read settings from")
+	fs.StringVar(&c.Listen, "listen", c.Listen, "address to listen on")
+	fs.StringVar(&c.Upstream, "upstream", c.Upstream, "URL of the upstream to forward verified requests to")
+	fs.IntVar(&c.Difficulty, "difficulty", c.Difficulty, "difficulty in leading zero bits")
+	fs.StringVar(&c.ChallengeKey, "challenge-key", c.ChallengeKey, "hex encoded challenge signing key, shared by every replica")
+	fs.DurationVar(&c.ChallengeTTL, "challenge-ttl", c.ChallengeTTL, "how long challenges can be answered")
+	fs.StringVar(&c.Session.Key, "session-key", c.Session.Key, "hex encoded session cookie key, shared by every replica")
+	fs.DurationVar(&c.Session.TTL, "session-ttl", c.Session.TTL, "how long a session lasts")
+	fs.IntVar(&c.Session.Requests, "session-requests", c.Session.Requests, "requests a session is good for, 0 for no limit")
+	fs.IntVar(&c.Session.RenewalDifficulty, "renewal-difficulty", c.Session.RenewalDifficulty, "difficulty to renew a session at, 0 for -difficulty")
+	fs.StringVar(&c.Assets, "assets", c.Assets, "directory holding powork.wasm and wasm_exec.js")
+	fs.StringVar(&exempt, "exempt", strings.Join(c.Exempt, ","), "comma separated paths to forward without a proof")
+
+	if err := fs.Parse(args); err == flag.ErrHelp {
+		return "", err
+	} else if err != nil {
+		return "", fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() > 0 {
+		return "", fmt.Errorf("%w: too many arguments", errUsage)
+	}
+
+	c.Exempt = nil
+	if exempt != "" {
+		c.Exempt = strings.Split(exempt, ",")
+	}
+	return path, nil
+}
+
+// serve runs the proxy until interrupted
+func serve(c *config, stderr io.Writer) error {
+	h, err := c.handler()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	srv := &http.Server{Addr: c.Listen, Handler: h, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	fmt.Fprintf(stderr, "powork-proxy: forwarding %s to %s\n", c.Listen, c.Upstream)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// decodeKey decodes the hex encoded key setting name, or returns a random key if it is empty
+func decodeKey(name, key string) ([]byte, error) {
+	if key == "" {
+		b := make([]byte, 32)
+		_, err := rand.Read(b)
+		return b, err
+	}
+
+	b, err := hex.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s is not hex", errUsage, name)
+	}
+	return b, nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powhttp"
+)
+
+// newProxy starts a proxy in front of an upstream that counts its requests and fails those
+// that still carry a proof
+func newProxy(t *testing.T, c *config) (*httptest.Server, *int) {
+	hits := new(int)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(powhttp.HeaderProof) != "" {
+			http.Error(w, "proof forwarded", http.StatusBadRequest)
+			return
+		}
+		*hits++
+		io.WriteString(w, "upstream "+r.URL.Path)
+	}))
+	t.Cleanup(upstream.Close)
+
+	c.Upstream = upstream.URL
+	h, err := c.handler()
+	if err != nil {
+		t.Fatalf("Error creating proxy: %v\n", err)
+	}
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return srv, hits
+}
+
+func TestProxy(t *testing.T) {
+	c := defaultConfig()
+	c.Difficulty = 8
+	c.Exempt = []string{"/healthz"}
+	srv, hits := newProxy(t, c)
+
+	resp, err := http.Get(srv.URL + "/page")
+	if err != nil {
+		t.Fatalf("Error sending request: %v\n", err)
+	}
+	var body challengeBody
+	json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if resp.StatusCode != powhttp.StatusChallenge || body.Challenge == "" || body.Difficulty != 8 {
+		t.Fatalf("Request without proof got status %v and body %+v\n", resp.StatusCode, body)
+	}
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Transport: &powhttp.Transport{}, Jar: jar}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(srv.URL + "/page")
+		if err != nil {
+			t.Fatalf("Error sending request: %v\n", err)
+		}
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(data) != "upstream /page" {
+			t.Fatalf("Request %d got status %v and body %q\n", i, resp.StatusCode, data)
+		}
+	}
+	// the second request was let through by the session of the first
+	if *hits != 2 || len(jar.Cookies(resp.Request.URL)) == 0 {
+		t.Fatalf("Upstream got %d requests, expected 2\n", *hits)
+	}
+
+	resp, err = http.Get(srv.URL + "/healthz")
+	if err != nil || resp.StatusCode != http.StatusOK || *hits != 3 {
+		t.Fatalf("Exempt path got status %v\n", resp.StatusCode)
+	}
+	resp.Body.Close()
+}
+
+func TestProxyChallengeKey(t *testing.T) {
+	c := defaultConfig()
+	c.Difficulty = 8
+	c.ChallengeKey = "000102030405060708090a0b0c0d0e0f"
+	srv, hits := newProxy(t, c)
+
+	resp, err := http.Get(srv.URL + "/page")
+	if err != nil {
+		t.Fatalf("Error sending request: %v\n", err)
+	}
+	challenge, _ := powhttp.DecodeChallenge(resp.Header.Get(powhttp.HeaderChallenge))
+	resp.Body.Close()
+
+	worker := powork.NewWorker()
+	worker.SetDifficulty(8)
+	pow, _ := worker.DoProofForChallenge(challenge, nil)
+	proof, _ := powhttp.EncodeProof(pow)
+
+	// a signed challenge is answered once, like a remembered one
+	for i, want := range []int{http.StatusOK, powhttp.StatusChallenge} {
+		req, _ := http.NewRequest("GET", srv.URL+"/page", nil)
+		req.Header.Set(powhttp.HeaderProof, proof)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error sending request: %v\n", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("Request %d with the proof got status %v, expected %v\n", i, resp.StatusCode, want)
+		}
+	}
+	if *hits != 1 {
+		t.Fatalf("Upstream got %d requests, expected 1\n", *hits)
+	}
+}
+
+func TestInterstitial(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "wasm_exec.js"), []byte("// wasm_exec"), 0o644)
+
+	c := defaultConfig()
+	c.Difficulty = 8
+	c.Assets = dir
+	srv, hits := newProxy(t, c)
+
+	req, _ := http.NewRequest("GET", srv.URL+"/page", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Error sending request: %v\n", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != powhttp.StatusChallenge || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") ||
+		!strings.Contains(string(data), resp.Header.Get(powhttp.HeaderChallenge)) || !strings.Contains(string(data), "powork.solve") {
+		t.Fatalf("Browser got status %v and page %s\n", resp.StatusCode, data)
+	}
+
+	resp, err = http.Get(srv.URL + assetsPath + "wasm_exec.js")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Assets are not served\n")
+	}
+	resp.Body.Close()
+
+	// the page trades its proof for a session without reaching the upstream
+	client := &http.Client{Transport: &powhttp.Transport{}}
+	resp, err = client.Get(srv.URL + verifyPath)
+	if err != nil || resp.StatusCode != http.StatusNoContent || len(resp.Cookies()) == 0 || *hits != 0 {
+		t.Fatalf("Verification got status %v and cookies %v\n", resp.StatusCode, resp.Cookies())
+	}
+	resp.Body.Close()
+}
+
+func TestConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "proxy.yaml")
+	os.WriteFile(path, []byte(`
+upstream: "http://localhost:3000"
+difficulty: 20
+session:
+  ttl: 1h
+exempt: ["/healthz", "/metrics"]
+`), 0o644)
+
+	c, err := parse([]string{"-config", path, "-difficulty", "12"}, io.Discard)
+	if err != nil {
+		t.Fatalf("Error parsing settings: %v\n", err)
+	}
+
+	// flags override the file, which overrides the defaults
+	if c.Difficulty != 12 || c.Upstream != "http://localhost:3000" || c.Session.TTL != time.Hour ||
+		c.Session.Requests != 100 || len(c.Exempt) != 2 {
+		t.Fatalf("Settings are %+v\n", c)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{
+		{"-nonsense"},
+		{"extra"},
+		{"-upstream", "ftp://localhost"},
+		{"-upstream", "http://localhost", "-difficulty", "1000"},
+		{"-upstream", "http://localhost", "-session-key", "not hex"},
+		{"-upstream", "http://localhost", "-session-key", "00"},
+		{"-upstream", "http://localhost", "-challenge-key", "00"},
+	} {
+		if status := run(args, io.Discard); status != exitUsage {
+			t.Fatalf("powork-proxy %v exited with %d, expected %d\n", args, status, exitUsage)
+		}
+	}
+}
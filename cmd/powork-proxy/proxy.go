@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powhttp"
+)
+
+// spentCapacity is the number of answered signed challenges a proxy remembers. Each replica
+// remembers its own, so a proof is accepted at most once by each.
+const spentCapacity = 100000
+
+// Paths the proxy serves itself rather than forwarding
+const (
+	// assetsPath is where the files of the assets directory are served
+	assetsPath = "/.powork/"
+	// verifyPath answers 204 to requests with a valid proof, so that the interstitial can get a
+	// session cookie without repeating the request it stands in for
+	verifyPath = "/.powork/verify"
+)
+
+// handler creates the proxy the settings describe
+func (c *config) handler() (http.Handler, error) {
+	upstream, err := url.Parse(c.Upstream)
+	if err != nil || (upstream.Scheme != "http" && upstream.Scheme != "https") || upstream.Host == "" {
+		return nil, fmt.Errorf("%w: upstream must be an http or https URL", errUsage)
+	}
+
+	w := powork.NewWorker()
+	if err := w.SetDifficulty(c.Difficulty); err != nil {
+		return nil, fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if err := w.SetChallengeTTL(c.ChallengeTTL); err != nil {
+		return nil, fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if c.ChallengeKey != "" {
+		key, err := decodeKey("challenge key", c.ChallengeKey)
+		if err != nil {
+			return nil, err
+		}
+		if err := w.SetChallengeKey(key); err != nil {
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		// signed challenges are only single use if the answered ones are recorded
+		w.SetSpentStore(powork.NewMemorySpentStore(spentCapacity), c.ChallengeTTL)
+	}
+
+	sessionKey, err := decodeKey("session key", c.Session.Key)
+	if err != nil {
+		return nil, err
+	}
+	if c.Session.TTL <= 0 || c.Session.Requests < 0 {
+		return nil, fmt.Errorf("%w: session ttl must be positive and requests not negative", errUsage)
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(upstream)
+			r.SetXForwarded()
+			r.Out.Header.Del(powhttp.HeaderProof)
+		},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == verifyPath {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if i, ok := w.(*interstitial); ok {
+			// the upstream's responses go out untouched
+			w = i.ResponseWriter
+		}
+		proxy.ServeHTTP(w, r)
+	})
+
+	exempt := c.Exempt
+	policy := func(r *http.Request) (int, bool) {
+		return 0, !slices.Contains(exempt, r.URL.Path)
+	}
+
+	gate, err := powhttp.NewHandler(next, powhttp.WithWorker(w),
+		powhttp.WithSession(sessionKey, c.Session.TTL, c.Session.Requests),
+		powhttp.WithRenewalDifficulty(c.Session.RenewalDifficulty),
+		powhttp.WithPolicy(policy))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	solver := c.Assets != ""
+	mux := http.NewServeMux()
+	if solver {
+		mux.Handle(assetsPath, http.StripPrefix(assetsPath, http.FileServer(http.Dir(c.Assets))))
+	}
+	gated := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gate.ServeHTTP(&interstitial{ResponseWriter: w, r: r, solver: solver}, r)
+	})
+	mux.Handle(verifyPath, gated)
+	mux.Handle("/", gated)
+	return mux, nil
+}
+
+// interstitial replaces the plain text body of challenge responses with a JSON body, or with
+// an HTML page for browsers
+type interstitial struct {
+	http.ResponseWriter
+	r      *http.Request
+	solver bool
+
+	// set once the challenge has been written, after which the middleware's body is dropped
+	replaced bool
+}
+
+// challengeBody is the JSON body of challenge responses
+type challengeBody struct {
+	Challenge  string `json:"challenge"`
+	Difficulty int    `json:"difficulty"`
+}
+
+func (i *interstitial) WriteHeader(status int) {
+	challenge := i.Header().Get(powhttp.HeaderChallenge)
+	if status != powhttp.StatusChallenge || challenge == "" || i.replaced {
+		i.ResponseWriter.WriteHeader(status)
+		return
+	}
+	i.replaced = true
+
+	difficulty, _ := strconv.Atoi(i.Header().Get(powhttp.HeaderDifficulty))
+	body := challengeBody{Challenge: challenge, Difficulty: difficulty}
+
+	i.Header().Set("Cache-Control", "no-store")
+	if !strings.Contains(i.r.Header.Get("Accept"), "text/html") {
+		i.Header().Set("Content-Type", "application/json")
+		i.ResponseWriter.WriteHeader(status)
+		json.NewEncoder(i.ResponseWriter).Encode(body)
+		return
+	}
+
+	i.Header().Set("Content-Type", "text/html; charset=utf-8")
+	i.ResponseWriter.WriteHeader(status)
+	page.Execute(i.ResponseWriter, struct {
+		challengeBody
+		Solver     bool
+		AssetsPath string
+		VerifyPath string
+	}{body, i.solver, assetsPath, verifyPath})
+}
+
+func (i *interstitial) Write(b []byte) (int, error) {
+	if i.replaced {
+		return len(b), nil
+	}
+	return i.ResponseWriter.Write(b)
+}
+
+// page is the interstitial shown to browsers. It solves the challenge, trades the proof for
+// a session cookie and reloads.
+var page = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Checking your browser</title>
+</head>
+<body>
+<h1>Checking your browser</h1>
+<p id="status">{{if .Solver}}This takes a few seconds and needs JavaScript.{{else}}This site needs a proof of work of {{.Difficulty}} bits before it lets you in.{{end}}</p>
+{{if .Solver}}<script src="{{.AssetsPath}}wasm_exec.js"></script>
+<script>
+(async () => {
+	const status = document.getElementById("status");
+	const go = new Go();
+	const wasm = await WebAssembly.instantiateStreaming(fetch("{{.AssetsPath}}powork.wasm"), go.importObject);
+	go.run(wasm.instance);
+	const proof = await powork.solve({{.Challenge}}, {{.Difficulty}}, (hashes) => {
+		status.textContent = "Checked " + hashes + " hashes";
+	});
+	const res = await fetch("{{.VerifyPath}}", {headers: {"X-PoWork": proof}, credentials: "same-origin"});
+	if (res.ok) {
+		location.reload();
+	} else {
+		status.textContent = "The check failed, reload the page to try again.";
+	}
+})();
+</script>{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,17 @@
+//go:build js && wasm
+
+// Command powork-wasm is a WebAssembly module that solves proof of work challenges in the
+// browser. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o powork.wasm ./cmd/powork-wasm
+//
+// and load it with the wasm_exec.js of the Go distribution. It registers globalThis.powork;
+// see the powjs package for the binding.
+package main
+
+import "github.com/Zumium/powork/powjs"
+
+func main() {
+	powjs.Register("powork")
+	select {}
+}
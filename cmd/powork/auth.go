@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Zumium/powork/powauth"
+	"github.com/Zumium/powork/powhttp"
+)
+
+// authFlags are the flags of the auth subcommand
+type authFlags struct {
+	workerFlags
+	addr            string
+	key             string
+	ttl             time.Duration
+	redis           string
+	capacity        int
+	sessionKey      string
+	sessionTTL      time.Duration
+	sessionRequests int
+}
+
+// auth runs an external authorization endpoint until interrupted
+func auth(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("auth", stderr)
+	var af authFlags
+	af.register(fs)
+	fs.StringVar(&af.addr, "addr", "localhost:8080", "address to listen on")
+	fs.StringVar(&af.key, "key", "", "hex encoded challenge signing key, shared by every replica")
+	fs.DurationVar(&af.ttl, "ttl", time.Minute, "how long challenges can be answered")
+	fs.StringVar(&af.redis, "redis", "", "address of a Redis to record answered signed challenges in, shared by every replica")
+	fs.IntVar(&af.capacity, "capacity", 100000, "answered signed challenges to hold in memory without -redis")
+	fs.StringVar(&af.sessionKey, "session-key", "", "hex encoded session cookie key, to let verified clients in without a proof for a while")
+	fs.DurationVar(&af.sessionTTL, "session-ttl", 10*time.Minute, "how long a session lasts")
+	fs.IntVar(&af.sessionRequests, "session-requests", 100, "requests a session is good for, 0 for no limit")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	h, err := af.handler()
+	if err != nil {
+		return err
+	}
+
+	return listenAndServe("auth", af.addr, h, stderr)
+}
+
+// handler creates the endpoint the flags describe
+func (f *authFlags) handler() (http.Handler, error) {
+	w, err := f.worker()
+	if err != nil {
+		return nil, err
+	}
+
+	if f.key != "" {
+		key, err := hex.DecodeString(f.key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: -key is not hex", errUsage)
+		}
+		if err := w.SetChallengeKey(key); err != nil {
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+
+		// signed challenges are only single use if the answered ones are recorded
+		store, err := spentStore(f.redis, f.capacity)
+		if err != nil {
+			return nil, err
+		}
+		w.SetSpentStore(store, f.ttl)
+	}
+	if err := w.SetChallengeTTL(f.ttl); err != nil {
+		return nil, fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	opts := []powhttp.Option{powhttp.WithWorker(w)}
+	if f.sessionKey != "" {
+		key, err := hex.DecodeString(f.sessionKey)
+		if err != nil {
+			return nil, fmt.Errorf("%w: -session-key is not hex", errUsage)
+		}
+		if len(key) < 16 {
+			return nil, fmt.Errorf("%w: -session-key must be at least 16 bytes", errUsage)
+		}
+		if f.sessionTTL <= 0 || f.sessionRequests < 0 {
+			return nil, fmt.Errorf("%w: -session-ttl must be positive and -session-requests not negative", errUsage)
+		}
+		opts = append(opts, powhttp.WithSession(key, f.sessionTTL, f.sessionRequests))
+	}
+
+	return powauth.Handler(opts...), nil
+}
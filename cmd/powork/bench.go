@@ -0,0 +1,81 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+// bench solves proofs for random messages at a range of difficulties and prints the hash
+// rate and solve times
+func bench(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("bench", stderr)
+	var wf workerFlags
+	wf.register(fs)
+	list := fs.String("difficulties", "8,12,16,20", "comma separated difficulties to benchmark")
+	proofs := fs.Int("proofs", 5, "proofs to solve per difficulty")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	difficulties, err := parseDifficulties(*list)
+	if err != nil {
+		return err
+	}
+	if *proofs < 1 {
+		return fmt.Errorf("%w: -proofs must be at least 1", errUsage)
+	}
+
+	w, err := wf.worker()
+	if err != nil {
+		return err
+	}
+	w.SetTimeoutDuration(0)
+
+	tw := tabwriter.NewWriter(stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "difficulty\tproofs\tmean time\tmean hashes\texpected hashes\thashes/s\t")
+	for _, d := range difficulties {
+		if err := w.SetDifficulty(d); err != nil {
+			return fmt.Errorf("%w: %v", errUsage, err)
+		}
+
+		var iterations int
+		start := time.Now()
+		for i := 0; i < *proofs; i++ {
+			msg := make([]byte, 32)
+			rand.Read(msg)
+			pow, err := w.DoProofFor(msg)
+			if err != nil {
+				return err
+			}
+			iterations += pow.GetIterations()
+		}
+		elapsed := time.Since(start)
+
+		fmt.Fprintf(tw, "%d\t%d\t%v\t%.0f\t%.0f\t%.0f\t\n", d, *proofs,
+			(elapsed / time.Duration(*proofs)).Round(time.Microsecond),
+			float64(iterations)/float64(*proofs), powork.EstimateIterations(d),
+			float64(iterations)/elapsed.Seconds())
+	}
+
+	return tw.Flush()
+}
+
+// parseDifficulties parses a comma separated list of difficulties
+func parseDifficulties(list string) ([]int, error) {
+	var ds []int
+	for _, s := range strings.Split(list, ",") {
+		d, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || d < 1 {
+			return nil, fmt.Errorf("%w: %q is not a difficulty", errUsage, s)
+		}
+		ds = append(ds, d)
+	}
+	return ds, nil
+}
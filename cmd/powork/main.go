@@ -0,0 +1,184 @@
+// Command powork computes, checks and benchmarks proofs of work from the command line.
+//
+// Usage:
+//
+//	powork solve [flags] [file]     prove the contents of file, or of stdin
+//	powork verify [flags] [proof]   check an encoded proof, given or read from stdin
+//	powork bench [flags]            measure the hash rate and solve time per difficulty
+//	powork vectors [flags]          print or verify test vectors
+//	powork serve [flags]            run a validation service, see package powservice
+//	powork auth [flags]             run an external authorization endpoint, see package powauth
+//
+// Proofs are written and read base64 encoded by default, as the X-PoWork header carries
+// them. Subcommands exit with status 1 when they fail, verify and vectors -verify also when
+// what they check is not valid, and with status 2 for bad usage. Run a subcommand with -h
+// for its flags.
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Zumium/powork"
+)
+
+// Exit statuses
+const (
+	exitOK    = 0
+	exitFail  = 1
+	exitUsage = 2
+)
+
+// errInvalid reports a proof or vector that failed verification
+var errInvalid = errors.New("not valid")
+
+// errUsage reports bad flags or arguments
+var errUsage = errors.New("bad usage")
+
+// errFlags reports flags the flag package could not parse, which it has reported itself
+var errFlags = errors.New("bad flags")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// command is a subcommand, running with its arguments after the subcommand name
+type command func(args []string, stdin io.Reader, stdout, stderr io.Writer) error
+
+var commands = map[string]command{
+	"solve":   solve,
+	"verify":  verify,
+	"bench":   bench,
+	"vectors": vectors,
+	"serve":   serve,
+	"auth":    auth,
+}
+
+// run runs the command line args and returns the exit status
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || commands[args[0]] == nil {
+		fmt.Fprintln(stderr, "usage: powork solve|verify|bench|vectors|serve|auth [flags]")
+		return exitUsage
+	}
+
+	err := commands[args[0]](args[1:], stdin, stdout, stderr)
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp), errors.Is(err, errFlags):
+		// the flag package has printed the usage
+		return exitUsage
+	}
+
+	fmt.Fprintf(stderr, "powork %s: %v\n", args[0], err)
+	if errors.Is(err, errUsage) {
+		return exitUsage
+	}
+	return exitFail
+}
+
+// workerFlags are the flags that configure the Worker of a subcommand
+type workerFlags struct {
+	alg         string
+	difficulty  int
+	concurrency int
+}
+
+func (f *workerFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.alg, "alg", "sha3-512", "hash algorithm, with a /be suffix for big-endian nonces")
+	fs.IntVar(&f.difficulty, "difficulty", 20, "difficulty in leading zero bits")
+	fs.IntVar(&f.concurrency, "concurrency", 0, "search goroutines, 0 for one per CPU")
+}
+
+// worker creates the Worker the flags describe
+func (f *workerFlags) worker() (*powork.Worker, error) {
+	name, bigEndian := strings.CutSuffix(f.alg, "/be")
+	w, err := powork.NewWorkerForAlgorithm(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	if bigEndian {
+		w.SetNonceByteOrder(binary.BigEndian)
+	}
+	if err := w.SetDifficulty(f.difficulty); err != nil {
+		return nil, fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if err := w.SetConcurrency(f.concurrency); err != nil {
+		return nil, fmt.Errorf("%w: %v", errUsage, err)
+	}
+	return w, nil
+}
+
+// newFlagSet creates the flag set of a subcommand, reporting errors to stderr
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("powork "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+// parse parses a subcommand's flags, allowing at most max arguments after them
+func parse(fs *flag.FlagSet, args []string, max int) error {
+	if err := fs.Parse(args); err == flag.ErrHelp {
+		return err
+	} else if err != nil {
+		return fmt.Errorf("%w: %v", errFlags, err)
+	}
+	if fs.NArg() > max {
+		return fmt.Errorf("%w: too many arguments", errUsage)
+	}
+	return nil
+}
+
+// encodeProof encodes a proof in one of the formats base64, hex and json
+func encodeProof(pow *powork.PoWork, format string) (string, error) {
+	if format == "json" {
+		data, err := json.Marshal(pow)
+		return string(data), err
+	}
+
+	data, err := pow.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+
+	switch format {
+	case "base64":
+		return base64.RawURLEncoding.EncodeToString(data), nil
+	case "hex":
+		return hex.EncodeToString(data), nil
+	}
+	return "", fmt.Errorf("%w: unknown format %q", errUsage, format)
+}
+
+// decodeProof decodes a proof encoded by encodeProof
+func decodeProof(s, format string) (*powork.PoWork, error) {
+	s = strings.TrimSpace(s)
+	pow := new(powork.PoWork)
+
+	var data []byte
+	var err error
+	switch format {
+	case "json":
+		return pow, pow.UnmarshalJSON([]byte(s))
+	case "base64":
+		data, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	case "hex":
+		data, err = hex.DecodeString(s)
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", errUsage, format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: proof is not %s", powork.ErrInvalidEncoding, format)
+	}
+
+	return pow, pow.UnmarshalBinary(data)
+}
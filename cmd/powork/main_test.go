@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runCLI runs a command line with stdin and returns its exit status and output
+func runCLI(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	status := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return status, stdout.String(), stderr.String()
+}
+
+func TestSolveVerify(t *testing.T) {
+	for _, format := range []string{"base64", "hex", "json"} {
+		status, proof, stderr := runCLI("a message", "solve", "-difficulty", "8", "-alg", "sha256/be", "-format", format)
+		if status != exitOK {
+			t.Fatalf("solve -format %s exited with %d: %s\n", format, status, stderr)
+		}
+
+		status, out, stderr := runCLI(proof, "verify", "-difficulty", "8", "-format", format)
+		if status != exitOK || !strings.Contains(out, "sha256/be") {
+			t.Fatalf("verify -format %s exited with %d: %s%s\n", format, status, out, stderr)
+		}
+
+		// a higher minimum than the proof was solved at
+		if status, _, _ := runCLI(proof, "verify", "-difficulty", "64", "-format", format); status != exitFail {
+			t.Fatalf("verify of a proof below the minimum exited with %d\n", status)
+		}
+	}
+}
+
+func TestSolveFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "file")
+	os.WriteFile(name, bytes.Repeat([]byte("a large file "), 10000), 0o644)
+	other := filepath.Join(dir, "other")
+	os.WriteFile(other, []byte("another file"), 0o644)
+
+	for _, digest := range []string{"-digest=false", "-digest=true"} {
+		status, proof, stderr := runCLI("", "solve", "-difficulty", "8", digest, name)
+		if status != exitOK {
+			t.Fatalf("solve %s exited with %d: %s\n", digest, status, stderr)
+		}
+
+		if status, _, stderr := runCLI(proof, "verify", "-difficulty", "8", digest, "-file", name); status != exitOK {
+			t.Fatalf("verify %s of the solved file exited with %d: %s\n", digest, status, stderr)
+		}
+		if status, _, _ := runCLI(proof, "verify", "-difficulty", "8", digest, "-file", other); status != exitFail {
+			t.Fatalf("verify %s of another file exited with %d\n", digest, status)
+		}
+	}
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"frobnicate"},
+		{"solve", "-alg", "md4"},
+		{"solve", "-format", "morse"},
+		{"solve", "-difficulty", "1000"},
+		{"solve", "a", "b"},
+		{"bench", "-difficulties", "8,x"},
+		{"verify", "-nonsense"},
+		{"serve", "-key", "not hex"},
+		{"serve", "-capacity", "0"},
+		{"auth", "-key", "000102030405060708090a0b0c0d0e0f", "-capacity", "0"},
+		{"auth", "-session-key", "not hex"},
+		{"auth", "-session-key", "00"},
+		{"auth", "-session-key", "000102030405060708090a0b0c0d0e0f", "-session-ttl", "0"},
+	} {
+		if status, _, _ := runCLI("", args...); status != exitUsage {
+			t.Fatalf("powork %v exited with %d, expected %d\n", args, status, exitUsage)
+		}
+	}
+
+	if status, _, _ := runCLI("garbage", "verify"); status != exitFail {
+		t.Fatalf("verify of garbage exited with %d\n", status)
+	}
+}
+
+func TestBench(t *testing.T) {
+	status, out, stderr := runCLI("", "bench", "-difficulties", "4,6", "-proofs", "2", "-alg", "blake3-256")
+	if status != exitOK {
+		t.Fatalf("bench exited with %d: %s\n", status, stderr)
+	}
+
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 3 || !strings.Contains(lines[0], "hashes/s") {
+		t.Fatalf("bench printed %q\n", out)
+	}
+}
+
+func TestVectors(t *testing.T) {
+	status, out, stderr := runCLI("", "vectors")
+	if status != exitOK {
+		t.Fatalf("vectors exited with %d: %s\n", status, stderr)
+	}
+
+	status, verified, stderr := runCLI(out, "vectors", "-verify", "-")
+	if status != exitOK || !strings.Contains(verified, "vectors verified") {
+		t.Fatalf("vectors -verify exited with %d: %s%s\n", status, verified, stderr)
+	}
+
+	tampered := strings.Replace(out, `"difficulty": 8`, `"difficulty": 9`, 1)
+	if status, _, _ := runCLI(tampered, "vectors", "-verify", "-"); status != exitFail {
+		t.Fatalf("vectors -verify of a tampered vector exited with %d\n", status)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+// Intervals between two progress updates: on a terminal the line is redrawn in place, while
+// logs get a line now and then
+const (
+	terminalUpdates = 200 * time.Millisecond
+	logUpdates      = 5 * time.Second
+)
+
+// progressPrinter reports the progress of a search as its Worker's progress callback
+type progressPrinter struct {
+	out      io.Writer
+	terminal bool
+	every    time.Duration
+	expected float64
+
+	last time.Time
+	// drawn is set when a line has been drawn on the terminal and not yet ended
+	drawn bool
+}
+
+func newProgressPrinter(out io.Writer, difficulty int) *progressPrinter {
+	p := &progressPrinter{out: out, terminal: isTerminal(out), expected: powork.EstimateIterations(difficulty)}
+	p.every = logUpdates
+	if p.terminal {
+		p.every = terminalUpdates
+	}
+	return p
+}
+
+// watch makes w report to p, at an iteration interval small enough for the updates to come
+// on time
+func (p *progressPrinter) watch(w *powork.Worker) {
+	w.SetProgressInterval(1 << 12)
+	w.SetProgressCallback(p.update)
+}
+
+// update prints the progress if it is time to
+func (p *progressPrinter) update(iterations uint64, elapsed time.Duration) {
+	if p.last.IsZero() && elapsed < p.every || !p.last.IsZero() && time.Since(p.last) < p.every {
+		return
+	}
+	p.last = time.Now()
+
+	line := p.line(iterations, elapsed)
+	if p.terminal {
+		fmt.Fprintf(p.out, "\r\033[K%s", line)
+		p.drawn = true
+		return
+	}
+	fmt.Fprintln(p.out, line)
+}
+
+// line describes the progress: the hash rate, the share of the expected work done, and how
+// long the rest of it should take. A search that has passed the expected work is unlucky
+// rather than nearly done, and is reported as such.
+func (p *progressPrinter) line(iterations uint64, elapsed time.Duration) string {
+	rate := float64(iterations) / elapsed.Seconds()
+	done := float64(iterations) / p.expected
+
+	eta := "past expected work"
+	if done < 1 && rate > 0 {
+		eta = "ETA " + time.Duration((p.expected-float64(iterations))/rate*float64(time.Second)).Round(time.Second).String()
+	}
+
+	return fmt.Sprintf("%d hashes in %v, %s/s, %.1f%% of expected work, %s",
+		iterations, elapsed.Round(time.Second), siRate(rate), 100*done, eta)
+}
+
+// finish ends the line drawn on a terminal
+func (p *progressPrinter) finish() {
+	if p.drawn {
+		fmt.Fprint(p.out, "\r\033[K")
+		p.drawn = false
+	}
+}
+
+// siRate formats a hash rate with an SI prefix
+func siRate(rate float64) string {
+	for _, unit := range []string{"", "k", "M", "G"} {
+		if rate < 1000 {
+			return fmt.Sprintf("%.1f %sH", rate, unit)
+		}
+		rate /= 1000
+	}
+	return fmt.Sprintf("%.1f TH", rate)
+}
+
+// isTerminal reports whether w is a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressPrinter(t *testing.T) {
+	var out bytes.Buffer
+	p := newProgressPrinter(&out, 20)
+	if p.terminal || p.every != logUpdates {
+		t.Fatalf("Buffer was taken for a terminal\n")
+	}
+
+	// too early for a first update
+	p.update(1000, time.Second)
+	if out.Len() != 0 {
+		t.Fatalf("Progress was printed before the update interval: %q\n", out.String())
+	}
+
+	// a quarter of the expected 2^20 hashes at 52428.8 hashes per second
+	p.update(1<<18, 5*time.Second)
+	line := out.String()
+	for _, want := range []string{"262144 hashes in 5s", "52.4 kH/s", "25.0% of expected work", "ETA 15s"} {
+		if !strings.Contains(line, want) {
+			t.Fatalf("Progress line %q does not contain %q\n", line, want)
+		}
+	}
+
+	// throttled until the next update is due
+	p.update(1<<19, 6*time.Second)
+	if out.String() != line {
+		t.Fatalf("Progress was printed again right away: %q\n", out.String())
+	}
+
+	if line := p.line(1<<21, time.Minute); !strings.Contains(line, "200.0% of expected work, past expected work") {
+		t.Fatalf("Progress line of an unlucky search is %q\n", line)
+	}
+}
+
+func TestSolveProgress(t *testing.T) {
+	status, _, stderr := runCLI("a message", "solve", "-difficulty", "4", "-progress")
+	if status != exitOK {
+		t.Fatalf("solve -progress exited with %d: %s\n", status, stderr)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powredis"
+	"github.com/Zumium/powork/powservice"
+	"github.com/redis/go-redis/v9"
+)
+
+// serveFlags are the flags of the serve subcommand
+type serveFlags struct {
+	workerFlags
+	addr     string
+	key      string
+	ttl      time.Duration
+	redis    string
+	capacity int
+}
+
+// serve runs a validation service until interrupted
+func serve(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("serve", stderr)
+	var sf serveFlags
+	sf.register(fs)
+	fs.StringVar(&sf.addr, "addr", "localhost:8080", "address to listen on")
+	fs.StringVar(&sf.key, "key", "", "hex encoded challenge signing key, shared by every replica")
+	fs.DurationVar(&sf.ttl, "ttl", time.Minute, "how long challenges can be answered")
+	fs.StringVar(&sf.redis, "redis", "", "address of a Redis to record spent proofs in, shared by every replica")
+	fs.IntVar(&sf.capacity, "capacity", 100000, "spent proofs to hold in memory without -redis")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	h, err := sf.handler()
+	if err != nil {
+		return err
+	}
+
+	return listenAndServe("serve", sf.addr, h, stderr)
+}
+
+// listenAndServe serves h on addr until interrupted, for the subcommand name
+func listenAndServe(name, addr string, h http.Handler, stderr io.Writer) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	srv := &http.Server{Addr: addr, Handler: h, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	fmt.Fprintf(stderr, "powork %s: listening on %s\n", name, addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// handler creates the validation service the flags describe
+func (f *serveFlags) handler() (http.Handler, error) {
+	w, err := f.worker()
+	if err != nil {
+		return nil, err
+	}
+
+	if f.key != "" {
+		key, err := hex.DecodeString(f.key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: -key is not hex", errUsage)
+		}
+		if err := w.SetChallengeKey(key); err != nil {
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+	}
+	if err := w.SetChallengeTTL(f.ttl); err != nil {
+		return nil, fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	store, err := spentStore(f.redis, f.capacity)
+	if err != nil {
+		return nil, err
+	}
+
+	return powservice.NewServer(w, powservice.WithSpentStore(store, f.ttl)), nil
+}
+
+// spentStore creates the store of spent proofs the -redis and -capacity flags describe
+func spentStore(addr string, capacity int) (powork.SpentStore, error) {
+	if addr != "" {
+		return powredis.New(redis.NewClient(&redis.Options{Addr: addr}), time.Second), nil
+	}
+
+	if capacity < 1 {
+		return nil, fmt.Errorf("%w: -capacity must be at least 1", errUsage)
+	}
+	return powork.NewMemorySpentStore(capacity), nil
+}
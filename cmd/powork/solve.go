@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Zumium/powork"
+)
+
+// solve computes a proof for the contents of a file or stdin and prints it encoded
+func solve(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("solve", stderr)
+	var wf workerFlags
+	wf.register(fs)
+	timeout := fs.Duration("timeout", 0, "give up after this long, 0 for never")
+	digest := fs.Bool("digest", false, "prove the SHA-256 digest of the input instead of the input itself, for large files")
+	format := fs.String("format", "base64", "proof encoding: base64, hex or json")
+	progress := fs.Bool("progress", isTerminal(stderr), "report the hash rate and expected time left on stderr")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	w, err := wf.worker()
+	if err != nil {
+		return err
+	}
+	w.SetTimeoutDuration(0)
+
+	in, closeIn, err := openInput(fs.Arg(0), stdin)
+	if err != nil {
+		return err
+	}
+	defer closeIn()
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	if *progress {
+		p := newProgressPrinter(stderr, wf.difficulty)
+		p.watch(w)
+		defer p.finish()
+	}
+
+	var pow *powork.PoWork
+	if *digest {
+		pow, err = w.DoProofForReaderContext(ctx, in)
+	} else {
+		var msg []byte
+		if msg, err = io.ReadAll(in); err == nil {
+			pow, err = w.DoProofForContext(ctx, msg)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	s, err := encodeProof(pow, *format)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, s)
+	return err
+}
+
+// openInput opens the named file, or returns stdin for "" and "-"
+func openInput(name string, stdin io.Reader) (io.Reader, func(), error) {
+	if name == "" || name == "-" {
+		return stdin, func() {}, nil
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, func() { f.Close() }, nil
+}
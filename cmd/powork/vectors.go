@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	powvectors "github.com/Zumium/powork/vectors"
+)
+
+// vectors prints the default test vectors as JSON, or verifies vectors read from a file or
+// stdin
+func vectors(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("vectors", stderr)
+	check := fs.String("verify", "", "verify the JSON array of vectors in this file, - for stdin")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	if *check == "" {
+		vs, err := powvectors.Default()
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(vs, "", "\t")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(stdout, string(data))
+		return err
+	}
+
+	in, closeIn, err := openInput(*check, stdin)
+	if err != nil {
+		return err
+	}
+	defer closeIn()
+
+	var vs []powvectors.Vector
+	if err := json.NewDecoder(in).Decode(&vs); err != nil {
+		return fmt.Errorf("vectors are not a JSON array: %v", err)
+	}
+
+	failed := 0
+	for i, v := range vs {
+		if err := powvectors.Verify(v); err != nil {
+			fmt.Fprintf(stdout, "vector %d: %v\n", i, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d vectors", errInvalid, failed, len(vs))
+	}
+
+	_, err = fmt.Fprintf(stdout, "%d vectors verified\n", len(vs))
+	return err
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Zumium/powork"
+)
+
+// verify checks an encoded proof, given as an argument or on stdin
+func verify(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("verify", stderr)
+	var wf workerFlags
+	wf.register(fs)
+	format := fs.String("format", "base64", "proof encoding: base64, hex or json")
+	file := fs.String("file", "", "also check that the proof is for the contents of this file")
+	digest := fs.Bool("digest", false, "with -file, the proof is for the SHA-256 digest of the file, as by solve -digest")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	encoded := fs.Arg(0)
+	if encoded == "" || encoded == "-" {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+		encoded = string(data)
+	}
+
+	pow, err := decodeProof(encoded, *format)
+	if err != nil {
+		return err
+	}
+
+	// without -alg, check the proof with the algorithm it declares
+	algSet := false
+	fs.Visit(func(f *flag.Flag) { algSet = algSet || f.Name == "alg" })
+	if !algSet && pow.GetAlgorithm() != "" {
+		wf.alg = pow.GetAlgorithm()
+		if pow.GetNonceByteOrder() == binary.BigEndian {
+			wf.alg += "/be"
+		}
+	}
+
+	w, err := wf.worker()
+	if err != nil {
+		return err
+	}
+
+	// -difficulty is the least difficulty accepted
+	ok, err := w.ValidateWithMinimum(pow, wf.difficulty)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: proof does not meet difficulty %d with %s", errInvalid, wf.difficulty, wf.alg)
+	}
+
+	if *file != "" {
+		msg, err := fileMessage(*file, *digest)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(msg, pow.GetMessage()) {
+			return fmt.Errorf("%w: proof is not for %s", errInvalid, *file)
+		}
+	}
+
+	_, err = fmt.Fprintln(stdout, pow)
+	return err
+}
+
+// fileMessage returns the message a proof of the named file is for
+func fileMessage(name string, digest bool) ([]byte, error) {
+	if !digest {
+		return os.ReadFile(name)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return powork.Digest(f)
+}
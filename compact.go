@@ -0,0 +1,96 @@
+package powork
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+)
+
+// BlockHeaderSize is the size of a Bitcoin-style block header
+const BlockHeaderSize = 80
+
+// CompactToTarget decodes a target from the compact "nBits" encoding used by Bitcoin: a one
+// byte size followed by a three byte mantissa, target = mantissa * 256^(size-3). Negative and
+// overflowing encodings are rejected.
+func CompactToTarget(bits uint32) (*big.Int, error) {
+	size := uint(bits >> 24)
+	mantissa := bits & 0x007fffff
+
+	if mantissa != 0 && bits&0x00800000 != 0 {
+		return nil, fmt.Errorf("%w: compact target %08x is negative", ErrInvalidDifficulty, bits)
+	}
+
+	if mantissa != 0 && (size > 34 || (mantissa > 0xff && size > 33) || (mantissa > 0xffff && size > 32)) {
+		return nil, fmt.Errorf("%w: compact target %08x overflows 256 bits", ErrInvalidDifficulty, bits)
+	}
+
+	if size <= 3 {
+		return big.NewInt(int64(mantissa >> (8 * (3 - size)))), nil
+	}
+
+	target := big.NewInt(int64(mantissa))
+	return target.Lsh(target, 8*(size-3)), nil
+}
+
+// TargetToCompact encodes a non-negative target in the compact "nBits" encoding, dropping
+// all but its three most significant bytes
+func TargetToCompact(target *big.Int) uint32 {
+	size := uint((target.BitLen() + 7) / 8)
+
+	var mantissa uint32
+	if size <= 3 {
+		mantissa = uint32(target.Uint64() << (8 * (3 - size)))
+	} else {
+		mantissa = uint32(new(big.Int).Rsh(target, 8*(size-3)).Uint64())
+	}
+
+	// the top mantissa bit is the sign, so move a set one into the next byte
+	if mantissa&0x00800000 != 0 {
+		mantissa >>= 8
+		size++
+	}
+
+	return uint32(size)<<24 | mantissa
+}
+
+// WorkForTarget returns the expected number of hashes needed to meet target with a 256 bit
+// hash, 2^256 / (target + 1), which is what Bitcoin sums into a chain's total work
+func WorkForTarget(target *big.Int) *big.Int {
+	work := new(big.Int).Lsh(big.NewInt(1), 256)
+	return work.Div(work, new(big.Int).Add(target, big.NewInt(1)))
+}
+
+// BlockHeaderHash returns the double SHA-256 of an 80 byte block header, in the byte order
+// in which it is compared to the target: the hash as computed, read as a little-endian number
+func BlockHeaderHash(header []byte) ([]byte, error) {
+	if len(header) != BlockHeaderSize {
+		return nil, fmt.Errorf("%w: block header must be %d bytes", ErrInvalidEncoding, BlockHeaderSize)
+	}
+
+	first := sha256.Sum256(header)
+	sum := sha256.Sum256(first[:])
+	return sum[:], nil
+}
+
+// ValidateBlockHeader checks the proof of work of a Bitcoin-style block header: its double
+// SHA-256, read as a little-endian number, must not exceed the target encoded in the header's
+// nBits field. Only the work is checked, not the rest of the header.
+func ValidateBlockHeader(header []byte) (bool, error) {
+	sum, err := BlockHeaderHash(header)
+	if err != nil {
+		return false, err
+	}
+
+	target, err := CompactToTarget(binary.LittleEndian.Uint32(header[72:76]))
+	if err != nil {
+		return false, err
+	}
+
+	// reverse into big-endian for big.Int
+	for i, j := 0, len(sum)-1; i < j; i, j = i+1, j-1 {
+		sum[i], sum[j] = sum[j], sum[i]
+	}
+
+	return new(big.Int).SetBytes(sum).Cmp(target) <= 0, nil
+}
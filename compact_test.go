@@ -0,0 +1,78 @@
+package powork
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// the header of the Bitcoin genesis block
+const genesisHeader = "0100000000000000000000000000000000000000000000000000000000000000000000003ba3edfd7a7b12b27ac72c3e67768f617fc81bc3888a51323a9fb8aa4b1e5e4a29ab5f49ffff001d1dac2b7c"
+
+func TestCompactTarget(t *testing.T) {
+	target, err := CompactToTarget(0x1d00ffff)
+	if err != nil {
+		t.Fatalf("Could not decode compact target: %v\n", err)
+	}
+
+	expected, _ := new(big.Int).SetString("00000000ffff0000000000000000000000000000000000000000000000000000", 16)
+	if target.Cmp(expected) != 0 {
+		t.Fatalf("Decoded %x, expected %x\n", target, expected)
+	}
+
+	if compact := TargetToCompact(target); compact != 0x1d00ffff {
+		t.Fatalf("Encoded %08x, expected 1d00ffff\n", compact)
+	}
+
+	if work := WorkForTarget(target); work.Cmp(big.NewInt(0x100010001)) != 0 {
+		t.Fatalf("Work is %x, expected 100010001\n", work)
+	}
+
+	for _, c := range []struct{ target, compact uint32 }{
+		{0x80, 0x02008000},
+		{0x12, 0x01120000},
+		{0x1234, 0x02123400},
+		{0x123456, 0x03123456},
+	} {
+		if compact := TargetToCompact(big.NewInt(int64(c.target))); compact != c.compact {
+			t.Fatalf("Encoded %x as %08x, expected %08x\n", c.target, compact, c.compact)
+		}
+		if decoded, _ := CompactToTarget(c.compact); decoded.Int64() != int64(c.target) {
+			t.Fatalf("Decoded %08x as %x, expected %x\n", c.compact, decoded, c.target)
+		}
+	}
+
+	for _, bits := range []uint32{0x04923456, 0xff123456} {
+		if _, err := CompactToTarget(bits); !errors.Is(err, ErrInvalidDifficulty) {
+			t.Fatalf("Expected ErrInvalidDifficulty for %08x, got %v\n", bits, err)
+		}
+	}
+}
+
+func TestValidateBlockHeader(t *testing.T) {
+	header, _ := hex.DecodeString(genesisHeader)
+
+	sum, err := BlockHeaderHash(header)
+	if err != nil {
+		t.Fatalf("Could not hash header: %v\n", err)
+	}
+	if got := hex.EncodeToString(sum); got != "6fe28c0ab6f1b372c1a6a246ae63f74f931e8365e15a089c68d6190000000000" {
+		t.Fatalf("Unexpected genesis hash %s\n", got)
+	}
+
+	ok, err := ValidateBlockHeader(header)
+	if err != nil || !ok {
+		t.Fatalf("Could not validate the genesis header: %v, %v\n", ok, err)
+	}
+
+	// any other nonce misses the target
+	header[76]++
+	if ok, _ := ValidateBlockHeader(header); ok {
+		t.Fatalf("Header with a changed nonce was accepted\n")
+	}
+
+	if _, err := ValidateBlockHeader(header[:79]); !errors.Is(err, ErrInvalidEncoding) {
+		t.Fatalf("Expected ErrInvalidEncoding for a short header, got %v\n", err)
+	}
+}
@@ -0,0 +1,62 @@
+package powork
+
+import "context"
+
+// LegacyResult is the anonymous struct type that the asynchronous API used before Result
+// was introduced.
+//
+// Deprecated: Use Result instead.
+type LegacyResult = struct {
+	*PoWork
+	error
+}
+
+// GetLegacyChannel returns a channel, with the given buffer, that can be used with
+// SendProofToLegacyChannel
+//
+// Deprecated: Use GetChannel instead.
+func GetLegacyChannel(buffer int) chan LegacyResult {
+	return make(chan LegacyResult, buffer)
+}
+
+// PrepareProofLegacy does the same thing as PrepareProof but returns the old anonymous
+// struct channel.
+//
+// Deprecated: Use PrepareProof instead.
+func (p *Worker) PrepareProofLegacy(msg []byte) chan LegacyResult {
+	return p.PrepareProofLegacyWithContext(context.Background(), msg)
+}
+
+// PrepareProofLegacyWithContext does the same thing as PrepareProofLegacy except carrying a context
+//
+// Deprecated: Use PrepareProofWithContext instead.
+func (p *Worker) PrepareProofLegacyWithContext(ctx context.Context, msg []byte) chan LegacyResult {
+	toR := make(chan LegacyResult, 1)
+
+	go func() {
+		r, e := p.DoProofForContext(ctx, msg)
+		toR <- LegacyResult{r, e}
+		close(toR)
+	}()
+
+	return toR
+}
+
+// SendProofToLegacyChannel does the same thing as SendProofToChannel but sends to the old
+// anonymous struct channel.
+//
+// Deprecated: Use SendProofToChannel instead.
+func (p *Worker) SendProofToLegacyChannel(msg []byte, c chan LegacyResult) {
+	p.SendProofToLegacyChannelWithContext(context.Background(), msg, c)
+}
+
+// SendProofToLegacyChannelWithContext does the same thing as SendProofToLegacyChannel except
+// carrying a context
+//
+// Deprecated: Use SendProofToChannelWithContext instead.
+func (p *Worker) SendProofToLegacyChannelWithContext(ctx context.Context, msg []byte, c chan LegacyResult) {
+	go func() {
+		r, e := p.DoProofForContext(ctx, msg)
+		c <- LegacyResult{r, e}
+	}()
+}
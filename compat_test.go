@@ -0,0 +1,38 @@
+package powork
+
+import "testing"
+
+func TestLegacyPrepareProof(t *testing.T) {
+	worker := NewWorker()
+
+	res := <-worker.PrepareProofLegacy([]byte("The old way, with anonymous structs"))
+	if res.error != nil {
+		t.Fatalf("Error: %v\n", res.error)
+	}
+
+	ok, _ := worker.ValidatePoWork(res.PoWork)
+	if !ok {
+		t.Fatalf("Proof did not validate!")
+	}
+}
+
+func TestLegacySendToChannel(t *testing.T) {
+	worker := NewWorker()
+
+	// channels declared with the old anonymous struct type still work
+	c := make(chan struct {
+		*PoWork
+		error
+	}, 1)
+
+	worker.SendProofToLegacyChannel([]byte("The old way, with anonymous structs"), c)
+	res := <-c
+	if res.error != nil {
+		t.Fatalf("Error occurred during proof generation: %v\n", res.error)
+	}
+
+	ok, _ := worker.ValidatePoWork(res.PoWork)
+	if !ok {
+		t.Fatalf("Could not validate message\n")
+	}
+}
@@ -0,0 +1,276 @@
+// Package cuckoo implements John Tromp's Cuckoo Cycle proof of work.
+//
+// A header defines a bipartite graph with 2^edgeBits edges between two sets of 2^edgeBits
+// nodes, the endpoints of edge n being SipHash-2-4 of 2n and 2n+1 keyed with the BLAKE2b-256
+// of the header. A proof is the sorted list of the edges of a cycle of the required length.
+// Finding one takes memory proportional to the graph, while checking one takes a hash per
+// edge of the cycle.
+package cuckoo
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/Zumium/powork"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Params are the size of the graph, as 2^EdgeBits edges, and the length of the cycle
+type Params struct {
+	EdgeBits    int
+	CycleLength int
+}
+
+// Default are 2^20 edges and cycles of length 42, the length Cuckoo Cycle is usually run with.
+// A graph has a cycle of length 42 with a chance of about 1 in 42.
+var Default = Params{EdgeBits: 20, CycleLength: 42}
+
+// maxPathLength bounds the paths followed by the solver. Longer paths are vanishingly rare
+// and only cost the solver a possible cycle.
+const maxPathLength = 8192
+
+// nilNode marks a node without an outgoing edge in the solver
+const nilNode = ^uint32(0)
+
+// Check reports whether the parameters are usable: between 2^4 and 2^31 edges, and an even
+// cycle length of at least 4, since every cycle in a bipartite graph is even
+func (p Params) Check() error {
+	if p.EdgeBits < 4 || p.EdgeBits > 31 {
+		return fmt.Errorf("%w: cuckoo edge bits must be between 4 and 31", powork.ErrInvalidSetting)
+	}
+
+	if p.CycleLength < 4 || p.CycleLength%2 != 0 {
+		return fmt.Errorf("%w: cuckoo cycle length must be even and at least 4", powork.ErrInvalidSetting)
+	}
+
+	return nil
+}
+
+// graph computes the edges of the graph of one header
+type graph struct {
+	key  siphash
+	mask uint64
+}
+
+func (p Params) newGraph(header []byte) *graph {
+	sum := blake2b.Sum256(header)
+
+	g := &graph{mask: 1<<uint(p.EdgeBits) - 1}
+	for i := range g.key {
+		g.key[i] = binary.LittleEndian.Uint64(sum[8*i:])
+	}
+	return g
+}
+
+// edge returns the endpoints of edge n as node ids: even ids on one side, odd on the other
+func (g *graph) edge(n uint32) (uint32, uint32) {
+	u := g.key.hash(2*uint64(n)) & g.mask
+	v := g.key.hash(2*uint64(n)+1) & g.mask
+	return uint32(2 * u), uint32(2*v + 1)
+}
+
+// Solve finds cycles of the required length in the graph of header and returns their edges,
+// each proof sorted. Most headers have none; vary a nonce in the header until one is found.
+func (p Params) Solve(ctx context.Context, header []byte) ([][]uint32, error) {
+	if err := p.Check(); err != nil {
+		return nil, err
+	}
+
+	g := p.newGraph(header)
+	edges := uint32(1) << uint(p.EdgeBits)
+
+	// each node points along the path towards the root of its tree
+	cuckoo := make([]uint32, 2*uint64(edges))
+	for i := range cuckoo {
+		cuckoo[i] = nilNode
+	}
+
+	path := func(u uint32, us []uint32) ([]uint32, bool) {
+		us = append(us[:0], u)
+		for cuckoo[u] != nilNode {
+			u = cuckoo[u]
+			if len(us) >= maxPathLength {
+				return us, false
+			}
+			us = append(us, u)
+		}
+		return us, true
+	}
+
+	var proofs [][]uint32
+	us := make([]uint32, 0, maxPathLength)
+	vs := make([]uint32, 0, maxPathLength)
+	for n := uint32(0); n < edges; n++ {
+		if n%(1<<16) == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		u0, v0 := g.edge(n)
+		if cuckoo[u0] == v0 || cuckoo[v0] == u0 {
+			// a duplicate edge
+			continue
+		}
+
+		var uok, vok bool
+		us, uok = path(u0, us)
+		vs, vok = path(v0, vs)
+		if !uok || !vok {
+			continue
+		}
+
+		nu, nv := len(us)-1, len(vs)-1
+		if us[nu] == vs[nv] {
+			// the edge closes a cycle; find where the two paths meet
+			min := nu
+			if nv < min {
+				min = nv
+			}
+			nu, nv = nu-min, nv-min
+			for us[nu] != vs[nv] {
+				nu++
+				nv++
+			}
+
+			if nu+nv+1 == p.CycleLength {
+				if proof := p.recover(g, u0, v0, us[:nu+1], vs[:nv+1]); len(proof) == p.CycleLength {
+					proofs = append(proofs, proof)
+				}
+			}
+			continue
+		}
+
+		// reverse the shorter path and attach it to the longer one
+		if nu < nv {
+			for i := nu; i > 0; i-- {
+				cuckoo[us[i]] = us[i-1]
+			}
+			cuckoo[u0] = v0
+		} else {
+			for i := nv; i > 0; i-- {
+				cuckoo[vs[i]] = vs[i-1]
+			}
+			cuckoo[v0] = u0
+		}
+	}
+
+	return proofs, nil
+}
+
+// recover finds the edges of the cycle made of edge (u0, v0) and the paths us and vs, which
+// meet at their last node
+func (p Params) recover(g *graph, u0, v0 uint32, us, vs []uint32) []uint32 {
+	type pair struct{ u, v uint32 }
+	cycle := make(map[pair]bool, p.CycleLength)
+
+	add := func(a, b uint32) {
+		if a&1 == 1 {
+			a, b = b, a
+		}
+		cycle[pair{a, b}] = true
+	}
+
+	add(u0, v0)
+	for i := 1; i < len(us); i++ {
+		add(us[i-1], us[i])
+	}
+	for i := 1; i < len(vs); i++ {
+		add(vs[i-1], vs[i])
+	}
+
+	proof := make([]uint32, 0, p.CycleLength)
+	edges := uint32(1) << uint(p.EdgeBits)
+	for n := uint32(0); n < edges && len(proof) < p.CycleLength; n++ {
+		u, v := g.edge(n)
+		if e := (pair{u, v}); cycle[e] {
+			proof = append(proof, n)
+			// a duplicate edge must not be used twice
+			delete(cycle, e)
+		}
+	}
+	return proof
+}
+
+// Verify checks a proof for header: it must list distinct edges in increasing order that form
+// a single cycle of the required length. A proof of the wrong length is reported as an error
+// wrapping powork.ErrInvalidEncoding.
+func (p Params) Verify(header []byte, proof []uint32) (bool, error) {
+	if err := p.Check(); err != nil {
+		return false, err
+	}
+
+	if len(proof) != p.CycleLength {
+		return false, fmt.Errorf("%w: cuckoo proof must have %d edges", powork.ErrInvalidEncoding, p.CycleLength)
+	}
+
+	g := p.newGraph(header)
+	edges := uint32(1) << uint(p.EdgeBits)
+
+	// endpoints of edge i at 2i and 2i+1
+	uvs := make([]uint32, 2*len(proof))
+	for i, n := range proof {
+		if n >= edges || (i > 0 && n <= proof[i-1]) {
+			return false, nil
+		}
+		uvs[2*i], uvs[2*i+1] = g.edge(n)
+	}
+
+	// walk the cycle; every node must have exactly one other edge of the proof
+	steps := 0
+	i := 0
+	for {
+		j := i
+		for k := (i + 2) % len(uvs); k != i; k = (k + 2) % len(uvs) {
+			if uvs[k] == uvs[i] {
+				if j != i {
+					// a branch
+					return false, nil
+				}
+				j = k
+			}
+		}
+
+		if j == i {
+			// a dead end
+			return false, nil
+		}
+
+		i = j ^ 1
+		steps++
+		if i == 0 {
+			break
+		}
+	}
+
+	return steps == p.CycleLength, nil
+}
+
+// Prove searches for a cycle for msg, trying the headers msg || nonce for nonces counting up
+// from 0 as 8 byte little-endian integers, and returns the first nonce with a cycle
+func (p Params) Prove(ctx context.Context, msg []byte) (uint64, []uint32, error) {
+	header := make([]byte, len(msg)+8)
+	copy(header, msg)
+
+	for nonce := uint64(0); ; nonce++ {
+		binary.LittleEndian.PutUint64(header[len(msg):], nonce)
+		proofs, err := p.Solve(ctx, header)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if len(proofs) > 0 {
+			return nonce, proofs[0], nil
+		}
+	}
+}
+
+// VerifyProof checks a nonce and proof returned by Prove for msg
+func (p Params) VerifyProof(msg []byte, nonce uint64, proof []uint32) (bool, error) {
+	header := make([]byte, len(msg)+8)
+	copy(header, msg)
+	binary.LittleEndian.PutUint64(header[len(msg):], nonce)
+
+	return p.Verify(header, proof)
+}
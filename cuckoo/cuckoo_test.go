@@ -0,0 +1,91 @@
+package cuckoo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Zumium/powork"
+)
+
+// a small graph and short cycles, which are common, so that the tests run quickly
+var testParams = Params{EdgeBits: 12, CycleLength: 6}
+
+func TestProveVerify(t *testing.T) {
+	msg := []byte("A message proven with a cycle")
+
+	nonce, proof, err := testParams.Prove(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("Could not find a cycle: %v\n", err)
+	}
+
+	ok, err := testParams.VerifyProof(msg, nonce, proof)
+	if err != nil || !ok {
+		t.Fatalf("Could not verify proof %v: %v, %v\n", proof, ok, err)
+	}
+
+	if ok, _ := testParams.VerifyProof(msg, nonce+1, proof); ok {
+		t.Fatalf("Proof verified for another nonce\n")
+	}
+
+	changed := append([]uint32(nil), proof...)
+	changed[len(changed)-1]++
+	if ok, _ := testParams.VerifyProof(msg, nonce, changed); ok {
+		t.Fatalf("Changed proof verified\n")
+	}
+
+	unsorted := append([]uint32(nil), proof...)
+	unsorted[0], unsorted[1] = unsorted[1], unsorted[0]
+	if ok, _ := testParams.VerifyProof(msg, nonce, unsorted); ok {
+		t.Fatalf("Unsorted proof verified\n")
+	}
+
+	if _, err := testParams.VerifyProof(msg, nonce, proof[1:]); !errors.Is(err, powork.ErrInvalidEncoding) {
+		t.Fatalf("Expected ErrInvalidEncoding for a short proof, got %v\n", err)
+	}
+}
+
+func TestSolveFindsValidCycles(t *testing.T) {
+	p := Params{EdgeBits: 16, CycleLength: 8}
+	if testing.Short() {
+		p = testParams
+	}
+
+	found := 0
+	for nonce := byte(0); nonce < 20; nonce++ {
+		header := []byte{'h', 'e', 'a', 'd', nonce}
+		proofs, err := p.Solve(context.Background(), header)
+		if err != nil {
+			t.Fatalf("Could not solve: %v\n", err)
+		}
+
+		for _, proof := range proofs {
+			if ok, err := p.Verify(header, proof); err != nil || !ok {
+				t.Fatalf("Solver returned an invalid proof %v: %v, %v\n", proof, ok, err)
+			}
+		}
+		found += len(proofs)
+	}
+
+	if found == 0 {
+		t.Fatalf("No cycles in 20 graphs\n")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if err := Default.Check(); err != nil {
+		t.Fatalf("Default parameters rejected: %v\n", err)
+	}
+
+	for _, p := range []Params{{EdgeBits: 3, CycleLength: 42}, {EdgeBits: 32, CycleLength: 42}, {EdgeBits: 20, CycleLength: 41}, {EdgeBits: 20, CycleLength: 2}} {
+		if err := p.Check(); !errors.Is(err, powork.ErrInvalidSetting) {
+			t.Fatalf("Expected ErrInvalidSetting for %+v, got %v\n", p, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := testParams.Prove(ctx, nil); err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v\n", err)
+	}
+}
@@ -0,0 +1,38 @@
+package cuckoo
+
+import "math/bits"
+
+// siphash is SipHash-2-4 with its four state words set directly from a 256 bit key, as in
+// Cuckoo Cycle, rather than derived from a 128 bit key
+type siphash [4]uint64
+
+func (k *siphash) hash(nonce uint64) uint64 {
+	v0, v1, v2, v3 := k[0], k[1], k[2], k[3]^nonce
+
+	round := func() {
+		v0 += v1
+		v2 += v3
+		v1 = bits.RotateLeft64(v1, 13)
+		v3 = bits.RotateLeft64(v3, 16)
+		v1 ^= v0
+		v3 ^= v2
+		v0 = bits.RotateLeft64(v0, 32)
+		v2 += v1
+		v0 += v3
+		v1 = bits.RotateLeft64(v1, 17)
+		v3 = bits.RotateLeft64(v3, 21)
+		v1 ^= v2
+		v3 ^= v0
+		v2 = bits.RotateLeft64(v2, 32)
+	}
+
+	round()
+	round()
+	v0 ^= nonce
+	v2 ^= 0xff
+	round()
+	round()
+	round()
+	round()
+	return v0 ^ v1 ^ v2 ^ v3
+}
@@ -0,0 +1,65 @@
+package powork
+
+import "time"
+
+// checkPeriod is the time an automatically sized check interval aims to leave between two
+// checks of a search's context
+const checkPeriod = 100 * time.Microsecond
+
+// calibrationIterations is the number of iterations timed to size an automatic check interval
+const calibrationIterations = 16
+
+// maxCheckInterval bounds automatically sized check intervals, so that a mismeasured hash
+// cost can't leave a canceled search running for long
+const maxCheckInterval = 1 << 16
+
+// SetCheckInterval sets how many iterations a search goroutine performs between two checks
+// for cancellation and timeout. Checking less often leaves more time for hashing, but a
+// canceled search may run on for up to n iterations. A value of 0, the default, sizes the
+// interval from the measured cost of a hash so that the context is checked about every 100
+// microseconds.
+func (p *Worker) SetCheckInterval(n uint64) {
+	p.checkInterval = n
+}
+
+// GetCheckInterval gets the number of iterations between two checks for cancellation, or 0
+// if it is sized automatically
+func (p *Worker) GetCheckInterval() uint64 {
+	return p.checkInterval
+}
+
+// deadlineCheck counts down the iterations of a search goroutine to its next check of the
+// context
+type deadlineCheck struct {
+	every, left uint64
+	// start is when an automatic interval started being measured, zero once it is sized
+	start time.Time
+}
+
+func (p *Worker) newDeadlineCheck() deadlineCheck {
+	if p.checkInterval > 0 {
+		return deadlineCheck{every: p.checkInterval, left: p.checkInterval}
+	}
+
+	return deadlineCheck{every: calibrationIterations, left: calibrationIterations, start: time.Now()}
+}
+
+// due counts an iteration and reports whether the context should be checked after it
+func (d *deadlineCheck) due() bool {
+	d.left--
+	if d.left > 0 {
+		return false
+	}
+
+	if !d.start.IsZero() {
+		cost := time.Since(d.start) / calibrationIterations
+		d.every = maxCheckInterval
+		if cost > 0 && uint64(checkPeriod/cost) < maxCheckInterval {
+			d.every = max(uint64(checkPeriod/cost), 1)
+		}
+		d.start = time.Time{}
+	}
+
+	d.left = d.every
+	return true
+}
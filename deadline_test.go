@@ -0,0 +1,52 @@
+package powork
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckInterval(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(40)
+	worker.SetCheckInterval(1000)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, cp, err := worker.DoProofForResumableContext(ctx, []byte("a hard proof"))
+	if !errors.Is(err, context.Canceled) || cp == nil {
+		t.Fatalf("Canceled search returned %v and checkpoint %v\n", err, cp)
+	}
+
+	if cp.GetIterations() != 1000 {
+		t.Fatalf("Canceled search stopped after %d iterations, expected 1000\n", cp.GetIterations())
+	}
+}
+
+func TestAutomaticCheckInterval(t *testing.T) {
+	// hashes slower than the check period are checked after every iteration
+	d := deadlineCheck{every: calibrationIterations, left: 1, start: time.Now().Add(-calibrationIterations * time.Millisecond)}
+	if !d.due() || d.every != 1 {
+		t.Fatalf("Slow hashes are checked every %d iterations, expected 1\n", d.every)
+	}
+
+	d = deadlineCheck{every: calibrationIterations, left: 1, start: time.Now().Add(-calibrationIterations * time.Microsecond)}
+	if d.due(); d.every > 100 || d.every < 50 {
+		t.Fatalf("Hashes taking a microsecond are checked every %d iterations, expected about 100\n", d.every)
+	}
+
+	// a timeout still cuts a search short
+	worker := NewWorker()
+	worker.SetDifficulty(40)
+	worker.SetTimeoutDuration(20 * time.Millisecond)
+
+	start := time.Now()
+	if _, err := worker.DoProofFor([]byte("a hard proof")); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Search did not time out: %v\n", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("Search timed out after %v, expected about 20ms\n", elapsed)
+	}
+}
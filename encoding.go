@@ -0,0 +1,205 @@
+package powork
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+const (
+	// binaryVersion is the version byte of proofs that carry nothing but a message
+	binaryVersion = 1
+	// binaryVersionFields is the version byte of proofs followed by optional fields
+	binaryVersionFields = 2
+)
+
+// Tags of the optional fields that follow the message in version 2 of the wire format
+const (
+	fieldChallenge = 1
+	fieldTimestamp = 2
+	// fieldAlgorithm holds the wire identifier of a registered algorithm
+	fieldAlgorithm = 3
+	// fieldAlgorithmName holds the name of an algorithm that is not registered
+	fieldAlgorithmName = 4
+	// fieldPrevious holds the hash of the previous proof in a Chain
+	fieldPrevious = 5
+	// fieldNonce holds a byte nonce, which replaces the proof in the header
+	fieldNonce = 6
+	// fieldExtension holds one extension: the length of its key as a byte, the key and
+	// the value
+	fieldExtension = 7
+)
+
+// binaryHeaderLen is the size of the fixed part of the wire format: version, difficulty,
+// proof and message length
+const binaryHeaderLen = 1 + 2 + 8 + 4
+
+// MarshalBinary implements encoding.BinaryMarshaler. The wire format is, in network byte order:
+//
+//	version    uint8
+//	difficulty uint16
+//	proof      uint64
+//	msgLen     uint32
+//	msg        [msgLen]byte
+//
+// Version 1 ends here. Version 2, used when the proof is bound to more than its message or
+// declares its hash algorithm, continues with optional fields until the end of the data:
+//
+//	tag        uint8
+//	len        uint16
+//	value      [len]byte
+//
+// A registered algorithm is sent as its one byte identifier, any other by name, as is one with
+// a big-endian nonce. A byte nonce is sent as a field of its own, its length giving the length
+// of the nonce.
+//
+// The number of iterations needed to find the proof is not transmitted.
+func (p *PoWork) MarshalBinary() ([]byte, error) {
+	if p.difficulty < 0 || p.difficulty > math.MaxUint16 {
+		return nil, fmt.Errorf("%w: does not fit in the wire format", ErrInvalidDifficulty)
+	}
+
+	if uint64(len(p.msg)) > math.MaxUint32 {
+		return nil, fmt.Errorf("%w: message is too long for the wire format", ErrInvalidEncoding)
+	}
+
+	buf := make([]byte, binaryHeaderLen, binaryHeaderLen+len(p.msg))
+	buf[0] = binaryVersion
+	binary.BigEndian.PutUint16(buf[1:], uint16(p.difficulty))
+	binary.BigEndian.PutUint64(buf[3:], p.proof)
+	binary.BigEndian.PutUint32(buf[11:], uint32(len(p.msg)))
+	buf = append(buf, p.msg...)
+
+	var fields bytes.Buffer
+	if err := p.writeBindings(&fields); err != nil {
+		return nil, err
+	}
+
+	// the algorithm is not hashed: a proof for the wrong algorithm simply fails validation
+	if id := p.algorithmID(); id != "" {
+		var err error
+		if a, ok := LookupAlgorithm(id); ok {
+			err = writeField(&fields, fieldAlgorithm, []byte{a.ID})
+		} else {
+			err = writeField(&fields, fieldAlgorithmName, []byte(id))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.nonceLen > 0 {
+		if err := writeField(&fields, fieldNonce, p.GetNonce()); err != nil {
+			return nil, err
+		}
+	}
+
+	if fields.Len() > 0 {
+		buf[0] = binaryVersionFields
+		buf = append(buf, fields.Bytes()...)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, decoding data produced by MarshalBinary.
+// The decoded proof still has to be checked with ValidatePoWork.
+func (p *PoWork) UnmarshalBinary(data []byte) error {
+	if len(data) < binaryHeaderLen {
+		return fmt.Errorf("%w: encoded proof is too short", ErrInvalidEncoding)
+	}
+
+	version := data[0]
+	if version != binaryVersion && version != binaryVersionFields {
+		return fmt.Errorf("%w: unsupported encoded proof version", ErrInvalidEncoding)
+	}
+
+	msgLen := binary.BigEndian.Uint32(data[11:])
+	rest := uint64(len(data) - binaryHeaderLen)
+	if rest < uint64(msgLen) || (version == binaryVersion && rest != uint64(msgLen)) {
+		return fmt.Errorf("%w: encoded proof length does not match its message length", ErrInvalidEncoding)
+	}
+
+	decoded := PoWork{
+		difficulty: int(binary.BigEndian.Uint16(data[1:])),
+		proof:      binary.BigEndian.Uint64(data[3:]),
+		msg:        append([]byte(nil), data[binaryHeaderLen:binaryHeaderLen+int(msgLen)]...),
+	}
+
+	var nonce []byte
+	fields := data[binaryHeaderLen+int(msgLen):]
+	for len(fields) > 0 {
+		if len(fields) < 3 {
+			return fmt.Errorf("%w: encoded proof has a truncated field", ErrInvalidEncoding)
+		}
+
+		tag := fields[0]
+		n := int(binary.BigEndian.Uint16(fields[1:]))
+		if len(fields)-3 < n {
+			return fmt.Errorf("%w: encoded proof has a truncated field", ErrInvalidEncoding)
+		}
+		value := append([]byte(nil), fields[3:3+n]...)
+		fields = fields[3+n:]
+
+		switch tag {
+		case fieldChallenge:
+			decoded.challenge = value
+		case fieldTimestamp:
+			if len(value) != 8 {
+				return fmt.Errorf("%w: encoded proof has an invalid timestamp", ErrInvalidEncoding)
+			}
+			decoded.timestamp = time.UnixMilli(int64(binary.BigEndian.Uint64(value)))
+		case fieldAlgorithm:
+			if len(value) != 1 {
+				return fmt.Errorf("%w: encoded proof has an invalid algorithm", ErrInvalidEncoding)
+			}
+			a, ok := LookupAlgorithmID(value[0])
+			if !ok {
+				return fmt.Errorf("%w: encoded proof has an unknown algorithm %d", ErrInvalidEncoding, value[0])
+			}
+			decoded.algorithm = a.Name
+		case fieldAlgorithmName:
+			decoded.setAlgorithmID(string(value))
+		case fieldPrevious:
+			decoded.previous = value
+		case fieldNonce:
+			// split once the byte order is known
+			nonce = value
+		case fieldExtension:
+			if err := decoded.decodeExtension(value); err != nil {
+				return err
+			}
+		default:
+			// a proof bound to something we don't understand can't be checked
+			return fmt.Errorf("%w: encoded proof has an unknown field", ErrInvalidEncoding)
+		}
+	}
+
+	if nonce != nil {
+		if err := decoded.setNonce(nonce); err != nil {
+			return err
+		}
+	}
+
+	*p = decoded
+	return nil
+}
+
+// writeField writes a tagged, length-prefixed field
+func writeField(w io.Writer, tag uint8, value []byte) error {
+	if len(value) > math.MaxUint16 {
+		return fmt.Errorf("%w: field is too long for the wire format", ErrInvalidEncoding)
+	}
+
+	var header [3]byte
+	header[0] = tag
+	binary.BigEndian.PutUint16(header[1:], uint16(len(value)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+
+	_, err := w.Write(value)
+	return err
+}
@@ -0,0 +1,57 @@
+package powork
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	worker := NewWorker()
+	proof, err := worker.DoProofForString("A message sent over the wire")
+	if err != nil {
+		t.Fatalf("An error occurred while calculating a default proof of work: %v\n", err)
+	}
+
+	data, err := proof.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Could not marshal proof: %v\n", err)
+	}
+
+	decoded := new(PoWork)
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Could not unmarshal proof: %v\n", err)
+	}
+
+	if !bytes.Equal(decoded.GetMessage(), proof.GetMessage()) || decoded.proof != proof.proof || decoded.difficulty != proof.difficulty {
+		t.Fatalf("Decoded proof differs from the original")
+	}
+
+	// a fresh worker, as on another machine, accepts the decoded proof
+	ok, err := NewWorker().ValidatePoWork(decoded)
+	if err != nil || !ok {
+		t.Fatalf("Decoded proof did not validate: %v\n", err)
+	}
+}
+
+func TestBinaryUnmarshalRejectsMalformed(t *testing.T) {
+	worker := NewWorker()
+	proof, _ := worker.DoProofForString("A message sent over the wire")
+	data, _ := proof.MarshalBinary()
+
+	badVersion := append([]byte(nil), data...)
+	badVersion[0] = 0xFF
+
+	cases := map[string][]byte{
+		"empty":       nil,
+		"short":       data[:binaryHeaderLen-1],
+		"truncated":   data[:len(data)-1],
+		"trailing":    append(append([]byte(nil), data...), 0),
+		"bad version": badVersion,
+	}
+
+	for name, c := range cases {
+		if err := new(PoWork).UnmarshalBinary(c); err == nil {
+			t.Fatalf("Malformed input (%v) was accepted\n", name)
+		}
+	}
+}
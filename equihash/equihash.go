@@ -0,0 +1,339 @@
+// Package equihash implements Equihash, the proof of work based on the generalized birthday
+// problem, with the hash and solution encoding of Zcash.
+//
+// A solution for parameters n and k is a list of 2^k distinct indices into a sequence of
+// n bit hashes of the input whose hashes XOR to zero, arranged so that every subtree of 2^r
+// indices XORs to zero in its first r*n/(k+1) bits. Finding one takes a lot of memory, while
+// checking one takes 2^k hashes.
+package equihash
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/Zumium/powork"
+)
+
+// Params are the Equihash parameters n, the hash length in bits, and k, the number of
+// collision rounds
+type Params struct {
+	N int
+	K int
+}
+
+// Zcash are the parameters of Zcash, n=200 and k=9. Solving them with this package takes
+// several hundred megabytes of memory.
+var Zcash = Params{N: 200, K: 9}
+
+// Check reports whether the parameters are usable: n must be a multiple of 8 and of k+1,
+// at most 512, and the collision length n/(k+1) must be below 31 bits.
+func (p Params) Check() error {
+	switch {
+	case p.K < 3 || p.K >= p.N:
+		return fmt.Errorf("%w: equihash k must be at least 3 and less than n", powork.ErrInvalidSetting)
+	case p.N%8 != 0 || p.N > 512:
+		return fmt.Errorf("%w: equihash n must be a multiple of 8 no larger than 512", powork.ErrInvalidSetting)
+	case p.N%(p.K+1) != 0:
+		return fmt.Errorf("%w: equihash n must be a multiple of k+1", powork.ErrInvalidSetting)
+	case p.N/(p.K+1)+1 >= 32:
+		return fmt.Errorf("%w: equihash collision length must be below 31 bits", powork.ErrInvalidSetting)
+	}
+
+	return nil
+}
+
+// SolutionSize returns the length of an encoded solution, 2^k indices of n/(k+1)+1 bits each
+func (p Params) SolutionSize() int {
+	return (1 << uint(p.K)) * (p.collisionBits() + 1) / 8
+}
+
+// collisionBits is the number of bits that collide in each round
+func (p Params) collisionBits() int {
+	return p.N / (p.K + 1)
+}
+
+// generator computes the hashes of the indices for one input. Each BLAKE2b output holds
+// 512/n hashes, so consecutive indices share a call.
+type generator struct {
+	p       Params
+	base    blake2b
+	perHash uint32
+	block   uint32
+	out     []byte
+}
+
+func (p Params) newGenerator(input []byte) *generator {
+	personal := make([]byte, 16)
+	copy(personal, "ZcashPoW")
+	binary.LittleEndian.PutUint32(personal[8:], uint32(p.N))
+	binary.LittleEndian.PutUint32(personal[12:], uint32(p.K))
+
+	perHash := 512 / p.N
+	base := newBlake2b(perHash*p.N/8, personal)
+	base.Write(input)
+
+	return &generator{p: p, base: base, perHash: uint32(perHash), block: ^uint32(0)}
+}
+
+// chunks writes the hash of index i into out, split into k+1 collision length chunks
+func (g *generator) chunks(i uint32, out []uint32) {
+	if block := i / g.perHash; block != g.block {
+		d := g.base
+		var b [4]byte
+		binary.LittleEndian.PutUint32(b[:], block)
+		d.Write(b[:])
+		g.out = d.sum()
+		g.block = block
+	}
+
+	size := g.p.N / 8
+	start := int(i%g.perHash) * size
+	expand(g.out[start:start+size], g.p.collisionBits(), out)
+}
+
+// expand splits the big-endian bit string x into len(out) values of c bits each
+func expand(x []byte, c int, out []uint32) {
+	var acc uint64
+	have := 0
+	j := 0
+	for _, b := range x {
+		acc = acc<<8 | uint64(b)
+		have += 8
+		for have >= c && j < len(out) {
+			have -= c
+			out[j] = uint32(acc>>uint(have)) & (1<<uint(c) - 1)
+			j++
+		}
+	}
+}
+
+// round is one level of the collision tree. Row r of a round joins rows left[r] and right[r]
+// of the previous round, the one with the smaller first index on the left.
+type round struct {
+	left  []uint32
+	right []uint32
+}
+
+// Solve finds the solutions for input, encoded as Zcash does. An input has about two
+// solutions on average, but may have none; vary a nonce in the input until one is found.
+func (p Params) Solve(ctx context.Context, input []byte) ([][]byte, error) {
+	if err := p.Check(); err != nil {
+		return nil, err
+	}
+
+	c := p.collisionBits()
+	stride := p.K + 1
+	rows := 1 << uint(c+1)
+
+	g := p.newGenerator(input)
+	chunks := make([]uint32, rows*stride)
+	first := make([]uint32, rows)
+	for i := 0; i < rows; i++ {
+		g.chunks(uint32(i), chunks[i*stride:(i+1)*stride])
+		first[i] = uint32(i)
+	}
+
+	rounds := make([]round, 0, p.K)
+	var solutions [][]byte
+	for r := 1; r <= p.K; r++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		n := len(first)
+		order := make([]int, n)
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(a, b int) bool {
+			return chunks[order[a]*stride] < chunks[order[b]*stride]
+		})
+
+		next := stride - 1
+		var nextChunks []uint32
+		var nextFirst []uint32
+		var rd round
+
+		for lo := 0; lo < n; {
+			hi := lo + 1
+			for hi < n && chunks[order[hi]*stride] == chunks[order[lo]*stride] {
+				hi++
+			}
+
+			for x := lo; x < hi; x++ {
+				for y := x + 1; y < hi; y++ {
+					a, b := order[x], order[y]
+					if first[a] == first[b] {
+						continue
+					}
+					if first[a] > first[b] {
+						a, b = b, a
+					}
+
+					if r == p.K {
+						if chunks[a*stride+1] != chunks[b*stride+1] {
+							continue
+						}
+						indices := p.indices(rounds, r-1, uint32(a))
+						indices = append(indices, p.indices(rounds, r-1, uint32(b))...)
+						if distinct(indices) {
+							solutions = append(solutions, p.encode(indices))
+						}
+						continue
+					}
+
+					merged := make([]uint32, next)
+					zero := true
+					for j := range merged {
+						merged[j] = chunks[a*stride+1+j] ^ chunks[b*stride+1+j]
+						zero = zero && merged[j] == 0
+					}
+					if zero {
+						// almost always two subtrees sharing their indices
+						continue
+					}
+
+					nextChunks = append(nextChunks, merged...)
+					nextFirst = append(nextFirst, first[a])
+					rd.left = append(rd.left, uint32(a))
+					rd.right = append(rd.right, uint32(b))
+				}
+			}
+			lo = hi
+		}
+
+		if r == p.K {
+			break
+		}
+
+		rounds = append(rounds, rd)
+		chunks, first, stride = nextChunks, nextFirst, next
+	}
+
+	return solutions, nil
+}
+
+// indices returns the leaf indices under row of the given round, in solution order
+func (p Params) indices(rounds []round, r int, row uint32) []uint32 {
+	if r == 0 {
+		return []uint32{row}
+	}
+
+	rd := rounds[r-1]
+	return append(p.indices(rounds, r-1, rd.left[row]), p.indices(rounds, r-1, rd.right[row])...)
+}
+
+// distinct reports whether no index appears twice
+func distinct(indices []uint32) bool {
+	sorted := append([]uint32(nil), indices...)
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a] < sorted[b] })
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i] == sorted[i-1] {
+			return false
+		}
+	}
+	return true
+}
+
+// encode packs the indices big-endian in n/(k+1)+1 bits each
+func (p Params) encode(indices []uint32) []byte {
+	width := uint(p.collisionBits() + 1)
+	out := make([]byte, 0, p.SolutionSize())
+
+	var acc uint64
+	var have uint
+	for _, i := range indices {
+		acc = acc<<width | uint64(i)
+		have += width
+		for have >= 8 {
+			have -= 8
+			out = append(out, byte(acc>>have))
+		}
+	}
+	return out
+}
+
+// decode unpacks an encoded solution into its indices
+func (p Params) decode(solution []byte) []uint32 {
+	indices := make([]uint32, 1<<uint(p.K))
+	expand(solution, p.collisionBits()+1, indices)
+	return indices
+}
+
+// Verify checks an encoded solution for input. A malformed solution is reported as an
+// error wrapping powork.ErrInvalidEncoding.
+func (p Params) Verify(input, solution []byte) (bool, error) {
+	if err := p.Check(); err != nil {
+		return false, err
+	}
+
+	if len(solution) != p.SolutionSize() {
+		return false, fmt.Errorf("%w: equihash solution must be %d bytes", powork.ErrInvalidEncoding, p.SolutionSize())
+	}
+
+	indices := p.decode(solution)
+	if !distinct(indices) {
+		return false, nil
+	}
+
+	stride := p.K + 1
+	g := p.newGenerator(input)
+	chunks := make([]uint32, len(indices)*stride)
+	first := make([]uint32, len(indices))
+	for j, i := range indices {
+		g.chunks(i, chunks[j*stride:(j+1)*stride])
+		first[j] = i
+	}
+
+	// merge neighbouring subtrees, checking the collision of each round
+	for r := 1; r <= p.K; r++ {
+		n := len(first) / 2
+		for j := 0; j < n; j++ {
+			a, b := 2*j, 2*j+1
+			if first[a] >= first[b] {
+				return false, nil
+			}
+
+			for x := 0; x < stride; x++ {
+				chunks[j*stride+x] = chunks[a*stride+x] ^ chunks[b*stride+x]
+			}
+			if chunks[j*stride+r-1] != 0 {
+				return false, nil
+			}
+			first[j] = first[a]
+		}
+		first = first[:n]
+	}
+
+	return chunks[p.K] == 0, nil
+}
+
+// Prove searches for a solution for msg, trying the inputs msg || nonce for nonces counting
+// up from 0 as 8 byte little-endian integers, and returns the first nonce with a solution
+func (p Params) Prove(ctx context.Context, msg []byte) (uint64, []byte, error) {
+	input := make([]byte, len(msg)+8)
+	copy(input, msg)
+
+	for nonce := uint64(0); ; nonce++ {
+		binary.LittleEndian.PutUint64(input[len(msg):], nonce)
+		solutions, err := p.Solve(ctx, input)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if len(solutions) > 0 {
+			return nonce, solutions[0], nil
+		}
+	}
+}
+
+// VerifyProof checks a nonce and solution returned by Prove for msg
+func (p Params) VerifyProof(msg []byte, nonce uint64, solution []byte) (bool, error) {
+	input := make([]byte, len(msg)+8)
+	copy(input, msg)
+	binary.LittleEndian.PutUint64(input[len(msg):], nonce)
+
+	return p.Verify(input, solution)
+}
@@ -0,0 +1,194 @@
+package equihash
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Zumium/powork"
+	xblake2b "golang.org/x/crypto/blake2b"
+)
+
+// small parameters that solve in a moment
+var testParams = Params{N: 48, K: 5}
+
+func TestBlake2b(t *testing.T) {
+	// without a personalization the hash is plain BLAKE2b
+	for _, size := range []int{32, 50, 64} {
+		for _, n := range []int{0, 1, 127, 128, 129, 300} {
+			data := bytes.Repeat([]byte{0x5a}, n)
+
+			d := newBlake2b(size, nil)
+			d.Write(data[:n/2])
+			d.Write(data[n/2:])
+
+			h, _ := xblake2b.New(size, nil)
+			h.Write(data)
+			if got, expected := d.sum(), h.Sum(nil); !bytes.Equal(got, expected) {
+				t.Fatalf("BLAKE2b-%d of %d bytes is %x, expected %x\n", size*8, n, got, expected)
+			}
+		}
+	}
+}
+
+func TestBlake2bPersonal(t *testing.T) {
+	// digests from Python's hashlib, which wraps the BLAKE2 reference implementation
+	for _, c := range []struct {
+		p        Params
+		data     []byte
+		expected string
+	}{
+		{Zcash, nil, "42fadb7376483e2167dbb245215129da15280a65062e68cf07cc9bc3f71905b8070472455b9fc809308919b7834c78b40726"},
+		{Zcash, []byte("abc"), "52e907446f88b0d5e63e3b2ed93b9cf178cff963d9b89e2a01fe2e42f247b0a58f8f40ccd4471fdadee85d6ab7e69be29285"},
+		{Zcash, bytes.Repeat([]byte{0x5a}, 300), "5a568ef97618bf6fbf7d5934397b007b6929b726789f3d608c46b6895a94e7555a0d0b4199c2e10a262cd4f8e2c1e1aec955"},
+		{testParams, []byte("abc"), "38098d28d404cb9304cafcb904d9eeed66be0850d68b0f6c7d22b95f2c2a1bbe15fe52a076b73618aca61a994c06b05e3264426c34bc263957fd9d40"},
+	} {
+		g := c.p.newGenerator(c.data)
+		if got := hex.EncodeToString(g.base.sum()); got != c.expected {
+			t.Fatalf("Personalized BLAKE2b for %+v of %d bytes is %s, expected %s\n", c.p, len(c.data), got, c.expected)
+		}
+	}
+}
+
+func TestZcash(t *testing.T) {
+	// the input of Zcash's own tests, with a 32 byte little-endian nonce of 1. The solution
+	// was checked by a separate verifier hashing with Python's hashlib.
+	input := make([]byte, 0, 104)
+	input = append(input, "Equihash is an asymmetric PoW based on the Generalised Birthday problem."...)
+	input = binary.LittleEndian.AppendUint64(input, 1)
+	input = append(input, make([]byte, 24)...)
+
+	data, err := os.ReadFile("testdata/zcash.hex")
+	if err != nil {
+		t.Fatalf("Could not read solution: %v\n", err)
+	}
+	solution, err := hex.DecodeString(strings.Join(strings.Fields(string(data)), ""))
+	if err != nil {
+		t.Fatalf("Could not decode solution: %v\n", err)
+	}
+
+	if ok, err := Zcash.Verify(input, solution); err != nil || !ok {
+		t.Fatalf("Could not verify Zcash solution: %v, %v\n", ok, err)
+	}
+	if indices := Zcash.decode(solution); indices[0] != 833 || indices[1] != 1498842 {
+		t.Fatalf("Solution starts with indices %v, expected [833 1498842]\n", indices[:2])
+	}
+
+	changed := append([]byte(nil), solution...)
+	changed[0] ^= 0x80
+	if ok, _ := Zcash.Verify(input, changed); ok {
+		t.Fatalf("Changed Zcash solution verified\n")
+	}
+	input[72] = 2
+	if ok, _ := Zcash.Verify(input, solution); ok {
+		t.Fatalf("Zcash solution verified for another nonce\n")
+	}
+
+	if testing.Short() {
+		return
+	}
+
+	// the solver finds it again, encoded the same way
+	input[72] = 1
+	solutions, err := Zcash.Solve(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Could not solve: %v\n", err)
+	}
+	for _, s := range solutions {
+		if bytes.Equal(s, solution) {
+			return
+		}
+	}
+	t.Fatalf("Solver did not find the Zcash solution among %d\n", len(solutions))
+}
+
+func TestSolveVerify(t *testing.T) {
+	input := []byte("Equihash is an asymmetric PoW based on the Generalised Birthday problem.")
+
+	nonce, solution, err := testParams.Prove(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Could not solve: %v\n", err)
+	}
+	if len(solution) != testParams.SolutionSize() {
+		t.Fatalf("Solution is %d bytes, expected %d\n", len(solution), testParams.SolutionSize())
+	}
+
+	ok, err := testParams.VerifyProof(input, nonce, solution)
+	if err != nil || !ok {
+		t.Fatalf("Could not verify solution: %v, %v\n", ok, err)
+	}
+
+	// another nonce, a changed index, or swapped subtrees are all invalid
+	if ok, _ := testParams.VerifyProof(input, nonce+1, solution); ok {
+		t.Fatalf("Solution verified for another nonce\n")
+	}
+
+	changed := append([]byte(nil), solution...)
+	changed[len(changed)-1] ^= 1
+	if ok, _ := testParams.VerifyProof(input, nonce, changed); ok {
+		t.Fatalf("Changed solution verified\n")
+	}
+
+	indices := testParams.decode(solution)
+	indices[0], indices[1] = indices[1], indices[0]
+	if ok, _ := testParams.VerifyProof(input, nonce, testParams.encode(indices)); ok {
+		t.Fatalf("Solution with swapped subtrees verified\n")
+	}
+
+	if _, err := testParams.Verify(input, solution[1:]); !errors.Is(err, powork.ErrInvalidEncoding) {
+		t.Fatalf("Expected ErrInvalidEncoding for a short solution, got %v\n", err)
+	}
+}
+
+func TestSolveAll(t *testing.T) {
+	p := Params{N: 96, K: 5}
+	if testing.Short() {
+		p = testParams
+	}
+
+	found := 0
+	for nonce := byte(0); nonce < 4; nonce++ {
+		input := []byte{'b', 'l', 'o', 'c', 'k', nonce}
+		solutions, err := p.Solve(context.Background(), input)
+		if err != nil {
+			t.Fatalf("Could not solve: %v\n", err)
+		}
+
+		for _, s := range solutions {
+			if ok, err := p.Verify(input, s); err != nil || !ok {
+				t.Fatalf("Solver returned an invalid solution: %v, %v\n", ok, err)
+			}
+		}
+		found += len(solutions)
+	}
+
+	if found == 0 {
+		t.Fatalf("No solutions for four inputs\n")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if err := Zcash.Check(); err != nil {
+		t.Fatalf("Zcash parameters rejected: %v\n", err)
+	}
+	if Zcash.SolutionSize() != 1344 {
+		t.Fatalf("Zcash solutions are %d bytes, expected 1344\n", Zcash.SolutionSize())
+	}
+
+	for _, p := range []Params{{N: 48, K: 2}, {N: 50, K: 4}, {N: 48, K: 6}, {N: 200, K: 4}} {
+		if err := p.Check(); !errors.Is(err, powork.ErrInvalidSetting) {
+			t.Fatalf("Expected ErrInvalidSetting for %+v, got %v\n", p, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := testParams.Solve(ctx, nil); err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v\n", err)
+	}
+}
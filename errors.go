@@ -0,0 +1,38 @@
+package powork
+
+import "errors"
+
+// Errors returned by the package. Errors carrying more detail wrap one of these, so test
+// for them with errors.Is.
+var (
+	// ErrTimeout is returned when the Worker's timeout elapses before a proof is found. It
+	// also matches context.DeadlineExceeded. Cancellation or expiry of a caller's context
+	// is reported with the context's own error instead.
+	ErrTimeout = errors.New("Timed out before finding a proof")
+
+	// ErrMaxIterations is returned when a search tries every nonce the Worker's maximum
+	// iterations allow without finding a proof
+	ErrMaxIterations = errors.New("Reached the maximum iterations before finding a proof")
+
+	// ErrInvalidDifficulty is returned for difficulties below 1 or too large to represent
+	ErrInvalidDifficulty = errors.New("Invalid difficulty")
+
+	// ErrDifficultyExceedsHash is returned when a difficulty asks for more leading zero
+	// bits than the hash produces
+	ErrDifficultyExceedsHash = errors.New("Difficulty exceeds the size of the hash")
+
+	// ErrInvalidSetting is returned by setters given an out of range value
+	ErrInvalidSetting = errors.New("Invalid setting")
+
+	// ErrInvalidEncoding is returned when decoding a malformed proof or challenge
+	ErrInvalidEncoding = errors.New("Invalid encoding")
+
+	// ErrInvalidStamp is returned when parsing or minting a malformed hashcash stamp
+	ErrInvalidStamp = errors.New("Invalid hashcash stamp")
+
+	// ErrNoSpentStore is returned by ValidateOnce on a Worker without a SpentStore
+	ErrNoSpentStore = errors.New("No spent proof store configured")
+
+	// ErrPoolClosed is returned when submitting a job to a Pool that has been closed
+	ErrPoolClosed = errors.New("Pool is closed")
+)
@@ -0,0 +1,111 @@
+package powork
+
+import (
+	"context"
+	"crypto/md5"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestErrTimeout(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(100)
+	worker.SetTimeout(100)
+
+	_, err := worker.DoProofForString("A message that takes forever")
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Worker timeout did not produce ErrTimeout: %v\n", err)
+	}
+
+	// the caller's own cancellation is not a worker timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = worker.DoProofForContext(ctx, []byte("A message that takes forever"))
+	if errors.Is(err, ErrTimeout) || err != context.DeadlineExceeded {
+		t.Fatalf("Caller deadline was reported as %v\n", err)
+	}
+
+	_, err = worker.MintHashcash("bob@example.com", "")
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Hashcash minting timeout did not produce ErrTimeout: %v\n", err)
+	}
+}
+
+func TestErrInvalidDifficulty(t *testing.T) {
+	worker := NewWorker()
+	if err := worker.SetDifficulty(0); !errors.Is(err, ErrInvalidDifficulty) {
+		t.Fatalf("Difficulty of 0 produced %v\n", err)
+	}
+
+	if worker.GetDifficulty() != 10 {
+		t.Fatalf("Rejected difficulty was stored\n")
+	}
+
+	if err := worker.SetTimeout(-1); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Negative timeout produced %v\n", err)
+	}
+}
+
+func TestErrDifficultyExceedsHash(t *testing.T) {
+	worker := NewWorker()
+	worker.SetHasher(md5.New())
+	if err := worker.SetDifficulty(129); !errors.Is(err, ErrDifficultyExceedsHash) {
+		t.Fatalf("Difficulty above 128 bits with MD5 produced %v\n", err)
+	}
+	if _, err := worker.NewChallengeWithDifficulty(129); !errors.Is(err, ErrDifficultyExceedsHash) {
+		t.Fatalf("Challenge above 128 bits with MD5 produced %v\n", err)
+	}
+
+	// a difficulty set before switching to a shorter hash is caught when it is used
+	worker = NewWorker()
+	worker.SetDifficulty(129)
+	worker.SetHasher(md5.New())
+
+	_, err := worker.ValidatePoWork(&PoWork{msg: []byte("A message")})
+	if !errors.Is(err, ErrDifficultyExceedsHash) {
+		t.Fatalf("Validating above 128 bits with MD5 produced %v\n", err)
+	}
+	if _, err := worker.DoProofFor([]byte("A message")); !errors.Is(err, ErrDifficultyExceedsHash) {
+		t.Fatalf("Searching above 128 bits with MD5 produced %v\n", err)
+	}
+}
+
+func TestMaxDifficulty(t *testing.T) {
+	for _, name := range []string{"sha3-512", "sha256", "sha512", "blake2b-256", "blake2b-512", "blake3-256", "sha256d"} {
+		worker, err := NewWorkerForAlgorithm(name)
+		if err != nil {
+			t.Fatalf("Could not create a Worker for %s: %v\n", name, err)
+		}
+
+		a, _ := LookupAlgorithm(name)
+		bits := a.New().Size() * 8
+		if worker.MaxDifficulty() != bits {
+			t.Fatalf("Maximum difficulty of %s is %d, expected %d\n", name, worker.MaxDifficulty(), bits)
+		}
+
+		if err := worker.SetDifficulty(bits); err != nil {
+			t.Fatalf("Difficulty of %d bits with %s produced %v\n", bits, name, err)
+		}
+		if err := worker.SetDifficulty(bits + 1); !errors.Is(err, ErrDifficultyExceedsHash) {
+			t.Fatalf("Difficulty of %d bits with %s produced %v\n", bits+1, name, err)
+		}
+		if worker.GetDifficulty() != bits {
+			t.Fatalf("Rejected difficulty was stored\n")
+		}
+	}
+}
+
+func TestErrInvalidEncoding(t *testing.T) {
+	if err := new(PoWork).UnmarshalBinary([]byte{1}); !errors.Is(err, ErrInvalidEncoding) {
+		t.Fatalf("Short encoding produced %v\n", err)
+	}
+
+	if _, err := ParseStamp("1:20"); !errors.Is(err, ErrInvalidStamp) {
+		t.Fatalf("Short stamp produced %v\n", err)
+	}
+
+	if _, err := NewWorker().ValidateOnce(new(PoWork)); !errors.Is(err, ErrNoSpentStore) {
+		t.Fatalf("ValidateOnce without a store produced %v\n", err)
+	}
+}
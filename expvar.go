@@ -0,0 +1,69 @@
+package powork
+
+import (
+	"expvar"
+	"sync"
+	"sync/atomic"
+)
+
+// ExpvarName is the name PublishExpvar publishes the counters under
+const ExpvarName = "powork"
+
+var (
+	expvarOnce    sync.Once
+	expvarEnabled atomic.Bool
+
+	expvarHashes      = new(expvar.Int)
+	expvarProofs      = new(expvar.Int)
+	expvarFailures    = new(expvar.Int)
+	expvarValidations = new(expvar.Int)
+	expvarRejections  = new(expvar.Int)
+)
+
+// PublishExpvar publishes counters of the work done by every Worker in the process with the
+// expvar package, as a map named "powork" served at /debug/vars:
+//
+//	hashes       hashes computed while searching for proofs
+//	proofs       proofs found
+//	failures     searches that ended without a proof
+//	validations  proofs validated
+//	rejections   proofs found invalid
+//
+// Only work done after the first call is counted. Calling it again does nothing.
+func PublishExpvar() {
+	expvarOnce.Do(func() {
+		m := expvar.NewMap(ExpvarName)
+		m.Set("hashes", expvarHashes)
+		m.Set("proofs", expvarProofs)
+		m.Set("failures", expvarFailures)
+		m.Set("validations", expvarValidations)
+		m.Set("rejections", expvarRejections)
+		expvarEnabled.Store(true)
+	})
+}
+
+// countSearch adds a finished search to the published counters
+func countSearch(hashes uint64, found bool) {
+	if !expvarEnabled.Load() {
+		return
+	}
+
+	expvarHashes.Add(int64(hashes))
+	if found {
+		expvarProofs.Add(1)
+	} else {
+		expvarFailures.Add(1)
+	}
+}
+
+// countValidation adds a validation to the published counters
+func countValidation(ok bool) {
+	if !expvarEnabled.Load() {
+		return
+	}
+
+	expvarValidations.Add(1)
+	if !ok {
+		expvarRejections.Add(1)
+	}
+}
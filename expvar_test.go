@@ -0,0 +1,39 @@
+package powork
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+)
+
+func TestPublishExpvar(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(8)
+
+	PublishExpvar()
+	PublishExpvar()
+
+	read := func() map[string]int64 {
+		var counters map[string]int64
+		if err := json.Unmarshal([]byte(expvar.Get(ExpvarName).String()), &counters); err != nil {
+			t.Fatalf("Error decoding published counters: %v\n", err)
+		}
+		return counters
+	}
+	before := read()
+
+	pow, _ := worker.DoProofFor([]byte("a message"))
+	worker.ValidatePoWork(pow)
+	pow.proof++
+	worker.ValidatePoWork(pow)
+
+	after := read()
+	if after["proofs"]-before["proofs"] != 1 || after["hashes"]-before["hashes"] != int64(pow.GetIterations())+1 {
+		t.Fatalf("Counted %d proofs and %d hashes, expected 1 and %d\n", after["proofs"]-before["proofs"],
+			after["hashes"]-before["hashes"], pow.GetIterations()+1)
+	}
+	if after["validations"]-before["validations"] != 2 || after["rejections"]-before["rejections"] != 1 {
+		t.Fatalf("Counted %d validations and %d rejections, expected 2 and 1\n",
+			after["validations"]-before["validations"], after["rejections"]-before["rejections"])
+	}
+}
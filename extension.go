@@ -0,0 +1,114 @@
+package powork
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// maxExtensionKeyLen is the longest extension key
+const maxExtensionKeyLen = math.MaxUint8
+
+// DoProofForExtensions calculates a proof of work for msg that is also bound to ext, key/value
+// pairs such as the recipient, the URL of a resource or a client ID. The extensions are
+// hashed along with the message and carried in the encoded proof, so a proof for one resource
+// can't be passed off as a proof for another. Keys must be between 1 and 255 bytes long.
+func (p *Worker) DoProofForExtensions(msg []byte, ext map[string]string) (*PoWork, error) {
+	return p.DoProofForExtensionsContext(context.Background(), msg, ext)
+}
+
+// DoProofForExtensionsContext does the same thing as DoProofForExtensions except carrying a context
+func (p *Worker) DoProofForExtensionsContext(ctx context.Context, msg []byte, ext map[string]string) (*PoWork, error) {
+	base := p.newBase(msg)
+	for k, v := range ext {
+		if err := base.setExtension(k, v, ErrInvalidSetting); err != nil {
+			return nil, err
+		}
+	}
+
+	return p.doProof(ctx, base)
+}
+
+// ValidateExtensions checks a proof as by ValidatePoWork, and also that it is bound to every
+// extension in want with the given value. Extensions of the proof that are not in want are
+// ignored.
+func (p *Worker) ValidateExtensions(pow *PoWork, want map[string]string) (bool, error) {
+	for k, v := range want {
+		if got, ok := pow.extensions[k]; !ok || got != v {
+			return false, nil
+		}
+	}
+
+	return p.ValidatePoWork(pow)
+}
+
+// GetExtension gets the value of the proof's extension key, if it has one
+func (p *PoWork) GetExtension(key string) (string, bool) {
+	v, ok := p.extensions[key]
+	return v, ok
+}
+
+// GetExtensions gets a copy of the proof's extensions, or nil if it has none
+func (p *PoWork) GetExtensions() map[string]string {
+	if len(p.extensions) == 0 {
+		return nil
+	}
+
+	ext := make(map[string]string, len(p.extensions))
+	for k, v := range p.extensions {
+		ext[k] = v
+	}
+	return ext
+}
+
+// setExtension adds an extension, reporting an invalid key as an error wrapping sentinel
+func (p *PoWork) setExtension(key, value string, sentinel error) error {
+	if len(key) == 0 || len(key) > maxExtensionKeyLen {
+		return fmt.Errorf("%w: extension key must be between 1 and %d bytes", sentinel, maxExtensionKeyLen)
+	}
+
+	if p.extensions == nil {
+		p.extensions = make(map[string]string)
+	}
+	p.extensions[key] = value
+	return nil
+}
+
+// writeExtensions writes the proof's extensions as fields, sorted by key so that the same
+// extensions always hash the same
+func (p *PoWork) writeExtensions(w io.Writer) error {
+	keys := make([]string, 0, len(p.extensions))
+	for k := range p.extensions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := p.extensions[k]
+		value := make([]byte, 0, 1+len(k)+len(v))
+		value = append(value, byte(len(k)))
+		value = append(value, k...)
+		value = append(value, v...)
+		if err := writeField(w, fieldExtension, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decodeExtension adds an extension decoded from a field written by writeExtensions
+func (p *PoWork) decodeExtension(value []byte) error {
+	if len(value) < 1 || len(value) < 1+int(value[0]) {
+		return fmt.Errorf("%w: encoded proof has a truncated extension", ErrInvalidEncoding)
+	}
+
+	key := string(value[1 : 1+int(value[0])])
+	if _, ok := p.extensions[key]; ok {
+		return fmt.Errorf("%w: encoded proof has a repeated extension", ErrInvalidEncoding)
+	}
+
+	return p.setExtension(key, string(value[1+int(value[0]):]), ErrInvalidEncoding)
+}
@@ -0,0 +1,76 @@
+package powork
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExtensions(t *testing.T) {
+	worker := NewWorker()
+	ext := map[string]string{"url": "https://example.com/signup", "client": "42"}
+
+	pow, err := worker.DoProofForExtensions([]byte("a bound message"), ext)
+	if err != nil {
+		t.Fatalf("Error doing proof: %v\n", err)
+	}
+
+	if v, ok := pow.GetExtension("url"); !ok || v != ext["url"] {
+		t.Fatalf("Proof has url %q, expected %q\n", v, ext["url"])
+	}
+
+	if ok, err := worker.ValidateExtensions(pow, map[string]string{"url": "https://example.com/signup"}); !ok || err != nil {
+		t.Fatalf("Proof did not validate for its own resource: %v\n", err)
+	}
+
+	if ok, _ := worker.ValidateExtensions(pow, map[string]string{"url": "https://example.com/login"}); ok {
+		t.Fatalf("Proof validated for another resource\n")
+	}
+
+	if ok, _ := worker.ValidateExtensions(pow, map[string]string{"recipient": "bob"}); ok {
+		t.Fatalf("Proof validated for an extension it does not have\n")
+	}
+
+	// the work covers the extensions
+	moved := *pow
+	moved.extensions = pow.GetExtensions()
+	moved.extensions["url"] = "https://example.com/login"
+	if string(moved.Hash()) == string(pow.Hash()) {
+		t.Fatalf("Changing an extension did not change the proof's hash\n")
+	}
+}
+
+func TestExtensionsEncoding(t *testing.T) {
+	worker := NewWorker()
+	ext := map[string]string{"recipient": "alice@example.com", "empty": ""}
+	pow, _ := worker.DoProofForExtensions([]byte("a bound message"), ext)
+
+	data, err := pow.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Error encoding proof: %v\n", err)
+	}
+	decoded := new(PoWork)
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Error decoding proof: %v\n", err)
+	}
+	if ok, err := worker.ValidateExtensions(decoded, ext); !ok || err != nil {
+		t.Fatalf("Decoded proof did not validate: %v\n", err)
+	}
+
+	js, _ := pow.MarshalJSON()
+	fromJSON := new(PoWork)
+	if err := fromJSON.UnmarshalJSON(js); err != nil {
+		t.Fatalf("Error decoding proof from JSON: %v\n", err)
+	}
+	if ok, err := worker.ValidateExtensions(fromJSON, ext); !ok || err != nil {
+		t.Fatalf("Proof decoded from JSON did not validate: %v\n", err)
+	}
+
+	if _, err := worker.DoProofForExtensions(nil, map[string]string{"": "v"}); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Empty extension key did not fail with ErrInvalidSetting: %v\n", err)
+	}
+
+	if _, err := worker.DoProofForExtensions(nil, map[string]string{strings.Repeat("k", 256): "v"}); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Long extension key did not fail with ErrInvalidSetting: %v\n", err)
+	}
+}
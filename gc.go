@@ -0,0 +1,175 @@
+package powork
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// A Collector is a store that can drop the records that have expired, such as the spent
+// proofs of a MemorySpentStore past their TTL, to stay bounded in a long-running service
+type Collector interface {
+	// Collect drops the expired records and returns how many it dropped
+	Collect(ctx context.Context) (int64, error)
+}
+
+// CollectResult describes one store's part in a pass of a GC
+type CollectResult struct {
+	// Store is the name the store was added to the GC under
+	Store     string
+	Reclaimed int64
+	Duration  time.Duration
+	Err       error
+}
+
+// A GC collects the expired records of a set of stores in the background, every interval.
+// Stores are collected one after the other, so a slow database doesn't compete with itself.
+// Its methods are safe for concurrent use.
+type GC struct {
+	interval time.Duration
+
+	mu        sync.Mutex
+	stores    []namedCollector
+	hooks     []func(CollectResult)
+	reclaimed map[string]int64
+	running   bool
+	cancel    context.CancelFunc
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// namedCollector is a store added to a GC
+type namedCollector struct {
+	name string
+	c    Collector
+}
+
+// NewGC creates a GC that collects its stores every interval once started
+func NewGC(interval time.Duration) (*GC, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("%w: interval must be greater than 0", ErrInvalidSetting)
+	}
+
+	return &GC{
+		interval:  interval,
+		reclaimed: make(map[string]int64),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}, nil
+}
+
+// Add adds a store to collect under name, which identifies it in CollectResults and Reclaimed
+func (g *GC) Add(name string, c Collector) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.stores = append(g.stores, namedCollector{name: name, c: c})
+}
+
+// OnCollect adds a function called with the result of every store's collection, to feed
+// metrics or logs. It is called on the GC's goroutine and should return quickly.
+func (g *GC) OnCollect(f func(CollectResult)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.hooks = append(g.hooks, f)
+}
+
+// Start starts collecting in the background. Starting a started or shut down GC does nothing.
+func (g *GC) Start() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.running || g.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	g.running = true
+	g.cancel = cancel
+	go g.run(ctx)
+}
+
+// Shutdown stops the GC, letting a pass in progress finish. If ctx is done first, the pass
+// is canceled and ctx's error returned. Shutting down a GC that was never started does
+// nothing.
+func (g *GC) Shutdown(ctx context.Context) error {
+	g.mu.Lock()
+	if !g.running {
+		g.mu.Unlock()
+		return nil
+	}
+	g.running = false
+	close(g.stop)
+	g.mu.Unlock()
+
+	select {
+	case <-g.done:
+		g.cancel()
+		return nil
+	case <-ctx.Done():
+		g.cancel()
+		return ctx.Err()
+	}
+}
+
+// Collect makes a pass over every store right away, and returns how many records were
+// dropped in all
+func (g *GC) Collect(ctx context.Context) int64 {
+	g.mu.Lock()
+	stores := append([]namedCollector(nil), g.stores...)
+	hooks := append([]func(CollectResult){}, g.hooks...)
+	g.mu.Unlock()
+
+	var total int64
+	for _, s := range stores {
+		if ctx.Err() != nil {
+			break
+		}
+
+		start := time.Now()
+		n, err := s.c.Collect(ctx)
+		r := CollectResult{Store: s.name, Reclaimed: n, Duration: time.Since(start), Err: err}
+
+		g.mu.Lock()
+		g.reclaimed[s.name] += n
+		g.mu.Unlock()
+		total += n
+
+		for _, h := range hooks {
+			h(r)
+		}
+	}
+	return total
+}
+
+// Reclaimed returns how many records the GC has dropped from each store so far
+func (g *GC) Reclaimed() map[string]int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	counts := make(map[string]int64, len(g.reclaimed))
+	for name, n := range g.reclaimed {
+		counts[name] = n
+	}
+	return counts
+}
+
+// run collects every interval until the GC is shut down
+func (g *GC) run(ctx context.Context) {
+	defer close(g.done)
+
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-g.stop:
+			return
+		case <-ticker.C:
+		}
+
+		g.Collect(ctx)
+	}
+}
@@ -0,0 +1,125 @@
+package powork
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowCollector is a Collector that takes until release is closed, or its context is done
+type slowCollector struct {
+	once    sync.Once
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c *slowCollector) Collect(ctx context.Context) (int64, error) {
+	c.once.Do(func() { close(c.started) })
+	select {
+	case <-c.release:
+		return 1, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+func TestGCCollects(t *testing.T) {
+	spent := NewMemorySpentStore(100)
+	bloom, _ := NewBloomSpentStore(1, 100, 0.01)
+	for i := 0; i < 3; i++ {
+		spent.Add(&PoWork{msg: []byte(fmt.Sprintf("spent %d", i))}, time.Millisecond)
+		bloom.Add(&PoWork{msg: []byte(fmt.Sprintf("bloom %d", i))}, 20*time.Millisecond)
+	}
+	spent.Add(&PoWork{msg: []byte("fresh")}, time.Minute)
+
+	gc, err := NewGC(10 * time.Millisecond)
+	if err != nil {
+		t.Fatalf("Error creating GC: %v\n", err)
+	}
+	gc.Add("spent", spent)
+	gc.Add("bloom", bloom)
+
+	var mu sync.Mutex
+	var results []CollectResult
+	gc.OnCollect(func(r CollectResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		results = append(results, r)
+	})
+
+	time.Sleep(30 * time.Millisecond)
+	gc.Start()
+	defer gc.Shutdown(context.Background())
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		reclaimed := gc.Reclaimed()
+		// the bloom store holds one proof in its LRU and two in its filter
+		if reclaimed["spent"] == 3 && reclaimed["bloom"] == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("GC reclaimed %v, expected 3 from each store\n", reclaimed)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if spent.Len() != 1 {
+		t.Fatalf("Spent store holds %d proofs after collection, expected 1\n", spent.Len())
+	}
+	if recent, filtered := bloom.Len(); recent != 0 || filtered != 0 {
+		t.Fatalf("Bloom store holds %d recent and %d filtered proofs after collection\n", recent, filtered)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(results) < 2 || results[0].Store != "spent" || results[1].Store != "bloom" {
+		t.Fatalf("Hook saw %+v, expected the stores in the order they were added\n", results)
+	}
+}
+
+func TestGCShutdown(t *testing.T) {
+	gc, _ := NewGC(time.Millisecond)
+	c := &slowCollector{started: make(chan struct{}), release: make(chan struct{})}
+	gc.Add("slow", c)
+	gc.Start()
+	<-c.started
+
+	// a pass in progress is let finish
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(c.release)
+	}()
+	if err := gc.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Error shutting down: %v\n", err)
+	}
+	if gc.Reclaimed()["slow"] == 0 {
+		t.Fatalf("Pass in progress did not finish before shutdown\n")
+	}
+
+	// unless it takes too long
+	gc, _ = NewGC(time.Millisecond)
+	c = &slowCollector{started: make(chan struct{}), release: make(chan struct{})}
+	gc.Add("slow", c)
+	gc.Start()
+	<-c.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := gc.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown of a stuck pass returned %v, expected context.DeadlineExceeded\n", err)
+	}
+
+	if err := gc.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutting down twice returned %v\n", err)
+	}
+}
+
+func TestGCSettings(t *testing.T) {
+	if _, err := NewGC(0); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Interval of 0 did not fail with ErrInvalidSetting: %v\n", err)
+	}
+}
@@ -0,0 +1,258 @@
+package powork
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Reasons a Guard gives for turning a proof away before hashing it
+const (
+	// GarbageEncoding is given for bytes that don't decode to a proof
+	GarbageEncoding = "encoding"
+	// GarbageAlgorithm is given for proofs with a hash algorithm or nonce byte order the
+	// Worker doesn't accept
+	GarbageAlgorithm = "algorithm"
+	// GarbageAge is given for proofs whose timestamp is missing or out of the Worker's
+	// maximum proof age
+	GarbageAge = "age"
+	// GarbageDifficulty is given for proofs answering a challenge that declare less difficulty
+	// than it asks for, or more than their hash has bits
+	GarbageDifficulty = "difficulty"
+	// GarbageChallenge is given for proofs whose challenge is missing, forged, expired or was
+	// never issued
+	GarbageChallenge = "challenge"
+	// GarbageRate is given for proofs from a source over its rate
+	GarbageRate = "rate"
+)
+
+// A Guard screens proofs before a Worker validates them, so that a flood of junk costs a
+// verifier map lookups rather than hashes. That matters most with memory hard algorithms,
+// where a single validation can take milliseconds. Proofs that fail a cheap check are turned
+// away without hashing: those that don't decode, declare an algorithm or age the Worker
+// would reject, or answer a challenge the Worker doesn't know or with less difficulty than it
+// asks for. Each source, such as
+// an IP address or peer ID, then gets a token bucket of validations, so that a source can't
+// keep the Worker hashing with proofs that look plausible but don't check out.
+//
+// Turned away proofs are reported to the Worker's Validated hooks with Method "Guard" and
+// Garbage set to the reason, and counted by Rejected.
+//
+// A Guard is safe for concurrent use.
+type Guard struct {
+	worker *Worker
+	rate   float64
+	burst  float64
+
+	mu       sync.Mutex
+	sources  map[string]*guardSource
+	swept    time.Time
+	rejected map[string]uint64
+}
+
+// guardSource is the token bucket of one source
+type guardSource struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewGuard creates a Guard in front of w that lets each source have rate validations a
+// second, with bursts of up to burst. A rate of 0 leaves sources unlimited, so that only
+// the cheap checks are made.
+func NewGuard(w *Worker, rate float64, burst int) (*Guard, error) {
+	if rate < 0 {
+		return nil, fmt.Errorf("%w: rate must not be negative", ErrInvalidSetting)
+	}
+
+	if rate > 0 && burst <= 0 {
+		return nil, fmt.Errorf("%w: burst must be greater than 0", ErrInvalidSetting)
+	}
+
+	return &Guard{
+		worker:   w,
+		rate:     rate,
+		burst:    float64(burst),
+		sources:  make(map[string]*guardSource),
+		swept:    time.Now(),
+		rejected: make(map[string]uint64),
+	}, nil
+}
+
+// Validate checks a proof from source as by the Worker's ValidatePoWorkContext, after
+// screening it
+func (g *Guard) Validate(ctx context.Context, source string, pow *PoWork) (bool, error) {
+	start := time.Now()
+	if reason := g.screen(source, pow, false, start); reason != "" {
+		g.turnAway(ctx, start, pow, reason)
+		return false, nil
+	}
+
+	return g.worker.ValidatePoWorkContext(ctx, pow)
+}
+
+// ValidateChallenge checks a proof from source as by the Worker's ValidateChallengeContext,
+// after screening it. It is stricter than the Worker in one respect: provers search at the
+// difficulty a challenge asks for and declare it, so a proof declaring less is turned away,
+// even if its hash happens to meet the challenge. A proof turned away leaves its challenge
+// outstanding.
+func (g *Guard) ValidateChallenge(ctx context.Context, source string, pow *PoWork) (bool, error) {
+	start := time.Now()
+	if reason := g.screen(source, pow, true, start); reason != "" {
+		g.turnAway(ctx, start, pow, reason)
+		return false, nil
+	}
+
+	return g.worker.ValidateChallengeContext(ctx, pow)
+}
+
+// ValidateEncoded decodes a proof encoded by PoWork.MarshalBinary and checks it as by
+// ValidateChallenge if it is bound to a challenge, or as by Validate if it isn't
+func (g *Guard) ValidateEncoded(ctx context.Context, source string, data []byte) (*PoWork, bool, error) {
+	pow := new(PoWork)
+	if err := pow.UnmarshalBinary(data); err != nil {
+		g.turnAway(ctx, time.Now(), nil, GarbageEncoding)
+		return nil, false, nil
+	}
+
+	var ok bool
+	var err error
+	if len(pow.challenge) > 0 {
+		ok, err = g.ValidateChallenge(ctx, source, pow)
+	} else {
+		ok, err = g.Validate(ctx, source, pow)
+	}
+	return pow, ok, err
+}
+
+// Rejected returns how many proofs the Guard has turned away, by reason
+func (g *Guard) Rejected() map[string]uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	counts := make(map[string]uint64, len(g.rejected))
+	for reason, n := range g.rejected {
+		counts[reason] = n
+	}
+	return counts
+}
+
+// Forgive refills the token bucket of source
+func (g *Guard) Forgive(source string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.sources, source)
+}
+
+// screen returns the reason to turn pow away for, or "" if it is worth hashing
+func (g *Guard) screen(source string, pow *PoWork, challenge bool, now time.Time) string {
+	if reason := g.worker.precheck(pow, challenge, now); reason != "" {
+		return reason
+	}
+
+	if g.rate > 0 && !g.take(source, now) {
+		return GarbageRate
+	}
+	return ""
+}
+
+// take takes a token from the bucket of source, reporting whether there was one
+func (g *Guard) take(source string, now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.sweep(now)
+
+	s, ok := g.sources[source]
+	if !ok {
+		s = &guardSource{tokens: g.burst, last: now}
+		g.sources[source] = s
+	}
+	s.tokens = min(g.burst, s.tokens+now.Sub(s.last).Seconds()*g.rate)
+	s.last = now
+
+	if s.tokens < 1 {
+		return false
+	}
+	s.tokens--
+	return true
+}
+
+// sweep drops sources whose buckets have filled up again, at most once per time it takes a
+// bucket to fill
+func (g *Guard) sweep(now time.Time) {
+	refill := time.Duration(g.burst / g.rate * float64(time.Second))
+	if now.Sub(g.swept) < max(refill, time.Second) {
+		return
+	}
+
+	for source, s := range g.sources {
+		if s.tokens+now.Sub(s.last).Seconds()*g.rate >= g.burst {
+			delete(g.sources, source)
+		}
+	}
+	g.swept = now
+}
+
+// turnAway counts a proof turned away for reason and reports it to the Worker's hooks
+func (g *Guard) turnAway(ctx context.Context, start time.Time, pow *PoWork, reason string) {
+	g.mu.Lock()
+	g.rejected[reason]++
+	g.mu.Unlock()
+
+	g.worker.screened(ctx, "Guard", start, pow, reason)
+}
+
+// precheck makes the checks of a validation that need no hashing, returning the reason pow
+// would fail them for, or "" if it might be valid. With challenge set, pow is checked as for
+// ValidateChallenge, without using its challenge up.
+func (p *Worker) precheck(pow *PoWork, challenge bool, now time.Time) string {
+	if !p.acceptsAlgorithm(pow) {
+		return GarbageAlgorithm
+	}
+
+	if !p.acceptsAge(pow, now) {
+		return GarbageAge
+	}
+
+	// ValidatePoWork judges the hash alone, whatever difficulty the proof declares
+	if !challenge {
+		return ""
+	}
+
+	bits := p.MaxDifficulty()
+	if f, ok := p.accepted[pow.algorithm]; ok && pow.algorithm != p.algorithm {
+		bits = f().Size() * 8
+	}
+	if pow.difficulty > bits {
+		return GarbageDifficulty
+	}
+
+	if len(pow.challenge) == 0 {
+		return GarbageChallenge
+	}
+
+	var required int
+	if p.challengeKey != nil {
+		expires, d, ok := p.openChallenge(pow.challenge)
+		if !ok || !now.Before(expires) || d <= 0 {
+			return GarbageChallenge
+		}
+		// a signed challenge sets the difficulty outright
+		required = d
+	} else {
+		d, ok := p.challenges.peek(pow.challenge, now)
+		if !ok {
+			return GarbageChallenge
+		}
+		required = max(d, p.difficulty)
+	}
+
+	// provers declare the difficulty they searched at, which is what they were asked for;
+	// proofs judged by a predicate have no difficulty to compare
+	if p.predicate == nil && pow.difficulty < required {
+		return GarbageDifficulty
+	}
+	return ""
+}
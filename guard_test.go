@@ -0,0 +1,174 @@
+package powork
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGuardScreens(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(8)
+
+	var validations []ValidationResult
+	worker.AddHooks(Hooks{Validated: func(_ context.Context, r ValidationResult) { validations = append(validations, r) }})
+
+	g, err := NewGuard(worker, 0, 0)
+	if err != nil {
+		t.Fatalf("Error creating guard: %v\n", err)
+	}
+
+	pow, _ := worker.DoProofFor([]byte("hello"))
+	if ok, err := g.Validate(context.Background(), "client", pow); !ok || err != nil {
+		t.Fatalf("Guard rejected a valid proof: %v\n", err)
+	}
+
+	// without a challenge the hash alone counts, as it does for ValidatePoWork
+	modest := *pow
+	modest.difficulty = 4
+	if ok, err := g.Validate(context.Background(), "client", &modest); !ok || err != nil {
+		t.Fatalf("Guard rejected a valid proof declaring difficulty 4: %v\n", err)
+	}
+
+	// a proof of an algorithm the Worker doesn't accept is turned away unhashed
+	other, _ := NewWorkerForAlgorithm("blake3-256")
+	other.SetDifficulty(8)
+	foreign, _ := other.DoProofFor([]byte("hello"))
+	if ok, _ := g.Validate(context.Background(), "client", foreign); ok {
+		t.Fatalf("Guard accepted a proof of another algorithm\n")
+	}
+
+	// one without a challenge, or with one never issued, can't answer a challenge
+	if ok, _ := g.ValidateChallenge(context.Background(), "client", pow); ok {
+		t.Fatalf("Guard accepted a proof without a challenge\n")
+	}
+	unknown, _ := worker.DoProofForChallenge(ChallengeFromBytes([]byte("never issued")), []byte("hello"))
+	if ok, _ := g.ValidateChallenge(context.Background(), "client", unknown); ok {
+		t.Fatalf("Guard accepted a proof for a challenge never issued\n")
+	}
+
+	if _, ok, _ := g.ValidateEncoded(context.Background(), "client", []byte{0xff}); ok {
+		t.Fatalf("Guard accepted bytes that don't decode\n")
+	}
+
+	want := map[string]uint64{GarbageAlgorithm: 1, GarbageChallenge: 2, GarbageEncoding: 1}
+	got := g.Rejected()
+	if len(got) != len(want) {
+		t.Fatalf("Guard rejected %v, expected %v\n", got, want)
+	}
+	for reason, n := range want {
+		if got[reason] != n {
+			t.Fatalf("Guard rejected %v, expected %v\n", got, want)
+		}
+	}
+
+	// the valid proofs went through ValidatePoWork, the rest were reported as garbage
+	if len(validations) != 6 || validations[0].Method != "ValidatePoWork" || validations[1].Method != "ValidatePoWork" {
+		t.Fatalf("Hooks saw %d validations, expected 6\n", len(validations))
+	}
+	for _, r := range validations[2:] {
+		if r.Method != "Guard" || r.Outcome() != "garbage" {
+			t.Fatalf("Turned away proof reported as %s %s, expected Guard garbage\n", r.Method, r.Outcome())
+		}
+	}
+}
+
+func TestGuardChallenge(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(8)
+	g, _ := NewGuard(worker, 0, 0)
+
+	c, _ := worker.NewChallengeWithDifficulty(10)
+
+	// a proof at the Worker's difficulty is not enough for the challenge, and is turned
+	// away without using the challenge up
+	short, _ := worker.DoProofForChallenge(c, []byte("hello"))
+	if ok, _ := g.ValidateChallenge(context.Background(), "client", short); ok {
+		t.Fatalf("Guard accepted a proof declaring less than its challenge\n")
+	}
+	if g.Rejected()[GarbageDifficulty] != 1 {
+		t.Fatalf("Guard rejected %v, expected a difficulty rejection\n", g.Rejected())
+	}
+
+	prover := NewWorker()
+	prover.SetDifficulty(c.GetDifficulty())
+	pow, _ := prover.DoProofForChallenge(c, []byte("hello"))
+	if ok, err := g.ValidateChallenge(context.Background(), "client", pow); !ok || err != nil {
+		t.Fatalf("Guard rejected a proof for an outstanding challenge: %v\n", err)
+	}
+
+	if ok, _ := g.ValidateChallenge(context.Background(), "client", pow); ok {
+		t.Fatalf("Guard accepted an answered challenge\n")
+	}
+	if g.Rejected()[GarbageChallenge] != 1 {
+		t.Fatalf("Guard rejected %v, expected a challenge rejection\n", g.Rejected())
+	}
+
+	// signed challenges are checked for their signature and expiry
+	worker.SetChallengeKey([]byte("0123456789abcdef"))
+	worker.SetChallengeTTL(time.Millisecond)
+	c, _ = worker.NewChallenge()
+	pow, _ = worker.DoProofForChallenge(c, []byte("hello"))
+	time.Sleep(2 * time.Millisecond)
+	if ok, _ := g.ValidateChallenge(context.Background(), "client", pow); ok {
+		t.Fatalf("Guard accepted an expired signed challenge\n")
+	}
+	if g.Rejected()[GarbageChallenge] != 2 {
+		t.Fatalf("Guard rejected %v, expected two challenge rejections\n", g.Rejected())
+	}
+}
+
+func TestGuardRate(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(4)
+
+	g, err := NewGuard(worker, 1, 2)
+	if err != nil {
+		t.Fatalf("Error creating guard: %v\n", err)
+	}
+
+	// a plausible proof that doesn't check out still costs a token
+	pow, _ := worker.DoProofFor([]byte("hello"))
+	forged := *pow
+	forged.msg = []byte("goodbye")
+	for {
+		if ok, _ := worker.ValidatePoWork(&forged); !ok {
+			break
+		}
+		forged.proof++
+	}
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := g.Validate(context.Background(), "flooder", &forged); ok {
+			t.Fatalf("Guard accepted a forged proof\n")
+		}
+	}
+
+	if ok, _ := g.Validate(context.Background(), "flooder", pow); ok {
+		t.Fatalf("Guard let a source over its rate through\n")
+	}
+	if g.Rejected()[GarbageRate] != 1 {
+		t.Fatalf("Guard rejected %v, expected a rate rejection\n", g.Rejected())
+	}
+
+	if ok, _ := g.Validate(context.Background(), "bystander", pow); !ok {
+		t.Fatalf("Guard held another source to the flooder's rate\n")
+	}
+
+	g.Forgive("flooder")
+	if ok, _ := g.Validate(context.Background(), "flooder", pow); !ok {
+		t.Fatalf("Guard held a forgiven source to its rate\n")
+	}
+}
+
+func TestGuardSettings(t *testing.T) {
+	worker := NewWorker()
+	if _, err := NewGuard(worker, -1, 1); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Negative rate did not fail with ErrInvalidSetting: %v\n", err)
+	}
+
+	if _, err := NewGuard(worker, 1, 0); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Rate without a burst did not fail with ErrInvalidSetting: %v\n", err)
+	}
+}
@@ -0,0 +1,207 @@
+package powork
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// HashcashValidity is how long after its date a hashcash stamp is accepted
+	HashcashValidity = 28 * 24 * time.Hour
+	// HashcashGrace is how far in the future a hashcash stamp's date may be, to allow for clock skew
+	HashcashGrace = 48 * time.Hour
+)
+
+// hashcashRandSize is the number of random bytes in the rand field of a minted stamp
+const hashcashRandSize = 12
+
+// A Stamp is a hashcash version 1 stamp of the form
+//
+//	1:bits:date:resource:ext:rand:counter
+//
+// as used in X-Hashcash mail headers. Hashcash stamps always use SHA-1.
+type Stamp struct {
+	Bits     int
+	Date     time.Time
+	Resource string
+	Ext      string
+	Rand     string
+	Counter  string
+
+	// date is the date field as it appeared in a parsed stamp, so that String reproduces
+	// the exact stamp that was hashed
+	date string
+}
+
+// ParseStamp parses a hashcash version 1 stamp. It only checks the syntax of the stamp;
+// use ValidateHashcash to check the work.
+func ParseStamp(stamp string) (*Stamp, error) {
+	fields := strings.Split(stamp, ":")
+	if len(fields) != 7 {
+		return nil, fmt.Errorf("%w: must have 7 fields", ErrInvalidStamp)
+	}
+
+	if fields[0] != "1" {
+		return nil, fmt.Errorf("%w: unsupported version", ErrInvalidStamp)
+	}
+
+	bits, err := strconv.Atoi(fields[1])
+	if err != nil || bits < 0 {
+		return nil, fmt.Errorf("%w: invalid bits field", ErrInvalidStamp)
+	}
+
+	date, err := parseHashcashDate(fields[2])
+	if err != nil {
+		return nil, err
+	}
+
+	return &Stamp{
+		Bits:     bits,
+		Date:     date,
+		Resource: fields[3],
+		Ext:      fields[4],
+		Rand:     fields[5],
+		Counter:  fields[6],
+		date:     fields[2],
+	}, nil
+}
+
+// parseHashcashDate parses a date in any of the YYMMDD[hhmm[ss]] forms, in UTC
+func parseHashcashDate(s string) (time.Time, error) {
+	var layout string
+	switch len(s) {
+	case 6:
+		layout = "060102"
+	case 10:
+		layout = "0601021504"
+	case 12:
+		layout = "060102150405"
+	default:
+		return time.Time{}, fmt.Errorf("%w: invalid date field", ErrInvalidStamp)
+	}
+
+	t, err := time.ParseInLocation(layout, s, time.UTC)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid date field", ErrInvalidStamp)
+	}
+	return t, nil
+}
+
+// String formats the stamp
+func (s *Stamp) String() string {
+	date := s.date
+	if date == "" {
+		date = s.Date.UTC().Format("060102")
+	}
+
+	return strings.Join([]string{"1", strconv.Itoa(s.Bits), date, s.Resource, s.Ext, s.Rand, s.Counter}, ":")
+}
+
+// work reports how many leading zero bits the SHA-1 hash of the stamp has
+func (s *Stamp) work() int {
+	sum := sha1.Sum([]byte(s.String()))
+	return leadingZeros(sum[:])
+}
+
+// MintHashcash mints a hashcash version 1 stamp for resource, such as an email address,
+// with the Worker's difficulty as its bits. ext is the stamp's extension field and is
+// usually empty.
+func (p *Worker) MintHashcash(resource, ext string) (string, error) {
+	return p.MintHashcashContext(context.Background(), resource, ext)
+}
+
+// MintHashcashContext does the same thing as MintHashcash except carrying a context
+func (p *Worker) MintHashcashContext(ctx context.Context, resource, ext string) (string, error) {
+	if strings.Contains(resource, ":") || strings.Contains(ext, ":") {
+		return "", fmt.Errorf("%w: resource and extension must not contain ':'", ErrInvalidStamp)
+	}
+
+	if p.difficulty > sha1.Size*8 {
+		return "", fmt.Errorf("%w: SHA-1 has 160 bits", ErrDifficultyExceedsHash)
+	}
+
+	r := make([]byte, hashcashRandSize)
+	if _, err := rand.Read(r); err != nil {
+		return "", err
+	}
+
+	stamp := &Stamp{
+		Bits:     p.difficulty,
+		Date:     time.Now().UTC(),
+		Resource: resource,
+		Ext:      ext,
+		Rand:     base64.StdEncoding.EncodeToString(r),
+	}
+	prefix := strings.TrimSuffix(stamp.String(), ":")
+
+	localCtx, cancelFunc := p.searchContext(ctx)
+	defer cancelFunc()
+
+	buf := make([]byte, 0, len(prefix)+1+13)
+	for counter := uint64(0); ; counter++ {
+		if p.maxIter > 0 && counter >= p.maxIter {
+			return "", ErrMaxIterations
+		}
+
+		buf = append(buf[:0], prefix...)
+		buf = append(buf, ':')
+		buf = strconv.AppendUint(buf, counter, 36)
+
+		sum := sha1.Sum(buf)
+		if leadingZeros(sum[:]) >= p.difficulty {
+			return string(buf), nil
+		}
+
+		select {
+		case <-localCtx.Done():
+			// canceled or timeout
+			return "", searchErr(ctx, localCtx)
+		default:
+			// continue with the next iteration of the loop
+		}
+	}
+}
+
+// ValidateHashcash checks a hashcash version 1 stamp. The stamp is valid if it is for
+// resource, claims at least the Worker's difficulty, actually achieves the bits it claims,
+// and is dated within HashcashValidity in the past or HashcashGrace in the future.
+// Stamps are not remembered, so detecting double spending is up to the caller.
+func (p *Worker) ValidateHashcash(stamp, resource string) (bool, error) {
+	s, err := ParseStamp(stamp)
+	if err != nil {
+		return false, err
+	}
+
+	if s.Resource != resource || s.Bits < p.difficulty {
+		return false, nil
+	}
+
+	now := time.Now()
+	if now.Sub(s.Date) > HashcashValidity || s.Date.Sub(now) > HashcashGrace {
+		return false, nil
+	}
+
+	return s.work() >= s.Bits, nil
+}
+
+// leadingZeros counts the leading zero bits of sum
+func leadingZeros(sum []byte) int {
+	n := 0
+	for _, x := range sum {
+		if x != 0 {
+			for x&0x80 == 0 {
+				n++
+				x <<= 1
+			}
+			return n
+		}
+		n += 8
+	}
+	return n
+}
@@ -0,0 +1,98 @@
+package powork
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHashcashKnownStamp(t *testing.T) {
+	// the example stamp from the hashcash documentation
+	const stamp = "1:20:1303030600:adam@cypherspace.org::McMybZIhxKXu57jd:ckvi"
+
+	s, err := ParseStamp(stamp)
+	if err != nil {
+		t.Fatalf("Could not parse stamp: %v\n", err)
+	}
+
+	if s.String() != stamp {
+		t.Fatalf("Stamp did not format back to itself: %v\n", s)
+	}
+
+	if s.Bits != 20 || s.Resource != "adam@cypherspace.org" || !s.Date.Equal(time.Date(2013, 3, 3, 6, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Stamp fields were not parsed correctly: %+v\n", s)
+	}
+
+	if s.work() < 20 {
+		t.Fatalf("Known stamp does not achieve its bits\n")
+	}
+
+	// it is far too old to be accepted today
+	ok, err := NewWorker().ValidateHashcash(stamp, "adam@cypherspace.org")
+	if err != nil || ok {
+		t.Fatalf("Expired stamp was accepted\n")
+	}
+}
+
+func TestHashcashMintAndValidate(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(16)
+
+	stamp, err := worker.MintHashcash("bob@example.com", "")
+	if err != nil {
+		t.Fatalf("Could not mint stamp: %v\n", err)
+	}
+
+	if !strings.HasPrefix(stamp, "1:16:") {
+		t.Fatalf("Minted stamp has the wrong form: %v\n", stamp)
+	}
+
+	ok, err := worker.ValidateHashcash(stamp, "bob@example.com")
+	if err != nil || !ok {
+		t.Fatalf("Minted stamp did not validate: %v\n", err)
+	}
+
+	ok, _ = worker.ValidateHashcash(stamp, "alice@example.com")
+	if ok {
+		t.Fatalf("Stamp was accepted for another resource\n")
+	}
+
+	stricter := NewWorker()
+	stricter.SetDifficulty(20)
+	ok, _ = stricter.ValidateHashcash(stamp, "bob@example.com")
+	if ok {
+		t.Fatalf("Stamp was accepted below the required difficulty\n")
+	}
+}
+
+func TestHashcashRejectsOverclaim(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(8)
+	stamp, _ := worker.MintHashcash("bob@example.com", "")
+
+	// claiming more bits than the counter achieves
+	s, _ := ParseStamp(stamp)
+	s.Bits = 60
+	ok, _ := worker.ValidateHashcash(s.String(), "bob@example.com")
+	if ok {
+		t.Fatalf("Stamp claiming more work than it contains was accepted\n")
+	}
+}
+
+func TestHashcashMalformed(t *testing.T) {
+	for _, stamp := range []string{
+		"",
+		"1:20:130303:bob",
+		"0:20:130303:bob::rand:0",
+		"1:x:130303:bob::rand:0",
+		"1:20:13030:bob::rand:0",
+	} {
+		if _, err := ParseStamp(stamp); err == nil {
+			t.Fatalf("Malformed stamp was parsed: %q\n", stamp)
+		}
+	}
+
+	if _, err := NewWorker().MintHashcash("bob:example.com", ""); err == nil {
+		t.Fatalf("Resource containing ':' was accepted\n")
+	}
+}
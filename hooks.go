@@ -0,0 +1,186 @@
+package powork
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Hooks are functions a Worker calls as it searches for and validates proofs, to feed
+// metrics, traces and logs. Any of them may be nil. They are called on the goroutines doing
+// the work, possibly concurrently, and should return quickly.
+type Hooks struct {
+	// SearchStart is called when a search for a proof begins. The context it returns, derived
+	// from ctx, is the one passed to SearchDone, so that a span can be started here and ended
+	// there.
+	SearchStart func(ctx context.Context, s SearchInfo) context.Context
+
+	// SearchDone is called when a search ends, with or without a proof
+	SearchDone func(ctx context.Context, r SearchResult)
+
+	// Validated is called when ValidatePoWork, ValidateWithMinimum, ValidateChallenge or
+	// ValidateOnce returns, for the validations that use them, when a Bank checks a deposit
+	// and when a Guard turns a proof away. ctx is the context passed to ValidatePoWorkContext,
+	// ValidateChallengeContext, the Bank or the Guard, or context.Background.
+	Validated func(ctx context.Context, r ValidationResult)
+}
+
+// SearchInfo describes a search that is beginning
+type SearchInfo struct {
+	Difficulty int
+	// Algorithm is the name of the Worker's hash algorithm, or "" if its hash is unnamed
+	Algorithm   string
+	Concurrency int
+}
+
+// SearchResult describes a finished search
+type SearchResult struct {
+	Difficulty int
+	Algorithm  string
+	// Iterations is the number of nonces tried
+	Iterations int
+	Duration   time.Duration
+	// Proof is the proof found, or nil if the search ended with Err
+	Proof *PoWork
+	Err   error
+}
+
+// ValidationResult describes a finished validation
+type ValidationResult struct {
+	// Method is the name of the Worker method that validated the proof, such as
+	// "ValidatePoWork"
+	Method   string
+	Proof    *PoWork
+	Valid    bool
+	Err      error
+	Duration time.Duration
+	// Replayed is set when a proof was rejected because it was already used: ValidateOnce
+	// found it in the SpentStore, or ValidateChallenge found its challenge answered before,
+	// expired or never issued
+	Replayed bool
+	// Garbage is set when a Guard turned the proof away without hashing it, to the reason,
+	// such as GarbageRate. Proof is nil for bytes that didn't decode.
+	Garbage string
+}
+
+// Outcome names the way the search ended: "solved", "timeout", "max_iterations", "canceled"
+// or "error"
+func (r SearchResult) Outcome() string {
+	switch {
+	case r.Err == nil:
+		return "solved"
+	case errors.Is(r.Err, ErrTimeout):
+		return "timeout"
+	case errors.Is(r.Err, ErrMaxIterations):
+		return "max_iterations"
+	case errors.Is(r.Err, context.Canceled):
+		return "canceled"
+	}
+	return "error"
+}
+
+// Outcome names the result of the validation: "valid", "invalid", "replayed", "garbage"
+// or "error"
+func (r ValidationResult) Outcome() string {
+	switch {
+	case r.Err != nil:
+		return "error"
+	case r.Valid:
+		return "valid"
+	case r.Replayed:
+		return "replayed"
+	case r.Garbage != "":
+		return "garbage"
+	}
+	return "invalid"
+}
+
+// AddHooks adds a set of hooks to the Worker, called after any added before
+func (p *Worker) AddHooks(h Hooks) {
+	p.hooks = append(p.hooks, h)
+}
+
+// ClearHooks removes every hook added with AddHooks
+func (p *Worker) ClearHooks() {
+	p.hooks = nil
+}
+
+// searchStarted calls the SearchStart hooks for a search on n goroutines, returning the
+// contexts to pass to SearchDone
+func (p *Worker) searchStarted(ctx context.Context, n int) []context.Context {
+	if len(p.hooks) == 0 {
+		return nil
+	}
+
+	s := SearchInfo{Difficulty: p.difficulty, Algorithm: p.algorithm, Concurrency: n}
+	ctxs := make([]context.Context, len(p.hooks))
+	for i, h := range p.hooks {
+		ctxs[i] = ctx
+		if h.SearchStart != nil {
+			ctxs[i] = h.SearchStart(ctx, s)
+		}
+	}
+	return ctxs
+}
+
+// searchDone calls the SearchDone hooks for a search that started at start and logs its end
+func (p *Worker) searchDone(ctx context.Context, ctxs []context.Context, start time.Time, pow, partial *PoWork, err error) {
+	if len(p.hooks) == 0 && p.logger == nil {
+		return
+	}
+
+	r := SearchResult{Difficulty: p.difficulty, Algorithm: p.algorithm, Duration: time.Since(start), Proof: pow, Err: err}
+	if pow != nil {
+		r.Iterations = pow.requiredIterations
+	} else if partial != nil {
+		r.Iterations = partial.requiredIterations
+	}
+
+	if p.logger != nil {
+		p.logSearchDone(ctx, r)
+	}
+
+	for i, h := range p.hooks {
+		if h.SearchDone != nil {
+			h.SearchDone(ctxs[i], r)
+		}
+	}
+}
+
+// validated calls the Validated hooks for a validation by method that started at start and
+// logs its result
+func (p *Worker) validated(ctx context.Context, method string, start time.Time, pow *PoWork, ok, replayed bool, err error) {
+	countValidation(ok)
+	if len(p.hooks) == 0 && p.logger == nil {
+		return
+	}
+
+	r := ValidationResult{Method: method, Proof: pow, Valid: ok, Err: err, Duration: time.Since(start), Replayed: replayed}
+	if p.logger != nil {
+		p.logValidation(ctx, r)
+	}
+	for _, h := range p.hooks {
+		if h.Validated != nil {
+			h.Validated(ctx, r)
+		}
+	}
+}
+
+// screened calls the Validated hooks for a proof turned away by method for reason without
+// being hashed, and logs it
+func (p *Worker) screened(ctx context.Context, method string, start time.Time, pow *PoWork, reason string) {
+	countValidation(false)
+	if len(p.hooks) == 0 && p.logger == nil {
+		return
+	}
+
+	r := ValidationResult{Method: method, Proof: pow, Duration: time.Since(start), Garbage: reason}
+	if p.logger != nil {
+		p.logValidation(ctx, r)
+	}
+	for _, h := range p.hooks {
+		if h.Validated != nil {
+			h.Validated(ctx, r)
+		}
+	}
+}
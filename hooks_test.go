@@ -0,0 +1,129 @@
+package powork
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type hookKey struct{}
+
+func TestHooks(t *testing.T) {
+	var mu sync.Mutex
+	var started []SearchInfo
+	var done []SearchResult
+	var validations []ValidationResult
+
+	worker := NewWorker()
+	worker.SetSpentStore(NewMemorySpentStore(10), time.Minute)
+	worker.AddHooks(Hooks{
+		SearchStart: func(ctx context.Context, s SearchInfo) context.Context {
+			mu.Lock()
+			defer mu.Unlock()
+			started = append(started, s)
+			return context.WithValue(ctx, hookKey{}, "span")
+		},
+		SearchDone: func(ctx context.Context, r SearchResult) {
+			if ctx.Value(hookKey{}) != "span" {
+				t.Errorf("SearchDone did not get the context returned by SearchStart\n")
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			done = append(done, r)
+		},
+	})
+	// hooks with only some functions set
+	worker.AddHooks(Hooks{Validated: func(_ context.Context, r ValidationResult) { validations = append(validations, r) }})
+
+	pow, _ := worker.DoProofFor([]byte("a message"))
+	if len(started) != 1 || started[0].Difficulty != 10 || started[0].Algorithm != "sha3-512" {
+		t.Fatalf("SearchStart was called with %+v\n", started)
+	}
+	if len(done) != 1 || done[0].Proof != pow || done[0].Iterations != pow.GetIterations() || done[0].Duration <= 0 {
+		t.Fatalf("SearchDone was called with %+v\n", done)
+	}
+
+	worker.SetMaxIterations(1)
+	worker.SetDifficulty(40)
+	worker.DoProofFor([]byte("a message"))
+	if len(done) != 2 || !errors.Is(done[1].Err, ErrMaxIterations) || done[1].Proof != nil {
+		t.Fatalf("SearchDone of a failed search was called with %+v\n", done[1])
+	}
+	worker.SetDifficulty(10)
+
+	worker.ValidatePoWork(pow)
+	worker.ValidateOnce(pow)
+	worker.ValidateOnce(pow)
+	if len(validations) != 3 {
+		t.Fatalf("Validated was called %d times, expected 3\n", len(validations))
+	}
+	for i, want := range []ValidationResult{
+		{Method: "ValidatePoWork", Valid: true},
+		{Method: "ValidateOnce", Valid: true},
+		{Method: "ValidateOnce", Replayed: true},
+	} {
+		got := validations[i]
+		if got.Method != want.Method || got.Valid != want.Valid || got.Replayed != want.Replayed || got.Proof != pow {
+			t.Fatalf("Validation %d is %+v, expected %+v\n", i, got, want)
+		}
+	}
+
+	worker.ClearHooks()
+	worker.ValidatePoWork(pow)
+	if len(validations) != 3 {
+		t.Fatalf("Validated was called after ClearHooks\n")
+	}
+}
+
+func TestHooksChallengeReplay(t *testing.T) {
+	worker := NewWorker()
+	var validations []ValidationResult
+	worker.AddHooks(Hooks{Validated: func(_ context.Context, r ValidationResult) { validations = append(validations, r) }})
+
+	challenge, _ := worker.NewChallenge()
+	pow, _ := worker.DoProofForChallenge(challenge, []byte("a message"))
+	worker.ValidateChallenge(pow)
+	worker.ValidateChallenge(pow)
+
+	if len(validations) != 2 || !validations[0].Valid || validations[1].Valid || !validations[1].Replayed {
+		t.Fatalf("Challenge validations were %+v\n", validations)
+	}
+	if validations[1].Method != "ValidateChallenge" {
+		t.Fatalf("Challenge validation was reported as %s\n", validations[1].Method)
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	searches := map[error]string{
+		nil:                             "solved",
+		ErrTimeout:                      "timeout",
+		ErrMaxIterations:                "max_iterations",
+		context.Canceled:                "canceled",
+		fmt.Errorf("%w: x", ErrTimeout): "timeout",
+		ErrInvalidSetting:               "error",
+	}
+	for err, want := range searches {
+		if got := (SearchResult{Err: err}).Outcome(); got != want {
+			t.Fatalf("Outcome of a search ending with %v is %q, expected %q\n", err, got, want)
+		}
+	}
+
+	validations := []struct {
+		r    ValidationResult
+		want string
+	}{
+		{ValidationResult{Valid: true}, "valid"},
+		{ValidationResult{}, "invalid"},
+		{ValidationResult{Replayed: true}, "replayed"},
+		{ValidationResult{Garbage: GarbageRate}, "garbage"},
+		{ValidationResult{Err: ErrInvalidEncoding}, "error"},
+	}
+	for _, v := range validations {
+		if got := v.r.Outcome(); got != v.want {
+			t.Fatalf("Outcome of %+v is %q, expected %q\n", v.r, got, v.want)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package powork
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A Job is a proof of work search running in the background that can be paused, resumed
+// and canceled, for example to yield the CPU while an app is in the background. Its methods
+// are safe for concurrent use.
+type Job struct {
+	st     *searchState
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// set before done is closed
+	pow *PoWork
+	err error
+}
+
+// PrepareProofJob starts working on a proof of work for msg like PrepareProof, and returns a
+// handle to control the search with
+func (p *Worker) PrepareProofJob(msg []byte) *Job {
+	return p.PrepareProofJobWithContext(context.Background(), msg)
+}
+
+// PrepareProofJobWithContext does the same thing as PrepareProofJob except carrying a context.
+// The Worker's timeout keeps running while the Job is paused; use SetTimeoutDuration(0) for
+// searches that may be paused for long.
+func (p *Worker) PrepareProofJobWithContext(ctx context.Context, msg []byte) *Job {
+	ctx, cancel := context.WithCancel(ctx)
+
+	st := p.newSearchState()
+	st.gate = new(pauseGate)
+	// count iterations for Progress even without a progress callback
+	callback := st.progress
+	st.progress = func(iterations uint64, elapsed time.Duration) {
+		if callback != nil {
+			callback(iterations, elapsed)
+		}
+	}
+
+	j := &Job{st: st, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer cancel()
+		j.pow, _, j.err = p.doSearch(ctx, st, p.newBase(msg))
+		close(j.done)
+	}()
+
+	return j
+}
+
+// Pause stops the search goroutines after their current iteration until Resume is called.
+// Pausing a paused Job does nothing.
+func (j *Job) Pause() {
+	j.st.gate.pause()
+}
+
+// Resume lets a paused search continue
+func (j *Job) Resume() {
+	j.st.gate.resume()
+}
+
+// Paused reports whether the Job is paused
+func (j *Job) Paused() bool {
+	return j.st.gate.paused.Load()
+}
+
+// Cancel stops the search for good, paused or not. Wait then returns context.Canceled.
+func (j *Job) Cancel() {
+	j.cancel()
+}
+
+// Progress returns the number of iterations performed so far and the time since the search
+// started, paused time included. Iterations are counted in steps of the Worker's progress
+// interval until the search finishes.
+func (j *Job) Progress() (uint64, time.Duration) {
+	select {
+	case <-j.done:
+		if j.pow != nil {
+			return uint64(j.pow.requiredIterations), time.Since(j.st.start)
+		}
+	default:
+	}
+
+	j.st.mu.Lock()
+	defer j.st.mu.Unlock()
+	return j.st.iterations, time.Since(j.st.start)
+}
+
+// Done returns a channel that is closed when the search has finished
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Wait blocks until the search has finished and returns its result
+func (j *Job) Wait() (*PoWork, error) {
+	<-j.done
+	return j.pow, j.err
+}
+
+// pauseGate holds search goroutines while it is paused
+type pauseGate struct {
+	paused atomic.Bool
+
+	mu      sync.Mutex
+	resumed chan struct{}
+}
+
+func (g *pauseGate) pause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.paused.Load() {
+		g.resumed = make(chan struct{})
+		g.paused.Store(true)
+	}
+}
+
+func (g *pauseGate) resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.paused.Load() {
+		g.paused.Store(false)
+		close(g.resumed)
+	}
+}
+
+// wait blocks while the gate is paused, or until ctx is done
+func (g *pauseGate) wait(ctx context.Context) {
+	g.mu.Lock()
+	resumed := g.resumed
+	paused := g.paused.Load()
+	g.mu.Unlock()
+
+	if !paused {
+		return
+	}
+
+	select {
+	case <-resumed:
+	case <-ctx.Done():
+	}
+}
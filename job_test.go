@@ -0,0 +1,71 @@
+package powork
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJobResult(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(8)
+
+	job := worker.PrepareProofJob([]byte("a job"))
+	pow, err := job.Wait()
+	if err != nil {
+		t.Fatalf("Job failed: %v\n", err)
+	}
+
+	if ok, err := worker.ValidatePoWork(pow); !ok || err != nil {
+		t.Fatalf("Job proof did not validate: %v\n", err)
+	}
+
+	if iterations, _ := job.Progress(); iterations != uint64(pow.GetIterations()) {
+		t.Fatalf("Finished job reports %d iterations, expected %d\n", iterations, pow.GetIterations())
+	}
+
+	select {
+	case <-job.Done():
+	default:
+		t.Fatalf("Finished job is not done\n")
+	}
+}
+
+func TestJobPauseResumeCancel(t *testing.T) {
+	for _, n := range []int{1, 4} {
+		worker := NewWorker()
+		worker.SetConcurrency(n)
+		worker.SetDifficulty(60)
+		worker.SetTimeoutDuration(0)
+		worker.SetProgressInterval(100)
+
+		job := worker.PrepareProofJob([]byte("a long job"))
+		time.Sleep(20 * time.Millisecond)
+
+		job.Pause()
+		if !job.Paused() {
+			t.Fatalf("Paused job does not report being paused\n")
+		}
+		// let the goroutines finish their current iteration
+		time.Sleep(10 * time.Millisecond)
+		before, _ := job.Progress()
+		time.Sleep(30 * time.Millisecond)
+		if after, _ := job.Progress(); after != before {
+			t.Fatalf("Paused job with %d goroutines went from %d to %d iterations\n", n, before, after)
+		}
+
+		job.Resume()
+		time.Sleep(20 * time.Millisecond)
+		if after, _ := job.Progress(); after <= before {
+			t.Fatalf("Resumed job with %d goroutines made no progress\n", n)
+		}
+
+		// canceling works while paused
+		job.Pause()
+		job.Cancel()
+		if _, err := job.Wait(); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Canceled job returned %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package powork
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// jsonPoWork is the JSON representation of a PoWork. The message is base64 encoded by
+// encoding/json.
+type jsonPoWork struct {
+	Message    []byte            `json:"msg"`
+	Nonce      uint64            `json:"nonce"`
+	Difficulty int               `json:"difficulty"`
+	Algorithm  string            `json:"alg,omitempty"`
+	Challenge  []byte            `json:"challenge,omitempty"`
+	Timestamp  int64             `json:"timestamp,omitempty"`
+	Previous   []byte            `json:"prev,omitempty"`
+	NonceBytes []byte            `json:"nonceBytes,omitempty"`
+	Extensions map[string]string `json:"ext,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler, producing an object of the form
+//
+//	{"msg":"<base64>","nonce":123,"difficulty":10,"alg":"sha3-512"}
+//
+// Proofs bound to a challenge also carry a base64 "challenge" member, timestamped
+// proofs a "timestamp" member in Unix milliseconds, links of a Chain a base64 "prev"
+// member, proofs with a byte nonce the whole nonce as a base64 "nonceBytes" member, and
+// proofs with extensions an "ext" object.
+func (p *PoWork) MarshalJSON() ([]byte, error) {
+	j := jsonPoWork{
+		Message:    p.msg,
+		Nonce:      p.proof,
+		Difficulty: p.difficulty,
+		Algorithm:  p.algorithmID(),
+		Challenge:  p.challenge,
+		Previous:   p.previous,
+		Extensions: p.extensions,
+	}
+
+	if !p.timestamp.IsZero() {
+		j.Timestamp = p.timestamp.UnixMilli()
+	}
+
+	if p.nonceLen > 0 {
+		j.NonceBytes = p.GetNonce()
+	}
+
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding data produced by MarshalJSON.
+// The decoded proof still has to be checked with ValidatePoWork.
+func (p *PoWork) UnmarshalJSON(data []byte) error {
+	var j jsonPoWork
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	if j.Difficulty < 0 {
+		return fmt.Errorf("%w: declared difficulty must not be negative", ErrInvalidDifficulty)
+	}
+
+	p.msg = j.Message
+	p.proof = j.Nonce
+	p.difficulty = j.Difficulty
+	p.setAlgorithmID(j.Algorithm)
+	p.challenge = j.Challenge
+	p.previous = j.Previous
+	p.extensions = nil
+	for k, v := range j.Extensions {
+		if err := p.setExtension(k, v, ErrInvalidEncoding); err != nil {
+			return err
+		}
+	}
+	p.timestamp = time.Time{}
+	if j.Timestamp != 0 {
+		p.timestamp = time.UnixMilli(j.Timestamp)
+	}
+	p.nonceLen, p.extraNonce = 0, nil
+	if j.NonceBytes != nil {
+		if err := p.setNonce(j.NonceBytes); err != nil {
+			return err
+		}
+	}
+	p.requiredIterations = 0
+	return nil
+}
+
+// jsonChallenge is the JSON representation of a Challenge
+type jsonChallenge struct {
+	Value      []byte `json:"challenge"`
+	Difficulty int    `json:"difficulty,omitempty"`
+	Expires    int64  `json:"expires,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler, producing an object of the form
+//
+//	{"challenge":"<base64>","difficulty":16,"expires":1700000000000}
+//
+// with the expiry in Unix milliseconds. Challenges built with ChallengeFromBytes have
+// neither difficulty nor expiry.
+func (c *Challenge) MarshalJSON() ([]byte, error) {
+	j := jsonChallenge{Value: c.value, Difficulty: c.difficulty}
+	if !c.expires.IsZero() {
+		j.Expires = c.expires.UnixMilli()
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding data produced by MarshalJSON. The
+// decoded difficulty and expiry are what the issuer declared; the issuer decides whether
+// a proof is acceptable.
+func (c *Challenge) UnmarshalJSON(data []byte) error {
+	var j jsonChallenge
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	if len(j.Value) == 0 {
+		return fmt.Errorf("%w: challenge is empty", ErrInvalidEncoding)
+	}
+	if j.Difficulty < 0 {
+		return fmt.Errorf("%w: declared difficulty must not be negative", ErrInvalidDifficulty)
+	}
+
+	c.value = j.Value
+	c.difficulty = j.Difficulty
+	c.expires = time.Time{}
+	if j.Expires != 0 {
+		c.expires = time.UnixMilli(j.Expires)
+	}
+	return nil
+}
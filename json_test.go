@@ -0,0 +1,98 @@
+package powork
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	worker := NewWorker()
+	proof, err := worker.DoProofForString("A message inside a REST payload")
+	if err != nil {
+		t.Fatalf("An error occurred while calculating a default proof of work: %v\n", err)
+	}
+
+	payload := struct {
+		User  string  `json:"user"`
+		Proof *PoWork `json:"proof"`
+	}{"bob", proof}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Could not marshal proof: %v\n", err)
+	}
+
+	var decoded struct {
+		User  string  `json:"user"`
+		Proof *PoWork `json:"proof"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Could not unmarshal proof: %v\n", err)
+	}
+
+	if !bytes.Equal(decoded.Proof.GetMessage(), proof.GetMessage()) || decoded.Proof.algorithm != "sha3-512" {
+		t.Fatalf("Decoded proof differs from the original: %s\n", data)
+	}
+
+	ok, err := NewWorker().ValidatePoWork(decoded.Proof)
+	if err != nil || !ok {
+		t.Fatalf("Decoded proof did not validate: %v\n", err)
+	}
+}
+
+func TestJSONAlgorithmMismatch(t *testing.T) {
+	data := []byte(`{"msg":"aGVsbG8=","nonce":1,"difficulty":10,"alg":"md5"}`)
+
+	proof := new(PoWork)
+	if err := json.Unmarshal(data, proof); err != nil {
+		t.Fatalf("Could not unmarshal proof: %v\n", err)
+	}
+
+	ok, err := NewWorker().ValidatePoWork(proof)
+	if err != nil || ok {
+		t.Fatalf("Proof declaring a different algorithm was accepted\n")
+	}
+
+	// workers with a custom hash have no algorithm id and do not check it
+	worker := NewWorker()
+	worker.SetHasher(md5.New())
+	if worker.algorithm != "" {
+		t.Fatalf("Custom hash kept the default algorithm id\n")
+	}
+}
+
+func TestChallengeJSON(t *testing.T) {
+	worker := NewWorker()
+	challenge, err := worker.NewChallengeWithDifficulty(12)
+	if err != nil {
+		t.Fatalf("Error issuing challenge: %v\n", err)
+	}
+
+	data, err := json.Marshal(challenge)
+	if err != nil {
+		t.Fatalf("Could not marshal challenge: %v\n", err)
+	}
+
+	decoded := new(Challenge)
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatalf("Could not unmarshal challenge: %v\n", err)
+	}
+
+	if !bytes.Equal(decoded.GetBytes(), challenge.GetBytes()) || decoded.GetDifficulty() != 12 ||
+		!decoded.GetExpiry().Equal(challenge.GetExpiry().Truncate(time.Millisecond)) {
+		t.Fatalf("Decoded challenge differs from the original: %s\n", data)
+	}
+
+	data, _ = json.Marshal(ChallengeFromBytes([]byte("abc")))
+	if string(data) != `{"challenge":"YWJj"}` {
+		t.Fatalf("Challenge without difficulty or expiry encodes as %s\n", data)
+	}
+
+	if err := json.Unmarshal([]byte(`{"difficulty":12}`), decoded); !errors.Is(err, ErrInvalidEncoding) {
+		t.Fatalf("Empty challenge did not fail with ErrInvalidEncoding: %v\n", err)
+	}
+}
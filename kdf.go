@@ -0,0 +1,39 @@
+package powork
+
+import "hash"
+
+// kdfHash adapts a key derivation function to hash.Hash so that memory-hard functions can
+// drive an ordinary Worker. Written data is buffered and derive runs once per Sum.
+type kdfHash struct {
+	buf    []byte
+	size   int
+	derive func(in []byte) []byte
+}
+
+func (k *kdfHash) Write(b []byte) (int, error) {
+	k.buf = append(k.buf, b...)
+	return len(b), nil
+}
+
+func (k *kdfHash) Sum(b []byte) []byte {
+	return append(b, k.derive(k.buf)...)
+}
+
+func (k *kdfHash) Reset() {
+	k.buf = k.buf[:0]
+}
+
+func (k *kdfHash) Size() int {
+	return k.size
+}
+
+func (k *kdfHash) BlockSize() int {
+	return 1
+}
+
+// newKDFHashFunc returns a constructor of kdfHash objects of the given size
+func newKDFHashFunc(size int, derive func(in []byte) []byte) func() hash.Hash {
+	return func() hash.Hash {
+		return &kdfHash{size: size, derive: derive}
+	}
+}
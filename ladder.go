@@ -0,0 +1,189 @@
+package powork
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// DefaultLadderRange is how many bits above its base difficulty a Ladder climbs by default
+const DefaultLadderRange = 8
+
+// A Ladder escalates the difficulty demanded from clients that make too many requests. It
+// counts the requests of each client, identified by a key such as an IP address or an API
+// token, over a sliding window. A client within the allowed number of requests per window is
+// asked for the base difficulty; every further allowance of requests adds a step, so the
+// work a client must do grows exponentially with its request rate. Clients that slow down
+// fall back down the ladder as the window slides on.
+//
+// A Ladder is safe for concurrent use.
+type Ladder struct {
+	base      int
+	window    time.Duration
+	allowance int
+
+	mu      sync.Mutex
+	step    int
+	max     int
+	clients map[string]*ladderClient
+	swept   time.Time
+}
+
+// ladderClient counts the requests of one client in the current window and the one before
+type ladderClient struct {
+	start    time.Time
+	current  int
+	previous int
+}
+
+// NewLadder creates a Ladder that asks clients for the base difficulty as long as they make
+// at most allowance requests per window
+func NewLadder(base int, window time.Duration, allowance int) (*Ladder, error) {
+	if base <= 0 {
+		return nil, fmt.Errorf("%w: must be at least 1", ErrInvalidDifficulty)
+	}
+
+	if window <= 0 {
+		return nil, fmt.Errorf("%w: window must be greater than 0", ErrInvalidSetting)
+	}
+
+	if allowance <= 0 {
+		return nil, fmt.Errorf("%w: allowance must be greater than 0", ErrInvalidSetting)
+	}
+
+	return &Ladder{
+		base:      base,
+		window:    window,
+		allowance: allowance,
+		step:      1,
+		max:       base + DefaultLadderRange,
+		clients:   make(map[string]*ladderClient),
+		swept:     time.Now(),
+	}, nil
+}
+
+// SetStep sets how many bits of difficulty each allowance of excess requests adds. The
+// default is 1, which doubles the work.
+func (l *Ladder) SetStep(bits int) error {
+	if bits <= 0 {
+		return fmt.Errorf("%w: step must be greater than 0", ErrInvalidSetting)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.step = bits
+	return nil
+}
+
+// SetMax sets the highest difficulty the Ladder demands. The default is DefaultLadderRange
+// bits above the base difficulty.
+func (l *Ladder) SetMax(difficulty int) error {
+	if difficulty < l.base {
+		return fmt.Errorf("%w: maximum must be at least the base difficulty", ErrInvalidDifficulty)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.max = difficulty
+	return nil
+}
+
+// Hit records a request by the client with the given key and returns the difficulty to
+// demand from it
+func (l *Ladder) Hit(key string) int {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	c, ok := l.clients[key]
+	if !ok {
+		c = &ladderClient{start: now}
+		l.clients[key] = c
+	}
+	c.advance(now, l.window)
+	c.current++
+
+	return l.difficultyFor(c, now)
+}
+
+// Difficulty returns the difficulty to demand from the client with the given key without
+// recording a request
+func (l *Ladder) Difficulty(key string) int {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c, ok := l.clients[key]
+	if !ok {
+		return l.base
+	}
+	c.advance(now, l.window)
+
+	return l.difficultyFor(c, now)
+}
+
+// Forgive forgets the requests of the client with the given key, putting it back at the
+// base difficulty
+func (l *Ladder) Forgive(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.clients, key)
+}
+
+// difficultyFor returns the difficulty for a client's estimated request rate
+func (l *Ladder) difficultyFor(c *ladderClient, now time.Time) int {
+	// weigh the previous window by how much of it the sliding window still covers
+	covered := 1 - float64(now.Sub(c.start))/float64(l.window)
+	rate := float64(c.previous)*covered + float64(c.current)
+
+	excess := math.Floor((rate - 1) / float64(l.allowance))
+	if excess <= 0 {
+		return l.base
+	}
+
+	if steps := float64(l.max-l.base) / float64(l.step); excess > steps {
+		return l.max
+	}
+
+	d := l.base + int(excess)*l.step
+	if d > l.max {
+		return l.max
+	}
+	return d
+}
+
+// sweep drops clients without requests in the last two windows, at most once per window
+func (l *Ladder) sweep(now time.Time) {
+	if now.Sub(l.swept) < l.window {
+		return
+	}
+
+	for key, c := range l.clients {
+		if now.Sub(c.start) >= 2*l.window {
+			delete(l.clients, key)
+		}
+	}
+	l.swept = now
+}
+
+// advance moves the client's windows along to the one now falls into
+func (c *ladderClient) advance(now time.Time, window time.Duration) {
+	elapsed := now.Sub(c.start)
+	if elapsed < window {
+		return
+	}
+
+	if elapsed < 2*window {
+		c.previous = c.current
+	} else {
+		c.previous = 0
+	}
+	c.current = 0
+	c.start = c.start.Add(elapsed / window * window)
+}
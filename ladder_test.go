@@ -0,0 +1,85 @@
+package powork
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLadderEscalates(t *testing.T) {
+	l, err := NewLadder(10, time.Hour, 5)
+	if err != nil {
+		t.Fatalf("Error creating ladder: %v\n", err)
+	}
+
+	expected := []int{10, 10, 10, 10, 10, 11, 11, 11, 11, 11, 12}
+	for i, e := range expected {
+		if d := l.Hit("offender"); d != e {
+			t.Fatalf("Request %d got difficulty %d, expected %d\n", i+1, d, e)
+		}
+	}
+
+	if d := l.Difficulty("offender"); d != 12 {
+		t.Fatalf("Offender has difficulty %d, expected 12\n", d)
+	}
+
+	if d := l.Hit("bystander"); d != 10 {
+		t.Fatalf("Another client got difficulty %d, expected 10\n", d)
+	}
+
+	l.Forgive("offender")
+	if d := l.Difficulty("offender"); d != 10 {
+		t.Fatalf("Forgiven client has difficulty %d, expected 10\n", d)
+	}
+}
+
+func TestLadderStepAndMax(t *testing.T) {
+	l, _ := NewLadder(10, time.Hour, 1)
+	l.SetStep(2)
+	l.SetMax(15)
+
+	expected := []int{10, 12, 14, 15, 15}
+	for i, e := range expected {
+		if d := l.Hit("client"); d != e {
+			t.Fatalf("Request %d got difficulty %d, expected %d\n", i+1, d, e)
+		}
+	}
+}
+
+func TestLadderCoolsDown(t *testing.T) {
+	l, _ := NewLadder(10, 50*time.Millisecond, 1)
+	for i := 0; i < 4; i++ {
+		l.Hit("client")
+	}
+	if d := l.Difficulty("client"); d != 13 {
+		t.Fatalf("Client has difficulty %d, expected 13\n", d)
+	}
+
+	time.Sleep(120 * time.Millisecond)
+	if d := l.Hit("client"); d != 10 {
+		t.Fatalf("Client has difficulty %d after slowing down, expected 10\n", d)
+	}
+}
+
+func TestLadderSettings(t *testing.T) {
+	if _, err := NewLadder(0, time.Second, 1); !errors.Is(err, ErrInvalidDifficulty) {
+		t.Fatalf("Zero base did not fail with ErrInvalidDifficulty: %v\n", err)
+	}
+
+	if _, err := NewLadder(10, 0, 1); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Zero window did not fail with ErrInvalidSetting: %v\n", err)
+	}
+
+	if _, err := NewLadder(10, time.Second, 0); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Zero allowance did not fail with ErrInvalidSetting: %v\n", err)
+	}
+
+	l, _ := NewLadder(10, time.Second, 1)
+	if err := l.SetStep(0); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Zero step did not fail with ErrInvalidSetting: %v\n", err)
+	}
+
+	if err := l.SetMax(9); !errors.Is(err, ErrInvalidDifficulty) {
+		t.Fatalf("Maximum below the base did not fail with ErrInvalidDifficulty: %v\n", err)
+	}
+}
@@ -0,0 +1,120 @@
+package powork
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"time"
+)
+
+// firstMilestone is the share of the expected work at which a search logs its first
+// progress milestone. Later milestones are each at twice the work of the one before.
+const firstMilestone = 0.25
+
+// SetLogger sets a logger the Worker reports to, to diagnose slow or failing searches and
+// rejected proofs. It logs at debug level when a search starts, passes a quarter and half
+// of the expected work and finds a proof, and when a proof is accepted. It logs at info level
+// when a search passes the expected work and each doubling of it, when it times out or
+// reaches the maximum iterations, and when a proof is rejected. Milestones are noticed
+// every progress interval. Passing nil stops logging.
+func (p *Worker) SetLogger(l *slog.Logger) {
+	p.logger = l
+}
+
+// GetLogger gets the logger the Worker reports to, or nil if it has none
+func (p *Worker) GetLogger() *slog.Logger {
+	return p.logger
+}
+
+// expectedIterations returns the expected number of hashes to find a proof for the Worker
+func (p *Worker) expectedIterations() float64 {
+	if p.target != nil {
+		return EstimateIterationsForTarget(p.target, p.MaxDifficulty())
+	}
+	return EstimateIterations(p.difficulty)
+}
+
+// logProgress wraps a progress callback to also log the milestones of a search
+func (p *Worker) logProgress(ctx context.Context, progress func(uint64, time.Duration)) func(uint64, time.Duration) {
+	expected := p.expectedIterations()
+	next := expected * firstMilestone
+	return func(iterations uint64, elapsed time.Duration) {
+		if progress != nil {
+			progress(iterations, elapsed)
+		}
+
+		if float64(iterations) < next {
+			return
+		}
+
+		share := float64(iterations) / expected
+		level := slog.LevelDebug
+		if share >= 1 {
+			level = slog.LevelInfo
+		}
+		p.logger.Log(ctx, level, "proof of work search in progress",
+			"iterations", iterations,
+			"elapsed", elapsed,
+			"expected_work", math.Round(share*100)/100)
+
+		for next <= float64(iterations) {
+			next *= 2
+		}
+	}
+}
+
+// logSearchStart logs the start of a search on n goroutines
+func (p *Worker) logSearchStart(ctx context.Context, n int) {
+	p.logger.DebugContext(ctx, "proof of work search started",
+		"difficulty", p.difficulty,
+		"algorithm", p.algorithm,
+		"concurrency", n,
+		"expected_iterations", p.expectedIterations())
+}
+
+// logSearchDone logs the end of a search
+func (p *Worker) logSearchDone(ctx context.Context, r SearchResult) {
+	attrs := []any{
+		"difficulty", r.Difficulty,
+		"algorithm", r.Algorithm,
+		"iterations", r.Iterations,
+		"elapsed", r.Duration,
+	}
+
+	switch {
+	case r.Err == nil:
+		p.logger.DebugContext(ctx, "proof of work found", attrs...)
+	case errors.Is(r.Err, ErrTimeout):
+		p.logger.InfoContext(ctx, "proof of work search timed out", append(attrs, "timeout", p.maxWait)...)
+	case errors.Is(r.Err, ErrMaxIterations):
+		p.logger.InfoContext(ctx, "proof of work search reached the maximum iterations", append(attrs, "max_iterations", p.maxIter)...)
+	default:
+		p.logger.DebugContext(ctx, "proof of work search stopped", append(attrs, "error", r.Err)...)
+	}
+}
+
+// logValidation logs the result of a validation
+func (p *Worker) logValidation(ctx context.Context, r ValidationResult) {
+	attrs := []any{"method", r.Method, "elapsed", r.Duration}
+	if r.Proof != nil {
+		attrs = append(attrs, "difficulty", r.Proof.difficulty, "algorithm", r.Proof.algorithmID())
+	}
+
+	if r.Valid {
+		p.logger.DebugContext(ctx, "proof of work accepted", attrs...)
+		return
+	}
+
+	// garbage is logged quietly so that a flood of it doesn't flood the log too
+	if r.Garbage != "" {
+		p.logger.DebugContext(ctx, "proof of work turned away", append(attrs, "reason", r.Garbage)...)
+		return
+	}
+
+	attrs = append(attrs, "outcome", r.Outcome())
+	if r.Err != nil {
+		attrs = append(attrs, "error", r.Err)
+	}
+	p.logger.InfoContext(ctx, "proof of work rejected", attrs...)
+}
@@ -0,0 +1,93 @@
+package powork
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	worker := NewWorker()
+	worker.SetDifficulty(8)
+	worker.SetProgressInterval(16)
+	worker.SetLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	pow, err := worker.DoProofFor([]byte("a message"))
+	if err != nil {
+		t.Fatalf("Error finding proof: %v\n", err)
+	}
+
+	logs := buf.String()
+	for _, want := range []string{"proof of work search started", "difficulty=8", "proof of work found"} {
+		if !strings.Contains(logs, want) {
+			t.Fatalf("Search logs lack %q:\n%s", want, logs)
+		}
+	}
+	// a quarter of the expected work is 64 iterations
+	if pow.GetIterations() >= 80 && !strings.Contains(logs, "search in progress") {
+		t.Fatalf("Search of %d iterations logged no milestone:\n%s", pow.GetIterations(), logs)
+	}
+
+	buf.Reset()
+	pow.proof++
+	worker.ValidatePoWork(pow)
+	if logs := buf.String(); !strings.Contains(logs, "proof of work rejected") || !strings.Contains(logs, "method=ValidatePoWork") {
+		t.Fatalf("Rejected proof was not logged:\n%s", logs)
+	}
+
+	worker.SetLogger(nil)
+	buf.Reset()
+	worker.DoProofFor([]byte("a message"))
+	if buf.Len() != 0 {
+		t.Fatalf("Worker logged after SetLogger(nil):\n%s", buf.String())
+	}
+}
+
+func TestLoggerTimeout(t *testing.T) {
+	var buf bytes.Buffer
+	worker := NewWorker()
+	worker.SetDifficulty(40)
+	worker.SetProgressInterval(1 << 10)
+	worker.SetTimeoutDuration(20 * time.Millisecond)
+	worker.SetLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	worker.DoProofForContext(context.Background(), []byte("a hard proof"))
+
+	// only info and above: no start or early milestones
+	logs := buf.String()
+	if !strings.Contains(logs, "proof of work search timed out") || !strings.Contains(logs, "timeout=20ms") {
+		t.Fatalf("Timed out search was not logged:\n%s", logs)
+	}
+	if strings.Contains(logs, "search started") || strings.Contains(logs, "search in progress") {
+		t.Fatalf("Debug events were logged at info level:\n%s", logs)
+	}
+}
+
+func TestLogProgress(t *testing.T) {
+	var buf bytes.Buffer
+	worker := NewWorker()
+	worker.SetDifficulty(10)
+	worker.SetLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	calls := 0
+	progress := worker.logProgress(context.Background(), func(uint64, time.Duration) { calls++ })
+	for _, n := range []uint64{100, 256, 300, 512, 1024, 1500, 5000} {
+		progress(n, time.Second)
+	}
+
+	if calls != 7 {
+		t.Fatalf("Wrapped progress callback was called %d times, expected 7\n", calls)
+	}
+
+	// milestones at 256, 512, 1024, then 5000 passes both 2048 and 4096 at once
+	if n := strings.Count(buf.String(), "search in progress"); n != 4 {
+		t.Fatalf("Logged %d milestones, expected 4:\n%s", n, buf.String())
+	}
+	if !strings.Contains(buf.String(), "expected_work=4.88") {
+		t.Fatalf("Milestone logs lack the share of the expected work:\n%s", buf.String())
+	}
+}
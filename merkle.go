@@ -0,0 +1,154 @@
+package powork
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+)
+
+// Merkle trees are built as in RFC 6962, with SHA-256 and distinct prefixes for leaves and
+// interior nodes, so that a leaf can't pass for a subtree
+const (
+	merkleLeafPrefix = 0
+	merkleNodePrefix = 1
+)
+
+// An InclusionProof shows that a message is the Index'th of the Size messages under a
+// Merkle root
+type InclusionProof struct {
+	Index int
+	Size  int
+	Path  [][]byte
+}
+
+// MerkleRoot returns the root of the Merkle tree over msgs, or nil if there are none
+func MerkleRoot(msgs [][]byte) []byte {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	levels := merkleLevels(msgs)
+	return levels[len(levels)-1][0]
+}
+
+func merkleLeaf(msg []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{merkleLeafPrefix})
+	h.Write(msg)
+	return h.Sum(nil)
+}
+
+func merkleNode(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{merkleNodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// merkleLevels returns the levels of the Merkle tree over msgs, from the leaf hashes up to
+// the root. Each level pairs up the nodes of the one below, and a last node without a partner
+// is carried up as it is, which builds the same tree as the recursive split of RFC 6962.
+func merkleLevels(msgs [][]byte) [][][]byte {
+	level := make([][]byte, len(msgs))
+	for i, msg := range msgs {
+		level[i] = merkleLeaf(msg)
+	}
+
+	levels := [][][]byte{level}
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i+1 < len(level); i += 2 {
+			next = append(next, merkleNode(level[i], level[i+1]))
+		}
+		if len(level)%2 == 1 {
+			next = append(next, level[len(level)-1])
+		}
+		levels = append(levels, next)
+		level = next
+	}
+	return levels
+}
+
+// merklePath returns the hashes needed to get from leaf m to the root of the tree with the
+// given levels, from the bottom up
+func merklePath(m int, levels [][][]byte) [][]byte {
+	var path [][]byte
+	for _, level := range levels[:len(levels)-1] {
+		// a node carried up has no sibling on its level
+		if sibling := m ^ 1; sibling < len(level) {
+			path = append(path, level[sibling])
+		}
+		m >>= 1
+	}
+	return path
+}
+
+// Verify reports whether the proof shows that msg is under root
+func (ip *InclusionProof) Verify(msg, root []byte) bool {
+	if ip.Index < 0 || ip.Index >= ip.Size {
+		return false
+	}
+
+	// RFC 9162, section 2.1.3.2
+	fn, sn := ip.Index, ip.Size-1
+	r := merkleLeaf(msg)
+	for _, p := range ip.Path {
+		if sn == 0 {
+			return false
+		}
+
+		if fn&1 == 1 || fn == sn {
+			r = merkleNode(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = merkleNode(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+
+	return sn == 0 && bytes.Equal(r, root)
+}
+
+// DoProofForMerkle calculates a single proof of work for many messages: the proof is for the
+// root of a Merkle tree over the messages, and each message gets an inclusion proof tying it
+// to the root. This costs one proof instead of len(msgs) while every message can still be
+// checked on its own with ValidateInclusion.
+func (p *Worker) DoProofForMerkle(msgs [][]byte) (*PoWork, []*InclusionProof, error) {
+	return p.DoProofForMerkleContext(context.Background(), msgs)
+}
+
+// DoProofForMerkleContext does the same thing as DoProofForMerkle except carrying a context
+func (p *Worker) DoProofForMerkleContext(ctx context.Context, msgs [][]byte) (*PoWork, []*InclusionProof, error) {
+	if len(msgs) == 0 {
+		return nil, nil, fmt.Errorf("%w: no messages to prove", ErrInvalidSetting)
+	}
+
+	levels := merkleLevels(msgs)
+	pow, err := p.DoProofForContext(ctx, levels[len(levels)-1][0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	proofs := make([]*InclusionProof, len(msgs))
+	for i := range msgs {
+		proofs[i] = &InclusionProof{Index: i, Size: len(msgs), Path: merklePath(i, levels)}
+	}
+	return pow, proofs, nil
+}
+
+// ValidateInclusion checks that msg is covered by a proof of work from DoProofForMerkle: ip
+// must tie msg to the Merkle root the proof is for, and the proof must be valid as by
+// ValidatePoWork
+func (p *Worker) ValidateInclusion(pow *PoWork, msg []byte, ip *InclusionProof) (bool, error) {
+	if !ip.Verify(msg, pow.msg) {
+		return false, nil
+	}
+
+	return p.ValidatePoWork(pow)
+}
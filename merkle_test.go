@@ -0,0 +1,95 @@
+package powork
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestMerkleRoot(t *testing.T) {
+	a, b, c := []byte("a"), []byte("b"), []byte("c")
+
+	expected := merkleNode(merkleNode(merkleLeaf(a), merkleLeaf(b)), merkleLeaf(c))
+	if root := MerkleRoot([][]byte{a, b, c}); !bytes.Equal(root, expected) {
+		t.Fatalf("Root of three leaves is %x, expected %x\n", root, expected)
+	}
+
+	if root := MerkleRoot([][]byte{a}); !bytes.Equal(root, merkleLeaf(a)) {
+		t.Fatalf("Root of one leaf is not the leaf hash\n")
+	}
+
+	if MerkleRoot(nil) != nil {
+		t.Fatalf("Root of no leaves is not nil\n")
+	}
+}
+
+// rfc6962Root computes the root over msgs by the recursive definition of RFC 6962
+func rfc6962Root(msgs [][]byte) []byte {
+	if len(msgs) == 1 {
+		return merkleLeaf(msgs[0])
+	}
+
+	k := 1
+	for k<<1 < len(msgs) {
+		k <<= 1
+	}
+	return merkleNode(rfc6962Root(msgs[:k]), rfc6962Root(msgs[k:]))
+}
+
+func TestInclusionProofs(t *testing.T) {
+	for size := 1; size <= 17; size++ {
+		msgs := make([][]byte, size)
+		for i := range msgs {
+			msgs[i] = []byte(fmt.Sprintf("message %d", i))
+		}
+		root := MerkleRoot(msgs)
+		if !bytes.Equal(root, rfc6962Root(msgs)) {
+			t.Fatalf("Root of %d leaves differs from RFC 6962's\n", size)
+		}
+
+		levels := merkleLevels(msgs)
+		for i := range msgs {
+			ip := &InclusionProof{Index: i, Size: size, Path: merklePath(i, levels)}
+			if !ip.Verify(msgs[i], root) {
+				t.Fatalf("Message %d of %d did not verify\n", i, size)
+			}
+
+			if ip.Verify([]byte("another message"), root) {
+				t.Fatalf("Another message verified as %d of %d\n", i, size)
+			}
+
+			if size > 1 {
+				moved := *ip
+				moved.Index = (i + 1) % size
+				if moved.Verify(msgs[i], root) {
+					t.Fatalf("Message %d of %d verified at index %d\n", i, size, moved.Index)
+				}
+			}
+		}
+	}
+}
+
+func TestDoProofForMerkle(t *testing.T) {
+	worker := NewWorker()
+	msgs := [][]byte{[]byte("first mail"), []byte("second mail"), []byte("third mail")}
+
+	pow, proofs, err := worker.DoProofForMerkle(msgs)
+	if err != nil {
+		t.Fatalf("Could not compute proof: %v\n", err)
+	}
+
+	for i, msg := range msgs {
+		if ok, err := worker.ValidateInclusion(pow, msg, proofs[i]); err != nil || !ok {
+			t.Fatalf("Message %d did not validate: %v, %v\n", i, ok, err)
+		}
+	}
+
+	if ok, _ := worker.ValidateInclusion(pow, []byte("fourth mail"), proofs[0]); ok {
+		t.Fatalf("A message outside the tree validated\n")
+	}
+
+	if _, _, err := worker.DoProofForMerkle(nil); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Expected ErrInvalidSetting for no messages, got %v\n", err)
+	}
+}
@@ -0,0 +1,70 @@
+package powork
+
+import (
+	"encoding"
+	"hash"
+)
+
+// midstate computes the hashes of a search's candidates without rehashing what they have in
+// common. Everything in the preimage but the nonce stays the same for the whole search, so
+// for hashes that can export their state, like those of the standard library, the state after
+// writing it is saved once and restored for every nonce. Other hashes rehash the whole
+// preimage.
+//
+// A midstate reuses its buffers, so hashing a candidate allocates nothing for the hashes of
+// the standard library. The sum it returns is only valid until the next call.
+type midstate struct {
+	h     hash.Hash
+	u     encoding.BinaryUnmarshaler
+	state []byte
+
+	nonce [MaxNonceLength]byte
+	out   []byte
+}
+
+// newMidstate prepares to hash candidates for pow with h
+func newMidstate(h hash.Hash, pow *PoWork) *midstate {
+	m := &midstate{h: h, out: make([]byte, 0, h.Size())}
+
+	marshaler, ok := h.(encoding.BinaryMarshaler)
+	if !ok {
+		return m
+	}
+	u, ok := h.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return m
+	}
+
+	h.Reset()
+	if err := pow.writePrefix(h); err != nil {
+		return m
+	}
+
+	state, err := marshaler.MarshalBinary()
+	if err != nil {
+		return m
+	}
+
+	m.u, m.state = u, state
+	return m
+}
+
+// sum computes the hash of pow, which must differ from the proof the midstate was prepared
+// with only in its nonce
+func (m *midstate) sum(pow *PoWork) ([]byte, error) {
+	if m.state == nil {
+		m.h.Reset()
+		if err := pow.writePrefix(m.h); err != nil {
+			return nil, err
+		}
+	} else if err := m.u.UnmarshalBinary(m.state); err != nil {
+		return nil, err
+	}
+
+	if _, err := m.h.Write(pow.appendNonce(m.nonce[:0])); err != nil {
+		return nil, err
+	}
+
+	m.out = m.h.Sum(m.out[:0])
+	return m.out, nil
+}
@@ -0,0 +1,78 @@
+package powork
+
+import (
+	"crypto/sha256"
+	"hash"
+	"strings"
+	"testing"
+)
+
+// opaqueHash hides whether the hash it wraps can export its state
+type opaqueHash struct{ hash.Hash }
+
+func TestMidstate(t *testing.T) {
+	msg := []byte(strings.Repeat("a long message ", 1000))
+
+	fast := NewWorkerWithHashFunc(sha256.New)
+	slow := NewWorkerWithHashFunc(func() hash.Hash { return opaqueHash{sha256.New()} })
+	for _, w := range []*Worker{fast, slow} {
+		w.SetDifficulty(12)
+		w.SetNonceLength(12)
+	}
+
+	if newMidstate(sha256.New(), &PoWork{msg: msg}).state == nil {
+		t.Fatalf("No midstate was saved for SHA-256\n")
+	}
+	if newMidstate(opaqueHash{sha256.New()}, &PoWork{msg: msg}).state != nil {
+		t.Fatalf("A midstate was saved for a hash that can't export its state\n")
+	}
+
+	ext := map[string]string{"to": "someone"}
+	a, err := fast.DoProofForExtensions(msg, ext)
+	if err != nil {
+		t.Fatalf("Error doing proof with a midstate: %v\n", err)
+	}
+	b, err := slow.DoProofForExtensions(msg, ext)
+	if err != nil {
+		t.Fatalf("Error doing proof without a midstate: %v\n", err)
+	}
+
+	if a.GetProof() != b.GetProof() {
+		t.Fatalf("Searches with and without a midstate found nonces %d and %d\n", a.GetProof(), b.GetProof())
+	}
+
+	if ok, err := slow.ValidatePoWork(a); !ok || err != nil {
+		t.Fatalf("Proof found with a midstate did not validate: %v\n", err)
+	}
+}
+
+func TestMidstateAllocs(t *testing.T) {
+	for _, w := range []*Worker{NewWorker(), NewWorkerWithHashFunc(sha256.New)} {
+		w.SetNonceLength(12)
+		pow := w.newBase([]byte("a message"))
+		ms := newMidstate(w.getHash(), pow)
+
+		allocs := testing.AllocsPerRun(100, func() {
+			pow.proof++
+			sum, _ := ms.sum(pow)
+			w.validSum(sum)
+		})
+		if allocs != 0 {
+			t.Fatalf("Hashing a candidate with %T allocated %v times, expected 0\n", ms.h, allocs)
+		}
+	}
+}
+
+func BenchmarkLargeMessage(b *testing.B) {
+	worker := NewWorker()
+	msg := []byte(strings.Repeat("a large message ", 4096))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := worker.DoProofFor(msg); err != nil {
+			b.Fatalf("Error doing proof: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// Package mobile exposes the solver to iOS and Android apps through gomobile:
+//
+//	gomobile bind -target=android github.com/Zumium/powork/mobile
+//	gomobile bind -target=ios github.com/Zumium/powork/mobile
+//
+// Its types only use what gomobile can bind: strings, byte slices, integers, errors and
+// interfaces the app implements. A Solver answers the challenges of the powhttp middleware,
+// taking the X-PoWork-Challenge and X-PoWork-Difficulty header values of a 428 response and
+// returning the X-PoWork header value for the retried request.
+//
+// Solve blocks until the proof is found, so call it off the main thread. Cancel, from any
+// thread, stops it.
+package mobile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powhttp"
+)
+
+// ErrCanceled is returned by Solve when Cancel stops it
+var ErrCanceled = errors.New("Search canceled")
+
+// ProgressListener is implemented by the app to follow a search
+type ProgressListener interface {
+	// OnProgress is called with the number of hashes tried so far and the milliseconds since
+	// the search started. It is called on the solving thread and should return quickly.
+	OnProgress(iterations int64, elapsedMillis int64)
+}
+
+// Solver computes proofs of work. Its methods are safe to call from any thread; it runs one
+// search at a time.
+type Solver struct {
+	mu            sync.Mutex
+	concurrency   int
+	maxDifficulty int
+	interval      int64
+	listener      ProgressListener
+	cancel        context.CancelFunc
+
+	solveMu sync.Mutex
+}
+
+// NewSolver creates a Solver that uses every core and solves challenges of any difficulty
+func NewSolver() *Solver {
+	return &Solver{}
+}
+
+// SetConcurrency sets how many threads a search uses, or every core if n is 0. Fewer threads
+// save battery and keep the device cooler.
+func (s *Solver) SetConcurrency(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.concurrency = n
+}
+
+// SetMaxDifficulty sets the highest difficulty the Solver agrees to solve, or no limit if n
+// is 0. Servers asking for more are refused with an error instead of draining the battery.
+func (s *Solver) SetMaxDifficulty(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxDifficulty = n
+}
+
+// SetListener sets the listener told about the progress of searches, every interval hashes,
+// or powork.DefaultProgressInterval if interval is 0. Passing nil removes it.
+func (s *Solver) SetListener(l ProgressListener, interval int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listener = l
+	s.interval = interval
+}
+
+// Solve computes the X-PoWork header value that answers an X-PoWork-Challenge header value
+// at the given difficulty
+func (s *Solver) Solve(challenge string, difficulty int) (string, error) {
+	c, err := powhttp.DecodeChallenge(challenge)
+	if err != nil {
+		return "", err
+	}
+
+	pow, err := s.solve(difficulty, func(ctx context.Context, w *powork.Worker) (*powork.PoWork, error) {
+		return w.DoProofForChallengeContext(ctx, c, nil)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return powhttp.EncodeProof(pow)
+}
+
+// SolveMessage computes a proof of work for msg at the given difficulty, for servers that
+// bind proofs to messages rather than challenges. It returns the proof in its binary encoding.
+func (s *Solver) SolveMessage(msg []byte, difficulty int) ([]byte, error) {
+	pow, err := s.solve(difficulty, func(ctx context.Context, w *powork.Worker) (*powork.PoWork, error) {
+		return w.DoProofForContext(ctx, msg)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pow.MarshalBinary()
+}
+
+// Cancel stops the search in progress, if any, which then returns ErrCanceled
+func (s *Solver) Cancel() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
+
+// solve sets up a Worker at difficulty and runs search with it
+func (s *Solver) solve(difficulty int, search func(context.Context, *powork.Worker) (*powork.PoWork, error)) (*powork.PoWork, error) {
+	s.solveMu.Lock()
+	defer s.solveMu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s.mu.Lock()
+	s.cancel = cancel
+	concurrency, maxDifficulty, listener, interval := s.concurrency, s.maxDifficulty, s.listener, s.interval
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.cancel = nil
+		s.mu.Unlock()
+	}()
+
+	if maxDifficulty > 0 && difficulty > maxDifficulty {
+		return nil, fmt.Errorf("%w: %d is above the Solver's maximum of %d", powork.ErrInvalidDifficulty, difficulty, maxDifficulty)
+	}
+
+	w := powork.NewWorker()
+	if err := w.SetDifficulty(difficulty); err != nil {
+		return nil, err
+	}
+	w.SetTimeoutDuration(0)
+	if concurrency > 0 {
+		if err := w.SetConcurrency(concurrency); err != nil {
+			return nil, err
+		}
+	}
+	if listener != nil {
+		if interval > 0 {
+			w.SetProgressInterval(uint64(interval))
+		}
+		w.SetProgressCallback(func(iterations uint64, elapsed time.Duration) {
+			listener.OnProgress(int64(iterations), elapsed.Milliseconds())
+		})
+	}
+
+	pow, err := search(ctx, w)
+	if errors.Is(err, context.Canceled) {
+		return nil, ErrCanceled
+	}
+	return pow, err
+}
@@ -0,0 +1,94 @@
+package mobile
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powhttp"
+)
+
+type listener struct {
+	calls atomic.Int64
+}
+
+func (l *listener) OnProgress(iterations int64, elapsedMillis int64) {
+	l.calls.Add(1)
+}
+
+func TestSolve(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h := powhttp.Handler(next, powhttp.WithDifficulty(16))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	l := new(listener)
+	s := NewSolver()
+	s.SetConcurrency(2)
+	s.SetListener(l, 1000)
+	proof, err := s.Solve(rec.Header().Get(powhttp.HeaderChallenge), 16)
+	if err != nil {
+		t.Fatalf("Error solving: %v\n", err)
+	}
+	if l.calls.Load() == 0 {
+		t.Fatalf("Listener was not told about progress\n")
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(powhttp.HeaderProof, proof)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Middleware answered the solved request with %d\n", rec.Code)
+	}
+}
+
+func TestSolveMessage(t *testing.T) {
+	data, err := NewSolver().SolveMessage([]byte("from a phone"), 12)
+	if err != nil {
+		t.Fatalf("Error solving: %v\n", err)
+	}
+
+	pow := new(powork.PoWork)
+	if err := pow.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Error decoding proof: %v\n", err)
+	}
+
+	w := powork.NewWorker()
+	w.SetDifficulty(12)
+	if ok, err := w.ValidatePoWork(pow); !ok || err != nil || pow.GetMessageString() != "from a phone" {
+		t.Fatalf("Proof is not valid: %v\n", err)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	s := NewSolver()
+	s.Cancel() // nothing to cancel
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.SolveMessage([]byte("a hard proof"), 60)
+		done <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	s.Cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrCanceled) {
+			t.Fatalf("Canceled search returned %v\n", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Search was not canceled\n")
+	}
+
+	s.SetMaxDifficulty(20)
+	if _, err := s.SolveMessage([]byte("too hard"), 21); !errors.Is(err, powork.ErrInvalidDifficulty) {
+		t.Fatalf("Difficulty above the maximum did not fail with ErrInvalidDifficulty: %v\n", err)
+	}
+}
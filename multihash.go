@@ -0,0 +1,102 @@
+package powork
+
+import (
+	"bytes"
+	"hash"
+)
+
+// A MultiHash computes the hashes of several candidates of a search in one call, as SIMD
+// implementations of a hash do by hashing one candidate per vector lane. Every candidate is
+// the same prefix followed by a nonce of its own.
+type MultiHash interface {
+	// Lanes is the number of candidates hashed per call
+	Lanes() int
+	// Size is the length of the hashes
+	Size() int
+	// Reset starts hashing candidates that begin with prefix
+	Reset(prefix []byte)
+	// Sum sets sums[i] to the hash of the prefix followed by nonces[i] for every lane. The
+	// nonces of one call all have the same length, and every sums[i] has room for Size bytes.
+	Sum(nonces [][]byte, sums [][]byte)
+}
+
+// SetMultiHash makes the Worker search with hashers obtained from f, one per search
+// goroutine, instead of with its hash objects. The hashes must be those of the Worker's hash
+// function, which is still used to validate proofs. A value of nil switches back.
+func (p *Worker) SetMultiHash(f func() MultiHash) {
+	p.getMulti = f
+}
+
+// candidateHasher computes the hashes of a search's candidates, which differ only in their
+// nonces
+type candidateHasher interface {
+	sum(pow *PoWork) ([]byte, error)
+}
+
+// newCandidateHasher prepares to hash candidates for pow with h, or with a MultiHash if the
+// Worker has one. Candidates follow each other by inc within the nonce space mask.
+func (p *Worker) newCandidateHasher(h hash.Hash, pow *PoWork, inc, mask uint64) candidateHasher {
+	if p.getMulti == nil {
+		return newMidstate(h, pow)
+	}
+
+	return newMultiMidstate(p.getMulti(), pow, inc, mask)
+}
+
+// multiMidstate hashes candidates a MultiHash's worth of lanes at a time, computing the
+// hashes of the candidates that follow the one asked for ahead of time
+type multiMidstate struct {
+	mh        MultiHash
+	inc, mask uint64
+
+	proofs []uint64
+	nonces [][]byte
+	sums   [][]byte
+	next   int
+}
+
+func newMultiMidstate(mh MultiHash, pow *PoWork, inc, mask uint64) *multiMidstate {
+	var prefix bytes.Buffer
+	pow.writePrefix(&prefix)
+	mh.Reset(prefix.Bytes())
+
+	n := mh.Lanes()
+	m := &multiMidstate{
+		mh:     mh,
+		inc:    inc,
+		mask:   mask,
+		proofs: make([]uint64, n),
+		nonces: make([][]byte, n),
+		sums:   make([][]byte, n),
+		next:   n,
+	}
+	for i := range m.nonces {
+		m.nonces[i] = make([]byte, 0, MaxNonceLength)
+		m.sums[i] = make([]byte, mh.Size())
+	}
+
+	return m
+}
+
+func (m *multiMidstate) sum(pow *PoWork) ([]byte, error) {
+	if m.next == len(m.proofs) || m.proofs[m.next] != pow.proof {
+		m.fill(pow)
+	}
+
+	sum := m.sums[m.next]
+	m.next++
+	return sum, nil
+}
+
+// fill hashes pow and the candidates after it
+func (m *multiMidstate) fill(pow *PoWork) {
+	candidate := *pow
+	for i := range m.proofs {
+		m.proofs[i] = candidate.proof
+		m.nonces[i] = candidate.appendNonce(m.nonces[i][:0])
+		candidate.proof = (candidate.proof + m.inc) & m.mask
+	}
+
+	m.mh.Sum(m.nonces, m.sums)
+	m.next = 0
+}
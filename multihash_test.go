@@ -0,0 +1,63 @@
+package powork
+
+import (
+	"crypto/sha256"
+	"testing"
+)
+
+// laneHash is a MultiHash that hashes its lanes one after the other with SHA-256
+type laneHash struct {
+	prefix []byte
+	calls  int
+}
+
+func (l *laneHash) Lanes() int { return 3 }
+
+func (l *laneHash) Size() int { return sha256.Size }
+
+func (l *laneHash) Reset(prefix []byte) { l.prefix = append([]byte(nil), prefix...) }
+
+func (l *laneHash) Sum(nonces [][]byte, sums [][]byte) {
+	l.calls++
+	for i := range nonces {
+		sum := sha256.Sum256(append(append([]byte(nil), l.prefix...), nonces[i]...))
+		copy(sums[i], sum[:])
+	}
+}
+
+func TestMultiHash(t *testing.T) {
+	plain := NewWorkerWithHashFunc(sha256.New)
+	multi := NewWorkerWithHashFunc(sha256.New)
+	var lanes *laneHash
+	multi.SetMultiHash(func() MultiHash {
+		lanes = new(laneHash)
+		return lanes
+	})
+
+	for _, w := range []*Worker{plain, multi} {
+		w.SetDifficulty(12)
+		w.SetNonceStride(7)
+		w.SetNonceBits(20)
+		w.SetStartNonce(1<<20 - 100)
+		w.SetNonceLength(3)
+	}
+
+	msg := []byte("a message")
+	a, err := multi.DoProofForExtensions(msg, map[string]string{"to": "someone"})
+	if err != nil {
+		t.Fatalf("Error doing proof with a MultiHash: %v\n", err)
+	}
+	b, _ := plain.DoProofForExtensions(msg, map[string]string{"to": "someone"})
+
+	if a.GetProof() != b.GetProof() || a.GetIterations() != b.GetIterations() {
+		t.Fatalf("Found nonce %d after %d iterations, expected %d after %d\n",
+			a.GetProof(), a.GetIterations(), b.GetProof(), b.GetIterations())
+	}
+	if expected := a.GetIterations()/3 + 1; lanes.calls != expected {
+		t.Fatalf("MultiHash was called %d times for %d iterations, expected %d\n", lanes.calls, a.GetIterations(), expected)
+	}
+
+	if ok, err := plain.ValidatePoWork(a); !ok || err != nil {
+		t.Fatalf("Proof found with a MultiHash did not validate: %v\n", err)
+	}
+}
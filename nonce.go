@@ -0,0 +1,211 @@
+package powork
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// MaxNonceLength is the longest byte nonce a proof may have
+const MaxNonceLength = 64
+
+// counterLen is the number of nonce bytes taken by the counter a search increments
+const counterLen = 8
+
+// SetRandomStart sets whether searches start from a random nonce instead of 0. Provers that
+// compute proofs for the same message then try different nonces and find different proofs,
+// rather than all repeating the same search. The random nonce comes from crypto/rand.
+func (p *Worker) SetRandomStart(enabled bool) {
+	p.randomStart = enabled
+}
+
+// SetStartNonce sets the nonce searches start from when they don't start from a random one.
+// The default is 0.
+func (p *Worker) SetStartNonce(n uint64) {
+	p.startAt = n
+}
+
+// SetNonceStride sets the distance between the nonces a search tries. Together with a
+// starting nonce it lets provers split the nonce space between them: with stride n, the
+// searches starting at 0 through n-1 never try the same nonce. The default is 1.
+func (p *Worker) SetNonceStride(stride uint64) error {
+	if stride == 0 {
+		return fmt.Errorf("%w: nonce stride must be at least 1", ErrInvalidSetting)
+	}
+
+	p.stride = stride
+	return nil
+}
+
+// SetNonceBits limits nonces to the given number of bits. A search wraps around at the end
+// of the nonce space, and fails with ErrMaxIterations once it has tried every nonce in it.
+// Nonces are still hashed at the Worker's nonce length, 8 bytes unless SetNonceLength says
+// otherwise. The default is 64.
+func (p *Worker) SetNonceBits(bits int) error {
+	if bits < 1 || bits > 64 {
+		return fmt.Errorf("%w: nonce bits must be between 1 and 64", ErrInvalidSetting)
+	}
+
+	p.nonceBits = bits
+	return nil
+}
+
+// SetNonceLength makes the Worker's proofs carry a nonce of n bytes instead of the usual 8
+// byte integer. The first bytes hold the search counter, little-endian, so nonces shorter
+// than 8 bytes limit the nonce space as SetNonceBits does. Any bytes after the eighth are
+// random if the Worker starts searches at random and zero otherwise, giving each search a
+// nonce space of its own. A value of 0, the default, switches back to 8 byte integers.
+func (p *Worker) SetNonceLength(n int) error {
+	if n < 0 || n > MaxNonceLength {
+		return fmt.Errorf("%w: nonce length must be between 0 and %d bytes", ErrInvalidSetting, MaxNonceLength)
+	}
+
+	p.nonceLen = n
+	return nil
+}
+
+// GetNonce gets the nonce as it is hashed: the proof as an 8 byte integer in the proof's
+// byte order, or the proof's byte nonce if it has one
+func (p *PoWork) GetNonce() []byte {
+	return p.appendNonce(make([]byte, 0, counterLen+len(p.extraNonce)))
+}
+
+// appendNonce appends the nonce as it is hashed to b
+func (p *PoWork) appendNonce(b []byte) []byte {
+	n := len(b)
+	if p.bigEndian {
+		b = binary.BigEndian.AppendUint64(b, p.proof)
+	} else {
+		b = binary.LittleEndian.AppendUint64(b, p.proof)
+	}
+
+	if p.nonceLen == 0 {
+		return b
+	}
+
+	if p.nonceLen <= counterLen {
+		if p.bigEndian {
+			// keep the low bytes of the counter, which come last
+			copy(b[n:], b[len(b)-p.nonceLen:])
+		}
+		return b[:n+p.nonceLen]
+	}
+	return append(b, p.extraNonce...)
+}
+
+// setNonce sets a byte nonce, splitting it into the counter and the bytes after it
+func (p *PoWork) setNonce(nonce []byte) error {
+	if len(nonce) == 0 || len(nonce) > MaxNonceLength {
+		return fmt.Errorf("%w: nonce must be between 1 and %d bytes", ErrInvalidEncoding, MaxNonceLength)
+	}
+
+	var counter [counterLen]byte
+	n := min(len(nonce), counterLen)
+	if p.bigEndian {
+		copy(counter[counterLen-n:], nonce)
+		p.proof = binary.BigEndian.Uint64(counter[:])
+	} else {
+		copy(counter[:], nonce)
+		p.proof = binary.LittleEndian.Uint64(counter[:])
+	}
+	p.nonceLen = len(nonce)
+	p.extraNonce = nil
+	if len(nonce) > n {
+		p.extraNonce = append([]byte(nil), nonce[n:]...)
+	}
+	return nil
+}
+
+// bigEndianSuffix marks the algorithm identifier of proofs whose nonce is hashed big-endian
+const bigEndianSuffix = "/be"
+
+// SetNonceByteOrder sets the byte order the nonce counter is hashed in, binary.LittleEndian
+// by default or binary.BigEndian. Big-endian proofs declare their algorithm with a "/be"
+// suffix in their encodings, "sha256/be" for example, and are only valid for Workers hashing
+// in the same order. Other byte orders fail with ErrInvalidSetting.
+func (p *Worker) SetNonceByteOrder(order binary.ByteOrder) error {
+	switch order {
+	case binary.LittleEndian:
+		p.bigEndian = false
+	case binary.BigEndian:
+		p.bigEndian = true
+	default:
+		return fmt.Errorf("%w: nonce byte order must be little-endian or big-endian", ErrInvalidSetting)
+	}
+	return nil
+}
+
+// GetNonceByteOrder gets the byte order the Worker hashes nonces in
+func (p *Worker) GetNonceByteOrder() binary.ByteOrder {
+	if p.bigEndian {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// GetNonceByteOrder gets the byte order the proof's nonce is hashed in
+func (p *PoWork) GetNonceByteOrder() binary.ByteOrder {
+	if p.bigEndian {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// algorithmID returns the identifier the proof declares its algorithm with in its encodings:
+// the algorithm's name, suffixed if the nonce is big-endian
+func (p *PoWork) algorithmID() string {
+	if p.bigEndian {
+		return p.algorithm + bigEndianSuffix
+	}
+	return p.algorithm
+}
+
+// setAlgorithmID sets the algorithm and nonce byte order from an identifier produced by
+// algorithmID
+func (p *PoWork) setAlgorithmID(id string) {
+	p.algorithm, p.bigEndian = strings.CutSuffix(id, bigEndianSuffix)
+}
+
+// nonceStride returns the distance between the nonces a search tries
+func (p *Worker) nonceStride() uint64 {
+	if p.stride == 0 {
+		return 1
+	}
+	return p.stride
+}
+
+// nonceMask returns the mask of the bits the search counter may have set
+func (p *Worker) nonceMask() uint64 {
+	bits := p.nonceBits
+	if bits == 0 {
+		bits = 64
+	}
+	if p.nonceLen > 0 && p.nonceLen < counterLen && 8*p.nonceLen < bits {
+		bits = 8 * p.nonceLen
+	}
+
+	if bits == 64 {
+		return ^uint64(0)
+	}
+	return 1<<uint(bits) - 1
+}
+
+// setStartNonce sets the nonce a new search for base starts from
+func (p *Worker) setStartNonce(base *PoWork) {
+	base.nonceLen = p.nonceLen
+	if p.nonceLen > counterLen {
+		base.extraNonce = make([]byte, p.nonceLen-counterLen)
+	}
+
+	if !p.randomStart {
+		base.proof = p.startAt & p.nonceMask()
+		return
+	}
+
+	var b [counterLen]byte
+	// crypto/rand.Read does not fail
+	rand.Read(b[:])
+	rand.Read(base.extraNonce)
+	base.proof = binary.LittleEndian.Uint64(b[:]) & p.nonceMask()
+}
@@ -0,0 +1,238 @@
+package powork
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRandomStart(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(8)
+	worker.SetRandomStart(true)
+
+	msg := []byte("the same message")
+	a, err := worker.DoProofFor(msg)
+	if err != nil {
+		t.Fatalf("Error doing proof: %v\n", err)
+	}
+	b, _ := worker.DoProofFor(msg)
+
+	// two searches starting at random over 2^64 nonces do not meet
+	if a.GetProof() == b.GetProof() {
+		t.Fatalf("Two random searches found the same nonce %d\n", a.GetProof())
+	}
+
+	for _, pow := range []*PoWork{a, b} {
+		if ok, err := worker.ValidatePoWork(pow); !ok || err != nil {
+			t.Fatalf("Proof from a random start did not validate: %v\n", err)
+		}
+	}
+}
+
+func TestNonceStride(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(8)
+	worker.SetTimeoutDuration(0)
+	if err := worker.SetNonceStride(3); err != nil {
+		t.Fatalf("Error setting stride: %v\n", err)
+	}
+
+	msg := []byte("a message split between provers")
+	for start := uint64(0); start < 3; start++ {
+		worker.SetStartNonce(start)
+		for _, n := range []int{1, 4} {
+			worker.SetConcurrency(n)
+			pow, err := worker.DoProofFor(msg)
+			if err != nil {
+				t.Fatalf("Error doing proof: %v\n", err)
+			}
+
+			if pow.GetProof()%3 != start {
+				t.Fatalf("Prover %d of 3 with %d goroutines found nonce %d\n", start, n, pow.GetProof())
+			}
+		}
+	}
+
+	if err := worker.SetNonceStride(0); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Zero stride did not fail with ErrInvalidSetting: %v\n", err)
+	}
+}
+
+func TestNonceBits(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(30)
+	worker.SetTimeoutDuration(0)
+	if err := worker.SetNonceBits(6); err != nil {
+		t.Fatalf("Error setting nonce bits: %v\n", err)
+	}
+
+	// 64 nonces are very unlikely to hold a 30 bit proof, wherever the search starts
+	worker.SetRandomStart(true)
+	for _, n := range []int{1, 4} {
+		worker.SetConcurrency(n)
+		pow, err := worker.DoProofFor([]byte("a small nonce space"))
+		if !errors.Is(err, ErrMaxIterations) {
+			t.Fatalf("Search of 64 nonces with %d goroutines returned %v, %v\n", n, pow, err)
+		}
+	}
+
+	// and an easy one is found within them
+	worker.SetDifficulty(2)
+	pow, err := worker.DoProofFor([]byte("a small nonce space"))
+	if err != nil || pow.GetProof() >= 64 {
+		t.Fatalf("Search of 64 nonces returned %v, %v\n", pow, err)
+	}
+
+	if err := worker.SetNonceBits(65); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("65 nonce bits did not fail with ErrInvalidSetting: %v\n", err)
+	}
+}
+
+func TestByteNonces(t *testing.T) {
+	for _, n := range []int{3, 8, 20} {
+		worker := NewWorker()
+		worker.SetDifficulty(8)
+		worker.SetRandomStart(true)
+		if err := worker.SetNonceLength(n); err != nil {
+			t.Fatalf("Error setting nonce length: %v\n", err)
+		}
+
+		pow, err := worker.DoProofFor([]byte("a message with a byte nonce"))
+		if err != nil {
+			t.Fatalf("Error doing proof with a %d byte nonce: %v\n", n, err)
+		}
+
+		if len(pow.GetNonce()) != n {
+			t.Fatalf("Proof has a %d byte nonce, expected %d\n", len(pow.GetNonce()), n)
+		}
+
+		if ok, err := worker.ValidatePoWork(pow); !ok || err != nil {
+			t.Fatalf("Proof with a %d byte nonce did not validate: %v\n", n, err)
+		}
+
+		data, err := pow.MarshalBinary()
+		if err != nil {
+			t.Fatalf("Error encoding proof: %v\n", err)
+		}
+		decoded := new(PoWork)
+		if err := decoded.UnmarshalBinary(data); err != nil {
+			t.Fatalf("Error decoding proof: %v\n", err)
+		}
+		if !bytes.Equal(decoded.GetNonce(), pow.GetNonce()) {
+			t.Fatalf("Decoded nonce is %x, expected %x\n", decoded.GetNonce(), pow.GetNonce())
+		}
+
+		js, _ := pow.MarshalJSON()
+		fromJSON := new(PoWork)
+		if err := fromJSON.UnmarshalJSON(js); err != nil || !bytes.Equal(fromJSON.GetNonce(), pow.GetNonce()) {
+			t.Fatalf("Nonce decoded from JSON is %x, expected %x: %v\n", fromJSON.GetNonce(), pow.GetNonce(), err)
+		}
+
+		// the nonce bytes after the counter are hashed too
+		if n > 8 {
+			tampered := *decoded
+			tampered.extraNonce = append([]byte(nil), decoded.extraNonce...)
+			tampered.extraNonce[0] ^= 1
+			if bytes.Equal(tampered.Hash(), decoded.Hash()) {
+				t.Fatalf("Changing the nonce did not change the proof's hash\n")
+			}
+		}
+	}
+
+	// an 8 byte integer nonce hashes as it always has
+	pow, _ := NewWorker().DoProofFor([]byte("a plain proof"))
+	if len(pow.GetNonce()) != 8 || binary.LittleEndian.Uint64(pow.GetNonce()) != pow.GetProof() {
+		t.Fatalf("Integer nonce is %x\n", pow.GetNonce())
+	}
+
+	if err := NewWorker().SetNonceLength(MaxNonceLength + 1); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Nonce longer than the maximum did not fail with ErrInvalidSetting: %v\n", err)
+	}
+
+	long := new(PoWork)
+	if err := long.setNonce(make([]byte, MaxNonceLength+1)); !errors.Is(err, ErrInvalidEncoding) {
+		t.Fatalf("Decoding a nonce longer than the maximum did not fail with ErrInvalidEncoding: %v\n", err)
+	}
+}
+
+func TestShortNonceLimitsSpace(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(30)
+	worker.SetTimeoutDuration(0)
+	worker.SetNonceLength(1)
+
+	// 256 one byte nonces are very unlikely to hold a 30 bit proof
+	if _, err := worker.DoProofFor([]byte("a tiny nonce space")); !errors.Is(err, ErrMaxIterations) {
+		t.Fatalf("Search of one byte nonces returned %v\n", err)
+	}
+}
+
+func TestNonceByteOrder(t *testing.T) {
+	vectors := []struct {
+		order binary.ByteOrder
+		nonce uint64
+		sum   string
+	}{
+		{binary.LittleEndian, 1, "e3e66e352f0cf321602f2d94aa0d7680706d7a30b5d582a77653d16c0bb0dc74"},
+		{binary.BigEndian, 1, "e3d9a79505ad20a935cdf1068aa144be98ffe9a9468fab37bc2faaa3ad61e259"},
+		{binary.LittleEndian, 0x0102030405060708, "6661e1164139cbd7dce9b0b6745b7233cf89c4e91f0dcbd365ce2b0140d9bd1a"},
+		{binary.BigEndian, 0x0102030405060708, "811059041f842f526f60db586fb11386f317e663d0853dbda3023fd104df12bd"},
+	}
+
+	for _, v := range vectors {
+		pow := &PoWork{msg: []byte("hello"), proof: v.nonce, bigEndian: v.order == binary.BigEndian}
+		sum, _ := pow.sum(sha256.New())
+		if hex.EncodeToString(sum) != v.sum {
+			t.Fatalf("SHA-256 of \"hello\" and %s nonce %#x is %x, expected %s\n", v.order, v.nonce, sum, v.sum)
+		}
+	}
+
+	worker, _ := NewWorkerForAlgorithm("sha256")
+	worker.SetNonceByteOrder(binary.BigEndian)
+	worker.SetNonceLength(5)
+	pow, err := worker.DoProofFor([]byte("a big-endian proof"))
+	if err != nil {
+		t.Fatalf("Error doing big-endian proof: %v\n", err)
+	}
+
+	// the low bytes of the counter, most significant first
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], pow.GetProof())
+	if !bytes.Equal(pow.GetNonce(), counter[3:]) || pow.GetNonceByteOrder() != binary.BigEndian {
+		t.Fatalf("Big-endian byte nonce is %x for counter %d\n", pow.GetNonce(), pow.GetProof())
+	}
+
+	data, _ := pow.MarshalBinary()
+	decoded := new(PoWork)
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Error decoding big-endian proof: %v\n", err)
+	}
+	if decoded.GetProof() != pow.GetProof() || decoded.GetAlgorithm() != "sha256" || !strings.Contains(decoded.String(), "sha256/be") {
+		t.Fatalf("Decoded big-endian proof is %v\n", decoded)
+	}
+	if ok, err := worker.ValidatePoWork(decoded); !ok || err != nil {
+		t.Fatalf("Decoded big-endian proof did not validate: %v\n", err)
+	}
+
+	data, _ = json.Marshal(pow)
+	decoded = new(PoWork)
+	if err := json.Unmarshal(data, decoded); err != nil || !bytes.Equal(decoded.GetNonce(), pow.GetNonce()) {
+		t.Fatalf("Big-endian proof did not survive JSON: %v\n", err)
+	}
+
+	// a little-endian Worker does not accept it
+	worker.SetNonceByteOrder(binary.LittleEndian)
+	if ok, _ := worker.ValidatePoWork(decoded); ok {
+		t.Fatalf("Big-endian proof validated with a little-endian Worker\n")
+	}
+
+	if err := worker.SetNonceByteOrder(nil); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Invalid byte order did not fail with ErrInvalidSetting: %v\n", err)
+	}
+}
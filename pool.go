@@ -0,0 +1,542 @@
+package powork
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"hash"
+	"sync"
+	"time"
+)
+
+// DefaultPoolFairness is how many jobs may start ahead of a waiting job of a lower class
+// before it gets a turn, unless the Pool is given another limit
+const DefaultPoolFairness = 8
+
+// A JobClass sorts the jobs of a Pool. Jobs of a higher class run before jobs of a lower
+// one, and an interactive job may preempt a running batch job.
+type JobClass int
+
+// Job classes, lowest first. The zero value is ClassNormal.
+const (
+	ClassBatch JobClass = iota - 1
+	ClassNormal
+	ClassInteractive
+)
+
+// numClasses is the number of job classes
+const numClasses = 3
+
+// index returns the position of the class in per class arrays
+func (c JobClass) index() int {
+	return int(c - ClassBatch)
+}
+
+func (c JobClass) valid() bool {
+	return c >= ClassBatch && c <= ClassInteractive
+}
+
+func (c JobClass) String() string {
+	switch c {
+	case ClassBatch:
+		return "batch"
+	case ClassNormal:
+		return "normal"
+	case ClassInteractive:
+		return "interactive"
+	}
+	return fmt.Sprintf("JobClass(%d)", int(c))
+}
+
+// A PoolJob asks a Pool for a proof of work for a message
+type PoolJob struct {
+	Msg []byte
+	// Ctx cancels the job, whether it is still queued or already running. A nil Ctx is
+	// context.Background.
+	Ctx context.Context
+	// Class sorts the job ahead of or behind the jobs of other classes. Jobs of an unknown
+	// class are batch jobs.
+	Class JobClass
+	// Priority orders the queue of the job's class: jobs of a higher priority run first,
+	// and jobs of the same priority in the order they were submitted
+	Priority int
+	// Result receives the outcome of the job. The Pool's goroutine waits until it is
+	// received, so give the channel room or read it promptly. Several jobs may share a
+	// channel. A nil Result discards the outcome.
+	Result chan<- Result
+}
+
+// QueueStats is a snapshot of the jobs of one class that went through a Pool
+type QueueStats struct {
+	// Queued is the number of jobs waiting to start
+	Queued int
+	// Started is the number of jobs that have started, not counting restarts after
+	// preemption
+	Started uint64
+	// Preempted is the number of times a running job was set aside for an interactive one
+	Preempted uint64
+	// TotalWait and MaxWait are the sum and longest of the times started jobs waited in
+	// the queue before they first started
+	TotalWait time.Duration
+	MaxWait   time.Duration
+}
+
+// AverageWait returns the average time started jobs waited in the queue
+func (s QueueStats) AverageWait() time.Duration {
+	if s.Started == 0 {
+		return 0
+	}
+	return s.TotalWait / time.Duration(s.Started)
+}
+
+// A Pool finds proofs of work for queued jobs on a fixed number of goroutines, each
+// searching for one proof at a time. Unlike SendProofToChannel it doesn't start a goroutine
+// per message, so a server stamping many outbound messages uses bounded resources. Its
+// methods are safe for concurrent use.
+//
+// Jobs are queued by class. The next job to start is the first of the highest class with
+// jobs waiting, except that once DefaultPoolFairness jobs, or the limit set with
+// SetFairness, have started ahead of a waiting job of a lower class, the job that has waited
+// longest goes next. When an interactive job arrives and no goroutine is free, a running
+// batch job is preempted: its search stops where it got to and the job is queued again in its
+// place, to be resumed from the nonce it reached.
+type Pool struct {
+	worker   *Worker
+	progress func(iterations uint64, elapsed time.Duration)
+	size     int
+
+	jobs chan PoolJob
+	quit chan struct{}
+	fed  chan struct{}
+
+	// ctx is canceled by Shutdown to stop the running jobs
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	cond       *sync.Cond
+	queues     [numClasses]jobQueue
+	seq        uint64
+	closing    bool
+	fairness   int
+	skipped    int
+	preemption bool
+	running    map[*queuedJob]context.CancelFunc
+	preempting int
+	stats      [numClasses]QueueStats
+
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewPool creates a Pool of size goroutines searching with w's settings, each with a hash
+// of its own. A size of 0 uses the Worker's concurrency. A Worker created with
+// NewWorkerWithHash has only one hash and always gets a single goroutine. The Worker must not
+// be reconfigured while the Pool is open.
+func NewPool(w *Worker, size int) *Pool {
+	if size <= 0 || w.getHash == nil {
+		size = w.searchers()
+	}
+
+	p := &Pool{
+		worker:     w,
+		progress:   w.progress,
+		size:       size,
+		jobs:       make(chan PoolJob),
+		quit:       make(chan struct{}),
+		fed:        make(chan struct{}),
+		fairness:   DefaultPoolFairness,
+		preemption: true,
+		running:    make(map[*queuedJob]context.CancelFunc),
+	}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	p.cond = sync.NewCond(&p.mu)
+
+	// progress callbacks of concurrent searches must not run concurrently
+	if p.progress != nil && size > 1 {
+		var progressMu sync.Mutex
+		p.progress = func(iterations uint64, elapsed time.Duration) {
+			progressMu.Lock()
+			defer progressMu.Unlock()
+			w.progress(iterations, elapsed)
+		}
+	}
+
+	go p.feed()
+	for i := 0; i < size; i++ {
+		h := w.hasher
+		if size > 1 {
+			h = w.getHash()
+		}
+
+		p.wg.Add(1)
+		go p.serve(h)
+	}
+
+	return p
+}
+
+// SetFairness sets how many jobs may start ahead of a waiting job of a lower class before
+// the job that has waited longest gets a turn. A limit of 0 lets lower classes wait for as
+// long as higher classes have jobs.
+func (p *Pool) SetFairness(n int) error {
+	if n < 0 {
+		return fmt.Errorf("%w: fairness cannot be negative", ErrInvalidSetting)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fairness = n
+	return nil
+}
+
+// SetPreemption sets whether interactive jobs preempt running batch jobs, which they do by
+// default
+func (p *Pool) SetPreemption(on bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.preemption = on
+}
+
+// Jobs returns a channel to submit jobs on. Once the Pool is closed the channel is no longer
+// read, so senders that may outlive the Pool should select on Done as well.
+func (p *Pool) Jobs() chan<- PoolJob {
+	return p.jobs
+}
+
+// Done returns a channel that is closed when the Pool stops accepting jobs
+func (p *Pool) Done() <-chan struct{} {
+	return p.quit
+}
+
+// Submit queues a normal job for msg and returns a channel that receives its outcome, or
+// ErrPoolClosed if the Pool no longer accepts jobs
+func (p *Pool) Submit(ctx context.Context, msg []byte, priority int) (<-chan Result, error) {
+	return p.SubmitClass(ctx, msg, ClassNormal, priority)
+}
+
+// SubmitClass does the same thing as Submit for a job of the given class
+func (p *Pool) SubmitClass(ctx context.Context, msg []byte, class JobClass, priority int) (<-chan Result, error) {
+	c := make(chan Result, 1)
+	if err := p.enqueue(PoolJob{Msg: msg, Ctx: ctx, Class: class, Priority: priority, Result: c}); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Queued returns the number of jobs waiting for a goroutine
+func (p *Pool) Queued() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.queued()
+}
+
+func (p *Pool) queued() int {
+	n := 0
+	for _, q := range p.queues {
+		n += len(q)
+	}
+	return n
+}
+
+// Stats returns a snapshot of the jobs of a class
+func (p *Pool) Stats(class JobClass) QueueStats {
+	if !class.valid() {
+		class = ClassBatch
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.stats[class.index()]
+	s.Queued = len(p.queues[class.index()])
+	return s
+}
+
+// Close stops accepting jobs and waits until every queued and running job is done
+func (p *Pool) Close() {
+	p.closeOnce.Do(func() {
+		close(p.quit)
+	})
+	<-p.fed
+
+	p.mu.Lock()
+	p.closing = true
+	p.cond.Broadcast()
+	p.mu.Unlock()
+
+	p.wg.Wait()
+}
+
+// Shutdown closes the Pool like Close. If ctx is done before the queue has drained, the jobs
+// still queued or running are canceled with context.Canceled, and Shutdown returns ctx's
+// error once their outcomes have been sent.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	closed := make(chan struct{})
+	go func() {
+		p.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		<-closed
+		return ctx.Err()
+	}
+}
+
+// feed queues the jobs received from the Jobs channel until the Pool is closed
+func (p *Pool) feed() {
+	defer close(p.fed)
+	for {
+		select {
+		case job := <-p.jobs:
+			// Close waits for feed to return before the Pool is closing, so this can't fail
+			p.enqueue(job)
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// enqueue queues a job, or returns ErrPoolClosed once the Pool is closing and its goroutines
+// may have stopped taking jobs
+func (p *Pool) enqueue(job PoolJob) error {
+	if !job.Class.valid() {
+		job.Class = ClassBatch
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closing {
+		return ErrPoolClosed
+	}
+
+	p.seq++
+	q := &p.queues[job.Class.index()]
+	heap.Push(q, &queuedJob{PoolJob: job, seq: p.seq, queued: time.Now()})
+	p.cond.Signal()
+
+	if job.Class == ClassInteractive && p.preemption {
+		p.preempt()
+	}
+	return nil
+}
+
+// preempt stops a running batch job if there are more interactive jobs waiting than
+// goroutines about to take them
+func (p *Pool) preempt() {
+	free := p.size - len(p.running) + p.preempting
+	if len(p.queues[ClassInteractive.index()]) <= free {
+		return
+	}
+
+	for job, stop := range p.running {
+		if job.Class == ClassBatch && !job.preempted {
+			job.preempted = true
+			p.preempting++
+			p.stats[ClassBatch.index()].Preempted++
+			stop()
+			return
+		}
+	}
+}
+
+// next waits for the next job to run and marks it as running with stop. It returns false
+// once the Pool is closed and the queue is empty.
+func (p *Pool) next(stop context.CancelFunc) (*queuedJob, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for p.queued() == 0 && !p.closing {
+		p.cond.Wait()
+	}
+
+	if p.queued() == 0 {
+		return nil, false
+	}
+
+	q := &p.queues[p.nextClass().index()]
+	job := heap.Pop(q).(*queuedJob)
+	if !job.started {
+		job.started = true
+		wait := time.Since(job.queued)
+		s := &p.stats[job.Class.index()]
+		s.Started++
+		s.TotalWait += wait
+		if wait > s.MaxWait {
+			s.MaxWait = wait
+		}
+	}
+
+	p.running[job] = stop
+	return job, true
+}
+
+// nextClass picks the class of the next job to start
+func (p *Pool) nextClass() JobClass {
+	highest := ClassBatch - 1
+	waiting := 0
+	for c := ClassInteractive; c >= ClassBatch; c-- {
+		if len(p.queues[c.index()]) > 0 {
+			if highest < ClassBatch {
+				highest = c
+			}
+			waiting++
+		}
+	}
+
+	if waiting == 1 {
+		p.skipped = 0
+		return highest
+	}
+
+	p.skipped++
+	if p.fairness == 0 || p.skipped <= p.fairness {
+		return highest
+	}
+
+	// the class whose next job has waited longest
+	p.skipped = 0
+	oldest := highest
+	for c := ClassBatch; c <= ClassInteractive; c++ {
+		q := p.queues[c.index()]
+		if len(q) > 0 && q[0].queued.Before(p.queues[oldest.index()][0].queued) {
+			oldest = c
+		}
+	}
+	return oldest
+}
+
+// finish marks a job as no longer running, and queues it again if it was preempted with a
+// partial search to resume. It reports whether the job was queued again.
+func (p *Pool) finish(job *queuedJob, partial *PoWork) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.running, job)
+	if !job.preempted {
+		return false
+	}
+
+	job.preempted = false
+	p.preempting--
+	if partial == nil || job.ctx().Err() != nil || p.ctx.Err() != nil {
+		return false
+	}
+
+	job.done += partial.requiredIterations
+	resume := new(PoWork)
+	*resume = *partial
+	resume.requiredIterations = 0
+	job.resume = resume
+
+	heap.Push(&p.queues[job.Class.index()], job)
+	p.cond.Signal()
+	return true
+}
+
+// serve runs jobs with h until the Pool is closed
+func (p *Pool) serve(h hash.Hash) {
+	defer p.wg.Done()
+	for {
+		ctx, cancel := context.WithCancel(p.ctx)
+		job, ok := p.next(cancel)
+		if !ok {
+			cancel()
+			return
+		}
+
+		r, partial := p.run(ctx, h, job)
+		cancel()
+		if p.finish(job, partial) {
+			continue
+		}
+
+		if job.Result != nil {
+			job.Result <- r
+		}
+	}
+}
+
+// run searches for the proof a job asks for, or resumes its search, until the search ends or
+// ctx, which the Pool cancels to preempt the job or shut down, is done
+func (p *Pool) run(ctx context.Context, h hash.Hash, job *queuedJob) (Result, *PoWork) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(job.ctx(), cancel)
+	defer stop()
+
+	if err := job.ctx().Err(); err != nil {
+		return Result{nil, err}, nil
+	}
+
+	w := p.worker
+	base := job.resume
+	if base == nil {
+		base = w.newBase(job.Msg)
+	}
+
+	st := w.newSearchState()
+	st.progress = p.progress
+	pow, partial, err := w.observeSearch(ctx, st, 1, func() (*PoWork, *PoWork, error) {
+		return w.searchOne(ctx, st, h, base)
+	})
+
+	if pow != nil {
+		pow.requiredIterations += job.done
+	}
+	// the job's own context is done, not just the Pool's
+	if err != nil && job.ctx().Err() != nil {
+		err = job.ctx().Err()
+	}
+	return Result{pow, err}, partial
+}
+
+// queuedJob is a job waiting in the queue or running
+type queuedJob struct {
+	PoolJob
+	seq    uint64
+	queued time.Time
+
+	// set by the Pool with its lock held
+	started   bool
+	preempted bool
+	// resume is the search to resume after preemption, and done the iterations it took so far
+	resume *PoWork
+	done   int
+}
+
+func (j *queuedJob) ctx() context.Context {
+	if j.Ctx == nil {
+		return context.Background()
+	}
+	return j.Ctx
+}
+
+// jobQueue is a heap of queued jobs, highest priority and then earliest submitted first
+type jobQueue []*queuedJob
+
+func (q jobQueue) Len() int { return len(q) }
+
+func (q jobQueue) Less(i, j int) bool {
+	if q[i].Priority != q[j].Priority {
+		return q[i].Priority > q[j].Priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q jobQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *jobQueue) Push(x any) { *q = append(*q, x.(*queuedJob)) }
+
+func (q *jobQueue) Pop() any {
+	old := *q
+	job := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return job
+}
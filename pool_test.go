@@ -0,0 +1,290 @@
+package powork
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(8)
+	pool := NewPool(worker, 3)
+
+	results := make(chan Result, 10)
+	for i := 0; i < 10; i++ {
+		pool.Jobs() <- PoolJob{Msg: []byte(fmt.Sprintf("message %d", i)), Result: results}
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 10; i++ {
+		r := <-results
+		if r.Err != nil {
+			t.Fatalf("Error finding proof: %v\n", r.Err)
+		}
+		if ok, err := worker.ValidatePoWork(r.Proof); !ok || err != nil {
+			t.Fatalf("Pool found an invalid proof: %v\n", err)
+		}
+		seen[string(r.Proof.GetMessage())] = true
+	}
+	if len(seen) != 10 {
+		t.Fatalf("Pool found proofs for %d messages, expected 10\n", len(seen))
+	}
+
+	pool.Close()
+	if _, err := pool.Submit(context.Background(), []byte("late"), 0); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("Submitting to a closed pool did not fail with ErrPoolClosed: %v\n", err)
+	}
+	select {
+	case <-pool.Done():
+	default:
+		t.Fatalf("Done is not closed after Close\n")
+	}
+}
+
+func TestPoolPriority(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(8)
+	// hold the only goroutine in its first search until every job is queued
+	release := make(chan struct{})
+	worker.SetProgressInterval(1)
+	worker.SetProgressCallback(func(uint64, time.Duration) { <-release })
+	pool := NewPool(worker, 1)
+	defer pool.Close()
+
+	results := make(chan Result, 6)
+	pool.Jobs() <- PoolJob{Msg: []byte("blocker"), Result: results}
+	for pool.Queued() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	for i, priority := range []int{0, 5, 1, 5, 9} {
+		pool.Jobs() <- PoolJob{Msg: []byte(fmt.Sprint(i)), Priority: priority, Result: results}
+	}
+	// the Jobs channel is read before the job is queued
+	for pool.Queued() != 5 {
+		time.Sleep(time.Millisecond)
+	}
+
+	close(release)
+	var order []string
+	for i := 0; i < 6; i++ {
+		r := <-results
+		order = append(order, string(r.Proof.GetMessage()))
+	}
+
+	// highest priority first, then in the order submitted
+	if fmt.Sprint(order) != "[blocker 4 1 3 2 0]" {
+		t.Fatalf("Jobs ran in the order %v\n", order)
+	}
+}
+
+func TestPoolCancel(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(8)
+	pool := NewPool(worker, 1)
+	defer pool.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c, _ := pool.Submit(ctx, []byte("a message"), 0)
+	if r := <-c; !errors.Is(r.Err, context.Canceled) || r.Proof != nil {
+		t.Fatalf("Canceled job returned %v\n", r.Err)
+	}
+}
+
+func TestPoolShutdown(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(40)
+	worker.SetTimeoutDuration(0)
+	pool := NewPool(worker, 2)
+
+	var results []<-chan Result
+	for i := 0; i < 4; i++ {
+		c, _ := pool.Submit(context.Background(), []byte(fmt.Sprintf("a hard proof %d", i)), 0)
+		results = append(results, c)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := pool.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown returned %v, expected context.DeadlineExceeded\n", err)
+	}
+
+	// running and queued jobs alike are canceled
+	for _, c := range results {
+		if r := <-c; !errors.Is(r.Err, context.Canceled) {
+			t.Fatalf("Job stopped by Shutdown returned %v\n", r.Err)
+		}
+	}
+}
+
+func TestPoolSubmitDuringClose(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(4)
+	pool := NewPool(worker, 2)
+
+	accepted := make(chan (<-chan Result), 1000)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < cap(accepted); i++ {
+			c, err := pool.Submit(context.Background(), []byte(fmt.Sprintf("message %d", i)), 0)
+			if err != nil {
+				return
+			}
+			accepted <- c
+		}
+	}()
+
+	time.Sleep(time.Millisecond)
+	pool.Close()
+	<-done
+	close(accepted)
+
+	// every job the pool accepted is run, however close to Close it was submitted
+	for c := range accepted {
+		select {
+		case <-c:
+		case <-time.After(time.Second):
+			t.Fatalf("Job accepted while the pool was closing was never run\n")
+		}
+	}
+}
+
+func TestPoolClasses(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(8)
+	release := make(chan struct{})
+	worker.SetProgressInterval(1)
+	worker.SetProgressCallback(func(uint64, time.Duration) { <-release })
+	pool := NewPool(worker, 1)
+	defer pool.Close()
+
+	results := make(chan Result, 6)
+	pool.Jobs() <- PoolJob{Msg: []byte("blocker"), Result: results}
+	for pool.Queued() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	jobs := []PoolJob{
+		{Msg: []byte("batch"), Class: ClassBatch, Priority: 9},
+		{Msg: []byte("normal"), Class: ClassNormal},
+		{Msg: []byte("interactive"), Class: ClassInteractive},
+		{Msg: []byte("unknown"), Class: JobClass(7)},
+	}
+	for _, job := range jobs {
+		job.Result = results
+		pool.Jobs() <- job
+	}
+	for pool.Queued() != 4 {
+		time.Sleep(time.Millisecond)
+	}
+	if s := pool.Stats(ClassBatch); s.Queued != 2 || s.Started != 0 {
+		t.Fatalf("Batch queue stats are %+v, expected 2 queued\n", s)
+	}
+
+	close(release)
+	var order []string
+	for i := 0; i < 5; i++ {
+		r := <-results
+		order = append(order, string(r.Proof.GetMessage()))
+	}
+
+	if fmt.Sprint(order) != "[blocker interactive normal batch unknown]" {
+		t.Fatalf("Jobs ran in the order %v\n", order)
+	}
+
+	s := pool.Stats(ClassBatch)
+	if s.Started != 2 || s.MaxWait <= 0 || s.AverageWait() <= 0 || s.AverageWait() > s.MaxWait {
+		t.Fatalf("Batch queue stats are %+v\n", s)
+	}
+}
+
+func TestPoolFairness(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(8)
+	release := make(chan struct{})
+	worker.SetProgressInterval(1)
+	worker.SetProgressCallback(func(uint64, time.Duration) { <-release })
+	pool := NewPool(worker, 1)
+	defer pool.Close()
+	if err := pool.SetFairness(-1); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Negative fairness did not fail with ErrInvalidSetting: %v\n", err)
+	}
+	pool.SetFairness(1)
+
+	results := make(chan Result, 6)
+	pool.Jobs() <- PoolJob{Msg: []byte("blocker"), Result: results}
+	for pool.Queued() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	pool.Jobs() <- PoolJob{Msg: []byte("batch"), Class: ClassBatch, Result: results}
+	for i := 0; i < 3; i++ {
+		pool.Jobs() <- PoolJob{Msg: []byte(fmt.Sprint(i)), Class: ClassInteractive, Result: results}
+	}
+	for pool.Queued() != 4 {
+		time.Sleep(time.Millisecond)
+	}
+
+	close(release)
+	var order []string
+	for i := 0; i < 5; i++ {
+		r := <-results
+		order = append(order, string(r.Proof.GetMessage()))
+	}
+
+	// the batch job waits for one interactive job only
+	if fmt.Sprint(order) != "[blocker 0 batch 1 2]" {
+		t.Fatalf("Jobs ran in the order %v\n", order)
+	}
+}
+
+func TestPoolPreemption(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(12)
+
+	// a batch job that takes a while, to be preempted early on
+	var msg []byte
+	var direct *PoWork
+	for i := 0; direct == nil || direct.GetIterations() < 100; i++ {
+		msg = []byte(fmt.Sprintf("batch %d", i))
+		direct, _ = worker.DoProofFor(msg)
+	}
+
+	release := make(chan struct{})
+	worker.SetProgressInterval(1)
+	worker.SetProgressCallback(func(uint64, time.Duration) { <-release })
+	pool := NewPool(worker, 1)
+	defer pool.Close()
+
+	results := make(chan Result, 2)
+	pool.Jobs() <- PoolJob{Msg: msg, Class: ClassBatch, Result: results}
+	for pool.Stats(ClassBatch).Started != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	pool.Jobs() <- PoolJob{Msg: []byte("interactive"), Class: ClassInteractive, Result: results}
+	for pool.Stats(ClassBatch).Preempted != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+
+	first, second := <-results, <-results
+	if first.Err != nil || string(first.Proof.GetMessage()) != "interactive" {
+		t.Fatalf("Interactive job did not finish first: %v\n", first.Err)
+	}
+
+	// the resumed search finds what an uninterrupted one does
+	if second.Err != nil || second.Proof.GetProof() != direct.GetProof() || second.Proof.GetIterations() != direct.GetIterations() {
+		t.Fatalf("Preempted job found nonce %d after %d iterations, expected %d after %d: %v\n",
+			second.Proof.GetProof(), second.Proof.GetIterations(), direct.GetProof(), direct.GetIterations(), second.Err)
+	}
+
+	if s := pool.Stats(ClassBatch); s.Started != 1 {
+		t.Fatalf("Preempted job was counted as started %d times\n", s.Started)
+	}
+}
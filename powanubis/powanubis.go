@@ -0,0 +1,230 @@
+// Package powanubis issues and checks challenges in the format of Anubis-style HTML
+// interstitials, which make browsers solve a proof of work in JavaScript before they reach a
+// site. A challenge is a hex string; the browser looks for a nonce such that
+//
+//	hex(SHA-256(challenge || decimal nonce))
+//
+// starts with difficulty zero digits, and submits the nonce along with that hash. Note that
+// the difficulty counts hex digits, four bits each.
+//
+// Challenges are derived from the request's fingerprint, by default its IP address, User-Agent
+// and Accept-Language, and the current time window with HMAC-SHA256, so an Issuer remembers
+// nothing: it recomputes the challenge when the browser answers. An Issuer serves the
+// challenge's JSON descriptor for the JS solver:
+//
+//	{"challenge":"<hex>","rules":{"algorithm":"fast","difficulty":4,"report_as":4}}
+//
+// and PassHandler checks answers given as the response and nonce query parameters.
+package powanubis
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powhttp"
+)
+
+// Defaults of an Issuer
+const (
+	// DefaultDifficulty is the number of leading zero hex digits demanded
+	DefaultDifficulty = 4
+	// DefaultWindow is how long a challenge stays the same for a client
+	DefaultWindow = 7 * 24 * time.Hour
+)
+
+// Algorithm is the solver algorithm named in descriptors
+const Algorithm = "fast"
+
+// checkInterval is how many nonces Solve tries between checks of its context
+const checkInterval = 1 << 12
+
+// Rules are the rules of a challenge descriptor
+type Rules struct {
+	Algorithm  string `json:"algorithm"`
+	Difficulty int    `json:"difficulty"`
+	ReportAs   int    `json:"report_as"`
+}
+
+// Descriptor is the JSON descriptor of a challenge, for the JS solver
+type Descriptor struct {
+	Challenge string `json:"challenge"`
+	Rules     Rules  `json:"rules"`
+}
+
+// Option configures an Issuer
+type Option func(*Issuer)
+
+// WithDifficulty sets the number of leading zero hex digits demanded
+func WithDifficulty(digits int) Option {
+	return func(i *Issuer) {
+		i.difficulty = digits
+	}
+}
+
+// WithWindow sets how long a challenge stays the same for a client. Answers are accepted for
+// the current window and the one before it.
+func WithWindow(d time.Duration) Option {
+	return func(i *Issuer) {
+		i.window = d
+	}
+}
+
+// WithFingerprint sets the function that identifies the client of a request, replacing the
+// default of IP address, User-Agent and Accept-Language. Behind a reverse proxy, use the
+// forwarded address.
+func WithFingerprint(f func(*http.Request) string) Option {
+	return func(i *Issuer) {
+		i.fingerprint = f
+	}
+}
+
+// Issuer issues and checks challenges. It is the http.Handler that serves descriptors.
+type Issuer struct {
+	key         []byte
+	difficulty  int
+	window      time.Duration
+	fingerprint func(*http.Request) string
+}
+
+// New creates an Issuer deriving its challenges under key, which must be at least 16 bytes.
+// Issuers sharing the key issue the same challenges.
+func New(key []byte, opts ...Option) (*Issuer, error) {
+	if len(key) < 16 {
+		return nil, fmt.Errorf("%w: challenge key must be at least 16 bytes", powork.ErrInvalidSetting)
+	}
+
+	i := &Issuer{
+		key:         append([]byte(nil), key...),
+		difficulty:  DefaultDifficulty,
+		window:      DefaultWindow,
+		fingerprint: Fingerprint,
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	if i.difficulty < 1 || i.difficulty > sha256.Size*2 {
+		return nil, fmt.Errorf("%w: must be between 1 and %d hex digits", powork.ErrInvalidDifficulty, sha256.Size*2)
+	}
+	if i.window <= 0 {
+		return nil, fmt.Errorf("%w: challenge window must be greater than 0", powork.ErrInvalidSetting)
+	}
+	return i, nil
+}
+
+// Fingerprint is the default fingerprint of a request: its IP address, User-Agent and
+// Accept-Language
+func Fingerprint(r *http.Request) string {
+	return strings.Join([]string{powhttp.RemoteIP(r), r.UserAgent(), r.Header.Get("Accept-Language")}, "\x00")
+}
+
+// GetDifficulty gets the number of leading zero hex digits demanded
+func (i *Issuer) GetDifficulty() int {
+	return i.difficulty
+}
+
+// Challenge returns the challenge for the client of r
+func (i *Issuer) Challenge(r *http.Request) string {
+	return i.challengeAt(r, i.windowOf(time.Now()))
+}
+
+// Descriptor returns the descriptor of the challenge for the client of r
+func (i *Issuer) Descriptor(r *http.Request) Descriptor {
+	return Descriptor{
+		Challenge: i.Challenge(r),
+		Rules:     Rules{Algorithm: Algorithm, Difficulty: i.difficulty, ReportAs: i.difficulty},
+	}
+}
+
+// Verify reports whether response and nonce answer the challenge of the client of r, for the
+// current time window or the one before it
+func (i *Issuer) Verify(r *http.Request, nonce int, response string) bool {
+	w := i.windowOf(time.Now())
+	for _, window := range []uint64{w, w - 1} {
+		if Check(i.challengeAt(r, window), nonce, i.difficulty, response) {
+			return true
+		}
+	}
+	return false
+}
+
+// windowOf returns the index of the time window t falls in
+func (i *Issuer) windowOf(t time.Time) uint64 {
+	return uint64(t.UnixNano() / int64(i.window))
+}
+
+// challengeAt derives the challenge for the client of r in a time window
+func (i *Issuer) challengeAt(r *http.Request, window uint64) string {
+	mac := hmac.New(sha256.New, i.key)
+	mac.Write(binary.BigEndian.AppendUint64(nil, window))
+	mac.Write([]byte{byte(i.difficulty)})
+	mac.Write([]byte(i.fingerprint(r)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ServeHTTP implements http.Handler, answering with the descriptor of the client's challenge
+func (i *Issuer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(i.Descriptor(r))
+}
+
+// PassHandler returns a handler that checks the answer in the response and nonce query
+// parameters of a request, and passes the request to pass if it is valid. pass typically
+// grants the client a session and redirects it back to where it came from. Invalid answers
+// get 403 Forbidden.
+func (i *Issuer) PassHandler(pass http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		nonce, err := strconv.Atoi(q.Get("nonce"))
+		if err != nil || nonce < 0 || !i.Verify(r, nonce, q.Get("response")) {
+			http.Error(w, "proof of work is not valid", http.StatusForbidden)
+			return
+		}
+
+		pass.ServeHTTP(w, r)
+	})
+}
+
+// hash returns the hex hash the solver computes for nonce
+func hash(challenge string, nonce int) string {
+	sum := sha256.Sum256([]byte(challenge + strconv.Itoa(nonce)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Check reports whether response is the hash of challenge and nonce, and starts with
+// difficulty zero hex digits
+func Check(challenge string, nonce, difficulty int, response string) bool {
+	return strings.HasPrefix(response, strings.Repeat("0", difficulty)) && hmac.Equal([]byte(hash(challenge, nonce)), []byte(response))
+}
+
+// Solve finds the first nonce from 0 on that answers challenge, as the JS solver does, and
+// returns it with its response
+func Solve(ctx context.Context, challenge string, difficulty int) (int, string, error) {
+	if difficulty < 1 || difficulty > sha256.Size*2 {
+		return 0, "", fmt.Errorf("%w: must be between 1 and %d hex digits", powork.ErrInvalidDifficulty, sha256.Size*2)
+	}
+
+	prefix := strings.Repeat("0", difficulty)
+	for nonce := 0; ; nonce++ {
+		if nonce%checkInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return 0, "", err
+			}
+		}
+
+		if response := hash(challenge, nonce); strings.HasPrefix(response, prefix) {
+			return nonce, response, nil
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package powanubis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+var key = []byte("a challenge key of 32 bytes.....")
+
+// request makes a request from a browser
+func request(target, addr, agent string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	r.RemoteAddr = addr
+	r.Header.Set("User-Agent", agent)
+	return r
+}
+
+func TestChallenge(t *testing.T) {
+	i, err := New(key, WithDifficulty(3))
+	if err != nil {
+		t.Fatalf("Error creating Issuer: %v\n", err)
+	}
+
+	browser := request("/", "192.0.2.1:1234", "Mozilla/5.0")
+	rec := httptest.NewRecorder()
+	i.ServeHTTP(rec, browser)
+
+	var d Descriptor
+	if err := json.Unmarshal(rec.Body.Bytes(), &d); err != nil || len(d.Challenge) != 64 || d.Rules.Difficulty != 3 || d.Rules.Algorithm != "fast" {
+		t.Fatalf("Descriptor is %s: %v\n", rec.Body, err)
+	}
+
+	// stable for the client, and another port is the same client
+	if i.Challenge(request("/", "192.0.2.1:4321", "Mozilla/5.0")) != d.Challenge {
+		t.Fatalf("Challenge changed for the same client\n")
+	}
+	if i.Challenge(request("/", "192.0.2.1:1234", "curl/8.0")) == d.Challenge {
+		t.Fatalf("Challenge is the same for another User-Agent\n")
+	}
+
+	nonce, response, err := Solve(context.Background(), d.Challenge, d.Rules.Difficulty)
+	if err != nil {
+		t.Fatalf("Error solving: %v\n", err)
+	}
+	if !i.Verify(browser, nonce, response) {
+		t.Fatalf("Answer is not valid\n")
+	}
+	if i.Verify(request("/", "192.0.2.2:1234", "Mozilla/5.0"), nonce, response) {
+		t.Fatalf("Answer is valid for another client\n")
+	}
+	if i.Verify(browser, nonce+1, response) {
+		t.Fatalf("Answer with another nonce is valid\n")
+	}
+}
+
+func TestSolve(t *testing.T) {
+	nonce, response, _ := Solve(context.Background(), "abc", 1)
+	if !Check("abc", nonce, 1, response) || response[0] != '0' {
+		t.Fatalf("Answer %d %s does not check out\n", nonce, response)
+	}
+	for n := 0; n < nonce; n++ {
+		if Check("abc", n, 1, hash("abc", n)) {
+			t.Fatalf("Nonce %d answers before %d\n", n, nonce)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := Solve(ctx, "abc", 64); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Canceled solve returned %v\n", err)
+	}
+}
+
+func TestPassHandler(t *testing.T) {
+	i, _ := New(key, WithDifficulty(2), WithWindow(time.Hour))
+	h := i.PassHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Query().Get("redir"), http.StatusFound)
+	}))
+
+	browser := request("/", "192.0.2.1:1234", "Mozilla/5.0")
+	nonce, response, _ := Solve(context.Background(), i.Challenge(browser), i.GetDifficulty())
+
+	pass := request("/pass?redir=/home&nonce="+strconv.Itoa(nonce)+"&response="+response, "192.0.2.1:1234", "Mozilla/5.0")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, pass)
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/home" {
+		t.Fatalf("Valid answer got %d\n", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, request("/pass?nonce=x&response="+response, "192.0.2.1:1234", "Mozilla/5.0"))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("Invalid answer got %d\n", rec.Code)
+	}
+
+	if _, err := New(key, WithDifficulty(65)); !errors.Is(err, powork.ErrInvalidDifficulty) {
+		t.Fatalf("Difficulty of 65 digits did not fail with ErrInvalidDifficulty: %v\n", err)
+	}
+}
@@ -0,0 +1,89 @@
+// Package powauth exposes the powhttp middleware as an external authorization endpoint, so
+// that reverse proxies that can't load Go code delegate the proof of work check to a powork
+// sidecar. It speaks the HTTP protocols of Traefik's ForwardAuth middleware and of Envoy's
+// ext_authz filter: the proxy sends the headers of each client request to the endpoint, and
+// forwards the request upstream if the endpoint answers 200. Any other response, here the
+// 428 carrying a challenge, is returned to the client as it is.
+//
+// With Traefik:
+//
+//	http:
+//	  middlewares:
+//	    powork:
+//	      forwardAuth:
+//	        address: "http://powork:8080/"
+//	        addAuthCookiesToResponse: ["powork_session"]
+//
+// With Envoy, point the http_service of the ext_authz filter at the endpoint, allow the
+// X-PoWork header upstream to it, and the powork_session cookie back to clients with
+// allowed_client_headers_on_success.
+//
+// The proxy's address is not the client's, so the original request is rebuilt from the
+// X-Forwarded-Method, X-Forwarded-Host, X-Forwarded-Uri and X-Forwarded-For headers before
+// the middleware sees it; Envoy sends the original path as the path of the check. This makes
+// RemoteIP and the policies and ladders of the middleware work on the client's request. The
+// headers are trusted, so run the endpoint where only the proxy reaches it.
+package powauth
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Zumium/powork/powhttp"
+)
+
+// HeaderVerified is the header of allowed responses carrying the difficulty the client's
+// proof achieves, or 0 if a session or policy let the request through. Have the proxy copy
+// it upstream, with authResponseHeaders or allowed_upstream_headers.
+const HeaderVerified = "X-PoWork-Verified"
+
+// Handler returns the endpoint, configured by the options of powhttp.Handler
+func Handler(opts ...powhttp.Option) http.Handler {
+	allow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var difficulty int
+		if v, ok := powhttp.VerifiedFromContext(r.Context()); ok {
+			difficulty = v.Difficulty
+		}
+		w.Header().Set(HeaderVerified, strconv.Itoa(difficulty))
+		w.WriteHeader(http.StatusOK)
+	})
+
+	h := powhttp.Handler(allow, opts...)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, Original(r))
+	})
+}
+
+// Original rebuilds the client request a check is for from the forwarded headers the proxy
+// sets. Without them, the check is taken for the request.
+func Original(r *http.Request) *http.Request {
+	o := r.Clone(r.Context())
+
+	if method := r.Header.Get("X-Forwarded-Method"); method != "" {
+		o.Method = method
+	}
+	if host := r.Header.Get("X-Forwarded-Host"); host != "" {
+		o.Host = host
+	}
+	if uri := r.Header.Get("X-Forwarded-Uri"); uri != "" {
+		if u, err := url.ParseRequestURI(uri); err == nil {
+			o.URL = u
+			o.RequestURI = uri
+		}
+	}
+
+	// the proxy appends the address it got the request from
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		last := forwarded[len(forwarded)-1]
+		if i := strings.LastIndexByte(last, ','); i >= 0 {
+			last = last[i+1:]
+		}
+		if ip := net.ParseIP(strings.TrimSpace(last)); ip != nil {
+			o.RemoteAddr = net.JoinHostPort(ip.String(), "0")
+		}
+	}
+	return o
+}
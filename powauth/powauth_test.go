@@ -0,0 +1,80 @@
+package powauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powhttp"
+)
+
+// check sends a ForwardAuth check for a client request to h
+func check(h http.Handler, uri, proof string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Forwarded-Method", "POST")
+	req.Header.Set("X-Forwarded-Host", "example.com")
+	req.Header.Set("X-Forwarded-Uri", uri)
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	if proof != "" {
+		req.Header.Set(powhttp.HeaderProof, proof)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler(t *testing.T) {
+	policy := func(r *http.Request) (int, bool) {
+		return 0, r.URL.Path != "/healthz"
+	}
+	h := Handler(powhttp.WithDifficulty(8), powhttp.WithPolicy(policy))
+
+	rec := check(h, "/signup?next=/", "")
+	if rec.Code != powhttp.StatusChallenge {
+		t.Fatalf("Check without proof got status %v\n", rec.Code)
+	}
+
+	challenge, _ := powhttp.DecodeChallenge(rec.Header().Get(powhttp.HeaderChallenge))
+	difficulty, _ := strconv.Atoi(rec.Header().Get(powhttp.HeaderDifficulty))
+	worker := powork.NewWorker()
+	worker.SetDifficulty(difficulty)
+	pow, err := worker.DoProofForChallenge(challenge, nil)
+	if err != nil {
+		t.Fatalf("Could not solve challenge: %v\n", err)
+	}
+	proof, _ := powhttp.EncodeProof(pow)
+
+	rec = check(h, "/signup?next=/", proof)
+	if d, _ := strconv.Atoi(rec.Header().Get(HeaderVerified)); rec.Code != http.StatusOK || d < 8 {
+		t.Fatalf("Check with proof got status %v and %s %q\n", rec.Code, HeaderVerified, rec.Header().Get(HeaderVerified))
+	}
+
+	// policies decide on the client's path, not the check's
+	if rec := check(h, "/healthz", ""); rec.Code != http.StatusOK || rec.Header().Get(HeaderVerified) != "0" {
+		t.Fatalf("Check of an exempt path got status %v\n", rec.Code)
+	}
+}
+
+func TestOriginal(t *testing.T) {
+	req := httptest.NewRequest("GET", "/check", nil)
+	req.Header.Set("X-Forwarded-Method", "PUT")
+	req.Header.Set("X-Forwarded-Host", "example.com")
+	req.Header.Set("X-Forwarded-Uri", "/items/1?x=y")
+	req.Header.Add("X-Forwarded-For", "198.51.100.1, 10.0.0.1")
+	req.Header.Add("X-Forwarded-For", "2001:db8::1")
+
+	o := Original(req)
+	if o.Method != "PUT" || o.Host != "example.com" || o.URL.Path != "/items/1" || o.URL.Query().Get("x") != "y" ||
+		powhttp.RemoteIP(o) != "2001:db8::1" {
+		t.Fatalf("Original request is %s %s%s from %s\n", o.Method, o.Host, o.URL, o.RemoteAddr)
+	}
+
+	// Envoy sends the client's path and method as those of the check
+	req = httptest.NewRequest("DELETE", "/items/2", nil)
+	if o := Original(req); o.Method != "DELETE" || o.URL.Path != "/items/2" || o.RemoteAddr != req.RemoteAddr {
+		t.Fatalf("Original request is %s %s from %s\n", o.Method, o.URL, o.RemoteAddr)
+	}
+}
@@ -0,0 +1,254 @@
+// Package powcaddy packages the powhttp middleware as a Caddy HTTP handler module, so that
+// a proof of work can be required declaratively in a Caddy config. Build Caddy with it:
+//
+//	xcaddy build --with github.com/Zumium/powork/powcaddy
+//
+// and add the powork directive to a site:
+//
+//	example.com {
+//		powork {
+//			difficulty 18
+//			challenge_ttl 1m
+//			challenge_key <hex>
+//			session <hex key> 10m 100
+//			renewal_difficulty 20
+//		}
+//		reverse_proxy localhost:3000
+//	}
+//
+// Every setting is optional, and "powork 18" sets the difficulty alone. In JSON configs the
+// module is http.handlers.powork. Outstanding challenges are held in memory and lost when
+// the config is reloaded, unless a challenge_key signs them.
+package powcaddy
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powhttp"
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/caddyserver/caddy/v2/caddyconfig/httpcaddyfile"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+)
+
+// spentCapacity is the number of answered signed challenges an instance remembers
+const spentCapacity = 100000
+
+func init() {
+	caddy.RegisterModule(Middleware{})
+	httpcaddyfile.RegisterHandlerDirective("powork", parseCaddyfile)
+	httpcaddyfile.RegisterDirectiveOrder("powork", httpcaddyfile.Before, "reverse_proxy")
+}
+
+// Middleware is the http.handlers.powork module. Requests without a valid proof are
+// answered with a challenge, and the others are passed down the route.
+type Middleware struct {
+	// Difficulty is the difficulty demanded from clients, 0 for the powork default
+	Difficulty int `json:"difficulty,omitempty"`
+	// ChallengeTTL is how long an issued challenge may be answered
+	ChallengeTTL caddy.Duration `json:"challenge_ttl,omitempty"`
+	// ChallengeKey is a hex encoded key to sign challenges with, so that every instance
+	// sharing it accepts them. Each instance accepts a proof at most once.
+	ChallengeKey string `json:"challenge_key,omitempty"`
+	// Session lets clients that have presented a proof in for a while without further work
+	Session *Session `json:"session,omitempty"`
+	// RenewalDifficulty is the difficulty demanded from clients whose session has run out
+	RenewalDifficulty int `json:"renewal_difficulty,omitempty"`
+
+	handler http.Handler
+}
+
+// Session configures the session cookies of the middleware, as powhttp.WithSession does
+type Session struct {
+	// Key is the hex encoded key to sign cookies with
+	Key string `json:"key"`
+	// TTL is how long a session lasts
+	TTL caddy.Duration `json:"ttl"`
+	// Requests is the number of requests a session is good for, 0 for any number
+	Requests int `json:"requests,omitempty"`
+}
+
+// call is the state of one request through the middleware
+type call struct {
+	next caddyhttp.Handler
+	err  error
+}
+
+// callKey is the context key of a request's call
+type callKey struct{}
+
+// CaddyModule returns the Caddy module information
+func (Middleware) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "http.handlers.powork",
+		New: func() caddy.Module { return new(Middleware) },
+	}
+}
+
+// Provision sets up the middleware
+func (m *Middleware) Provision(ctx caddy.Context) error {
+	w := powork.NewWorker()
+	if m.ChallengeKey != "" {
+		key, err := hex.DecodeString(m.ChallengeKey)
+		if err != nil {
+			return fmt.Errorf("%w: challenge_key is not hex", powork.ErrInvalidSetting)
+		}
+		if err := w.SetChallengeKey(key); err != nil {
+			return err
+		}
+		// signed challenges are only single use if the answered ones are recorded
+		w.SetSpentStore(powork.NewMemorySpentStore(spentCapacity), time.Minute)
+	}
+
+	opts := []powhttp.Option{
+		powhttp.WithWorker(w),
+		powhttp.WithDifficulty(m.Difficulty),
+		powhttp.WithChallengeTTL(time.Duration(m.ChallengeTTL)),
+		powhttp.WithRenewalDifficulty(m.RenewalDifficulty),
+	}
+	if m.Session != nil {
+		key, err := hex.DecodeString(m.Session.Key)
+		if err != nil {
+			return fmt.Errorf("%w: session key is not hex", powork.ErrInvalidSetting)
+		}
+		opts = append(opts, powhttp.WithSession(key, time.Duration(m.Session.TTL), m.Session.Requests))
+	}
+
+	h, err := powhttp.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := r.Context().Value(callKey{}).(*call)
+		c.err = c.next.ServeHTTP(w, r)
+	}), opts...)
+	if err != nil {
+		return err
+	}
+	m.handler = h
+	return nil
+}
+
+// Validate checks the settings of the middleware
+func (m *Middleware) Validate() error {
+	if m.Difficulty < 0 || m.Difficulty > powork.NewWorker().MaxDifficulty() {
+		return fmt.Errorf("%w: difficulty %d is out of range", powork.ErrInvalidDifficulty, m.Difficulty)
+	}
+	if m.ChallengeTTL < 0 || m.RenewalDifficulty < 0 {
+		return fmt.Errorf("%w: challenge_ttl and renewal_difficulty must not be negative", powork.ErrInvalidSetting)
+	}
+	if m.Session != nil && (m.Session.Key == "" || m.Session.TTL <= 0 || m.Session.Requests < 0) {
+		return fmt.Errorf("%w: session needs a key, a positive ttl and a number of requests", powork.ErrInvalidSetting)
+	}
+	return nil
+}
+
+// ServeHTTP implements caddyhttp.MiddlewareHandler
+func (m *Middleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
+	c := &call{next: next}
+	m.handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), callKey{}, c)))
+	return c.err
+}
+
+// UnmarshalCaddyfile sets up the middleware from Caddyfile tokens:
+//
+//	powork [<difficulty>] {
+//		difficulty <bits>
+//		challenge_ttl <duration>
+//		challenge_key <hex>
+//		session <hex key> <ttl> [<requests>]
+//		renewal_difficulty <bits>
+//	}
+func (m *Middleware) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
+	d.Next() // the directive name
+
+	if d.NextArg() {
+		if err := parseInt(d, &m.Difficulty); err != nil {
+			return err
+		}
+	}
+	if d.NextArg() {
+		return d.ArgErr()
+	}
+
+	for d.NextBlock(0) {
+		switch d.Val() {
+		case "difficulty":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			if err := parseInt(d, &m.Difficulty); err != nil {
+				return err
+			}
+		case "renewal_difficulty":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			if err := parseInt(d, &m.RenewalDifficulty); err != nil {
+				return err
+			}
+		case "challenge_ttl":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			ttl, err := caddy.ParseDuration(d.Val())
+			if err != nil {
+				return d.Errf("parsing challenge_ttl: %v", err)
+			}
+			m.ChallengeTTL = caddy.Duration(ttl)
+		case "challenge_key":
+			if !d.AllArgs(&m.ChallengeKey) {
+				return d.ArgErr()
+			}
+		case "session":
+			args := d.RemainingArgs()
+			if len(args) < 2 || len(args) > 3 {
+				return d.ArgErr()
+			}
+			ttl, err := caddy.ParseDuration(args[1])
+			if err != nil {
+				return d.Errf("parsing session ttl: %v", err)
+			}
+			m.Session = &Session{Key: args[0], TTL: caddy.Duration(ttl)}
+			if len(args) == 3 {
+				if m.Session.Requests, err = strconv.Atoi(args[2]); err != nil {
+					return d.Errf("parsing session requests: %v", err)
+				}
+			}
+		default:
+			return d.Errf("unrecognized powork setting %q", d.Val())
+		}
+
+		if d.NextArg() {
+			return d.ArgErr()
+		}
+	}
+	return nil
+}
+
+// parseInt parses the current token as an integer into v
+func parseInt(d *caddyfile.Dispenser, v *int) error {
+	n, err := strconv.Atoi(d.Val())
+	if err != nil {
+		return d.Errf("parsing %s: %v", d.Val(), err)
+	}
+	*v = n
+	return nil
+}
+
+// parseCaddyfile creates the middleware of a powork directive
+func parseCaddyfile(h httpcaddyfile.Helper) (caddyhttp.MiddlewareHandler, error) {
+	m := new(Middleware)
+	err := m.UnmarshalCaddyfile(h.Dispenser)
+	return m, err
+}
+
+// Interface guards
+var (
+	_ caddy.Provisioner           = (*Middleware)(nil)
+	_ caddy.Validator             = (*Middleware)(nil)
+	_ caddyhttp.MiddlewareHandler = (*Middleware)(nil)
+	_ caddyfile.Unmarshaler       = (*Middleware)(nil)
+)
@@ -0,0 +1,130 @@
+package powcaddy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powhttp"
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+)
+
+func TestUnmarshalCaddyfile(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`powork {
+		difficulty 18
+		challenge_ttl 2m
+		challenge_key 000102030405060708090a0b0c0d0e0f
+		session 0f0e0d0c0b0a09080706050403020100 10m 100
+		renewal_difficulty 20
+	}`)
+
+	var m Middleware
+	if err := m.UnmarshalCaddyfile(d); err != nil {
+		t.Fatalf("Error parsing Caddyfile: %v\n", err)
+	}
+	if m.Difficulty != 18 || time.Duration(m.ChallengeTTL) != 2*time.Minute || m.RenewalDifficulty != 20 ||
+		m.Session == nil || time.Duration(m.Session.TTL) != 10*time.Minute || m.Session.Requests != 100 {
+		t.Fatalf("Caddyfile parsed into %+v\n", m)
+	}
+	if err := m.Validate(); err != nil {
+		t.Fatalf("Parsed settings are not valid: %v\n", err)
+	}
+
+	var short Middleware
+	if err := short.UnmarshalCaddyfile(caddyfile.NewTestDispenser("powork 12")); err != nil || short.Difficulty != 12 {
+		t.Fatalf("Short form parsed into %+v: %v\n", short, err)
+	}
+
+	for _, bad := range []string{
+		"powork 12 13",
+		"powork x",
+		"powork {\n frobnicate\n}",
+		"powork {\n difficulty\n}",
+		"powork {\n session key\n}",
+		"powork {\n challenge_ttl forever\n}",
+	} {
+		var m Middleware
+		if err := m.UnmarshalCaddyfile(caddyfile.NewTestDispenser(bad)); err == nil {
+			t.Fatalf("Caddyfile %q parsed\n", bad)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	for _, key := range []string{"", "000102030405060708090a0b0c0d0e0f"} {
+		testMiddleware(t, &Middleware{Difficulty: 8, ChallengeKey: key})
+	}
+}
+
+func testMiddleware(t *testing.T, m *Middleware) {
+	ctx, cancel := caddy.NewContext(caddy.Context{Context: context.Background()})
+	defer cancel()
+	if err := m.Provision(ctx); err != nil {
+		t.Fatalf("Error provisioning: %v\n", err)
+	}
+
+	var calls int
+	next := caddyhttp.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		calls++
+		if v, ok := powhttp.VerifiedFromContext(r.Context()); !ok || v.Difficulty < 8 {
+			t.Fatalf("Next handler has no proof\n")
+		}
+		return caddyhttp.Error(http.StatusTeapot, nil)
+	})
+
+	rec := httptest.NewRecorder()
+	if err := m.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil), next); err != nil || rec.Code != powhttp.StatusChallenge {
+		t.Fatalf("Request without proof got status %v and error %v\n", rec.Code, err)
+	}
+
+	challenge, _ := powhttp.DecodeChallenge(rec.Header().Get(powhttp.HeaderChallenge))
+	difficulty, _ := strconv.Atoi(rec.Header().Get(powhttp.HeaderDifficulty))
+	worker := powork.NewWorker()
+	worker.SetDifficulty(difficulty)
+	pow, err := worker.DoProofForChallenge(challenge, nil)
+	if err != nil {
+		t.Fatalf("Could not solve challenge: %v\n", err)
+	}
+	proof, _ := powhttp.EncodeProof(pow)
+
+	// errors of the rest of the route reach Caddy
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(powhttp.HeaderProof, proof)
+	err = m.ServeHTTP(httptest.NewRecorder(), req, next)
+	if herr, ok := err.(caddyhttp.HandlerError); !ok || herr.StatusCode != http.StatusTeapot || calls != 1 {
+		t.Fatalf("Request with proof returned %v\n", err)
+	}
+
+	// and the proof is good once
+	rec = httptest.NewRecorder()
+	if err := m.ServeHTTP(rec, req, next); err != nil || rec.Code != powhttp.StatusChallenge || calls != 1 {
+		t.Fatalf("Replayed proof got status %v and error %v\n", rec.Code, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, m := range []Middleware{
+		{Difficulty: -1},
+		{Difficulty: 1000},
+		{ChallengeTTL: -1},
+		{Session: &Session{TTL: caddy.Duration(time.Minute)}},
+		{Session: &Session{Key: "00", TTL: 0}},
+	} {
+		if err := m.Validate(); err == nil {
+			t.Fatalf("Settings %+v are valid\n", m)
+		}
+	}
+
+	ctx, cancel := caddy.NewContext(caddy.Context{Context: context.Background()})
+	defer cancel()
+	m := &Middleware{ChallengeKey: "not hex"}
+	if err := m.Provision(ctx); err == nil {
+		t.Fatalf("Provisioned with a challenge key that is not hex\n")
+	}
+}
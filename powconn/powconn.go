@@ -0,0 +1,342 @@
+// Package powconn gates raw TCP services, such as game servers and message brokers, behind a
+// proof of work. A Listener hands the application only connections whose client has solved a
+// challenge, so a flood of connections costs the clients far more than the server.
+//
+// The handshake runs as soon as a connection is accepted, before any application data:
+//
+//	server → client  frame: difficulty uint8, challenge
+//	client → server  frame: the proof, as encoded by powork.PoWork.MarshalBinary
+//	server → client  status uint8: 0 if the proof is accepted
+//
+// A frame is a uint16 length in network byte order followed by that many bytes. A rejected
+// client is sent a non-zero status and disconnected. Clients run their side with
+// Client.Handshake or Client.DialContext.
+package powconn
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+// DefaultTimeout is how long a client has to complete the handshake, unless another timeout
+// is set with WithTimeout
+const DefaultTimeout = 10 * time.Second
+
+// DefaultMaxPending is how many handshakes a Listener runs at once, unless another limit is
+// set with WithMaxPending
+const DefaultMaxPending = 1024
+
+// Handshake statuses
+const (
+	statusAccepted = 0
+	statusRejected = 1
+)
+
+// ErrRejected is returned by Client.Handshake when the server rejects the proof
+var ErrRejected = errors.New("Proof of work handshake rejected")
+
+// Option configures a Listener
+type Option func(*Listener)
+
+// WithWorker sets the Worker used to issue challenges and validate proofs. By default
+// powork.NewWorker is used.
+func WithWorker(w *powork.Worker) Option {
+	return func(l *Listener) {
+		l.worker = w
+	}
+}
+
+// WithDifficulty sets the difficulty demanded from clients, overriding the difficulty of the
+// Listener's Worker
+func WithDifficulty(difficulty int) Option {
+	return func(l *Listener) {
+		l.difficulty = difficulty
+	}
+}
+
+// WithTimeout sets how long a client has to complete the handshake
+func WithTimeout(d time.Duration) Option {
+	return func(l *Listener) {
+		l.timeout = d
+	}
+}
+
+// WithMaxPending sets how many handshakes may run at once. Connections accepted while the
+// limit is reached are closed straight away.
+func WithMaxPending(n int) Option {
+	return func(l *Listener) {
+		l.maxPending = n
+	}
+}
+
+// Listener is a net.Listener whose Accept only returns connections that completed the
+// handshake. Handshakes run concurrently, so slow clients don't hold up the others.
+type Listener struct {
+	net.Listener
+
+	worker     *powork.Worker
+	difficulty int
+	timeout    time.Duration
+	maxPending int
+
+	pending chan struct{}
+	conns   chan net.Conn
+	failed  chan struct{}
+	err     error // set before failed is closed
+	closed  chan struct{}
+	once    sync.Once
+}
+
+// NewListener wraps l and starts accepting connections from it
+func NewListener(l net.Listener, opts ...Option) *Listener {
+	pl := &Listener{
+		Listener:   l,
+		timeout:    DefaultTimeout,
+		maxPending: DefaultMaxPending,
+	}
+	for _, opt := range opts {
+		opt(pl)
+	}
+
+	if pl.worker == nil {
+		pl.worker = powork.NewWorker()
+	}
+	if pl.difficulty > 0 {
+		pl.worker.SetDifficulty(pl.difficulty)
+	}
+
+	pl.pending = make(chan struct{}, max(pl.maxPending, 1))
+	pl.conns = make(chan net.Conn)
+	pl.failed = make(chan struct{})
+	pl.closed = make(chan struct{})
+	go pl.serve()
+	return pl
+}
+
+// Listen announces on the local network address like net.Listen, gating its connections
+// behind a proof of work
+func Listen(network, address string, opts ...Option) (*Listener, error) {
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewListener(l, opts...), nil
+}
+
+// Accept waits for a connection that completed the handshake
+func (l *Listener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	case <-l.failed:
+		return nil, l.err
+	}
+}
+
+// Close stops accepting connections. Connections that complete their handshake afterwards are
+// closed.
+func (l *Listener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return l.Listener.Close()
+}
+
+// serve accepts connections and starts their handshakes until the wrapped listener fails
+func (l *Listener) serve() {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			l.err = err
+			close(l.failed)
+			return
+		}
+
+		select {
+		case l.pending <- struct{}{}:
+			go l.handshake(conn)
+		default:
+			conn.Close()
+		}
+	}
+}
+
+// handshake runs the handshake on conn and hands it to Accept if it passes
+func (l *Listener) handshake(conn net.Conn) {
+	defer func() { <-l.pending }()
+
+	conn.SetDeadline(time.Now().Add(l.timeout))
+	if err := l.challenge(conn); err != nil {
+		conn.Close()
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	select {
+	case l.conns <- conn:
+	case <-l.closed:
+		conn.Close()
+	}
+}
+
+// challenge sends conn a challenge and checks the proof it answers with
+func (l *Listener) challenge(conn net.Conn) error {
+	challenge, err := l.worker.NewChallenge()
+	if err != nil {
+		return err
+	}
+
+	if challenge.GetDifficulty() > 0xff {
+		return fmt.Errorf("%w: handshake difficulty must be at most 255", powork.ErrInvalidDifficulty)
+	}
+
+	frame := append([]byte{byte(challenge.GetDifficulty())}, challenge.GetBytes()...)
+	if err := writeFrame(conn, frame); err != nil {
+		return err
+	}
+
+	data, err := readFrame(conn)
+	if err != nil {
+		return err
+	}
+
+	pow := new(powork.PoWork)
+	ok := false
+	if pow.UnmarshalBinary(data) == nil {
+		ok, err = l.worker.ValidateChallenge(pow)
+		ok = ok && err == nil
+	}
+
+	if !ok {
+		conn.Write([]byte{statusRejected})
+		return ErrRejected
+	}
+
+	_, err = conn.Write([]byte{statusAccepted})
+	return err
+}
+
+// Client runs the client side of the handshake
+type Client struct {
+	// NewWorker returns the Worker used to solve a challenge. Its difficulty is replaced by
+	// the difficulty the server asks for. If nil, powork.NewWorker is used.
+	NewWorker func() *powork.Worker
+
+	// MaxDifficulty is the highest difficulty the Client agrees to solve. Zero means no limit.
+	MaxDifficulty int
+
+	// Dialer dials the connections of DialContext. If nil, a zero net.Dialer is used.
+	Dialer *net.Dialer
+}
+
+// DialContext connects to the address on the named network like net.Dialer.DialContext, and
+// runs the handshake before returning the connection
+func (c *Client) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	d := c.Dialer
+	if d == nil {
+		d = new(net.Dialer)
+	}
+
+	conn, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.Handshake(ctx, conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Handshake reads the server's challenge from conn, answers it and waits for the verdict. It
+// returns nil once the server has accepted the proof and the application protocol can start.
+// The deadline of ctx applies to conn for the duration of the handshake.
+func (c *Client) Handshake(ctx context.Context, conn net.Conn) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	frame, err := readFrame(conn)
+	if err != nil {
+		return err
+	}
+	if len(frame) < 2 {
+		return fmt.Errorf("%w: challenge frame is too short", powork.ErrInvalidEncoding)
+	}
+
+	difficulty := int(frame[0])
+	if difficulty == 0 {
+		return fmt.Errorf("%w: server sent a challenge without a difficulty", powork.ErrInvalidDifficulty)
+	}
+	if c.MaxDifficulty > 0 && difficulty > c.MaxDifficulty {
+		return fmt.Errorf("%w: challenge of difficulty %d is above the limit of %d", powork.ErrInvalidDifficulty, difficulty, c.MaxDifficulty)
+	}
+
+	var worker *powork.Worker
+	if c.NewWorker != nil {
+		worker = c.NewWorker()
+	} else {
+		worker = powork.NewWorker()
+	}
+
+	if err := worker.SetDifficulty(difficulty); err != nil {
+		return err
+	}
+
+	pow, err := worker.DoProofForChallengeContext(ctx, powork.ChallengeFromBytes(frame[1:]), nil)
+	if err != nil {
+		return err
+	}
+
+	data, err := pow.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if err := writeFrame(conn, data); err != nil {
+		return err
+	}
+
+	var status [1]byte
+	if _, err := io.ReadFull(conn, status[:]); err != nil {
+		return err
+	}
+	if status[0] != statusAccepted {
+		return ErrRejected
+	}
+	return nil
+}
+
+// writeFrame writes data with its length in front
+func writeFrame(w io.Writer, data []byte) error {
+	if len(data) > 0xffff {
+		return fmt.Errorf("%w: frame is too long", powork.ErrInvalidEncoding)
+	}
+
+	frame := binary.BigEndian.AppendUint16(make([]byte, 0, 2+len(data)), uint16(len(data)))
+	_, err := w.Write(append(frame, data...))
+	return err
+}
+
+// readFrame reads a frame written by writeFrame
+func readFrame(r io.Reader) ([]byte, error) {
+	var n [2]byte
+	if _, err := io.ReadFull(r, n[:]); err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, binary.BigEndian.Uint16(n[:]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,126 @@
+package powconn
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+// listen starts a Listener on a local port that echoes lines on the connections it accepts
+func listen(t *testing.T, opts ...Option) *Listener {
+	l, err := Listen("tcp", "127.0.0.1:0", opts...)
+	if err != nil {
+		t.Fatalf("Error listening: %v\n", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				line, _ := bufio.NewReader(conn).ReadString('\n')
+				conn.Write([]byte(line))
+			}()
+		}
+	}()
+	return l
+}
+
+func TestListener(t *testing.T) {
+	l := listen(t, WithDifficulty(8))
+
+	client := &Client{MaxDifficulty: 16}
+	conn, err := client.DialContext(context.Background(), "tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Error dialing: %v\n", err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte("hello\n"))
+	if line, err := bufio.NewReader(conn).ReadString('\n'); err != nil || line != "hello\n" {
+		t.Fatalf("Echo returned %q: %v\n", line, err)
+	}
+
+	client.MaxDifficulty = 4
+	if _, err := client.DialContext(context.Background(), "tcp", l.Addr().String()); !errors.Is(err, powork.ErrInvalidDifficulty) {
+		t.Fatalf("Dial above the maximum difficulty returned %v\n", err)
+	}
+
+	l.Close()
+	if _, err := l.Accept(); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("Accept on a closed listener returned %v\n", err)
+	}
+}
+
+func TestRejected(t *testing.T) {
+	l := listen(t, WithDifficulty(8))
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Error dialing: %v\n", err)
+	}
+	defer conn.Close()
+
+	if _, err := readFrame(conn); err != nil {
+		t.Fatalf("Error reading challenge: %v\n", err)
+	}
+
+	// a proof for another challenge
+	solver := powork.NewWorker()
+	solver.SetDifficulty(8)
+	pow, _ := solver.DoProofForChallenge(powork.ChallengeFromBytes([]byte("made up")), nil)
+	data, _ := pow.MarshalBinary()
+	writeFrame(conn, data)
+
+	status, _ := io.ReadAll(conn)
+	if len(status) != 1 || status[0] != statusRejected {
+		t.Fatalf("Server answered a proof for another challenge with %v\n", status)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	l := listen(t, WithDifficulty(8), WithTimeout(50*time.Millisecond), WithMaxPending(1))
+
+	// a client that never answers holds the only handshake slot
+	stalled, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Error dialing: %v\n", err)
+	}
+	defer stalled.Close()
+	if _, err := readFrame(stalled); err != nil {
+		t.Fatalf("Error reading challenge: %v\n", err)
+	}
+
+	// so the next connection is turned away
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Error dialing: %v\n", err)
+	}
+	defer conn.Close()
+	if _, err := readFrame(conn); err == nil {
+		t.Fatalf("Connection over the handshake limit was challenged\n")
+	}
+
+	// until the stalled handshake times out
+	if _, err := io.ReadAll(stalled); err != nil {
+		t.Fatalf("Stalled connection was not closed: %v\n", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	client := new(Client)
+	conn, err = client.DialContext(context.Background(), "tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Error dialing after the timeout: %v\n", err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,68 @@
+// Package powecho adapts the powhttp middleware to the Echo web framework:
+//
+//	e := echo.New()
+//	e.POST("/signup", signup, powecho.Middleware(powhttp.WithDifficulty(16)))
+//
+// Middleware takes the options of powhttp.Handler. Requests without a valid proof are
+// answered with a challenge; handlers of the others read the proof with Proof and Difficulty.
+package powecho
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powhttp"
+	"github.com/labstack/echo/v4"
+)
+
+// call is the state of one request through the middleware
+type call struct {
+	c      echo.Context
+	next   echo.HandlerFunc
+	passed bool
+	err    error
+}
+
+// callKey is the context key of a request's call
+type callKey struct{}
+
+// Middleware returns Echo middleware that gates handlers behind a proof of work as
+// powhttp.Handler does
+func Middleware(opts ...powhttp.Option) echo.MiddlewareFunc {
+	h := powhttp.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cl := r.Context().Value(callKey{}).(*call)
+		cl.passed = true
+		cl.c.SetRequest(r)
+		cl.err = cl.next(cl.c)
+	}), opts...)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			cl := &call{c: c, next: next}
+			r := c.Request()
+			h.ServeHTTP(c.Response(), r.WithContext(context.WithValue(r.Context(), callKey{}, cl)))
+			return cl.err
+		}
+	}
+}
+
+// Proof returns the proof the middleware let the request through with. Requests let through
+// by a session, a policy or a rate limiter carry none.
+func Proof(c echo.Context) (*powork.PoWork, bool) {
+	v, ok := powhttp.VerifiedFromContext(c.Request().Context())
+	if !ok {
+		return nil, false
+	}
+	return v.Proof, true
+}
+
+// Difficulty returns the number of leading zero bits the request's proof achieves, or 0 if
+// it carries none
+func Difficulty(c echo.Context) int {
+	v, ok := powhttp.VerifiedFromContext(c.Request().Context())
+	if !ok {
+		return 0
+	}
+	return v.Difficulty
+}
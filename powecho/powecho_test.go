@@ -0,0 +1,78 @@
+package powecho
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powhttp"
+	"github.com/labstack/echo/v4"
+)
+
+// solve answers the challenge in a 428 response
+func solve(t *testing.T, rec *httptest.ResponseRecorder) string {
+	challenge, err := powhttp.DecodeChallenge(rec.Header().Get(powhttp.HeaderChallenge))
+	if err != nil {
+		t.Fatalf("Challenge response has no valid challenge: %v\n", rec.Header())
+	}
+
+	difficulty, _ := strconv.Atoi(rec.Header().Get(powhttp.HeaderDifficulty))
+	worker := powork.NewWorker()
+	worker.SetDifficulty(difficulty)
+	pow, err := worker.DoProofForChallenge(challenge, nil)
+	if err != nil {
+		t.Fatalf("Could not solve challenge: %v\n", err)
+	}
+
+	header, _ := powhttp.EncodeProof(pow)
+	return header
+}
+
+func TestMiddleware(t *testing.T) {
+	var calls, difficulty int
+	e := echo.New()
+	e.POST("/signup", func(c echo.Context) error {
+		calls++
+		if _, ok := Proof(c); !ok {
+			t.Fatalf("Handler has no proof\n")
+		}
+		difficulty = Difficulty(c)
+		return c.String(http.StatusOK, "ok")
+	}, Middleware(powhttp.WithDifficulty(8)))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("POST", "/signup", nil))
+	if rec.Code != powhttp.StatusChallenge || calls != 0 {
+		t.Fatalf("Request without proof got status %v, calling the handler %d times\n", rec.Code, calls)
+	}
+
+	req := httptest.NewRequest("POST", "/signup", nil)
+	req.Header.Set(powhttp.HeaderProof, solve(t, rec))
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "ok" || calls != 1 {
+		t.Fatalf("Request with proof got status %v and body %q\n", rec.Code, rec.Body.String())
+	}
+
+	if difficulty < 8 {
+		t.Fatalf("Handler saw a difficulty of %d, expected at least 8\n", difficulty)
+	}
+}
+
+func TestMiddlewareError(t *testing.T) {
+	e := echo.New()
+	e.POST("/signup", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusTeapot)
+	}, Middleware(powhttp.WithDifficulty(8), powhttp.WithPolicy(func(*http.Request) (int, bool) {
+		return 0, false
+	})))
+
+	// errors of the handler reach Echo's error handler
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("POST", "/signup", nil))
+	if rec.Code != http.StatusTeapot {
+		t.Fatalf("Handler error got status %v\n", rec.Code)
+	}
+}
@@ -0,0 +1,104 @@
+// Package powfiber adapts the powhttp middleware to the Fiber web framework:
+//
+//	app := fiber.New()
+//	app.Post("/signup", powfiber.Middleware(powhttp.WithDifficulty(16)), signup)
+//
+// Middleware takes the options of powhttp.Handler. Requests without a valid proof are
+// answered with a challenge; handlers of the others read the proof with Proof and Difficulty.
+// Fiber does not run on net/http, so each request is converted for the middleware, and
+// WithLadder, WithRateLimit and WithSubject see it as a net/http request.
+package powfiber
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powhttp"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+)
+
+// localsVerified is the key of the Verified of a request in fiber.Ctx.Locals
+const localsVerified = "powork.verified"
+
+// passedKey is the context key of the request a handler let through, if any
+type passedKey struct{}
+
+// Middleware returns Fiber middleware that gates handlers behind a proof of work as
+// powhttp.Handler does
+func Middleware(opts ...powhttp.Option) fiber.Handler {
+	h := powhttp.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*r.Context().Value(passedKey{}).(**http.Request) = r
+	}), opts...)
+
+	return func(c *fiber.Ctx) error {
+		r, err := adaptor.ConvertRequest(c, true)
+		if err != nil {
+			return err
+		}
+
+		var passed *http.Request
+		w := &responseWriter{header: make(http.Header)}
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), passedKey{}, &passed)))
+
+		// Set-Cookie headers of sessions are written either way
+		for k, values := range w.header {
+			for _, v := range values {
+				c.Response().Header.Add(k, v)
+			}
+		}
+
+		if passed == nil {
+			return c.Status(w.status).Send(w.body)
+		}
+
+		if v, ok := powhttp.VerifiedFromContext(passed.Context()); ok {
+			c.Locals(localsVerified, v)
+		}
+		return c.Next()
+	}
+}
+
+// Proof returns the proof the middleware let the request through with. Requests let through
+// by a session, a policy or a rate limiter carry none.
+func Proof(c *fiber.Ctx) (*powork.PoWork, bool) {
+	v, ok := c.Locals(localsVerified).(*powhttp.Verified)
+	if !ok {
+		return nil, false
+	}
+	return v.Proof, true
+}
+
+// Difficulty returns the number of leading zero bits the request's proof achieves, or 0 if
+// it carries none
+func Difficulty(c *fiber.Ctx) int {
+	v, ok := c.Locals(localsVerified).(*powhttp.Verified)
+	if !ok {
+		return 0
+	}
+	return v.Difficulty
+}
+
+// responseWriter records the response of the middleware
+type responseWriter struct {
+	header http.Header
+	status int
+	body   []byte
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	w.body = append(w.body, b...)
+	return len(b), nil
+}
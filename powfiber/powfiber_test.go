@@ -0,0 +1,75 @@
+package powfiber
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powhttp"
+	"github.com/gofiber/fiber/v2"
+)
+
+// solve answers the challenge in a 428 response
+func solve(t *testing.T, resp *http.Response) string {
+	challenge, err := powhttp.DecodeChallenge(resp.Header.Get(powhttp.HeaderChallenge))
+	if err != nil {
+		t.Fatalf("Challenge response has no valid challenge: %v\n", resp.Header)
+	}
+
+	difficulty, _ := strconv.Atoi(resp.Header.Get(powhttp.HeaderDifficulty))
+	worker := powork.NewWorker()
+	worker.SetDifficulty(difficulty)
+	pow, err := worker.DoProofForChallenge(challenge, nil)
+	if err != nil {
+		t.Fatalf("Could not solve challenge: %v\n", err)
+	}
+
+	header, _ := powhttp.EncodeProof(pow)
+	return header
+}
+
+func TestMiddleware(t *testing.T) {
+	var calls, difficulty int
+	app := fiber.New()
+	app.Post("/signup", Middleware(powhttp.WithDifficulty(8)), func(c *fiber.Ctx) error {
+		calls++
+		if _, ok := Proof(c); !ok {
+			t.Fatalf("Handler has no proof\n")
+		}
+		difficulty = Difficulty(c)
+		return c.SendString("ok")
+	})
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/signup", nil), int(time.Minute/time.Millisecond))
+	if err != nil {
+		t.Fatalf("Error sending request: %v\n", err)
+	}
+	if resp.StatusCode != powhttp.StatusChallenge || calls != 0 {
+		t.Fatalf("Request without proof got status %v, calling the handler %d times\n", resp.StatusCode, calls)
+	}
+
+	req := httptest.NewRequest("POST", "/signup", nil)
+	req.Header.Set(powhttp.HeaderProof, solve(t, resp))
+	resp, err = app.Test(req, int(time.Minute/time.Millisecond))
+	if err != nil {
+		t.Fatalf("Error sending request: %v\n", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "ok" || calls != 1 {
+		t.Fatalf("Request with proof got status %v and body %q\n", resp.StatusCode, body)
+	}
+
+	if difficulty < 8 {
+		t.Fatalf("Handler saw a difficulty of %d, expected at least 8\n", difficulty)
+	}
+
+	// the same proof is not accepted twice
+	resp, _ = app.Test(req, int(time.Minute/time.Millisecond))
+	if resp.StatusCode != powhttp.StatusChallenge || calls != 1 {
+		t.Fatalf("Replayed proof got status %v\n", resp.StatusCode)
+	}
+}
@@ -0,0 +1,61 @@
+// Package powgin adapts the powhttp middleware to the Gin web framework:
+//
+//	r := gin.Default()
+//	r.POST("/signup", powgin.Middleware(powhttp.WithDifficulty(16)), signup)
+//
+// Middleware takes the options of powhttp.Handler. Requests without a valid proof are
+// answered with a challenge and aborted; handlers of the others read the proof with Proof and
+// Difficulty.
+package powgin
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powhttp"
+	"github.com/gin-gonic/gin"
+)
+
+// passedKey is the context key of the request a handler let through, if any
+type passedKey struct{}
+
+// Middleware returns Gin middleware that gates handlers behind a proof of work as
+// powhttp.Handler does
+func Middleware(opts ...powhttp.Option) gin.HandlerFunc {
+	h := powhttp.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*r.Context().Value(passedKey{}).(**http.Request) = r
+	}), opts...)
+
+	return func(c *gin.Context) {
+		var passed *http.Request
+		h.ServeHTTP(c.Writer, c.Request.WithContext(context.WithValue(c.Request.Context(), passedKey{}, &passed)))
+
+		if passed == nil {
+			c.Abort()
+			return
+		}
+		c.Request = passed
+		c.Next()
+	}
+}
+
+// Proof returns the proof the middleware let the request through with. Requests let through
+// by a session, a policy or a rate limiter carry none.
+func Proof(c *gin.Context) (*powork.PoWork, bool) {
+	v, ok := powhttp.VerifiedFromContext(c.Request.Context())
+	if !ok {
+		return nil, false
+	}
+	return v.Proof, true
+}
+
+// Difficulty returns the number of leading zero bits the request's proof achieves, or 0 if
+// it carries none
+func Difficulty(c *gin.Context) int {
+	v, ok := powhttp.VerifiedFromContext(c.Request.Context())
+	if !ok {
+		return 0
+	}
+	return v.Difficulty
+}
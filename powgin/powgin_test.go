@@ -0,0 +1,64 @@
+package powgin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powhttp"
+	"github.com/gin-gonic/gin"
+)
+
+// solve answers the challenge in a 428 response
+func solve(t *testing.T, rec *httptest.ResponseRecorder) string {
+	challenge, err := powhttp.DecodeChallenge(rec.Header().Get(powhttp.HeaderChallenge))
+	if err != nil {
+		t.Fatalf("Challenge response has no valid challenge: %v\n", rec.Header())
+	}
+
+	difficulty, _ := strconv.Atoi(rec.Header().Get(powhttp.HeaderDifficulty))
+	worker := powork.NewWorker()
+	worker.SetDifficulty(difficulty)
+	pow, err := worker.DoProofForChallenge(challenge, nil)
+	if err != nil {
+		t.Fatalf("Could not solve challenge: %v\n", err)
+	}
+
+	header, _ := powhttp.EncodeProof(pow)
+	return header
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var calls, difficulty int
+	r := gin.New()
+	r.POST("/signup", Middleware(powhttp.WithDifficulty(8)), func(c *gin.Context) {
+		calls++
+		if _, ok := Proof(c); !ok {
+			t.Fatalf("Handler has no proof\n")
+		}
+		difficulty = Difficulty(c)
+		c.String(http.StatusOK, "ok")
+	})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("POST", "/signup", nil))
+	if rec.Code != powhttp.StatusChallenge || calls != 0 {
+		t.Fatalf("Request without proof got status %v, calling the handler %d times\n", rec.Code, calls)
+	}
+
+	req := httptest.NewRequest("POST", "/signup", nil)
+	req.Header.Set(powhttp.HeaderProof, solve(t, rec))
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "ok" || calls != 1 {
+		t.Fatalf("Request with proof got status %v and body %q\n", rec.Code, rec.Body.String())
+	}
+
+	if difficulty < 8 {
+		t.Fatalf("Handler saw a difficulty of %d, expected at least 8\n", difficulty)
+	}
+}
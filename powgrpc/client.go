@@ -0,0 +1,114 @@
+package powgrpc
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Client answers the proof of work challenges of a gRPC server. A unary call that fails for
+// want of a proof is retried once with a proof for the challenge in the trailer. A stream
+// can't be replayed, so a challenge is fetched from the Challenges service and proven before
+// the stream is opened; streams to servers without the service are opened without a proof.
+//
+//	client := &powgrpc.Client{MaxDifficulty: 24}
+//	conn, err := grpc.NewClient(target,
+//		grpc.WithChainUnaryInterceptor(client.UnaryInterceptor()),
+//		grpc.WithChainStreamInterceptor(client.StreamInterceptor()),
+//	)
+type Client struct {
+	// NewWorker returns the Worker used to solve a challenge. Its difficulty is replaced by
+	// the difficulty the server asks for. If nil, powork.NewWorker is used.
+	NewWorker func() *powork.Worker
+
+	// MaxDifficulty is the highest difficulty the Client agrees to solve. Calls challenged
+	// above it fail with the server's error. Zero means no limit.
+	MaxDifficulty int
+}
+
+// UnaryInterceptor returns an interceptor that answers challenges to unary calls
+func (c *Client) UnaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		var trailer metadata.MD
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Trailer(&trailer))...)
+		if status.Code(err) != codes.FailedPrecondition || len(trailer.Get(MetadataChallenge)) == 0 {
+			return err
+		}
+
+		proof, solveErr := c.solve(ctx, trailer)
+		if solveErr != nil {
+			return err
+		}
+
+		ctx = metadata.AppendToOutgoingContext(ctx, MetadataProof, proof)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamInterceptor returns an interceptor that proves a challenge before opening a stream
+func (c *Client) StreamInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		var header metadata.MD
+		err := cc.Invoke(ctx, ChallengeMethod, &emptypb.Empty{}, &emptypb.Empty{}, grpc.Header(&header))
+		switch {
+		case status.Code(err) == codes.Unimplemented:
+			return streamer(ctx, desc, cc, method, opts...)
+		case err != nil:
+			return nil, err
+		}
+
+		proof, err := c.solve(ctx, header)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx = metadata.AppendToOutgoingContext(ctx, MetadataProof, proof)
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+// solve returns the encoded proof for the challenge in md
+func (c *Client) solve(ctx context.Context, md metadata.MD) (string, error) {
+	challenges, difficulties := md.Get(MetadataChallenge), md.Get(MetadataDifficulty)
+	if len(challenges) == 0 || len(difficulties) == 0 {
+		return "", status.Error(codes.FailedPrecondition, "server sent no challenge")
+	}
+
+	difficulty, err := strconv.Atoi(difficulties[0])
+	if err != nil || difficulty <= 0 {
+		return "", status.Error(codes.FailedPrecondition, "server sent an invalid difficulty")
+	}
+	if c.MaxDifficulty > 0 && difficulty > c.MaxDifficulty {
+		return "", status.Errorf(codes.FailedPrecondition, "challenge of difficulty %d is above the limit of %d", difficulty, c.MaxDifficulty)
+	}
+
+	challenge, err := powhttp.DecodeChallenge(challenges[0])
+	if err != nil {
+		return "", err
+	}
+
+	var worker *powork.Worker
+	if c.NewWorker != nil {
+		worker = c.NewWorker()
+	} else {
+		worker = powork.NewWorker()
+	}
+
+	if err := worker.SetDifficulty(difficulty); err != nil {
+		return "", err
+	}
+
+	pow, err := worker.DoProofForChallengeContext(ctx, challenge, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return powhttp.EncodeProof(pow)
+}
@@ -0,0 +1,247 @@
+// Package powgrpc provides gRPC interceptors that gate calls behind a proof of work.
+//
+// A call without a valid proof fails with codes.FailedPrecondition, and the trailer of the
+// response carries a fresh challenge in x-powork-challenge metadata and the required
+// difficulty in x-powork-difficulty. The client computes a proof bound to the challenge and
+// repeats the call with the encoded proof in x-powork metadata. Challenges can also be asked
+// for ahead of a call with the Challenges service the Server registers, whose Issue RPC
+// answers with the same metadata in its header. The values are encoded as by the powhttp
+// package.
+//
+// On the client side, the interceptors of a Client do all of this automatically.
+package powgrpc
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const (
+	// MetadataProof is the request metadata key carrying an encoded proof of work
+	MetadataProof = "x-powork"
+	// MetadataChallenge is the response metadata key carrying a challenge to prove
+	MetadataChallenge = "x-powork-challenge"
+	// MetadataDifficulty is the response metadata key carrying the required difficulty
+	MetadataDifficulty = "x-powork-difficulty"
+
+	// ChallengeService is the name of the service that issues challenges
+	ChallengeService = "powork.Challenges"
+	// ChallengeMethod is the full name of the RPC that issues challenges. It takes and
+	// returns a google.protobuf.Empty.
+	ChallengeMethod = "/" + ChallengeService + "/Issue"
+)
+
+// Option configures the Server returned by NewServer
+type Option func(*config)
+
+type config struct {
+	worker     *powork.Worker
+	difficulty int
+	ttl        time.Duration
+	exempt     []string
+}
+
+// WithWorker sets the Worker used to validate proofs. Its difficulty is the difficulty
+// demanded from clients. By default powork.NewWorker is used.
+func WithWorker(w *powork.Worker) Option {
+	return func(c *config) {
+		c.worker = w
+	}
+}
+
+// WithDifficulty sets the difficulty demanded from clients, overriding the difficulty of
+// the Server's Worker
+func WithDifficulty(difficulty int) Option {
+	return func(c *config) {
+		c.difficulty = difficulty
+	}
+}
+
+// WithChallengeTTL sets how long an issued challenge may be answered, overriding the
+// challenge TTL of the Server's Worker
+func WithChallengeTTL(d time.Duration) Option {
+	return func(c *config) {
+		c.ttl = d
+	}
+}
+
+// WithExemptMethods lets calls to the given full method names, such as
+// "/grpc.health.v1.Health/Check", through without a proof
+func WithExemptMethods(methods ...string) Option {
+	return func(c *config) {
+		c.exempt = append(c.exempt, methods...)
+	}
+}
+
+// Server demands proofs of work for the calls to a gRPC server. Install its interceptors,
+// and register it to serve challenges ahead of calls:
+//
+//	pow := powgrpc.NewServer(powgrpc.WithDifficulty(16))
+//	s := grpc.NewServer(
+//		grpc.ChainUnaryInterceptor(pow.UnaryInterceptor()),
+//		grpc.ChainStreamInterceptor(pow.StreamInterceptor()),
+//	)
+//	pow.Register(s)
+type Server struct {
+	worker *powork.Worker
+	exempt map[string]bool
+}
+
+// NewServer creates a Server. Each challenge it issues can be used once.
+func NewServer(opts ...Option) *Server {
+	c := &config{
+		worker: powork.NewWorker(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.difficulty > 0 {
+		c.worker.SetDifficulty(c.difficulty)
+	}
+
+	if c.ttl > 0 {
+		c.worker.SetChallengeTTL(c.ttl)
+	}
+
+	exempt := map[string]bool{ChallengeMethod: true}
+	for _, m := range c.exempt {
+		exempt[m] = true
+	}
+
+	return &Server{worker: c.worker, exempt: exempt}
+}
+
+// UnaryInterceptor returns an interceptor that only lets unary calls with a valid proof
+// reach their handler
+func (s *Server) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if trailer, err := s.check(ctx, info.FullMethod); err != nil {
+			if trailer != nil {
+				grpc.SetTrailer(ctx, trailer)
+			}
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor returns an interceptor that only lets streams opened with a valid proof
+// reach their handler
+func (s *Server) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if trailer, err := s.check(ss.Context(), info.FullMethod); err != nil {
+			if trailer != nil {
+				ss.SetTrailer(trailer)
+			}
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+// Register registers the Challenges service with a gRPC server
+func (s *Server) Register(r grpc.ServiceRegistrar) {
+	r.RegisterService(&challengeServiceDesc, s)
+}
+
+// check validates the proof in the metadata of a call to method. A call without a valid
+// proof gets an error and the trailer to answer it with.
+func (s *Server) check(ctx context.Context, method string) (metadata.MD, error) {
+	if s.exempt[method] {
+		return nil, nil
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if proofs := md.Get(MetadataProof); len(proofs) > 0 && s.verify(ctx, proofs[0]) {
+			return nil, nil
+		}
+	}
+
+	md, err := s.challenge()
+	if err != nil {
+		return nil, status.Error(codes.Internal, "could not issue challenge")
+	}
+	return md, status.Error(codes.FailedPrecondition, "proof of work required")
+}
+
+// verify checks an x-powork metadata value
+func (s *Server) verify(ctx context.Context, value string) bool {
+	pow, err := powhttp.DecodeProof(value)
+	if err != nil {
+		return false
+	}
+
+	ok, err := s.worker.ValidateChallengeContext(ctx, pow)
+	return err == nil && ok
+}
+
+// challenge issues a challenge and returns the metadata carrying it
+func (s *Server) challenge() (metadata.MD, error) {
+	c, err := s.worker.NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata.Pairs(
+		MetadataChallenge, powhttp.EncodeChallenge(c),
+		MetadataDifficulty, strconv.Itoa(c.GetDifficulty()),
+	), nil
+}
+
+// issue serves ChallengeMethod
+func (s *Server) issue(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	md, err := s.challenge()
+	if err != nil {
+		return nil, status.Error(codes.Internal, "could not issue challenge")
+	}
+
+	if err := grpc.SetHeader(ctx, md); err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// challengeIssuer is the service type of the Challenges service
+type challengeIssuer interface {
+	issue(ctx context.Context, in *emptypb.Empty) (*emptypb.Empty, error)
+}
+
+var challengeServiceDesc = grpc.ServiceDesc{
+	ServiceName: ChallengeService,
+	HandlerType: (*challengeIssuer)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Issue",
+		Handler:    issueHandler,
+	}},
+}
+
+func issueHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+
+	issuer := srv.(challengeIssuer)
+	if interceptor == nil {
+		return issuer.issue(ctx, in)
+	}
+
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: ChallengeMethod}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return issuer.issue(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
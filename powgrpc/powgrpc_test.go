@@ -0,0 +1,133 @@
+package powgrpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// serve starts a gRPC health server gated by pow and returns a connection to it made with
+// opts
+func serve(t *testing.T, pow *Server, register bool, opts ...grpc.DialOption) *grpc.ClientConn {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(pow.UnaryInterceptor()),
+		grpc.ChainStreamInterceptor(pow.StreamInterceptor()),
+	)
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	if register {
+		pow.Register(s)
+	}
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	dial := func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }
+	opts = append(opts, grpc.WithContextDialer(dial), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient("passthrough:///bufnet", opts...)
+	if err != nil {
+		t.Fatalf("Error connecting: %v\n", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestServerChallenges(t *testing.T) {
+	conn := serve(t, NewServer(WithDifficulty(8)), true)
+	client := healthpb.NewHealthClient(conn)
+
+	var trailer metadata.MD
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}, grpc.Trailer(&trailer))
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Call without proof failed with %v\n", err)
+	}
+	if len(trailer.Get(MetadataChallenge)) != 1 || trailer.Get(MetadataDifficulty)[0] != "8" {
+		t.Fatalf("Trailer of a challenged call is %v\n", trailer)
+	}
+
+	// the answer goes through, once
+	proof, err := (&Client{}).solve(context.Background(), trailer)
+	if err != nil {
+		t.Fatalf("Error solving challenge: %v\n", err)
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), MetadataProof, proof)
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Call with proof failed: %v\n", err)
+	}
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Replayed proof failed with %v\n", err)
+	}
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), MetadataProof, "not a proof!")
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Garbage proof failed with %v\n", err)
+	}
+}
+
+func TestServerExemptMethods(t *testing.T) {
+	conn := serve(t, NewServer(WithExemptMethods("/grpc.health.v1.Health/Check")), false)
+	if _, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Call to an exempt method failed: %v\n", err)
+	}
+}
+
+func TestClient(t *testing.T) {
+	c := &Client{}
+	conn := serve(t, NewServer(WithDifficulty(8)), true,
+		grpc.WithChainUnaryInterceptor(c.UnaryInterceptor()),
+		grpc.WithChainStreamInterceptor(c.StreamInterceptor()),
+	)
+	client := healthpb.NewHealthClient(conn)
+
+	resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("Unary call failed: %v\n", err)
+	}
+
+	stream, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("Error opening stream: %v\n", err)
+	}
+	if resp, err := stream.Recv(); err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("Stream failed: %v\n", err)
+	}
+}
+
+func TestClientMaxDifficulty(t *testing.T) {
+	c := &Client{MaxDifficulty: 4}
+	conn := serve(t, NewServer(WithDifficulty(8)), true,
+		grpc.WithChainUnaryInterceptor(c.UnaryInterceptor()),
+		grpc.WithChainStreamInterceptor(c.StreamInterceptor()),
+	)
+	client := healthpb.NewHealthClient(conn)
+
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Unary call above the maximum difficulty failed with %v\n", err)
+	}
+
+	if _, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{}); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Stream above the maximum difficulty failed with %v\n", err)
+	}
+}
+
+func TestClientWithoutChallengeService(t *testing.T) {
+	c := &Client{}
+	conn := serve(t, NewServer(WithDifficulty(8)), false, grpc.WithChainStreamInterceptor(c.StreamInterceptor()))
+
+	// the stream is opened without a proof and then rejected
+	stream, err := healthpb.NewHealthClient(conn).Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("Error opening stream: %v\n", err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Stream without proof failed with %v\n", err)
+	}
+}
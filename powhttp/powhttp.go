@@ -0,0 +1,378 @@
+// Package powhttp provides net/http middleware that gates requests behind a proof of work.
+//
+// A request without a valid proof is rejected with 428 Precondition Required. The response
+// carries a fresh challenge in the X-PoWork-Challenge header and the required difficulty in
+// the X-PoWork-Difficulty header. The client computes a proof of work bound to the challenge
+// and repeats the request with the encoded proof in the X-PoWork header.
+//
+// With a powork.Ladder, clients that make too many requests are handed challenges of a
+// higher difficulty. With a rate limiter, clients within their rate are let through without
+// a proof, and only clients over it are challenged.
+package powhttp
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powrate"
+)
+
+const (
+	// HeaderProof is the request header carrying an encoded proof of work
+	HeaderProof = "X-PoWork"
+	// HeaderChallenge is the response header carrying a challenge to prove
+	HeaderChallenge = "X-PoWork-Challenge"
+	// HeaderDifficulty is the response header carrying the required difficulty
+	HeaderDifficulty = "X-PoWork-Difficulty"
+
+	// StatusChallenge is the status code of responses that issue a challenge
+	StatusChallenge = http.StatusPreconditionRequired
+)
+
+// Option configures the middleware returned by Handler
+type Option func(*config)
+
+type config struct {
+	worker     *powork.Worker
+	difficulty int
+	ttl        time.Duration
+	ladder     *powork.Ladder
+	key        func(*http.Request) string
+	limiter    func(*http.Request) powrate.Limiter
+	scale      func(owed float64) int
+	subject    func(*http.Request) string
+	sessions   *sessions
+	renewal    int
+	policy     func(*http.Request) (int, bool)
+	err        error
+}
+
+// WithWorker sets the Worker used to validate proofs. Its difficulty is the difficulty
+// demanded from clients. By default powork.NewWorker is used.
+func WithWorker(w *powork.Worker) Option {
+	return func(c *config) {
+		c.worker = w
+	}
+}
+
+// WithDifficulty sets the difficulty demanded from clients. It is set on the middleware's
+// Worker, so a Worker passed to WithWorker is changed for its other users too.
+func WithDifficulty(difficulty int) Option {
+	return func(c *config) {
+		c.difficulty = difficulty
+	}
+}
+
+// WithChallengeTTL sets how long an issued challenge may be answered. Like WithDifficulty,
+// it is set on the middleware's Worker.
+func WithChallengeTTL(d time.Duration) Option {
+	return func(c *config) {
+		c.ttl = d
+	}
+}
+
+// WithLadder escalates the difficulty of the challenges handed to clients that make too many
+// requests, counting every request the middleware sees. Clients are told apart by key, or by
+// RemoteIP if key is nil. The ladder's base difficulty should match the Worker's.
+func WithLadder(l *powork.Ladder, key func(*http.Request) string) Option {
+	return func(c *config) {
+		c.ladder = l
+		c.key = key
+	}
+}
+
+// WithRateLimit lets requests within their client's rate through without a proof of work.
+// A request over the rate is challenged instead of rejected, at the difficulty returned by
+// difficulty for the tokens the client owes, or the Worker's difficulty if that is higher.
+// Requests carrying a valid proof are not counted against the rate. See the powrate package
+// for limiters and for ways to compute the difficulty:
+//
+//	clients := powrate.NewClients(5, 10)
+//	limit := func(r *http.Request) powrate.Limiter { return clients.Get(powhttp.RemoteIP(r)) }
+//	scale := powrate.Scale{Base: 16}
+//	h := powhttp.Handler(next, powhttp.WithDifficulty(16), powhttp.WithRateLimit(limit, scale.Difficulty))
+func WithRateLimit(limiter func(*http.Request) powrate.Limiter, difficulty func(owed float64) int) Option {
+	return func(c *config) {
+		c.limiter = limiter
+		c.scale = difficulty
+	}
+}
+
+// WithSubject only accepts proofs bound to the subject returned by subject for the request,
+// such as its API key or session ID, so that clients can't share proofs or answer each
+// other's challenges. Clients bind their proofs with powork.DoProofForChallengeSubject, or
+// with a Transport whose Subject returns the same value. Requests whose subject is "" are
+// always challenged.
+func WithSubject(subject func(*http.Request) string) Option {
+	return func(c *config) {
+		c.subject = subject
+	}
+}
+
+// WithPolicy decides per request whether a proof is required, and at what difficulty, so
+// that expensive endpoints can demand more work than cheap ones and health checks can skip
+// the middleware. Requests for which policy returns false are passed to the next handler
+// untouched; a difficulty of 0 means the Worker's. Proofs are held to the difficulty of the
+// request they are presented with, so a proof earned on a cheap route does not pass on an
+// expensive one. Likewise, a session set by WithSession is only good for routes whose
+// difficulty the proof it was earned with achieves, and routes with a difficulty of their
+// own ignore WithRenewalDifficulty. Unless the Worker signs its challenges, it holds proofs
+// to at least its own difficulty, so give it the lowest difficulty of any route:
+//
+//	policy := func(r *http.Request) (int, bool) {
+//		switch r.URL.Path {
+//		case "/healthz":
+//			return 0, false
+//		case "/signup":
+//			return 20, true
+//		}
+//		return 0, true
+//	}
+//	h := powhttp.Handler(mux, powhttp.WithDifficulty(12), powhttp.WithPolicy(policy))
+func WithPolicy(policy func(r *http.Request) (difficulty int, required bool)) Option {
+	return func(c *config) {
+		c.policy = policy
+	}
+}
+
+// RemoteIP returns the IP address a request came from. Behind a reverse proxy this is the
+// proxy's; pass WithLadder a key that reads the forwarded address instead.
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Handler wraps next so that it only receives requests carrying a valid proof of work for a
+// challenge previously issued by this handler. Each challenge can be used once; if the Worker
+// signs its challenges, it must have a SpentStore to record the answered ones in, shared by
+// every replica that shares its key. Handler panics if an option is invalid, such as a session
+// key that is too short; NewHandler returns the error instead.
+func Handler(next http.Handler, opts ...Option) http.Handler {
+	h, err := NewHandler(next, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+// NewHandler is like Handler, but returns an error if an option is invalid
+func NewHandler(next http.Handler, opts ...Option) (http.Handler, error) {
+	c := &config{
+		worker: powork.NewWorker(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	if c.worker.SignsChallenges() && c.worker.GetSpentStore() == nil {
+		return nil, fmt.Errorf("%w: a Worker that signs its challenges needs one to use them up", powork.ErrNoSpentStore)
+	}
+
+	if c.difficulty > 0 {
+		if err := c.worker.SetDifficulty(c.difficulty); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.ttl > 0 {
+		if err := c.worker.SetChallengeTTL(c.ttl); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.ladder != nil && c.key == nil {
+		c.key = RemoteIP
+	}
+
+	return &handler{
+		next:     next,
+		worker:   c.worker,
+		ladder:   c.ladder,
+		key:      c.key,
+		limiter:  c.limiter,
+		scale:    c.scale,
+		subject:  c.subject,
+		sessions: c.sessions,
+		renewal:  c.renewal,
+		policy:   c.policy,
+	}, nil
+}
+
+type handler struct {
+	next     http.Handler
+	worker   *powork.Worker
+	ladder   *powork.Ladder
+	key      func(*http.Request) string
+	limiter  func(*http.Request) powrate.Limiter
+	scale    func(owed float64) int
+	subject  func(*http.Request) string
+	sessions *sessions
+	renewal  int
+	policy   func(*http.Request) (int, bool)
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	difficulty := h.worker.GetDifficulty()
+	// the difficulty proofs for this request are held to, beyond their challenge's
+	var minimum int
+	if h.policy != nil {
+		d, required := h.policy(r)
+		if !required {
+			h.next.ServeHTTP(w, r)
+			return
+		}
+		if d > 0 {
+			difficulty = min(d, h.worker.MaxDifficulty())
+			minimum = difficulty
+		}
+	}
+
+	var hit int
+	if h.ladder != nil {
+		hit = h.ladder.Hit(h.key(r))
+	}
+
+	if h.sessions != nil {
+		switch h.sessions.use(r, max(minimum, hit)) {
+		case sessionValid:
+			h.next.ServeHTTP(w, r)
+			return
+		case sessionSpent:
+			if h.renewal > 0 && minimum == 0 {
+				difficulty = h.renewal
+			}
+		}
+	}
+	difficulty = max(difficulty, hit)
+
+	if header := r.Header.Get(HeaderProof); header != "" {
+		if pow, measured := h.verify(r, header, minimum); pow != nil {
+			v := &Verified{Proof: pow, Difficulty: measured}
+			if h.sessions != nil {
+				h.sessions.issue(w, r, v.Difficulty)
+			}
+			h.next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), verifiedKey{}, v)))
+			return
+		}
+	}
+
+	if h.limiter != nil {
+		owed := h.limiter(r).Take(time.Now())
+		if owed <= 0 {
+			h.next.ServeHTTP(w, r)
+			return
+		}
+
+		if d := h.scale(owed); d > difficulty {
+			difficulty = min(d, h.worker.MaxDifficulty())
+		}
+	}
+
+	challenge, err := h.worker.NewChallengeWithDifficulty(difficulty)
+	if err != nil {
+		http.Error(w, "could not issue challenge", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(HeaderChallenge, EncodeChallenge(challenge))
+	w.Header().Set(HeaderDifficulty, strconv.Itoa(challenge.GetDifficulty()))
+	http.Error(w, "proof of work required", StatusChallenge)
+}
+
+// verify checks the X-PoWork header value of r, also holding the proof to minimum, and
+// returns the proof and the difficulty it achieves if it is valid
+func (h *handler) verify(r *http.Request, header string, minimum int) (*powork.PoWork, int) {
+	pow, err := DecodeProof(header)
+	if err != nil {
+		return nil, 0
+	}
+
+	var ok bool
+	if h.subject != nil {
+		ok, err = h.worker.ValidateChallengeSubjectContext(r.Context(), pow, h.subject(r))
+	} else {
+		ok, err = h.worker.ValidateChallengeContext(r.Context(), pow)
+	}
+	if err != nil || !ok {
+		return nil, 0
+	}
+
+	// measured after validation, so that a junk proof costs a single hash
+	measured := h.worker.MeasureDifficulty(pow)
+	if measured < minimum {
+		return nil, 0
+	}
+	return pow, measured
+}
+
+// Verified is the proof a request was let through with
+type Verified struct {
+	Proof *powork.PoWork
+	// Difficulty is the number of leading zero bits the proof achieves, which may be more
+	// than it was asked for
+	Difficulty int
+}
+
+// verifiedKey is the context key of a request's Verified
+type verifiedKey struct{}
+
+// VerifiedFromContext returns the proof the middleware let a request through with. Requests
+// let through by a session, a policy or a rate limiter carry none.
+func VerifiedFromContext(ctx context.Context) (*Verified, bool) {
+	v, ok := ctx.Value(verifiedKey{}).(*Verified)
+	return v, ok
+}
+
+// EncodeChallenge encodes a challenge for use as an X-PoWork-Challenge header value
+func EncodeChallenge(c *powork.Challenge) string {
+	return base64.RawURLEncoding.EncodeToString(c.GetBytes())
+}
+
+// DecodeChallenge decodes an X-PoWork-Challenge header value produced by EncodeChallenge
+func DecodeChallenge(header string) (*powork.Challenge, error) {
+	b, err := base64.RawURLEncoding.DecodeString(header)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("%w: challenge header is not valid base64", powork.ErrInvalidEncoding)
+	}
+
+	return powork.ChallengeFromBytes(b), nil
+}
+
+// EncodeProof encodes a proof for use as an X-PoWork header value
+func EncodeProof(pow *powork.PoWork) (string, error) {
+	data, err := pow.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeProof decodes an X-PoWork header value produced by EncodeProof
+func DecodeProof(header string) (*powork.PoWork, error) {
+	data, err := base64.RawURLEncoding.DecodeString(header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: proof header is not valid base64", powork.ErrInvalidEncoding)
+	}
+
+	pow := new(powork.PoWork)
+	if err := pow.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+
+	return pow, nil
+}
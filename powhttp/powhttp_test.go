@@ -0,0 +1,284 @@
+package powhttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powrate"
+	"golang.org/x/time/rate"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok"))
+})
+
+// solve answers the challenge in a 428 response
+func solve(t *testing.T, rec *httptest.ResponseRecorder) string {
+	challenge, err := DecodeChallenge(rec.Header().Get(HeaderChallenge))
+	if err != nil {
+		t.Fatalf("Challenge response has no valid challenge: %v\n", rec.Header())
+	}
+
+	difficulty, err := strconv.Atoi(rec.Header().Get(HeaderDifficulty))
+	if err != nil {
+		t.Fatalf("Challenge response has no valid difficulty: %v\n", rec.Header())
+	}
+
+	worker := powork.NewWorker()
+	worker.SetDifficulty(difficulty)
+	pow, err := worker.DoProofForChallenge(challenge, nil)
+	if err != nil {
+		t.Fatalf("Could not solve challenge: %v\n", err)
+	}
+
+	header, err := EncodeProof(pow)
+	if err != nil {
+		t.Fatalf("Could not encode proof: %v\n", err)
+	}
+	return header
+}
+
+func TestHandlerChallengeAndAccept(t *testing.T) {
+	h := Handler(okHandler, WithDifficulty(8))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != StatusChallenge {
+		t.Fatalf("Request without proof got status %v\n", rec.Code)
+	}
+	if rec.Header().Get(HeaderDifficulty) != "8" {
+		t.Fatalf("Wrong difficulty advertised: %v\n", rec.Header().Get(HeaderDifficulty))
+	}
+
+	proof := solve(t, rec)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderProof, proof)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+		t.Fatalf("Request with valid proof got status %v\n", rec.Code)
+	}
+
+	// a challenge can only be answered once
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != StatusChallenge {
+		t.Fatalf("Replayed proof got status %v\n", rec.Code)
+	}
+
+	// the next handler sees the proof
+	var v *Verified
+	inspect := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v, _ = VerifiedFromContext(r.Context())
+	}), WithDifficulty(8))
+	rec = httptest.NewRecorder()
+	inspect.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderProof, solve(t, rec))
+	inspect.ServeHTTP(httptest.NewRecorder(), req)
+	if v == nil || v.Difficulty < 8 || v.Proof.GetDifficulty() != 8 {
+		t.Fatalf("Handler saw verified proof %+v\n", v)
+	}
+}
+
+func TestHandlerSignedChallenge(t *testing.T) {
+	w := powork.NewWorker()
+	w.SetChallengeKey([]byte("a challenge signing key"))
+	if _, err := NewHandler(okHandler, WithWorker(w)); !errors.Is(err, powork.ErrNoSpentStore) {
+		t.Fatalf("Worker signing challenges without a spent store did not fail with ErrNoSpentStore: %v\n", err)
+	}
+
+	w.SetSpentStore(powork.NewMemorySpentStore(100), time.Minute)
+	h := Handler(okHandler, WithWorker(w), WithDifficulty(8))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderProof, solve(t, rec))
+
+	// a signed challenge can only be answered once too
+	for i, want := range []int{http.StatusOK, StatusChallenge, StatusChallenge} {
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Fatalf("Request %d with the proof got status %v, expected %v\n", i, rec.Code, want)
+		}
+	}
+}
+
+func TestHandlerDifficultyTooHigh(t *testing.T) {
+	if _, err := NewHandler(okHandler, WithDifficulty(1000)); !errors.Is(err, powork.ErrDifficultyExceedsHash) {
+		t.Fatalf("Difficulty beyond the hash did not fail with ErrDifficultyExceedsHash: %v\n", err)
+	}
+}
+
+func TestHandlerRejectsUnissuedChallenge(t *testing.T) {
+	h := Handler(okHandler, WithDifficulty(8))
+
+	worker := powork.NewWorker()
+	worker.SetDifficulty(8)
+	pow, _ := worker.DoProofForChallenge(powork.ChallengeFromBytes([]byte("a challenge I made up myself")), nil)
+	proof, _ := EncodeProof(pow)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderProof, proof)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != StatusChallenge {
+		t.Fatalf("Proof for an unissued challenge got status %v\n", rec.Code)
+	}
+}
+
+func TestHandlerRejectsGarbage(t *testing.T) {
+	h := Handler(okHandler)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderProof, "not a proof!")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != StatusChallenge {
+		t.Fatalf("Garbage proof got status %v\n", rec.Code)
+	}
+}
+
+func TestHandlerLadder(t *testing.T) {
+	ladder, _ := powork.NewLadder(8, time.Hour, 2)
+	h := Handler(okHandler, WithDifficulty(8), WithLadder(ladder, nil))
+
+	expected := []string{"8", "8", "9", "9", "10"}
+	var rec *httptest.ResponseRecorder
+	for i, e := range expected {
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		if d := rec.Header().Get(HeaderDifficulty); d != e {
+			t.Fatalf("Request %d was asked for difficulty %v, expected %v\n", i+1, d, e)
+		}
+	}
+
+	// the answer is held to the escalated difficulty, and counts as another request
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderProof, solve(t, rec))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Request with a proof at the escalated difficulty got status %v\n", rec.Code)
+	}
+
+	// another client starts at the bottom
+	req = httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.0.2.99:1234"
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if d := rec.Header().Get(HeaderDifficulty); d != "8" {
+		t.Fatalf("Another client was asked for difficulty %v\n", d)
+	}
+}
+
+func TestHandlerRateLimit(t *testing.T) {
+	clients := powrate.NewClients(rate.Every(time.Hour), 2)
+	limit := func(r *http.Request) powrate.Limiter { return clients.Get(RemoteIP(r)) }
+	h := Handler(okHandler, WithDifficulty(8), WithRateLimit(limit, powrate.Scale{Base: 8}.Difficulty))
+
+	// the burst goes through without proofs, then the difficulty grows with the debt
+	expected := []string{"", "", "8", "9", "10", "10"}
+	var rec *httptest.ResponseRecorder
+	for i, e := range expected {
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		if e == "" && rec.Code != http.StatusOK {
+			t.Fatalf("Request %d within the rate got status %v\n", i+1, rec.Code)
+		}
+		if d := rec.Header().Get(HeaderDifficulty); d != e {
+			t.Fatalf("Request %d was asked for difficulty %v, expected %v\n", i+1, d, e)
+		}
+	}
+
+	// a valid proof goes through and is not counted against the rate
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderProof, solve(t, rec))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Request with a proof got status %v\n", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if d := rec.Header().Get(HeaderDifficulty); d != "11" {
+		t.Fatalf("Request after the proof was asked for difficulty %v, expected 11\n", d)
+	}
+
+	// difficulties beyond the hash are capped
+	h = Handler(okHandler, WithDifficulty(8), WithRateLimit(limit, func(float64) int { return 1 << 20 }))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if d := rec.Header().Get(HeaderDifficulty); d != "512" {
+		t.Fatalf("Unbounded difficulty was capped at %v, expected 512\n", d)
+	}
+}
+
+func TestHandlerPolicy(t *testing.T) {
+	policy := func(r *http.Request) (int, bool) {
+		switch r.URL.Path {
+		case "/healthz":
+			return 0, false
+		case "/signup":
+			return 12, true
+		}
+		return 0, true
+	}
+	h := Handler(okHandler, WithDifficulty(6), WithPolicy(policy))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Health check got status %v\n", rec.Code)
+	}
+
+	for path, want := range map[string]string{"/": "6", "/signup": "12"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != StatusChallenge || rec.Header().Get(HeaderDifficulty) != want {
+			t.Fatalf("Request for %s got status %v at difficulty %v\n", path, rec.Code, rec.Header().Get(HeaderDifficulty))
+		}
+	}
+
+	// a proof good enough for a cheap route does not pass on an expensive one
+	worker := powork.NewWorker()
+	worker.SetDifficulty(6)
+	for {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		challenge, _ := DecodeChallenge(rec.Header().Get(HeaderChallenge))
+		pow, _ := worker.DoProofForChallenge(challenge, nil)
+		if worker.MeasureDifficulty(pow) >= 12 {
+			continue
+		}
+
+		proof, _ := EncodeProof(pow)
+		req := httptest.NewRequest("GET", "/signup", nil)
+		req.Header.Set(HeaderProof, proof)
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != StatusChallenge {
+			t.Fatalf("Cheap proof on an expensive route got status %v\n", rec.Code)
+		}
+		break
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/signup", nil))
+	req := httptest.NewRequest("GET", "/signup", nil)
+	req.Header.Set(HeaderProof, solve(t, rec))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Proof for the expensive route got status %v\n", rec.Code)
+	}
+}
@@ -0,0 +1,192 @@
+package powhttp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+// CookieSession is the name of the cookie carrying a session
+const CookieSession = "powork_session"
+
+const (
+	sessionIDLen    = 16
+	sessionBodyLen  = sessionIDLen + 8 + 2
+	sessionValueLen = sessionBodyLen + sha256.Size
+)
+
+// WithSession lets a client that has presented a valid proof make further requests for up
+// to ttl without more work, up to requests of them, or any number if requests is 0. The
+// middleware then sets a session cookie signed with key along with the response. The key
+// must be at least 16 bytes and ttl greater than 0, or the middleware fails to build. A
+// session only lets requests through that demand no more difficulty, by their policy or
+// ladder, than the proof it was earned with achieves. Requests are counted by the
+// middleware in memory, so replicas of a service that share the key each allow the requests.
+func WithSession(key []byte, ttl time.Duration, requests int) Option {
+	return func(c *config) {
+		if len(key) < 16 {
+			c.err = fmt.Errorf("%w: session key must be at least 16 bytes", powork.ErrInvalidSetting)
+			return
+		}
+
+		if ttl <= 0 {
+			c.err = fmt.Errorf("%w: session TTL must be greater than 0", powork.ErrInvalidSetting)
+			return
+		}
+
+		if requests < 0 {
+			c.err = fmt.Errorf("%w: session requests must not be negative", powork.ErrInvalidSetting)
+			return
+		}
+
+		c.sessions = &sessions{
+			key:      append([]byte(nil), key...),
+			ttl:      ttl,
+			requests: requests,
+			used:     make(map[string]*sessionUse),
+			swept:    time.Now(),
+		}
+	}
+}
+
+// WithRenewalDifficulty sets the difficulty demanded from clients whose session, set by
+// WithSession, has expired or run out of requests, instead of the Worker's. A renewal can be
+// made cheaper than a first visit this way only if the Worker signs its challenges, since
+// the Worker holds proofs for the challenges it remembers to at least its own difficulty.
+func WithRenewalDifficulty(difficulty int) Option {
+	return func(c *config) {
+		c.renewal = difficulty
+	}
+}
+
+// sessions issues and checks session cookies
+type sessions struct {
+	key      []byte
+	ttl      time.Duration
+	requests int
+
+	mu    sync.Mutex
+	used  map[string]*sessionUse
+	swept time.Time
+}
+
+// sessionUse counts the requests made in a session
+type sessionUse struct {
+	requests int
+	expires  time.Time
+}
+
+// sessionState is what a request's cookie says about its session
+type sessionState int
+
+const (
+	sessionNone sessionState = iota
+	sessionValid
+	// sessionSpent is a genuine session that has expired or run out of requests
+	sessionSpent
+)
+
+// use checks the session cookie of r, and counts r against it if it is valid and was earned
+// at difficulty or more
+func (s *sessions) use(r *http.Request, difficulty int) sessionState {
+	cookie, err := r.Cookie(CookieSession)
+	if err != nil {
+		return sessionNone
+	}
+
+	v, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil || len(v) != sessionValueLen || !hmac.Equal(s.sign(v[:sessionBodyLen]), v[sessionBodyLen:]) {
+		return sessionNone
+	}
+
+	now := time.Now()
+	expires := time.UnixMilli(int64(binary.BigEndian.Uint64(v[sessionIDLen:])))
+	if !now.Before(expires) {
+		return sessionSpent
+	}
+	if int(binary.BigEndian.Uint16(v[sessionIDLen+8:])) < difficulty {
+		return sessionNone
+	}
+	if s.requests == 0 {
+		return sessionValid
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	id := string(v[:sessionIDLen])
+	u := s.used[id]
+	if u == nil {
+		// issued by a replica, or before a restart
+		u = &sessionUse{expires: expires}
+		s.used[id] = u
+	}
+	if u.requests >= s.requests {
+		return sessionSpent
+	}
+	u.requests++
+	return sessionValid
+}
+
+// issue sets a new session cookie on w, earned with a proof of difficulty. The request
+// presenting the proof counts as the first of the session.
+func (s *sessions) issue(w http.ResponseWriter, r *http.Request, difficulty int) {
+	v := make([]byte, sessionBodyLen, sessionValueLen)
+	if _, err := rand.Read(v[:sessionIDLen]); err != nil {
+		return
+	}
+
+	now := time.Now()
+	expires := now.Add(s.ttl)
+	binary.BigEndian.PutUint64(v[sessionIDLen:], uint64(expires.UnixMilli()))
+	binary.BigEndian.PutUint16(v[sessionIDLen+8:], uint16(min(difficulty, math.MaxUint16)))
+	v = append(v, s.sign(v)...)
+
+	if s.requests > 0 {
+		s.mu.Lock()
+		s.sweep(now)
+		s.used[string(v[:sessionIDLen])] = &sessionUse{requests: 1, expires: expires}
+		s.mu.Unlock()
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieSession,
+		Value:    base64.RawURLEncoding.EncodeToString(v),
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// sweep forgets expired sessions, at most once per session TTL. The caller must hold s.mu.
+func (s *sessions) sweep(now time.Time) {
+	if now.Sub(s.swept) < s.ttl {
+		return
+	}
+
+	for id, u := range s.used {
+		if !now.Before(u.expires) {
+			delete(s.used, id)
+		}
+	}
+	s.swept = now
+}
+
+func (s *sessions) sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
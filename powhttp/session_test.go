@@ -0,0 +1,219 @@
+package powhttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+var sessionKey = []byte("session key of 16 bytes or more")
+
+// serveWithProof answers the challenge in rec and sends the request again
+func serveWithProof(t *testing.T, h http.Handler, rec *httptest.ResponseRecorder) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderProof, solve(t, rec))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// serveWithCookie sends a request carrying cookie
+func serveWithCookie(h http.Handler, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/", nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSessionRequests(t *testing.T) {
+	h := Handler(okHandler, WithDifficulty(8), WithSession(sessionKey, time.Minute, 3))
+
+	rec := serveWithCookie(h, nil)
+	rec = serveWithProof(t, h, rec)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Request with valid proof got status %v\n", rec.Code)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != CookieSession || !cookies[0].HttpOnly {
+		t.Fatalf("Proof did not earn a session cookie: %v\n", cookies)
+	}
+
+	// the proof's request was the first of three
+	for i := 2; i <= 3; i++ {
+		if rec := serveWithCookie(h, cookies[0]); rec.Code != http.StatusOK {
+			t.Fatalf("Request %d of the session got status %v\n", i, rec.Code)
+		}
+	}
+	if rec := serveWithCookie(h, cookies[0]); rec.Code != StatusChallenge {
+		t.Fatalf("Request beyond the session got status %v\n", rec.Code)
+	}
+
+	// a tampered cookie is no session
+	forged := *cookies[0]
+	forged.Value = strings.ToUpper(forged.Value[:4]) + forged.Value[4:]
+	if rec := serveWithCookie(h, &forged); rec.Code != StatusChallenge {
+		t.Fatalf("Request with a tampered cookie got status %v\n", rec.Code)
+	}
+	other := Handler(okHandler, WithDifficulty(8), WithSession([]byte("another session key of 16 bytes"), time.Minute, 0))
+	if rec := serveWithCookie(other, cookies[0]); rec.Code != StatusChallenge {
+		t.Fatalf("Cookie signed with another key got status %v\n", rec.Code)
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	w := powork.NewWorker()
+	w.SetChallengeKey([]byte("a challenge signing key"))
+	w.SetSpentStore(powork.NewMemorySpentStore(100), time.Minute)
+	h := Handler(okHandler, WithWorker(w), WithDifficulty(12),
+		WithSession(sessionKey, 50*time.Millisecond, 0), WithRenewalDifficulty(6))
+
+	rec := serveWithCookie(h, nil)
+	if rec.Header().Get(HeaderDifficulty) != "12" {
+		t.Fatalf("First visit was challenged at difficulty %v\n", rec.Header().Get(HeaderDifficulty))
+	}
+	cookie := serveWithProof(t, h, rec).Result().Cookies()[0]
+
+	for i := 0; i < 10; i++ {
+		if rec := serveWithCookie(h, cookie); rec.Code != http.StatusOK {
+			t.Fatalf("Request in an unlimited session got status %v\n", rec.Code)
+		}
+	}
+
+	// the renewal is cheaper than the first visit
+	time.Sleep(60 * time.Millisecond)
+	rec = serveWithCookie(h, cookie)
+	if rec.Code != StatusChallenge || rec.Header().Get(HeaderDifficulty) != "6" {
+		t.Fatalf("Expired session got status %v at difficulty %v\n", rec.Code, rec.Header().Get(HeaderDifficulty))
+	}
+	if rec := serveWithProof(t, h, rec); rec.Code != http.StatusOK || len(rec.Result().Cookies()) != 1 {
+		t.Fatalf("Renewal got status %v\n", rec.Code)
+	}
+}
+
+func TestSessionTransport(t *testing.T) {
+	var served int32
+	counted := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&served, 1)
+	})
+	srv := httptest.NewServer(Handler(counted, WithDifficulty(8), WithSession(sessionKey, time.Minute, 0)))
+	defer srv.Close()
+
+	var solved int32
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar, Transport: &Transport{NewWorker: func() *powork.Worker {
+		atomic.AddInt32(&solved, 1)
+		return powork.NewWorker()
+	}}}
+	for i := 0; i < 5; i++ {
+		resp, err := client.Get(srv.URL)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("Request %d failed: %v\n", i, err)
+		}
+		resp.Body.Close()
+	}
+
+	if solved != 1 || served != 5 {
+		t.Fatalf("Client solved %d challenges for %d requests served\n", solved, served)
+	}
+}
+
+func TestSessionSettings(t *testing.T) {
+	for _, key := range [][]byte{nil, {}, []byte("too short")} {
+		if _, err := NewHandler(okHandler, WithSession(key, time.Minute, 0)); !errors.Is(err, powork.ErrInvalidSetting) {
+			t.Fatalf("Session key %q did not fail with ErrInvalidSetting: %v\n", key, err)
+		}
+	}
+
+	for _, ttl := range []time.Duration{0, -time.Minute} {
+		if _, err := NewHandler(okHandler, WithSession(sessionKey, ttl, 0)); !errors.Is(err, powork.ErrInvalidSetting) {
+			t.Fatalf("Session TTL %v did not fail with ErrInvalidSetting: %v\n", ttl, err)
+		}
+	}
+
+	if _, err := NewHandler(okHandler, WithSession(sessionKey, time.Minute, -1)); !errors.Is(err, powork.ErrInvalidSetting) {
+		t.Fatalf("Negative session requests did not fail with ErrInvalidSetting: %v\n", err)
+	}
+}
+
+func TestSessionSweep(t *testing.T) {
+	issuer := Handler(okHandler, WithDifficulty(8), WithSession(sessionKey, 20*time.Millisecond, 3))
+	checker := Handler(okHandler, WithDifficulty(8), WithSession(sessionKey, 20*time.Millisecond, 3))
+
+	check := func() {
+		rec := serveWithProof(t, issuer, serveWithCookie(issuer, nil))
+		if rec = serveWithCookie(checker, rec.Result().Cookies()[0]); rec.Code != http.StatusOK {
+			t.Fatalf("Session of another replica got status %v\n", rec.Code)
+		}
+	}
+
+	// a replica that only checks sessions forgets them once they expire
+	check()
+	time.Sleep(40 * time.Millisecond)
+	check()
+	if n := len(checker.(*handler).sessions.used); n != 1 {
+		t.Fatalf("Checking replica remembers %d sessions, expected 1\n", n)
+	}
+}
+
+func TestSessionPolicy(t *testing.T) {
+	policy := func(r *http.Request) (int, bool) {
+		if r.URL.Path == "/signup" {
+			return 12, true
+		}
+		return 0, true
+	}
+	h := Handler(okHandler, WithDifficulty(6), WithPolicy(policy), WithSession(sessionKey, time.Minute, 0))
+
+	serve := func(path, proof string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if proof != "" {
+			req.Header.Set(HeaderProof, proof)
+		}
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// earn a session on the cheap route with a proof short of the expensive one
+	worker := powork.NewWorker()
+	worker.SetDifficulty(6)
+	var cookie *http.Cookie
+	for cookie == nil {
+		challenge, _ := DecodeChallenge(serve("/", "", nil).Header().Get(HeaderChallenge))
+		pow, _ := worker.DoProofForChallenge(challenge, nil)
+		if worker.MeasureDifficulty(pow) >= 12 {
+			continue
+		}
+		proof, _ := EncodeProof(pow)
+		cookie = serve("/", proof, nil).Result().Cookies()[0]
+	}
+
+	if rec := serve("/", "", cookie); rec.Code != http.StatusOK {
+		t.Fatalf("Session on the route it was earned on got status %v\n", rec.Code)
+	}
+	rec := serve("/signup", "", cookie)
+	if rec.Code != StatusChallenge || rec.Header().Get(HeaderDifficulty) != "12" {
+		t.Fatalf("Cheap session on an expensive route got status %v\n", rec.Code)
+	}
+
+	// a session earned on the expensive route is good for both
+	cookie = serve("/signup", solve(t, rec), cookie).Result().Cookies()[0]
+	for _, path := range []string{"/", "/signup"} {
+		if rec := serve(path, "", cookie); rec.Code != http.StatusOK {
+			t.Fatalf("Expensive session on %s got status %v\n", path, rec.Code)
+		}
+	}
+}
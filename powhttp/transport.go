@@ -0,0 +1,128 @@
+package powhttp
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/Zumium/powork"
+)
+
+// Transport is an http.RoundTripper that answers proof of work challenges. When a response
+// has status 428 or 402 and carries an X-PoWork-Challenge header, Transport computes a proof
+// for the challenge and retries the request once with the proof attached.
+//
+// Requests with a body are only retried if their GetBody field is set, as it is for requests
+// created by http.NewRequest with common body types.
+type Transport struct {
+	// Base is the underlying RoundTripper. If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+
+	// NewWorker returns the Worker used to solve a challenge. Its difficulty is replaced by
+	// the difficulty the server asks for. If nil, powork.NewWorker is used.
+	NewWorker func() *powork.Worker
+
+	// MaxDifficulty is the highest difficulty the Transport agrees to solve. Challenges above
+	// it are returned to the caller unanswered. Zero means no limit.
+	MaxDifficulty int
+
+	// Subject returns the subject to bind proofs for req to, for servers that use
+	// WithSubject. If nil, proofs are not bound to a subject.
+	Subject func(req *http.Request) string
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base().RoundTrip(req)
+	if err != nil || !isChallenge(resp) {
+		return resp, err
+	}
+
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		// the body has been consumed and cannot be sent again
+		return resp, nil
+	}
+
+	challenge := resp.Header.Get(HeaderChallenge)
+	difficulty, err := strconv.Atoi(resp.Header.Get(HeaderDifficulty))
+	if err != nil || difficulty <= 0 || (t.MaxDifficulty > 0 && difficulty > t.MaxDifficulty) {
+		return resp, nil
+	}
+
+	proof, err := t.solve(req, challenge, difficulty)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		retry.Body = body
+	}
+	retry.Header.Set(HeaderProof, proof)
+
+	// drain the challenge response so the connection can be reused
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	return t.base().RoundTrip(retry)
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+
+	return http.DefaultTransport
+}
+
+// solve computes the encoded proof for challenge. Challenges are issued for a single use, so
+// the proof is not kept.
+func (t *Transport) solve(req *http.Request, challenge string, difficulty int) (string, error) {
+	var subject string
+	if t.Subject != nil {
+		subject = t.Subject(req)
+	}
+
+	var worker *powork.Worker
+	if t.NewWorker != nil {
+		worker = t.NewWorker()
+	} else {
+		worker = powork.NewWorker()
+	}
+
+	if err := worker.SetDifficulty(difficulty); err != nil {
+		return "", err
+	}
+
+	c, err := DecodeChallenge(challenge)
+	if err != nil {
+		return "", err
+	}
+
+	var pow *powork.PoWork
+	if subject != "" {
+		pow, err = worker.DoProofForChallengeSubjectContext(req.Context(), c, nil, subject)
+	} else {
+		pow, err = worker.DoProofForChallengeContext(req.Context(), c, nil)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return EncodeProof(pow)
+}
+
+// isChallenge reports whether resp asks for a proof of work
+func isChallenge(resp *http.Response) bool {
+	if resp.StatusCode != StatusChallenge && resp.StatusCode != http.StatusPaymentRequired {
+		return false
+	}
+
+	return resp.Header.Get(HeaderChallenge) != ""
+}
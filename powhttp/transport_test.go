@@ -0,0 +1,122 @@
+package powhttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Zumium/powork"
+)
+
+func TestTransportSolvesChallenge(t *testing.T) {
+	var served int32
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&served, 1)
+		io.Copy(w, r.Body)
+	})
+
+	srv := httptest.NewServer(Handler(echo, WithDifficulty(8)))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &Transport{}}
+	resp, err := client.Post(srv.URL, "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("Request failed: %v\n", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "hello" {
+		t.Fatalf("Unexpected response %v: %q\n", resp.StatusCode, body)
+	}
+
+	if served != 1 {
+		t.Fatalf("Handler was called %v times\n", served)
+	}
+}
+
+func TestTransportMaxDifficulty(t *testing.T) {
+	srv := httptest.NewServer(Handler(okHandler, WithDifficulty(30)))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &Transport{MaxDifficulty: 20}}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v\n", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != StatusChallenge {
+		t.Fatalf("Challenge above MaxDifficulty was answered: %v\n", resp.StatusCode)
+	}
+}
+
+func TestTransportSolvesEachChallenge(t *testing.T) {
+	srv := httptest.NewServer(Handler(okHandler, WithDifficulty(8)))
+	defer srv.Close()
+
+	var solves int32
+	client := &http.Client{Transport: &Transport{NewWorker: func() *powork.Worker {
+		atomic.AddInt32(&solves, 1)
+		return powork.NewWorker()
+	}}}
+
+	// every request is issued a fresh challenge, which is solved anew
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("Request failed: %v\n", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Request %d got status %v\n", i, resp.StatusCode)
+		}
+	}
+
+	if solves != 2 {
+		t.Fatalf("Transport solved %d challenges for two requests\n", solves)
+	}
+}
+
+func TestTransportSubject(t *testing.T) {
+	apiKey := func(r *http.Request) string { return r.Header.Get("X-API-Key") }
+	h := Handler(okHandler, WithDifficulty(8), WithSubject(apiKey))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	client := &http.Client{Transport: &Transport{Subject: apiKey}}
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	req.Header.Set("X-API-Key", "key-1")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v\n", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Request with a bound proof got status %v\n", resp.StatusCode)
+	}
+
+	// a proof bound to one key does not serve another
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	challenge, _ := DecodeChallenge(rec.Header().Get(HeaderChallenge))
+	worker := powork.NewWorker()
+	worker.SetDifficulty(8)
+	pow, _ := worker.DoProofForChallengeSubject(challenge, nil, "key-1")
+	proof, _ := EncodeProof(pow)
+
+	for _, key := range []string{"key-2", "key-1"} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("X-API-Key", key)
+		r.Header.Set(HeaderProof, proof)
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if want := map[string]int{"key-1": http.StatusOK, "key-2": StatusChallenge}[key]; rec.Code != want {
+			t.Fatalf("Proof bound to key-1 presented with %s got status %v\n", key, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+//go:build js && wasm
+
+package powjs
+
+import (
+	"context"
+	"syscall/js"
+)
+
+// Register installs the binding as the global object name, such as "powork", with a solve
+// method. The Go program must keep running for the binding to work; block in main after
+// calling Register.
+func Register(name string) {
+	obj := js.Global().Get("Object").New()
+	obj.Set("solve", js.FuncOf(solve))
+	js.Global().Set(name, obj)
+}
+
+// solve implements solve(challenge, difficulty, onProgress), returning a Promise of the proof
+func solve(this js.Value, args []js.Value) any {
+	promise := js.Global().Get("Promise")
+	errorCtor := js.Global().Get("Error")
+
+	if len(args) < 2 || args[0].Type() != js.TypeString || args[1].Type() != js.TypeNumber {
+		return promise.Call("reject", errorCtor.New("solve expects a challenge string and a difficulty"))
+	}
+
+	challenge, difficulty := args[0].String(), args[1].Int()
+	var progress func(uint64)
+	if len(args) > 2 && args[2].Type() == js.TypeFunction {
+		callback := args[2]
+		progress = func(iterations uint64) {
+			callback.Invoke(float64(iterations))
+		}
+	}
+
+	executor := js.FuncOf(func(this js.Value, handlers []js.Value) any {
+		resolve, reject := handlers[0], handlers[1]
+		// the executor must return before the search can run
+		go func() {
+			proof, err := Solve(context.Background(), challenge, difficulty, progress)
+			if err != nil {
+				reject.Invoke(errorCtor.New(err.Error()))
+				return
+			}
+			resolve.Invoke(proof)
+		}()
+		return nil
+	})
+	defer executor.Release()
+
+	return promise.New(executor)
+}
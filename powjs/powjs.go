@@ -0,0 +1,56 @@
+// Package powjs lets web frontends solve the challenges of the powhttp middleware in the
+// browser, with the solver compiled to WebAssembly. Built with GOOS=js GOARCH=wasm, Register
+// installs a JavaScript binding:
+//
+//	const proof = await powork.solve(challenge, difficulty, (iterations) => { ... })
+//
+// where challenge and difficulty are the values of the X-PoWork-Challenge and
+// X-PoWork-Difficulty headers of a 428 response, and proof is the value to send in the
+// X-PoWork header of the retried request. The optional callback is called as the search
+// progresses, with the number of hashes tried so far.
+//
+// WebAssembly runs on a single thread, and a search keeps it busy until it finds the proof.
+// Run the solver in a Web Worker so that the page stays responsive. The cmd/powork-wasm
+// command is a ready-made module that registers the binding as globalThis.powork.
+package powjs
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powhttp"
+)
+
+// ProgressInterval is the number of hashes between two progress callbacks of Solve
+const ProgressInterval = 10000
+
+// Solve computes the X-PoWork header value that answers an X-PoWork-Challenge header value at
+// the given difficulty. If progress is not nil, it is called with the number of hashes tried
+// every ProgressInterval hashes.
+func Solve(ctx context.Context, challenge string, difficulty int, progress func(iterations uint64)) (string, error) {
+	c, err := powhttp.DecodeChallenge(challenge)
+	if err != nil {
+		return "", err
+	}
+
+	worker := powork.NewWorker()
+	if err := worker.SetDifficulty(difficulty); err != nil {
+		return "", err
+	}
+	// a browser tab is not bound by the server's patience
+	worker.SetTimeoutDuration(0)
+	if progress != nil {
+		worker.SetProgressInterval(ProgressInterval)
+		worker.SetProgressCallback(func(iterations uint64, elapsed time.Duration) {
+			progress(iterations)
+		})
+	}
+
+	pow, err := worker.DoProofForChallengeContext(ctx, c, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return powhttp.EncodeProof(pow)
+}
@@ -0,0 +1,60 @@
+package powjs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powhttp"
+)
+
+func TestSolve(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h := powhttp.Handler(next, powhttp.WithDifficulty(16))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	challenge := rec.Header().Get(powhttp.HeaderChallenge)
+	difficulty, _ := strconv.Atoi(rec.Header().Get(powhttp.HeaderDifficulty))
+
+	var calls int
+	proof, err := Solve(context.Background(), challenge, difficulty, func(iterations uint64) {
+		if iterations < uint64(calls+1)*ProgressInterval {
+			t.Fatalf("Progress reported %d iterations at call %d\n", iterations, calls+1)
+		}
+		calls++
+	})
+	if err != nil {
+		t.Fatalf("Error solving: %v\n", err)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(powhttp.HeaderProof, proof)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Middleware answered the solved request with %d\n", rec.Code)
+	}
+}
+
+func TestSolveInvalid(t *testing.T) {
+	if _, err := Solve(context.Background(), "not base64!", 8, nil); !errors.Is(err, powork.ErrInvalidEncoding) {
+		t.Fatalf("Invalid challenge did not fail with ErrInvalidEncoding: %v\n", err)
+	}
+
+	w := powork.NewWorker()
+	c, _ := w.NewChallenge()
+	if _, err := Solve(context.Background(), powhttp.EncodeChallenge(c), 0, nil); !errors.Is(err, powork.ErrInvalidDifficulty) {
+		t.Fatalf("Difficulty 0 did not fail with ErrInvalidDifficulty: %v\n", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Solve(ctx, powhttp.EncodeChallenge(c), 40, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Canceled search returned %v\n", err)
+	}
+}
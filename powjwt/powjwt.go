@@ -0,0 +1,273 @@
+// Package powjwt trades proofs of work for JSON Web Tokens, built on golang-jwt, so that one
+// expensive proof grants a client a short-lived token instead of costing it a proof per
+// request. An Issuer validates a proof and mints a token, either with Exchange or behind the
+// powhttp middleware with TokenHandler; its Handler admits requests that carry a valid token
+// in an "Authorization: Bearer" header.
+//
+// Proofs can also travel inside a JWT, in the "pow" claim of ProofClaims, in the encoding of
+// the X-PoWork header of the powhttp package.
+package powjwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powhttp"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultTTL is how long the tokens of an Issuer are valid unless it is given another TTL
+const DefaultTTL = 15 * time.Minute
+
+// ErrRejected is returned by Issuer.Exchange when the proof is not valid
+var ErrRejected = errors.New("Proof of work rejected")
+
+// Claims are the claims of the tokens an Issuer mints. The subject is the one passed to
+// Exchange, and Difficulty the difficulty of the proof the token was traded for.
+type Claims struct {
+	jwt.RegisteredClaims
+	Difficulty int `json:"pow_difficulty,omitempty"`
+}
+
+// ProofClaims carry a proof of work in the "pow" claim of a JWT
+type ProofClaims struct {
+	jwt.RegisteredClaims
+	Proof string `json:"pow,omitempty"`
+}
+
+// SetProof puts pow in the claims
+func (c *ProofClaims) SetProof(pow *powork.PoWork) error {
+	proof, err := powhttp.EncodeProof(pow)
+	if err != nil {
+		return err
+	}
+
+	c.Proof = proof
+	return nil
+}
+
+// GetProof gets the proof in the claims
+func (c *ProofClaims) GetProof() (*powork.PoWork, error) {
+	if c.Proof == "" {
+		return nil, fmt.Errorf("%w: claims carry no proof", powork.ErrInvalidEncoding)
+	}
+
+	return powhttp.DecodeProof(c.Proof)
+}
+
+// Option configures an Issuer
+type Option func(*Issuer)
+
+// WithTTL sets how long minted tokens are valid. The default is DefaultTTL.
+func WithTTL(d time.Duration) Option {
+	return func(i *Issuer) {
+		i.ttl = d
+	}
+}
+
+// WithIssuer sets the "iss" claim of minted tokens, and requires it of verified tokens
+func WithIssuer(iss string) Option {
+	return func(i *Issuer) {
+		i.issuer = iss
+	}
+}
+
+// WithAudience sets the "aud" claim of minted tokens, and requires it of verified tokens
+func WithAudience(aud string) Option {
+	return func(i *Issuer) {
+		i.audience = aud
+	}
+}
+
+// WithSigningMethod signs tokens with method and signKey and verifies them with verifyKey,
+// replacing HS256 with the key passed to New. Use it for asymmetric methods such as EdDSA, so
+// that services can verify tokens without being able to mint them.
+func WithSigningMethod(method jwt.SigningMethod, signKey, verifyKey any) Option {
+	return func(i *Issuer) {
+		i.method = method
+		i.signKey = signKey
+		i.verifyKey = verifyKey
+	}
+}
+
+// Issuer mints tokens for valid proofs and verifies them. Its methods are safe for
+// concurrent use.
+type Issuer struct {
+	worker    *powork.Worker
+	method    jwt.SigningMethod
+	signKey   any
+	verifyKey any
+	ttl       time.Duration
+	issuer    string
+	audience  string
+}
+
+// New creates an Issuer validating proofs with w and signing tokens with key under HS256
+func New(w *powork.Worker, key []byte, opts ...Option) *Issuer {
+	i := &Issuer{
+		worker:    w,
+		method:    jwt.SigningMethodHS256,
+		signKey:   key,
+		verifyKey: key,
+		ttl:       DefaultTTL,
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+// Exchange validates pow and mints a token for subject, which may be empty. A proof bound to
+// a challenge must answer a challenge the Worker issued, and if subject is not empty must be
+// bound to it; other proofs are checked with ValidateOnce, so the Worker needs a SpentStore
+// to accept them. So does a Worker that signs its challenges, to use them up. It returns the
+// token and when it expires.
+func (i *Issuer) Exchange(ctx context.Context, pow *powork.PoWork, subject string) (string, time.Time, error) {
+	if i.worker.SignsChallenges() && i.worker.GetSpentStore() == nil {
+		return "", time.Time{}, fmt.Errorf("%w: a Worker that signs its challenges needs one to use them up", powork.ErrNoSpentStore)
+	}
+
+	var ok bool
+	var err error
+	switch {
+	case len(pow.GetChallenge()) > 0 && subject != "":
+		ok, err = i.worker.ValidateChallengeSubjectContext(ctx, pow, subject)
+	case len(pow.GetChallenge()) > 0:
+		ok, err = i.worker.ValidateChallengeContext(ctx, pow)
+	default:
+		ok, err = i.worker.ValidateOnce(pow)
+	}
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if !ok {
+		return "", time.Time{}, ErrRejected
+	}
+
+	return i.Mint(subject, pow.GetDifficulty())
+}
+
+// Mint mints a token for subject without a proof, recording difficulty, which may be 0, in
+// its claims. Use it where the proof has already been checked.
+func (i *Issuer) Mint(subject string, difficulty int) (string, time.Time, error) {
+	now := time.Now()
+	expires := now.Add(i.ttl)
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    i.issuer,
+			Subject:   subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+		Difficulty: difficulty,
+	}
+	if i.audience != "" {
+		claims.Audience = jwt.ClaimStrings{i.audience}
+	}
+
+	token, err := jwt.NewWithClaims(i.method, claims).SignedString(i.signKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expires, nil
+}
+
+// Verify checks a token minted by the Issuer and returns its claims
+func (i *Issuer) Verify(token string) (*Claims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{i.method.Alg()}),
+		jwt.WithExpirationRequired(),
+	}
+	if i.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(i.issuer))
+	}
+	if i.audience != "" {
+		opts = append(opts, jwt.WithAudience(i.audience))
+	}
+
+	claims := new(Claims)
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) {
+		return i.verifyKey, nil
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// claimsKey is the context key of the claims of a verified token
+type claimsKey struct{}
+
+// FromContext returns the claims of the token of a request admitted by Handler
+func FromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
+
+// Handler wraps next so that it only receives requests carrying a valid token in an
+// "Authorization: Bearer" header. Other requests get 401 Unauthorized. The token's claims are
+// available to next through FromContext.
+func (i *Issuer) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			unauthorized(w)
+			return
+		}
+
+		claims, err := i.Verify(token)
+		if err != nil {
+			unauthorized(w)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, claims)))
+	})
+}
+
+// TokenResponse is the response body of TokenHandler
+type TokenResponse struct {
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+}
+
+// TokenHandler returns a handler that mints a token for every request it receives, for the
+// subject returned by subject, or without a subject if subject is nil. It checks no proof, so
+// serve it behind the powhttp middleware, with the same subject; the token records the
+// difficulty of the proof the middleware let the request through with, if any:
+//
+//	http.Handle("/token", powhttp.Handler(issuer.TokenHandler(nil), powhttp.WithDifficulty(20)))
+func (i *Issuer) TokenHandler(subject func(*http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var sub string
+		if subject != nil {
+			sub = subject(r)
+		}
+
+		var difficulty int
+		if v, ok := powhttp.VerifiedFromContext(r.Context()); ok {
+			difficulty = v.Proof.GetDifficulty()
+		}
+
+		token, expires, err := i.Mint(sub, difficulty)
+		if err != nil {
+			http.Error(w, "could not mint token", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(TokenResponse{Token: token, Expires: expires})
+	})
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	http.Error(w, "valid token required", http.StatusUnauthorized)
+}
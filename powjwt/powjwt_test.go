@@ -0,0 +1,170 @@
+package powjwt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powhttp"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var key = []byte("0123456789abcdef0123456789abcdef")
+
+func TestExchange(t *testing.T) {
+	w := powork.NewWorker()
+	w.SetDifficulty(8)
+	issuer := New(w, key, WithIssuer("powork"), WithAudience("api"), WithTTL(time.Minute))
+
+	c, _ := w.NewChallenge()
+	pow, _ := w.DoProofForChallengeSubject(c, nil, "client-1")
+	token, expires, err := issuer.Exchange(context.Background(), pow, "client-1")
+	if err != nil {
+		t.Fatalf("Error exchanging proof: %v\n", err)
+	}
+	if time.Until(expires) > time.Minute || time.Until(expires) < 50*time.Second {
+		t.Fatalf("Token expires at %v\n", expires)
+	}
+
+	claims, err := issuer.Verify(token)
+	if err != nil || claims.Subject != "client-1" || claims.Difficulty != 8 {
+		t.Fatalf("Token has claims %+v: %v\n", claims, err)
+	}
+
+	// the challenge is used up
+	if _, _, err := issuer.Exchange(context.Background(), pow, "client-1"); !errors.Is(err, ErrRejected) {
+		t.Fatalf("Second exchange of a proof returned %v\n", err)
+	}
+
+	// a proof without a challenge needs a spent store
+	plain, _ := w.DoProofForString("no challenge")
+	if _, _, err := issuer.Exchange(context.Background(), plain, ""); !errors.Is(err, powork.ErrNoSpentStore) {
+		t.Fatalf("Exchange without a spent store returned %v\n", err)
+	}
+
+	other := New(w, key, WithIssuer("someone else"))
+	if _, err := other.Verify(token); err == nil {
+		t.Fatalf("Token verified for another issuer\n")
+	}
+	expired := New(w, key, WithTTL(-time.Second))
+	token, _, _ = expired.Mint("client-1", 0)
+	if _, err := expired.Verify(token); !errors.Is(err, jwt.ErrTokenExpired) {
+		t.Fatalf("Expired token returned %v\n", err)
+	}
+}
+
+func TestExchangeSigned(t *testing.T) {
+	w := powork.NewWorker()
+	w.SetDifficulty(8)
+	w.SetChallengeKey([]byte("a challenge signing key"))
+	issuer := New(w, key)
+
+	c, _ := w.NewChallenge()
+	pow, _ := w.DoProofForChallenge(c, nil)
+	if _, _, err := issuer.Exchange(context.Background(), pow, ""); !errors.Is(err, powork.ErrNoSpentStore) {
+		t.Fatalf("Exchange of a signed challenge without a spent store returned %v\n", err)
+	}
+
+	// with one, a proof is traded for a token once
+	w.SetSpentStore(powork.NewMemorySpentStore(100), time.Minute)
+	if _, _, err := issuer.Exchange(context.Background(), pow, ""); err != nil {
+		t.Fatalf("Error exchanging proof: %v\n", err)
+	}
+	if _, _, err := issuer.Exchange(context.Background(), pow, ""); !errors.Is(err, ErrRejected) {
+		t.Fatalf("Second exchange of a proof returned %v\n", err)
+	}
+}
+
+func TestSigningMethod(t *testing.T) {
+	public, private, _ := ed25519.GenerateKey(nil)
+	issuer := New(powork.NewWorker(), nil, WithSigningMethod(jwt.SigningMethodEdDSA, private, public))
+
+	token, _, err := issuer.Mint("client", 0)
+	if err != nil {
+		t.Fatalf("Error minting token: %v\n", err)
+	}
+	if _, err := issuer.Verify(token); err != nil {
+		t.Fatalf("Error verifying token: %v\n", err)
+	}
+
+	// a token signed with HS256 does not pass for one signed with EdDSA
+	forged, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{}).SignedString(key)
+	if _, err := issuer.Verify(forged); err == nil {
+		t.Fatalf("Token with another signing method verified\n")
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	w := powork.NewWorker()
+	w.SetDifficulty(8)
+	issuer := New(w, key)
+
+	api := issuer.Handler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		claims, ok := FromContext(r.Context())
+		if !ok {
+			t.Fatalf("Handler has no claims\n")
+		}
+		rw.Write([]byte(claims.Subject))
+	}))
+	tokens := powhttp.Handler(issuer.TokenHandler(func(r *http.Request) string { return "me" }), powhttp.WithWorker(w))
+
+	srv := httptest.NewServer(tokens)
+	defer srv.Close()
+	client := &http.Client{Transport: &powhttp.Transport{}}
+	resp, err := client.Post(srv.URL, "", nil)
+	if err != nil {
+		t.Fatalf("Error getting token: %v\n", err)
+	}
+	var tr TokenResponse
+	json.NewDecoder(resp.Body).Decode(&tr)
+	resp.Body.Close()
+
+	if claims, err := issuer.Verify(tr.Token); err != nil || claims.Difficulty != 8 {
+		t.Fatalf("Token from the token handler has claims %+v: %v\n", claims, err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+tr.Token)
+	api.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "me" {
+		t.Fatalf("Request with a token got %d %q\n", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	api.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") != "Bearer" {
+		t.Fatalf("Request without a token got %d\n", rec.Code)
+	}
+}
+
+func TestProofClaims(t *testing.T) {
+	w := powork.NewWorker()
+	w.SetDifficulty(8)
+	pow, _ := w.DoProofForString("in a token")
+
+	var claims ProofClaims
+	if _, err := claims.GetProof(); !errors.Is(err, powork.ErrInvalidEncoding) {
+		t.Fatalf("Empty claims returned %v\n", err)
+	}
+	claims.SetProof(pow)
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+
+	parsed := new(ProofClaims)
+	if _, err := jwt.ParseWithClaims(token, parsed, func(*jwt.Token) (any, error) { return key, nil }); err != nil {
+		t.Fatalf("Error parsing token: %v\n", err)
+	}
+	got, err := parsed.GetProof()
+	if err != nil {
+		t.Fatalf("Error getting proof: %v\n", err)
+	}
+	if ok, _ := w.ValidatePoWork(got); !ok || got.GetMessageString() != "in a token" {
+		t.Fatalf("Proof from the token is not valid\n")
+	}
+}
@@ -0,0 +1,115 @@
+// Package powkafka stamps Kafka messages produced with Sarama with a proof of work and checks
+// the stamps of consumed messages, as described in package powmq. The interceptors plug into
+// the client's config:
+//
+//	config := sarama.NewConfig()
+//	config.Producer.Interceptors = []sarama.ProducerInterceptor{&powkafka.Stamper{Worker: worker}}
+//	config.Consumer.Interceptors = []sarama.ConsumerInterceptor{&powkafka.Checker{Verifier: powmq.NewVerifier(worker)}}
+//
+// Consumers then read the verdict with Difficulty, and drop or deprioritize the messages
+// whose stamp achieves too little. A stamp is bound to the topic a message is produced to.
+package powkafka
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/IBM/sarama"
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powmq"
+)
+
+// HeaderVerified is the header in which a Checker records the difficulty a consumed message's
+// stamp achieves. Copies of it sent by producers are removed.
+const HeaderVerified = "X-PoWork-Verified"
+
+// Stamper is a sarama.ProducerInterceptor that stamps messages as they are sent. Sarama runs
+// interceptors on the way into the producer, so each stamp holds up the messages behind it;
+// keep the difficulty low enough for the producer's throughput.
+type Stamper struct {
+	Worker *powork.Worker
+
+	// OnError is called with the messages that could not be stamped, which are sent without
+	// a stamp. If nil, they are sent silently.
+	OnError func(msg *sarama.ProducerMessage, err error)
+}
+
+// OnSend implements sarama.ProducerInterceptor
+func (s *Stamper) OnSend(msg *sarama.ProducerMessage) {
+	var data []byte
+	if msg.Value != nil {
+		var err error
+		if data, err = msg.Value.Encode(); err != nil {
+			s.fail(msg, err)
+			return
+		}
+	}
+
+	stamp, err := powmq.Stamp(context.Background(), s.Worker, msg.Topic, data)
+	if err != nil {
+		s.fail(msg, err)
+		return
+	}
+
+	msg.Headers = append(removeHeader(msg.Headers, powmq.Header),
+		sarama.RecordHeader{Key: []byte(powmq.Header), Value: []byte(stamp)})
+}
+
+func (s *Stamper) fail(msg *sarama.ProducerMessage, err error) {
+	if s.OnError != nil {
+		s.OnError(msg, err)
+	}
+}
+
+// Checker is a sarama.ConsumerInterceptor that checks the stamps of consumed messages and
+// records the verdict for Difficulty
+type Checker struct {
+	Verifier *powmq.Verifier
+}
+
+// OnConsume implements sarama.ConsumerInterceptor
+func (c *Checker) OnConsume(msg *sarama.ConsumerMessage) {
+	d := Verify(c.Verifier, msg)
+
+	headers := msg.Headers[:0]
+	for _, h := range msg.Headers {
+		if h != nil && string(h.Key) != HeaderVerified {
+			headers = append(headers, h)
+		}
+	}
+	msg.Headers = append(headers, &sarama.RecordHeader{Key: []byte(HeaderVerified), Value: []byte(strconv.Itoa(d))})
+}
+
+// Verify returns the difficulty the stamp of msg achieves, or 0 if it carries no valid stamp
+func Verify(v *powmq.Verifier, msg *sarama.ConsumerMessage) int {
+	var stamp string
+	for _, h := range msg.Headers {
+		if h != nil && string(h.Key) == powmq.Header {
+			stamp = string(h.Value)
+		}
+	}
+	return v.Verify(msg.Topic, msg.Value, stamp)
+}
+
+// Difficulty returns the difficulty a Checker has found the stamp of msg to achieve, or 0
+// if it carries no valid stamp or no Checker has seen it
+func Difficulty(msg *sarama.ConsumerMessage) int {
+	for _, h := range msg.Headers {
+		if h != nil && string(h.Key) == HeaderVerified {
+			d, _ := strconv.Atoi(string(h.Value))
+			return d
+		}
+	}
+	return 0
+}
+
+// removeHeader returns headers without those named key
+func removeHeader(headers []sarama.RecordHeader, key string) []sarama.RecordHeader {
+	kept := headers[:0]
+	for _, h := range headers {
+		if string(h.Key) != key {
+			kept = append(kept, h)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,70 @@
+package powkafka
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powmq"
+)
+
+// consumed turns a produced message into the message its consumer gets
+func consumed(msg *sarama.ProducerMessage) *sarama.ConsumerMessage {
+	c := &sarama.ConsumerMessage{Topic: msg.Topic}
+	c.Value, _ = msg.Value.Encode()
+	for _, h := range msg.Headers {
+		c.Headers = append(c.Headers, &sarama.RecordHeader{Key: h.Key, Value: h.Value})
+	}
+	return c
+}
+
+func TestInterceptors(t *testing.T) {
+	w := powork.NewWorker()
+	w.SetDifficulty(8)
+
+	msg := &sarama.ProducerMessage{Topic: "orders", Value: sarama.StringEncoder("an order")}
+	(&Stamper{Worker: w}).OnSend(msg)
+
+	checker := &Checker{Verifier: powmq.NewVerifier(w)}
+	c := consumed(msg)
+	checker.OnConsume(c)
+	if Difficulty(c) < 8 {
+		t.Fatalf("Stamped message checked at difficulty %d\n", Difficulty(c))
+	}
+
+	// a forged verdict is replaced
+	forged := &sarama.ConsumerMessage{Topic: "orders", Value: []byte("spam"), Headers: []*sarama.RecordHeader{
+		{Key: []byte(HeaderVerified), Value: []byte("64")},
+	}}
+	checker.OnConsume(forged)
+	if Difficulty(forged) != 0 || len(forged.Headers) != 1 {
+		t.Fatalf("Forged message checked at difficulty %d\n", Difficulty(forged))
+	}
+
+	// a stamp is bound to its topic
+	c = consumed(msg)
+	c.Topic = "invoices"
+	if d := Verify(powmq.NewVerifier(w), c); d != 0 {
+		t.Fatalf("Stamp verified on another topic at difficulty %d\n", d)
+	}
+}
+
+// failingEncoder is a message value that can't be encoded
+type failingEncoder struct{}
+
+func (failingEncoder) Encode() ([]byte, error) { return nil, errors.New("no value") }
+func (failingEncoder) Length() int             { return 0 }
+
+func TestStamperError(t *testing.T) {
+	var failed error
+	s := &Stamper{Worker: powork.NewWorker(), OnError: func(msg *sarama.ProducerMessage, err error) {
+		failed = err
+	}}
+
+	msg := &sarama.ProducerMessage{Topic: "orders", Value: failingEncoder{}}
+	s.OnSend(msg)
+	if failed == nil || len(msg.Headers) != 0 {
+		t.Fatalf("Message that can't be encoded was stamped\n")
+	}
+}
@@ -0,0 +1,207 @@
+// Package powmail brings hashcash anti-spam to mail systems. Senders add an X-Hashcash header
+// for every recipient of an outgoing message, holding a stamp bound to the recipient's
+// address and the day it was minted. Receivers accept a message for a recipient when it
+// carries a valid stamp for them that hasn't been spent on another message.
+//
+// Stamps are hashcash version 1, minted with powork.Worker.MintHashcash, so they interoperate
+// with other hashcash implementations.
+package powmail
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+// Header is the name of the header carrying a stamp
+const Header = "X-Hashcash"
+
+// SpentTTL is how long a spent stamp has to stay recorded: as long as it could still be accepted
+const SpentTTL = powork.HashcashValidity + powork.HashcashGrace
+
+// recipientHeaders are the headers whose addresses get a stamp
+var recipientHeaders = []string{"To", "Cc", "Bcc"}
+
+// Mint mints a stamp for each recipient in h's To, Cc and Bcc headers with w, and returns the
+// values of the X-Hashcash headers to add to the message
+func Mint(ctx context.Context, w *powork.Worker, h mail.Header) ([]string, error) {
+	var stamps []string
+	seen := make(map[string]bool)
+	for _, name := range recipientHeaders {
+		addrs, err := h.AddressList(name)
+		if errors.Is(err, mail.ErrHeaderNotPresent) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %s header: %v", powork.ErrInvalidStamp, name, err)
+		}
+
+		for _, addr := range addrs {
+			resource := Resource(addr.Address)
+			if seen[resource] {
+				continue
+			}
+			seen[resource] = true
+
+			stamp, err := w.MintHashcashContext(ctx, resource, "")
+			if err != nil {
+				return nil, err
+			}
+			stamps = append(stamps, stamp)
+		}
+	}
+
+	return stamps, nil
+}
+
+// Sign mints stamps for the recipients of the raw message msg, as Mint does, and returns the
+// message with X-Hashcash headers in front, ready to be sent over SMTP
+func Sign(ctx context.Context, w *powork.Worker, msg []byte) ([]byte, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(msg))
+	if err != nil {
+		return nil, err
+	}
+
+	stamps, err := Mint(ctx, w, m.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	for _, stamp := range stamps {
+		fmt.Fprintf(&b, "%s: %s\r\n", Header, stamp)
+	}
+	b.Write(msg)
+	return b.Bytes(), nil
+}
+
+// Resource returns the resource of the stamps for an address: the address in lower case
+func Resource(address string) string {
+	return strings.ToLower(address)
+}
+
+// A Store records spent stamps, so that a stamp is only accepted once
+type Store interface {
+	// Spend records stamp for ttl. It reports whether stamp was already recorded, checking
+	// and recording atomically so that concurrent callers can't both spend the same stamp.
+	Spend(stamp string, ttl time.Duration) (bool, error)
+}
+
+// Verifier checks the stamps of inbound mail. Its methods are safe for concurrent use if its
+// Store's are.
+type Verifier struct {
+	worker *powork.Worker
+	store  Store
+}
+
+// NewVerifier creates a Verifier that checks stamps with w, demanding its difficulty, and
+// records them in store. A nil store disables double-spend detection.
+func NewVerifier(w *powork.Worker, store Store) *Verifier {
+	return &Verifier{worker: w, store: store}
+}
+
+// Verify reports whether h carries a valid, unspent stamp for recipient, and spends it
+func (v *Verifier) Verify(h mail.Header, recipient string) (bool, error) {
+	resource := Resource(recipient)
+	for _, value := range h[Header] {
+		stamp := strings.TrimSpace(value)
+		ok, err := v.worker.ValidateHashcash(stamp, resource)
+		if err != nil || !ok {
+			// a malformed or foreign stamp doesn't spoil the others
+			continue
+		}
+
+		if v.store == nil {
+			return true, nil
+		}
+
+		spent, err := v.store.Spend(stamp, SpentTTL)
+		if err != nil {
+			return false, err
+		}
+		if !spent {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// VerifyMessage does the same thing as Verify for the headers of the raw message read from r
+func (v *Verifier) VerifyMessage(r io.Reader, recipient string) (bool, error) {
+	m, err := mail.ReadMessage(bufio.NewReader(r))
+	if err != nil {
+		return false, err
+	}
+
+	return v.Verify(m.Header, recipient)
+}
+
+// MemoryStore is an in-memory LRU Store. When full it forgets the least recently spent stamp,
+// so its capacity should comfortably exceed the number of stamps accepted per SpentTTL.
+type MemoryStore struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type spentStamp struct {
+	stamp   string
+	expires time.Time
+}
+
+// NewMemoryStore creates a MemoryStore holding at most capacity stamps
+func NewMemoryStore(capacity int) *MemoryStore {
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	return &MemoryStore{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Spend implements Store
+func (s *MemoryStore) Spend(stamp string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[stamp]; ok {
+		if !now.After(e.Value.(*spentStamp).expires) {
+			s.order.MoveToFront(e)
+			return true, nil
+		}
+		s.order.Remove(e)
+		delete(s.entries, stamp)
+	}
+
+	for s.order.Len() >= s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*spentStamp).stamp)
+	}
+
+	s.entries[stamp] = s.order.PushFront(&spentStamp{stamp: stamp, expires: now.Add(ttl)})
+	return false, nil
+}
+
+// Len returns the number of stamps the store currently holds, including expired ones not yet dropped
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
@@ -0,0 +1,100 @@
+package powmail
+
+import (
+	"bytes"
+	"context"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/Zumium/powork"
+)
+
+const message = "From: alice@example.com\r\n" +
+	"To: Bob <Bob@Example.com>, carol@example.org\r\n" +
+	"Cc: bob@example.com\r\n" +
+	"Subject: hello\r\n" +
+	"\r\n" +
+	"Hi!\r\n"
+
+func TestSignVerify(t *testing.T) {
+	w := powork.NewWorker()
+	w.SetDifficulty(10)
+
+	signed, err := Sign(context.Background(), w, []byte(message))
+	if err != nil {
+		t.Fatalf("Error signing message: %v\n", err)
+	}
+	if !bytes.HasSuffix(signed, []byte(message)) {
+		t.Fatalf("Signing changed the message\n")
+	}
+
+	m, _ := mail.ReadMessage(bytes.NewReader(signed))
+	if stamps := m.Header[Header]; len(stamps) != 2 {
+		t.Fatalf("Signed message has %d stamps, expected one per recipient\n", len(stamps))
+	}
+
+	v := NewVerifier(w, NewMemoryStore(100))
+	for _, recipient := range []string{"bob@example.com", "carol@example.org"} {
+		if ok, err := v.VerifyMessage(bytes.NewReader(signed), recipient); !ok || err != nil {
+			t.Fatalf("Message has no valid stamp for %s: %v\n", recipient, err)
+		}
+	}
+
+	// the stamps are spent
+	if ok, _ := v.Verify(m.Header, "bob@example.com"); ok {
+		t.Fatalf("Spent stamp is valid again\n")
+	}
+
+	if ok, _ := v.Verify(m.Header, "dave@example.net"); ok {
+		t.Fatalf("Message has a stamp for a recipient it was not signed for\n")
+	}
+}
+
+func TestVerifyDifficulty(t *testing.T) {
+	w := powork.NewWorker()
+	w.SetDifficulty(4)
+	stamps, err := Mint(context.Background(), w, mail.Header{"To": {"bob@example.com"}})
+	if err != nil || len(stamps) != 1 {
+		t.Fatalf("Error minting stamps: %v\n", err)
+	}
+
+	// among garbage and a stamp below the receiver's difficulty
+	h := mail.Header{Header: {"garbage", stamps[0]}}
+	strict := powork.NewWorker()
+	strict.SetDifficulty(20)
+	if ok, _ := NewVerifier(strict, nil).Verify(h, "bob@example.com"); ok {
+		t.Fatalf("Stamp below the difficulty is valid\n")
+	}
+
+	// without a store stamps can be reused
+	v := NewVerifier(w, nil)
+	for range 2 {
+		if ok, err := v.Verify(h, "BOB@example.com"); !ok || err != nil {
+			t.Fatalf("Stamp is not valid without a store: %v\n", err)
+		}
+	}
+
+	if _, err := Mint(context.Background(), w, mail.Header{"To": {"not an address"}}); err == nil || !strings.Contains(err.Error(), "To") {
+		t.Fatalf("Minting for a bad address returned %v\n", err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore(2)
+	for _, stamp := range []string{"a", "b", "c"} {
+		if spent, _ := s.Spend(stamp, SpentTTL); spent {
+			t.Fatalf("Stamp %s is spent before it was\n", stamp)
+		}
+	}
+
+	if s.Len() != 2 {
+		t.Fatalf("Store holds %d stamps, expected its capacity of 2\n", s.Len())
+	}
+	if spent, _ := s.Spend("c", SpentTTL); !spent {
+		t.Fatalf("Spent stamp is not recorded\n")
+	}
+	if spent, _ := s.Spend("a", SpentTTL); spent {
+		t.Fatalf("Least recently spent stamp was not forgotten\n")
+	}
+}
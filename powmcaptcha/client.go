@@ -0,0 +1,102 @@
+package powmcaptcha
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Zumium/powork"
+)
+
+// Client solves the challenges of an mCaptcha server, the powmcaptcha Server or mCaptcha's
+// own. Its methods are safe for concurrent use.
+type Client struct {
+	// URL is the base URL of the server, such as "https://captcha.example.com"
+	URL string
+
+	// HTTPClient makes the requests. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// MaxDifficultyFactor is the highest difficulty factor the Client agrees to solve. Zero
+	// means no limit.
+	MaxDifficultyFactor uint32
+}
+
+// Token fetches a challenge for the site with the given key, solves it, and returns the token
+// the server gives for it. The site's form submits the token to the site's backend.
+func (c *Client) Token(ctx context.Context, key string) (string, error) {
+	var config Config
+	if err := c.call(ctx, PathConfig, configRequest{Key: key}, &config); err != nil {
+		return "", err
+	}
+
+	if c.MaxDifficultyFactor > 0 && config.DifficultyFactor > c.MaxDifficultyFactor {
+		return "", fmt.Errorf("%w: difficulty factor %d is above the limit of %d", powork.ErrInvalidDifficulty, config.DifficultyFactor, c.MaxDifficultyFactor)
+	}
+
+	work, err := Solve(ctx, config)
+	if err != nil {
+		return "", err
+	}
+	work.Key = key
+
+	var resp tokenResponse
+	if err := c.call(ctx, PathVerify, work, &resp); err != nil {
+		return "", err
+	}
+	return resp.Token, nil
+}
+
+// SiteVerify asks the server whether token was issued for the site with the given key, as a
+// site's backend does. The token is used up.
+func (c *Client) SiteVerify(ctx context.Context, key, secret, token string) (bool, error) {
+	var resp siteVerifyResponse
+	if err := c.call(ctx, PathSiteVerify, siteVerifyRequest{Key: key, Token: token, Secret: secret}, &resp); err != nil {
+		return false, err
+	}
+	return resp.Valid, nil
+}
+
+// call posts req to the endpoint at path and decodes the response into resp
+func (c *Client) call(ctx context.Context, path string, req, resp any) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(c.URL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/json")
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxRequestSize))
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		var e errorResponse
+		if json.Unmarshal(data, &e) == nil && e.Error != "" {
+			return fmt.Errorf("mcaptcha: %s: %s", res.Status, e.Error)
+		}
+		return fmt.Errorf("mcaptcha: %s", res.Status)
+	}
+
+	return json.Unmarshal(data, resp)
+}
@@ -0,0 +1,162 @@
+// Package powmcaptcha speaks the protocol of mCaptcha (https://mcaptcha.org), so that powork
+// can serve as a self-hosted backend for existing mCaptcha widgets, and so that Go programs
+// can solve mCaptcha challenges.
+//
+// The protocol has three JSON endpoints:
+//
+//	POST /api/v1/pow/config      {"key":"<sitekey>"}                                → Config
+//	POST /api/v1/pow/verify      {"key":...,"string":...,"nonce":...,"result":...}  → {"token":"..."}
+//	POST /api/v1/pow/siteverify  {"key":...,"token":...,"secret":...}               → {"valid":true}
+//
+// The widget fetches a Config for its site key, solves it and trades the proof for a token,
+// which the site's backend then checks with siteverify, or in process with
+// Server.VerifyToken. Challenge strings and tokens are single use.
+//
+// mCaptcha's proof of work is its own scheme rather than the one of powork.Worker: a nonce is
+// a solution when the first 16 bytes of
+//
+//	SHA-256(salt || uint64 len(string), little-endian || string || uint64 nonce, big-endian)
+//
+// read as a big-endian integer, are at least 2¹²⁸-1 - (2¹²⁸-1)/difficultyFactor. The
+// difficulty factor is the expected number of hashes; DifficultyFactor converts a
+// difficulty in bits.
+package powmcaptcha
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"math"
+	"math/big"
+	"math/bits"
+
+	"github.com/Zumium/powork"
+)
+
+// Paths of the protocol's endpoints
+const (
+	PathConfig     = "/api/v1/pow/config"
+	PathVerify     = "/api/v1/pow/verify"
+	PathSiteVerify = "/api/v1/pow/siteverify"
+)
+
+// checkInterval is how many nonces Solve tries between checks of its context
+const checkInterval = 1 << 12
+
+// ErrUnknownSite is returned for site keys the Server has no site for
+var ErrUnknownSite = errors.New("Unknown site key")
+
+// Config is a challenge, as returned by PathConfig
+type Config struct {
+	String           string `json:"string"`
+	DifficultyFactor uint32 `json:"difficulty_factor"`
+	Salt             string `json:"salt"`
+}
+
+// Work is a solved challenge, as sent to PathVerify
+type Work struct {
+	Key    string `json:"key"`
+	String string `json:"string"`
+	Nonce  uint64 `json:"nonce"`
+	Result string `json:"result"`
+
+	// Time and WorkerType are reported by the widget and ignored
+	Time       uint32 `json:"time,omitempty"`
+	WorkerType string `json:"worker_type,omitempty"`
+}
+
+// DifficultyFactor returns the difficulty factor that takes as much work as difficulty
+// leading zero bits
+func DifficultyFactor(difficulty int) (uint32, error) {
+	if difficulty < 1 || difficulty > 32 {
+		return 0, fmt.Errorf("%w: must be between 1 and 32 bits", powork.ErrInvalidDifficulty)
+	}
+	return uint32(min(uint64(1)<<difficulty, math.MaxUint32)), nil
+}
+
+// score is a 128-bit score, as its high and low halves
+type score struct{ hi, lo uint64 }
+
+// less reports whether s is less than t
+func (s score) less(t score) bool {
+	return s.hi < t.hi || (s.hi == t.hi && s.lo < t.lo)
+}
+
+// String formats the score in decimal, as mCaptcha sends it
+func (s score) String() string {
+	n := new(big.Int).SetUint64(s.hi)
+	n.Lsh(n, 64)
+	return n.Or(n, new(big.Int).SetUint64(s.lo)).String()
+}
+
+// target returns the lowest score that solves a challenge of the difficulty factor
+func target(factor uint32) score {
+	if factor == 0 {
+		factor = 1
+	}
+
+	// max - max/factor
+	qhi, r := bits.Div64(0, math.MaxUint64, uint64(factor))
+	qlo, _ := bits.Div64(r, math.MaxUint64, uint64(factor))
+	lo, borrow := bits.Sub64(math.MaxUint64, qlo, 0)
+	hi, _ := bits.Sub64(math.MaxUint64, qhi, borrow)
+	return score{hi, lo}
+}
+
+// prefix returns a SHA-256 state that has hashed everything before the nonce
+func prefix(salt, s string) hash.Hash {
+	h := sha256.New()
+	h.Write([]byte(salt))
+	h.Write(binary.LittleEndian.AppendUint64(nil, uint64(len(s))))
+	h.Write([]byte(s))
+	return h
+}
+
+// scoreOf computes the score of nonce with h, which has hashed the prefix, using sum as scratch space
+func scoreOf(h hash.Hash, nonce uint64, sum []byte) score {
+	h.Write(binary.BigEndian.AppendUint64(nil, nonce))
+	sum = h.Sum(sum[:0])
+	return score{binary.BigEndian.Uint64(sum), binary.BigEndian.Uint64(sum[8:])}
+}
+
+// Solve finds the first nonce, counting from 1 as mCaptcha does, that solves c
+func Solve(ctx context.Context, c Config) (Work, error) {
+	if c.DifficultyFactor == 0 {
+		return Work{}, fmt.Errorf("%w: difficulty factor must be at least 1", powork.ErrInvalidDifficulty)
+	}
+
+	// every nonce starts from the hashed prefix
+	state, err := prefix(c.Salt, c.String).(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return Work{}, err
+	}
+
+	want := target(c.DifficultyFactor)
+	h := sha256.New()
+	restore := h.(encoding.BinaryUnmarshaler)
+	sum := make([]byte, 0, sha256.Size)
+
+	for nonce := uint64(1); ; nonce++ {
+		if nonce%checkInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return Work{}, err
+			}
+		}
+
+		restore.UnmarshalBinary(state)
+		if s := scoreOf(h, nonce, sum); !s.less(want) {
+			return Work{String: c.String, Nonce: nonce, Result: s.String()}, nil
+		}
+	}
+}
+
+// Check reports whether w solves a challenge of the given salt and difficulty factor, and
+// whether its result is the score mCaptcha would compute for it
+func Check(salt string, factor uint32, w Work) bool {
+	s := scoreOf(prefix(salt, w.String), w.Nonce, nil)
+	return !s.less(target(factor)) && s.String() == w.Result
+}
@@ -0,0 +1,133 @@
+package powmcaptcha
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+func TestTarget(t *testing.T) {
+	if got := target(1); got != (score{}) {
+		t.Fatalf("Target of factor 1 is %v, expected 0\n", got)
+	}
+
+	// 2¹²⁸-1 - (2¹²⁸-1)/2 = 2¹²⁷
+	if got := target(2); got.String() != "170141183460469231731687303715884105728" {
+		t.Fatalf("Target of factor 2 is %v, expected 2^127\n", got)
+	}
+
+	if got := (score{1, 0}).String(); got != "18446744073709551616" {
+		t.Fatalf("2^64 formats as %s\n", got)
+	}
+}
+
+func TestSolveCheck(t *testing.T) {
+	c := Config{String: "a challenge", DifficultyFactor: 5000, Salt: "a salt of the server"}
+	w, err := Solve(context.Background(), c)
+	if err != nil {
+		t.Fatalf("Error solving: %v\n", err)
+	}
+
+	if !Check(c.Salt, c.DifficultyFactor, w) {
+		t.Fatalf("Solution does not check out\n")
+	}
+
+	// the first solution from nonce 1 on, so no smaller nonce works
+	for nonce := uint64(1); nonce < w.Nonce; nonce++ {
+		if s := scoreOf(prefix(c.Salt, c.String), nonce, nil); !s.less(target(c.DifficultyFactor)) {
+			t.Fatalf("Nonce %d solves the challenge before %d\n", nonce, w.Nonce)
+		}
+	}
+
+	bad := w
+	bad.Result = "1"
+	if Check(c.Salt, c.DifficultyFactor, bad) {
+		t.Fatalf("Solution with a wrong result checks out\n")
+	}
+	if Check("another salt", c.DifficultyFactor, w) {
+		t.Fatalf("Solution checks out under another salt\n")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Solve(ctx, Config{String: "hard", DifficultyFactor: 1 << 31}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Canceled solve returned %v\n", err)
+	}
+}
+
+func TestServer(t *testing.T) {
+	s := NewServer("account secret")
+	factor, _ := DifficultyFactor(8)
+	s.AddSite("sitekey", factor)
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	client := &Client{URL: srv.URL, HTTPClient: srv.Client()}
+	ctx := context.Background()
+
+	token, err := client.Token(ctx, "sitekey")
+	if err != nil {
+		t.Fatalf("Error getting token: %v\n", err)
+	}
+
+	if _, err := client.SiteVerify(ctx, "sitekey", "wrong secret", token); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("Site verify with the wrong secret returned %v\n", err)
+	}
+	if ok, err := client.SiteVerify(ctx, "sitekey", "account secret", token); !ok || err != nil {
+		t.Fatalf("Token is not valid: %v\n", err)
+	}
+	if ok, _ := client.SiteVerify(ctx, "sitekey", "account secret", token); ok {
+		t.Fatalf("Used token is valid again\n")
+	}
+
+	if _, err := client.Token(ctx, "unknown"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("Token for an unknown site returned %v\n", err)
+	}
+
+	client.MaxDifficultyFactor = 16
+	if _, err := client.Token(ctx, "sitekey"); !errors.Is(err, powork.ErrInvalidDifficulty) {
+		t.Fatalf("Token above the maximum difficulty returned %v\n", err)
+	}
+}
+
+func TestServerChallenges(t *testing.T) {
+	s := NewServer("secret", WithCapacity(1), WithTTL(time.Minute))
+	s.AddSite("a", 100)
+	s.AddSite("b", 100)
+
+	c, err := s.NewConfig("a")
+	if err != nil {
+		t.Fatalf("Error issuing config: %v\n", err)
+	}
+	if _, err := s.NewConfig("a"); !errors.Is(err, ErrBusy) {
+		t.Fatalf("Config beyond the capacity returned %v\n", err)
+	}
+
+	w, _ := Solve(context.Background(), c)
+
+	// work for another site's challenge uses the challenge up
+	w.Key = "b"
+	if _, ok, _ := s.Verify(w); ok {
+		t.Fatalf("Work for another site is valid\n")
+	}
+	w.Key = "a"
+	if _, ok, _ := s.Verify(w); ok {
+		t.Fatalf("Work for a used challenge is valid\n")
+	}
+
+	c, _ = s.NewConfig("a")
+	w, _ = Solve(context.Background(), c)
+	w.Key = "a"
+	token, ok, err := s.Verify(w)
+	if !ok || err != nil {
+		t.Fatalf("Work is not valid: %v\n", err)
+	}
+	if s.VerifyToken("b", token) {
+		t.Fatalf("Token is valid for another site\n")
+	}
+}
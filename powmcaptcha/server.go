@@ -0,0 +1,305 @@
+package powmcaptcha
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+// DefaultTTL is how long challenge strings and tokens can be used, unless another TTL is set
+// with WithTTL
+const DefaultTTL = 5 * time.Minute
+
+// DefaultCapacity is how many challenge strings and tokens a Server keeps outstanding,
+// unless another capacity is set with WithCapacity
+const DefaultCapacity = 100000
+
+// maxRequestSize bounds the size of request bodies
+const maxRequestSize = 1 << 16
+
+// ErrBusy is returned when a Server has as many challenges or tokens outstanding as it can hold
+var ErrBusy = errors.New("Too many outstanding challenges")
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithSalt sets the salt of the challenges. By default a random salt is chosen, so that
+// the salt changes when the Server restarts.
+func WithSalt(salt string) Option {
+	return func(s *Server) {
+		s.salt = salt
+	}
+}
+
+// WithTTL sets how long challenge strings and tokens can be used
+func WithTTL(d time.Duration) Option {
+	return func(s *Server) {
+		s.ttl = d
+	}
+}
+
+// WithCapacity sets how many challenge strings, and separately how many tokens, the Server
+// keeps outstanding. Requests beyond it fail with ErrBusy until some expire or are used.
+func WithCapacity(n int) Option {
+	return func(s *Server) {
+		s.capacity = n
+	}
+}
+
+// Server is an mCaptcha backend, and the http.Handler of its endpoints. Its methods are safe
+// for concurrent use.
+type Server struct {
+	secret   string
+	salt     string
+	ttl      time.Duration
+	capacity int
+
+	mu         sync.Mutex
+	sites      map[string]uint32
+	challenges map[string]outstanding
+	tokens     map[string]outstanding
+}
+
+// outstanding is an issued challenge string or token
+type outstanding struct {
+	key     string
+	factor  uint32
+	expires time.Time
+}
+
+// NewServer creates a Server whose siteverify endpoint demands secret
+func NewServer(secret string, opts ...Option) *Server {
+	s := &Server{
+		secret:     secret,
+		ttl:        DefaultTTL,
+		capacity:   DefaultCapacity,
+		sites:      make(map[string]uint32),
+		challenges: make(map[string]outstanding),
+		tokens:     make(map[string]outstanding),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.salt == "" {
+		s.salt = randomString()
+	}
+	return s
+}
+
+// AddSite adds a site, or changes its difficulty factor
+func (s *Server) AddSite(key string, factor uint32) error {
+	if key == "" {
+		return fmt.Errorf("%w: site key must not be empty", powork.ErrInvalidSetting)
+	}
+	if factor == 0 {
+		return fmt.Errorf("%w: difficulty factor must be at least 1", powork.ErrInvalidDifficulty)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sites[key] = factor
+	return nil
+}
+
+// RemoveSite removes a site. Its outstanding challenges and tokens are no longer accepted.
+func (s *Server) RemoveSite(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sites, key)
+}
+
+// NewConfig issues a challenge for the site with the given key
+func (s *Server) NewConfig(key string) (Config, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	factor, ok := s.sites[key]
+	if !ok {
+		return Config{}, ErrUnknownSite
+	}
+
+	str := randomString()
+	if err := s.add(s.challenges, str, outstanding{key: key, factor: factor, expires: now.Add(s.ttl)}, now); err != nil {
+		return Config{}, err
+	}
+	return Config{String: str, DifficultyFactor: factor, Salt: s.salt}, nil
+}
+
+// Verify checks a solved challenge and returns a token for it. It reports false without an
+// error for work that does not solve an outstanding challenge of its site.
+func (s *Server) Verify(w Work) (string, bool, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	c, ok := s.take(s.challenges, w.String, now)
+	_, known := s.sites[w.Key]
+	s.mu.Unlock()
+
+	// the challenge is used up whether or not the work checks out
+	if !ok || !known || c.key != w.Key || !Check(s.salt, c.factor, w) {
+		return "", false, nil
+	}
+
+	token := randomString()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.add(s.tokens, token, outstanding{key: w.Key, expires: now.Add(s.ttl)}, now); err != nil {
+		return "", false, err
+	}
+	return token, true, nil
+}
+
+// VerifyToken reports whether token was issued for the site with the given key, and uses it up
+func (s *Server) VerifyToken(key, token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.take(s.tokens, token, time.Now())
+	_, known := s.sites[key]
+	return ok && known && t.key == key
+}
+
+// add records an outstanding value, dropping expired ones if m is full. The caller must hold s.mu.
+func (s *Server) add(m map[string]outstanding, v string, o outstanding, now time.Time) error {
+	if len(m) >= s.capacity {
+		for k, e := range m {
+			if now.After(e.expires) {
+				delete(m, k)
+			}
+		}
+		if len(m) >= s.capacity {
+			return ErrBusy
+		}
+	}
+
+	m[v] = o
+	return nil
+}
+
+// take removes an outstanding value and reports whether it had not expired. The caller must
+// hold s.mu.
+func (s *Server) take(m map[string]outstanding, v string, now time.Time) (outstanding, bool) {
+	o, ok := m[v]
+	delete(m, v)
+	return o, ok && !now.After(o.expires)
+}
+
+// randomString returns a random string to use as a salt, challenge string or token
+func randomString() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// configRequest is the request body of PathConfig
+type configRequest struct {
+	Key string `json:"key"`
+}
+
+// tokenResponse is the response body of PathVerify
+type tokenResponse struct {
+	Token string `json:"token"`
+}
+
+// siteVerifyRequest is the request body of PathSiteVerify
+type siteVerifyRequest struct {
+	Key    string `json:"key"`
+	Token  string `json:"token"`
+	Secret string `json:"secret"`
+}
+
+// siteVerifyResponse is the response body of PathSiteVerify
+type siteVerifyResponse struct {
+	Valid bool `json:"valid"`
+}
+
+// errorResponse is the body of responses with an error status
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// ServeHTTP implements http.Handler, serving the protocol's endpoints
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{"method not allowed"})
+		return
+	}
+
+	switch r.URL.Path {
+	case PathConfig:
+		var req configRequest
+		if !decode(w, r, &req) {
+			return
+		}
+
+		c, err := s.NewConfig(req.Key)
+		switch {
+		case errors.Is(err, ErrUnknownSite):
+			writeJSON(w, http.StatusNotFound, errorResponse{err.Error()})
+		case err != nil:
+			writeJSON(w, http.StatusServiceUnavailable, errorResponse{err.Error()})
+		default:
+			writeJSON(w, http.StatusOK, c)
+		}
+
+	case PathVerify:
+		var work Work
+		if !decode(w, r, &work) {
+			return
+		}
+
+		token, ok, err := s.Verify(work)
+		switch {
+		case err != nil:
+			writeJSON(w, http.StatusServiceUnavailable, errorResponse{err.Error()})
+		case !ok:
+			writeJSON(w, http.StatusBadRequest, errorResponse{"proof of work is not valid"})
+		default:
+			writeJSON(w, http.StatusOK, tokenResponse{token})
+		}
+
+	case PathSiteVerify:
+		var req siteVerifyRequest
+		if !decode(w, r, &req) {
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(req.Secret), []byte(s.secret)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{"wrong secret"})
+			return
+		}
+		writeJSON(w, http.StatusOK, siteVerifyResponse{s.VerifyToken(req.Key, req.Token)})
+
+	default:
+		writeJSON(w, http.StatusNotFound, errorResponse{"not found"})
+	}
+}
+
+// decode decodes the JSON body of a request into v, answering the request if it can't
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestSize)).Decode(v); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
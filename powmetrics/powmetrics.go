@@ -0,0 +1,152 @@
+// Package powmetrics exports Prometheus metrics for the searches and validations of
+// powork Workers.
+//
+// Create a Metrics, register it, and instrument every Worker to count. A server using
+// powhttp instruments the Worker it passes to WithWorker:
+//
+//	m := powmetrics.New("myapp")
+//	prometheus.MustRegister(m)
+//	m.Instrument(worker)
+//	http.Handle("/metrics", promhttp.Handler())
+package powmetrics
+
+import (
+	"context"
+
+	"github.com/Zumium/powork"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics is a prometheus.Collector of the metrics of the Workers it instruments:
+//
+//	powork_searches_total{algorithm, outcome}        searches, by outcome: solved, timeout,
+//	                                                 max_iterations, canceled or error
+//	powork_search_duration_seconds{algorithm}        histogram of the time searches took
+//	powork_search_iterations{algorithm}              histogram of the nonces searches tried
+//	powork_validations_total{method, result}         validations, by result: valid, invalid,
+//	                                                 replayed, garbage or error
+//	powork_garbage_total{reason}                     proofs a powork.Guard turned away, by
+//	                                                 the reason they were garbage
+//	powork_gc_collections_total{store, result}       collections by a powork.GC, by result:
+//	                                                 ok or error
+//	powork_gc_reclaimed_total{store}                 expired records a powork.GC dropped
+//	powork_validation_duration_seconds{method}       histogram of the time validations took
+//
+// Each name is prefixed with the namespace passed to New, if any, as in
+// myapp_powork_searches_total.
+type Metrics struct {
+	searches           *prometheus.CounterVec
+	searchDuration     *prometheus.HistogramVec
+	searchIterations   *prometheus.HistogramVec
+	validations        *prometheus.CounterVec
+	validationDuration *prometheus.HistogramVec
+	garbage            *prometheus.CounterVec
+	collections        *prometheus.CounterVec
+	reclaimed          *prometheus.CounterVec
+}
+
+// New creates Metrics whose names are prefixed with namespace, which may be empty
+func New(namespace string) *Metrics {
+	return &Metrics{
+		searches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "powork", Name: "searches_total",
+			Help: "Proof of work searches, by outcome.",
+		}, []string{"algorithm", "outcome"}),
+		searchDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "powork", Name: "search_duration_seconds",
+			Help:    "Time proof of work searches took.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 4, 10),
+		}, []string{"algorithm"}),
+		searchIterations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "powork", Name: "search_iterations",
+			Help:    "Nonces proof of work searches tried.",
+			Buckets: prometheus.ExponentialBuckets(16, 4, 12),
+		}, []string{"algorithm"}),
+		validations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "powork", Name: "validations_total",
+			Help: "Proof of work validations, by result.",
+		}, []string{"method", "result"}),
+		validationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "powork", Name: "validation_duration_seconds",
+			Help:    "Time proof of work validations took.",
+			Buckets: prometheus.ExponentialBuckets(0.000001, 4, 10),
+		}, []string{"method"}),
+		garbage: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "powork", Name: "garbage_total",
+			Help: "Proofs of work turned away without hashing, by reason.",
+		}, []string{"reason"}),
+		collections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "powork", Name: "gc_collections_total",
+			Help: "Collections of expired records from stores, by result.",
+		}, []string{"store", "result"}),
+		reclaimed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "powork", Name: "gc_reclaimed_total",
+			Help: "Expired records dropped from stores.",
+		}, []string{"store"}),
+	}
+}
+
+// Instrument makes w report to the Metrics
+func (m *Metrics) Instrument(w *powork.Worker) {
+	w.AddHooks(m.Hooks())
+}
+
+// InstrumentGC makes gc report its collections to the Metrics
+func (m *Metrics) InstrumentGC(gc *powork.GC) {
+	gc.OnCollect(m.collected)
+}
+
+// Hooks returns the hooks Instrument adds, for Workers configured some other way
+func (m *Metrics) Hooks() powork.Hooks {
+	return powork.Hooks{
+		SearchDone: m.searchDone,
+		Validated:  m.validated,
+	}
+}
+
+func (m *Metrics) searchDone(_ context.Context, r powork.SearchResult) {
+	m.searches.WithLabelValues(r.Algorithm, r.Outcome()).Inc()
+	m.searchDuration.WithLabelValues(r.Algorithm).Observe(r.Duration.Seconds())
+	m.searchIterations.WithLabelValues(r.Algorithm).Observe(float64(r.Iterations))
+}
+
+func (m *Metrics) validated(_ context.Context, r powork.ValidationResult) {
+	m.validations.WithLabelValues(r.Method, r.Outcome()).Inc()
+	m.validationDuration.WithLabelValues(r.Method).Observe(r.Duration.Seconds())
+	if r.Garbage != "" {
+		m.garbage.WithLabelValues(r.Garbage).Inc()
+	}
+}
+
+func (m *Metrics) collected(r powork.CollectResult) {
+	result := "ok"
+	if r.Err != nil {
+		result = "error"
+	}
+	m.collections.WithLabelValues(r.Store, result).Inc()
+	m.reclaimed.WithLabelValues(r.Store).Add(float64(r.Reclaimed))
+}
+
+// Describe implements prometheus.Collector
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.searches.Describe(ch)
+	m.searchDuration.Describe(ch)
+	m.searchIterations.Describe(ch)
+	m.validations.Describe(ch)
+	m.validationDuration.Describe(ch)
+	m.garbage.Describe(ch)
+	m.collections.Describe(ch)
+	m.reclaimed.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.searches.Collect(ch)
+	m.searchDuration.Collect(ch)
+	m.searchIterations.Collect(ch)
+	m.validations.Collect(ch)
+	m.validationDuration.Collect(ch)
+	m.garbage.Collect(ch)
+	m.collections.Collect(ch)
+	m.reclaimed.Collect(ch)
+}
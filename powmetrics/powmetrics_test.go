@@ -0,0 +1,141 @@
+package powmetrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Zumium/powork"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	m := New("test")
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(m); err != nil {
+		t.Fatalf("Error registering metrics: %v\n", err)
+	}
+
+	worker := powork.NewWorker()
+	worker.SetDifficulty(8)
+	m.Instrument(worker)
+
+	pow, err := worker.DoProofFor([]byte("a message"))
+	if err != nil {
+		t.Fatalf("Error finding proof: %v\n", err)
+	}
+	worker.ValidatePoWork(pow)
+
+	worker.SetDifficulty(40)
+	worker.SetTimeoutDuration(time.Millisecond)
+	worker.DoProofFor([]byte("a hard proof"))
+	worker.ValidatePoWork(pow)
+
+	if n := testutil.ToFloat64(m.searches.WithLabelValues("sha3-512", "solved")); n != 1 {
+		t.Fatalf("Counted %v solved searches, expected 1\n", n)
+	}
+	if n := testutil.ToFloat64(m.searches.WithLabelValues("sha3-512", "timeout")); n != 1 {
+		t.Fatalf("Counted %v timed out searches, expected 1\n", n)
+	}
+	if n := testutil.ToFloat64(m.validations.WithLabelValues("ValidatePoWork", "valid")); n != 1 {
+		t.Fatalf("Counted %v valid proofs, expected 1\n", n)
+	}
+	if n := testutil.ToFloat64(m.validations.WithLabelValues("ValidatePoWork", "invalid")); n != 1 {
+		t.Fatalf("Counted %v invalid proofs, expected 1\n", n)
+	}
+
+	if n := testutil.CollectAndCount(m, "test_powork_search_iterations"); n != 1 {
+		t.Fatalf("Collected %d iteration histograms, expected 1\n", n)
+	}
+
+	expected := `
+		# HELP test_powork_searches_total Proof of work searches, by outcome.
+		# TYPE test_powork_searches_total counter
+		test_powork_searches_total{algorithm="sha3-512",outcome="solved"} 1
+		test_powork_searches_total{algorithm="sha3-512",outcome="timeout"} 1
+	`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "test_powork_searches_total"); err != nil {
+		t.Fatalf("Unexpected metrics: %v\n", err)
+	}
+}
+
+func TestReplays(t *testing.T) {
+	m := New("")
+	worker := powork.NewWorker()
+	worker.SetDifficulty(8)
+	m.Instrument(worker)
+
+	challenge, err := worker.NewChallenge()
+	if err != nil {
+		t.Fatalf("Error issuing challenge: %v\n", err)
+	}
+
+	pow, err := worker.DoProofForChallenge(challenge, []byte("a message"))
+	if err != nil {
+		t.Fatalf("Error finding proof: %v\n", err)
+	}
+
+	worker.ValidateChallenge(pow)
+	worker.ValidateChallenge(pow)
+
+	if n := testutil.ToFloat64(m.validations.WithLabelValues("ValidateChallenge", "valid")); n != 1 {
+		t.Fatalf("Counted %v valid proofs, expected 1\n", n)
+	}
+	if n := testutil.ToFloat64(m.validations.WithLabelValues("ValidateChallenge", "replayed")); n != 1 {
+		t.Fatalf("Counted %v replayed proofs, expected 1\n", n)
+	}
+}
+
+func TestGarbage(t *testing.T) {
+	m := New("")
+	worker := powork.NewWorker()
+	worker.SetDifficulty(8)
+	m.Instrument(worker)
+
+	g, err := powork.NewGuard(worker, 0, 0)
+	if err != nil {
+		t.Fatalf("Error creating guard: %v\n", err)
+	}
+
+	pow, err := worker.DoProofFor([]byte("a message"))
+	if err != nil {
+		t.Fatalf("Error finding proof: %v\n", err)
+	}
+	g.ValidateChallenge(context.Background(), "client", pow)
+	g.ValidateEncoded(context.Background(), "client", []byte("garbage"))
+
+	if n := testutil.ToFloat64(m.validations.WithLabelValues("Guard", "garbage")); n != 2 {
+		t.Fatalf("Counted %v garbage proofs, expected 2\n", n)
+	}
+	if n := testutil.ToFloat64(m.garbage.WithLabelValues(powork.GarbageChallenge)); n != 1 {
+		t.Fatalf("Counted %v proofs without a challenge, expected 1\n", n)
+	}
+	if n := testutil.ToFloat64(m.garbage.WithLabelValues(powork.GarbageEncoding)); n != 1 {
+		t.Fatalf("Counted %v proofs that don't decode, expected 1\n", n)
+	}
+}
+
+func TestGC(t *testing.T) {
+	m := New("")
+	gc, err := powork.NewGC(time.Hour)
+	if err != nil {
+		t.Fatalf("Error creating GC: %v\n", err)
+	}
+	m.InstrumentGC(gc)
+
+	store := powork.NewMemorySpentStore(10)
+	store.Add(&powork.PoWork{}, time.Millisecond)
+	gc.Add("spent", store)
+
+	time.Sleep(10 * time.Millisecond)
+	gc.Collect(context.Background())
+
+	if n := testutil.ToFloat64(m.collections.WithLabelValues("spent", "ok")); n != 1 {
+		t.Fatalf("Counted %v collections, expected 1\n", n)
+	}
+	if n := testutil.ToFloat64(m.reclaimed.WithLabelValues("spent")); n != 1 {
+		t.Fatalf("Counted %v reclaimed records, expected 1\n", n)
+	}
+}
@@ -0,0 +1,78 @@
+// Package powmq stamps the messages of message brokers with a proof of work, so that the
+// consumers of a multi-tenant cluster can drop or deprioritize traffic that didn't pay for
+// itself. A stamp is a proof for the SHA-256 of the message body, bound to the topic or
+// subject it is published on, and travels in the X-PoWork header of the message.
+//
+// Brokers redeliver messages, so stamps are not single-use. To bound how long a stamp can be
+// replayed on its topic, have the producers' Workers timestamp their proofs and the
+// consumers' Workers set a maximum proof age.
+//
+// The pownats and powkafka subpackages apply stamps to NATS and Kafka messages.
+package powmq
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+
+	"github.com/Zumium/powork"
+)
+
+// Header is the name of the message header carrying a stamp
+const Header = "X-PoWork"
+
+// ExtensionTopic is the extension of stamps holding the topic they are bound to
+const ExtensionTopic = "topic"
+
+// Stamp computes the X-PoWork header value for a message with body data published on topic
+func Stamp(ctx context.Context, w *powork.Worker, topic string, data []byte) (string, error) {
+	digest, _ := powork.Digest(bytes.NewReader(data))
+	pow, err := w.DoProofForExtensionsContext(ctx, digest, map[string]string{ExtensionTopic: topic})
+	if err != nil {
+		return "", err
+	}
+
+	b, err := pow.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Verifier checks the stamps of consumed messages. Its methods are safe for concurrent use.
+type Verifier struct {
+	worker *powork.Worker
+}
+
+// NewVerifier creates a Verifier that checks stamps with w, demanding its difficulty
+func NewVerifier(w *powork.Worker) *Verifier {
+	return &Verifier{worker: w}
+}
+
+// Verify returns the difficulty that header, the value of a message's X-PoWork header,
+// achieves as a stamp for a message with body data consumed from topic. It returns 0 if
+// header is empty or is not a valid stamp for the message.
+func (v *Verifier) Verify(topic string, data []byte, header string) int {
+	if header == "" {
+		return 0
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(header)
+	if err != nil {
+		return 0
+	}
+
+	pow := new(powork.PoWork)
+	if err := pow.UnmarshalBinary(b); err != nil {
+		return 0
+	}
+	if t, ok := pow.GetExtension(ExtensionTopic); !ok || t != topic {
+		return 0
+	}
+
+	digest, _ := powork.Digest(bytes.NewReader(data))
+	if ok, err := v.worker.ValidateDigest(pow, digest); err != nil || !ok {
+		return 0
+	}
+	return v.worker.MeasureDifficulty(pow)
+}
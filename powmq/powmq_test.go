@@ -0,0 +1,63 @@
+package powmq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+func TestStampVerify(t *testing.T) {
+	w := powork.NewWorker()
+	w.SetDifficulty(8)
+
+	stamp, err := Stamp(context.Background(), w, "orders", []byte("an order"))
+	if err != nil {
+		t.Fatalf("Error stamping message: %v\n", err)
+	}
+
+	v := NewVerifier(w)
+	if d := v.Verify("orders", []byte("an order"), stamp); d < 8 {
+		t.Fatalf("Valid stamp verified at difficulty %d\n", d)
+	}
+
+	for _, c := range []struct {
+		topic, data, header string
+	}{
+		{"orders", "an order", ""},
+		{"orders", "an order", "not a stamp"},
+		{"orders", "another order", stamp},
+		{"invoices", "an order", stamp},
+	} {
+		if d := v.Verify(c.topic, []byte(c.data), c.header); d != 0 {
+			t.Fatalf("Stamp %q of %q on %q verified at difficulty %d\n", c.header, c.data, c.topic, d)
+		}
+	}
+
+	// consumers demanding more than the stamp achieves reject it
+	strict := powork.NewWorker()
+	strict.SetDifficulty(v.Verify("orders", []byte("an order"), stamp) + 1)
+	if d := NewVerifier(strict).Verify("orders", []byte("an order"), stamp); d != 0 {
+		t.Fatalf("Stamp below the difficulty verified at %d\n", d)
+	}
+}
+
+func TestStampAge(t *testing.T) {
+	w := powork.NewWorker()
+	w.SetDifficulty(8)
+	w.SetProofTimestamps(true)
+
+	stamp, err := Stamp(context.Background(), w, "orders", []byte("an order"))
+	if err != nil {
+		t.Fatalf("Error stamping message: %v\n", err)
+	}
+
+	consumer := powork.NewWorker()
+	consumer.SetDifficulty(8)
+	consumer.SetMaxProofAge(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if d := NewVerifier(consumer).Verify("orders", []byte("an order"), stamp); d != 0 {
+		t.Fatalf("Stamp older than the maximum age verified at difficulty %d\n", d)
+	}
+}
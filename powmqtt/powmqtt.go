@@ -0,0 +1,184 @@
+// Package powmqtt is a mochi-mqtt hook that makes MQTT clients solve a proof of work before
+// the broker accepts their connection, a lightweight defense against connection floods for
+// IoT brokers:
+//
+//	server := mqtt.New(nil)
+//	err := server.AddHook(new(powmqtt.Hook), &powmqtt.Options{Server: server, Worker: worker})
+//	err = server.AddHook(new(auth.AllowHook), nil)
+//
+// A CONNECT without a valid proof is refused with "not authorized". For MQTT 5 clients, the
+// CONNACK carries a challenge issued by the broker and its difficulty in the powork-challenge
+// and powork-difficulty user properties. The client solves the challenge for its client ID
+// with Solve and connects again, with the proof in the powork-proof user property of its
+// CONNECT or in its password. A proof is good for one connection.
+//
+// MQTT 3 clients can't read the properties of a CONNACK. Give them challenges through
+// another channel, such as a powservice challenge endpoint whose Worker shares the hook's
+// challenge key.
+//
+// The hook runs on OnConnect, before authentication, and does not authenticate clients
+// itself: the broker still needs an authentication hook, such as auth.AllowHook, and a client
+// must pass both.
+package powmqtt
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+
+	"github.com/Zumium/powork"
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/packets"
+)
+
+// User properties of the handshake
+const (
+	// PropertyChallenge is the CONNACK property carrying a challenge to prove
+	PropertyChallenge = "powork-challenge"
+	// PropertyDifficulty is the CONNACK property carrying the required difficulty
+	PropertyDifficulty = "powork-difficulty"
+	// PropertyProof is the CONNECT property carrying a proof
+	PropertyProof = "powork-proof"
+)
+
+// Options configures a Hook
+type Options struct {
+	// Server is the broker the hook is added to, which sends the challenges
+	Server *mqtt.Server
+	// Worker issues challenges and validates proofs. If nil, powork.NewWorker is used.
+	Worker *powork.Worker
+}
+
+// Hook is the mochi-mqtt hook. Add it to a server with Options.
+type Hook struct {
+	mqtt.HookBase
+	server *mqtt.Server
+	worker *powork.Worker
+}
+
+// ID implements mqtt.Hook
+func (h *Hook) ID() string {
+	return "powork"
+}
+
+// Provides implements mqtt.Hook
+func (h *Hook) Provides(b byte) bool {
+	return b == mqtt.OnConnect
+}
+
+// Init implements mqtt.Hook, taking *Options as config
+func (h *Hook) Init(config any) error {
+	opts, ok := config.(*Options)
+	if !ok || opts.Server == nil {
+		return fmt.Errorf("%w: powmqtt hook needs *Options with a Server", powork.ErrInvalidSetting)
+	}
+
+	h.server = opts.Server
+	h.worker = opts.Worker
+	if h.worker == nil {
+		h.worker = powork.NewWorker()
+	}
+	return nil
+}
+
+// OnConnect implements mqtt.Hook, refusing clients without a valid proof with a challenge
+func (h *Hook) OnConnect(cl *mqtt.Client, pk packets.Packet) error {
+	id := string(pk.Connect.ClientIdentifier)
+	if id == "" {
+		// the proof has to be bound to an ID the client knows in advance
+		h.server.SendConnack(cl, packets.ErrClientIdentifierNotValid, false, nil)
+		return packets.ErrClientIdentifierNotValid
+	}
+
+	proof := property(pk.Properties.User, PropertyProof)
+	if proof == "" {
+		proof = string(pk.Connect.Password)
+	}
+	if h.verify(proof, id) {
+		return nil
+	}
+
+	challenge, err := h.worker.NewChallenge()
+	if err != nil {
+		h.server.SendConnack(cl, packets.ErrUnspecifiedError, false, nil)
+		return err
+	}
+
+	props := &packets.Properties{User: []packets.UserProperty{
+		{Key: PropertyChallenge, Val: base64.RawURLEncoding.EncodeToString(challenge.GetBytes())},
+		{Key: PropertyDifficulty, Val: strconv.Itoa(challenge.GetDifficulty())},
+	}}
+	h.server.SendConnack(cl, packets.ErrNotAuthorized, false, props)
+	return packets.ErrNotAuthorized
+}
+
+// verify reports whether proof is a valid proof for a challenge of the hook bound to id
+func (h *Hook) verify(proof, id string) bool {
+	if proof == "" {
+		return false
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(proof)
+	if err != nil {
+		return false
+	}
+
+	pow := new(powork.PoWork)
+	if err := pow.UnmarshalBinary(data); err != nil {
+		return false
+	}
+
+	ok, err := h.worker.ValidateChallengeSubject(pow, id)
+	return ok && err == nil
+}
+
+// Solve computes the proof that answers the value of a powork-challenge property at the given
+// difficulty for a client connecting as clientID. Send it in the powork-proof property or the
+// password of the next CONNECT.
+func Solve(ctx context.Context, challenge string, difficulty int, clientID string) (string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(challenge)
+	if err != nil || len(b) == 0 {
+		return "", fmt.Errorf("%w: challenge is not valid base64", powork.ErrInvalidEncoding)
+	}
+
+	worker := powork.NewWorker()
+	if err := worker.SetDifficulty(difficulty); err != nil {
+		return "", err
+	}
+
+	pow, err := worker.DoProofForChallengeSubjectContext(ctx, powork.ChallengeFromBytes(b), nil, clientID)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := pow.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// Challenge returns the challenge and difficulty carried by the user properties of a CONNACK
+// that refused a connection, if it carries one
+func Challenge(props []packets.UserProperty) (string, int, bool) {
+	challenge := property(props, PropertyChallenge)
+	difficulty, err := strconv.Atoi(property(props, PropertyDifficulty))
+	if challenge == "" || err != nil {
+		return "", 0, false
+	}
+	return challenge, difficulty, true
+}
+
+// property returns the value of the user property key, or "" if there is none
+func property(props []packets.UserProperty, key string) string {
+	for _, p := range props {
+		if p.Key == key {
+			return p.Val
+		}
+	}
+	return ""
+}
+
+// Interface guards
+var _ mqtt.Hook = (*Hook)(nil)
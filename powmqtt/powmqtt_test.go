@@ -0,0 +1,150 @@
+package powmqtt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Zumium/powork"
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+)
+
+// newBroker starts a broker with the hook on loopback and returns its address
+func newBroker(t *testing.T, w *powork.Worker) string {
+	server := mqtt.New(&mqtt.Options{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	if err := server.AddHook(new(Hook), &Options{Server: server, Worker: w}); err != nil {
+		t.Fatalf("Error adding hook: %v\n", err)
+	}
+	server.AddHook(new(auth.AllowHook), nil)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v\n", err)
+	}
+	if err := server.AddListener(listeners.NewNet("test", l)); err != nil {
+		t.Fatalf("Error adding listener: %v\n", err)
+	}
+	if err := server.Serve(); err != nil {
+		t.Fatalf("Error starting broker: %v\n", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return l.Addr().String()
+}
+
+// connect sends an MQTT 5 CONNECT to addr and returns the CONNACK
+func connect(t *testing.T, addr, id string, props []packets.UserProperty, password string) packets.Packet {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Error dialing broker: %v\n", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	pk := packets.Packet{
+		FixedHeader:     packets.FixedHeader{Type: packets.Connect},
+		ProtocolVersion: 5,
+		Connect: packets.ConnectParams{
+			ProtocolName:     []byte("MQTT"),
+			ClientIdentifier: id,
+			Keepalive:        30,
+			Clean:            true,
+			PasswordFlag:     password != "",
+			Password:         []byte(password),
+			UsernameFlag:     password != "",
+			Username:         []byte("device"),
+		},
+		Properties: packets.Properties{User: props},
+	}
+	var buf bytes.Buffer
+	if err := pk.ConnectEncode(&buf); err != nil {
+		t.Fatalf("Error encoding CONNECT: %v\n", err)
+	}
+	conn.Write(buf.Bytes())
+
+	r := bufio.NewReader(conn)
+	var ack packets.Packet
+	ack.ProtocolVersion = 5
+	b, err := r.ReadByte()
+	if err != nil {
+		t.Fatalf("Error reading CONNACK: %v\n", err)
+	}
+	ack.FixedHeader.Decode(b)
+	n, _, err := packets.DecodeLength(r)
+	if err != nil {
+		t.Fatalf("Error reading CONNACK: %v\n", err)
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		t.Fatalf("Error reading CONNACK: %v\n", err)
+	}
+	if err := ack.ConnackDecode(body); err != nil {
+		t.Fatalf("Error decoding CONNACK: %v\n", err)
+	}
+	return ack
+}
+
+func TestHook(t *testing.T) {
+	w := powork.NewWorker()
+	w.SetDifficulty(8)
+	addr := newBroker(t, w)
+
+	ack := connect(t, addr, "sensor-1", nil, "")
+	challenge, difficulty, ok := Challenge(ack.Properties.User)
+	if ack.ReasonCode != packets.ErrNotAuthorized.Code || !ok || difficulty != 8 {
+		t.Fatalf("CONNECT without proof got reason %#x and properties %v\n", ack.ReasonCode, ack.Properties.User)
+	}
+
+	proof, err := Solve(context.Background(), challenge, difficulty, "sensor-1")
+	if err != nil {
+		t.Fatalf("Error solving challenge: %v\n", err)
+	}
+
+	// the proof is bound to the client ID
+	if ack := connect(t, addr, "sensor-2", []packets.UserProperty{{Key: PropertyProof, Val: proof}}, ""); ack.ReasonCode != packets.ErrNotAuthorized.Code {
+		t.Fatalf("CONNECT with the proof of another client got reason %#x\n", ack.ReasonCode)
+	}
+
+	// the failed attempt did not spend the challenge
+	if ack := connect(t, addr, "sensor-1", []packets.UserProperty{{Key: PropertyProof, Val: proof}}, ""); ack.ReasonCode != packets.CodeSuccess.Code {
+		t.Fatalf("CONNECT with proof got reason %#x\n", ack.ReasonCode)
+	}
+
+	// a proof is good for one connection
+	if ack := connect(t, addr, "sensor-1", []packets.UserProperty{{Key: PropertyProof, Val: proof}}, ""); ack.ReasonCode != packets.ErrNotAuthorized.Code {
+		t.Fatalf("Replayed proof got reason %#x\n", ack.ReasonCode)
+	}
+}
+
+func TestHookPassword(t *testing.T) {
+	w := powork.NewWorker()
+	w.SetDifficulty(8)
+	addr := newBroker(t, w)
+
+	challenge, difficulty, _ := Challenge(connect(t, addr, "sensor-1", nil, "").Properties.User)
+	proof, err := Solve(context.Background(), challenge, difficulty, "sensor-1")
+	if err != nil {
+		t.Fatalf("Error solving challenge: %v\n", err)
+	}
+
+	if ack := connect(t, addr, "sensor-1", nil, proof); ack.ReasonCode != packets.CodeSuccess.Code {
+		t.Fatalf("CONNECT with the proof as password got reason %#x\n", ack.ReasonCode)
+	}
+
+	if ack := connect(t, addr, "", nil, ""); ack.ReasonCode != packets.ErrClientIdentifierNotValid.Code {
+		t.Fatalf("CONNECT without a client ID got reason %#x\n", ack.ReasonCode)
+	}
+}
+
+func TestInit(t *testing.T) {
+	if err := new(Hook).Init(nil); err == nil {
+		t.Fatalf("Hook initialized without options\n")
+	}
+}
@@ -0,0 +1,61 @@
+// Package pownats stamps NATS messages with a proof of work and checks the stamps of
+// received messages, as described in package powmq:
+//
+//	err := pownats.Publish(ctx, nc, worker, "orders", data)
+//
+//	v := powmq.NewVerifier(worker)
+//	sub, err := nc.Subscribe("orders", pownats.Handler(v, handle, nil))
+//
+// A stamp is bound to the subject a message is published on, so subscribers receive it on
+// the same subject. Request-reply and queue subscriptions work the same way.
+package pownats
+
+import (
+	"context"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powmq"
+	"github.com/nats-io/nats.go"
+)
+
+// StampMsg adds a stamp for msg's subject and data to its headers
+func StampMsg(ctx context.Context, w *powork.Worker, msg *nats.Msg) error {
+	stamp, err := powmq.Stamp(ctx, w, msg.Subject, msg.Data)
+	if err != nil {
+		return err
+	}
+
+	if msg.Header == nil {
+		msg.Header = nats.Header{}
+	}
+	msg.Header.Set(powmq.Header, stamp)
+	return nil
+}
+
+// Publish publishes data on subject like nats.Conn.Publish, with a stamp computed by w
+func Publish(ctx context.Context, nc *nats.Conn, w *powork.Worker, subject string, data []byte) error {
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	if err := StampMsg(ctx, w, msg); err != nil {
+		return err
+	}
+	return nc.PublishMsg(msg)
+}
+
+// Verify returns the difficulty the stamp of msg achieves, or 0 if it carries no valid stamp
+func Verify(v *powmq.Verifier, msg *nats.Msg) int {
+	return v.Verify(msg.Subject, msg.Data, msg.Header.Get(powmq.Header))
+}
+
+// Handler wraps the message handler of a subscription so that next only receives messages
+// with a valid stamp. The others are passed to unstamped, for example to handle them at a lower
+// priority, or dropped if unstamped is nil.
+func Handler(v *powmq.Verifier, next, unstamped nats.MsgHandler) nats.MsgHandler {
+	return func(msg *nats.Msg) {
+		if Verify(v, msg) > 0 {
+			next(msg)
+		} else if unstamped != nil {
+			unstamped(msg)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package pownats
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Zumium/powork"
+	"github.com/Zumium/powork/powmq"
+	"github.com/nats-io/nats.go"
+)
+
+func TestHandler(t *testing.T) {
+	w := powork.NewWorker()
+	w.SetDifficulty(8)
+
+	stamped := nats.NewMsg("orders")
+	stamped.Data = []byte("an order")
+	if err := StampMsg(context.Background(), w, stamped); err != nil {
+		t.Fatalf("Error stamping message: %v\n", err)
+	}
+	if Verify(powmq.NewVerifier(w), stamped) < 8 {
+		t.Fatalf("Stamped message does not verify\n")
+	}
+
+	// a stamp copied onto a message on another subject
+	moved := nats.NewMsg("invoices")
+	moved.Data = stamped.Data
+	moved.Header = stamped.Header
+
+	var got, low []string
+	h := Handler(powmq.NewVerifier(w), func(msg *nats.Msg) {
+		got = append(got, msg.Subject)
+	}, func(msg *nats.Msg) {
+		low = append(low, msg.Subject)
+	})
+	for _, msg := range []*nats.Msg{stamped, moved, nats.NewMsg("plain")} {
+		h(msg)
+	}
+
+	if len(got) != 1 || got[0] != "orders" || len(low) != 2 {
+		t.Fatalf("Handler passed %v on and %v as unstamped\n", got, low)
+	}
+
+	// without a handler for them, unstamped messages are dropped
+	Handler(powmq.NewVerifier(w), func(msg *nats.Msg) {
+		t.Fatalf("Unstamped message was passed on\n")
+	}, nil)(moved)
+}
@@ -0,0 +1,379 @@
+// Package pownegotiate lets clients and servers agree on a hash algorithm and a difficulty,
+// so that clients of different abilities aren't all held to one configuration: a phone
+// without a fast SHA-3 can be offered BLAKE3, and a slow client can be offered less work
+// than the server would like, down to the least it accepts.
+//
+// The exchange takes one round trip, over any transport that carries messages:
+//
+//	client → server  Hello: the algorithms the client supports, in its order of preference,
+//	                 the hash rate it measured for each, and the most time it will spend
+//	server → client  Offer: the algorithm and difficulty chosen, and a challenge to prove,
+//	                 or a refusal
+//
+// The client then presents a proof for the challenge as the transport's protocol has it,
+// and the server checks it with Server.Validate. Every message starts with the Version of
+// the exchange and its type, in network byte order:
+//
+//	Hello    version uint8, type uint8 = 1, budget uint32 (milliseconds), count uint8,
+//	         then for each algorithm: identifier uint8, hash rate uint64 (hashes a second,
+//	         0 if not measured)
+//	Offer    version uint8, type uint8 = 2, algorithm identifier uint8, difficulty uint8,
+//	         challenge
+//	Refusal  version uint8, type uint8 = 3, reason uint8
+//
+// Algorithms are named by their identifiers in the powork registry. A server refuses Hellos
+// of a version newer than its own with a Refusal of its own version, so that clients can
+// fall back.
+package pownegotiate
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+// Version is the version of the exchange this package speaks
+const Version = 1
+
+// Message types
+const (
+	typeHello   = 1
+	typeOffer   = 2
+	typeRefusal = 3
+)
+
+// Refusal reasons
+const (
+	reasonVersion   = 1
+	reasonAlgorithm = 2
+	reasonBudget    = 3
+)
+
+// helloHeaderLen is the size of the fixed part of an encoded Hello
+const helloHeaderLen = 1 + 1 + 4 + 1
+
+// helloAlgorithmLen is the size of each algorithm of an encoded Hello
+const helloAlgorithmLen = 1 + 8
+
+var (
+	// ErrUnsupportedVersion is returned for messages of a version this package doesn't speak
+	ErrUnsupportedVersion = errors.New("Unsupported negotiation version")
+	// ErrNoCommonAlgorithm is returned when the server offers none of the client's algorithms
+	ErrNoCommonAlgorithm = errors.New("No common proof of work algorithm")
+	// ErrOverBudget is returned when every algorithm the client and server have in common
+	// would take the client longer than its budget at the least difficulty the server accepts
+	ErrOverBudget = errors.New("Proof of work over the client's budget")
+)
+
+// A Hello is the message a client opens the exchange with
+type Hello struct {
+	// Algorithms are the names of the algorithms the client supports, in its order of
+	// preference. Algorithms that aren't registered can't be sent.
+	Algorithms []string
+	// HashRates are the hashes a second the client computes each algorithm at, such as by
+	// MeasureHashRates. Algorithms without a rate are assumed fast enough for any difficulty.
+	HashRates map[string]float64
+	// Budget is the most time the client will spend on the proof, or 0 for no limit
+	Budget time.Duration
+}
+
+// An Offer is the server's choice of algorithm and difficulty, with a challenge to prove
+type Offer struct {
+	Algorithm  string
+	Difficulty int
+	Challenge  *powork.Challenge
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (h *Hello) MarshalBinary() ([]byte, error) {
+	if len(h.Algorithms) > math.MaxUint8 {
+		return nil, fmt.Errorf("%w: at most %d algorithms", powork.ErrInvalidSetting, math.MaxUint8)
+	}
+
+	budget := h.Budget.Milliseconds()
+	if budget < 0 || budget > math.MaxUint32 {
+		return nil, fmt.Errorf("%w: budget out of range", powork.ErrInvalidSetting)
+	}
+
+	data := make([]byte, helloHeaderLen, helloHeaderLen+len(h.Algorithms)*helloAlgorithmLen)
+	data[0] = Version
+	data[1] = typeHello
+	binary.BigEndian.PutUint32(data[2:], uint32(budget))
+	data[6] = uint8(len(h.Algorithms))
+
+	for _, name := range h.Algorithms {
+		a, ok := powork.LookupAlgorithm(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown algorithm %q", powork.ErrInvalidSetting, name)
+		}
+		data = append(data, a.ID)
+		data = binary.BigEndian.AppendUint64(data, uint64(h.HashRates[name]))
+	}
+
+	return data, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, decoding data produced by
+// MarshalBinary. Algorithms this side hasn't registered are left out.
+func (h *Hello) UnmarshalBinary(data []byte) error {
+	if err := checkHeader(data, typeHello); err != nil {
+		return err
+	}
+
+	if len(data) < helloHeaderLen || len(data) != helloHeaderLen+int(data[6])*helloAlgorithmLen {
+		return fmt.Errorf("%w: encoded hello has the wrong length", powork.ErrInvalidEncoding)
+	}
+
+	h.Budget = time.Duration(binary.BigEndian.Uint32(data[2:])) * time.Millisecond
+	h.Algorithms = nil
+	h.HashRates = make(map[string]float64)
+	for rest := data[helloHeaderLen:]; len(rest) > 0; rest = rest[helloAlgorithmLen:] {
+		a, ok := powork.LookupAlgorithmID(rest[0])
+		if !ok {
+			continue
+		}
+		h.Algorithms = append(h.Algorithms, a.Name)
+		if rate := binary.BigEndian.Uint64(rest[1:]); rate > 0 {
+			h.HashRates[a.Name] = float64(rate)
+		}
+	}
+
+	return nil
+}
+
+// EncodeReply encodes the server's reply to a Hello: the Offer, or a Refusal if err is one
+// of the errors returned by Server.Negotiate. It takes Negotiate's results as they are:
+//
+//	reply, err := pownegotiate.EncodeReply(server.Negotiate(&hello))
+func EncodeReply(o *Offer, err error) ([]byte, error) {
+	switch {
+	case errors.Is(err, ErrUnsupportedVersion):
+		return []byte{Version, typeRefusal, reasonVersion}, nil
+	case errors.Is(err, ErrNoCommonAlgorithm):
+		return []byte{Version, typeRefusal, reasonAlgorithm}, nil
+	case errors.Is(err, ErrOverBudget):
+		return []byte{Version, typeRefusal, reasonBudget}, nil
+	case err != nil:
+		return nil, err
+	}
+
+	a, ok := powork.LookupAlgorithm(o.Algorithm)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown algorithm %q", powork.ErrInvalidSetting, o.Algorithm)
+	}
+
+	if o.Difficulty <= 0 || o.Difficulty > math.MaxUint8 {
+		return nil, fmt.Errorf("%w: difficulty out of range", powork.ErrInvalidDifficulty)
+	}
+
+	return append([]byte{Version, typeOffer, a.ID, uint8(o.Difficulty)}, o.Challenge.GetBytes()...), nil
+}
+
+// DecodeReply decodes a reply encoded by EncodeReply. A Refusal is returned as the error the
+// server refused with, whatever the version it was sent with.
+func DecodeReply(data []byte) (*Offer, error) {
+	if len(data) >= 2 && data[1] == typeRefusal {
+		if len(data) != 3 {
+			return nil, fmt.Errorf("%w: encoded refusal has the wrong length", powork.ErrInvalidEncoding)
+		}
+
+		switch data[2] {
+		case reasonVersion:
+			return nil, fmt.Errorf("%w: server speaks version %d", ErrUnsupportedVersion, data[0])
+		case reasonAlgorithm:
+			return nil, ErrNoCommonAlgorithm
+		case reasonBudget:
+			return nil, ErrOverBudget
+		}
+		return nil, fmt.Errorf("%w: unknown refusal reason %d", powork.ErrInvalidEncoding, data[2])
+	}
+
+	if err := checkHeader(data, typeOffer); err != nil {
+		return nil, err
+	}
+
+	if len(data) < 5 {
+		return nil, fmt.Errorf("%w: encoded offer is too short", powork.ErrInvalidEncoding)
+	}
+
+	a, ok := powork.LookupAlgorithmID(data[2])
+	if !ok {
+		return nil, fmt.Errorf("%w: offer of unknown algorithm %d", powork.ErrInvalidEncoding, data[2])
+	}
+
+	if data[3] == 0 {
+		return nil, fmt.Errorf("%w: offer of difficulty 0", powork.ErrInvalidEncoding)
+	}
+
+	return &Offer{Algorithm: a.Name, Difficulty: int(data[3]), Challenge: powork.ChallengeFromBytes(data[4:])}, nil
+}
+
+// checkHeader checks the version and type a message starts with
+func checkHeader(data []byte, typ uint8) error {
+	if len(data) < 2 {
+		return fmt.Errorf("%w: encoded message is too short", powork.ErrInvalidEncoding)
+	}
+
+	if data[0] != Version {
+		return fmt.Errorf("%w: version %d", ErrUnsupportedVersion, data[0])
+	}
+
+	if data[1] != typ {
+		return fmt.Errorf("%w: unexpected message type %d", powork.ErrInvalidEncoding, data[1])
+	}
+	return nil
+}
+
+// A Server chooses what to offer clients from the algorithms it has been configured with.
+// Configure a Server before using it; its methods are then safe for concurrent use.
+type Server struct {
+	algorithms []serverAlgorithm
+}
+
+// serverAlgorithm is an algorithm a Server offers
+type serverAlgorithm struct {
+	worker *powork.Worker
+	max    int
+}
+
+// NewServer creates a Server that offers no algorithms yet
+func NewServer() *Server {
+	return new(Server)
+}
+
+// Add offers the algorithm of w, which issues the challenges and validates the proofs. The
+// Worker's difficulty is the least the Server accepts; clients whose budget allows it are
+// asked for up to max. Workers of the same algorithm are tried in the order they were added.
+func (s *Server) Add(w *powork.Worker, max int) error {
+	if _, ok := powork.LookupAlgorithm(w.GetAlgorithm()); !ok {
+		return fmt.Errorf("%w: worker's algorithm is not registered", powork.ErrInvalidSetting)
+	}
+
+	if max < w.GetDifficulty() || max > min(w.MaxDifficulty(), math.MaxUint8) {
+		return fmt.Errorf("%w: maximum must be between the worker's difficulty and its hash size", powork.ErrInvalidDifficulty)
+	}
+
+	s.algorithms = append(s.algorithms, serverAlgorithm{worker: w, max: max})
+	return nil
+}
+
+// Negotiate chooses the algorithm and difficulty to offer the client that sent h, and issues
+// a challenge. The Server takes the first of the client's algorithms, in its order of
+// preference, that it offers and the client can compute within its budget, at the highest
+// difficulty that fits. It fails with ErrNoCommonAlgorithm or ErrOverBudget if there is none.
+func (s *Server) Negotiate(h *Hello) (*Offer, error) {
+	common := false
+	for _, name := range h.Algorithms {
+		for _, a := range s.algorithms {
+			if a.worker.GetAlgorithm() != name {
+				continue
+			}
+			common = true
+
+			if o, err := a.offer(h); o != nil || err != nil {
+				return o, err
+			}
+		}
+	}
+
+	if common {
+		return nil, ErrOverBudget
+	}
+	return nil, ErrNoCommonAlgorithm
+}
+
+// offer issues the offer of a to the client that sent h, or returns nil if the least
+// difficulty a accepts is over the client's budget
+func (a *serverAlgorithm) offer(h *Hello) (*Offer, error) {
+	name := a.worker.GetAlgorithm()
+
+	difficulty := a.max
+	if rate := h.HashRates[name]; rate > 0 && h.Budget > 0 {
+		// the highest difficulty whose expected work the client gets through in time
+		difficulty = min(difficulty, int(math.Floor(math.Log2(rate*h.Budget.Seconds()))))
+	}
+	if difficulty < a.worker.GetDifficulty() {
+		return nil, nil
+	}
+
+	c, err := a.worker.NewChallengeWithDifficulty(difficulty)
+	if err != nil {
+		return nil, err
+	}
+	return &Offer{Algorithm: name, Difficulty: difficulty, Challenge: c}, nil
+}
+
+// Handle answers an encoded Hello with an encoded reply, for transports that pass messages
+// through as they are. A Hello of another version is refused.
+func (s *Server) Handle(data []byte) ([]byte, error) {
+	var h Hello
+	if err := h.UnmarshalBinary(data); err != nil {
+		if errors.Is(err, ErrUnsupportedVersion) {
+			return EncodeReply(nil, err)
+		}
+		return nil, err
+	}
+
+	return EncodeReply(s.Negotiate(&h))
+}
+
+// Validate checks a proof for a challenge the Server offered, as by the ValidateChallengeContext
+// of the Workers of the algorithm the proof declares. Each is tried in turn, since any of them
+// may have issued the challenge, until one accepts the proof.
+func (s *Server) Validate(ctx context.Context, pow *powork.PoWork) (bool, error) {
+	var err error
+	for _, a := range s.algorithms {
+		if a.worker.GetAlgorithm() != pow.GetAlgorithm() {
+			continue
+		}
+
+		var ok bool
+		if ok, err = a.worker.ValidateChallengeContext(ctx, pow); ok {
+			return true, nil
+		}
+	}
+
+	return false, err
+}
+
+// MeasureHashRates measures the hashes a second this machine computes each of the named
+// algorithms at, for a Hello. Each measurement takes a fraction of a second.
+func MeasureHashRates(names ...string) (map[string]float64, error) {
+	const difficulty = 16
+
+	rates := make(map[string]float64, len(names))
+	for _, name := range names {
+		w, err := powork.NewWorkerForAlgorithm(name)
+		if err != nil {
+			return nil, err
+		}
+
+		d, err := w.EstimateDuration(difficulty)
+		if err != nil {
+			return nil, err
+		}
+		rates[name] = powork.EstimateIterations(difficulty) / d.Seconds()
+	}
+
+	return rates, nil
+}
+
+// Solve computes a proof of work for msg answering an Offer, giving up when ctx is done
+func Solve(ctx context.Context, o *Offer, msg []byte) (*powork.PoWork, error) {
+	w, err := powork.NewWorkerForAlgorithm(o.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.SetDifficulty(o.Difficulty); err != nil {
+		return nil, err
+	}
+	w.SetTimeoutDuration(0)
+
+	return w.DoProofForChallengeContext(ctx, o.Challenge, msg)
+}
@@ -0,0 +1,194 @@
+package pownegotiate
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+func newServer(t *testing.T) *Server {
+	s := NewServer()
+	for _, name := range []string{"sha3-512", "blake3-256"} {
+		w, _ := powork.NewWorkerForAlgorithm(name)
+		w.SetDifficulty(8)
+		if err := s.Add(w, 12); err != nil {
+			t.Fatalf("Error adding %s: %v\n", name, err)
+		}
+	}
+	return s
+}
+
+func TestNegotiate(t *testing.T) {
+	s := newServer(t)
+
+	// the client's preference wins among the algorithms the server offers
+	o, err := s.Negotiate(&Hello{Algorithms: []string{"blake3-256", "sha3-512"}})
+	if err != nil || o.Algorithm != "blake3-256" || o.Difficulty != 12 {
+		t.Fatalf("Negotiated %+v, expected blake3-256 at 12: %v\n", o, err)
+	}
+
+	o, err = s.Negotiate(&Hello{Algorithms: []string{"sha256", "sha3-512", "blake3-256"}})
+	if err != nil || o.Algorithm != "sha3-512" {
+		t.Fatalf("Negotiated %+v, expected sha3-512: %v\n", o, err)
+	}
+
+	// an algorithm over the client's budget gives way to the next it prefers
+	slow := &Hello{Algorithms: []string{"sha3-512", "blake3-256"}, HashRates: map[string]float64{"sha3-512": 100}, Budget: time.Second}
+	if o, err := s.Negotiate(slow); err != nil || o.Algorithm != "blake3-256" {
+		t.Fatalf("Negotiated %+v, expected blake3-256 for a client slow at sha3-512: %v\n", o, err)
+	}
+
+	// a slow client is asked for what it gets through in its budget: 2^10 hashes
+	hello := &Hello{Algorithms: []string{"sha3-512"}, HashRates: map[string]float64{"sha3-512": 1100}, Budget: time.Second}
+	if o, err := s.Negotiate(hello); err != nil || o.Difficulty != 10 {
+		t.Fatalf("Negotiated %+v with a slow client, expected difficulty 10: %v\n", o, err)
+	}
+
+	// and turned away if that is less than the server accepts
+	hello.HashRates["sha3-512"] = 100
+	if _, err := s.Negotiate(hello); !errors.Is(err, ErrOverBudget) {
+		t.Fatalf("Client over budget got %v, expected ErrOverBudget\n", err)
+	}
+
+	if _, err := s.Negotiate(&Hello{Algorithms: []string{"sha256"}}); !errors.Is(err, ErrNoCommonAlgorithm) {
+		t.Fatalf("Client without a common algorithm got %v, expected ErrNoCommonAlgorithm\n", err)
+	}
+}
+
+func TestExchange(t *testing.T) {
+	s := newServer(t)
+
+	hello := &Hello{
+		Algorithms: []string{"blake3-256"},
+		HashRates:  map[string]float64{"blake3-256": 3000},
+		Budget:     time.Second,
+	}
+	data, err := hello.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Error encoding hello: %v\n", err)
+	}
+
+	reply, err := s.Handle(data)
+	if err != nil {
+		t.Fatalf("Error handling hello: %v\n", err)
+	}
+
+	offer, err := DecodeReply(reply)
+	if err != nil || offer.Algorithm != "blake3-256" || offer.Difficulty != 11 {
+		t.Fatalf("Decoded offer %+v, expected blake3-256 at 11: %v\n", offer, err)
+	}
+
+	pow, err := Solve(context.Background(), offer, []byte("hello"))
+	if err != nil {
+		t.Fatalf("Error solving offer: %v\n", err)
+	}
+
+	if ok, err := s.Validate(context.Background(), pow); !ok || err != nil {
+		t.Fatalf("Proof for the offer was rejected: %v\n", err)
+	}
+
+	// challenges are used once
+	if ok, _ := s.Validate(context.Background(), pow); ok {
+		t.Fatalf("Proof for an answered challenge was accepted\n")
+	}
+}
+
+func TestSeveralWorkers(t *testing.T) {
+	s := NewServer()
+	for _, difficulty := range []int{20, 8} {
+		w, _ := powork.NewWorkerForAlgorithm("blake3-256")
+		w.SetDifficulty(difficulty)
+		if err := s.Add(w, difficulty+4); err != nil {
+			t.Fatalf("Error adding worker: %v\n", err)
+		}
+	}
+
+	// a client too slow for the first worker is offered a challenge by the second, whose
+	// proofs the server has to check with that worker
+	hello := &Hello{Algorithms: []string{"blake3-256"}, HashRates: map[string]float64{"blake3-256": 1100}, Budget: time.Second}
+	offer, err := s.Negotiate(hello)
+	if err != nil || offer.Difficulty != 10 {
+		t.Fatalf("Negotiated %+v, expected difficulty 10: %v\n", offer, err)
+	}
+
+	pow, err := Solve(context.Background(), offer, []byte("hello"))
+	if err != nil {
+		t.Fatalf("Error solving offer: %v\n", err)
+	}
+	if ok, err := s.Validate(context.Background(), pow); !ok || err != nil {
+		t.Fatalf("Proof for the second worker's offer was rejected: %v\n", err)
+	}
+}
+
+func TestRefusals(t *testing.T) {
+	s := newServer(t)
+
+	data, _ := (&Hello{Algorithms: []string{"sha256"}}).MarshalBinary()
+	reply, _ := s.Handle(data)
+	if _, err := DecodeReply(reply); !errors.Is(err, ErrNoCommonAlgorithm) {
+		t.Fatalf("Decoded refusal as %v, expected ErrNoCommonAlgorithm\n", err)
+	}
+
+	// a client of a later version is told the server's
+	data[0] = Version + 1
+	reply, err := s.Handle(data)
+	if err != nil {
+		t.Fatalf("Error handling hello of a later version: %v\n", err)
+	}
+	if _, err := DecodeReply(reply); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("Decoded refusal as %v, expected ErrUnsupportedVersion\n", err)
+	}
+
+	data[0] = Version
+	if _, err := s.Handle(data[:3]); !errors.Is(err, powork.ErrInvalidEncoding) {
+		t.Fatalf("Truncated hello did not fail with ErrInvalidEncoding: %v\n", err)
+	}
+}
+
+func TestHelloEncoding(t *testing.T) {
+	hello := &Hello{
+		Algorithms: []string{"sha256", "blake2b-512"},
+		HashRates:  map[string]float64{"sha256": 5e6},
+		Budget:     1500 * time.Millisecond,
+	}
+	data, err := hello.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Error encoding hello: %v\n", err)
+	}
+
+	// an algorithm the server doesn't know is left out
+	data = append(data, 250, 0, 0, 0, 0, 0, 0, 0, 1)
+	data[6]++
+
+	var decoded Hello
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Error decoding hello: %v\n", err)
+	}
+
+	if len(decoded.Algorithms) != 2 || decoded.Algorithms[0] != "sha256" || decoded.Algorithms[1] != "blake2b-512" {
+		t.Fatalf("Decoded algorithms %v\n", decoded.Algorithms)
+	}
+	if decoded.Budget != hello.Budget || decoded.HashRates["sha256"] != 5e6 || len(decoded.HashRates) != 1 {
+		t.Fatalf("Decoded hello %+v differs from the original\n", decoded)
+	}
+
+	if _, err := (&Hello{Algorithms: []string{"sha1"}}).MarshalBinary(); !errors.Is(err, powork.ErrInvalidSetting) {
+		t.Fatalf("Unknown algorithm did not fail with ErrInvalidSetting: %v\n", err)
+	}
+}
+
+func TestServerSettings(t *testing.T) {
+	w, _ := powork.NewWorkerForAlgorithm("sha256")
+	w.SetDifficulty(16)
+	if err := NewServer().Add(w, 8); !errors.Is(err, powork.ErrInvalidDifficulty) {
+		t.Fatalf("Maximum below the worker's difficulty did not fail with ErrInvalidDifficulty: %v\n", err)
+	}
+
+	if err := NewServer().Add(powork.NewWorkerWithHash(sha256.New()), 16); !errors.Is(err, powork.ErrInvalidSetting) {
+		t.Fatalf("Worker with an unnamed hash did not fail with ErrInvalidSetting: %v\n", err)
+	}
+}
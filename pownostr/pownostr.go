@@ -0,0 +1,265 @@
+// Package pownostr mines and validates Nostr proof of work as specified by NIP-13. The work
+// of an event is the number of leading zero bits of its ID, the SHA-256 of its NIP-01
+// serialization. Miners vary a nonce tag,
+//
+//	["nonce", "<nonce>", "<target difficulty>"]
+//
+// whose third entry commits to the difficulty aimed at, so that an event that happens to
+// exceed a low target can't be passed off as aimed at a higher one.
+//
+// Mining changes the event's ID, so events are signed after they are mined.
+package pownostr
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/bits"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Zumium/powork"
+)
+
+// checkInterval is how many nonces a mining goroutine tries between checks of its context
+const checkInterval = 1 << 12
+
+// Event is a Nostr event
+type Event struct {
+	ID        string     `json:"id"`
+	PubKey    string     `json:"pubkey"`
+	CreatedAt int64      `json:"created_at"`
+	Kind      int        `json:"kind"`
+	Tags      [][]string `json:"tags"`
+	Content   string     `json:"content"`
+	Sig       string     `json:"sig"`
+}
+
+// Serialize returns the NIP-01 serialization of the event that its ID is the hash of:
+//
+//	[0,"<pubkey>",<created_at>,<kind>,<tags>,"<content>"]
+func (e *Event) Serialize() []byte {
+	prefix, suffix := e.serialize(-1)
+	return append(prefix, suffix...)
+}
+
+// serialize returns the serialization split in front of the value of the tag at index
+// nonceTag, or whole in prefix if nonceTag is -1
+func (e *Event) serialize(nonceTag int) ([]byte, []byte) {
+	b := []byte(`[0,`)
+	b = appendString(b, e.PubKey)
+	b = append(b, ',')
+	b = strconv.AppendInt(b, e.CreatedAt, 10)
+	b = append(b, ',')
+	b = strconv.AppendInt(b, int64(e.Kind), 10)
+	b = append(b, ",["...)
+
+	var prefix []byte
+	for i, tag := range e.Tags {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, '[')
+		for j, v := range tag {
+			if j > 0 {
+				b = append(b, ',')
+			}
+			if i == nonceTag && j == 1 {
+				// the nonce goes between the quotes
+				b = append(b, '"')
+				prefix, b = b, nil
+				b = append(b, '"')
+				continue
+			}
+			b = appendString(b, v)
+		}
+		b = append(b, ']')
+	}
+
+	b = append(b, "],"...)
+	b = appendString(b, e.Content)
+	b = append(b, ']')
+
+	if prefix == nil {
+		return b, nil
+	}
+	return prefix, b
+}
+
+// appendString appends s as a JSON string escaped as NIP-01 demands: quotes, backslashes and
+// control characters only
+func appendString(b []byte, s string) []byte {
+	b = append(b, '"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			b = append(b, `\"`...)
+		case '\\':
+			b = append(b, `\\`...)
+		case '\n':
+			b = append(b, `\n`...)
+		case '\r':
+			b = append(b, `\r`...)
+		case '\t':
+			b = append(b, `\t`...)
+		case '\b':
+			b = append(b, `\b`...)
+		case '\f':
+			b = append(b, `\f`...)
+		default:
+			if c < 0x20 {
+				b = fmt.Appendf(b, `\u%04x`, c)
+			} else {
+				b = append(b, c)
+			}
+		}
+	}
+	return append(b, '"')
+}
+
+// ComputeID returns the ID the event should have: the hex SHA-256 of its serialization
+func (e *Event) ComputeID() string {
+	sum := sha256.Sum256(e.Serialize())
+	return hex.EncodeToString(sum[:])
+}
+
+// Difficulty returns the number of leading zero bits of a hex event ID, or 0 if it is not hex
+func Difficulty(id string) int {
+	b, err := hex.DecodeString(id)
+	if err != nil {
+		return 0
+	}
+	return leadingZeros(b)
+}
+
+// leadingZeros counts the leading zero bits of sum
+func leadingZeros(sum []byte) int {
+	n := 0
+	for _, x := range sum {
+		if x != 0 {
+			return n + bits.LeadingZeros8(x)
+		}
+		n += 8
+	}
+	return n
+}
+
+// Committed returns the target difficulty committed to in the event's nonce tag, if it has
+// one with a target
+func (e *Event) Committed() (int, bool) {
+	for _, tag := range e.Tags {
+		if len(tag) >= 3 && tag[0] == "nonce" {
+			d, err := strconv.Atoi(tag[2])
+			return d, err == nil
+		}
+	}
+	return 0, false
+}
+
+// Mine finds a nonce that gives the event at least difficulty leading zero bits, searching on
+// one goroutine per CPU. It replaces the event's nonce tag, or adds one, and sets its ID; the
+// event has to be signed afterwards.
+func Mine(ctx context.Context, e *Event, difficulty int) error {
+	if difficulty < 1 || difficulty > sha256.Size*8 {
+		return fmt.Errorf("%w: must be between 1 and %d bits", powork.ErrInvalidDifficulty, sha256.Size*8)
+	}
+
+	// the nonce tag goes last, with a placeholder where the nonce goes
+	tags := make([][]string, 0, len(e.Tags)+1)
+	for _, tag := range e.Tags {
+		if len(tag) == 0 || tag[0] != "nonce" {
+			tags = append(tags, tag)
+		}
+	}
+	tags = append(tags, []string{"nonce", "", strconv.Itoa(difficulty)})
+	e.Tags = tags
+
+	prefix, suffix := e.serialize(len(tags) - 1)
+	h := sha256.New()
+	h.Write(prefix)
+	state, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lanes := runtime.NumCPU()
+	var found atomic.Bool
+	var nonce uint64
+	var wg sync.WaitGroup
+	for lane := range lanes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if n, ok := mineLane(ctx, state, suffix, difficulty, uint64(lane), uint64(lanes)); ok && found.CompareAndSwap(false, true) {
+				nonce = n
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if !found.Load() {
+		return ctx.Err()
+	}
+
+	tags[len(tags)-1][1] = strconv.FormatUint(nonce, 10)
+	e.ID = e.ComputeID()
+	return nil
+}
+
+// mineLane tries the nonces start, start+step, ... until one meets difficulty or ctx is done
+func mineLane(ctx context.Context, state, suffix []byte, difficulty int, start, step uint64) (uint64, bool) {
+	h := sha256.New()
+	restore := h.(encoding.BinaryUnmarshaler)
+	buf := make([]byte, 0, 20+len(suffix))
+	sum := make([]byte, 0, sha256.Size)
+
+	for i, n := 0, start; ; i, n = i+1, n+step {
+		if i%checkInterval == 0 && ctx.Err() != nil {
+			return 0, false
+		}
+
+		restore.UnmarshalBinary(state)
+		buf = strconv.AppendUint(buf[:0], n, 10)
+		buf = append(buf, suffix...)
+		h.Write(buf)
+		if sum = h.Sum(sum[:0]); leadingZeros(sum) >= difficulty {
+			return n, true
+		}
+	}
+}
+
+// MineJSON mines the event in JSON data as Mine does and returns it re-encoded
+func MineJSON(ctx context.Context, data []byte, difficulty int) ([]byte, error) {
+	var e Event
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+
+	if err := Mine(ctx, &e, difficulty); err != nil {
+		return nil, err
+	}
+	return json.Marshal(&e)
+}
+
+// Validate reports whether the event's ID is the hash of its content and has at least min
+// leading zero bits. An event whose nonce tag commits to a target below min is not valid
+// even if its ID happens to meet min, as NIP-13 recommends. The signature is not checked.
+func Validate(e *Event, min int) bool {
+	if e.ID != e.ComputeID() || Difficulty(e.ID) < min {
+		return false
+	}
+
+	if target, ok := e.Committed(); ok && target < min {
+		return false
+	}
+	return true
+}
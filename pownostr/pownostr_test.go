@@ -0,0 +1,95 @@
+package pownostr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/Zumium/powork"
+)
+
+// nip13Example is the example event of NIP-13
+const nip13Example = `{
+	"id": "000006d8c378af1779d2feebc7603a125d99eca0ccf1085959b307f64e5dd358",
+	"pubkey": "a48380f4cfcc1ad5378294fcac36439770f9c878dd880ffa94bb74ea54a6f243",
+	"created_at": 1651794653,
+	"kind": 1,
+	"tags": [["nonce", "776797", "20"]],
+	"content": "It's just me mining my own business"
+}`
+
+func TestNIP13Example(t *testing.T) {
+	var e Event
+	if err := json.Unmarshal([]byte(nip13Example), &e); err != nil {
+		t.Fatalf("Error decoding example: %v\n", err)
+	}
+
+	if id := e.ComputeID(); id != e.ID {
+		t.Fatalf("Example has ID %s, computed %s\n", e.ID, id)
+	}
+	if d := Difficulty(e.ID); d != 21 {
+		t.Fatalf("Example has difficulty %d, expected 21\n", d)
+	}
+
+	if !Validate(&e, 20) {
+		t.Fatalf("Example is not valid at difficulty 20\n")
+	}
+	// it has 21 bits, but only committed to 20
+	if Validate(&e, 21) {
+		t.Fatalf("Example is valid above its committed target\n")
+	}
+
+	e.Content = "tampered"
+	if Validate(&e, 1) {
+		t.Fatalf("Tampered example is valid\n")
+	}
+}
+
+func TestSerialize(t *testing.T) {
+	e := Event{PubKey: "ab", CreatedAt: 1, Kind: 1, Tags: [][]string{{"p", "cd"}}, Content: "a \"quote\"\n\\ é\x01"}
+	want := `[0,"ab",1,1,[["p","cd"]],"a \"quote\"\n\\ é\u0001"]`
+	if got := string(e.Serialize()); got != want {
+		t.Fatalf("Serialized as %s, expected %s\n", got, want)
+	}
+}
+
+func TestMine(t *testing.T) {
+	e := &Event{
+		PubKey:    "a48380f4cfcc1ad5378294fcac36439770f9c878dd880ffa94bb74ea54a6f243",
+		CreatedAt: 1700000000,
+		Kind:      1,
+		Tags:      [][]string{{"t", "powork"}, {"nonce", "1", "4"}},
+		Content:   "mined with powork",
+	}
+
+	if err := Mine(context.Background(), e, 12); err != nil {
+		t.Fatalf("Error mining: %v\n", err)
+	}
+	if len(e.Tags) != 2 || e.Tags[1][0] != "nonce" || e.Tags[1][2] != "12" {
+		t.Fatalf("Mined event has tags %v\n", e.Tags)
+	}
+	if !Validate(e, 12) {
+		t.Fatalf("Mined event is not valid\n")
+	}
+
+	data, _ := json.Marshal(e)
+	mined, err := MineJSON(context.Background(), data, 8)
+	if err != nil {
+		t.Fatalf("Error mining JSON: %v\n", err)
+	}
+	var back Event
+	json.Unmarshal(mined, &back)
+	if !Validate(&back, 8) {
+		t.Fatalf("Mined JSON event is not valid\n")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Mine(ctx, e, 200); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Canceled mining returned %v\n", err)
+	}
+	if err := Mine(context.Background(), e, 0); !errors.Is(err, powork.ErrInvalidDifficulty) {
+		t.Fatalf("Mining at difficulty 0 returned %v\n", err)
+	}
+}
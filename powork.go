@@ -4,8 +4,13 @@ package powork
 import (
 	"context"
 	"encoding/binary"
-	"errors"
+	"fmt"
 	"hash"
+	"io"
+	"log/slog"
+	"math/big"
+	"runtime"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/sha3"
@@ -13,28 +18,75 @@ import (
 
 // Worker represents an object that calculates proofs of work and verifies them.
 type Worker struct {
-	difficulty int
-	// getHash    func() hash.Hash
-	hasher  hash.Hash
-	maxWait int
+	difficulty  int
+	getHash     func() hash.Hash
+	hasher      hash.Hash
+	algorithm   string
+	maxWait     time.Duration
+	maxIter     uint64
+	concurrency int
+	predicate   Predicate
+	target      *big.Int
+	accepted    map[string]func() hash.Hash
+
+	challengeTTL time.Duration
+	challengeKey []byte
+	challenges   *challengeSet
+
+	spent    SpentStore
+	spentTTL time.Duration
+
+	timestamps  bool
+	maxProofAge time.Duration
+	clockSkew   time.Duration
+
+	randomStart bool
+	startAt     uint64
+	stride      uint64
+	nonceBits   int
+	nonceLen    int
+	bigEndian   bool
+
+	progress         func(iterations uint64, elapsed time.Duration)
+	progressInterval uint64
+
+	bestEffort    bool
+	getMulti      func() MultiHash
+	checkInterval uint64
+	hooks         []Hooks
+	logger        *slog.Logger
+
+	statsMu     sync.Mutex
+	stats       Stats
+	proofHashes uint64 // hashes spent on successful searches
 }
 
 // A PoWork represents a (potentially valid) proof of work for a given message
 type PoWork struct {
 	msg                []byte
 	proof              uint64
+	difficulty         int
+	algorithm          string
+	challenge          []byte
+	timestamp          time.Time
+	previous           []byte
+	extensions         map[string]string
+	nonceLen           int
+	extraNonce         []byte
+	bigEndian          bool
 	requiredIterations int
 }
 
+// Result carries the outcome of an asynchronous proof computation. Exactly one of Proof
+// and Err is non-nil.
+type Result struct {
+	Proof *PoWork
+	Err   error
+}
+
 // GetChannel returns a channel, with the given buffer, that can be used with SendProofToChannel
-func GetChannel(buffer int) chan struct {
-	*PoWork
-	error
-} {
-	return make(chan struct {
-		*PoWork
-		error
-	}, buffer)
+func GetChannel(buffer int) chan Result {
+	return make(chan Result, buffer)
 }
 
 // GetMessage gets the message that the proof of work relates to
@@ -47,9 +99,62 @@ func (p *PoWork) GetMessageString() string {
 	return string(p.msg)
 }
 
+// GetDifficulty gets the difficulty the proof was solved at
+func (p *PoWork) GetDifficulty() int {
+	return p.difficulty
+}
+
+// GetProof gets the nonce that makes the proof valid
+func (p *PoWork) GetProof() uint64 {
+	return p.proof
+}
+
+// GetIterations gets the number of nonces that were tried and rejected before the proof was
+// found, summed across all search goroutines. It is 0 for proofs that were decoded rather
+// than computed.
+func (p *PoWork) GetIterations() int {
+	return p.requiredIterations
+}
+
+// stringMessageLen is the number of message bytes String shows before truncating
+const stringMessageLen = 32
+
+// String summarizes the proof for logging and debugging. Long messages are truncated.
+func (p *PoWork) String() string {
+	msg := p.msg
+	more := ""
+	if len(msg) > stringMessageLen {
+		msg = msg[:stringMessageLen]
+		more = "..."
+	}
+
+	s := fmt.Sprintf("PoWork{msg: %q%s (%d bytes), nonce: %d, difficulty: %d", msg, more, len(p.msg), p.proof, p.difficulty)
+	if id := p.algorithmID(); id != "" {
+		s += ", alg: " + id
+	}
+	if len(p.challenge) > 0 {
+		s += fmt.Sprintf(", challenge: %x", p.challenge)
+	}
+	if !p.timestamp.IsZero() {
+		s += ", timestamp: " + p.timestamp.UTC().Format(time.RFC3339Nano)
+	}
+	if len(p.previous) > 0 {
+		s += fmt.Sprintf(", previous: %x", p.previous)
+	}
+	if len(p.extensions) > 0 {
+		s += fmt.Sprintf(", ext: %v", p.extensions)
+	}
+	if p.nonceLen > 0 {
+		s += fmt.Sprintf(", nonceBytes: %x", p.GetNonce())
+	}
+	return s + fmt.Sprintf(", iterations: %d}", p.requiredIterations)
+}
+
 // NewWorker creates a new Worker with sensible defaults: SHA3-512, 10 bit difficulty, and a 5 second timeout.
 func NewWorker() *Worker {
-	return NewWorkerWithHash(sha3.New512()) // SHA3-512 by default
+	w := NewWorkerWithHashFunc(sha3.New512) // SHA3-512 by default
+	w.algorithm = "sha3-512"
+	return w
 }
 
 // NewWorkerWithHash creates a new worker with given hash
@@ -57,61 +162,146 @@ func NewWorkerWithHash(h hash.Hash) *Worker {
 	w := new(Worker)
 	w.difficulty = 10
 	w.hasher = h
-	w.maxWait = 5000
+	w.maxWait = 5 * time.Second
+	w.concurrency = 1
+	w.challengeTTL = time.Minute
+	w.challenges = newChallengeSet()
+	w.progressInterval = DefaultProgressInterval
+	return w
+}
+
+// NewWorkerWithHashFunc creates a new worker that obtains its hash objects from f
+func NewWorkerWithHashFunc(f func() hash.Hash) *Worker {
+	w := NewWorkerWithHash(f())
+	w.getHash = f
 	return w
 }
 
 // SetDifficulty sets the difficulty of the proof calculated. A higher value represents a more difficult proof. Increases exponentially.
+// Difficulties above MaxDifficulty fail with ErrDifficultyExceedsHash.
 func (p *Worker) SetDifficulty(difficulty int) error {
-	p.difficulty = difficulty
 	if difficulty <= 0 {
-		return errors.New("Difficulty must be at least 1")
+		return fmt.Errorf("%w: must be at least 1", ErrInvalidDifficulty)
+	}
+
+	if bits := p.MaxDifficulty(); difficulty > bits {
+		return fmt.Errorf("%w: %v bits requested, hash has %v", ErrDifficultyExceedsHash, difficulty, bits)
 	}
 
+	p.difficulty = difficulty
 	return nil
 }
 
-// SetTimeout sets the amount of time a Worker will spend computing a proof of work before giving up.
+// MaxDifficulty gets the highest difficulty the Worker's hash allows, its size in bits. The
+// difficulty is not checked again when the hash changes; searches and validations at a
+// difficulty the new hash can't meet fail with ErrDifficultyExceedsHash.
+func (p *Worker) MaxDifficulty() int {
+	return p.hasher.Size() * 8
+}
+
+// GetDifficulty gets the difficulty of the proofs the Worker calculates and accepts
+func (p *Worker) GetDifficulty() int {
+	return p.difficulty
+}
+
+// SetTimeout sets the amount of time, in milliseconds, a Worker will spend computing a proof of
+// work before giving up. A value of 0 means no timeout.
 func (p *Worker) SetTimeout(milliseconds int) error {
-	p.maxWait = milliseconds
-	if p.maxWait < 0 {
-		return errors.New("Timeout must be greater than or equal to 0")
+	return p.SetTimeoutDuration(time.Duration(milliseconds) * time.Millisecond)
+}
+
+// SetTimeoutDuration sets the amount of time a Worker will spend computing a proof of work
+// before giving up. A value of 0 means no timeout, leaving cancellation to the caller's context.
+func (p *Worker) SetTimeoutDuration(d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("%w: timeout must be greater than or equal to 0", ErrInvalidSetting)
 	}
 
+	p.maxWait = d
 	return nil
 }
 
-// SetHasher sets the hash object that the Worker will use
+// GetTimeout gets the amount of time a Worker will spend computing a proof of work, or 0 for no timeout
+func (p *Worker) GetTimeout() time.Duration {
+	return p.maxWait
+}
+
+// SetMaxIterations bounds a proof search to its first n nonces, 0 through n-1 unless the
+// Worker starts elsewhere in the nonce space. A search that exhausts them fails with
+// ErrMaxIterations, whatever the concurrency. A value of 0 means no bound.
+func (p *Worker) SetMaxIterations(n uint64) {
+	p.maxIter = n
+}
+
+// GetMaxIterations gets the number of nonces a proof search may try, or 0 for no bound
+func (p *Worker) GetMaxIterations() uint64 {
+	return p.maxIter
+}
+
+// searchContext derives the context a search runs under from the caller's ctx, adding the Worker's timeout
+func (p *Worker) searchContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.maxWait == 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, p.maxWait)
+}
+
+// SetHasher sets the hash object that the Worker will use. A Worker configured with a single
+// hash object always searches on one goroutine and must not validate proofs concurrently;
+// use SetHashFunc to lift both restrictions.
 func (p *Worker) SetHasher(h hash.Hash) {
 	p.hasher = h
+	p.getHash = nil
+	p.algorithm = ""
+}
+
+// SetHashFunc sets the constructor the Worker uses to obtain fresh hash objects, one per
+// search goroutine.
+func (p *Worker) SetHashFunc(f func() hash.Hash) {
+	p.hasher = f()
+	p.getHash = f
+	p.algorithm = ""
+}
+
+// SetConcurrency sets the number of goroutines used to search for a proof. Each goroutine
+// tries every n-th nonce from a different starting point. A value of 0 uses GOMAXPROCS.
+// The default is 1.
+func (p *Worker) SetConcurrency(n int) error {
+	if n < 0 {
+		return fmt.Errorf("%w: concurrency must be greater than or equal to 0", ErrInvalidSetting)
+	}
+
+	p.concurrency = n
+	return nil
+}
+
+// searchers returns the number of goroutines a proof search will actually use
+func (p *Worker) searchers() int {
+	if p.getHash == nil {
+		return 1
+	}
+
+	if p.concurrency == 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+
+	return p.concurrency
 }
 
 // PrepareProof starts working on creating a proof of work for the passed message and
 // returns immediately.
-func (p *Worker) PrepareProof(msg []byte) chan struct {
-	*PoWork
-	error
-} {
-	return p.PrepareProofWithContext(context.TODO(), msg)
+func (p *Worker) PrepareProof(msg []byte) chan Result {
+	return p.PrepareProofWithContext(context.Background(), msg)
 }
 
 // PrepareProofWithContext does the same thing as PrepareProof except carrying a context
-func (p *Worker) PrepareProofWithContext(ctx context.Context, msg []byte) chan struct {
-	*PoWork
-	error
-} {
-
-	toR := make(chan struct {
-		*PoWork
-		error
-	}, 1)
+func (p *Worker) PrepareProofWithContext(ctx context.Context, msg []byte) chan Result {
+	toR := make(chan Result, 1)
 
 	go func() {
-		r, e := p.doProof(ctx, msg)
-		toR <- struct {
-			*PoWork
-			error
-		}{r, e}
+		r, e := p.DoProofForContext(ctx, msg)
+		toR <- Result{r, e}
 		close(toR)
 	}()
 
@@ -120,24 +310,15 @@ func (p *Worker) PrepareProofWithContext(ctx context.Context, msg []byte) chan s
 
 // SendProofToChannel begins computing a proof of work for the given message, and sends it to
 // the passed channel upon completion.
-func (p *Worker) SendProofToChannel(msg []byte, c chan struct {
-	*PoWork
-	error
-}) {
-	p.SendProofToChannelWithContext(context.TODO(), msg, c)
+func (p *Worker) SendProofToChannel(msg []byte, c chan Result) {
+	p.SendProofToChannelWithContext(context.Background(), msg, c)
 }
 
 // SendProofToChannelWithContext does the same thing as SendProofToChannel except carrying a context
-func (p *Worker) SendProofToChannelWithContext(ctx context.Context, msg []byte, c chan struct {
-	*PoWork
-	error
-}) {
+func (p *Worker) SendProofToChannelWithContext(ctx context.Context, msg []byte, c chan Result) {
 	go func() {
-		r, e := p.doProof(ctx, msg)
-		c <- struct {
-			*PoWork
-			error
-		}{r, e}
+		r, e := p.DoProofForContext(ctx, msg)
+		c <- Result{r, e}
 	}()
 }
 
@@ -148,31 +329,215 @@ func (p *Worker) DoProofForString(msg string) (*PoWork, error) {
 
 // DoProofForStringWithContext does the same thing as DoProofForString except carrying a context
 func (p *Worker) DoProofForStringWithContext(ctx context.Context, msg string) (*PoWork, error) {
-	return p.doProof(ctx, []byte(msg))
+	return p.DoProofForContext(ctx, []byte(msg))
 }
 
 // DoProofFor calculates a proof of work for a byte slice
 func (p *Worker) DoProofFor(msg []byte) (*PoWork, error) {
-	return p.doProof(context.TODO(), msg)
+	return p.DoProofForContext(context.Background(), msg)
+}
+
+// DoProofForContext calculates a proof of work for a byte slice, giving up as soon as ctx
+// is canceled or its deadline passes. The Worker's own timeout still applies on top of ctx.
+func (p *Worker) DoProofForContext(ctx context.Context, msg []byte) (*PoWork, error) {
+	return p.doProof(ctx, p.newBase(msg))
 }
 
-// DoProofForWithContext does the same thing as DoProofFor except carrying a context
+// DoProofForWithContext does the same thing as DoProofForContext
 func (p *Worker) DoProofForWithContext(ctx context.Context, msg []byte) (*PoWork, error) {
-	return p.doProof(ctx, msg)
+	return p.DoProofForContext(ctx, msg)
 }
 
-func (p *Worker) doProof(ctx context.Context, msg []byte) (*PoWork, error) {
-	toR := new(PoWork)
-	toR.msg = msg
-	toR.proof = 0
-	toR.requiredIterations = 0
+// newBase creates the PoWork a search for msg starts from, at the Worker's first nonce and carrying
+// a timestamp if the Worker adds them
+func (p *Worker) newBase(msg []byte) *PoWork {
+	base := &PoWork{msg: msg, bigEndian: p.bigEndian}
+	p.setStartNonce(base)
+	if p.timestamps || p.maxProofAge > 0 {
+		base.timestamp = time.Now().Truncate(time.Millisecond)
+	}
+	return base
+}
+
+// doProof searches for a proof of work for base, which carries the message and everything
+// else the proof is bound to
+func (p *Worker) doProof(ctx context.Context, base *PoWork) (*PoWork, error) {
+	pow, _, err := p.doSearch(ctx, p.newSearchState(), base)
+	return pow, err
+}
 
-	// timeoutChannel := time.After(time.Duration(p.maxWait) * time.Millisecond)
-	localCtx, cancelFunc := context.WithTimeout(ctx, time.Duration(p.maxWait)*time.Millisecond)
+// doSearch searches for a proof of work for base starting at the nonce base carries. If the
+// search ends without a proof because it was canceled, timed out or ran out of iterations,
+// it also returns how far it got: a copy of base whose nonce is the lowest one not yet tried
+// and whose iterations are the number of nonces tried.
+func (p *Worker) doSearch(ctx context.Context, st *searchState, base *PoWork) (*PoWork, *PoWork, error) {
+	return p.observeSearch(ctx, st, p.searchers(), func() (*PoWork, *PoWork, error) {
+		return p.runSearch(ctx, st, base)
+	})
+}
+
+// observeSearch runs a search on n goroutines, reporting it to the Worker's hooks and logger
+func (p *Worker) observeSearch(ctx context.Context, st *searchState, n int, run func() (*PoWork, *PoWork, error)) (*PoWork, *PoWork, error) {
+	start := time.Now()
+	ctxs := p.searchStarted(ctx, n)
+	if p.logger != nil {
+		p.logSearchStart(ctx, n)
+		st.progress = p.logProgress(ctx, st.progress)
+	}
+
+	pow, partial, err := run()
+	p.searchDone(ctx, ctxs, start, pow, partial, err)
+	return pow, partial, err
+}
+
+// runSearch does the work of doSearch
+func (p *Worker) runSearch(ctx context.Context, st *searchState, base *PoWork) (*PoWork, *PoWork, error) {
+	n := p.searchers()
+	if n == 1 {
+		return p.searchOne(ctx, st, p.hasher, base)
+	}
+
+	localCtx, cancelFunc := p.searchContext(ctx)
 	defer cancelFunc()
 
+	results := make(chan Result, n)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(start uint64) {
+			defer wg.Done()
+			r, e := p.search(localCtx, st, p.getHash(), base, start, uint64(n))
+			results <- Result{r, e}
+		}(uint64(i))
+	}
+
+	var winner *PoWork
+	var firstErr error
+	exhausted := 0
+	iterations := 0
+	// lanes leave off at different nonces; everything before the nearest has been tried
+	mask := p.nonceMask()
+	next := ^uint64(0)
+	for i := 0; i < n; i++ {
+		r := <-results
+		if r.Err == nil && winner == nil {
+			winner = r.Proof
+			// stop the losers
+			cancelFunc()
+		} else if r.Err == ErrMaxIterations {
+			// the others may still find a proof in their share of the nonces
+			exhausted++
+		} else if r.Err != nil && firstErr == nil && r.Err != localCtx.Err() {
+			firstErr = r.Err
+			cancelFunc()
+		}
+
+		if r.Proof != nil {
+			iterations += r.Proof.requiredIterations
+			if offset := (r.Proof.proof - base.proof) & mask; offset < next {
+				next = offset
+			}
+		}
+	}
+	wg.Wait()
+	p.recordSearch(st, iterations, winner != nil)
+
+	if winner != nil {
+		winner.requiredIterations = iterations
+		return winner, nil, nil
+	}
+
+	if firstErr != nil {
+		return nil, nil, firstErr
+	}
+
+	if best := st.bestEffortProof(ctx, iterations); best != nil {
+		return best, nil, nil
+	}
+
+	partial := new(PoWork)
+	*partial = *base
+	partial.proof = (base.proof + next) & mask
+	partial.difficulty = p.difficulty
+	partial.algorithm = p.algorithm
+	partial.requiredIterations = iterations
+
+	if exhausted == n {
+		return nil, partial, ErrMaxIterations
+	}
+
+	return nil, partial, searchErr(ctx, localCtx)
+}
+
+// searchOne searches for a proof of work for base on the calling goroutine, hashing with h.
+// It returns how far it got as doSearch does.
+func (p *Worker) searchOne(ctx context.Context, st *searchState, h hash.Hash, base *PoWork) (*PoWork, *PoWork, error) {
+	localCtx, cancelFunc := p.searchContext(ctx)
+	defer cancelFunc()
+
+	r, err := p.search(localCtx, st, h, base, 0, 1)
+	if r != nil {
+		p.recordSearch(st, r.requiredIterations, err == nil)
+	}
+	if err != nil {
+		if err != localCtx.Err() && err != ErrMaxIterations {
+			return nil, r, err
+		}
+
+		if best := st.bestEffortProof(ctx, r.requiredIterations); best != nil {
+			return best, nil, nil
+		}
+
+		if err == localCtx.Err() {
+			return nil, r, searchErr(ctx, localCtx)
+		}
+		return nil, r, err
+	}
+	return r, nil, nil
+}
+
+// searchErr returns the error for a search whose context local, derived from the caller's
+// ctx, is done. The caller's own cancellation or deadline is reported as is; the Worker's
+// timeout becomes ErrTimeout.
+func searchErr(ctx, local context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%w: %w", ErrTimeout, local.Err())
+}
+
+// search tries the nonces start, start+stride, start+2*stride, ... steps of the Worker's
+// nonce stride away from the nonce base carries, using h, until one validates or ctx is
+// done. The returned PoWork is non-nil even on cancellation so the caller can account for
+// the iterations performed. st is shared by all goroutines searching for the same proof.
+func (p *Worker) search(ctx context.Context, st *searchState, h hash.Hash, base *PoWork, start, stride uint64) (*PoWork, error) {
+	toR := new(PoWork)
+	*toR = *base
+	step, mask := p.nonceStride(), p.nonceMask()
+	// offset is the distance from the first nonce of the search, which grows by stride steps
+	// while the nonce itself wraps around at the end of the nonce space
+	offset, inc := start*step, stride*step
+	toR.proof = (base.proof + offset) & mask
+	toR.difficulty = p.difficulty
+	toR.algorithm = p.algorithm
+	toR.requiredIterations = 0
+	best := -1
+	ms := p.newCandidateHasher(h, toR, inc, mask)
+	check := p.newDeadlineCheck()
+
 	for {
-		res, err := p.ValidatePoWork(toR)
+		if offset > mask || (p.maxIter > 0 && offset/step >= p.maxIter) {
+			return toR, ErrMaxIterations
+		}
+
+		sum, err := ms.sum(toR)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := p.validSum(sum)
 		if err != nil {
 			return nil, err
 		}
@@ -180,13 +545,33 @@ func (p *Worker) doProof(ctx context.Context, msg []byte) (*PoWork, error) {
 		if res {
 			break
 		}
+		if st.bestEffort {
+			// only improvements on this goroutine's own best can be the best overall
+			if bits := leadingZeros(sum); bits > best {
+				best = bits
+				st.offer(toR, bits)
+			}
+		}
 		toR.requiredIterations++
-		toR.proof++
+		if offset > mask-inc {
+			// this goroutine has tried its share of the whole nonce space
+			return toR, ErrMaxIterations
+		}
+		offset += inc
+		toR.proof = (base.proof + offset) & mask
+		st.iterated(toR.requiredIterations)
+
+		if st.gate != nil && st.gate.paused.Load() {
+			st.gate.wait(ctx)
+		}
 
+		if !check.due() {
+			continue
+		}
 		select {
-		case <-localCtx.Done():
+		case <-ctx.Done():
 			// canceled or timeout
-			return nil, localCtx.Err()
+			return toR, ctx.Err()
 		default:
 			// continue with the next iteration of the loop
 		}
@@ -197,24 +582,232 @@ func (p *Worker) doProof(ctx context.Context, msg []byte) (*PoWork, error) {
 
 // ValidatePoWork checks the validity of a proof of work. If the proof is valid,
 // true is returned. Otherwise, false. If true is returned, then the
-// error returned must be nil.
+// error returned must be nil. A proof that declares a hash algorithm other than
+// the Worker's, or one accepted with SetAcceptedAlgorithms, is never valid, and
+// neither is a proof older than the Worker's maximum proof age.
 func (p *Worker) ValidatePoWork(pow *PoWork) (bool, error) {
-	// hash := p.getHash()
+	return p.ValidatePoWorkContext(context.Background(), pow)
+}
+
+// ValidatePoWorkContext does the same thing as ValidatePoWork, passing ctx to the Worker's
+// hooks so that the validation can be traced as part of a request
+func (p *Worker) ValidatePoWorkContext(ctx context.Context, pow *PoWork) (bool, error) {
+	start := time.Now()
+	ok, err := p.validatePoWork(pow)
+	p.validated(ctx, "ValidatePoWork", start, pow, ok, false, err)
+	return ok, err
+}
+
+// validatePoWork does the work of ValidatePoWork without calling hooks
+func (p *Worker) validatePoWork(pow *PoWork) (bool, error) {
+	if !p.acceptsAlgorithm(pow) || !p.acceptsAge(pow, time.Now()) {
+		return false, nil
+	}
 
-	p.hasher.Reset()
-	_, err := p.hasher.Write(pow.msg)
+	return p.validate(p.hasherFor(pow), pow)
+}
+
+// ValidateWithMinimum checks a proof against the difficulty it declares rather than the
+// Worker's, and accepts it if that difficulty is at least minBits. This lets a verifier accept
+// proofs from provers configured with a higher difficulty than its own. Algorithm and age are
+// checked as by ValidatePoWork.
+func (p *Worker) ValidateWithMinimum(pow *PoWork, minBits int) (bool, error) {
+	start := time.Now()
+	ok, err := p.validateWithMinimum(pow, minBits)
+	p.validated(context.Background(), "ValidateWithMinimum", start, pow, ok, false, err)
+	return ok, err
+}
+
+// validateWithMinimum does the work of ValidateWithMinimum without calling hooks
+func (p *Worker) validateWithMinimum(pow *PoWork, minBits int) (bool, error) {
+	if minBits < 1 {
+		return false, fmt.Errorf("%w: minimum must be greater than 0", ErrInvalidDifficulty)
+	}
+
+	if pow.difficulty < minBits {
+		return false, nil
+	}
+
+	if !p.acceptsAlgorithm(pow) || !p.acceptsAge(pow, time.Now()) {
+		return false, nil
+	}
+
+	return p.validateAt(p.hasherFor(pow), pow, pow.difficulty)
+}
+
+// acceptsAlgorithm reports whether the hash algorithm pow declares, if any, is the Worker's
+// or one of its accepted algorithms, and whether its nonce is in the Worker's byte order
+func (p *Worker) acceptsAlgorithm(pow *PoWork) bool {
+	if pow.bigEndian != p.bigEndian {
+		return false
+	}
+
+	if pow.algorithm == "" || p.algorithm == "" || pow.algorithm == p.algorithm {
+		return true
+	}
+
+	_, ok := p.accepted[pow.algorithm]
+	return ok
+}
+
+// hasherFor returns a hash object to validate pow with: the Worker's own, unless pow declares
+// another accepted algorithm
+func (p *Worker) hasherFor(pow *PoWork) hash.Hash {
+	if pow.algorithm != p.algorithm {
+		if f, ok := p.accepted[pow.algorithm]; ok {
+			return f()
+		}
+	}
+
+	return p.validationHasher()
+}
+
+// validationHasher returns a hash object for a single validation. Workers configured with a
+// hash constructor get a fresh object so that validation is safe for concurrent use.
+func (p *Worker) validationHasher() hash.Hash {
+	if p.getHash != nil {
+		return p.getHash()
+	}
+
+	return p.hasher
+}
+
+// writeBindings writes everything besides the message that the proof is bound to, each value
+// tagged and length-prefixed. Proofs bound to nothing but their message write nothing, so
+// they hash exactly as H(msg || nonce).
+func (p *PoWork) writeBindings(w io.Writer) error {
+	if len(p.challenge) > 0 {
+		if err := writeField(w, fieldChallenge, p.challenge); err != nil {
+			return err
+		}
+	}
+
+	if !p.timestamp.IsZero() {
+		var ts [8]byte
+		binary.BigEndian.PutUint64(ts[:], uint64(p.timestamp.UnixMilli()))
+		if err := writeField(w, fieldTimestamp, ts[:]); err != nil {
+			return err
+		}
+	}
+
+	if len(p.previous) > 0 {
+		if err := writeField(w, fieldPrevious, p.previous); err != nil {
+			return err
+		}
+	}
+
+	return p.writeExtensions(w)
+}
+
+func (p *Worker) validate(h hash.Hash, pow *PoWork) (bool, error) {
+	sum, err := pow.sum(h)
 	if err != nil {
 		return false, err
 	}
+	return p.validSum(sum)
+}
+
+// validSum reports whether a proof's hash satisfies the Worker's predicate or, without one,
+// its difficulty
+func (p *Worker) validSum(sum []byte) (bool, error) {
+	if p.predicate != nil {
+		return p.predicate.Valid(sum), nil
+	}
+	return meetsDifficulty(sum, p.difficulty)
+}
+
+// writePreimage writes everything the proof's hash is computed over
+func (p *PoWork) writePreimage(w io.Writer) error {
+	if err := p.writePrefix(w); err != nil {
+		return err
+	}
+
+	return p.writeNonce(w)
+}
+
+// writePrefix writes the part of the preimage that is the same for every nonce
+func (p *PoWork) writePrefix(w io.Writer) error {
+	if err := p.writeBindings(w); err != nil {
+		return err
+	}
+
+	_, err := w.Write(p.msg)
+	return err
+}
+
+// writeNonce writes the part of the preimage that changes from one candidate to the next
+func (p *PoWork) writeNonce(w io.Writer) error {
+	var buf [MaxNonceLength]byte
+	_, err := w.Write(p.appendNonce(buf[:0]))
+	return err
+}
 
-	err = binary.Write(p.hasher, binary.LittleEndian, pow.proof)
+// sum computes the proof's hash with h
+func (p *PoWork) sum(h hash.Hash) ([]byte, error) {
+	h.Reset()
+	if err := p.writePreimage(h); err != nil {
+		return nil, err
+	}
+
+	return h.Sum(nil), nil
+}
+
+// Sum computes the proof's hash with a hash object from h: the hash of its bindings, message
+// and nonce, which meets the difficulty if the proof is valid
+func (p *PoWork) Sum(h func() hash.Hash) []byte {
+	sum, err := p.sum(h())
+	if err != nil {
+		return nil
+	}
+
+	return sum
+}
+
+// LeadingZeroBits reports how many leading zero bits the proof's hash actually has when
+// computed with a hash object from h. This is usually more than the difficulty the proof
+// was solved at.
+func (p *PoWork) LeadingZeroBits(h func() hash.Hash) int {
+	sum, err := p.sum(h())
+	if err != nil {
+		return 0
+	}
+
+	return leadingZeros(sum)
+}
+
+// MeasureDifficulty reports how many leading zero bits the proof achieves with the Worker's
+// hash, so that proofs can be ranked by the work they represent. A proof that declares a
+// hash algorithm the Worker does not accept achieves 0.
+func (p *Worker) MeasureDifficulty(pow *PoWork) int {
+	if !p.acceptsAlgorithm(pow) {
+		return 0
+	}
+
+	sum, err := pow.sum(p.hasherFor(pow))
+	if err != nil {
+		return 0
+	}
+
+	return leadingZeros(sum)
+}
+
+// validateAt checks pow against the given difficulty instead of the Worker's
+func (p *Worker) validateAt(h hash.Hash, pow *PoWork, difficulty int) (bool, error) {
+	sum, err := pow.sum(h)
 	if err != nil {
 		return false, err
 	}
+	return meetsDifficulty(sum, difficulty)
+}
+
+// meetsDifficulty reports whether sum starts with difficulty zero bits
+func meetsDifficulty(sum []byte, difficulty int) (bool, error) {
+	if difficulty > len(sum)*8 {
+		return false, fmt.Errorf("%w: %v bits requested, hash has %v", ErrDifficultyExceedsHash, difficulty, len(sum)*8)
+	}
 
-	sum := p.hasher.Sum(nil)
-	// validate that the first N bits of the sum are 0, where N = p.difficulty
-	N := p.difficulty
+	// validate that the first N bits of the sum are 0, where N = difficulty
+	N := difficulty
 	for _, x := range sum {
 		for i := 0; i < 8; i++ {
 			if (x<<1)>>1 == x {
@@ -231,6 +824,6 @@ func (p *Worker) ValidatePoWork(pow *PoWork) (bool, error) {
 		}
 	}
 
-	return false, errors.New("Buffer overrun: not enough bits in hash")
-
+	// only reachable for an all zero sum and a difficulty below 1
+	return true, nil
 }
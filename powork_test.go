@@ -1,8 +1,13 @@
 package powork
 
+import "bytes"
+import "errors"
+import "fmt"
+import "strings"
 import "testing"
 import "time"
 import "crypto/md5"
+import "golang.org/x/crypto/sha3"
 import "golang.org/x/net/context"
 
 func TestDefaultPoWork(t *testing.T) {
@@ -129,13 +134,13 @@ func TestDefaultTimeout(t *testing.T) {
 	go func(c chan int) {
 		_, err := toR.DoProofFor(s)
 		if err == nil {
-			t.Fatalf("Difficulty of 100 did not produce a timeout.")
+			t.Errorf("Difficulty of 100 did not produce a timeout.")
 		}
 		c <- 1
 	}(c)
 
-	beforeTimeout := time.After(time.Duration(toR.maxWait/2) * time.Millisecond)
-	afterTimeout := time.After(time.Duration(toR.maxWait*2) * time.Millisecond)
+	beforeTimeout := time.After(toR.maxWait / 2)
+	afterTimeout := time.After(toR.maxWait * 2)
 
 	select {
 	case <-c:
@@ -172,15 +177,15 @@ func TestCustomTimeout(t *testing.T) {
 	go func(c chan int) {
 		_, err := toR.DoProofFor(s)
 		if err == nil {
-			t.Fatalf("Difficulty of 100 did not produce a timeout.")
+			t.Errorf("Difficulty of 100 did not produce a timeout.")
 		}
 		c <- 1
 	}(c)
 
 	toR.SetTimeout(1000)
 
-	beforeTimeout := time.After(time.Duration(toR.maxWait/2) * time.Millisecond)
-	afterTimeout := time.After(time.Duration(toR.maxWait*2) * time.Millisecond)
+	beforeTimeout := time.After(toR.maxWait / 2)
+	afterTimeout := time.After(toR.maxWait * 2)
 
 	select {
 	case <-c:
@@ -204,6 +209,43 @@ func TestCustomTimeout(t *testing.T) {
 	}
 }
 
+func TestTimeoutDuration(t *testing.T) {
+	toR := NewWorker()
+	if err := toR.SetTimeoutDuration(250 * time.Millisecond); err != nil {
+		t.Fatalf("Could not set timeout: %v\n", err)
+	}
+	if toR.GetTimeout() != 250*time.Millisecond {
+		t.Fatalf("Timeout is %v, expected 250ms\n", toR.GetTimeout())
+	}
+
+	toR.SetTimeout(1500)
+	if toR.GetTimeout() != 1500*time.Millisecond {
+		t.Fatalf("Millisecond timeout is %v, expected 1.5s\n", toR.GetTimeout())
+	}
+
+	if err := toR.SetTimeoutDuration(-time.Second); err == nil {
+		t.Fatalf("Negative timeout was accepted\n")
+	}
+}
+
+func TestZeroTimeoutMeansUnlimited(t *testing.T) {
+	toR := NewWorker()
+	toR.SetTimeout(0)
+
+	// a zero timeout used to expire before the first iteration
+	if _, err := toR.DoProofForString("A message with all the time in the world"); err != nil {
+		t.Fatalf("Zero timeout did not mean unlimited: %v\n", err)
+	}
+
+	// the caller's context still stops the search
+	toR.SetDifficulty(100)
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancelFunc()
+	if _, err := toR.DoProofForContext(ctx, []byte("A message that takes forever")); err != context.DeadlineExceeded {
+		t.Fatalf("Unlimited search ignored the caller's context: %v\n", err)
+	}
+}
+
 func TestCustomHash(t *testing.T) {
 	toR := NewWorker()
 	toR.SetHasher(md5.New())
@@ -239,11 +281,11 @@ func TestAsync(t *testing.T) {
 	// wait on the result
 	res := <-recvr
 
-	if res.error != nil {
-		t.Fatalf("Error: %v\n", res.error)
+	if res.Err != nil {
+		t.Fatalf("Error: %v\n", res.Err)
 	}
 
-	proof := res.PoWork
+	proof := res.Proof
 
 	ok, _ := worker.ValidatePoWork(proof)
 
@@ -265,11 +307,11 @@ func TestSendToChannel(t *testing.T) {
 
 	res := <-c
 
-	if res.error != nil {
-		t.Fatalf("Error occurred during proof generation: %v\n", res.error)
+	if res.Err != nil {
+		t.Fatalf("Error occurred during proof generation: %v\n", res.Err)
 	}
 
-	ok, _ := worker.ValidatePoWork(res.PoWork)
+	ok, _ := worker.ValidatePoWork(res.Proof)
 	if !ok {
 		t.Fatalf("Could not validate message\n")
 	}
@@ -301,7 +343,7 @@ func TestCancelCalculationPerpareProof(t *testing.T) {
 	<-time.After(time.Second)
 	cancelFunc()
 	r := <-out
-	if r.error != context.Canceled {
+	if r.Err != context.Canceled {
 		t.Fatal("error type wrong")
 	}
 }
@@ -312,21 +354,85 @@ func TestCancelCalculationSendProof(t *testing.T) {
 
 	worker.SetDifficulty(30)
 
-	out := make(chan struct {
-		*PoWork
-		error
-	}, 1)
+	out := GetChannel(1)
 	defer close(out)
 	ctx, cancelFunc := context.WithCancel(context.Background())
 	worker.SendProofToChannelWithContext(ctx, messageToProve, out)
 	<-time.After(time.Second)
 	cancelFunc()
 	r := <-out
-	if r.error != context.Canceled {
+	if r.Err != context.Canceled {
 		t.Fatal("error type wrong")
 	}
 }
 
+func TestDeadlineDoProofForContext(t *testing.T) {
+	worker := NewWorker()
+	messageToProve := []byte("I'll prove I did some work with this very message!")
+
+	worker.SetDifficulty(100)
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancelFunc()
+
+	start := time.Now()
+	_, err := worker.DoProofForContext(ctx, messageToProve)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Expected context.DeadlineExceeded, got: %v\n", err)
+	}
+
+	if time.Since(start) >= worker.maxWait {
+		t.Fatalf("Context deadline was not honored before the worker timeout")
+	}
+}
+
+func TestParallelPoWork(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(14)
+	if err := worker.SetConcurrency(4); err != nil {
+		t.Fatalf("Could not set concurrency: %v\n", err)
+	}
+
+	proof, err := worker.DoProofForString("A parallel test message")
+	if err != nil {
+		t.Fatalf("An error occurred while calculating a parallel proof of work: %v\n", err)
+	}
+
+	ok, err := worker.ValidatePoWork(proof)
+	if err != nil || !ok {
+		t.Fatalf("Parallel proof did not validate: %v\n", err)
+	}
+
+	t.Logf("Required iterations: %v\n", proof.requiredIterations)
+}
+
+func TestParallelTimeout(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(100)
+	worker.SetConcurrency(0)
+	worker.SetTimeout(200)
+
+	_, err := worker.DoProofForString("A parallel test message")
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Expected a timeout from the parallel search, got: %v\n", err)
+	}
+}
+
+func TestParallelSharedHasherFallsBack(t *testing.T) {
+	worker := NewWorker()
+	worker.SetHasher(md5.New())
+	worker.SetConcurrency(4)
+
+	if n := worker.searchers(); n != 1 {
+		t.Fatalf("Worker with a single hash object used %v goroutines\n", n)
+	}
+
+	worker.SetHashFunc(md5.New)
+	if n := worker.searchers(); n != 4 {
+		t.Fatalf("Worker with a hash constructor used %v goroutines, expected 4\n", n)
+	}
+}
+
 func BenchmarkDefaultPoWork(b *testing.B) {
 	worker := NewWorker()
 	messageToProve := "I'll prove I did some work with this very message! Benchmark"
@@ -358,6 +464,23 @@ func BenchmarkIncreasedDifficulty(b *testing.B) {
 	}
 }
 
+func BenchmarkParallelPoWork(b *testing.B) {
+	worker := NewWorker()
+	worker.SetDifficulty(20)
+	worker.SetConcurrency(0)
+	messageToProve := "I'll prove I did some work with this very message! Benchmark"
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		proof, _ := worker.DoProofForString(messageToProve)
+		ok, _ := worker.ValidatePoWork(proof)
+		if !ok {
+			b.Fatalf("Proof did not validate")
+		}
+	}
+}
+
 func BenchmarkAsyncProof(b *testing.B) {
 	worker := NewWorker()
 	messageToProve := []byte("Will send a proof of this message to a channel")
@@ -373,14 +496,144 @@ func BenchmarkAsyncProof(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		res := <-c
 
-		if res.error != nil {
-			b.Fatalf("Error occurred during proof generation: %v\n", res.error)
+		if res.Err != nil {
+			b.Fatalf("Error occurred during proof generation: %v\n", res.Err)
 		}
 
-		ok, _ := worker.ValidatePoWork(res.PoWork)
+		ok, _ := worker.ValidatePoWork(res.Proof)
 		if !ok {
 			b.Fatalf("Could not validate message\n")
 		}
 	}
 
 }
+
+func TestMaxIterations(t *testing.T) {
+	worker := NewWorker()
+	worker.SetTimeout(0)
+	worker.SetDifficulty(8)
+
+	msg := []byte("A message with a bounded search")
+	pow, err := worker.DoProofFor(msg)
+	if err != nil {
+		t.Fatalf("Could not compute proof: %v\n", err)
+	}
+
+	// a bound just high enough still finds the same proof
+	worker.SetMaxIterations(pow.proof + 1)
+	if worker.GetMaxIterations() != pow.proof+1 {
+		t.Fatalf("Max iterations is %d, expected %d\n", worker.GetMaxIterations(), pow.proof+1)
+	}
+	bounded, err := worker.DoProofFor(msg)
+	if err != nil || bounded.proof != pow.proof {
+		t.Fatalf("Bounded search did not find proof %d: %v, %v\n", pow.proof, bounded, err)
+	}
+
+	// one less and every goroutine runs out of nonces
+	worker.SetMaxIterations(pow.proof)
+	for _, n := range []int{1, 4} {
+		worker.SetConcurrency(n)
+		if _, err := worker.DoProofFor(msg); err != ErrMaxIterations {
+			t.Fatalf("Expected ErrMaxIterations with %d goroutines, got %v\n", n, err)
+		}
+	}
+
+	worker.SetDifficulty(20)
+	worker.SetMaxIterations(1)
+	if _, err := worker.MintHashcash("adam@cypherspace.org", ""); err != ErrMaxIterations {
+		t.Fatalf("Expected ErrMaxIterations when minting, got %v\n", err)
+	}
+}
+
+func TestProofAccessors(t *testing.T) {
+	worker := NewWorker()
+	pow, err := worker.DoProofForString("A message to inspect")
+	if err != nil {
+		t.Fatalf("Could not compute proof: %v\n", err)
+	}
+
+	if pow.GetProof() != pow.proof || pow.GetIterations() != pow.requiredIterations {
+		t.Fatalf("Accessors do not match the proof: %d, %d\n", pow.GetProof(), pow.GetIterations())
+	}
+
+	// with a nonce counting up from 0 on one goroutine, every earlier nonce was rejected
+	if uint64(pow.GetIterations()) != pow.GetProof() {
+		t.Fatalf("Expected %d iterations, got %d\n", pow.GetProof(), pow.GetIterations())
+	}
+
+	expected := fmt.Sprintf("PoWork{msg: \"A message to inspect\" (20 bytes), nonce: %d, difficulty: 10, alg: sha3-512, iterations: %d}", pow.proof, pow.requiredIterations)
+	if pow.String() != expected {
+		t.Fatalf("Got %s, expected %s\n", pow.String(), expected)
+	}
+
+	long := &PoWork{msg: bytes.Repeat([]byte("x"), 100), challenge: []byte{0xab, 0xcd}}
+	expected = "PoWork{msg: \"" + strings.Repeat("x", stringMessageLen) + "\"... (100 bytes), nonce: 0, difficulty: 0, challenge: abcd, iterations: 0}"
+	if long.String() != expected {
+		t.Fatalf("Got %s, expected %s\n", long.String(), expected)
+	}
+}
+
+func TestValidateWithMinimum(t *testing.T) {
+	prover := NewWorker()
+	prover.SetDifficulty(12)
+	pow, err := prover.DoProofForString("A message proven above the minimum")
+	if err != nil {
+		t.Fatalf("Could not compute proof: %v\n", err)
+	}
+	if pow.GetDifficulty() != 12 {
+		t.Fatalf("Proof declares difficulty %d, expected 12\n", pow.GetDifficulty())
+	}
+
+	// the verifier's own difficulty does not matter
+	verifier := NewWorker()
+	verifier.SetDifficulty(20)
+
+	for _, c := range []struct {
+		min int
+		ok  bool
+	}{{8, true}, {12, true}, {13, false}} {
+		ok, err := verifier.ValidateWithMinimum(pow, c.min)
+		if err != nil || ok != c.ok {
+			t.Fatalf("Minimum %d: got %v, %v, expected %v\n", c.min, ok, err, c.ok)
+		}
+	}
+
+	// overstating the difficulty does not help
+	forged := *pow
+	forged.difficulty = 60
+	if ok, _ := verifier.ValidateWithMinimum(&forged, 40); ok {
+		t.Fatalf("Proof with an overstated difficulty was accepted\n")
+	}
+
+	if _, err := verifier.ValidateWithMinimum(pow, 0); !errors.Is(err, ErrInvalidDifficulty) {
+		t.Fatalf("Expected ErrInvalidDifficulty for a minimum of 0, got %v\n", err)
+	}
+}
+
+func TestMeasureDifficulty(t *testing.T) {
+	worker := NewWorker()
+	pow, err := worker.DoProofForString("A message to measure")
+	if err != nil {
+		t.Fatalf("Could not compute proof: %v\n", err)
+	}
+
+	achieved := worker.MeasureDifficulty(pow)
+	if achieved < pow.GetDifficulty() {
+		t.Fatalf("Proof achieves %d bits, less than its difficulty %d\n", achieved, pow.GetDifficulty())
+	}
+
+	if bits := pow.LeadingZeroBits(sha3.New512); bits != achieved {
+		t.Fatalf("LeadingZeroBits is %d, MeasureDifficulty %d\n", bits, achieved)
+	}
+
+	// the proof is valid up to exactly the measured difficulty
+	if ok, _ := worker.validateAt(sha3.New512(), pow, achieved+1); ok {
+		t.Fatalf("Proof is valid above its measured difficulty %d\n", achieved)
+	}
+
+	other := *pow
+	other.algorithm = "md5"
+	if worker.MeasureDifficulty(&other) != 0 {
+		t.Fatalf("Proof for another algorithm was measured\n")
+	}
+}
@@ -0,0 +1,133 @@
+// Package poworktest provides fakes and helpers for testing code that computes or checks
+// proofs of work, without spending CPU time on real proofs.
+package poworktest
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/Zumium/powork"
+)
+
+// NewProof returns a canned proof with the given message, nonce and declared difficulty. It
+// is not valid for a real Worker unless the nonce happens to be a solution.
+func NewProof(msg []byte, nonce uint64, difficulty int) *powork.PoWork {
+	data, _ := json.Marshal(struct {
+		Message    []byte `json:"msg"`
+		Nonce      uint64 `json:"nonce"`
+		Difficulty int    `json:"difficulty"`
+	}{msg, nonce, difficulty})
+
+	pow := new(powork.PoWork)
+	// cannot fail for data marshalled from the same fields
+	pow.UnmarshalJSON(data)
+	return pow
+}
+
+// Prover is a powork.Prover that returns canned proofs instantly: nonce 0 at Difficulty for
+// every message, or Err if it is set. It records the messages it is asked to prove and is
+// safe for concurrent use.
+type Prover struct {
+	Difficulty int
+	Err        error
+
+	mu       sync.Mutex
+	messages [][]byte
+}
+
+// DoProofFor implements powork.Prover
+func (p *Prover) DoProofFor(msg []byte) (*powork.PoWork, error) {
+	return p.DoProofForContext(context.Background(), msg)
+}
+
+// DoProofForContext implements powork.Prover. It fails with the context's error if ctx is
+// already done.
+func (p *Prover) DoProofForContext(ctx context.Context, msg []byte) (*powork.PoWork, error) {
+	p.mu.Lock()
+	p.messages = append(p.messages, append([]byte(nil), msg...))
+	p.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if p.Err != nil {
+		return nil, p.Err
+	}
+
+	return NewProof(msg, 0, p.Difficulty), nil
+}
+
+// Messages returns the messages the Prover was asked to prove, in order
+func (p *Prover) Messages() [][]byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([][]byte(nil), p.messages...)
+}
+
+// Verifier is a powork.Verifier that returns Valid and Err for every proof. It counts the
+// proofs it checks and is safe for concurrent use.
+type Verifier struct {
+	Valid bool
+	Err   error
+
+	mu    sync.Mutex
+	calls int
+}
+
+// ValidatePoWork implements powork.Verifier
+func (v *Verifier) ValidatePoWork(pow *powork.PoWork) (bool, error) {
+	v.mu.Lock()
+	v.calls++
+	v.mu.Unlock()
+
+	if v.Err != nil {
+		return false, v.Err
+	}
+	return v.Valid, nil
+}
+
+// Calls returns the number of proofs the Verifier has checked
+func (v *Verifier) Calls() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.calls
+}
+
+// NewWorker returns a real Worker at difficulty 1, whose proofs take a couple of hashes,
+// for tests that need proofs a real Worker accepts
+func NewWorker() *powork.Worker {
+	w := powork.NewWorker()
+	w.SetDifficulty(1)
+	return w
+}
+
+// RequireValid fails the test immediately unless v accepts pow
+func RequireValid(t testing.TB, v powork.Verifier, pow *powork.PoWork) {
+	t.Helper()
+
+	ok, err := v.ValidatePoWork(pow)
+	if err != nil {
+		t.Fatalf("Proof %v could not be validated: %v", pow, err)
+	}
+	if !ok {
+		t.Fatalf("Proof %v is not valid", pow)
+	}
+}
+
+// RequireInvalid fails the test immediately unless v rejects pow without an error
+func RequireInvalid(t testing.TB, v powork.Verifier, pow *powork.PoWork) {
+	t.Helper()
+
+	ok, err := v.ValidatePoWork(pow)
+	if err != nil {
+		t.Fatalf("Proof %v could not be validated: %v", pow, err)
+	}
+	if ok {
+		t.Fatalf("Proof %v is valid", pow)
+	}
+}
@@ -0,0 +1,67 @@
+package poworktest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Zumium/powork"
+)
+
+func TestProver(t *testing.T) {
+	p := &Prover{Difficulty: 20}
+
+	pow, err := p.DoProofFor([]byte("first"))
+	if err != nil {
+		t.Fatalf("Fake prover failed: %v\n", err)
+	}
+	if pow.GetDifficulty() != 20 || pow.GetProof() != 0 || pow.GetMessageString() != "first" {
+		t.Fatalf("Unexpected canned proof %v\n", pow)
+	}
+
+	p.Err = errors.New("out of coffee")
+	if _, err := p.DoProofFor([]byte("second")); err != p.Err {
+		t.Fatalf("Expected the configured error, got %v\n", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.DoProofForContext(ctx, []byte("third")); err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v\n", err)
+	}
+
+	msgs := p.Messages()
+	if len(msgs) != 3 || !bytes.Equal(msgs[1], []byte("second")) {
+		t.Fatalf("Unexpected recorded messages %q\n", msgs)
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	v := &Verifier{Valid: true}
+	RequireValid(t, v, NewProof([]byte("anything"), 0, 1))
+
+	v.Valid = false
+	RequireInvalid(t, v, NewProof([]byte("anything"), 0, 1))
+
+	if v.Calls() != 2 {
+		t.Fatalf("Verifier counted %d calls, expected 2\n", v.Calls())
+	}
+}
+
+func TestRealWorker(t *testing.T) {
+	var w powork.Prover = NewWorker()
+	pow, err := w.DoProofFor([]byte("cheap but real"))
+	if err != nil {
+		t.Fatalf("Could not compute proof: %v\n", err)
+	}
+
+	v := powork.NewWorker()
+	v.SetDifficulty(1)
+	RequireValid(t, v, pow)
+
+	// a canned proof with an arbitrary nonce does not fool a real Worker
+	canned := NewProof([]byte("cheap but real"), pow.GetProof()+1, 40)
+	v.SetDifficulty(40)
+	RequireInvalid(t, v, canned)
+}
@@ -0,0 +1,110 @@
+// Package powotel traces the searches and validations of powork Workers with OpenTelemetry
+// spans, so that the time spent on proofs of work shows up in the traces of the requests
+// that needed them.
+//
+// A search is traced as a "powork.Search" span that is a child of the span in the context
+// the search was given, such as the one passed to DoProofForContext. A validation is traced
+// as a span named after the validating method, such as "powork.ValidateChallenge", that is
+// a child of the span in the context passed to ValidatePoWorkContext or
+// ValidateChallengeContext. Spans carry the difficulty, algorithm and outcome as attributes,
+// and searches the number of iterations too:
+//
+//	tracer := powotel.New(nil)
+//	tracer.Instrument(worker)
+package powotel
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zumium/powork"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the spans
+const ScopeName = "github.com/Zumium/powork/powotel"
+
+// Span attribute keys
+const (
+	AttrDifficulty  = attribute.Key("powork.difficulty")
+	AttrAlgorithm   = attribute.Key("powork.algorithm")
+	AttrConcurrency = attribute.Key("powork.concurrency")
+	AttrIterations  = attribute.Key("powork.iterations")
+	AttrOutcome     = attribute.Key("powork.outcome")
+)
+
+// Tracer starts spans for the Workers it instruments
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// New creates a Tracer whose spans come from tp, or from the global TracerProvider if tp is
+// nil
+func New(tp trace.TracerProvider) *Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+
+	return &Tracer{tracer: tp.Tracer(ScopeName)}
+}
+
+// Instrument makes w trace its searches and validations
+func (t *Tracer) Instrument(w *powork.Worker) {
+	w.AddHooks(t.Hooks())
+}
+
+// Hooks returns the hooks Instrument adds, for Workers configured some other way
+func (t *Tracer) Hooks() powork.Hooks {
+	return powork.Hooks{
+		SearchStart: t.searchStart,
+		SearchDone:  t.searchDone,
+		Validated:   t.validated,
+	}
+}
+
+func (t *Tracer) searchStart(ctx context.Context, s powork.SearchInfo) context.Context {
+	ctx, _ = t.tracer.Start(ctx, "powork.Search", trace.WithAttributes(
+		AttrDifficulty.Int(s.Difficulty),
+		AttrAlgorithm.String(s.Algorithm),
+		AttrConcurrency.Int(s.Concurrency),
+	))
+	return ctx
+}
+
+func (t *Tracer) searchDone(ctx context.Context, r powork.SearchResult) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(
+		AttrIterations.Int(r.Iterations),
+		AttrOutcome.String(r.Outcome()),
+	)
+	if r.Err != nil {
+		span.RecordError(r.Err)
+		span.SetStatus(codes.Error, r.Err.Error())
+	}
+	span.End()
+}
+
+// validated records a validation that has already happened as a span that started when it did
+func (t *Tracer) validated(ctx context.Context, r powork.ValidationResult) {
+	end := time.Now()
+	attrs := []attribute.KeyValue{AttrOutcome.String(r.Outcome())}
+	if r.Proof != nil {
+		attrs = append(attrs,
+			AttrDifficulty.Int(r.Proof.GetDifficulty()),
+			AttrAlgorithm.String(r.Proof.GetAlgorithm()),
+		)
+	}
+
+	_, span := t.tracer.Start(ctx, "powork."+r.Method,
+		trace.WithTimestamp(end.Add(-r.Duration)),
+		trace.WithAttributes(attrs...),
+	)
+	if r.Err != nil {
+		span.RecordError(r.Err)
+		span.SetStatus(codes.Error, r.Err.Error())
+	}
+	span.End(trace.WithTimestamp(end))
+}
@@ -0,0 +1,78 @@
+package powotel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Zumium/powork"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// attr gets the value of a span attribute
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	worker := powork.NewWorker()
+	worker.SetDifficulty(8)
+	New(tp).Instrument(worker)
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	pow, err := worker.DoProofForContext(ctx, []byte("a message"))
+	if err != nil {
+		t.Fatalf("Error finding proof: %v\n", err)
+	}
+	worker.ValidatePoWorkContext(ctx, pow)
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Recorded %d spans, expected 3\n", len(spans))
+	}
+
+	search, validate := spans[0], spans[1]
+	if search.Name() != "powork.Search" || search.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("Search span %q is not a child of the request\n", search.Name())
+	}
+	if attr(search, AttrDifficulty).AsInt64() != 8 || attr(search, AttrIterations).AsInt64() != int64(pow.GetIterations()) ||
+		attr(search, AttrOutcome).AsString() != "solved" || attr(search, AttrAlgorithm).AsString() != "sha3-512" {
+		t.Fatalf("Search span has attributes %v\n", search.Attributes())
+	}
+
+	if validate.Name() != "powork.ValidatePoWork" || validate.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("Validation span %q is not a child of the request\n", validate.Name())
+	}
+	if attr(validate, AttrOutcome).AsString() != "valid" || !validate.EndTime().After(validate.StartTime()) {
+		t.Fatalf("Validation span has attributes %v\n", validate.Attributes())
+	}
+}
+
+func TestTracerFailedSearch(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	worker := powork.NewWorker()
+	worker.SetDifficulty(40)
+	worker.SetTimeoutDuration(time.Millisecond)
+	New(tp).Instrument(worker)
+
+	worker.DoProofFor([]byte("a hard proof"))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Status().Code != codes.Error || attr(spans[0], AttrOutcome).AsString() != "timeout" {
+		t.Fatalf("Timed out search was not recorded as an error\n")
+	}
+}
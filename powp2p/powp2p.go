@@ -0,0 +1,409 @@
+// Package powp2p protects libp2p nodes from connection exhaustion and eclipse attacks by
+// admitting new peers only once they have solved a proof of work. Each proof is bound to the
+// IDs of both peers, so it can't be reused to connect to another node or under another
+// identity, and connecting many identities costs a proof each.
+//
+// A Gate is both the node's libp2p ConnectionGater and the handler of the admission protocol:
+//
+//	gate := powp2p.NewGate(powp2p.WithDifficulty(20))
+//	h, err := libp2p.New(libp2p.ConnectionGater(gate))
+//	gate.Attach(h)
+//
+// An inbound connection from a peer that hasn't been admitted is let in on probation. The peer
+// must open a stream for ProtocolID within the timeout and answer the challenge sent on it,
+// or its connection is closed. Peers connect to gated nodes with Client.Connect:
+//
+//	server → peer  frame: difficulty uint8, challenge
+//	peer → server  frame: the proof, bound to the subject "<peer ID> <server ID>"
+//	server → peer  status uint8: 0 if the proof is accepted
+//
+// A frame is a uint16 length in network byte order followed by that many bytes. Handlers of
+// other protocols can check Gate.Admitted to refuse peers on probation.
+package powp2p
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Zumium/powork"
+	"github.com/libp2p/go-libp2p/core/connmgr"
+	"github.com/libp2p/go-libp2p/core/control"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// ProtocolID is the libp2p protocol of the admission handshake
+const ProtocolID protocol.ID = "/powork/admit/1.0.0"
+
+// DefaultTimeout is how long a peer on probation has to complete the handshake, unless
+// another timeout is set with WithTimeout
+const DefaultTimeout = 10 * time.Second
+
+// DefaultMaxPending is how many peers may be on probation at once, unless another limit is
+// set with WithMaxPending
+const DefaultMaxPending = 256
+
+// DefaultAdmissionTTL is how long a peer stays admitted, unless another duration is set with
+// WithAdmissionTTL
+const DefaultAdmissionTTL = time.Hour
+
+// Handshake statuses
+const (
+	statusAccepted = 0
+	statusRejected = 1
+)
+
+// ErrRejected is returned by Client.Admit when the node rejects the proof
+var ErrRejected = errors.New("Proof of work admission rejected")
+
+// Option configures a Gate
+type Option func(*Gate)
+
+// WithWorker sets the Worker used to issue challenges and validate proofs. By default
+// powork.NewWorker is used.
+func WithWorker(w *powork.Worker) Option {
+	return func(g *Gate) {
+		g.worker = w
+	}
+}
+
+// WithDifficulty sets the difficulty demanded from peers, overriding the difficulty of the
+// Gate's Worker
+func WithDifficulty(difficulty int) Option {
+	return func(g *Gate) {
+		g.difficulty = difficulty
+	}
+}
+
+// WithTimeout sets how long a peer on probation has to complete the handshake
+func WithTimeout(d time.Duration) Option {
+	return func(g *Gate) {
+		g.timeout = d
+	}
+}
+
+// WithMaxPending sets how many peers may be on probation at once. Inbound connections from
+// other peers that haven't been admitted are refused while the limit is reached.
+func WithMaxPending(n int) Option {
+	return func(g *Gate) {
+		g.maxPending = n
+	}
+}
+
+// WithAdmissionTTL sets how long a peer stays admitted after its proof, connected or not.
+// Peers whose admission runs out are put on probation the next time they connect.
+func WithAdmissionTTL(d time.Duration) Option {
+	return func(g *Gate) {
+		g.ttl = d
+	}
+}
+
+// Gate is a libp2p ConnectionGater that admits inbound peers once they have solved a proof
+// of work. Outbound connections, which the node chose to make, are not gated. Its methods are
+// safe for concurrent use.
+type Gate struct {
+	worker     *powork.Worker
+	difficulty int
+	timeout    time.Duration
+	maxPending int
+	ttl        time.Duration
+
+	mu       sync.Mutex
+	admitted map[peer.ID]time.Time // until when
+	pending  map[peer.ID]time.Time // the deadline of its handshake
+	swept    time.Time
+}
+
+// NewGate creates a Gate. Pass it to libp2p.ConnectionGater, then Attach it to the host.
+func NewGate(opts ...Option) *Gate {
+	g := &Gate{
+		timeout:    DefaultTimeout,
+		maxPending: DefaultMaxPending,
+		ttl:        DefaultAdmissionTTL,
+		admitted:   make(map[peer.ID]time.Time),
+		pending:    make(map[peer.ID]time.Time),
+		swept:      time.Now(),
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	if g.worker == nil {
+		g.worker = powork.NewWorker()
+	}
+	if g.difficulty > 0 {
+		g.worker.SetDifficulty(g.difficulty)
+	}
+	g.worker.SetChallengeTTL(g.timeout)
+	return g
+}
+
+// Attach installs the handler of the admission protocol on h
+func (g *Gate) Attach(h host.Host) {
+	h.SetStreamHandler(ProtocolID, g.handle)
+}
+
+// Admitted reports whether p has been admitted and its admission has not run out
+func (g *Gate) Admitted(p peer.ID) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return time.Now().Before(g.admitted[p])
+}
+
+// InterceptPeerDial implements connmgr.ConnectionGater, allowing every dial
+func (g *Gate) InterceptPeerDial(p peer.ID) bool {
+	return true
+}
+
+// InterceptAddrDial implements connmgr.ConnectionGater, allowing every dial
+func (g *Gate) InterceptAddrDial(p peer.ID, addr ma.Multiaddr) bool {
+	return true
+}
+
+// InterceptAccept implements connmgr.ConnectionGater. The peer is not known yet, so every
+// connection is accepted up to InterceptSecured.
+func (g *Gate) InterceptAccept(addrs network.ConnMultiaddrs) bool {
+	return true
+}
+
+// InterceptSecured implements connmgr.ConnectionGater, putting inbound peers that haven't been
+// admitted on probation, or refusing them when too many are on probation already
+func (g *Gate) InterceptSecured(dir network.Direction, p peer.ID, addrs network.ConnMultiaddrs) bool {
+	if dir != network.DirInbound {
+		return true
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	g.sweep(now)
+	if now.Before(g.admitted[p]) {
+		return true
+	}
+	if deadline, ok := g.pending[p]; ok && now.Before(deadline) {
+		return true
+	}
+	if len(g.pending) >= g.maxPending {
+		return false
+	}
+
+	g.pending[p] = now.Add(g.timeout)
+	return true
+}
+
+// InterceptUpgraded implements connmgr.ConnectionGater, closing the connection of a peer on
+// probation that hasn't been admitted by the end of its timeout
+func (g *Gate) InterceptUpgraded(conn network.Conn) (bool, control.DisconnectReason) {
+	if conn.Stat().Direction != network.DirInbound {
+		return true, 0
+	}
+
+	p := conn.RemotePeer()
+	if !g.Admitted(p) {
+		time.AfterFunc(g.timeout, func() {
+			if !g.Admitted(p) {
+				conn.Close()
+			}
+		})
+	}
+	return true, 0
+}
+
+// sweep forgets expired probations and admissions. g.mu must be held.
+func (g *Gate) sweep(now time.Time) {
+	if now.Sub(g.swept) < g.timeout {
+		return
+	}
+	g.swept = now
+
+	for p, deadline := range g.pending {
+		if !now.Before(deadline) {
+			delete(g.pending, p)
+		}
+	}
+	for p, until := range g.admitted {
+		if !now.Before(until) {
+			delete(g.admitted, p)
+		}
+	}
+}
+
+// handle runs the node's side of the handshake on a stream for ProtocolID
+func (g *Gate) handle(s network.Stream) {
+	defer s.Close()
+
+	p := s.Conn().RemotePeer()
+	s.SetDeadline(time.Now().Add(g.timeout))
+	if err := g.challenge(s, subject(p, s.Conn().LocalPeer())); err != nil {
+		s.Reset()
+		if errors.Is(err, ErrRejected) && !g.Admitted(p) {
+			s.Conn().Close()
+		}
+		return
+	}
+
+	g.mu.Lock()
+	g.admitted[p] = time.Now().Add(g.ttl)
+	delete(g.pending, p)
+	g.mu.Unlock()
+}
+
+// challenge sends s a challenge and checks the proof it answers with
+func (g *Gate) challenge(s network.Stream, subject string) error {
+	challenge, err := g.worker.NewChallenge()
+	if err != nil {
+		return err
+	}
+
+	if challenge.GetDifficulty() > 0xff {
+		return fmt.Errorf("%w: handshake difficulty must be at most 255", powork.ErrInvalidDifficulty)
+	}
+
+	frame := append([]byte{byte(challenge.GetDifficulty())}, challenge.GetBytes()...)
+	if err := writeFrame(s, frame); err != nil {
+		return err
+	}
+
+	data, err := readFrame(s)
+	if err != nil {
+		return err
+	}
+
+	pow := new(powork.PoWork)
+	ok := false
+	if pow.UnmarshalBinary(data) == nil {
+		ok, err = g.worker.ValidateChallengeSubject(pow, subject)
+		ok = ok && err == nil
+	}
+
+	if !ok {
+		s.Write([]byte{statusRejected})
+		return ErrRejected
+	}
+
+	_, err = s.Write([]byte{statusAccepted})
+	return err
+}
+
+// Client runs the peer's side of the handshake
+type Client struct {
+	// NewWorker returns the Worker used to solve a challenge. Its difficulty is replaced by
+	// the difficulty the node asks for. If nil, powork.NewWorker is used.
+	NewWorker func() *powork.Worker
+
+	// MaxDifficulty is the highest difficulty the Client agrees to solve. Zero means no limit.
+	MaxDifficulty int
+}
+
+// Connect connects h to the node pi like host.Host.Connect, and gets h admitted by it
+func (c *Client) Connect(ctx context.Context, h host.Host, pi peer.AddrInfo) error {
+	if err := h.Connect(ctx, pi); err != nil {
+		return err
+	}
+	return c.Admit(ctx, h, pi.ID)
+}
+
+// Admit runs the handshake with the node p that h is connected to. It returns nil once the
+// node has accepted the proof.
+func (c *Client) Admit(ctx context.Context, h host.Host, p peer.ID) error {
+	s, err := h.NewStream(ctx, p, ProtocolID)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		s.SetDeadline(deadline)
+	}
+
+	frame, err := readFrame(s)
+	if err != nil {
+		return err
+	}
+	if len(frame) < 2 {
+		return fmt.Errorf("%w: challenge frame is too short", powork.ErrInvalidEncoding)
+	}
+
+	difficulty := int(frame[0])
+	if difficulty == 0 {
+		return fmt.Errorf("%w: node sent a challenge without a difficulty", powork.ErrInvalidDifficulty)
+	}
+	if c.MaxDifficulty > 0 && difficulty > c.MaxDifficulty {
+		return fmt.Errorf("%w: challenge of difficulty %d is above the limit of %d", powork.ErrInvalidDifficulty, difficulty, c.MaxDifficulty)
+	}
+
+	var worker *powork.Worker
+	if c.NewWorker != nil {
+		worker = c.NewWorker()
+	} else {
+		worker = powork.NewWorker()
+	}
+
+	if err := worker.SetDifficulty(difficulty); err != nil {
+		return err
+	}
+
+	pow, err := worker.DoProofForChallengeSubjectContext(ctx, powork.ChallengeFromBytes(frame[1:]), nil, subject(h.ID(), p))
+	if err != nil {
+		return err
+	}
+
+	data, err := pow.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if err := writeFrame(s, data); err != nil {
+		return err
+	}
+
+	var status [1]byte
+	if _, err := io.ReadFull(s, status[:]); err != nil {
+		return err
+	}
+	if status[0] != statusAccepted {
+		return ErrRejected
+	}
+	return nil
+}
+
+// subject is the subject of the proofs with which initiator gets admitted by responder
+func subject(initiator, responder peer.ID) string {
+	return initiator.String() + " " + responder.String()
+}
+
+// writeFrame writes data with its length in front
+func writeFrame(w io.Writer, data []byte) error {
+	if len(data) > 0xffff {
+		return fmt.Errorf("%w: frame is too long", powork.ErrInvalidEncoding)
+	}
+
+	frame := binary.BigEndian.AppendUint16(make([]byte, 0, 2+len(data)), uint16(len(data)))
+	_, err := w.Write(append(frame, data...))
+	return err
+}
+
+// readFrame reads a frame written by writeFrame
+func readFrame(r io.Reader) ([]byte, error) {
+	var n [2]byte
+	if _, err := io.ReadFull(r, n[:]); err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, binary.BigEndian.Uint16(n[:]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Interface guards
+var _ connmgr.ConnectionGater = (*Gate)(nil)
@@ -0,0 +1,112 @@
+package powp2p
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Zumium/powork"
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// newNode starts a gated node listening on loopback
+func newNode(t *testing.T, opts ...Option) (*Gate, host.Host) {
+	gate := NewGate(opts...)
+	h, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"), libp2p.ConnectionGater(gate))
+	if err != nil {
+		t.Fatalf("Error starting node: %v\n", err)
+	}
+	t.Cleanup(func() { h.Close() })
+
+	gate.Attach(h)
+	return gate, h
+}
+
+// newPeer starts a node that only dials out
+func newPeer(t *testing.T) host.Host {
+	h, err := libp2p.New(libp2p.NoListenAddrs)
+	if err != nil {
+		t.Fatalf("Error starting peer: %v\n", err)
+	}
+	t.Cleanup(func() { h.Close() })
+	return h
+}
+
+func info(h host.Host) peer.AddrInfo {
+	return peer.AddrInfo{ID: h.ID(), Addrs: h.Addrs()}
+}
+
+func TestAdmit(t *testing.T) {
+	gate, node := newNode(t, WithDifficulty(8))
+	p := newPeer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := &Client{}
+	if err := client.Connect(ctx, p, info(node)); err != nil {
+		t.Fatalf("Error getting admitted: %v\n", err)
+	}
+	if !gate.Admitted(p.ID()) || gate.Admitted(node.ID()) {
+		t.Fatalf("Peer is not admitted after its proof\n")
+	}
+
+	strict := &Client{MaxDifficulty: 4}
+	if err := strict.Admit(ctx, p, node.ID()); !errors.Is(err, powork.ErrInvalidDifficulty) {
+		t.Fatalf("Challenge above the limit did not fail with ErrInvalidDifficulty: %v\n", err)
+	}
+}
+
+func TestProbation(t *testing.T) {
+	gate, node := newNode(t, WithDifficulty(8), WithTimeout(300*time.Millisecond))
+	p := newPeer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := p.Connect(ctx, info(node)); err != nil {
+		t.Fatalf("Error connecting: %v\n", err)
+	}
+
+	// the connection is closed once the timeout passes without a proof
+	deadline := time.Now().Add(5 * time.Second)
+	for node.Network().Connectedness(p.ID()) == network.Connected {
+		if time.Now().After(deadline) {
+			t.Fatalf("Peer on probation is still connected\n")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if gate.Admitted(p.ID()) {
+		t.Fatalf("Peer was admitted without a proof\n")
+	}
+}
+
+func TestMaxPending(t *testing.T) {
+	_, node := newNode(t, WithDifficulty(8), WithMaxPending(1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := newPeer(t).Connect(ctx, info(node)); err != nil {
+		t.Fatalf("Error connecting: %v\n", err)
+	}
+
+	// the first peer is still on probation. The dialer may finish its side of the security
+	// handshake before the node refuses the connection, so wait for the refusal.
+	p := newPeer(t)
+	p.Connect(ctx, info(node))
+	deadline := time.Now().Add(5 * time.Second)
+	for p.Network().Connectedness(node.ID()) == network.Connected {
+		if time.Now().After(deadline) {
+			t.Fatalf("Peer connected while too many are on probation\n")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if node.Network().Connectedness(p.ID()) == network.Connected {
+		t.Fatalf("Node holds a connection while too many peers are on probation\n")
+	}
+}
@@ -0,0 +1,186 @@
+// Package powrate turns a rate limiter into a proof of work gate. A client over its rate is
+// handed a challenge instead of a 429 Too Many Requests, and the difficulty of the challenge
+// grows with how far over its rate the client is, so that a burst costs a little work and a
+// flood costs a lot.
+//
+// Limiters are token buckets like those of golang.org/x/time/rate. The tokens a client owes
+// for a request are converted into a difficulty with a Scale, or with DifficultyForWait. The
+// powhttp middleware takes a Limiter per request with powhttp.WithRateLimit.
+package powrate
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Zumium/powork"
+	"golang.org/x/time/rate"
+)
+
+// A Limiter counts the requests of a client against its rate
+type Limiter interface {
+	// Take counts a request made at now and returns the number of tokens the client owes
+	// for it, or 0 if the request is within the client's rate
+	Take(now time.Time) float64
+}
+
+// FromRate adapts a token bucket from golang.org/x/time/rate. A request over the rate still
+// takes its token, leaving the bucket in debt, so a client that keeps on requesting owes
+// more and more until it slows down. A request that can never be allowed, because the
+// limiter's burst is 0, owes an infinite number of tokens.
+func FromRate(l *rate.Limiter) Limiter {
+	return rateLimiter{l}
+}
+
+type rateLimiter struct {
+	l *rate.Limiter
+}
+
+func (r rateLimiter) Take(now time.Time) float64 {
+	res := r.l.ReserveN(now, 1)
+	if !res.OK() {
+		return math.Inf(1)
+	}
+
+	delay := res.DelayFrom(now)
+	if delay <= 0 {
+		return 0
+	}
+	return delay.Seconds() * float64(r.l.Limit())
+}
+
+// Clients keeps a token bucket for each client, told apart by a key such as an IP address
+// or an API token, created on first use. Buckets that have filled up again are dropped from
+// time to time. Clients is safe for concurrent use.
+type Clients struct {
+	limit rate.Limit
+	burst int
+
+	mu      sync.Mutex
+	buckets map[string]*rate.Limiter
+	swept   time.Time
+}
+
+// NewClients creates Clients whose buckets allow limit requests per second on average, in
+// bursts of up to burst requests
+func NewClients(limit rate.Limit, burst int) *Clients {
+	return &Clients{
+		limit:   limit,
+		burst:   burst,
+		buckets: make(map[string]*rate.Limiter),
+		swept:   time.Now(),
+	}
+}
+
+// Get returns the Limiter of the client with the given key
+func (c *Clients) Get(key string) Limiter {
+	return FromRate(c.bucket(key, time.Now()))
+}
+
+// Take counts a request by the client with the given key, as by Get(key).Take(now)
+func (c *Clients) Take(key string, now time.Time) float64 {
+	return FromRate(c.bucket(key, now)).Take(now)
+}
+
+// Len returns the number of clients with a bucket
+func (c *Clients) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.buckets)
+}
+
+func (c *Clients) bucket(key string, now time.Time) *rate.Limiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sweep(now)
+
+	l, ok := c.buckets[key]
+	if !ok {
+		l = rate.NewLimiter(c.limit, c.burst)
+		c.buckets[key] = l
+	}
+	return l
+}
+
+// sweep drops the buckets that are full, and so no different from new ones, at most once in
+// the time an empty bucket takes to fill up, or once a second. Buckets that never refill are
+// kept.
+func (c *Clients) sweep(now time.Time) {
+	if c.limit <= 0 {
+		return
+	}
+
+	refill := float64(c.burst) / float64(c.limit)
+	if now.Sub(c.swept).Seconds() < math.Max(refill, 1) {
+		return
+	}
+
+	for key, l := range c.buckets {
+		if l.TokensAt(now) >= float64(c.burst) {
+			delete(c.buckets, key)
+		}
+	}
+	c.swept = now
+}
+
+// A Scale converts the tokens a client owes into the difficulty to demand from it. A client
+// owing up to Step tokens is asked for Base, and each doubling of the debt adds a bit, so
+// the work demanded grows in proportion to the debt, up to Max.
+type Scale struct {
+	Base int
+	// Step is the debt, in tokens, that Base pays for. The default is 1.
+	Step float64
+	// Max is the highest difficulty demanded. The default is powork.DefaultLadderRange
+	// bits above Base.
+	Max int
+}
+
+// Difficulty returns the difficulty for a client owing owed tokens, or Base if it owes none
+func (s Scale) Difficulty(owed float64) int {
+	step := s.Step
+	if step <= 0 {
+		step = 1
+	}
+
+	max := s.Max
+	if max <= 0 {
+		max = s.Base + powork.DefaultLadderRange
+	}
+
+	d := s.Base
+	if owed > step {
+		bits := math.Ceil(math.Log2(owed / step))
+		if bits >= float64(max-s.Base) {
+			return max
+		}
+		d += int(bits)
+	}
+
+	if d > max {
+		return max
+	}
+	return d
+}
+
+// DifficultyForWait returns the difficulty whose expected work takes about as long at
+// hashRate hashes per second as the client would have had to wait to earn the tokens it
+// owes at limit tokens per second. The client then pays for going over its rate with the
+// time it would have spent waiting, in work. The difficulty is at least 1, and a debt that
+// can never be paid off gets math.MaxInt32.
+func DifficultyForWait(owed float64, limit rate.Limit, hashRate float64) int {
+	if owed <= 0 || hashRate <= 0 {
+		return 1
+	}
+
+	wait := owed / float64(limit)
+	hashes := wait * hashRate
+	if hashes <= 2 || math.IsNaN(hashes) {
+		return 1
+	}
+
+	if math.IsInf(hashes, 1) {
+		return math.MaxInt32
+	}
+	return int(math.Round(math.Log2(hashes)))
+}
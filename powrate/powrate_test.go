@@ -0,0 +1,86 @@
+package powrate
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestFromRate(t *testing.T) {
+	now := time.Now()
+	l := FromRate(rate.NewLimiter(10, 2))
+
+	// the burst is free, then each request owes one token more than the one before
+	expected := []float64{0, 0, 1, 2, 3}
+	for i, e := range expected {
+		if owed := l.Take(now); math.Abs(owed-e) > 1e-6 {
+			t.Fatalf("Request %d owes %v tokens, expected %v\n", i+1, owed, e)
+		}
+	}
+
+	// the debt is paid off at 10 tokens a second
+	if owed := l.Take(now.Add(time.Second)); owed != 0 {
+		t.Fatalf("Request after the debt was paid off owes %v tokens\n", owed)
+	}
+
+	if owed := FromRate(rate.NewLimiter(10, 0)).Take(now); !math.IsInf(owed, 1) {
+		t.Fatalf("Request to a limiter without burst owes %v tokens\n", owed)
+	}
+}
+
+func TestClients(t *testing.T) {
+	now := time.Now()
+	c := NewClients(1, 1)
+
+	if c.Take("a", now) != 0 || c.Take("a", now) == 0 {
+		t.Fatalf("Client did not run out of tokens after its burst\n")
+	}
+	if c.Take("b", now) != 0 {
+		t.Fatalf("Another client was limited\n")
+	}
+	if c.Get("b").Take(now) == 0 {
+		t.Fatalf("Get did not return the client's bucket\n")
+	}
+
+	// full buckets are dropped
+	c.Take("c", now.Add(time.Hour))
+	if n := c.Len(); n != 1 {
+		t.Fatalf("Kept %d buckets, expected 1\n", n)
+	}
+}
+
+func TestScale(t *testing.T) {
+	s := Scale{Base: 16, Step: 2, Max: 20}
+
+	expected := map[float64]int{0: 16, 1: 16, 2: 16, 3: 17, 4: 17, 8: 18, 16: 19, 1000: 20, math.Inf(1): 20}
+	for owed, e := range expected {
+		if d := s.Difficulty(owed); d != e {
+			t.Fatalf("Debt of %v tokens got difficulty %d, expected %d\n", owed, d, e)
+		}
+	}
+
+	// default step and maximum
+	if d := (Scale{Base: 10}).Difficulty(1e12); d != 18 {
+		t.Fatalf("Default maximum is %d, expected 18\n", d)
+	}
+	if d := (Scale{Base: 10}).Difficulty(2); d != 11 {
+		t.Fatalf("Debt of two tokens got difficulty %d, expected 11\n", d)
+	}
+}
+
+func TestDifficultyForWait(t *testing.T) {
+	// 2 tokens at 2 a second is a second's wait, 2^20 hashes
+	if d := DifficultyForWait(2, 2, 1<<20); d != 20 {
+		t.Fatalf("A second's wait got difficulty %d, expected 20\n", d)
+	}
+
+	if d := DifficultyForWait(0, 2, 1<<20); d != 1 {
+		t.Fatalf("No debt got difficulty %d, expected 1\n", d)
+	}
+
+	if d := DifficultyForWait(math.Inf(1), 2, 1<<20); d != math.MaxInt32 {
+		t.Fatalf("Infinite debt got difficulty %d\n", d)
+	}
+}
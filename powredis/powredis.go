@@ -0,0 +1,72 @@
+// Package powredis provides a Redis backed powork.SpentStore, so that replay protection
+// works across several servers sharing one Redis.
+package powredis
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zumium/powork"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultPrefix is the prefix of the keys Store writes, unless another one is set
+const DefaultPrefix = "powork:spent:"
+
+// Store is a powork.SpentStore that records spent proofs as Redis keys. Keys expire after
+// the TTL passed by the Worker, which for timestamped proofs is the time left until the
+// proof's timestamp falls outside the Worker's maximum proof age.
+type Store struct {
+	client  redis.UniversalClient
+	prefix  string
+	timeout time.Duration
+}
+
+// New creates a Store using client. Each Redis command is given at most timeout to complete;
+// a timeout of 0 means no limit.
+func New(client redis.UniversalClient, timeout time.Duration) *Store {
+	return &Store{client: client, prefix: DefaultPrefix, timeout: timeout}
+}
+
+// SetPrefix sets the prefix of the keys the Store writes, so that several applications can share a Redis
+func (s *Store) SetPrefix(prefix string) {
+	s.prefix = prefix
+}
+
+// Seen implements powork.SpentStore
+func (s *Store) Seen(pow *powork.PoWork) (bool, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	n, err := s.client.Exists(ctx, s.key(pow)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+// Add implements powork.SpentStore. It uses SET NX, so concurrent servers can't both add a proof.
+func (s *Store) Add(pow *powork.PoWork, ttl time.Duration) (bool, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	added, err := s.client.SetNX(ctx, s.key(pow), 1, ttl).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return !added, nil
+}
+
+func (s *Store) key(pow *powork.PoWork) string {
+	return s.prefix + powork.SpentKey(pow)
+}
+
+func (s *Store) context() (context.Context, context.CancelFunc) {
+	if s.timeout == 0 {
+		return context.WithCancel(context.Background())
+	}
+
+	return context.WithTimeout(context.Background(), s.timeout)
+}
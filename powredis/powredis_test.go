@@ -0,0 +1,89 @@
+package powredis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Zumium/powork"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newStore(t *testing.T) (*Store, *miniredis.Miniredis) {
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return New(client, time.Second), srv
+}
+
+func TestStoreSharedAcrossWorkers(t *testing.T) {
+	store, _ := newStore(t)
+
+	// two servers sharing one Redis
+	a := powork.NewWorker()
+	a.SetSpentStore(store, time.Minute)
+	b := powork.NewWorker()
+	b.SetSpentStore(store, time.Minute)
+
+	proof, _ := powork.NewWorker().DoProofForString("A message accepted once across the fleet")
+
+	ok, err := a.ValidateOnce(proof)
+	if err != nil || !ok {
+		t.Fatalf("Fresh proof did not validate: %v\n", err)
+	}
+
+	ok, err = b.ValidateOnce(proof)
+	if err != nil || ok {
+		t.Fatalf("Proof replayed on another server was accepted\n")
+	}
+}
+
+func TestStoreExpiry(t *testing.T) {
+	store, srv := newStore(t)
+	store.SetPrefix("test:")
+
+	proof, _ := powork.NewWorker().DoProofForString("A message")
+	if dup, err := store.Add(proof, time.Minute); err != nil || dup {
+		t.Fatalf("Fresh proof reported as duplicate: %v\n", err)
+	}
+
+	if !srv.Exists("test:" + powork.SpentKey(proof)) {
+		t.Fatalf("Proof was not stored under the configured prefix\n")
+	}
+
+	srv.FastForward(2 * time.Minute)
+	if seen, _ := store.Seen(proof); seen {
+		t.Fatalf("Expired proof was still seen\n")
+	}
+}
+
+func TestStoreExpiryFollowsTimestamp(t *testing.T) {
+	store, srv := newStore(t)
+
+	worker := powork.NewWorker()
+	worker.SetMaxProofAge(time.Minute)
+	worker.SetSpentStore(store, 24*time.Hour)
+
+	proof, _ := worker.DoProofForString("A timestamped message")
+	if ok, err := worker.ValidateOnce(proof); err != nil || !ok {
+		t.Fatalf("Fresh proof did not validate: %v\n", err)
+	}
+
+	ttl := srv.TTL(DefaultPrefix + powork.SpentKey(proof))
+	if ttl <= 0 || ttl > time.Minute {
+		t.Fatalf("Spent proof expires after %v, expected at most a minute\n", ttl)
+	}
+}
+
+func TestStoreErrors(t *testing.T) {
+	store, srv := newStore(t)
+	srv.Close()
+
+	proof := new(powork.PoWork)
+	if _, err := store.Seen(proof); err == nil {
+		t.Fatalf("Seen did not report an unreachable Redis\n")
+	}
+	if _, err := store.Add(proof, time.Minute); err == nil {
+		t.Fatalf("Add did not report an unreachable Redis\n")
+	}
+}
@@ -0,0 +1,214 @@
+// Package powretry keeps datagram servers stateless until a client has done some work, in the
+// manner of QUIC's Retry packets. The server answers a client's first packet with a retry
+// token instead of state, and only creates state for packets that echo the token along with a
+// proof of work for it:
+//
+//	token, _ := retry.Token(addr)  // send it in a retry packet
+//	...
+//	ok, _ := retry.Validate(addr, echoed)  // the client's encoded proof
+//
+// A token carries its expiry and difficulty, authenticated with HMAC-SHA256 under the
+// server's key along with the client's address, so the server remembers nothing between the
+// two packets, and a token obtained from one address is useless from another. The client
+// answers with a proof bound to the token as its challenge, in the encoding of
+// powork.PoWork.MarshalBinary; the proof carries the token, so there is nothing else to echo.
+//
+// Being stateless, a token and its proof can be replayed from the same address until the
+// token expires. Keep the TTL short.
+package powretry
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+// DefaultTTL is how long a token can be answered, unless another TTL is set with WithTTL
+const DefaultTTL = 10 * time.Second
+
+// A token is laid out as
+//
+//	version    uint8
+//	expires    int64 (Unix milliseconds)
+//	difficulty uint16
+//	random     [tokenRandomLen]byte
+//	mac        [sha256.Size]byte
+//
+// where mac is the HMAC-SHA256 of everything before it followed by the client's address.
+const (
+	tokenVersion   = 1
+	tokenRandomLen = 8
+	tokenBodyLen   = 1 + 8 + 2 + tokenRandomLen
+	tokenLen       = tokenBodyLen + sha256.Size
+)
+
+// Option configures a Retry
+type Option func(*Retry)
+
+// WithWorker sets the Worker used to validate proofs. By default powork.NewWorker is used.
+func WithWorker(w *powork.Worker) Option {
+	return func(r *Retry) {
+		r.worker = w
+	}
+}
+
+// WithDifficulty sets the difficulty of the tokens, instead of the difficulty of the Worker
+func WithDifficulty(difficulty int) Option {
+	return func(r *Retry) {
+		r.difficulty = difficulty
+	}
+}
+
+// WithTTL sets how long a token can be answered
+func WithTTL(d time.Duration) Option {
+	return func(r *Retry) {
+		r.ttl = d
+	}
+}
+
+// Retry issues and checks retry tokens. Its methods are safe for concurrent use.
+type Retry struct {
+	key        []byte
+	worker     *powork.Worker
+	difficulty int
+	ttl        time.Duration
+}
+
+// New creates a Retry authenticating its tokens under key, which must be at least 16 bytes.
+// Servers sharing the key accept each other's tokens.
+func New(key []byte, opts ...Option) (*Retry, error) {
+	if len(key) < 16 {
+		return nil, fmt.Errorf("%w: retry key must be at least 16 bytes", powork.ErrInvalidSetting)
+	}
+
+	r := &Retry{key: append([]byte(nil), key...), ttl: DefaultTTL}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.worker == nil {
+		r.worker = powork.NewWorker()
+	}
+	if r.difficulty == 0 {
+		r.difficulty = r.worker.GetDifficulty()
+	}
+
+	if r.difficulty < 1 || r.difficulty > min(r.worker.MaxDifficulty(), math.MaxUint16) {
+		return nil, fmt.Errorf("%w: retry difficulty must be between 1 and %d", powork.ErrInvalidDifficulty, r.worker.MaxDifficulty())
+	}
+	if r.ttl <= 0 {
+		return nil, fmt.Errorf("%w: retry TTL must be greater than 0", powork.ErrInvalidSetting)
+	}
+	return r, nil
+}
+
+// Token issues a token for the client at addr
+func (r *Retry) Token(addr net.Addr) ([]byte, error) {
+	return r.TokenWithDifficulty(addr, r.difficulty)
+}
+
+// TokenWithDifficulty issues a token as Token does that demands the given difficulty, for
+// example to make clients work harder while the server is under load
+func (r *Retry) TokenWithDifficulty(addr net.Addr, difficulty int) ([]byte, error) {
+	if difficulty < 1 || difficulty > min(r.worker.MaxDifficulty(), math.MaxUint16) {
+		return nil, fmt.Errorf("%w: retry difficulty must be between 1 and %d", powork.ErrInvalidDifficulty, r.worker.MaxDifficulty())
+	}
+
+	t := make([]byte, tokenBodyLen, tokenLen)
+	t[0] = tokenVersion
+	binary.BigEndian.PutUint64(t[1:], uint64(time.Now().Add(r.ttl).UnixMilli()))
+	binary.BigEndian.PutUint16(t[9:], uint16(difficulty))
+	if _, err := rand.Read(t[11:]); err != nil {
+		return nil, err
+	}
+
+	return append(t, r.mac(t, addr)...), nil
+}
+
+// Validate checks the encoded proof echoed by the client at addr. It is valid if it is bound
+// to an unexpired token issued to addr and meets the token's difficulty. Data that is not an
+// encoded proof is reported as an error wrapping powork.ErrInvalidEncoding.
+func (r *Retry) Validate(addr net.Addr, data []byte) (bool, error) {
+	pow := new(powork.PoWork)
+	if err := pow.UnmarshalBinary(data); err != nil {
+		return false, err
+	}
+
+	return r.ValidatePoWork(addr, pow)
+}
+
+// ValidatePoWork does the same thing as Validate for a decoded proof
+func (r *Retry) ValidatePoWork(addr net.Addr, pow *powork.PoWork) (bool, error) {
+	t := pow.GetChallenge()
+	if len(t) != tokenLen || t[0] != tokenVersion || !hmac.Equal(r.mac(t[:tokenBodyLen], addr), t[tokenBodyLen:]) {
+		return false, nil
+	}
+
+	if expires := time.UnixMilli(int64(binary.BigEndian.Uint64(t[1:]))); !time.Now().Before(expires) {
+		return false, nil
+	}
+
+	return r.worker.ValidateWithMinimum(pow, int(binary.BigEndian.Uint16(t[9:])))
+}
+
+// mac authenticates the body of a token for addr
+func (r *Retry) mac(body []byte, addr net.Addr) []byte {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write(body)
+	mac.Write([]byte(addr.String()))
+	return mac.Sum(nil)
+}
+
+// Difficulty returns the difficulty a token demands, so that a client knows how much work to do
+func Difficulty(token []byte) (int, error) {
+	if len(token) != tokenLen || token[0] != tokenVersion {
+		return 0, fmt.Errorf("%w: not a retry token", powork.ErrInvalidEncoding)
+	}
+	return int(binary.BigEndian.Uint16(token[9:])), nil
+}
+
+// Client answers retry tokens
+type Client struct {
+	// NewWorker returns the Worker used to solve a token. Its difficulty is replaced by the
+	// difficulty of the token. If nil, powork.NewWorker is used.
+	NewWorker func() *powork.Worker
+
+	// MaxDifficulty is the highest difficulty the Client agrees to solve. Zero means no limit.
+	MaxDifficulty int
+}
+
+// Solve computes a proof for token and returns it encoded, ready to echo to the server
+func (c *Client) Solve(ctx context.Context, token []byte) ([]byte, error) {
+	difficulty, err := Difficulty(token)
+	if err != nil {
+		return nil, err
+	}
+	if c.MaxDifficulty > 0 && difficulty > c.MaxDifficulty {
+		return nil, fmt.Errorf("%w: token of difficulty %d is above the limit of %d", powork.ErrInvalidDifficulty, difficulty, c.MaxDifficulty)
+	}
+
+	var worker *powork.Worker
+	if c.NewWorker != nil {
+		worker = c.NewWorker()
+	} else {
+		worker = powork.NewWorker()
+	}
+
+	if err := worker.SetDifficulty(difficulty); err != nil {
+		return nil, err
+	}
+
+	pow, err := worker.DoProofForChallengeContext(ctx, powork.ChallengeFromBytes(token), nil)
+	if err != nil {
+		return nil, err
+	}
+	return pow.MarshalBinary()
+}
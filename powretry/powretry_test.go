@@ -0,0 +1,96 @@
+package powretry
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+var (
+	key    = []byte("a retry key of 32 bytes or so...")
+	client = &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 4433}
+	other  = &net.UDPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 4433}
+)
+
+func TestRetry(t *testing.T) {
+	r, err := New(key, WithDifficulty(8))
+	if err != nil {
+		t.Fatalf("Error creating Retry: %v\n", err)
+	}
+
+	token, err := r.Token(client)
+	if err != nil {
+		t.Fatalf("Error issuing token: %v\n", err)
+	}
+	if d, err := Difficulty(token); d != 8 || err != nil {
+		t.Fatalf("Token demands difficulty %d: %v\n", d, err)
+	}
+
+	echoed, err := new(Client).Solve(context.Background(), token)
+	if err != nil {
+		t.Fatalf("Error solving token: %v\n", err)
+	}
+
+	if ok, err := r.Validate(client, echoed); !ok || err != nil {
+		t.Fatalf("Echoed proof is not valid: %v\n", err)
+	}
+
+	// another server sharing the key needs no state either
+	shared, _ := New(key, WithDifficulty(8))
+	if ok, _ := shared.Validate(client, echoed); !ok {
+		t.Fatalf("Echoed proof is not valid at another server\n")
+	}
+
+	if ok, _ := r.Validate(other, echoed); ok {
+		t.Fatalf("Echoed proof is valid from another address\n")
+	}
+
+	stranger, _ := New([]byte("another key of at least 16 bytes"), WithDifficulty(8))
+	if ok, _ := stranger.Validate(client, echoed); ok {
+		t.Fatalf("Echoed proof is valid under another key\n")
+	}
+
+	if _, err := r.Validate(client, []byte("garbage")); !errors.Is(err, powork.ErrInvalidEncoding) {
+		t.Fatalf("Garbage did not fail with ErrInvalidEncoding: %v\n", err)
+	}
+}
+
+func TestRetryDifficulty(t *testing.T) {
+	r, _ := New(key, WithDifficulty(8))
+
+	token, _ := r.TokenWithDifficulty(client, 12)
+	if _, err := (&Client{MaxDifficulty: 10}).Solve(context.Background(), token); !errors.Is(err, powork.ErrInvalidDifficulty) {
+		t.Fatalf("Token above the maximum difficulty returned %v\n", err)
+	}
+
+	// a proof at less than the token demands
+	solver := powork.NewWorker()
+	solver.SetDifficulty(8)
+	pow, _ := solver.DoProofForChallenge(powork.ChallengeFromBytes(token), nil)
+	if ok, _ := r.ValidatePoWork(client, pow); ok {
+		t.Fatalf("Proof below the token's difficulty is valid\n")
+	}
+
+	if _, err := New([]byte("short")); !errors.Is(err, powork.ErrInvalidSetting) {
+		t.Fatalf("Short key did not fail with ErrInvalidSetting: %v\n", err)
+	}
+	if _, err := New(key, WithDifficulty(1<<20)); !errors.Is(err, powork.ErrInvalidDifficulty) {
+		t.Fatalf("Huge difficulty did not fail with ErrInvalidDifficulty: %v\n", err)
+	}
+}
+
+func TestRetryExpiry(t *testing.T) {
+	r, _ := New(key, WithDifficulty(8), WithTTL(time.Millisecond))
+
+	token, _ := r.Token(client)
+	echoed, _ := new(Client).Solve(context.Background(), token)
+	time.Sleep(5 * time.Millisecond)
+
+	if ok, _ := r.Validate(client, echoed); ok {
+		t.Fatalf("Proof for an expired token is valid\n")
+	}
+}
@@ -0,0 +1,94 @@
+package powservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Zumium/powork"
+)
+
+// Client calls a validation service. It is a powork.Verifier, so a frontend can validate
+// proofs with it wherever it would use a Worker. Its methods are safe for concurrent use.
+type Client struct {
+	// URL is the base URL of the service, such as "http://powork.internal:8080"
+	URL string
+
+	// HTTPClient makes the requests. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+}
+
+var _ powork.Verifier = (*Client)(nil)
+
+// NewChallenge asks the service for a challenge at the given difficulty, or at its own if
+// difficulty is 0
+func (c *Client) NewChallenge(ctx context.Context, difficulty int) (*powork.Challenge, error) {
+	challenge := new(powork.Challenge)
+	if err := c.call(ctx, PathChallenges, ChallengeRequest{Difficulty: difficulty}, challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// Verify asks the service whether a proof is valid. A proof the service rejects with an
+// error is reported as not valid along with that error.
+func (c *Client) Verify(ctx context.Context, pow *powork.PoWork) (bool, error) {
+	var resp VerifyResponse
+	if err := c.call(ctx, PathVerify, VerifyRequest{Proof: pow}, &resp); err != nil {
+		return false, err
+	}
+
+	if resp.Error != "" {
+		return false, fmt.Errorf("%s", resp.Error)
+	}
+	return resp.Valid, nil
+}
+
+// ValidatePoWork implements powork.Verifier, calling Verify without a deadline
+func (c *Client) ValidatePoWork(pow *powork.PoWork) (bool, error) {
+	return c.Verify(context.Background(), pow)
+}
+
+// call posts req to the endpoint at path and decodes the response into resp
+func (c *Client) call(ctx context.Context, path string, req, resp any) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(c.URL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/json")
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxRequestSize))
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		var e errorResponse
+		if json.Unmarshal(data, &e) == nil && e.Error != "" {
+			return fmt.Errorf("validation service: %s: %s", res.Status, e.Error)
+		}
+		return fmt.Errorf("validation service: %s", res.Status)
+	}
+
+	return json.Unmarshal(data, resp)
+}
@@ -0,0 +1,182 @@
+// Package powservice runs proof of work challenges and validation as a service, so that a
+// fleet of frontends can hand verification off to it and share one record of spent proofs.
+//
+// The service speaks JSON over HTTP:
+//
+//	POST /v1/challenges  {"difficulty":16}          →  {"challenge":"<base64>","difficulty":16,"expires":...}
+//	POST /v1/verify      {"proof":{...}}            →  {"valid":true}
+//
+// The request body of /v1/challenges may be empty, to issue a challenge at the Worker's
+// difficulty. Proofs are in the JSON encoding of powork.PoWork. A proof bound to a challenge
+// must answer a challenge the service issued; other proofs are checked as by ValidatePoWork. Invalid proofs get
+// {"valid":false}, with an "error" member when validation failed with an error, and bad
+// requests get status 400.
+//
+// To run several replicas of the service, give their Workers the same challenge key and
+// their Servers a shared SpentStore, such as the one of the powredis package, with
+// WithSpentStore. Every proof accepted is then recorded in the store, so that each is
+// accepted once across the fleet.
+//
+// The service does not authenticate its callers; run it where only the frontends reach it.
+// Client is the frontends' side of the protocol.
+package powservice
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+// maxRequestSize bounds the size of request bodies
+const maxRequestSize = 1 << 20
+
+// Paths of the service's endpoints
+const (
+	PathChallenges = "/v1/challenges"
+	PathVerify     = "/v1/verify"
+)
+
+// ChallengeRequest is the request body of PathChallenges
+type ChallengeRequest struct {
+	// Difficulty is the difficulty to issue the challenge at, or 0 for the Worker's
+	Difficulty int `json:"difficulty,omitempty"`
+}
+
+// VerifyRequest is the request body of PathVerify
+type VerifyRequest struct {
+	Proof *powork.PoWork `json:"proof"`
+}
+
+// VerifyResponse is the response body of PathVerify
+type VerifyResponse struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+// errorResponse is the body of responses with an error status
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithSpentStore records the proofs the Server accepts in s for ttl, and rejects proofs that
+// are already recorded there. The ttl should be at least the Worker's challenge TTL, or its
+// maximum proof age for timestamped proofs.
+func WithSpentStore(s powork.SpentStore, ttl time.Duration) Option {
+	return func(srv *Server) {
+		srv.spent = s
+		srv.spentTTL = ttl
+	}
+}
+
+// Server is the http.Handler of the service
+type Server struct {
+	worker   *powork.Worker
+	spent    powork.SpentStore
+	spentTTL time.Duration
+	mux      *http.ServeMux
+}
+
+// NewServer creates a Server issuing challenges and validating proofs with w
+func NewServer(w *powork.Worker, opts ...Option) *Server {
+	s := &Server{worker: w, mux: http.NewServeMux()}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.mux.HandleFunc("POST "+PathChallenges, s.challenge)
+	s.mux.HandleFunc("POST "+PathVerify, s.verify)
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) challenge(w http.ResponseWriter, r *http.Request) {
+	var req ChallengeRequest
+	if err := decode(r, &req, true); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return
+	}
+
+	difficulty := req.Difficulty
+	if difficulty == 0 {
+		difficulty = s.worker.GetDifficulty()
+	}
+
+	c, err := s.worker.NewChallengeWithDifficulty(difficulty)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (s *Server) verify(w http.ResponseWriter, r *http.Request) {
+	var req VerifyRequest
+	if err := decode(r, &req, false); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return
+	}
+	if req.Proof == nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{"request has no proof"})
+		return
+	}
+
+	ok, err := s.validate(r, req.Proof)
+	resp := VerifyResponse{Valid: ok && err == nil}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// validate checks a proof, recording it as spent if the Server has a SpentStore
+func (s *Server) validate(r *http.Request, pow *powork.PoWork) (bool, error) {
+	if s.spent != nil {
+		if seen, err := s.spent.Seen(pow); err != nil || seen {
+			return false, err
+		}
+	}
+
+	var ok bool
+	var err error
+	if len(pow.GetChallenge()) > 0 {
+		ok, err = s.worker.ValidateChallengeContext(r.Context(), pow)
+	} else {
+		ok, err = s.worker.ValidatePoWorkContext(r.Context(), pow)
+	}
+	if err != nil || !ok || s.spent == nil {
+		return ok, err
+	}
+
+	// only real work makes it into the store
+	seen, err := s.spent.Add(pow, s.spentTTL)
+	if err != nil {
+		return false, err
+	}
+	return !seen, nil
+}
+
+// decode decodes the JSON body of a request into v. An empty body is allowed if optional.
+func decode(r *http.Request, v any, optional bool) error {
+	err := json.NewDecoder(io.LimitReader(r.Body, maxRequestSize)).Decode(v)
+	if err == io.EOF && optional {
+		return nil
+	}
+	return err
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,131 @@
+package powservice
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+// newService starts a service and returns a Client for it
+func newService(t *testing.T, w *powork.Worker, opts ...Option) *Client {
+	srv := httptest.NewServer(NewServer(w, opts...))
+	t.Cleanup(srv.Close)
+	return &Client{URL: srv.URL, HTTPClient: srv.Client()}
+}
+
+func TestChallengeVerify(t *testing.T) {
+	w := powork.NewWorker()
+	w.SetDifficulty(8)
+	client := newService(t, w)
+	ctx := context.Background()
+
+	challenge, err := client.NewChallenge(ctx, 0)
+	if err != nil || challenge.GetDifficulty() != 8 {
+		t.Fatalf("Error getting challenge: %v\n", err)
+	}
+
+	solver := powork.NewWorker()
+	solver.SetDifficulty(challenge.GetDifficulty())
+	pow, _ := solver.DoProofForChallenge(challenge, []byte("a request"))
+
+	if ok, err := client.Verify(ctx, pow); !ok || err != nil {
+		t.Fatalf("Proof for the challenge is not valid: %v\n", err)
+	}
+
+	// challenges are single use
+	if ok, _ := client.Verify(ctx, pow); ok {
+		t.Fatalf("Proof for a used challenge is valid\n")
+	}
+
+	// a challenge the service did not issue
+	pow, _ = solver.DoProofForChallenge(powork.ChallengeFromBytes([]byte("made up")), []byte("a request"))
+	if ok, _ := client.ValidatePoWork(pow); ok {
+		t.Fatalf("Proof for a made up challenge is valid\n")
+	}
+
+	if _, err := client.NewChallenge(ctx, 1000); err == nil {
+		t.Fatalf("Challenge at difficulty 1000 was issued\n")
+	}
+}
+
+func TestSharedSpentStore(t *testing.T) {
+	key := []byte("a key shared by the replicas")
+	store := powork.NewMemorySpentStore(100)
+
+	// two replicas sharing a challenge key and a spent store
+	var replicas []*Client
+	for range 2 {
+		w := powork.NewWorker()
+		w.SetDifficulty(8)
+		w.SetChallengeKey(key)
+		replicas = append(replicas, newService(t, w, WithSpentStore(store, time.Minute)))
+	}
+	ctx := context.Background()
+
+	challenge, err := replicas[0].NewChallenge(ctx, 10)
+	if err != nil || challenge.GetDifficulty() != 10 {
+		t.Fatalf("Error getting challenge: %v\n", err)
+	}
+
+	solver := powork.NewWorker()
+	solver.SetDifficulty(challenge.GetDifficulty())
+	pow, _ := solver.DoProofForChallenge(challenge, []byte("a request"))
+
+	if ok, err := replicas[1].Verify(ctx, pow); !ok || err != nil {
+		t.Fatalf("Proof is not valid at another replica: %v\n", err)
+	}
+	for _, replica := range replicas {
+		if ok, _ := replica.Verify(ctx, pow); ok {
+			t.Fatalf("Spent proof is valid again\n")
+		}
+	}
+
+	// proofs without a challenge are spent too
+	pow, _ = solver.DoProofFor([]byte("no challenge"))
+	if ok, err := replicas[0].Verify(ctx, pow); !ok || err != nil {
+		t.Fatalf("Proof without a challenge is not valid: %v\n", err)
+	}
+	if ok, _ := replicas[1].Verify(ctx, pow); ok {
+		t.Fatalf("Spent proof without a challenge is valid again\n")
+	}
+}
+
+func TestBadRequests(t *testing.T) {
+	srv := httptest.NewServer(NewServer(powork.NewWorker()))
+	defer srv.Close()
+
+	for _, req := range []struct{ path, body string }{
+		{PathVerify, ""},
+		{PathVerify, "{}"},
+		{PathVerify, `{"proof":{"msg":"not base64"}}`},
+		{PathChallenges, "not json"},
+	} {
+		res, err := http.Post(srv.URL+req.path, "application/json", strings.NewReader(req.body))
+		if err != nil {
+			t.Fatalf("Error posting to %s: %v\n", req.path, err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s with body %q returned %s\n", req.path, req.body, res.Status)
+		}
+	}
+
+	res, err := http.Get(srv.URL + PathVerify)
+	if err != nil {
+		t.Fatalf("Error getting %s: %v\n", PathVerify, err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("GET %s returned %s\n", PathVerify, res.Status)
+	}
+
+	client := &Client{URL: srv.URL}
+	if err := client.call(context.Background(), PathVerify, struct{}{}, new(VerifyResponse)); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Fatalf("Bad request to the client returned %v\n", err)
+	}
+}
@@ -0,0 +1,268 @@
+package powshard
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+// Defaults of a Coordinator
+const (
+	DefaultRangeSize = 1 << 20
+	DefaultLeaseTTL  = 10 * time.Second
+)
+
+// Option configures a Coordinator
+type Option func(*Coordinator)
+
+// WithRangeSize sets the number of nonces in each leased range. Smaller ranges lose less work
+// when a worker disappears; larger ranges mean fewer requests. The default is
+// DefaultRangeSize.
+func WithRangeSize(n uint64) Option {
+	return func(c *Coordinator) {
+		c.rangeSize = n
+	}
+}
+
+// WithLeaseTTL sets how long a lease lasts without a renewal before its range is handed out
+// again. The default is DefaultLeaseTTL.
+func WithLeaseTTL(d time.Duration) Option {
+	return func(c *Coordinator) {
+		c.ttl = d
+	}
+}
+
+// Coordinator leases nonce ranges to workers. It is the http.Handler of the protocol, and
+// its methods are safe for concurrent use.
+type Coordinator struct {
+	worker    *powork.Worker
+	rangeSize uint64
+	ttl       time.Duration
+	mux       *http.ServeMux
+
+	solveMu sync.Mutex // one job at a time
+
+	mu     sync.Mutex
+	job    *job
+	nextID uint64
+}
+
+// job is the search in progress
+type job struct {
+	id     string
+	msg    []byte
+	next   uint64      // start of the next range never leased
+	retry  []nonceSpan // ranges whose lease expired
+	leases map[string]*lease
+	solved chan *powork.PoWork
+	done   bool
+}
+
+type nonceSpan struct {
+	start, count uint64
+}
+
+type lease struct {
+	nonceSpan
+	expires time.Time
+}
+
+// NewCoordinator creates a Coordinator for proofs at the difficulty of w, which validates
+// the proofs workers report
+func NewCoordinator(w *powork.Worker, opts ...Option) *Coordinator {
+	c := &Coordinator{
+		worker:    w,
+		rangeSize: DefaultRangeSize,
+		ttl:       DefaultLeaseTTL,
+		mux:       http.NewServeMux(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.mux.HandleFunc("POST "+PathLease, c.lease)
+	c.mux.HandleFunc("POST "+PathRenew, c.renew)
+	c.mux.HandleFunc("POST "+PathReport, c.report)
+	return c
+}
+
+// ServeHTTP implements http.Handler
+func (c *Coordinator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mux.ServeHTTP(w, r)
+}
+
+// Solve leases the nonce space of msg to workers and returns the proof once one of them
+// reports it, or ctx's error if it is done first. Jobs run one at a time; a second call
+// waits for the first to finish.
+func (c *Coordinator) Solve(ctx context.Context, msg []byte) (*powork.PoWork, error) {
+	if c.rangeSize == 0 {
+		return nil, fmt.Errorf("%w: range size must be at least 1", powork.ErrInvalidSetting)
+	}
+
+	c.solveMu.Lock()
+	defer c.solveMu.Unlock()
+
+	c.mu.Lock()
+	c.nextID++
+	j := &job{
+		id:     "j" + strconv.FormatUint(c.nextID, 10),
+		msg:    append([]byte(nil), msg...),
+		leases: make(map[string]*lease),
+		solved: make(chan *powork.PoWork, 1),
+	}
+	c.job = j
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		j.done = true
+		c.job = nil
+	}()
+
+	select {
+	case pow := <-j.solved:
+		return pow, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Leases returns the number of ranges leased and not yet reported
+func (c *Coordinator) Leases() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.job == nil {
+		return 0
+	}
+	return len(c.job.leases)
+}
+
+// reclaim puts the ranges of expired leases up for lease again. The caller must hold c.mu.
+func (c *Coordinator) reclaim(j *job, now time.Time) {
+	for id, l := range j.leases {
+		if now.After(l.expires) {
+			j.retry = append(j.retry, l.nonceSpan)
+			delete(j.leases, id)
+		}
+	}
+}
+
+func (c *Coordinator) lease(w http.ResponseWriter, r *http.Request) {
+	var req struct{}
+	if err := decode(r, &req, true); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	j := c.job
+	if j == nil || j.done {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	now := time.Now()
+	c.reclaim(j, now)
+
+	var span nonceSpan
+	if n := len(j.retry); n > 0 {
+		span, j.retry = j.retry[n-1], j.retry[:n-1]
+	} else {
+		span = nonceSpan{start: j.next, count: c.rangeSize}
+		if j.next+c.rangeSize < j.next {
+			// the last range ends at the top of the nonce space
+			span.count = -j.next
+		}
+		j.next += span.count
+	}
+
+	c.nextID++
+	id := "l" + strconv.FormatUint(c.nextID, 10)
+	j.leases[id] = &lease{nonceSpan: span, expires: now.Add(c.ttl)}
+
+	writeJSON(w, http.StatusOK, Lease{
+		ID:         id,
+		Job:        j.id,
+		Message:    j.msg,
+		Difficulty: c.worker.GetDifficulty(),
+		Start:      span.start,
+		Count:      span.count,
+		TTL:        c.ttl.Milliseconds(),
+	})
+}
+
+func (c *Coordinator) renew(w http.ResponseWriter, r *http.Request) {
+	var req RenewRequest
+	if err := decode(r, &req, false); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var l *lease
+	if c.job != nil && !c.job.done {
+		now := time.Now()
+		c.reclaim(c.job, now)
+		if l = c.job.leases[req.Lease]; l != nil {
+			l.expires = now.Add(c.ttl)
+		}
+	}
+	writeJSON(w, http.StatusOK, Response{OK: l != nil})
+}
+
+func (c *Coordinator) report(w http.ResponseWriter, r *http.Request) {
+	var req ReportRequest
+	if err := decode(r, &req, false); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return
+	}
+
+	c.mu.Lock()
+	j := c.job
+	var l *lease
+	if j != nil {
+		l = j.leases[req.Lease]
+	}
+	c.mu.Unlock()
+
+	if req.Proof == nil {
+		// a range is searched once even if its lease expired meanwhile
+		if l != nil {
+			c.mu.Lock()
+			delete(j.leases, req.Lease)
+			c.mu.Unlock()
+		}
+		writeJSON(w, http.StatusOK, Response{OK: l != nil})
+		return
+	}
+
+	// a proof is welcome from any lease of the job
+	if j == nil || !bytes.Equal(req.Proof.GetMessage(), j.msg) {
+		writeJSON(w, http.StatusOK, Response{})
+		return
+	}
+	if ok, err := c.worker.ValidatePoWorkContext(r.Context(), req.Proof); err != nil || !ok {
+		writeJSON(w, http.StatusOK, Response{})
+		return
+	}
+
+	c.mu.Lock()
+	if !j.done {
+		j.done = true
+		j.solved <- req.Proof
+	}
+	c.mu.Unlock()
+	writeJSON(w, http.StatusOK, Response{OK: true})
+}
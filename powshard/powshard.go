@@ -0,0 +1,96 @@
+// Package powshard splits the search for one proof of work across remote workers by nonce
+// range. A Coordinator leases disjoint ranges of the nonce space to workers, hands a range
+// out again when its lease expires without a report, and calls every worker off as soon as
+// one of them finds the proof.
+//
+// The Coordinator speaks JSON over HTTP:
+//
+//	POST /v1/lease   {}                 →  {"lease":"l1","job":"j1","msg":"<base64>","difficulty":32,"start":0,"count":1048576,"ttl":10000}
+//	POST /v1/renew   {"lease":"l1"}     →  {"ok":true}
+//	POST /v1/report  {"lease":"l1","proof":{...}}  →  {"ok":true}
+//
+// A lease request gets status 204 when there is no job. Workers renew their lease at least
+// once per "ttl" milliseconds while they search; a renewal answered with {"ok":false} means
+// the job is over or the range was handed to someone else, and the worker should stop. A
+// worker reports its range with the proof it found, or without a proof once it has searched
+// the whole range. Proofs are in the JSON encoding of powork.PoWork, and workers must hash
+// with the Coordinator's algorithm.
+//
+// The Coordinator does not authenticate its workers; run it where only they reach it.
+// Worker is the workers' side of the protocol.
+package powshard
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+// maxRequestSize bounds the size of request and response bodies
+const maxRequestSize = 1 << 20
+
+// Paths of the Coordinator's endpoints
+const (
+	PathLease  = "/v1/lease"
+	PathRenew  = "/v1/renew"
+	PathReport = "/v1/report"
+)
+
+// Lease is a range of nonces leased to a worker, the response body of PathLease
+type Lease struct {
+	ID         string `json:"lease"`
+	Job        string `json:"job"`
+	Message    []byte `json:"msg"`
+	Difficulty int    `json:"difficulty"`
+	// Start is the first nonce of the range, and Count the number of nonces in it
+	Start uint64 `json:"start"`
+	Count uint64 `json:"count"`
+	// TTL is how long the lease lasts without a renewal, in milliseconds
+	TTL int64 `json:"ttl"`
+}
+
+// GetTTL gets how long the lease lasts without a renewal
+func (l *Lease) GetTTL() time.Duration {
+	return time.Duration(l.TTL) * time.Millisecond
+}
+
+// RenewRequest is the request body of PathRenew
+type RenewRequest struct {
+	Lease string `json:"lease"`
+}
+
+// ReportRequest is the request body of PathReport
+type ReportRequest struct {
+	Lease string `json:"lease"`
+	// Proof is the proof found in the range, or nil if the range has none
+	Proof *powork.PoWork `json:"proof,omitempty"`
+}
+
+// Response is the response body of PathRenew and PathReport. For a renewal, OK reports
+// whether the worker should keep searching; for a report, whether the report was accepted.
+type Response struct {
+	OK bool `json:"ok"`
+}
+
+// errorResponse is the body of responses with an error status
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// decode decodes the JSON body of a request into v. An empty body is allowed if optional.
+func decode(r *http.Request, v any, optional bool) error {
+	err := json.NewDecoder(io.LimitReader(r.Body, maxRequestSize)).Decode(v)
+	if err == io.EOF && optional {
+		return nil
+	}
+	return err
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,144 @@
+package powshard
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+// serve starts a Coordinator for proofs of the given difficulty
+func serve(t *testing.T, difficulty int, opts ...Option) (*Coordinator, string) {
+	w := powork.NewWorker()
+	w.SetDifficulty(difficulty)
+
+	c := NewCoordinator(w, opts...)
+	srv := httptest.NewServer(c)
+	t.Cleanup(srv.Close)
+	return c, srv.URL
+}
+
+// waitLeases waits until the Coordinator has a job with n leases out
+func waitLeases(t *testing.T, c *Coordinator, n int) {
+	for deadline := time.Now().Add(5 * time.Second); c.Leases() < n; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d leases out, expected %d\n", c.Leases(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSolve(t *testing.T) {
+	c, url := serve(t, 16, WithRangeSize(4096))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		w := &Worker{URL: url, PollInterval: 5 * time.Millisecond}
+		go w.Run(ctx)
+	}
+
+	for _, msg := range []string{"first job", "second job"} {
+		pow, err := c.Solve(ctx, []byte(msg))
+		if err != nil {
+			t.Fatalf("Error solving: %v\n", err)
+		}
+
+		w := powork.NewWorker()
+		w.SetDifficulty(16)
+		if ok, err := w.ValidatePoWork(pow); !ok || err != nil || pow.GetMessageString() != msg {
+			t.Fatalf("Solution is not a valid proof for %q: %v\n", msg, err)
+		}
+	}
+}
+
+func TestLeases(t *testing.T) {
+	c, url := serve(t, 40, WithRangeSize(100), WithLeaseTTL(50*time.Millisecond))
+	w := &Worker{URL: url}
+	ctx := context.Background()
+
+	if lease, err := w.Lease(ctx); lease != nil || err != nil {
+		t.Fatalf("Lease without a job returned %+v: %v\n", lease, err)
+	}
+
+	solveCtx, cancel := context.WithCancel(ctx)
+	solved := make(chan error, 1)
+	go func() {
+		_, err := c.Solve(solveCtx, []byte("a hard proof"))
+		solved <- err
+	}()
+
+	var first *Lease
+	for first == nil {
+		var err error
+		if first, err = w.Lease(ctx); err != nil {
+			t.Fatalf("Error leasing: %v\n", err)
+		}
+	}
+	second, _ := w.Lease(ctx)
+	if first.Start != 0 || first.Count != 100 || second.Start != 100 || first.Difficulty != 40 || first.GetTTL() != 50*time.Millisecond {
+		t.Fatalf("Leased ranges %+v and %+v\n", first, second)
+	}
+
+	// the first range is reported, the second expires and is leased again
+	var resp Response
+	if _, err := w.call(ctx, PathReport, ReportRequest{Lease: first.ID}, &resp); err != nil || !resp.OK {
+		t.Fatalf("Report was not accepted: %v\n", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, err := w.call(ctx, PathRenew, RenewRequest{Lease: second.ID}, &resp); err != nil || resp.OK {
+		t.Fatalf("Expired lease was renewed: %v\n", err)
+	}
+	if again, _ := w.Lease(ctx); again.Start != 100 || again.ID == second.ID {
+		t.Fatalf("Expired range was not leased again: %+v\n", again)
+	}
+	if next, _ := w.Lease(ctx); next.Start != 200 {
+		t.Fatalf("Next range starts at %d, expected 200\n", next.Start)
+	}
+
+	// a wrong proof does not end the job
+	pow, _ := powork.NewWorker().DoProofFor([]byte("another message"))
+	if _, err := w.call(ctx, PathReport, ReportRequest{Lease: first.ID, Proof: pow}, &resp); err != nil || resp.OK {
+		t.Fatalf("Proof for another message was accepted: %v\n", err)
+	}
+
+	cancel()
+	if err := <-solved; err != context.Canceled {
+		t.Fatalf("Canceled Solve returned %v\n", err)
+	}
+}
+
+func TestSearchCalledOff(t *testing.T) {
+	c, url := serve(t, 40, WithRangeSize(1<<40), WithLeaseTTL(300*time.Millisecond))
+	w := &Worker{URL: url}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go c.Solve(ctx, []byte("a hard proof"))
+
+	var lease *Lease
+	for lease == nil {
+		lease, _ = w.Lease(context.Background())
+	}
+	waitLeases(t, c, 1)
+
+	searched := make(chan error, 1)
+	go func() { searched <- w.Search(context.Background(), lease) }()
+
+	// the lease is still being renewed
+	time.Sleep(time.Second)
+	if c.Leases() != 1 {
+		t.Fatalf("Lease under search expired\n")
+	}
+
+	cancel()
+	select {
+	case err := <-searched:
+		if err != errLeaseLost {
+			t.Fatalf("Search called off returned %v\n", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Search was not called off\n")
+	}
+}
@@ -0,0 +1,177 @@
+package powshard
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+// DefaultPollInterval is how long a Worker waits to ask again when the Coordinator has no job
+const DefaultPollInterval = time.Second
+
+// Worker searches the nonce ranges a Coordinator leases to it
+type Worker struct {
+	// URL is the base URL of the Coordinator, such as "http://coordinator.internal:8080"
+	URL string
+
+	// HTTPClient makes the requests. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// NewWorker returns the powork.Worker to search with. Its hash algorithm must match the
+	// Coordinator's, and its concurrency sets how many cores each search uses. Its
+	// difficulty, starting nonce, iteration limit and timeout are overridden for each range.
+	// By default powork.NewWorker is used.
+	NewWorker func() *powork.Worker
+
+	// PollInterval is how long to wait when the Coordinator has no job. If 0,
+	// DefaultPollInterval is used.
+	PollInterval time.Duration
+}
+
+// Run leases and searches ranges until ctx is done or a request fails
+func (w *Worker) Run(ctx context.Context) error {
+	for {
+		lease, err := w.Lease(ctx)
+		if err != nil {
+			return err
+		}
+
+		if lease == nil {
+			poll := w.PollInterval
+			if poll == 0 {
+				poll = DefaultPollInterval
+			}
+
+			select {
+			case <-time.After(poll):
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if err := w.Search(ctx, lease); err != nil && !errors.Is(err, errLeaseLost) {
+			return err
+		}
+	}
+}
+
+// errLeaseLost is returned by Search when the Coordinator calls the search off
+var errLeaseLost = errors.New("lease lost")
+
+// Lease asks the Coordinator for a range to search. It returns nil without an error when
+// the Coordinator has no job.
+func (w *Worker) Lease(ctx context.Context) (*Lease, error) {
+	lease := new(Lease)
+	status, err := w.call(ctx, PathLease, struct{}{}, lease)
+	if err != nil || status == http.StatusNoContent {
+		return nil, err
+	}
+	return lease, nil
+}
+
+// Search searches the range of lease and reports the result, renewing the lease while it
+// runs. The search stops early when the Coordinator says the job is over.
+func (w *Worker) Search(ctx context.Context, lease *Lease) error {
+	search, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lost := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(max(lease.GetTTL()/3, time.Millisecond))
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				var resp Response
+				if _, err := w.call(search, PathRenew, RenewRequest{Lease: lease.ID}, &resp); err == nil && !resp.OK {
+					close(lost)
+					cancel()
+					return
+				}
+			case <-search.Done():
+				return
+			}
+		}
+	}()
+
+	pw := powork.NewWorker()
+	if w.NewWorker != nil {
+		pw = w.NewWorker()
+	}
+	if err := pw.SetDifficulty(lease.Difficulty); err != nil {
+		return err
+	}
+	pw.SetRandomStart(false)
+	pw.SetStartNonce(lease.Start)
+	pw.SetMaxIterations(lease.Count)
+	pw.SetTimeoutDuration(0)
+
+	pow, err := pw.DoProofForContext(search, lease.Message)
+	if pow == nil {
+		select {
+		case <-lost:
+			return errLeaseLost
+		default:
+		}
+		if !errors.Is(err, powork.ErrMaxIterations) {
+			return err
+		}
+	}
+
+	_, err = w.call(ctx, PathReport, ReportRequest{Lease: lease.ID, Proof: pow}, &Response{})
+	return err
+}
+
+// call posts req to the endpoint at path and decodes the response into resp, returning the
+// response status
+func (w *Worker) call(ctx context.Context, path string, req, resp any) (int, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return 0, err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(w.URL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	r.Header.Set("Content-Type", "application/json")
+
+	client := w.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(r)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxRequestSize))
+	if err != nil {
+		return 0, err
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return res.StatusCode, json.Unmarshal(data, resp)
+	case http.StatusNoContent:
+		return res.StatusCode, nil
+	}
+
+	var e errorResponse
+	if json.Unmarshal(data, &e) == nil && e.Error != "" {
+		return res.StatusCode, fmt.Errorf("coordinator: %s: %s", res.Status, e.Error)
+	}
+	return res.StatusCode, fmt.Errorf("coordinator: %s", res.Status)
+}
@@ -0,0 +1,234 @@
+// Package powsql provides a powork.SpentStore backed by a SQL database through database/sql,
+// for deployments that already run Postgres or MySQL and would rather not add Redis.
+//
+// Spent proofs are rows of a single table keyed by powork.SpentKey, with the time the record
+// expires. Create the table with Migrate, or run the statements returned by Schema with the
+// migration tool the deployment already uses. Expired rows are ignored, and deleted by
+// Collect or, in the background, by RunCollector or a powork.GC collecting several stores:
+//
+//	store := powsql.New(db, powsql.Postgres, time.Second)
+//	if err := store.Migrate(ctx); err != nil {
+//		log.Fatal(err)
+//	}
+//	go store.RunCollector(ctx, time.Minute, func(err error) { log.Print(err) })
+//	worker.SetSpentStore(store, time.Hour)
+package powsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+// DefaultTable is the name of the table Store records spent proofs in, unless another one is set
+const DefaultTable = "powork_spent"
+
+// Dialect is the flavour of SQL a database speaks
+type Dialect int
+
+const (
+	// Postgres is the dialect of PostgreSQL and of databases compatible with it, such as
+	// CockroachDB
+	Postgres Dialect = iota
+	// MySQL is the dialect of MySQL and MariaDB
+	MySQL
+	// SQLite is the dialect of SQLite
+	SQLite
+)
+
+// tableName matches the table names Store accepts, which are spliced into its statements
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,62}$`)
+
+// Store is a powork.SpentStore that records spent proofs as rows of a SQL table. Rows
+// expire after the TTL passed by the Worker, which for timestamped proofs is the time left
+// until the proof's timestamp falls outside the Worker's maximum proof age. Servers sharing
+// a database share the store.
+type Store struct {
+	db      *sql.DB
+	dialect Dialect
+	table   string
+	timeout time.Duration
+}
+
+var (
+	_ powork.SpentStore = (*Store)(nil)
+	_ powork.Collector  = (*Store)(nil)
+)
+
+// New creates a Store using db, which speaks dialect. Each statement is given at most
+// timeout to complete; a timeout of 0 means no limit.
+func New(db *sql.DB, dialect Dialect, timeout time.Duration) *Store {
+	return &Store{db: db, dialect: dialect, table: DefaultTable, timeout: timeout}
+}
+
+// SetTable sets the name of the table the Store records spent proofs in, so that several
+// applications can share a database. Names are limited to letters, digits and underscores.
+func (s *Store) SetTable(name string) error {
+	if !tableName.MatchString(name) {
+		return fmt.Errorf("%w: table name must be letters, digits and underscores", powork.ErrInvalidSetting)
+	}
+
+	s.table = name
+	return nil
+}
+
+// Seen implements powork.SpentStore
+func (s *Store) Seen(pow *powork.PoWork) (bool, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	query := fmt.Sprintf("SELECT 1 FROM %s WHERE proof_key = %s AND expires > %s", s.table, s.arg(1), s.arg(2))
+	var one int
+	err := s.db.QueryRowContext(ctx, query, powork.SpentKey(pow), time.Now().UnixMilli()).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Add implements powork.SpentStore. The row is inserted only if no row has the proof's key,
+// so concurrent servers can't both add a proof; an expired row in the way is deleted first.
+func (s *Store) Add(pow *powork.PoWork, ttl time.Duration) (bool, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	key := powork.SpentKey(pow)
+	now := time.Now()
+
+	expired := fmt.Sprintf("DELETE FROM %s WHERE proof_key = %s AND expires <= %s", s.table, s.arg(1), s.arg(2))
+	if _, err := s.db.ExecContext(ctx, expired, key, now.UnixMilli()); err != nil {
+		return false, err
+	}
+
+	res, err := s.db.ExecContext(ctx, s.insert(), key, now.Add(ttl).UnixMilli())
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 0, nil
+}
+
+// Collect deletes the rows of proofs whose records have expired, and returns how many it
+// deleted
+func (s *Store) Collect(ctx context.Context) (int64, error) {
+	res, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE expires <= %s", s.table, s.arg(1)), time.Now().UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// RunCollector calls Collect every interval until ctx is done. Errors are passed to onError,
+// which may be nil, and collection carries on.
+func (s *Store) RunCollector(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := s.Collect(ctx); err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+	}
+}
+
+// Schema returns the statements that create the Store's table, each run once, in order, to
+// bring the table to the version after its index. Migrate runs and tracks them; run them
+// from another migration tool instead to keep every schema change in one place.
+func (s *Store) Schema() []string {
+	return []string{
+		fmt.Sprintf("CREATE TABLE %s (proof_key VARCHAR(64) NOT NULL PRIMARY KEY, expires BIGINT NOT NULL)", s.table),
+		fmt.Sprintf("CREATE INDEX %s_expires ON %s (expires)", s.table, s.table),
+	}
+}
+
+// Migrate runs the statements of Schema that have not been run against the database yet,
+// recording the version reached in a table named after the Store's with a _schema suffix.
+// Each statement runs in a transaction with the version update, so a failed migration can
+// be retried, although MySQL commits schema changes on its own. Servers starting at the
+// same time may race to migrate; the loser fails and can retry.
+func (s *Store) Migrate(ctx context.Context) error {
+	versions := s.table + "_schema"
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version INTEGER NOT NULL)", versions)); err != nil {
+		return err
+	}
+
+	var version int
+	if err := s.db.QueryRowContext(ctx, fmt.Sprintf("SELECT COALESCE(MAX(version), 0) FROM %s", versions)).Scan(&version); err != nil {
+		return err
+	}
+
+	steps := s.Schema()
+	for v := version; v < len(steps); v++ {
+		if err := s.migrate(ctx, versions, v+1, steps[v]); err != nil {
+			return fmt.Errorf("migrating to version %d: %w", v+1, err)
+		}
+	}
+
+	return nil
+}
+
+// migrate runs a schema statement and records the version it brings the table to
+func (s *Store) migrate(ctx context.Context, versions string, version int, statement string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, statement); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (version) VALUES (%s)", versions, s.arg(1)), version); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insert returns the statement that inserts a row unless one with its key exists
+func (s *Store) insert() string {
+	switch s.dialect {
+	case MySQL:
+		return fmt.Sprintf("INSERT IGNORE INTO %s (proof_key, expires) VALUES (?, ?)", s.table)
+	case SQLite:
+		return fmt.Sprintf("INSERT OR IGNORE INTO %s (proof_key, expires) VALUES (?, ?)", s.table)
+	}
+	return fmt.Sprintf("INSERT INTO %s (proof_key, expires) VALUES ($1, $2) ON CONFLICT (proof_key) DO NOTHING", s.table)
+}
+
+// arg returns the placeholder of the nth argument of a statement
+func (s *Store) arg(n int) string {
+	if s.dialect == Postgres {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+func (s *Store) context() (context.Context, context.CancelFunc) {
+	if s.timeout == 0 {
+		return context.WithCancel(context.Background())
+	}
+
+	return context.WithTimeout(context.Background(), s.timeout)
+}
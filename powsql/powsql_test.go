@@ -0,0 +1,169 @@
+package powsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Zumium/powork"
+	_ "modernc.org/sqlite"
+)
+
+func newStore(t *testing.T) *Store {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Error opening database: %v\n", err)
+	}
+	// every connection to :memory: opens a database of its own
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	store := New(db, SQLite, time.Second)
+	if err := store.Migrate(context.Background()); err != nil {
+		t.Fatalf("Error migrating: %v\n", err)
+	}
+	return store
+}
+
+func TestStoreSharedAcrossWorkers(t *testing.T) {
+	store := newStore(t)
+
+	// two servers sharing one database
+	a := powork.NewWorker()
+	a.SetSpentStore(store, time.Minute)
+	b := powork.NewWorker()
+	b.SetSpentStore(store, time.Minute)
+
+	proof, _ := powork.NewWorker().DoProofForString("A message accepted once across the fleet")
+
+	ok, err := a.ValidateOnce(proof)
+	if err != nil || !ok {
+		t.Fatalf("Fresh proof did not validate: %v\n", err)
+	}
+
+	ok, err = b.ValidateOnce(proof)
+	if err != nil || ok {
+		t.Fatalf("Proof replayed on another server was accepted: %v\n", err)
+	}
+}
+
+func TestStoreExpiry(t *testing.T) {
+	store := newStore(t)
+	proof, _ := powork.NewWorker().DoProofForString("A message")
+
+	if dup, err := store.Add(proof, 50*time.Millisecond); dup || err != nil {
+		t.Fatalf("Fresh proof was reported as spent: %v\n", err)
+	}
+	if seen, _ := store.Seen(proof); !seen {
+		t.Fatalf("Added proof was not seen\n")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if seen, _ := store.Seen(proof); seen {
+		t.Fatalf("Expired proof was still seen\n")
+	}
+
+	// the expired row doesn't stand in the way of recording the proof again
+	if dup, err := store.Add(proof, time.Minute); dup || err != nil {
+		t.Fatalf("Proof whose record expired was reported as spent: %v\n", err)
+	}
+	if dup, _ := store.Add(proof, time.Minute); !dup {
+		t.Fatalf("Adding a recorded proof was not reported\n")
+	}
+}
+
+func TestCollect(t *testing.T) {
+	store := newStore(t)
+	worker := powork.NewWorker()
+
+	for _, msg := range []string{"a", "b", "c"} {
+		proof, _ := worker.DoProofForString(msg)
+		store.Add(proof, 10*time.Millisecond)
+	}
+	proof, _ := worker.DoProofForString("d")
+	store.Add(proof, time.Minute)
+
+	time.Sleep(50 * time.Millisecond)
+	n, err := store.Collect(context.Background())
+	if err != nil || n != 3 {
+		t.Fatalf("Collected %d rows, expected 3: %v\n", n, err)
+	}
+
+	if seen, _ := store.Seen(proof); !seen {
+		t.Fatalf("Unexpired proof was collected\n")
+	}
+
+	var rows int
+	store.db.QueryRow("SELECT COUNT(*) FROM " + store.table).Scan(&rows)
+	if rows != 1 {
+		t.Fatalf("Table holds %d rows after collection, expected 1\n", rows)
+	}
+}
+
+func TestRunCollector(t *testing.T) {
+	store := newStore(t)
+	proof, _ := powork.NewWorker().DoProofForString("A message")
+	store.Add(proof, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		store.RunCollector(ctx, 10*time.Millisecond, func(err error) { t.Errorf("Error collecting: %v\n", err) })
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		var rows int
+		store.db.QueryRow("SELECT COUNT(*) FROM " + store.table).Scan(&rows)
+		if rows == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Collector left %d expired rows\n", rows)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+}
+
+func TestMigrate(t *testing.T) {
+	store := newStore(t)
+
+	// migrating again finds nothing to do
+	if err := store.Migrate(context.Background()); err != nil {
+		t.Fatalf("Error migrating a migrated database: %v\n", err)
+	}
+
+	var version int
+	store.db.QueryRow("SELECT MAX(version) FROM " + store.table + "_schema").Scan(&version)
+	if version != len(store.Schema()) {
+		t.Fatalf("Schema is at version %d, expected %d\n", version, len(store.Schema()))
+	}
+
+	if err := store.SetTable("spent; DROP TABLE users"); !errors.Is(err, powork.ErrInvalidSetting) {
+		t.Fatalf("Unsafe table name did not fail with ErrInvalidSetting: %v\n", err)
+	}
+
+	if err := store.SetTable("other_spent"); err != nil {
+		t.Fatalf("Error setting table: %v\n", err)
+	}
+	if err := store.Migrate(context.Background()); err != nil {
+		t.Fatalf("Error migrating a second table: %v\n", err)
+	}
+}
+
+func TestPlaceholders(t *testing.T) {
+	postgres := New(nil, Postgres, 0)
+	if got := postgres.insert(); got != "INSERT INTO powork_spent (proof_key, expires) VALUES ($1, $2) ON CONFLICT (proof_key) DO NOTHING" {
+		t.Fatalf("Postgres insert is %q\n", got)
+	}
+
+	if got := New(nil, MySQL, 0).arg(2); got != "?" {
+		t.Fatalf("MySQL placeholder is %q, expected ?\n", got)
+	}
+}
@@ -0,0 +1,318 @@
+package powstratum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/Zumium/powork"
+)
+
+// Coordinator hands jobs to miners and checks their shares. Its methods are safe for
+// concurrent use.
+type Coordinator struct {
+	worker          *powork.Worker
+	shareDifficulty int
+	onShare         func(miner string, pow *powork.PoWork)
+
+	solveMu sync.Mutex // one job at a time
+
+	mu     sync.Mutex
+	miners map[*minerConn]struct{}
+	job    *jobState
+	nextID uint64
+	closed bool
+	ls     []net.Listener
+}
+
+// minerConn is a connected miner
+type minerConn struct {
+	conn  net.Conn
+	codec *codec
+	id    string // set once subscribed
+	name  string
+}
+
+// jobState is the job being worked on
+type jobState struct {
+	Job
+	solved chan *powork.PoWork
+	once   sync.Once
+	seen   map[string]struct{}
+}
+
+// NewCoordinator creates a Coordinator that validates shares with w, whose algorithm miners
+// must use, and asks for shares of the given difficulty. The share difficulty trades the
+// Coordinator's load against how finely work is accounted: a miner submits about one share per
+// 2^shareDifficulty hashes.
+func NewCoordinator(w *powork.Worker, shareDifficulty int) (*Coordinator, error) {
+	if shareDifficulty < 1 || shareDifficulty > w.MaxDifficulty() {
+		return nil, fmt.Errorf("%w: share difficulty must be between 1 and %d", powork.ErrInvalidDifficulty, w.MaxDifficulty())
+	}
+
+	return &Coordinator{
+		worker:          w,
+		shareDifficulty: shareDifficulty,
+		miners:          make(map[*minerConn]struct{}),
+	}, nil
+}
+
+// OnShare sets a function called with every share the Coordinator accepts, including the one
+// that solves the job, for example to credit miners for their work in a powork.ShareLedger.
+// It must not block.
+func (c *Coordinator) OnShare(f func(miner string, pow *powork.PoWork)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onShare = f
+}
+
+// Miners returns the number of subscribed miners
+func (c *Coordinator) Miners() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for m := range c.miners {
+		if m.id != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// Serve accepts miners on l until l fails or the Coordinator is closed
+func (c *Coordinator) Serve(l net.Listener) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return net.ErrClosed
+	}
+	c.ls = append(c.ls, l)
+	c.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			c.mu.Lock()
+			closed := c.closed
+			c.mu.Unlock()
+			if closed {
+				return net.ErrClosed
+			}
+			return err
+		}
+
+		go c.serveMiner(conn)
+	}
+}
+
+// Close stops serving and disconnects every miner
+func (c *Coordinator) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	for _, l := range c.ls {
+		l.Close()
+	}
+	for m := range c.miners {
+		m.conn.Close()
+	}
+	return nil
+}
+
+// Solve hands msg to the miners as a job of the given difficulty, and returns the proof once
+// a miner finds it, or ctx's error if it is done first. The proof is bound to msg and to the
+// extension naming its miner. Jobs run one at a time; a second call waits for the first to
+// finish.
+func (c *Coordinator) Solve(ctx context.Context, msg []byte, difficulty int) (*powork.PoWork, error) {
+	if difficulty < c.shareDifficulty || difficulty > c.worker.MaxDifficulty() {
+		return nil, fmt.Errorf("%w: job difficulty must be between the share difficulty and %d", powork.ErrInvalidDifficulty, c.worker.MaxDifficulty())
+	}
+
+	c.solveMu.Lock()
+	defer c.solveMu.Unlock()
+
+	c.mu.Lock()
+	c.nextID++
+	job := &jobState{
+		Job: Job{
+			ID:              "j" + strconv.FormatUint(c.nextID, 10),
+			Message:         append([]byte(nil), msg...),
+			Difficulty:      difficulty,
+			ShareDifficulty: c.shareDifficulty,
+		},
+		solved: make(chan *powork.PoWork, 1),
+		seen:   make(map[string]struct{}),
+	}
+	c.job = job
+	miners := c.subscribed()
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.job = nil
+		miners := c.subscribed()
+		c.mu.Unlock()
+
+		for _, m := range miners {
+			m.codec.write(message{Method: methodDone}, doneParams{Job: job.ID}, nil)
+		}
+	}()
+
+	// miners that subscribe later are notified when they do
+	for _, m := range miners {
+		m.codec.write(message{Method: methodNotify}, job.Job, nil)
+	}
+
+	select {
+	case pow := <-job.solved:
+		return pow, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// subscribed returns the subscribed miners. The caller must hold c.mu.
+func (c *Coordinator) subscribed() []*minerConn {
+	var miners []*minerConn
+	for m := range c.miners {
+		if m.id != "" {
+			miners = append(miners, m)
+		}
+	}
+	return miners
+}
+
+// serveMiner runs the protocol with a miner until it disconnects
+func (c *Coordinator) serveMiner(conn net.Conn) {
+	m := &minerConn{conn: conn, codec: newCodec(conn)}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		conn.Close()
+		return
+	}
+	c.miners[m] = struct{}{}
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.miners, m)
+		c.mu.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		req, err := m.codec.read()
+		if err != nil {
+			return
+		}
+
+		var result any
+		switch req.Method {
+		case methodSubscribe:
+			result, err = c.subscribe(m, req.Params)
+		case methodSubmit:
+			result, err = c.submit(m, req.Params)
+		default:
+			err = fmt.Errorf("unknown method %q", req.Method)
+		}
+
+		resp := message{ID: req.ID}
+		if err != nil {
+			resp.Error = err.Error()
+			m.codec.write(resp, nil, nil)
+		} else {
+			m.codec.write(resp, nil, result)
+		}
+
+		// a new miner joins the job in progress
+		if req.Method == methodSubscribe && err == nil {
+			c.mu.Lock()
+			job := c.job
+			c.mu.Unlock()
+			if job != nil {
+				m.codec.write(message{Method: methodNotify}, job.Job, nil)
+			}
+		}
+	}
+}
+
+// subscribe handles a subscribe request
+func (c *Coordinator) subscribe(m *minerConn, params json.RawMessage) (any, error) {
+	var p subscribeParams
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if m.id != "" {
+		return nil, errors.New("already subscribed")
+	}
+	c.nextID++
+	m.id = "m" + strconv.FormatUint(c.nextID, 10)
+	m.name = p.Name
+	return subscribeResult{Miner: m.id}, nil
+}
+
+// submit handles a submitted share
+func (c *Coordinator) submit(m *minerConn, params json.RawMessage) (any, error) {
+	var p submitParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	pow := new(powork.PoWork)
+	if err := pow.UnmarshalJSON(p.Proof); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	job, id, onShare := c.job, m.id, c.onShare
+	c.mu.Unlock()
+
+	switch {
+	case id == "":
+		return nil, errors.New("not subscribed")
+	case job == nil || job.ID != p.Job:
+		// stale shares are normal right after a job ends
+		return submitResult{}, nil
+	}
+
+	if miner, _ := pow.GetExtension(ExtensionMiner); miner != id || string(pow.GetMessage()) != string(job.Message) || pow.GetDifficulty() != job.Difficulty {
+		return submitResult{}, nil
+	}
+
+	if ok, err := c.worker.ValidateShare(pow, job.ShareDifficulty); err != nil || !ok {
+		return submitResult{}, err
+	}
+
+	key := powork.SpentKey(pow)
+	c.mu.Lock()
+	_, dup := job.seen[key]
+	job.seen[key] = struct{}{}
+	c.mu.Unlock()
+	if dup {
+		return submitResult{}, nil
+	}
+
+	if onShare != nil {
+		onShare(id, pow)
+	}
+
+	solved, _ := c.worker.ValidateWithMinimum(pow, job.Difficulty)
+	if solved {
+		job.once.Do(func() { job.solved <- pow })
+	}
+	return submitResult{Accepted: true, Solved: solved}, nil
+}
@@ -0,0 +1,204 @@
+package powstratum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Zumium/powork"
+)
+
+// ErrRejected is returned by Miner.Run when the Coordinator refuses to subscribe the miner
+var ErrRejected = errors.New("Subscription rejected")
+
+// Miner works on the jobs of a Coordinator, submitting shares as it finds them
+type Miner struct {
+	// NewWorker returns the Worker to search with. Its hash algorithm must match the
+	// Coordinator's, and its concurrency sets how many cores the Miner uses. Its difficulty,
+	// predicate, starting nonce and timeout are overridden for each job. By default
+	// powork.NewWorker is used.
+	NewWorker func() *powork.Worker
+	// Name is sent to the Coordinator to identify the miner in logs
+	Name string
+
+	accepted atomic.Uint64
+	rejected atomic.Uint64
+}
+
+// Accepted returns the number of shares the Coordinator has accepted
+func (m *Miner) Accepted() uint64 {
+	return m.accepted.Load()
+}
+
+// Rejected returns the number of shares the Coordinator has rejected
+func (m *Miner) Rejected() uint64 {
+	return m.rejected.Load()
+}
+
+// Dial connects to the Coordinator at addr and runs the Miner on the connection
+func (m *Miner) Dial(ctx context.Context, addr string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return m.Run(ctx, conn)
+}
+
+// Run subscribes to the Coordinator on conn and works on its jobs until ctx is done or the
+// connection fails. Run closes conn when it returns.
+func (m *Miner) Run(ctx context.Context, conn net.Conn) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// unblock reads when ctx is done
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	c := newCodec(conn)
+	if err := c.write(message{ID: 1, Method: methodSubscribe}, subscribeParams{Name: m.Name}, nil); err != nil {
+		return err
+	}
+
+	r := &minerRun{miner: m, codec: c, nextID: 1}
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	// stop the jobs and the connection before waiting for the jobs
+	defer cancel()
+
+	for {
+		msg, err := c.read()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		switch {
+		case msg.ID == 1 && r.id == "":
+			var res subscribeResult
+			if msg.Error != "" || json.Unmarshal(msg.Result, &res) != nil || res.Miner == "" {
+				return fmt.Errorf("%w: %s", ErrRejected, msg.Error)
+			}
+			r.id = res.Miner
+
+		case msg.Method == methodNotify:
+			var job Job
+			if err := json.Unmarshal(msg.Params, &job); err != nil {
+				return err
+			}
+			jobCtx := r.start(ctx, job.ID)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				r.mine(jobCtx, job)
+			}()
+
+		case msg.Method == methodDone:
+			var p doneParams
+			if err := json.Unmarshal(msg.Params, &p); err != nil {
+				return err
+			}
+			r.stopJob(p.Job)
+
+		case msg.Method == "" && msg.ID != 0:
+			var res submitResult
+			if msg.Error == "" && json.Unmarshal(msg.Result, &res) == nil && res.Accepted {
+				m.accepted.Add(1)
+			} else {
+				m.rejected.Add(1)
+			}
+		}
+	}
+}
+
+// minerRun is the state of one connection of a Miner
+type minerRun struct {
+	miner *Miner
+	codec *codec
+	id    string
+
+	mu     sync.Mutex
+	nextID uint64
+	job    string
+	cancel context.CancelFunc
+}
+
+// start stops the job being worked on, if any, and returns the context to work on job in
+func (r *minerRun) start(ctx context.Context, job string) context.Context {
+	r.stop()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.job = job
+	return ctx
+}
+
+// stop stops the job being worked on, if any
+func (r *minerRun) stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+}
+
+// stopJob stops the job being worked on if it is job
+func (r *minerRun) stopJob(job string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.job == job && r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+}
+
+// mine searches for shares of job until it is stopped
+func (r *minerRun) mine(ctx context.Context, job Job) {
+	w := powork.NewWorker()
+	if r.miner.NewWorker != nil {
+		w = r.miner.NewWorker()
+	}
+	if err := w.SetDifficulty(job.Difficulty); err != nil {
+		return
+	}
+	w.SetPredicate(powork.LeadingZeros(job.ShareDifficulty))
+	w.SetTimeoutDuration(0)
+	w.SetRandomStart(false)
+
+	ext := map[string]string{ExtensionMiner: r.id}
+	for start := uint64(0); ; {
+		w.SetStartNonce(start)
+		pow, err := w.DoProofForExtensionsContext(ctx, job.Message, ext)
+		if err != nil {
+			return
+		}
+
+		proof, err := pow.MarshalJSON()
+		if err != nil {
+			return
+		}
+
+		r.mu.Lock()
+		r.nextID++
+		id := r.nextID
+		r.mu.Unlock()
+		if r.codec.write(message{ID: id, Method: methodSubmit}, submitParams{Job: job.ID, Proof: proof}, nil) != nil {
+			return
+		}
+
+		start = pow.GetProof() + 1
+	}
+}
@@ -0,0 +1,144 @@
+// Package powstratum lets many machines search for one high-difficulty proof of work
+// together, with a minimal protocol in the style of Stratum. A Coordinator hands a job, a
+// message and its difficulty, to every connected Miner. Miners look for shares, proofs that
+// meet a lower share difficulty, and submit each as evidence of their work; the share that
+// also meets the job's difficulty is the proof, and the Coordinator stops everyone.
+//
+// Each miner's proofs are bound to its miner ID with the "miner" extension, so no two miners
+// ever try the same nonce for the same preimage, and a share can't be claimed by another
+// miner. The proof keeps the extension.
+//
+// The protocol runs over TCP as newline-delimited JSON messages:
+//
+//	miner → {"id":1,"method":"subscribe","params":{"name":"rig-1"}}
+//	      ← {"id":1,"result":{"miner":"m1"}}
+//	      ← {"method":"notify","params":{"job":"j1","msg":"<base64>","difficulty":40,"share_difficulty":20}}
+//	miner → {"id":2,"method":"submit","params":{"job":"j1","proof":{...}}}
+//	      ← {"id":2,"result":{"accepted":true,"solved":false}}
+//	      ← {"method":"done","params":{"job":"j1"}}
+//
+// where proofs are in the JSON encoding of powork.PoWork. Failed requests get an "error"
+// member instead of a result. Miners must hash with the Coordinator's algorithm.
+package powstratum
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ExtensionMiner is the extension that binds a proof to the miner that found it
+const ExtensionMiner = "miner"
+
+// maxMessageSize bounds the size of a protocol message
+const maxMessageSize = 1 << 20
+
+// Protocol methods
+const (
+	methodSubscribe = "subscribe"
+	methodNotify    = "notify"
+	methodSubmit    = "submit"
+	methodDone      = "done"
+)
+
+// Job is a search for a proof, as notified to miners
+type Job struct {
+	ID              string `json:"job"`
+	Message         []byte `json:"msg"`
+	Difficulty      int    `json:"difficulty"`
+	ShareDifficulty int    `json:"share_difficulty"`
+}
+
+// message is a request, response or notification of the protocol
+type message struct {
+	ID     uint64          `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+type subscribeParams struct {
+	Name string `json:"name"`
+}
+
+type subscribeResult struct {
+	Miner string `json:"miner"`
+}
+
+type submitParams struct {
+	Job   string          `json:"job"`
+	Proof json.RawMessage `json:"proof"`
+}
+
+type submitResult struct {
+	Accepted bool `json:"accepted"`
+	Solved   bool `json:"solved"`
+}
+
+type doneParams struct {
+	Job string `json:"job"`
+}
+
+// codec reads and writes protocol messages. Writes are safe for concurrent use.
+type codec struct {
+	r *bufio.Reader
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func newCodec(rw io.ReadWriter) *codec {
+	return &codec{r: bufio.NewReaderSize(rw, 4096), w: rw}
+}
+
+// read reads the next message
+func (c *codec) read() (*message, error) {
+	var line []byte
+	for {
+		chunk, more, err := c.r.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, chunk...)
+		if len(line) > maxMessageSize {
+			return nil, errors.New("protocol message is too long")
+		}
+		if !more {
+			break
+		}
+	}
+
+	m := new(message)
+	if err := json.Unmarshal(line, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// write writes a message with params or result encoded from v
+func (c *codec) write(m message, params, result any) error {
+	var err error
+	if params != nil {
+		if m.Params, err = json.Marshal(params); err != nil {
+			return err
+		}
+	}
+	if result != nil {
+		if m.Result, err = json.Marshal(result); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err = c.w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,195 @@
+package powstratum
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+// serve starts a Coordinator on a local port
+func serve(t *testing.T, shareDifficulty int) (*Coordinator, string) {
+	c, err := NewCoordinator(powork.NewWorker(), shareDifficulty)
+	if err != nil {
+		t.Fatalf("Error creating coordinator: %v\n", err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v\n", err)
+	}
+	go c.Serve(l)
+	t.Cleanup(func() { c.Close() })
+	return c, l.Addr().String()
+}
+
+// waitMiners waits until n miners have subscribed
+func waitMiners(t *testing.T, c *Coordinator, n int) {
+	for deadline := time.Now().Add(5 * time.Second); c.Miners() < n; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d miners subscribed, expected %d\n", c.Miners(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSolve(t *testing.T) {
+	c, addr := serve(t, 6)
+
+	var mu sync.Mutex
+	shares := make(map[string]int)
+	c.OnShare(func(miner string, pow *powork.PoWork) {
+		mu.Lock()
+		defer mu.Unlock()
+		shares[miner]++
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	miners := []*Miner{{Name: "a"}, {Name: "b"}}
+	for _, m := range miners {
+		go m.Dial(ctx, addr)
+	}
+	waitMiners(t, c, len(miners))
+
+	for _, msg := range []string{"first job", "second job"} {
+		pow, err := c.Solve(ctx, []byte(msg), 14)
+		if err != nil {
+			t.Fatalf("Error solving: %v\n", err)
+		}
+
+		w := powork.NewWorker()
+		w.SetDifficulty(14)
+		if ok, err := w.ValidatePoWork(pow); !ok || err != nil || pow.GetMessageString() != msg {
+			t.Fatalf("Solution is not a valid proof for %q: %v\n", msg, err)
+		}
+		miner, _ := pow.GetExtension(ExtensionMiner)
+		mu.Lock()
+		n := shares[miner]
+		mu.Unlock()
+		if n == 0 {
+			t.Fatalf("Solution is bound to miner %q, which has no shares\n", miner)
+		}
+	}
+
+	// about 2^8 shares per job at these difficulties
+	mu.Lock()
+	total := shares["m1"] + shares["m2"]
+	mu.Unlock()
+	if total < 10 {
+		t.Fatalf("Coordinator accepted %d shares\n", total)
+	}
+	if miners[0].Accepted()+miners[1].Accepted() == 0 {
+		t.Fatalf("Miners counted no accepted shares\n")
+	}
+}
+
+func TestSolveCanceled(t *testing.T) {
+	c, _ := serve(t, 6)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.Solve(ctx, []byte("nobody is mining"), 14); err != context.DeadlineExceeded {
+		t.Fatalf("Solve without miners returned %v\n", err)
+	}
+
+	if _, err := c.Solve(context.Background(), []byte("too easy"), 4); err == nil {
+		t.Fatalf("Solve below the share difficulty did not fail\n")
+	}
+}
+
+// rawMiner speaks the protocol directly
+type rawMiner struct {
+	t     *testing.T
+	codec *codec
+}
+
+func dialRaw(t *testing.T, addr string) *rawMiner {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Error dialing: %v\n", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &rawMiner{t: t, codec: newCodec(conn)}
+}
+
+// call sends a request and returns its response, skipping notifications
+func (r *rawMiner) call(id uint64, method string, params any) *message {
+	if err := r.codec.write(message{ID: id, Method: method}, params, nil); err != nil {
+		r.t.Fatalf("Error writing request: %v\n", err)
+	}
+	for {
+		m, err := r.codec.read()
+		if err != nil {
+			r.t.Fatalf("Error reading response: %v\n", err)
+		}
+		if m.ID == id {
+			return m
+		}
+	}
+}
+
+func TestSubmit(t *testing.T) {
+	c, addr := serve(t, 6)
+	r := dialRaw(t, addr)
+
+	pow, _ := powork.NewWorker().DoProofFor([]byte("job"))
+	proof, _ := pow.MarshalJSON()
+	if resp := r.call(1, methodSubmit, submitParams{Job: "j1", Proof: proof}); resp.Error == "" {
+		t.Fatalf("Submit before subscribing did not fail\n")
+	}
+
+	var sub subscribeResult
+	if resp := r.call(2, methodSubscribe, subscribeParams{Name: "raw"}); json.Unmarshal(resp.Result, &sub) != nil || sub.Miner == "" {
+		t.Fatalf("Subscribe returned %s\n", resp.Error)
+	}
+	if resp := r.call(3, methodSubscribe, nil); resp.Error == "" {
+		t.Fatalf("Subscribing twice did not fail\n")
+	}
+	if resp := r.call(4, "unknown", nil); resp.Error == "" {
+		t.Fatalf("Unknown method did not fail\n")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Solve(ctx, []byte("job"), 30)
+	m, err := r.codec.read()
+	if err != nil || m.Method != methodNotify {
+		t.Fatalf("Expected a job notification, got %+v: %v\n", m, err)
+	}
+	var job Job
+	json.Unmarshal(m.Params, &job)
+
+	submit := func(id uint64, ext map[string]string, difficulty int) submitResult {
+		w := powork.NewWorker()
+		w.SetDifficulty(difficulty)
+		w.SetPredicate(powork.LeadingZeros(job.ShareDifficulty))
+		pow, err := w.DoProofForExtensions(job.Message, ext)
+		if err != nil {
+			t.Fatalf("Error finding share: %v\n", err)
+		}
+		proof, _ := pow.MarshalJSON()
+
+		var res submitResult
+		json.Unmarshal(r.call(id, methodSubmit, submitParams{Job: job.ID, Proof: proof}).Result, &res)
+		return res
+	}
+
+	mine := map[string]string{ExtensionMiner: sub.Miner}
+	if res := submit(5, mine, job.Difficulty); !res.Accepted || res.Solved {
+		t.Fatalf("Share was not accepted: %+v\n", res)
+	}
+	if res := submit(6, mine, job.Difficulty); res.Accepted {
+		t.Fatalf("Repeated share was accepted\n")
+	}
+	if res := submit(7, map[string]string{ExtensionMiner: "m999"}, job.Difficulty); res.Accepted {
+		t.Fatalf("Share bound to another miner was accepted\n")
+	}
+	if res := submit(8, mine, job.Difficulty-1); res.Accepted {
+		t.Fatalf("Share declaring another difficulty was accepted\n")
+	}
+}
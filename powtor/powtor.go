@@ -0,0 +1,243 @@
+// Package powtor implements the v1 client puzzle of Tor onion services' denial of service
+// defense, for implementers that verify the proofs of work of introduction requests.
+//
+// A client proves work for a service's blinded ID and current seed by finding a nonce and an
+// Equi-X solution S for the challenge
+//
+//	P || ID || C || N || E        P = "Tor hs intro v1\0", ID = blinded ID, C = seed,
+//	                              N = nonce, E = effort as a big-endian uint32
+//
+// such that R * E <= 2³²-1, where R is the 32-bit BLAKE2b digest of the challenge followed by
+// S, read big-endian. The proof travels in the PoW extension of the INTRODUCE1 cell, which
+// identifies the seed by its first four bytes. A Verifier tracks the current and previous
+// seed and rejects replayed nonces.
+//
+// Equi-X itself, an asymmetric puzzle built on the HashX family of randomly generated hash
+// functions, is not part of this package. Plug an implementation in through the EquiX
+// interface, for example bindings to Tor's C library.
+package powtor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/Zumium/powork"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Sizes of the puzzle's fields
+const (
+	SeedSize     = 32
+	SeedHeadSize = 4
+	NonceSize    = 16
+	SolutionSize = 16
+	IDSize       = 32
+)
+
+// Version is the version of the puzzle, as carried in the PoW extension
+const Version = 1
+
+// Personalization is the string every challenge starts with
+const Personalization = "Tor hs intro v1\x00"
+
+// encodedLen is the size of an encoded Proof
+const encodedLen = 1 + NonceSize + 4 + SeedHeadSize + SolutionSize
+
+// EquiX solves and verifies Equi-X puzzles
+type EquiX interface {
+	// Solve returns the solutions of the puzzle for challenge, of which there may be none
+	Solve(challenge []byte) ([][SolutionSize]byte, error)
+	// Verify reports whether solution solves the puzzle for challenge
+	Verify(challenge []byte, solution [SolutionSize]byte) bool
+}
+
+// Proof is the proof of work of an introduction request
+type Proof struct {
+	Nonce    [NonceSize]byte
+	Effort   uint32
+	SeedHead [SeedHeadSize]byte
+	Solution [SolutionSize]byte
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, encoding the proof as the body of the
+// INTRODUCE1 PoW extension:
+//
+//	version  uint8
+//	nonce    [16]byte
+//	effort   uint32
+//	seed     [4]byte, the head of the seed
+//	solution [16]byte
+func (p *Proof) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, encodedLen)
+	b = append(b, Version)
+	b = append(b, p.Nonce[:]...)
+	b = binary.BigEndian.AppendUint32(b, p.Effort)
+	b = append(b, p.SeedHead[:]...)
+	return append(b, p.Solution[:]...), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, decoding data produced by MarshalBinary
+func (p *Proof) UnmarshalBinary(data []byte) error {
+	if len(data) != encodedLen {
+		return fmt.Errorf("%w: PoW extension must be %d bytes", powork.ErrInvalidEncoding, encodedLen)
+	}
+	if data[0] != Version {
+		return fmt.Errorf("%w: unsupported PoW version %d", powork.ErrInvalidEncoding, data[0])
+	}
+
+	data = data[1:]
+	copy(p.Nonce[:], data)
+	p.Effort = binary.BigEndian.Uint32(data[NonceSize:])
+	copy(p.SeedHead[:], data[NonceSize+4:])
+	copy(p.Solution[:], data[NonceSize+4+SeedHeadSize:])
+	return nil
+}
+
+// Challenge returns the Equi-X challenge for a blinded ID, seed, nonce and effort
+func Challenge(id [IDSize]byte, seed [SeedSize]byte, nonce [NonceSize]byte, effort uint32) []byte {
+	c := make([]byte, 0, len(Personalization)+IDSize+SeedSize+NonceSize+4)
+	c = append(c, Personalization...)
+	c = append(c, id[:]...)
+	c = append(c, seed[:]...)
+	c = append(c, nonce[:]...)
+	return binary.BigEndian.AppendUint32(c, effort)
+}
+
+// MeetsEffort reports whether an Equi-X solution for challenge, the challenge of effort,
+// meets that effort: whether R * effort <= 2³²-1 for the 32-bit BLAKE2b digest R of the
+// challenge and solution
+func MeetsEffort(challenge []byte, solution [SolutionSize]byte, effort uint32) bool {
+	h, _ := blake2b.New(4, nil)
+	h.Write(challenge)
+	h.Write(solution[:])
+	r := binary.BigEndian.Uint32(h.Sum(nil))
+	return uint64(r)*uint64(effort) <= math.MaxUint32
+}
+
+// Solve finds a proof of the given effort for a service's blinded ID and seed, trying random
+// nonces in order
+func Solve(ctx context.Context, eq EquiX, id [IDSize]byte, seed [SeedSize]byte, effort uint32) (*Proof, error) {
+	p := &Proof{Effort: effort}
+	copy(p.SeedHead[:], seed[:])
+	if _, err := rand.Read(p.Nonce[:]); err != nil {
+		return nil, err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		challenge := Challenge(id, seed, p.Nonce, effort)
+		solutions, err := eq.Solve(challenge)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range solutions {
+			if MeetsEffort(challenge, s, effort) {
+				p.Solution = s
+				return p, nil
+			}
+		}
+
+		incrementNonce(&p.Nonce)
+	}
+}
+
+// incrementNonce adds one to a nonce read as a little-endian integer
+func incrementNonce(n *[NonceSize]byte) {
+	for i := range n {
+		n[i]++
+		if n[i] != 0 {
+			return
+		}
+	}
+}
+
+// Verifier checks the proofs of the introduction requests of one onion service. Its methods
+// are safe for concurrent use.
+type Verifier struct {
+	eq EquiX
+	id [IDSize]byte
+
+	mu      sync.Mutex
+	seeds   [2]*seedState // current and previous
+	minimum uint32
+}
+
+// seedState is a seed and the nonces seen with it
+type seedState struct {
+	seed [SeedSize]byte
+	seen map[[NonceSize]byte]struct{}
+}
+
+// NewVerifier creates a Verifier for the service with the given blinded ID. It accepts no
+// proofs until a seed is set.
+func NewVerifier(eq EquiX, id [IDSize]byte) *Verifier {
+	return &Verifier{eq: eq, id: id}
+}
+
+// SetSeed makes seed the current seed, keeping the current one as the previous seed so that
+// proofs solved just before the rotation are still accepted. Nonces seen with the seed that
+// is dropped are forgotten.
+func (v *Verifier) SetSeed(seed [SeedSize]byte) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.seeds[1] = v.seeds[0]
+	v.seeds[0] = &seedState{seed: seed, seen: make(map[[NonceSize]byte]struct{})}
+}
+
+// SetMinimumEffort sets the least effort the Verifier accepts, as the service suggests to
+// clients in its descriptor. The default is 0, accepting any valid proof.
+func (v *Verifier) SetMinimumEffort(effort uint32) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.minimum = effort
+}
+
+// Verify reports whether p is a valid proof for the current or previous seed whose nonce
+// hasn't been used with that seed before. A valid proof's nonce is recorded.
+func (v *Verifier) Verify(p *Proof) bool {
+	v.mu.Lock()
+	s := v.lookup(p.SeedHead)
+	minimum := v.minimum
+	if s == nil || p.Effort < minimum {
+		v.mu.Unlock()
+		return false
+	}
+	if _, seen := s.seen[p.Nonce]; seen {
+		v.mu.Unlock()
+		return false
+	}
+	v.mu.Unlock()
+
+	// the expensive checks run without the lock
+	challenge := Challenge(v.id, s.seed, p.Nonce, p.Effort)
+	if !MeetsEffort(challenge, p.Solution, p.Effort) || !v.eq.Verify(challenge, p.Solution) {
+		return false
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if _, seen := s.seen[p.Nonce]; seen {
+		return false
+	}
+	s.seen[p.Nonce] = struct{}{}
+	return true
+}
+
+// lookup finds the seed a proof names by its head. The caller must hold v.mu.
+func (v *Verifier) lookup(head [SeedHeadSize]byte) *seedState {
+	for _, s := range v.seeds {
+		if s != nil && [SeedHeadSize]byte(s.seed[:SeedHeadSize]) == head {
+			return s
+		}
+	}
+	return nil
+}
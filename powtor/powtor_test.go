@@ -0,0 +1,120 @@
+package powtor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/Zumium/powork"
+)
+
+// fakeEquiX stands in for Equi-X: the one solution of a challenge is its truncated SHA-256,
+// and one challenge in four has none
+type fakeEquiX struct{}
+
+func (fakeEquiX) Solve(challenge []byte) ([][SolutionSize]byte, error) {
+	sum := sha256.Sum256(challenge)
+	if sum[31]%4 == 0 {
+		return nil, nil
+	}
+	return [][SolutionSize]byte{[SolutionSize]byte(sum[:SolutionSize])}, nil
+}
+
+func (f fakeEquiX) Verify(challenge []byte, solution [SolutionSize]byte) bool {
+	s, _ := f.Solve(challenge)
+	return len(s) == 1 && s[0] == solution
+}
+
+var (
+	id    = [IDSize]byte{1, 2, 3}
+	seed1 = [SeedSize]byte{0xaa, 1}
+	seed2 = [SeedSize]byte{0xbb, 2}
+	seed3 = [SeedSize]byte{0xcc, 3}
+)
+
+func TestSolveVerify(t *testing.T) {
+	v := NewVerifier(fakeEquiX{}, id)
+	v.SetSeed(seed1)
+
+	p, err := Solve(context.Background(), fakeEquiX{}, id, seed1, 100)
+	if err != nil {
+		t.Fatalf("Error solving: %v\n", err)
+	}
+
+	data, _ := p.MarshalBinary()
+	decoded := new(Proof)
+	if err := decoded.UnmarshalBinary(data); err != nil || *decoded != *p {
+		t.Fatalf("Error decoding proof: %v\n", err)
+	}
+
+	if !v.Verify(decoded) {
+		t.Fatalf("Proof is not valid\n")
+	}
+	if v.Verify(decoded) {
+		t.Fatalf("Replayed proof is valid\n")
+	}
+
+	// a claimed effort the work does not meet
+	inflated := *p
+	inflated.Effort = 1 << 30
+	if v.Verify(&inflated) {
+		t.Fatalf("Proof with an inflated effort is valid\n")
+	}
+
+	if err := decoded.UnmarshalBinary(data[:10]); !errors.Is(err, powork.ErrInvalidEncoding) {
+		t.Fatalf("Truncated extension did not fail with ErrInvalidEncoding: %v\n", err)
+	}
+}
+
+func TestSeedRotation(t *testing.T) {
+	v := NewVerifier(fakeEquiX{}, id)
+
+	p, _ := Solve(context.Background(), fakeEquiX{}, id, seed1, 10)
+	if v.Verify(p) {
+		t.Fatalf("Proof is valid before a seed is set\n")
+	}
+
+	v.SetSeed(seed1)
+	v.SetSeed(seed2)
+	if !v.Verify(p) {
+		t.Fatalf("Proof for the previous seed is not valid\n")
+	}
+
+	q, _ := Solve(context.Background(), fakeEquiX{}, id, seed2, 10)
+	v.SetSeed(seed3)
+	if !v.Verify(q) {
+		t.Fatalf("Proof for the previous seed is not valid\n")
+	}
+
+	p, _ = Solve(context.Background(), fakeEquiX{}, id, seed1, 10)
+	if v.Verify(p) {
+		t.Fatalf("Proof for a dropped seed is valid\n")
+	}
+
+	v.SetMinimumEffort(50)
+	p, _ = Solve(context.Background(), fakeEquiX{}, id, seed3, 10)
+	if v.Verify(p) {
+		t.Fatalf("Proof below the minimum effort is valid\n")
+	}
+}
+
+func TestMeetsEffort(t *testing.T) {
+	challenge := Challenge(id, seed1, [NonceSize]byte{}, 1)
+	if len(challenge) != 16+32+32+16+4 || string(challenge[:16]) != Personalization || binary.BigEndian.Uint32(challenge[96:]) != 1 {
+		t.Fatalf("Challenge is laid out as %x\n", challenge)
+	}
+
+	// efforts 0 and 1 accept any digest
+	var s [SolutionSize]byte
+	if !MeetsEffort(challenge, s, 1) || !MeetsEffort(challenge, s, 0) {
+		t.Fatalf("Effort 1 is not met\n")
+	}
+
+	n := [NonceSize]byte{0xff, 0xff}
+	incrementNonce(&n)
+	if n[0] != 0 || n[1] != 0 || n[2] != 1 {
+		t.Fatalf("Incremented nonce is %x\n", n)
+	}
+}
@@ -0,0 +1,66 @@
+package powws
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Gorilla returns a handler that upgrades requests with u, runs the handshake and passes the
+// connections that pass it to handle. Connections that fail it are closed with a policy
+// violation.
+func (s *Server) Gorilla(u *websocket.Upgrader, handle func(*websocket.Conn, *http.Request)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := u.Upgrade(w, r, nil)
+		if err != nil {
+			// the upgrader has answered the request
+			return
+		}
+
+		if err := s.Handshake(r.Context(), GorillaConn(conn)); err != nil {
+			msg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "proof of work required")
+			conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+			conn.Close()
+			return
+		}
+
+		handle(conn, r)
+	})
+}
+
+// GorillaConn adapts a github.com/gorilla/websocket connection to run a handshake over. The
+// connection's read and write deadlines follow the context's deadline; cancellation without
+// a deadline is not noticed until the next message.
+func GorillaConn(c *websocket.Conn) Conn {
+	return gorillaConn{c}
+}
+
+type gorillaConn struct {
+	c *websocket.Conn
+}
+
+func (g gorillaConn) ReadMessage(ctx context.Context) ([]byte, error) {
+	deadline, _ := ctx.Deadline()
+	g.c.SetReadDeadline(deadline)
+	defer g.c.SetReadDeadline(time.Time{})
+
+	for {
+		typ, data, err := g.c.ReadMessage()
+		if err != nil {
+			return nil, err
+		}
+		if typ == websocket.TextMessage || typ == websocket.BinaryMessage {
+			return data, nil
+		}
+	}
+}
+
+func (g gorillaConn) WriteMessage(ctx context.Context, data []byte) error {
+	deadline, _ := ctx.Deadline()
+	g.c.SetWriteDeadline(deadline)
+	defer g.c.SetWriteDeadline(time.Time{})
+
+	return g.c.WriteMessage(websocket.TextMessage, data)
+}
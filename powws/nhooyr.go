@@ -0,0 +1,46 @@
+package powws
+
+import (
+	"context"
+	"net/http"
+
+	"nhooyr.io/websocket"
+)
+
+// Nhooyr returns a handler that accepts requests with opts, runs the handshake and passes the
+// connections that pass it to handle. Connections that fail it are closed with a policy
+// violation.
+func (s *Server) Nhooyr(opts *websocket.AcceptOptions, handle func(*websocket.Conn, *http.Request)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, opts)
+		if err != nil {
+			// Accept has answered the request
+			return
+		}
+
+		if err := s.Handshake(r.Context(), NhooyrConn(conn)); err != nil {
+			conn.Close(websocket.StatusPolicyViolation, "proof of work required")
+			return
+		}
+
+		handle(conn, r)
+	})
+}
+
+// NhooyrConn adapts a nhooyr.io/websocket connection to run a handshake over
+func NhooyrConn(c *websocket.Conn) Conn {
+	return nhooyrConn{c}
+}
+
+type nhooyrConn struct {
+	c *websocket.Conn
+}
+
+func (n nhooyrConn) ReadMessage(ctx context.Context) ([]byte, error) {
+	_, data, err := n.c.Read(ctx)
+	return data, err
+}
+
+func (n nhooyrConn) WriteMessage(ctx context.Context, data []byte) error {
+	return n.c.Write(ctx, websocket.MessageText, data)
+}
@@ -0,0 +1,212 @@
+// Package powws runs a proof of work handshake at the start of a WebSocket connection, before
+// the application protocol starts, so that every connection costs its client some work.
+//
+// The handshake is three text messages in JSON:
+//
+//	server → client  {"challenge":"<base64>","difficulty":16,"expires":1700000000000}
+//	client → server  {"msg":"","nonce":123,"difficulty":16,"challenge":"<base64>",...}
+//	server → client  {"ok":true}
+//
+// The first is a challenge in the JSON encoding of powork.Challenge, the second a proof for
+// it in the JSON encoding of powork.PoWork, and the third the server's verdict. A rejected
+// proof gets {"ok":false,"error":"..."} and the server closes the connection. Once the
+// verdict is sent, the connection belongs to the application.
+//
+// Server.Gorilla and Server.Nhooyr wrap the handlers of the github.com/gorilla/websocket and
+// nhooyr.io/websocket packages so that they only get connections that passed the handshake.
+// Clients run the other side with Client.Handshake on a connection adapted by GorillaConn or
+// NhooyrConn.
+package powws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+// DefaultTimeout is how long a client has to complete the handshake, unless another timeout
+// is set with WithTimeout
+const DefaultTimeout = 30 * time.Second
+
+// ErrRejected is returned by Client.Handshake when the server rejects the proof, and by
+// Server.Handshake when the client sends no valid proof
+var ErrRejected = errors.New("Proof of work handshake rejected")
+
+// Conn is a connection that exchanges whole messages, which the handshake runs over.
+// GorillaConn and NhooyrConn adapt the connections of the WebSocket packages.
+type Conn interface {
+	// ReadMessage reads the next data message
+	ReadMessage(ctx context.Context) ([]byte, error)
+	// WriteMessage writes data as a text message
+	WriteMessage(ctx context.Context, data []byte) error
+}
+
+// verdict is the last message of the handshake
+type verdict struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithWorker sets the Worker used to issue challenges and validate proofs. By default
+// powork.NewWorker is used.
+func WithWorker(w *powork.Worker) Option {
+	return func(s *Server) {
+		s.worker = w
+	}
+}
+
+// WithDifficulty sets the difficulty demanded from clients, overriding the difficulty of the
+// Server's Worker
+func WithDifficulty(difficulty int) Option {
+	return func(s *Server) {
+		s.difficulty = difficulty
+	}
+}
+
+// WithTimeout sets how long a client has to complete the handshake
+func WithTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.timeout = d
+	}
+}
+
+// Server runs the server side of the handshake
+type Server struct {
+	worker     *powork.Worker
+	difficulty int
+	timeout    time.Duration
+}
+
+// NewServer creates a Server
+func NewServer(opts ...Option) *Server {
+	s := &Server{timeout: DefaultTimeout}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.worker == nil {
+		s.worker = powork.NewWorker()
+	}
+	if s.difficulty > 0 {
+		s.worker.SetDifficulty(s.difficulty)
+	}
+	return s
+}
+
+// Handshake sends conn a challenge and waits for a valid proof for it. It returns nil once
+// the client has been told its proof is valid, and an error wrapping ErrRejected if the
+// client's answer was not a valid proof; conn should then be closed.
+func (s *Server) Handshake(ctx context.Context, conn Conn) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	challenge, err := s.worker.NewChallenge()
+	if err != nil {
+		return err
+	}
+
+	if err := writeJSON(ctx, conn, challenge); err != nil {
+		return err
+	}
+
+	data, err := conn.ReadMessage(ctx)
+	if err != nil {
+		return err
+	}
+
+	var reason string
+	pow := new(powork.PoWork)
+	if err := pow.UnmarshalJSON(data); err != nil {
+		reason = "proof is not valid JSON"
+	} else if ok, err := s.worker.ValidateChallengeContext(ctx, pow); err != nil || !ok {
+		reason = "proof of work is not valid"
+	}
+
+	if reason != "" {
+		writeJSON(ctx, conn, verdict{Error: reason})
+		return fmt.Errorf("%w: %s", ErrRejected, reason)
+	}
+
+	return writeJSON(ctx, conn, verdict{OK: true})
+}
+
+// Client runs the client side of the handshake
+type Client struct {
+	// NewWorker returns the Worker used to solve a challenge. Its difficulty is replaced by
+	// the difficulty the server asks for. If nil, powork.NewWorker is used.
+	NewWorker func() *powork.Worker
+
+	// MaxDifficulty is the highest difficulty the Client agrees to solve. Zero means no limit.
+	MaxDifficulty int
+}
+
+// Handshake reads the server's challenge from conn, answers it and waits for the verdict. It
+// returns nil once the server has accepted the proof and the application protocol can start.
+func (c *Client) Handshake(ctx context.Context, conn Conn) error {
+	data, err := conn.ReadMessage(ctx)
+	if err != nil {
+		return err
+	}
+
+	challenge := new(powork.Challenge)
+	if err := challenge.UnmarshalJSON(data); err != nil {
+		return err
+	}
+
+	difficulty := challenge.GetDifficulty()
+	if difficulty <= 0 {
+		return fmt.Errorf("%w: server sent a challenge without a difficulty", powork.ErrInvalidDifficulty)
+	}
+	if c.MaxDifficulty > 0 && difficulty > c.MaxDifficulty {
+		return fmt.Errorf("%w: challenge of difficulty %d is above the limit of %d", powork.ErrInvalidDifficulty, difficulty, c.MaxDifficulty)
+	}
+
+	var worker *powork.Worker
+	if c.NewWorker != nil {
+		worker = c.NewWorker()
+	} else {
+		worker = powork.NewWorker()
+	}
+
+	if err := worker.SetDifficulty(difficulty); err != nil {
+		return err
+	}
+
+	pow, err := worker.DoProofForChallengeContext(ctx, challenge, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := writeJSON(ctx, conn, pow); err != nil {
+		return err
+	}
+
+	data, err = conn.ReadMessage(ctx)
+	if err != nil {
+		return err
+	}
+
+	var v verdict
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("%w: server sent an invalid verdict", powork.ErrInvalidEncoding)
+	}
+	if !v.OK {
+		return fmt.Errorf("%w: %s", ErrRejected, v.Error)
+	}
+	return nil
+}
+
+func writeJSON(ctx context.Context, conn Conn, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(ctx, data)
+}
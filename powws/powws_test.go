@@ -0,0 +1,135 @@
+package powws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Zumium/powork"
+	gorilla "github.com/gorilla/websocket"
+	"nhooyr.io/websocket"
+)
+
+// echo answers a text message with the same message
+func echo(c *gorilla.Conn, r *http.Request) {
+	defer c.Close()
+	if _, data, err := c.ReadMessage(); err == nil {
+		c.WriteMessage(gorilla.TextMessage, data)
+	}
+}
+
+func TestGorilla(t *testing.T) {
+	s := NewServer(WithDifficulty(8))
+	srv := httptest.NewServer(s.Gorilla(&gorilla.Upgrader{}, echo))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	conn, _, err := gorilla.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Error dialing: %v\n", err)
+	}
+	defer conn.Close()
+
+	client := &Client{MaxDifficulty: 16}
+	if err := client.Handshake(context.Background(), GorillaConn(conn)); err != nil {
+		t.Fatalf("Handshake failed: %v\n", err)
+	}
+
+	// the application protocol runs after the handshake
+	conn.WriteMessage(gorilla.TextMessage, []byte("hello"))
+	if _, data, err := conn.ReadMessage(); err != nil || string(data) != "hello" {
+		t.Fatalf("Echo returned %q: %v\n", data, err)
+	}
+
+	// a client that won't solve the challenge gets nowhere
+	conn, _, err = gorilla.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Error dialing: %v\n", err)
+	}
+	defer conn.Close()
+
+	client = &Client{MaxDifficulty: 4}
+	if err := client.Handshake(context.Background(), GorillaConn(conn)); !errors.Is(err, powork.ErrInvalidDifficulty) {
+		t.Fatalf("Handshake above the maximum difficulty returned %v\n", err)
+	}
+}
+
+func TestNhooyr(t *testing.T) {
+	s := NewServer(WithDifficulty(8))
+	srv := httptest.NewServer(s.Nhooyr(nil, func(c *websocket.Conn, r *http.Request) {
+		defer c.CloseNow()
+		if _, data, err := c.Read(r.Context()); err == nil {
+			c.Write(r.Context(), websocket.MessageText, data)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	conn, _, err := websocket.Dial(ctx, srv.URL, nil)
+	if err != nil {
+		t.Fatalf("Error dialing: %v\n", err)
+	}
+	defer conn.CloseNow()
+
+	if err := new(Client).Handshake(ctx, NhooyrConn(conn)); err != nil {
+		t.Fatalf("Handshake failed: %v\n", err)
+	}
+
+	conn.Write(ctx, websocket.MessageText, []byte("hello"))
+	if _, data, err := conn.Read(ctx); err != nil || string(data) != "hello" {
+		t.Fatalf("Echo returned %q: %v\n", data, err)
+	}
+}
+
+// pipeConn is one end of an in-memory Conn pair
+type pipeConn struct {
+	in  <-chan []byte
+	out chan<- []byte
+}
+
+func (p pipeConn) ReadMessage(ctx context.Context) ([]byte, error) {
+	select {
+	case data := <-p.in:
+		return data, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p pipeConn) WriteMessage(ctx context.Context, data []byte) error {
+	select {
+	case p.out <- data:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestRejected(t *testing.T) {
+	toServer, toClient := make(chan []byte, 1), make(chan []byte, 1)
+	server := pipeConn{in: toServer, out: toClient}
+	client := pipeConn{in: toClient, out: toServer}
+
+	done := make(chan error)
+	go func() {
+		done <- NewServer(WithDifficulty(8)).Handshake(context.Background(), server)
+	}()
+
+	// answer the challenge with a proof for another one
+	<-toClient
+	solver := powork.NewWorker()
+	solver.SetDifficulty(8)
+	pow, _ := solver.DoProofForChallenge(powork.ChallengeFromBytes([]byte("made up")), nil)
+	data, _ := pow.MarshalJSON()
+	client.WriteMessage(context.Background(), data)
+
+	if err := <-done; !errors.Is(err, ErrRejected) {
+		t.Fatalf("Server accepted a proof for another challenge: %v\n", err)
+	}
+	if v := string(<-toClient); !strings.Contains(v, `"ok":false`) {
+		t.Fatalf("Server sent verdict %s\n", v)
+	}
+}
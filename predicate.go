@@ -0,0 +1,54 @@
+package powork
+
+import (
+	"bytes"
+	"math/big"
+)
+
+// A Predicate decides whether a hash sum is a valid proof of work. The default rule is a
+// number of leading zero bits given by the Worker's difficulty; set a Predicate with
+// SetPredicate to use another rule. Predicates must be safe for concurrent use.
+type Predicate interface {
+	Valid(sum []byte) bool
+}
+
+// PredicateFunc adapts an ordinary function to a Predicate
+type PredicateFunc func(sum []byte) bool
+
+// Valid calls f(sum)
+func (f PredicateFunc) Valid(sum []byte) bool {
+	return f(sum)
+}
+
+// LeadingZeros returns a Predicate accepting sums that start with at least bits zero bits.
+// This is the rule a Worker uses without a Predicate.
+func LeadingZeros(bits int) Predicate {
+	return PredicateFunc(func(sum []byte) bool {
+		return leadingZeros(sum) >= bits
+	})
+}
+
+// BelowTarget returns a Predicate accepting sums that, read as a big-endian unsigned integer,
+// are less than or equal to target
+func BelowTarget(target *big.Int) Predicate {
+	t := new(big.Int).Set(target)
+	return PredicateFunc(func(sum []byte) bool {
+		return new(big.Int).SetBytes(sum).Cmp(t) <= 0
+	})
+}
+
+// HasSuffix returns a Predicate accepting sums that end with suffix
+func HasSuffix(suffix []byte) Predicate {
+	s := append([]byte(nil), suffix...)
+	return PredicateFunc(func(sum []byte) bool {
+		return bytes.HasSuffix(sum, s)
+	})
+}
+
+// SetPredicate sets the rule the Worker's proofs must satisfy, replacing the leading zero
+// bits counted by the difficulty. The difficulty is still recorded in proofs, and still
+// applies to challenges and to ValidateWithMinimum. Passing nil restores the default rule.
+func (p *Worker) SetPredicate(pred Predicate) {
+	p.predicate = pred
+	p.target = nil
+}
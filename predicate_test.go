@@ -0,0 +1,63 @@
+package powork
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+func TestBuiltinPredicates(t *testing.T) {
+	sum := []byte{0x00, 0x1f, 0xab, 0xcd}
+
+	for _, c := range []struct {
+		name string
+		pred Predicate
+		ok   bool
+	}{
+		{"LeadingZeros(11)", LeadingZeros(11), true},
+		{"LeadingZeros(12)", LeadingZeros(12), false},
+		{"BelowTarget(equal)", BelowTarget(big.NewInt(0x1fabcd)), true},
+		{"BelowTarget(below)", BelowTarget(big.NewInt(0x1fabcc)), false},
+		{"HasSuffix(abcd)", HasSuffix([]byte{0xab, 0xcd}), true},
+		{"HasSuffix(cdcd)", HasSuffix([]byte{0xcd, 0xcd}), false},
+	} {
+		if c.pred.Valid(sum) != c.ok {
+			t.Fatalf("%s: expected %v\n", c.name, c.ok)
+		}
+	}
+}
+
+func TestSetPredicate(t *testing.T) {
+	worker := NewWorker()
+	worker.SetPredicate(HasSuffix([]byte{0x42}))
+
+	pow, err := worker.DoProofForString("A message ending in 0x42")
+	if err != nil {
+		t.Fatalf("Could not compute proof: %v\n", err)
+	}
+
+	sum, _ := pow.sum(worker.validationHasher())
+	if sum[len(sum)-1] != 0x42 {
+		t.Fatalf("Proof hash %x does not end in 0x42\n", sum)
+	}
+
+	ok, err := worker.ValidatePoWork(pow)
+	if err != nil || !ok {
+		t.Fatalf("Could not validate proof: %v, %v\n", ok, err)
+	}
+
+	// back to leading zeros at the difficulty
+	worker.SetPredicate(nil)
+	if ok, _ := worker.ValidatePoWork(pow); ok != (leadingZeros(sum) >= worker.GetDifficulty()) {
+		t.Fatalf("Default rule was not restored\n")
+	}
+
+	calls := 0
+	worker.SetPredicate(PredicateFunc(func(sum []byte) bool {
+		calls++
+		return bytes.HasPrefix(sum, []byte{0})
+	}))
+	if _, err := worker.DoProofForString("A message for a custom rule"); err != nil || calls == 0 {
+		t.Fatalf("Custom rule was not used: %v\n", err)
+	}
+}
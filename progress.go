@@ -0,0 +1,71 @@
+package powork
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultProgressInterval is the number of iterations between two progress callbacks, unless
+// the Worker is given another interval
+const DefaultProgressInterval = 100000
+
+// SetProgressCallback sets a function the Worker calls while searching for a proof, every
+// time the search has performed another progress interval worth of iterations. It receives
+// the total number of iterations so far, across all search goroutines, and the time since
+// the search started. Calls are never concurrent, but come from the search goroutines, so
+// f should return quickly. Passing nil removes the callback.
+func (p *Worker) SetProgressCallback(f func(iterations uint64, elapsed time.Duration)) {
+	p.progress = f
+}
+
+// SetProgressInterval sets how many iterations pass between two progress callbacks
+func (p *Worker) SetProgressInterval(n uint64) error {
+	if n == 0 {
+		return fmt.Errorf("%w: progress interval must be at least 1", ErrInvalidSetting)
+	}
+
+	p.progressInterval = n
+	return nil
+}
+
+// searchState is shared by all goroutines searching for one proof
+type searchState struct {
+	start    time.Time
+	interval uint64
+	progress func(iterations uint64, elapsed time.Duration)
+
+	// gate pauses the search goroutines of a Job
+	gate *pauseGate
+
+	// bestEffort makes the search goroutines offer their best hashes, which are kept in
+	// best and bestBits
+	bestEffort bool
+
+	// mu serializes progress callbacks and guards iterations and the best proof
+	mu         sync.Mutex
+	iterations uint64
+	best       *PoWork
+	bestBits   int
+}
+
+func (p *Worker) newSearchState() *searchState {
+	return &searchState{
+		start:      time.Now(),
+		interval:   p.progressInterval,
+		progress:   p.progress,
+		bestEffort: p.bestEffort,
+	}
+}
+
+// iterated is called by a search goroutine after its local'th iteration
+func (st *searchState) iterated(local int) {
+	if st.progress == nil || uint64(local)%st.interval != 0 {
+		return
+	}
+
+	st.mu.Lock()
+	st.iterations += st.interval
+	st.progress(st.iterations, time.Since(st.start))
+	st.mu.Unlock()
+}
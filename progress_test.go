@@ -0,0 +1,56 @@
+package powork
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgressCallback(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(100)
+	worker.SetTimeout(300)
+	worker.SetProgressInterval(1000)
+
+	calls := 0
+	var last uint64
+	worker.SetProgressCallback(func(iterations uint64, elapsed time.Duration) {
+		if iterations <= last || iterations%1000 != 0 {
+			t.Errorf("Progress went from %v to %v iterations\n", last, iterations)
+		}
+		last = iterations
+		calls++
+	})
+
+	worker.DoProofForString("A message that takes forever")
+	if calls == 0 {
+		t.Fatalf("Progress callback was never called\n")
+	}
+
+	t.Logf("%v progress callbacks, %v iterations\n", calls, last)
+}
+
+func TestProgressCallbackParallel(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(100)
+	worker.SetTimeout(300)
+	worker.SetConcurrency(4)
+	worker.SetProgressInterval(1000)
+
+	var last uint64
+	worker.SetProgressCallback(func(iterations uint64, elapsed time.Duration) {
+		// totals are shared by all goroutines, and calls are serialized
+		if iterations <= last {
+			t.Errorf("Progress went from %v to %v iterations\n", last, iterations)
+		}
+		last = iterations
+	})
+
+	worker.DoProofForString("A message that takes forever")
+	if last == 0 {
+		t.Fatalf("Progress callback was never called\n")
+	}
+
+	if err := worker.SetProgressInterval(0); err == nil {
+		t.Fatalf("Progress interval of 0 was accepted\n")
+	}
+}
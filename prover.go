@@ -0,0 +1,20 @@
+package powork
+
+import "context"
+
+// A Prover computes proofs of work. Worker is a Prover; depend on this interface instead to
+// substitute another engine, or a fake in tests.
+type Prover interface {
+	DoProofFor(msg []byte) (*PoWork, error)
+	DoProofForContext(ctx context.Context, msg []byte) (*PoWork, error)
+}
+
+// A Verifier checks proofs of work. Worker is a Verifier.
+type Verifier interface {
+	ValidatePoWork(pow *PoWork) (bool, error)
+}
+
+var (
+	_ Prover   = (*Worker)(nil)
+	_ Verifier = (*Worker)(nil)
+)
@@ -0,0 +1,48 @@
+package powork
+
+import (
+	"context"
+	"testing"
+)
+
+// fakeProver returns proofs without doing any work
+type fakeProver struct {
+	calls int
+}
+
+func (f *fakeProver) DoProofFor(msg []byte) (*PoWork, error) {
+	return f.DoProofForContext(context.Background(), msg)
+}
+
+func (f *fakeProver) DoProofForContext(ctx context.Context, msg []byte) (*PoWork, error) {
+	f.calls++
+	return &PoWork{msg: msg}, nil
+}
+
+// acceptAll accepts every proof
+type acceptAll struct{}
+
+func (acceptAll) ValidatePoWork(pow *PoWork) (bool, error) {
+	return true, nil
+}
+
+// submit is application code that only knows the interfaces
+func submit(p Prover, v Verifier, msg string) (bool, error) {
+	pow, err := p.DoProofFor([]byte(msg))
+	if err != nil {
+		return false, err
+	}
+	return v.ValidatePoWork(pow)
+}
+
+func TestProverVerifier(t *testing.T) {
+	worker := NewWorker()
+	if ok, err := submit(worker, worker, "A real proof"); err != nil || !ok {
+		t.Fatalf("Worker did not work as Prover and Verifier: %v, %v\n", ok, err)
+	}
+
+	fake := &fakeProver{}
+	if ok, err := submit(fake, acceptAll{}, "A fake proof"); err != nil || !ok || fake.calls != 1 {
+		t.Fatalf("Fakes were not used: %v, %v, %d calls\n", ok, err, fake.calls)
+	}
+}
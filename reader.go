@@ -0,0 +1,57 @@
+package powork
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+)
+
+// Digest returns the SHA-256 of everything read from r, which is the message DoProofForReader
+// proves
+func Digest(r io.Reader) ([]byte, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// DoProofForReader calculates a proof of work for the contents of r without holding them in
+// memory: r is read once into its Digest, and the proof is computed for the digest. This is
+// how large files are stamped.
+func (p *Worker) DoProofForReader(r io.Reader) (*PoWork, error) {
+	return p.DoProofForReaderContext(context.Background(), r)
+}
+
+// DoProofForReaderContext does the same thing as DoProofForReader except carrying a context,
+// which only applies to the search. Reading r is not interrupted.
+func (p *Worker) DoProofForReaderContext(ctx context.Context, r io.Reader) (*PoWork, error) {
+	digest, err := Digest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.DoProofForContext(ctx, digest)
+}
+
+// ValidateDigest checks a proof from DoProofForReader against the Digest of the contents it
+// should be for, as by ValidatePoWork
+func (p *Worker) ValidateDigest(pow *PoWork, digest []byte) (bool, error) {
+	if !bytes.Equal(pow.msg, digest) {
+		return false, nil
+	}
+
+	return p.ValidatePoWork(pow)
+}
+
+// ValidateReader checks a proof from DoProofForReader against the contents of r, reading
+// them once
+func (p *Worker) ValidateReader(pow *PoWork, r io.Reader) (bool, error) {
+	digest, err := Digest(r)
+	if err != nil {
+		return false, err
+	}
+
+	return p.ValidateDigest(pow, digest)
+}
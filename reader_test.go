@@ -0,0 +1,58 @@
+package powork
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// failingReader returns some data and then an error
+type failingReader struct{ read bool }
+
+func (f *failingReader) Read(b []byte) (int, error) {
+	if f.read {
+		return 0, errors.New("disk on fire")
+	}
+	f.read = true
+	return copy(b, "some data"), nil
+}
+
+func TestDoProofForReader(t *testing.T) {
+	worker := NewWorker()
+	contents := strings.Repeat("a large file ", 100000)
+
+	pow, err := worker.DoProofForReader(strings.NewReader(contents))
+	if err != nil {
+		t.Fatalf("Error doing proof: %v\n", err)
+	}
+
+	if ok, err := worker.ValidateReader(pow, strings.NewReader(contents)); !ok || err != nil {
+		t.Fatalf("Proof did not validate against its contents: %v\n", err)
+	}
+
+	digest, _ := Digest(strings.NewReader(contents))
+	if !bytes.Equal(pow.GetMessage(), digest) {
+		t.Fatalf("Proof is not for the digest of its contents\n")
+	}
+	if ok, err := worker.ValidateDigest(pow, digest); !ok || err != nil {
+		t.Fatalf("Proof did not validate against its digest: %v\n", err)
+	}
+
+	if ok, _ := worker.ValidateReader(pow, strings.NewReader(contents+".")); ok {
+		t.Fatalf("Proof validated against other contents\n")
+	}
+}
+
+func TestDoProofForReaderError(t *testing.T) {
+	worker := NewWorker()
+	if _, err := worker.DoProofForReader(&failingReader{}); err == nil || err == io.EOF {
+		t.Fatalf("Failing reader did not fail the proof: %v\n", err)
+	}
+
+	pow, _ := worker.DoProofForReader(strings.NewReader("some data"))
+	if _, err := worker.ValidateReader(pow, &failingReader{}); err == nil {
+		t.Fatalf("Failing reader did not fail validation\n")
+	}
+}
@@ -0,0 +1,146 @@
+package powork
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"sync"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
+	"lukechampine.com/blake3"
+)
+
+// An Algorithm is a hash algorithm known by name and by a one byte wire identifier. Proofs
+// for a registered algorithm carry its identifier when encoded with MarshalBinary.
+type Algorithm struct {
+	ID   uint8
+	Name string
+	New  func() hash.Hash
+}
+
+// Identifiers of the built-in algorithms
+const (
+	AlgorithmSHA3_512   = 1
+	AlgorithmSHA256     = 2
+	AlgorithmSHA512     = 3
+	AlgorithmBLAKE2b256 = 4
+	AlgorithmBLAKE2b512 = 5
+	AlgorithmBLAKE3_256 = 6
+	AlgorithmSHA256d    = 7
+)
+
+var registry = struct {
+	sync.RWMutex
+	byName map[string]Algorithm
+	byID   map[uint8]Algorithm
+}{
+	byName: make(map[string]Algorithm),
+	byID:   make(map[uint8]Algorithm),
+}
+
+func init() {
+	for _, a := range []Algorithm{
+		{AlgorithmSHA3_512, "sha3-512", sha3.New512},
+		{AlgorithmSHA256, "sha256", sha256.New},
+		{AlgorithmSHA512, "sha512", sha512.New},
+		{AlgorithmBLAKE2b256, "blake2b-256", func() hash.Hash { h, _ := blake2b.New256(nil); return h }},
+		{AlgorithmBLAKE2b512, "blake2b-512", func() hash.Hash { h, _ := blake2b.New512(nil); return h }},
+		{AlgorithmBLAKE3_256, "blake3-256", func() hash.Hash { return blake3.New(32, nil) }},
+		{AlgorithmSHA256d, "sha256d", DoubleHash(sha256.New)},
+	} {
+		registerAlgorithm(a)
+	}
+}
+
+// firstUserAlgorithm is the lowest identifier RegisterAlgorithm accepts
+const firstUserAlgorithm = 128
+
+// RegisterAlgorithm adds a hash algorithm to the registry. Names and identifiers must be
+// unique, and identifiers below 128 are reserved for PoWork.
+func RegisterAlgorithm(a Algorithm) error {
+	if a.ID < firstUserAlgorithm {
+		return fmt.Errorf("%w: algorithm identifiers below %d are reserved", ErrInvalidSetting, firstUserAlgorithm)
+	}
+	return registerAlgorithm(a)
+}
+
+func registerAlgorithm(a Algorithm) error {
+	if a.ID == 0 || a.Name == "" || a.New == nil {
+		return fmt.Errorf("%w: algorithm needs an identifier, a name and a constructor", ErrInvalidSetting)
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, ok := registry.byName[a.Name]; ok {
+		return fmt.Errorf("%w: algorithm %q is already registered", ErrInvalidSetting, a.Name)
+	}
+	if _, ok := registry.byID[a.ID]; ok {
+		return fmt.Errorf("%w: algorithm identifier %d is already registered", ErrInvalidSetting, a.ID)
+	}
+
+	registry.byName[a.Name] = a
+	registry.byID[a.ID] = a
+	return nil
+}
+
+// LookupAlgorithm finds a registered algorithm by name
+func LookupAlgorithm(name string) (Algorithm, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	a, ok := registry.byName[name]
+	return a, ok
+}
+
+// LookupAlgorithmID finds a registered algorithm by wire identifier
+func LookupAlgorithmID(id uint8) (Algorithm, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	a, ok := registry.byID[id]
+	return a, ok
+}
+
+// NewWorkerForAlgorithm creates a Worker with the defaults of NewWorker, hashing with the
+// registered algorithm of the given name
+func NewWorkerForAlgorithm(name string) (*Worker, error) {
+	a, ok := LookupAlgorithm(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown algorithm %q", ErrInvalidSetting, name)
+	}
+
+	w := NewWorkerWithHashFunc(a.New)
+	w.algorithm = a.Name
+	return w, nil
+}
+
+// SetAcceptedAlgorithms makes the Worker also accept proofs for the given registered
+// algorithms, each validated with its own hash, so that clients configured with different
+// hashes can talk to one server. The Worker still computes proofs with its own hash. Passing
+// no names only accepts the Worker's own algorithm again.
+func (p *Worker) SetAcceptedAlgorithms(names ...string) error {
+	accepted := make(map[string]func() hash.Hash, len(names))
+	for _, name := range names {
+		a, ok := LookupAlgorithm(name)
+		if !ok {
+			return fmt.Errorf("%w: unknown algorithm %q", ErrInvalidSetting, name)
+		}
+		accepted[name] = a.New
+	}
+
+	p.accepted = accepted
+	return nil
+}
+
+// GetAlgorithm gets the name of the Worker's hash algorithm, or "" if its hash is unnamed
+func (p *Worker) GetAlgorithm() string {
+	return p.algorithm
+}
+
+// GetAlgorithm gets the name of the hash algorithm the proof declares, or "" if it declares none
+func (p *PoWork) GetAlgorithm() string {
+	return p.algorithm
+}
@@ -0,0 +1,95 @@
+package powork
+
+import (
+	"crypto/md5"
+	"errors"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	for _, name := range []string{"sha3-512", "sha256", "sha512", "blake2b-256", "blake2b-512", "blake3-256", "sha256d"} {
+		a, ok := LookupAlgorithm(name)
+		if !ok {
+			t.Fatalf("Algorithm %s is not registered\n", name)
+		}
+
+		if byID, ok := LookupAlgorithmID(a.ID); !ok || byID.Name != name {
+			t.Fatalf("Identifier %d does not find %s\n", a.ID, name)
+		}
+	}
+
+	if err := RegisterAlgorithm(Algorithm{200, "md5", md5.New}); err != nil {
+		t.Fatalf("Could not register md5: %v\n", err)
+	}
+	defer func() {
+		registry.Lock()
+		delete(registry.byName, "md5")
+		delete(registry.byID, 200)
+		registry.Unlock()
+	}()
+
+	for _, a := range []Algorithm{{200, "md5-again", md5.New}, {201, "md5", md5.New}, {0, "zero", md5.New}, {8, "reserved", md5.New}, {202, "", md5.New}, {203, "nil", nil}} {
+		if err := RegisterAlgorithm(a); !errors.Is(err, ErrInvalidSetting) {
+			t.Fatalf("Expected ErrInvalidSetting registering %d %q, got %v\n", a.ID, a.Name, err)
+		}
+	}
+
+	if _, err := NewWorkerForAlgorithm("sha1"); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Expected ErrInvalidSetting for an unknown algorithm, got %v\n", err)
+	}
+
+	if w, _ := NewWorkerForAlgorithm("blake3-256"); w.GetAlgorithm() != "blake3-256" {
+		t.Fatalf("Worker for blake3-256 has algorithm %q\n", w.GetAlgorithm())
+	}
+}
+
+func TestAlgorithmOnTheWire(t *testing.T) {
+	client, err := NewWorkerForAlgorithm("sha256")
+	if err != nil {
+		t.Fatalf("Could not create worker: %v\n", err)
+	}
+
+	pow, err := client.DoProofForString("A message from a SHA-256 client")
+	if err != nil {
+		t.Fatalf("Could not compute proof: %v\n", err)
+	}
+
+	data, _ := pow.MarshalBinary()
+	decoded := new(PoWork)
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Could not decode proof: %v\n", err)
+	}
+	if decoded.GetAlgorithm() != "sha256" {
+		t.Fatalf("Decoded algorithm is %q, expected sha256\n", decoded.GetAlgorithm())
+	}
+
+	server := NewWorker()
+	if ok, _ := server.ValidatePoWork(decoded); ok {
+		t.Fatalf("SHA3-512 server accepted a SHA-256 proof without being told to\n")
+	}
+
+	if err := server.SetAcceptedAlgorithms("sha256", "blake3-256"); err != nil {
+		t.Fatalf("Could not accept algorithms: %v\n", err)
+	}
+	if ok, err := server.ValidatePoWork(decoded); err != nil || !ok {
+		t.Fatalf("Server did not validate the SHA-256 proof: %v, %v\n", ok, err)
+	}
+
+	// its own proofs still validate
+	own, _ := server.DoProofForString("A message from the server")
+	if ok, _ := server.ValidatePoWork(own); !ok {
+		t.Fatalf("Server no longer validates its own proofs\n")
+	}
+
+	// unregistered algorithms travel by name
+	argon, _ := NewArgon2idWorker(testArgon2Params)
+	apow, _ := argon.DoProofForString("A memory-hard message")
+	data, _ = apow.MarshalBinary()
+	if err := decoded.UnmarshalBinary(data); err != nil || decoded.GetAlgorithm() != apow.GetAlgorithm() {
+		t.Fatalf("Unregistered algorithm did not survive the wire: %q, %v\n", decoded.GetAlgorithm(), err)
+	}
+
+	if err := server.SetAcceptedAlgorithms("sha1"); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Expected ErrInvalidSetting for an unknown algorithm, got %v\n", err)
+	}
+}
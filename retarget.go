@@ -0,0 +1,213 @@
+package powork
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRetargetWindow is the number of solves a Retargeter waits for between adjustments
+	DefaultRetargetWindow = 16
+	// maxRetargetStep bounds how many bits a window adjustment moves the difficulty, which
+	// limits the work to a quarter or four times the previous, as Bitcoin does
+	maxRetargetStep = 2
+	// minSolveTime stands in for shorter, or equal, times between solves
+	minSolveTime = time.Millisecond
+)
+
+// NextDifficulty computes the difficulty that brings the time between proofs to interval,
+// given the times at which proofs of the given difficulty were solved, oldest first. As in
+// Bitcoin, the expected work is scaled by how far the observed time falls short of or
+// exceeds the desired one, clamped to a factor of four in either direction. With fewer than
+// two solves there is nothing to measure and the difficulty is returned unchanged.
+func NextDifficulty(difficulty int, solves []time.Time, interval time.Duration) int {
+	if len(solves) < 2 || interval <= 0 {
+		return difficulty
+	}
+
+	elapsed := solves[len(solves)-1].Sub(solves[0])
+	if minimum := time.Duration(len(solves)-1) * minSolveTime; elapsed < minimum {
+		elapsed = minimum
+	}
+
+	expected := time.Duration(len(solves)-1) * interval
+	step := math.Log2(float64(expected) / float64(elapsed))
+	step = math.Max(-maxRetargetStep, math.Min(maxRetargetStep, step))
+
+	next := difficulty + int(math.Round(step))
+	if next < 1 {
+		return 1
+	}
+	return next
+}
+
+// A Retargeter adjusts a difficulty to keep the time between proofs close to a desired
+// interval as hardware and load change. Feed it the time of every solve with Observe.
+//
+// By default it retargets as Bitcoin does: once every window of solves, with NextDifficulty.
+// With SetSmoothing it instead keeps an exponential moving average of the hash rate the solves
+// imply, and retargets after every solve. A Retargeter is safe for concurrent use.
+type Retargeter struct {
+	interval time.Duration
+
+	mu         sync.Mutex
+	difficulty int
+	min, max   int
+	window     int
+	alpha      float64
+	solves     []time.Time
+	last       time.Time
+	logRate    float64
+	rated      bool
+}
+
+// NewRetargeter creates a Retargeter aiming for interval between proofs, starting from the
+// given difficulty
+func NewRetargeter(interval time.Duration, difficulty int) (*Retargeter, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("%w: interval must be greater than 0", ErrInvalidSetting)
+	}
+
+	if difficulty <= 0 {
+		return nil, fmt.Errorf("%w: must be at least 1", ErrInvalidDifficulty)
+	}
+
+	return &Retargeter{
+		interval:   interval,
+		difficulty: difficulty,
+		min:        1,
+		max:        math.MaxInt32,
+		window:     DefaultRetargetWindow,
+	}, nil
+}
+
+// SetWindow sets the number of solves between adjustments. Larger windows react more slowly
+// but are less thrown off by luck. The default is DefaultRetargetWindow.
+func (r *Retargeter) SetWindow(n int) error {
+	if n < 2 {
+		return fmt.Errorf("%w: window must be at least 2", ErrInvalidSetting)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.window = n
+	r.solves = r.solves[:0]
+	return nil
+}
+
+// SetSmoothing switches to the moving average controller, weighting each solve by alpha,
+// which must be between 0 and 1. Larger values react faster. A value of 0, the default,
+// switches back to windows.
+func (r *Retargeter) SetSmoothing(alpha float64) error {
+	if alpha < 0 || alpha > 1 {
+		return fmt.Errorf("%w: smoothing must be between 0 and 1", ErrInvalidSetting)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.alpha = alpha
+	r.solves = r.solves[:0]
+	r.rated = false
+	return nil
+}
+
+// SetBounds limits the difficulty the Retargeter moves to. The current difficulty is clamped
+// into the bounds at once.
+func (r *Retargeter) SetBounds(min, max int) error {
+	if min < 1 || max < min {
+		return fmt.Errorf("%w: bounds must be at least 1 and in order", ErrInvalidDifficulty)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.min, r.max = min, max
+	r.difficulty = r.clamp(r.difficulty)
+	return nil
+}
+
+// Difficulty returns the difficulty proofs should be solved at now
+func (r *Retargeter) Difficulty() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.difficulty
+}
+
+// Apply sets w's difficulty to the current one
+func (r *Retargeter) Apply(w *Worker) error {
+	return w.SetDifficulty(r.Difficulty())
+}
+
+// Observe records a proof solved at the current difficulty at time t and returns the
+// difficulty to use from now on. Solves that are not later than the previous one are ignored.
+func (r *Retargeter) Observe(t time.Time) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.last.IsZero() && !t.After(r.last) {
+		return r.difficulty
+	}
+
+	if r.alpha > 0 {
+		r.observeSmoothed(t)
+	} else {
+		r.observeWindow(t)
+	}
+
+	r.last = t
+	return r.difficulty
+}
+
+func (r *Retargeter) observeWindow(t time.Time) {
+	r.solves = append(r.solves, t)
+	if len(r.solves) <= r.window {
+		// a window of n solves needs n intervals, so the first solve of a window is the
+		// last of the previous one
+		return
+	}
+
+	r.difficulty = r.clamp(NextDifficulty(r.difficulty, r.solves, r.interval))
+	r.solves = append(r.solves[:0], t)
+}
+
+func (r *Retargeter) observeSmoothed(t time.Time) {
+	if r.last.IsZero() {
+		return
+	}
+
+	elapsed := t.Sub(r.last)
+	if elapsed < minSolveTime {
+		elapsed = minSolveTime
+	}
+
+	// log2 of the hash rate that solving 2^difficulty hashes in elapsed implies, so that the
+	// average stays meaningful as the difficulty changes
+	logRate := float64(r.difficulty) - math.Log2(elapsed.Seconds())
+	if r.rated {
+		r.logRate = r.alpha*logRate + (1-r.alpha)*r.logRate
+	} else {
+		r.logRate, r.rated = logRate, true
+	}
+
+	next := math.Round(r.logRate + math.Log2(r.interval.Seconds()))
+	if next > math.MaxInt32 {
+		next = math.MaxInt32
+	}
+	r.difficulty = r.clamp(int(next))
+}
+
+// clamp limits difficulty to the bounds
+func (r *Retargeter) clamp(difficulty int) int {
+	if difficulty < r.min {
+		return r.min
+	}
+	if difficulty > r.max {
+		return r.max
+	}
+	return difficulty
+}
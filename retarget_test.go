@@ -0,0 +1,143 @@
+package powork
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// solvesEvery returns n solve times spaced d apart
+func solvesEvery(n int, d time.Duration) []time.Time {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	solves := make([]time.Time, n)
+	for i := range solves {
+		solves[i] = start.Add(time.Duration(i) * d)
+	}
+	return solves
+}
+
+func TestNextDifficulty(t *testing.T) {
+	cases := []struct {
+		every    time.Duration
+		expected int
+	}{
+		{time.Second, 10},
+		{500 * time.Millisecond, 11},
+		{2 * time.Second, 9},
+		// clamped to a factor of four
+		{time.Millisecond, 12},
+		{time.Hour, 8},
+	}
+
+	for _, c := range cases {
+		if d := NextDifficulty(10, solvesEvery(8, c.every), time.Second); d != c.expected {
+			t.Fatalf("Solves every %v retargeted to %d, expected %d\n", c.every, d, c.expected)
+		}
+	}
+
+	if d := NextDifficulty(10, solvesEvery(1, time.Second), time.Millisecond); d != 10 {
+		t.Fatalf("A single solve changed the difficulty to %d\n", d)
+	}
+
+	if d := NextDifficulty(1, solvesEvery(8, time.Hour), time.Second); d != 1 {
+		t.Fatalf("Difficulty fell to %d\n", d)
+	}
+}
+
+func TestRetargeterWindow(t *testing.T) {
+	r, err := NewRetargeter(time.Second, 10)
+	if err != nil {
+		t.Fatalf("Error creating retargeter: %v\n", err)
+	}
+	if err := r.SetWindow(4); err != nil {
+		t.Fatalf("Error setting window: %v\n", err)
+	}
+
+	// solves twice as fast as wanted: unchanged until the window is full
+	solves := solvesEvery(9, 500*time.Millisecond)
+	for i, s := range solves[:4] {
+		if d := r.Observe(s); d != 10 {
+			t.Fatalf("Difficulty changed to %d after %d solves\n", d, i+1)
+		}
+	}
+	if d := r.Observe(solves[4]); d != 11 {
+		t.Fatalf("Difficulty is %d after a full window, expected 11\n", d)
+	}
+
+	for _, s := range solves[5:] {
+		r.Observe(s)
+	}
+	if d := r.Difficulty(); d != 12 {
+		t.Fatalf("Difficulty is %d after two windows, expected 12\n", d)
+	}
+
+	if d := r.Observe(solves[0]); d != 12 {
+		t.Fatalf("An earlier solve changed the difficulty to %d\n", d)
+	}
+
+	w := NewWorker()
+	if err := r.Apply(w); err != nil || w.GetDifficulty() != 12 {
+		t.Fatalf("Worker difficulty is %d after Apply: %v\n", w.GetDifficulty(), err)
+	}
+}
+
+func TestRetargeterSmoothing(t *testing.T) {
+	r, _ := NewRetargeter(time.Second, 10)
+	if err := r.SetSmoothing(0.5); err != nil {
+		t.Fatalf("Error setting smoothing: %v\n", err)
+	}
+
+	// a machine doing 2^12 hashes per second; each solve takes 2^d of them
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.Observe(now)
+	for i := 0; i < 20; i++ {
+		now = now.Add(time.Duration(float64(time.Second) * EstimateIterations(r.Difficulty()) / 4096))
+		r.Observe(now)
+	}
+
+	if d := r.Difficulty(); d != 12 {
+		t.Fatalf("Difficulty settled at %d, expected 12\n", d)
+	}
+}
+
+func TestRetargeterBounds(t *testing.T) {
+	r, _ := NewRetargeter(time.Second, 10)
+	if err := r.SetBounds(9, 11); err != nil {
+		t.Fatalf("Error setting bounds: %v\n", err)
+	}
+	r.SetWindow(2)
+
+	for _, s := range solvesEvery(9, time.Millisecond) {
+		r.Observe(s)
+	}
+	if d := r.Difficulty(); d != 11 {
+		t.Fatalf("Difficulty is %d, expected it to stop at 11\n", d)
+	}
+
+	if err := r.SetBounds(1, 4); err != nil || r.Difficulty() != 4 {
+		t.Fatalf("Difficulty is %d after lowering the bounds: %v\n", r.Difficulty(), err)
+	}
+}
+
+func TestRetargeterSettings(t *testing.T) {
+	if _, err := NewRetargeter(0, 10); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Zero interval did not fail with ErrInvalidSetting: %v\n", err)
+	}
+
+	if _, err := NewRetargeter(time.Second, 0); !errors.Is(err, ErrInvalidDifficulty) {
+		t.Fatalf("Zero difficulty did not fail with ErrInvalidDifficulty: %v\n", err)
+	}
+
+	r, _ := NewRetargeter(time.Second, 10)
+	if err := r.SetWindow(1); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Window of 1 did not fail with ErrInvalidSetting: %v\n", err)
+	}
+
+	if err := r.SetSmoothing(1.5); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Smoothing of 1.5 did not fail with ErrInvalidSetting: %v\n", err)
+	}
+
+	if err := r.SetBounds(5, 4); !errors.Is(err, ErrInvalidDifficulty) {
+		t.Fatalf("Bounds out of order did not fail with ErrInvalidDifficulty: %v\n", err)
+	}
+}
@@ -0,0 +1,52 @@
+package powork
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// scryptSalt is the fixed salt of scrypt proofs. The nonce already makes every input unique.
+var scryptSalt = []byte("powork.scrypt")
+
+// ScryptParams are the cost parameters of a scrypt Worker
+type ScryptParams struct {
+	// N is the CPU and memory cost, a power of two greater than 1
+	N int
+	// R is the block size
+	R int
+	// P is the parallelization
+	P int
+	// KeyLen is the length of the hash, in bytes
+	KeyLen int
+}
+
+// DefaultScryptParams use N=2^14, r=8 and p=1, about 16 MiB per hash, for a 32 byte hash
+var DefaultScryptParams = ScryptParams{N: 1 << 14, R: 8, P: 1, KeyLen: 32}
+
+// NewScryptWorker creates a Worker that hashes with the memory-hard scrypt instead of a
+// plain hash. The hash of a proof is scrypt(preimage, "powork.scrypt", N, r, p, keyLen), so
+// any scrypt implementation can verify it. Like NewArgon2idWorker, the Worker starts at a
+// difficulty of 4, and proofs are only valid for Workers with the same parameters.
+func NewScryptWorker(params ScryptParams) (*Worker, error) {
+	if params.N <= 1 || params.N&(params.N-1) != 0 {
+		return nil, fmt.Errorf("%w: scrypt N must be a power of two greater than 1", ErrInvalidSetting)
+	}
+
+	if params.KeyLen < 1 {
+		return nil, fmt.Errorf("%w: scrypt key length must be at least 1", ErrInvalidSetting)
+	}
+
+	// let scrypt check the remaining limits once rather than on every hash
+	if _, err := scrypt.Key(nil, scryptSalt, params.N, params.R, params.P, params.KeyLen); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSetting, err)
+	}
+
+	w := NewWorkerWithHashFunc(newKDFHashFunc(params.KeyLen, func(in []byte) []byte {
+		key, _ := scrypt.Key(in, scryptSalt, params.N, params.R, params.P, params.KeyLen)
+		return key
+	}))
+	w.difficulty = 4
+	w.algorithm = fmt.Sprintf("scrypt-n%d-r%d-p%d-l%d", params.N, params.R, params.P, params.KeyLen)
+	return w, nil
+}
@@ -0,0 +1,56 @@
+package powork
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// cheap parameters so that the tests run quickly
+var testScryptParams = ScryptParams{N: 16, R: 1, P: 1, KeyLen: 32}
+
+func TestScryptWorker(t *testing.T) {
+	worker, err := NewScryptWorker(testScryptParams)
+	if err != nil {
+		t.Fatalf("Could not create worker: %v\n", err)
+	}
+
+	pow, err := worker.DoProofForString("A memory-hard message")
+	if err != nil {
+		t.Fatalf("Could not compute proof: %v\n", err)
+	}
+
+	ok, err := worker.ValidatePoWork(pow)
+	if err != nil || !ok {
+		t.Fatalf("Could not validate proof: %v, %v\n", ok, err)
+	}
+
+	var preimage bytes.Buffer
+	pow.writePreimage(&preimage)
+	expected, _ := scrypt.Key(preimage.Bytes(), []byte("powork.scrypt"), 16, 1, 1, 32)
+	if sum, _ := pow.sum(worker.validationHasher()); !bytes.Equal(sum, expected) {
+		t.Fatalf("Hash %x is not scrypt %x\n", sum, expected)
+	}
+
+	params := testScryptParams
+	params.N = 32
+	other, _ := NewScryptWorker(params)
+	if ok, _ := other.ValidatePoWork(pow); ok {
+		t.Fatalf("Proof was valid with other parameters\n")
+	}
+}
+
+func TestScryptParams(t *testing.T) {
+	for _, params := range []ScryptParams{
+		{N: 1, R: 1, P: 1, KeyLen: 32},
+		{N: 24, R: 1, P: 1, KeyLen: 32},
+		{N: 16, R: 0, P: 1, KeyLen: 32},
+		{N: 16, R: 1, P: 1, KeyLen: 0},
+	} {
+		if _, err := NewScryptWorker(params); !errors.Is(err, ErrInvalidSetting) {
+			t.Fatalf("Expected ErrInvalidSetting for %+v, got %v\n", params, err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package sha256x
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// blockGeneric runs the SHA-256 compression function on one block
+func blockGeneric(dig *[8]uint32, p []byte) {
+	var w [64]uint32
+	for t := 0; t < 16; t++ {
+		w[t] = binary.BigEndian.Uint32(p[4*t:])
+	}
+	for t := 16; t < 64; t++ {
+		v1, v2 := w[t-2], w[t-15]
+		s1 := bits.RotateLeft32(v1, -17) ^ bits.RotateLeft32(v1, -19) ^ (v1 >> 10)
+		s0 := bits.RotateLeft32(v2, -7) ^ bits.RotateLeft32(v2, -18) ^ (v2 >> 3)
+		w[t] = s1 + w[t-7] + s0 + w[t-16]
+	}
+
+	compress(dig, &w)
+}
+
+// compress runs the rounds of the compression function over a message schedule
+func compress(dig *[8]uint32, w *[64]uint32) {
+	a, b, c, d, e, f, g, h := dig[0], dig[1], dig[2], dig[3], dig[4], dig[5], dig[6], dig[7]
+	for t := 0; t < 64; t++ {
+		t1 := h + (bits.RotateLeft32(e, -6) ^ bits.RotateLeft32(e, -11) ^ bits.RotateLeft32(e, -25)) +
+			((e & f) ^ (^e & g)) + k[t] + w[t]
+		t2 := (bits.RotateLeft32(a, -2) ^ bits.RotateLeft32(a, -13) ^ bits.RotateLeft32(a, -22)) +
+			((a & b) ^ (a & c) ^ (b & c))
+		h, g, f, e, d, c, b, a = g, f, e, d+t1, c, b, a, t1+t2
+	}
+
+	dig[0] += a
+	dig[1] += b
+	dig[2] += c
+	dig[3] += d
+	dig[4] += e
+	dig[5] += f
+	dig[6] += g
+	dig[7] += h
+}
+
+// block8Generic runs the compression function on one block for each lane. Only the first 16
+// words of w are read; the rest are overwritten with the message schedule.
+func block8Generic(dig *[8][Lanes]uint32, w *[64][Lanes]uint32) {
+	for t := 16; t < 64; t++ {
+		for lane := 0; lane < Lanes; lane++ {
+			v1, v2 := w[t-2][lane], w[t-15][lane]
+			s1 := bits.RotateLeft32(v1, -17) ^ bits.RotateLeft32(v1, -19) ^ (v1 >> 10)
+			s0 := bits.RotateLeft32(v2, -7) ^ bits.RotateLeft32(v2, -18) ^ (v2 >> 3)
+			w[t][lane] = s1 + w[t-7][lane] + s0 + w[t-16][lane]
+		}
+	}
+
+	for lane := 0; lane < Lanes; lane++ {
+		var d [8]uint32
+		var lw [64]uint32
+		for i := range d {
+			d[i] = dig[i][lane]
+		}
+		for t := range lw {
+			lw[t] = w[t][lane]
+		}
+
+		compress(&d, &lw)
+
+		for i := range d {
+			dig[i][lane] = d[i]
+		}
+	}
+}
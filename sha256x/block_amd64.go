@@ -0,0 +1,20 @@
+//go:build amd64 && !purego
+
+package sha256x
+
+import "golang.org/x/sys/cpu"
+
+var useAVX2 = cpu.X86.HasAVX2
+
+func block8(dig *[8][Lanes]uint32, w *[64][Lanes]uint32) {
+	if useAVX2 {
+		blockAVX2(dig, w, &k)
+		return
+	}
+	block8Generic(dig, w)
+}
+
+// blockAVX2 is block8Generic with a lane per 32-bit element of the vector registers
+//
+//go:noescape
+func blockAVX2(dig *[8][Lanes]uint32, w *[64][Lanes]uint32, k *[64]uint32)
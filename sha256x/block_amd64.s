@@ -0,0 +1,108 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// Each Y register holds one 32-bit word of the state or message schedule for all eight
+// lanes. Rotations are two shifts xored together; the parts of a rotation never overlap, so
+// xoring them into the sum of rotations is the same as oring them first.
+
+// ROUND runs one round of the compression function, leaving a new a in h and a new e in d,
+// so that the next round is ROUND(h, a, b, c, d, e, f, g). The round constant is at koff(AX)
+// and the message word at woff(BX).
+#define ROUND(a, b, c, d, e, f, g, h, koff, woff) \
+	VPSRLD $6, e, Y8; VPSLLD $26, e, Y9; VPXOR Y9, Y8, Y8; \
+	VPSRLD $11, e, Y9; VPXOR Y9, Y8, Y8; VPSLLD $21, e, Y9; VPXOR Y9, Y8, Y8; \
+	VPSRLD $25, e, Y9; VPXOR Y9, Y8, Y8; VPSLLD $7, e, Y9; VPXOR Y9, Y8, Y8; \
+	VPAND f, e, Y9; VPANDN g, e, Y10; VPXOR Y10, Y9, Y9; \
+	VPADDD Y9, Y8, Y8; VPADDD h, Y8, Y8; \
+	VPBROADCASTD koff(AX), Y9; VPADDD Y9, Y8, Y8; \
+	VPADDD woff(BX), Y8, Y8; \
+	VPADDD Y8, d, d; \
+	VPSRLD $2, a, Y9; VPSLLD $30, a, Y10; VPXOR Y10, Y9, Y9; \
+	VPSRLD $13, a, Y10; VPXOR Y10, Y9, Y9; VPSLLD $19, a, Y10; VPXOR Y10, Y9, Y9; \
+	VPSRLD $22, a, Y10; VPXOR Y10, Y9, Y9; VPSLLD $10, a, Y10; VPXOR Y10, Y9, Y9; \
+	VPOR b, a, Y10; VPAND c, Y10, Y10; VPAND b, a, Y11; VPOR Y11, Y10, Y10; \
+	VPADDD Y10, Y9, Y9; \
+	VPADDD Y9, Y8, h
+
+// func blockAVX2(dig *[8][Lanes]uint32, w *[64][Lanes]uint32, k *[64]uint32)
+TEXT ·blockAVX2(SB), NOSPLIT, $0-24
+	MOVQ dig+0(FP), DI
+	MOVQ w+8(FP), SI
+	MOVQ k+16(FP), DX
+
+	// expand the message schedule: BX points at w[t-16]
+	MOVQ SI, BX
+	MOVQ $48, CX
+
+schedule:
+	// Y1 = sigma0(w[t-15])
+	VMOVDQU 32(BX), Y0
+	VPSRLD $7, Y0, Y1; VPSLLD $25, Y0, Y2; VPXOR Y2, Y1, Y1
+	VPSRLD $18, Y0, Y2; VPXOR Y2, Y1, Y1; VPSLLD $14, Y0, Y2; VPXOR Y2, Y1, Y1
+	VPSRLD $3, Y0, Y2; VPXOR Y2, Y1, Y1
+
+	// Y3 = sigma1(w[t-2])
+	VMOVDQU 448(BX), Y0
+	VPSRLD $17, Y0, Y3; VPSLLD $15, Y0, Y2; VPXOR Y2, Y3, Y3
+	VPSRLD $19, Y0, Y2; VPXOR Y2, Y3, Y3; VPSLLD $13, Y0, Y2; VPXOR Y2, Y3, Y3
+	VPSRLD $10, Y0, Y2; VPXOR Y2, Y3, Y3
+
+	// w[t] = sigma1(w[t-2]) + w[t-7] + sigma0(w[t-15]) + w[t-16]
+	VPADDD Y3, Y1, Y1
+	VPADDD 288(BX), Y1, Y1
+	VPADDD 0(BX), Y1, Y1
+	VMOVDQU Y1, 512(BX)
+
+	ADDQ $32, BX
+	DECQ CX
+	JNZ schedule
+
+	VMOVDQU 0(DI), Y0
+	VMOVDQU 32(DI), Y1
+	VMOVDQU 64(DI), Y2
+	VMOVDQU 96(DI), Y3
+	VMOVDQU 128(DI), Y4
+	VMOVDQU 160(DI), Y5
+	VMOVDQU 192(DI), Y6
+	VMOVDQU 224(DI), Y7
+
+	// eight rounds per iteration bring the registers back to their places
+	MOVQ DX, AX
+	MOVQ SI, BX
+	MOVQ $8, CX
+
+rounds:
+	ROUND(Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7, 0, 0)
+	ROUND(Y7, Y0, Y1, Y2, Y3, Y4, Y5, Y6, 4, 32)
+	ROUND(Y6, Y7, Y0, Y1, Y2, Y3, Y4, Y5, 8, 64)
+	ROUND(Y5, Y6, Y7, Y0, Y1, Y2, Y3, Y4, 12, 96)
+	ROUND(Y4, Y5, Y6, Y7, Y0, Y1, Y2, Y3, 16, 128)
+	ROUND(Y3, Y4, Y5, Y6, Y7, Y0, Y1, Y2, 20, 160)
+	ROUND(Y2, Y3, Y4, Y5, Y6, Y7, Y0, Y1, 24, 192)
+	ROUND(Y1, Y2, Y3, Y4, Y5, Y6, Y7, Y0, 28, 224)
+
+	ADDQ $32, AX
+	ADDQ $256, BX
+	DECQ CX
+	JNZ rounds
+
+	VPADDD 0(DI), Y0, Y0
+	VMOVDQU Y0, 0(DI)
+	VPADDD 32(DI), Y1, Y1
+	VMOVDQU Y1, 32(DI)
+	VPADDD 64(DI), Y2, Y2
+	VMOVDQU Y2, 64(DI)
+	VPADDD 96(DI), Y3, Y3
+	VMOVDQU Y3, 96(DI)
+	VPADDD 128(DI), Y4, Y4
+	VMOVDQU Y4, 128(DI)
+	VPADDD 160(DI), Y5, Y5
+	VMOVDQU Y5, 160(DI)
+	VPADDD 192(DI), Y6, Y6
+	VMOVDQU Y6, 192(DI)
+	VPADDD 224(DI), Y7, Y7
+	VMOVDQU Y7, 224(DI)
+
+	VZEROUPPER
+	RET
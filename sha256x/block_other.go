@@ -0,0 +1,9 @@
+//go:build !amd64 || purego
+
+package sha256x
+
+const useAVX2 = false
+
+func block8(dig *[8][Lanes]uint32, w *[64][Lanes]uint32) {
+	block8Generic(dig, w)
+}
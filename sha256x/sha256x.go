@@ -0,0 +1,151 @@
+// Package sha256x implements a powork.MultiHash that computes eight SHA-256 hashes at once,
+// one per 32-bit lane of the AVX2 vector registers.
+//
+// Hashing candidates side by side keeps the vector units busy where hashing them one at a
+// time is bound by the latency of each round. It pays off most on CPUs with AVX2 but without
+// the SHA extensions, where crypto/sha256 falls back to its vector code for one message at a
+// time; with the extensions the two are about as fast. Machines without AVX2, and builds with
+// the purego tag, fall back to portable code that is slower than crypto/sha256; check
+// Available before opting in.
+package sha256x
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/Zumium/powork"
+)
+
+// Lanes is the number of hashes computed per call
+const Lanes = 8
+
+const blockSize = 64
+
+// initState holds the initial hash values of SHA-256
+var initState = [8]uint32{
+	0x6a09e667, 0xbb67ae85, 0x3c6ef372, 0xa54ff53a,
+	0x510e527f, 0x9b05688c, 0x1f83d9ab, 0x5be0cd19,
+}
+
+// k holds the round constants of SHA-256
+var k = [64]uint32{
+	0x428a2f98, 0x71374491, 0xb5c0fbcf, 0xe9b5dba5, 0x3956c25b, 0x59f111f1, 0x923f82a4, 0xab1c5ed5,
+	0xd807aa98, 0x12835b01, 0x243185be, 0x550c7dc3, 0x72be5d74, 0x80deb1fe, 0x9bdc06a7, 0xc19bf174,
+	0xe49b69c1, 0xefbe4786, 0x0fc19dc6, 0x240ca1cc, 0x2de92c6f, 0x4a7484aa, 0x5cb0a9dc, 0x76f988da,
+	0x983e5152, 0xa831c66d, 0xb00327c8, 0xbf597fc7, 0xc6e00bf3, 0xd5a79147, 0x06ca6351, 0x14292967,
+	0x27b70a85, 0x2e1b2138, 0x4d2c6dfc, 0x53380d13, 0x650a7354, 0x766a0abb, 0x81c2c92e, 0x92722c85,
+	0xa2bfe8a1, 0xa81a664b, 0xc24b8b70, 0xc76c51a3, 0xd192e819, 0xd6990624, 0xf40e3585, 0x106aa070,
+	0x19a4c116, 0x1e376c08, 0x2748774c, 0x34b0bcb5, 0x391c0cb3, 0x4ed8aa4a, 0x5b9cca4f, 0x682e6ff3,
+	0x748f82ee, 0x78a5636f, 0x84c87814, 0x8cc70208, 0x90befffa, 0xa4506ceb, 0xbef9a3f7, 0xc67178f2,
+}
+
+// Available reports whether the lanes are hashed with AVX2
+func Available() bool {
+	return useAVX2
+}
+
+// NewWorker creates a Worker with the defaults of powork.NewWorkerForAlgorithm("sha256") that
+// searches with New if Available. Its proofs are ordinary SHA-256 proofs.
+func NewWorker() *powork.Worker {
+	w, _ := powork.NewWorkerForAlgorithm("sha256")
+	if Available() {
+		w.SetMultiHash(New)
+	}
+	return w
+}
+
+// New returns a MultiHash computing SHA-256, for use with Worker.SetMultiHash
+func New() powork.MultiHash {
+	return new(hasher)
+}
+
+// hasher is the MultiHash returned by New. The state is kept transposed, word by lane, as the
+// vector code processes it.
+type hasher struct {
+	// prefix state after its whole blocks, and the bytes after them
+	mid    [8]uint32
+	tail   []byte
+	length uint64
+
+	// the final blocks of every lane, laid out for nonces of nonceLen bytes: the tail, the
+	// nonce and the padding, of which only the nonce changes from call to call
+	nonceLen int
+	n        int
+	blocks   [Lanes][3 * blockSize]byte
+
+	dig [8][Lanes]uint32
+	w   [64][Lanes]uint32
+}
+
+func (h *hasher) Lanes() int { return Lanes }
+
+func (h *hasher) Size() int { return sha256.Size }
+
+func (h *hasher) Reset(prefix []byte) {
+	h.mid = initState
+	n := len(prefix) / blockSize * blockSize
+	for i := 0; i < n; i += blockSize {
+		blockGeneric(&h.mid, prefix[i:i+blockSize])
+	}
+
+	h.tail = append(h.tail[:0], prefix[n:]...)
+	h.length = uint64(len(prefix))
+	h.nonceLen = -1
+}
+
+// layout prepares the final blocks for nonces of nonceLen bytes
+func (h *hasher) layout(nonceLen int) {
+	used := len(h.tail) + nonceLen
+	// the tail, the nonce and the padding take one to three blocks
+	h.n = (used + 1 + 8 + blockSize - 1) / blockSize
+	h.nonceLen = nonceLen
+
+	for lane := range h.blocks {
+		b := h.blocks[lane][:h.n*blockSize]
+		copy(b, h.tail)
+		b[used] = 0x80
+		clear(b[used+1 : len(b)-8])
+		binary.BigEndian.PutUint64(b[len(b)-8:], (h.length+uint64(nonceLen))*8)
+	}
+}
+
+func (h *hasher) Sum(nonces [][]byte, sums [][]byte) {
+	if len(nonces[0]) != h.nonceLen {
+		h.layout(len(nonces[0]))
+	}
+	for lane := range h.blocks {
+		copy(h.blocks[lane][len(h.tail):], nonces[lane])
+	}
+
+	for word := range h.dig {
+		for lane := range h.dig[word] {
+			h.dig[word][lane] = h.mid[word]
+		}
+	}
+
+	// only the words holding part of the nonce differ between lanes
+	lo, hi := len(h.tail)/4, (len(h.tail)+h.nonceLen+3)/4
+	for i := 0; i < h.n; i++ {
+		for t := 0; t < 16; t++ {
+			at := 4 * (i*16 + t)
+			if at/4 < lo || at/4 >= hi {
+				v := binary.BigEndian.Uint32(h.blocks[0][at:])
+				for lane := range h.w[t] {
+					h.w[t][lane] = v
+				}
+				continue
+			}
+
+			for lane := range h.w[t] {
+				h.w[t][lane] = binary.BigEndian.Uint32(h.blocks[lane][at:])
+			}
+		}
+		block8(&h.dig, &h.w)
+	}
+
+	for lane := range sums {
+		for word := range h.dig {
+			binary.BigEndian.PutUint32(sums[lane][4*word:], h.dig[word][lane])
+		}
+	}
+}
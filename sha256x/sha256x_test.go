@@ -0,0 +1,122 @@
+package sha256x
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/Zumium/powork"
+)
+
+func TestSum(t *testing.T) {
+	h := New()
+	sums := make([][]byte, Lanes)
+	for i := range sums {
+		sums[i] = make([]byte, sha256.Size)
+	}
+
+	// tails and nonces that take one, two and three blocks
+	for _, prefixLen := range []int{0, 1, 47, 55, 56, 63, 64, 65, 130, 1000} {
+		for _, nonceLen := range []int{1, 8, 9, 32, 64} {
+			prefix := bytes.Repeat([]byte{'p'}, prefixLen)
+			h.Reset(prefix)
+
+			nonces := make([][]byte, Lanes)
+			for i := range nonces {
+				nonces[i] = bytes.Repeat([]byte{byte(i)}, nonceLen)
+			}
+			h.Sum(nonces, sums)
+
+			for i := range nonces {
+				expected := sha256.Sum256(append(prefix, nonces[i]...))
+				if !bytes.Equal(sums[i], expected[:]) {
+					t.Fatalf("Lane %d hashed a %d byte prefix and a %d byte nonce to %x, expected %x\n",
+						i, prefixLen, nonceLen, sums[i], expected)
+				}
+			}
+		}
+	}
+}
+
+func TestBlockGeneric(t *testing.T) {
+	var dig, expected [8][Lanes]uint32
+	var w, generic [64][Lanes]uint32
+	for t := 0; t < 16; t++ {
+		for lane := 0; lane < Lanes; lane++ {
+			w[t][lane] = uint32(t*Lanes+lane) * 0x9e3779b9
+		}
+	}
+	for word := range dig {
+		for lane := range dig[word] {
+			dig[word][lane] = initState[word] + uint32(lane)
+		}
+	}
+	expected, generic = dig, w
+
+	block8(&dig, &w)
+	block8Generic(&expected, &generic)
+	if dig != expected {
+		t.Fatalf("block8 and block8Generic disagree\n")
+	}
+}
+
+func TestWorker(t *testing.T) {
+	fast := NewWorker()
+	plain, _ := powork.NewWorkerForAlgorithm("sha256")
+	for _, w := range []*powork.Worker{fast, plain} {
+		w.SetDifficulty(16)
+		w.SetConcurrency(2)
+	}
+	fast.SetMultiHash(New)
+
+	for i := 0; i < 4; i++ {
+		msg := []byte(fmt.Sprintf("message %d", i))
+		a, err := fast.DoProofFor(msg)
+		if err != nil {
+			t.Fatalf("Error doing proof: %v\n", err)
+		}
+		if ok, err := plain.ValidatePoWork(a); !ok || err != nil {
+			t.Fatalf("Proof found with sha256x did not validate: %v\n", err)
+		}
+
+		// a single goroutine tries the nonces in the same order either way
+		fast.SetConcurrency(1)
+		plain.SetConcurrency(1)
+		a, _ = fast.DoProofFor(msg)
+		b, _ := plain.DoProofFor(msg)
+		if a.GetProof() != b.GetProof() || a.GetIterations() != b.GetIterations() {
+			t.Fatalf("Found nonce %d after %d iterations, expected %d after %d\n",
+				a.GetProof(), a.GetIterations(), b.GetProof(), b.GetIterations())
+		}
+		fast.SetConcurrency(2)
+		plain.SetConcurrency(2)
+	}
+}
+
+func BenchmarkSum(b *testing.B) {
+	h := New()
+	h.Reset([]byte("a message to prove"))
+	nonces := make([][]byte, Lanes)
+	sums := make([][]byte, Lanes)
+	for i := range nonces {
+		nonces[i] = make([]byte, 8)
+		sums[i] = make([]byte, sha256.Size)
+	}
+
+	b.SetBytes(Lanes)
+	for i := 0; i < b.N; i++ {
+		h.Sum(nonces, sums)
+	}
+}
+
+func BenchmarkSHA256(b *testing.B) {
+	msg := []byte("a message to prove12345678")
+
+	b.SetBytes(Lanes)
+	for i := 0; i < b.N; i++ {
+		for lane := 0; lane < Lanes; lane++ {
+			sha256.Sum256(msg)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package powork
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ValidateShare checks that pow is a share: a proof whose hash has at least shareDifficulty
+// leading zero bits, below the difficulty it declares. In pooled and incentivized proving,
+// contributors submit shares as evidence of the work they do towards a harder target; each
+// share stands for about EstimateIterations(shareDifficulty) hashes. A share that also meets
+// its declared difficulty is a full proof, which ValidateWithMinimum accepts.
+func (p *Worker) ValidateShare(pow *PoWork, shareDifficulty int) (bool, error) {
+	start := time.Now()
+	ok, err := p.validateShare(pow, shareDifficulty)
+	p.validated(context.Background(), "ValidateShare", start, pow, ok, false, err)
+	return ok, err
+}
+
+// validateShare does the work of ValidateShare without calling hooks
+func (p *Worker) validateShare(pow *PoWork, shareDifficulty int) (bool, error) {
+	if shareDifficulty < 1 || shareDifficulty > p.MaxDifficulty() {
+		return false, fmt.Errorf("%w: share difficulty must be between 1 and %d", ErrInvalidDifficulty, p.MaxDifficulty())
+	}
+
+	if !p.acceptsAlgorithm(pow) || !p.acceptsAge(pow, time.Now()) {
+		return false, nil
+	}
+
+	return p.validateAt(p.hasherFor(pow), pow, shareDifficulty)
+}
+
+// A ShareLedger validates the shares contributors submit and keeps account of the work
+// each has done, for example to split a reward between the members of a pool in proportion
+// to their work. Each share is credited once. Its methods are safe for concurrent use.
+type ShareLedger struct {
+	worker *Worker
+
+	mu         sync.Mutex
+	difficulty int
+	work       map[string]float64
+	shares     map[string]uint64
+	seen       map[string]struct{}
+}
+
+// NewShareLedger creates a ShareLedger validating shares with w at the given share difficulty
+func NewShareLedger(w *Worker, shareDifficulty int) (*ShareLedger, error) {
+	l := &ShareLedger{worker: w}
+	if err := l.SetShareDifficulty(shareDifficulty); err != nil {
+		return nil, err
+	}
+
+	l.Reset()
+	return l, nil
+}
+
+// SetShareDifficulty changes the difficulty of the shares the ledger accepts from now on.
+// Shares already credited keep the work they were credited at.
+func (l *ShareLedger) SetShareDifficulty(difficulty int) error {
+	if difficulty < 1 || difficulty > l.worker.MaxDifficulty() {
+		return fmt.Errorf("%w: share difficulty must be between 1 and %d", ErrInvalidDifficulty, l.worker.MaxDifficulty())
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.difficulty = difficulty
+	return nil
+}
+
+// GetShareDifficulty gets the difficulty of the shares the ledger accepts
+func (l *ShareLedger) GetShareDifficulty() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.difficulty
+}
+
+// Submit validates a share from contributor and credits it with the expected work of a share.
+// It reports false for shares that are not valid or were already credited.
+func (l *ShareLedger) Submit(contributor string, pow *PoWork) (bool, error) {
+	difficulty := l.GetShareDifficulty()
+	if ok, err := l.worker.ValidateShare(pow, difficulty); err != nil || !ok {
+		return false, err
+	}
+
+	key := SpentKey(pow)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.seen[key]; ok {
+		return false, nil
+	}
+	l.seen[key] = struct{}{}
+	l.work[contributor] += EstimateIterations(difficulty)
+	l.shares[contributor]++
+	return true, nil
+}
+
+// Shares returns the number of shares credited to contributor
+func (l *ShareLedger) Shares(contributor string) uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.shares[contributor]
+}
+
+// Work returns the expected number of hashes contributor computed to find its shares
+func (l *ShareLedger) Work(contributor string) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.work[contributor]
+}
+
+// TotalWork returns the expected number of hashes computed by every contributor
+func (l *ShareLedger) TotalWork() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	total := 0.0
+	for _, w := range l.work {
+		total += w
+	}
+	return total
+}
+
+// Contributors returns the contributors credited with shares, sorted
+func (l *ShareLedger) Contributors() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	names := make([]string, 0, len(l.work))
+	for name := range l.work {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Portions returns each contributor's fraction of the total work. The fractions add up to 1,
+// or the map is empty if no share has been credited.
+func (l *ShareLedger) Portions() map[string]float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	total := 0.0
+	for _, w := range l.work {
+		total += w
+	}
+
+	portions := make(map[string]float64, len(l.work))
+	for name, w := range l.work {
+		portions[name] = w / total
+	}
+	return portions
+}
+
+// Reset clears the ledger, for example at the start of a new round. Shares credited before
+// the reset may be credited again after it.
+func (l *ShareLedger) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.work = make(map[string]float64)
+	l.shares = make(map[string]uint64)
+	l.seen = make(map[string]struct{})
+}
@@ -0,0 +1,90 @@
+package powork
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// findShare finds a share at shareDifficulty for a proof declaring difficulty
+func findShare(t *testing.T, msg string, difficulty, shareDifficulty int) *PoWork {
+	worker := NewWorker()
+	worker.SetDifficulty(difficulty)
+	worker.SetPredicate(LeadingZeros(shareDifficulty))
+	pow, err := worker.DoProofForString(msg)
+	if err != nil {
+		t.Fatalf("Error finding share: %v\n", err)
+	}
+	return pow
+}
+
+func TestValidateShare(t *testing.T) {
+	worker := NewWorker()
+	share := findShare(t, "pooled work", 40, 8)
+
+	if ok, err := worker.ValidateShare(share, 8); !ok || err != nil {
+		t.Fatalf("Share did not validate: %v\n", err)
+	}
+	if ok, _ := worker.ValidateWithMinimum(share, 40); ok {
+		t.Fatalf("Share validated as a full proof\n")
+	}
+
+	bits := worker.MeasureDifficulty(share)
+	if ok, _ := worker.ValidateShare(share, bits+1); ok {
+		t.Fatalf("Share with %d bits validated at %d\n", bits, bits+1)
+	}
+	if _, err := worker.ValidateShare(share, 0); !errors.Is(err, ErrInvalidDifficulty) {
+		t.Fatalf("Share difficulty 0 did not fail with ErrInvalidDifficulty: %v\n", err)
+	}
+}
+
+func TestShareLedger(t *testing.T) {
+	ledger, err := NewShareLedger(NewWorker(), 6)
+	if err != nil {
+		t.Fatalf("Error creating ledger: %v\n", err)
+	}
+
+	for i, msg := range []string{"a1", "a2", "a3", "b1"} {
+		name := msg[:1]
+		if ok, err := ledger.Submit(name, findShare(t, msg, 30, 6)); !ok || err != nil {
+			t.Fatalf("Share %d was not credited: %v\n", i, err)
+		}
+	}
+
+	// the same share again, and a share below the ledger's difficulty
+	if ok, _ := ledger.Submit("b", findShare(t, "b1", 30, 6)); ok {
+		t.Fatalf("Repeated share was credited\n")
+	}
+	for i := 0; ; i++ {
+		share := findShare(t, string(rune('c'+i)), 30, 1)
+		if NewWorker().MeasureDifficulty(share) < 6 {
+			if ok, _ := ledger.Submit("c", share); ok {
+				t.Fatalf("Share below the ledger's difficulty was credited\n")
+			}
+			break
+		}
+	}
+
+	if ledger.Shares("a") != 3 || ledger.Work("b") != 64 || ledger.TotalWork() != 256 {
+		t.Fatalf("Ledger credited %d shares to a, %v hashes to b, %v in total\n", ledger.Shares("a"), ledger.Work("b"), ledger.TotalWork())
+	}
+
+	// a harder share counts for more
+	ledger.SetShareDifficulty(8)
+	ledger.Submit("b", findShare(t, "b2", 30, 8))
+	portions := ledger.Portions()
+	if math.Abs(portions["a"]-0.375) > 1e-9 || math.Abs(portions["b"]-0.625) > 1e-9 {
+		t.Fatalf("Portions are %v\n", portions)
+	}
+	if names := ledger.Contributors(); len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Fatalf("Contributors are %v\n", names)
+	}
+
+	ledger.Reset()
+	if ledger.TotalWork() != 0 || len(ledger.Portions()) != 0 {
+		t.Fatalf("Reset ledger has work\n")
+	}
+	if ok, _ := ledger.Submit("b", findShare(t, "b2", 30, 8)); !ok {
+		t.Fatalf("Share was not credited again after a reset\n")
+	}
+}
@@ -0,0 +1,136 @@
+package powork
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+)
+
+// ExtensionSigner is the extension carrying the public key a signed proof is bound to, base64
+// encoded with the URL alphabet and no padding
+const ExtensionSigner = "signer"
+
+// signedVersion is the version byte of encoded signed proofs
+const signedVersion = 1
+
+// signedContext is prepended to the encoded proof to form the message that is signed, so
+// that the signature can't be taken for one made for another purpose
+const signedContext = "powork signed proof v1\x00"
+
+// A SignedProof is a proof of work signed with the Ed25519 key of its prover, so that the
+// work can be attributed to an identity, such as in a reputation system. The proof is bound
+// to the public key before the search, so a proof can't be stripped of its signature and
+// signed again by someone who did not do the work.
+type SignedProof struct {
+	proof     *PoWork
+	data      []byte // the encoded proof, as signed
+	signature []byte
+}
+
+// DoSignedProofFor calculates a proof of work for msg bound to the public key of key, and
+// signs it with key
+func (p *Worker) DoSignedProofFor(msg []byte, key ed25519.PrivateKey) (*SignedProof, error) {
+	return p.DoSignedProofForContext(context.Background(), msg, key)
+}
+
+// DoSignedProofForContext does the same thing as DoSignedProofFor except carrying a context
+func (p *Worker) DoSignedProofForContext(ctx context.Context, msg []byte, key ed25519.PrivateKey) (*SignedProof, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("%w: signing key must be %d bytes", ErrInvalidSetting, ed25519.PrivateKeySize)
+	}
+
+	public := key.Public().(ed25519.PublicKey)
+	pow, err := p.DoProofForExtensionsContext(ctx, msg, map[string]string{
+		ExtensionSigner: base64.RawURLEncoding.EncodeToString(public),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := pow.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	return &SignedProof{
+		proof:     pow,
+		data:      data,
+		signature: ed25519.Sign(key, append([]byte(signedContext), data...)),
+	}, nil
+}
+
+// GetProof gets the signed proof of work
+func (s *SignedProof) GetProof() *PoWork {
+	return s.proof
+}
+
+// GetSigner gets the public key the proof is bound to, or nil if the proof has none
+func (s *SignedProof) GetSigner() ed25519.PublicKey {
+	v, ok := s.proof.extensions[ExtensionSigner]
+	if !ok {
+		return nil
+	}
+
+	key, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil
+	}
+	return key
+}
+
+// GetSignature gets the Ed25519 signature of the proof
+func (s *SignedProof) GetSignature() []byte {
+	return s.signature
+}
+
+// VerifySignature reports whether the proof is signed by the key it is bound to. It does not
+// check the work; ValidateSigned checks both.
+func (s *SignedProof) VerifySignature() bool {
+	signer := s.GetSigner()
+	return signer != nil && ed25519.Verify(signer, append([]byte(signedContext), s.data...), s.signature)
+}
+
+// ValidateSigned checks a signed proof: its signature as by VerifySignature, and its work as
+// by ValidatePoWork. The signer, who did the work, is s.GetSigner().
+func (p *Worker) ValidateSigned(s *SignedProof) (bool, error) {
+	if !s.VerifySignature() {
+		return false, nil
+	}
+
+	return p.ValidatePoWork(s.proof)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. The encoding is:
+//
+//	version   uint8
+//	signature [64]byte
+//	proof     the proof as encoded by PoWork.MarshalBinary
+func (s *SignedProof) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 0, 1+ed25519.SignatureSize+len(s.data))
+	data = append(data, signedVersion)
+	data = append(data, s.signature...)
+	return append(data, s.data...), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, decoding data produced by MarshalBinary
+func (s *SignedProof) UnmarshalBinary(data []byte) error {
+	if len(data) < 1+ed25519.SignatureSize {
+		return fmt.Errorf("%w: encoded signed proof is too short", ErrInvalidEncoding)
+	}
+
+	if data[0] != signedVersion {
+		return fmt.Errorf("%w: unsupported encoded signed proof version", ErrInvalidEncoding)
+	}
+
+	pow := new(PoWork)
+	encoded := data[1+ed25519.SignatureSize:]
+	if err := pow.UnmarshalBinary(encoded); err != nil {
+		return err
+	}
+
+	s.proof = pow
+	s.data = append([]byte(nil), encoded...)
+	s.signature = append([]byte(nil), data[1:1+ed25519.SignatureSize]...)
+	return nil
+}
@@ -0,0 +1,77 @@
+package powork
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+)
+
+func TestSignedProof(t *testing.T) {
+	public, private, _ := ed25519.GenerateKey(nil)
+	worker := NewWorker()
+	worker.SetDifficulty(8)
+
+	signed, err := worker.DoSignedProofFor([]byte("work for my reputation"), private)
+	if err != nil {
+		t.Fatalf("Error computing signed proof: %v\n", err)
+	}
+	if !bytes.Equal(signed.GetSigner(), public) {
+		t.Fatalf("Proof is bound to another key\n")
+	}
+
+	data, _ := signed.MarshalBinary()
+	decoded := new(SignedProof)
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Error decoding signed proof: %v\n", err)
+	}
+	if ok, err := worker.ValidateSigned(decoded); !ok || err != nil {
+		t.Fatalf("Decoded signed proof did not validate: %v\n", err)
+	}
+	if decoded.GetProof().GetMessageString() != "work for my reputation" || !bytes.Equal(decoded.GetSigner(), public) {
+		t.Fatalf("Decoded signed proof differs from the original\n")
+	}
+
+	// a flipped signature bit, and work below the difficulty
+	data[1] ^= 1
+	decoded.UnmarshalBinary(data)
+	if ok, _ := worker.ValidateSigned(decoded); ok {
+		t.Fatalf("Proof with a bad signature validated\n")
+	}
+	worker.SetDifficulty(40)
+	if ok, _ := worker.ValidateSigned(signed); ok {
+		t.Fatalf("Signed proof validated above its difficulty\n")
+	}
+
+	if err := decoded.UnmarshalBinary(data[:10]); !errors.Is(err, ErrInvalidEncoding) {
+		t.Fatalf("Truncated signed proof did not fail with ErrInvalidEncoding: %v\n", err)
+	}
+}
+
+func TestSignedProofResigned(t *testing.T) {
+	_, prover, _ := ed25519.GenerateKey(nil)
+	_, thief, _ := ed25519.GenerateKey(nil)
+	worker := NewWorker()
+	worker.SetDifficulty(8)
+
+	signed, _ := worker.DoSignedProofFor([]byte("someone's work"), prover)
+
+	// signing someone else's work does not make it the signer's: the proof names its prover
+	stolen := &SignedProof{proof: signed.proof, data: signed.data}
+	stolen.signature = ed25519.Sign(thief, append([]byte(signedContext), signed.data...))
+	if ok, _ := worker.ValidateSigned(stolen); ok {
+		t.Fatalf("Proof signed by another key validated\n")
+	}
+
+	// nor does a proof without a signer
+	plain, _ := worker.DoProofForString("unsigned work")
+	data, _ := plain.MarshalBinary()
+	unsigned := &SignedProof{proof: plain, data: data, signature: ed25519.Sign(thief, append([]byte(signedContext), data...))}
+	if unsigned.GetSigner() != nil || unsigned.VerifySignature() {
+		t.Fatalf("Proof without a signer has a valid signature\n")
+	}
+
+	if _, err := worker.DoSignedProofFor([]byte("x"), prover[:10]); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Short key did not fail with ErrInvalidSetting: %v\n", err)
+	}
+}
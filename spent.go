@@ -0,0 +1,209 @@
+package powork
+
+import (
+	"container/list"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// A SpentStore records proofs that have already been accepted, so that they can't be
+// replayed. Implementations must be safe for concurrent use.
+type SpentStore interface {
+	// Seen reports whether pow has been recorded and its record has not expired
+	Seen(pow *PoWork) (bool, error)
+
+	// Add records pow for ttl. It reports whether pow was already recorded, checking and
+	// recording atomically so that concurrent callers can't both add the same proof.
+	Add(pow *PoWork, ttl time.Duration) (bool, error)
+}
+
+// SpentKey returns the key under which stores record pow. It is a digest of everything the
+// proof's work covers, so two proofs share a key exactly when they represent the same work.
+func SpentKey(pow *PoWork) string {
+	return hex.EncodeToString(pow.Hash())
+}
+
+// SetSpentStore sets the store ValidateOnce records accepted proofs in, and how long they stay
+// recorded. Timestamped proofs validated by a Worker with a maximum proof age are instead
+// recorded for exactly as long as they could still be accepted.
+func (p *Worker) SetSpentStore(s SpentStore, ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("%w: spent proof TTL must be greater than 0", ErrInvalidSetting)
+	}
+
+	p.spent = s
+	p.spentTTL = ttl
+	return nil
+}
+
+// GetSpentStore gets the store set with SetSpentStore, or nil if the Worker has none
+func (p *Worker) GetSpentStore() SpentStore {
+	return p.spent
+}
+
+// ValidateOnce does the same thing as ValidatePoWork, but also records valid proofs in the
+// Worker's SpentStore and rejects proofs that are already recorded there.
+func (p *Worker) ValidateOnce(pow *PoWork) (bool, error) {
+	start := time.Now()
+	ok, replayed, err := p.validateOnce(pow)
+	p.validated(context.Background(), "ValidateOnce", start, pow, ok, replayed, err)
+	return ok, err
+}
+
+// validateOnce does the work of ValidateOnce, also reporting whether the proof was rejected
+// because it was already spent
+func (p *Worker) validateOnce(pow *PoWork) (bool, bool, error) {
+	if p.spent == nil {
+		return false, false, ErrNoSpentStore
+	}
+
+	seen, err := p.spent.Seen(pow)
+	if err != nil || seen {
+		return false, seen, err
+	}
+
+	ok, err := p.validatePoWork(pow)
+	if err != nil || !ok {
+		return false, false, err
+	}
+
+	// only real work makes it into the store
+	seen, err = p.spent.Add(pow, p.spentTTLFor(pow, time.Now()))
+	if err != nil {
+		return false, false, err
+	}
+
+	return !seen, seen, nil
+}
+
+// spentTTLFor returns how long pow has to stay recorded as spent
+func (p *Worker) spentTTLFor(pow *PoWork, now time.Time) time.Duration {
+	if p.maxProofAge == 0 || pow.timestamp.IsZero() {
+		return p.spentTTL
+	}
+
+	ttl := pow.timestamp.Add(p.maxProofAge + p.clockSkew).Sub(now)
+	if ttl < time.Millisecond {
+		ttl = time.Millisecond
+	}
+	return ttl
+}
+
+// MemorySpentStore is an in-memory LRU SpentStore. When full it forgets the least recently
+// used proof, so its capacity should comfortably exceed the number of proofs accepted per TTL.
+type MemorySpentStore struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[string]*list.Element
+	// evicted, if set, is called with each entry dropped to make room
+	evicted func(*spentEntry)
+}
+
+type spentEntry struct {
+	key     string
+	expires time.Time
+}
+
+// NewMemorySpentStore creates a MemorySpentStore holding at most capacity proofs
+func NewMemorySpentStore(capacity int) *MemorySpentStore {
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	return &MemorySpentStore{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Seen implements SpentStore
+func (s *MemorySpentStore) Seen(pow *PoWork) (bool, error) {
+	key := SpentKey(pow)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.live(key, time.Now()), nil
+}
+
+// Add implements SpentStore
+func (s *MemorySpentStore) Add(pow *PoWork, ttl time.Duration) (bool, error) {
+	key := SpentKey(pow)
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.live(key, now) {
+		return true, nil
+	}
+
+	s.record(key, now.Add(ttl))
+	return false, nil
+}
+
+// Len returns the number of proofs the store currently holds, including expired ones not yet dropped
+func (s *MemorySpentStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+// Collect implements Collector, dropping the proofs whose records have expired
+func (s *MemorySpentStore) Collect(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.collect(time.Now()), nil
+}
+
+// collect drops the entries expired at now and returns how many it dropped. The caller must
+// hold s.mu.
+func (s *MemorySpentStore) collect(now time.Time) int64 {
+	var n int64
+	for key, e := range s.entries {
+		if now.After(e.Value.(*spentEntry).expires) {
+			s.order.Remove(e)
+			delete(s.entries, key)
+			n++
+		}
+	}
+	return n
+}
+
+// record adds key until expires, evicting the least recently used keys to make room. The
+// caller must hold s.mu.
+func (s *MemorySpentStore) record(key string, expires time.Time) {
+	for s.order.Len() >= s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		e := oldest.Value.(*spentEntry)
+		delete(s.entries, e.key)
+		if s.evicted != nil {
+			s.evicted(e)
+		}
+	}
+
+	s.entries[key] = s.order.PushFront(&spentEntry{key: key, expires: expires})
+}
+
+// live reports whether key is recorded and unexpired at now, dropping it if it has expired
+// and marking it used otherwise. The caller must hold s.mu.
+func (s *MemorySpentStore) live(key string, now time.Time) bool {
+	e, ok := s.entries[key]
+	if !ok {
+		return false
+	}
+
+	if now.After(e.Value.(*spentEntry).expires) {
+		s.order.Remove(e)
+		delete(s.entries, key)
+		return false
+	}
+
+	s.order.MoveToFront(e)
+	return true
+}
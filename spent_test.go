@@ -0,0 +1,113 @@
+package powork
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestValidateOnce(t *testing.T) {
+	worker := NewWorker()
+	worker.SetSpentStore(NewMemorySpentStore(100), time.Minute)
+
+	proof, _ := worker.DoProofForString("A message that must only be accepted once")
+
+	ok, err := worker.ValidateOnce(proof)
+	if err != nil || !ok {
+		t.Fatalf("Fresh proof did not validate: %v\n", err)
+	}
+
+	ok, err = worker.ValidateOnce(proof)
+	if err != nil || ok {
+		t.Fatalf("Replayed proof was accepted\n")
+	}
+
+	// re-declaring the difficulty doesn't make it a different proof
+	proof.difficulty++
+	ok, _ = worker.ValidateOnce(proof)
+	if ok {
+		t.Fatalf("Replayed proof with a different declared difficulty was accepted\n")
+	}
+}
+
+func TestValidateOnceConcurrent(t *testing.T) {
+	worker := NewWorker()
+	worker.SetSpentStore(NewMemorySpentStore(100), time.Minute)
+	proof, _ := worker.DoProofForString("A message that must only be accepted once")
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := worker.ValidateOnce(proof); ok {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if accepted != 1 {
+		t.Fatalf("Proof was accepted %v times\n", accepted)
+	}
+}
+
+func TestValidateOnceWithoutStore(t *testing.T) {
+	worker := NewWorker()
+	proof, _ := worker.DoProofForString("A message")
+	if _, err := worker.ValidateOnce(proof); err == nil {
+		t.Fatalf("ValidateOnce without a store did not fail\n")
+	}
+}
+
+func TestMemorySpentStoreExpiryAndEviction(t *testing.T) {
+	store := NewMemorySpentStore(2)
+	a := &PoWork{msg: []byte("a")}
+	b := &PoWork{msg: []byte("b")}
+	c := &PoWork{msg: []byte("c")}
+
+	store.Add(a, 50*time.Millisecond)
+	if seen, _ := store.Seen(a); !seen {
+		t.Fatalf("Added proof was not seen\n")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if seen, _ := store.Seen(a); seen {
+		t.Fatalf("Expired proof was still seen\n")
+	}
+
+	store.Add(a, time.Minute)
+	store.Add(b, time.Minute)
+	store.Add(c, time.Minute)
+	if store.Len() != 2 {
+		t.Fatalf("Store holds %v proofs, capacity is 2\n", store.Len())
+	}
+
+	if seen, _ := store.Seen(a); seen {
+		t.Fatalf("Least recently used proof was not evicted\n")
+	}
+
+	if dup, _ := store.Add(c, time.Minute); !dup {
+		t.Fatalf("Adding a recorded proof was not reported\n")
+	}
+}
+
+func TestMemorySpentStoreCollect(t *testing.T) {
+	store := NewMemorySpentStore(10)
+	store.Add(&PoWork{msg: []byte("a")}, time.Millisecond)
+	store.Add(&PoWork{msg: []byte("b")}, time.Minute)
+
+	time.Sleep(10 * time.Millisecond)
+	if n, err := store.Collect(context.Background()); n != 1 || err != nil {
+		t.Fatalf("Collected %d proofs, expected 1: %v\n", n, err)
+	}
+
+	if store.Len() != 1 {
+		t.Fatalf("Store holds %v proofs after collection, expected 1\n", store.Len())
+	}
+}
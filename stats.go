@@ -0,0 +1,59 @@
+package powork
+
+import "time"
+
+// Stats is a snapshot of the work a Worker has done
+type Stats struct {
+	// Hashes is the number of hashes computed while searching for proofs
+	Hashes uint64
+	// Proofs is the number of proofs found
+	Proofs uint64
+	// Failures is the number of searches that ended without a proof
+	Failures uint64
+	// AverageIterations is the average number of hashes it took to find a proof
+	AverageIterations float64
+	// HashRate is the number of hashes per second in the most recent search
+	HashRate float64
+}
+
+// Stats returns a snapshot of the Worker's statistics
+func (p *Worker) Stats() Stats {
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+	return p.stats
+}
+
+// ResetStats clears the Worker's statistics
+func (p *Worker) ResetStats() {
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+	p.stats = Stats{}
+	p.proofHashes = 0
+}
+
+// recordSearch adds a finished search to the Worker's statistics. iterations is the number of
+// failed attempts, so a successful search computed one more hash than that.
+func (p *Worker) recordSearch(st *searchState, iterations int, found bool) {
+	hashes := uint64(iterations)
+	if found {
+		hashes++
+	}
+	elapsed := time.Since(st.start)
+	countSearch(hashes, found)
+
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+
+	p.stats.Hashes += hashes
+	if found {
+		p.stats.Proofs++
+		p.proofHashes += hashes
+		p.stats.AverageIterations = float64(p.proofHashes) / float64(p.stats.Proofs)
+	} else {
+		p.stats.Failures++
+	}
+
+	if elapsed > 0 {
+		p.stats.HashRate = float64(hashes) / elapsed.Seconds()
+	}
+}
@@ -0,0 +1,57 @@
+package powork
+
+import "testing"
+
+func TestStats(t *testing.T) {
+	worker := NewWorker()
+
+	var total uint64
+	for _, s := range []string{"one", "two", "three"} {
+		proof, err := worker.DoProofForString(s)
+		if err != nil {
+			t.Fatalf("Could not calculate proof: %v\n", err)
+		}
+		total += uint64(proof.requiredIterations) + 1
+	}
+
+	stats := worker.Stats()
+	if stats.Proofs != 3 || stats.Failures != 0 {
+		t.Fatalf("Expected 3 proofs and no failures, got %+v\n", stats)
+	}
+
+	if stats.Hashes != total {
+		t.Fatalf("Counted %v hashes, expected %v\n", stats.Hashes, total)
+	}
+
+	if stats.AverageIterations != float64(total)/3 {
+		t.Fatalf("Average iterations is %v, expected %v\n", stats.AverageIterations, float64(total)/3)
+	}
+
+	if stats.HashRate <= 0 {
+		t.Fatalf("Hash rate was not measured\n")
+	}
+
+	worker.SetDifficulty(100)
+	worker.SetTimeout(50)
+	worker.DoProofForString("A message that takes forever")
+	stats = worker.Stats()
+	if stats.Failures != 1 || stats.Hashes <= total || stats.Proofs != 3 {
+		t.Fatalf("Failed search was not recorded: %+v\n", stats)
+	}
+
+	worker.ResetStats()
+	if worker.Stats() != (Stats{}) {
+		t.Fatalf("Stats were not reset\n")
+	}
+}
+
+func TestStatsParallel(t *testing.T) {
+	worker := NewWorker()
+	worker.SetConcurrency(4)
+
+	proof, _ := worker.DoProofForString("A parallel message")
+	stats := worker.Stats()
+	if stats.Proofs != 1 || stats.Hashes != uint64(proof.requiredIterations)+1 {
+		t.Fatalf("Parallel search was not recorded correctly: %+v\n", stats)
+	}
+}
@@ -0,0 +1,102 @@
+package powork
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+)
+
+// ExtensionSubject is the extension a proof's subject is carried in
+const ExtensionSubject = "subject"
+
+// DoProofForSubject calculates a proof of work for msg bound to subject, the identity of the
+// client that is going to present it, such as an API key, a session ID or an IP address. The
+// subject is hashed with the message and carried in the proof, so that a proof computed by
+// one client can't be handed to another. Subjects travel in the clear; bind to a digest of
+// secrets such as API keys rather than to the secret itself.
+func (p *Worker) DoProofForSubject(msg []byte, subject string) (*PoWork, error) {
+	return p.DoProofForSubjectContext(context.Background(), msg, subject)
+}
+
+// DoProofForSubjectContext does the same thing as DoProofForSubject except carrying a context
+func (p *Worker) DoProofForSubjectContext(ctx context.Context, msg []byte, subject string) (*PoWork, error) {
+	base, err := p.subjectBase(msg, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.doProof(ctx, base)
+}
+
+// DoProofForChallengeSubject calculates a proof of work for msg bound both to challenge c and
+// to subject, for servers that check who answers their challenges
+func (p *Worker) DoProofForChallengeSubject(c *Challenge, msg []byte, subject string) (*PoWork, error) {
+	return p.DoProofForChallengeSubjectContext(context.Background(), c, msg, subject)
+}
+
+// DoProofForChallengeSubjectContext does the same thing as DoProofForChallengeSubject except
+// carrying a context
+func (p *Worker) DoProofForChallengeSubjectContext(ctx context.Context, c *Challenge, msg []byte, subject string) (*PoWork, error) {
+	if len(c.value) == 0 {
+		return nil, fmt.Errorf("%w: challenge must not be empty", ErrInvalidEncoding)
+	}
+
+	base, err := p.subjectBase(msg, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	base.challenge = c.value
+	return p.doProof(ctx, base)
+}
+
+// subjectBase returns the starting point of a search for msg bound to subject
+func (p *Worker) subjectBase(msg []byte, subject string) (*PoWork, error) {
+	if subject == "" {
+		return nil, fmt.Errorf("%w: subject must not be empty", ErrInvalidSetting)
+	}
+
+	base := p.newBase(msg)
+	if err := base.setExtension(ExtensionSubject, subject, ErrInvalidSetting); err != nil {
+		return nil, err
+	}
+	return base, nil
+}
+
+// GetSubject gets the subject the proof is bound to, or "" if there is none
+func (p *PoWork) GetSubject() string {
+	return p.extensions[ExtensionSubject]
+}
+
+// ValidateSubject checks a proof as by ValidatePoWork, and also that it is bound to subject,
+// the identity of the client presenting it
+func (p *Worker) ValidateSubject(pow *PoWork, subject string) (bool, error) {
+	if !pow.hasSubject(subject) {
+		return false, nil
+	}
+
+	return p.ValidatePoWork(pow)
+}
+
+// ValidateChallengeSubject checks a proof as by ValidateChallenge, and also that it is bound
+// to subject. A proof bound to another subject does not use its challenge up, so a client
+// can't spend the challenges it sees others answer.
+func (p *Worker) ValidateChallengeSubject(pow *PoWork, subject string) (bool, error) {
+	return p.ValidateChallengeSubjectContext(context.Background(), pow, subject)
+}
+
+// ValidateChallengeSubjectContext does the same thing as ValidateChallengeSubject, passing ctx
+// to the Worker's hooks
+func (p *Worker) ValidateChallengeSubjectContext(ctx context.Context, pow *PoWork, subject string) (bool, error) {
+	if !pow.hasSubject(subject) {
+		return false, nil
+	}
+
+	return p.ValidateChallengeContext(ctx, pow)
+}
+
+// hasSubject reports whether the proof is bound to subject, in constant time
+func (p *PoWork) hasSubject(subject string) bool {
+	got, ok := p.extensions[ExtensionSubject]
+	return ok && subject != "" && subtle.ConstantTimeCompare([]byte(got), []byte(subject)) == 1
+}
@@ -0,0 +1,69 @@
+package powork
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSubject(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(8)
+
+	pow, err := worker.DoProofForSubject([]byte("a request"), "session-1")
+	if err != nil {
+		t.Fatalf("Error computing proof: %v\n", err)
+	}
+	if pow.GetSubject() != "session-1" {
+		t.Fatalf("Proof is bound to subject %q\n", pow.GetSubject())
+	}
+
+	if ok, err := worker.ValidateSubject(pow, "session-1"); !ok || err != nil {
+		t.Fatalf("Proof did not validate for its subject: %v\n", err)
+	}
+	if ok, _ := worker.ValidateSubject(pow, "session-2"); ok {
+		t.Fatalf("Proof validated for another subject\n")
+	}
+	if ok, _ := worker.ValidateSubject(pow, ""); ok {
+		t.Fatalf("Proof validated for an empty subject\n")
+	}
+
+	// the subject is part of the work, not just a label
+	data, _ := pow.MarshalBinary()
+	decoded := new(PoWork)
+	decoded.UnmarshalBinary(data)
+	decoded.extensions[ExtensionSubject] = "session-2"
+	if ok, _ := worker.ValidateSubject(decoded, "session-2"); ok {
+		t.Fatalf("Proof with a rewritten subject validated\n")
+	}
+
+	plain, _ := worker.DoProofForString("a request")
+	if ok, _ := worker.ValidateSubject(plain, "session-1"); ok {
+		t.Fatalf("Proof without a subject validated for one\n")
+	}
+
+	if _, err := worker.DoProofForSubject([]byte("a request"), ""); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Empty subject did not fail with ErrInvalidSetting: %v\n", err)
+	}
+}
+
+func TestChallengeSubject(t *testing.T) {
+	worker := NewWorker()
+	worker.SetDifficulty(8)
+
+	c, _ := worker.NewChallenge()
+	pow, err := worker.DoProofForChallengeSubject(c, nil, "203.0.113.7")
+	if err != nil {
+		t.Fatalf("Error computing proof: %v\n", err)
+	}
+
+	// a proof presented by another client leaves the challenge for its owner
+	if ok, _ := worker.ValidateChallengeSubject(pow, "198.51.100.1"); ok {
+		t.Fatalf("Proof validated for another subject\n")
+	}
+	if ok, err := worker.ValidateChallengeSubject(pow, "203.0.113.7"); !ok || err != nil {
+		t.Fatalf("Proof did not validate for its subject: %v\n", err)
+	}
+	if ok, _ := worker.ValidateChallengeSubject(pow, "203.0.113.7"); ok {
+		t.Fatalf("Challenge was answered twice\n")
+	}
+}
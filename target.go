@@ -0,0 +1,74 @@
+package powork
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// SetTarget makes the Worker accept hash sums that, read as a big-endian unsigned integer,
+// are less than or equal to target, instead of counting leading zero bits. A target can
+// express difficulties between two whole numbers of bits; see TargetForDifficulty. The
+// target is compared as it is, so it must be meant for the output length of the Worker's
+// hash, as the targets of TargetForDifficulty are; a target for SHA-256 is far too easy for
+// a 512 bit hash. Passing nil restores the leading zero bits rule.
+func (p *Worker) SetTarget(target *big.Int) error {
+	if target == nil {
+		p.target = nil
+		p.predicate = nil
+		return nil
+	}
+
+	if target.Sign() <= 0 {
+		return fmt.Errorf("%w: target must be greater than 0", ErrInvalidSetting)
+	}
+
+	p.target = new(big.Int).Set(target)
+	p.predicate = BelowTarget(p.target)
+	return nil
+}
+
+// GetTarget gets the target set with SetTarget, or nil if the Worker has none
+func (p *Worker) GetTarget() *big.Int {
+	if p.target == nil {
+		return nil
+	}
+
+	return new(big.Int).Set(p.target)
+}
+
+// TargetForDifficulty returns the target that makes a proof for the Worker's hash as hard as
+// bits leading zero bits would, where bits need not be a whole number. For whole numbers the
+// target accepts exactly the sums the leading zero bits rule does.
+func (p *Worker) TargetForDifficulty(bits float64) (*big.Int, error) {
+	size := p.hasher.Size() * 8
+	if bits < 0 || bits > float64(size) || math.IsNaN(bits) {
+		return nil, fmt.Errorf("%w: %v bits requested, hash has %v", ErrInvalidDifficulty, bits, size)
+	}
+
+	// 2^(size-bits) - 1, with the fractional part of the exponent as a float64 mantissa
+	exp := float64(size) - bits
+	whole := math.Floor(exp)
+	f := new(big.Float).SetFloat64(math.Exp2(exp - whole))
+	f.SetMantExp(f, int(whole))
+
+	target, _ := f.Int(nil)
+	return target.Sub(target, big.NewInt(1)), nil
+}
+
+// EstimateIterationsForTarget returns the expected number of hashes needed to find a proof
+// for target with a hash of hashBits output bits, which is 2^hashBits / (target + 1)
+func EstimateIterationsForTarget(target *big.Int, hashBits int) float64 {
+	if target == nil || target.Sign() < 0 {
+		return math.Inf(1)
+	}
+
+	space := new(big.Float).SetMantExp(big.NewFloat(1), hashBits)
+	accepted := new(big.Float).SetInt(new(big.Int).Add(target, big.NewInt(1)))
+	expected, _ := new(big.Float).Quo(space, accepted).Float64()
+	if expected < 1 {
+		return 1
+	}
+
+	return expected
+}
@@ -0,0 +1,78 @@
+package powork
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestTargetForDifficulty(t *testing.T) {
+	worker := NewWorker()
+
+	ten, err := worker.TargetForDifficulty(10)
+	if err != nil {
+		t.Fatalf("Could not compute target: %v\n", err)
+	}
+
+	expected := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 502), big.NewInt(1))
+	if ten.Cmp(expected) != 0 {
+		t.Fatalf("Target for 10 bits is %x, expected %x\n", ten, expected)
+	}
+
+	half, _ := worker.TargetForDifficulty(10.5)
+	eleven, _ := worker.TargetForDifficulty(11)
+	if half.Cmp(ten) >= 0 || half.Cmp(eleven) <= 0 {
+		t.Fatalf("Target for 10.5 bits is not between 10 and 11 bits\n")
+	}
+
+	if n := EstimateIterationsForTarget(ten, 512); math.Abs(n-1024) > 1e-6 {
+		t.Fatalf("Expected 1024 iterations for 10 bits, got %v\n", n)
+	}
+	if n := EstimateIterationsForTarget(half, 512); math.Abs(n-math.Exp2(10.5)) > 1e-6 {
+		t.Fatalf("Expected %v iterations for 10.5 bits, got %v\n", math.Exp2(10.5), n)
+	}
+
+	for _, bits := range []float64{-1, 513, math.NaN()} {
+		if _, err := worker.TargetForDifficulty(bits); !errors.Is(err, ErrInvalidDifficulty) {
+			t.Fatalf("Expected ErrInvalidDifficulty for %v bits, got %v\n", bits, err)
+		}
+	}
+}
+
+func TestSetTarget(t *testing.T) {
+	worker := NewWorker()
+	target, _ := worker.TargetForDifficulty(9.5)
+	if err := worker.SetTarget(target); err != nil {
+		t.Fatalf("Could not set target: %v\n", err)
+	}
+
+	pow, err := worker.DoProofForString("A message below the target")
+	if err != nil {
+		t.Fatalf("Could not compute proof: %v\n", err)
+	}
+
+	sum, _ := pow.sum(worker.validationHasher())
+	if new(big.Int).SetBytes(sum).Cmp(target) > 0 {
+		t.Fatalf("Proof hash %x is above the target\n", sum)
+	}
+
+	if ok, err := worker.ValidatePoWork(pow); err != nil || !ok {
+		t.Fatalf("Could not validate proof: %v, %v\n", ok, err)
+	}
+
+	// the Worker keeps its own copy
+	target.SetInt64(1)
+	if worker.GetTarget().Cmp(target) == 0 {
+		t.Fatalf("Changing the caller's target changed the Worker's\n")
+	}
+
+	if err := worker.SetTarget(big.NewInt(0)); !errors.Is(err, ErrInvalidSetting) {
+		t.Fatalf("Expected ErrInvalidSetting for a zero target, got %v\n", err)
+	}
+
+	worker.SetTarget(nil)
+	if worker.GetTarget() != nil || worker.predicate != nil {
+		t.Fatalf("Target was not cleared\n")
+	}
+}
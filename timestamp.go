@@ -0,0 +1,56 @@
+package powork
+
+import (
+	"fmt"
+	"time"
+)
+
+// SetProofTimestamps sets whether the proofs the Worker calculates carry the time their
+// search started. The timestamp is bound into the proof, so it can't be changed afterwards,
+// and a long search ages its proof before it is found.
+// Workers with a maximum proof age always timestamp their proofs.
+func (p *Worker) SetProofTimestamps(enabled bool) {
+	p.timestamps = enabled
+}
+
+// SetMaxProofAge sets how old a proof may be for the Worker to accept it. Once set, proofs
+// without a timestamp are rejected. A value of 0, the default, accepts proofs of any age.
+func (p *Worker) SetMaxProofAge(d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("%w: maximum proof age must be greater than or equal to 0", ErrInvalidSetting)
+	}
+
+	p.maxProofAge = d
+	return nil
+}
+
+// SetClockSkew sets how far the prover's clock may be off from the Worker's. Proof ages are
+// checked with this much leeway in both directions. The default is 0.
+func (p *Worker) SetClockSkew(d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("%w: clock skew must be greater than or equal to 0", ErrInvalidSetting)
+	}
+
+	p.clockSkew = d
+	return nil
+}
+
+// GetTimestamp gets the time the search for the proof started, or the zero time if it
+// carries none
+func (p *PoWork) GetTimestamp() time.Time {
+	return p.timestamp
+}
+
+// acceptsAge reports whether pow is young enough for the Worker at now
+func (p *Worker) acceptsAge(pow *PoWork, now time.Time) bool {
+	if p.maxProofAge == 0 {
+		return true
+	}
+
+	if pow.timestamp.IsZero() {
+		return false
+	}
+
+	age := now.Sub(pow.timestamp)
+	return age <= p.maxProofAge+p.clockSkew && -age <= p.clockSkew
+}
@@ -0,0 +1,107 @@
+package powork
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTimestampedProof(t *testing.T) {
+	worker := NewWorker()
+	worker.SetMaxProofAge(time.Minute)
+
+	before := time.Now().Truncate(time.Millisecond)
+	proof, err := worker.DoProofForString("A message with a timestamp")
+	if err != nil {
+		t.Fatalf("Could not calculate proof: %v\n", err)
+	}
+
+	if proof.GetTimestamp().Before(before) || proof.GetTimestamp().After(time.Now()) {
+		t.Fatalf("Proof timestamp %v is not the solve time\n", proof.GetTimestamp())
+	}
+
+	ok, err := worker.ValidatePoWork(proof)
+	if err != nil || !ok {
+		t.Fatalf("Timestamped proof did not validate: %v\n", err)
+	}
+
+	// the timestamp is bound into the proof
+	proof.timestamp = proof.timestamp.Add(time.Second)
+	ok, _ = worker.ValidatePoWork(proof)
+	if ok {
+		t.Fatalf("Proof with a modified timestamp was accepted\n")
+	}
+}
+
+func TestStaleProofRejected(t *testing.T) {
+	prover := NewWorker()
+	prover.SetProofTimestamps(true)
+	proof, _ := prover.DoProofForString("A message with a timestamp")
+
+	verifier := NewWorker()
+	verifier.SetMaxProofAge(50 * time.Millisecond)
+
+	now := time.Now()
+	if !verifier.acceptsAge(proof, now) {
+		t.Fatalf("Fresh proof was rejected\n")
+	}
+
+	if verifier.acceptsAge(proof, now.Add(time.Second)) {
+		t.Fatalf("Stale proof was accepted\n")
+	}
+
+	if verifier.acceptsAge(proof, now.Add(-time.Second)) {
+		t.Fatalf("Proof from the future was accepted\n")
+	}
+
+	verifier.SetClockSkew(2 * time.Second)
+	if !verifier.acceptsAge(proof, now.Add(time.Second)) || !verifier.acceptsAge(proof, now.Add(-time.Second)) {
+		t.Fatalf("Clock skew tolerance was not applied\n")
+	}
+
+	// without a timestamp there is nothing to check the age of
+	plain, _ := NewWorker().DoProofForString("A message without a timestamp")
+	if ok, _ := verifier.ValidatePoWork(plain); ok {
+		t.Fatalf("Proof without a timestamp was accepted by a worker with a maximum age\n")
+	}
+}
+
+func TestTimestampEncoding(t *testing.T) {
+	worker := NewWorker()
+	worker.SetMaxProofAge(time.Minute)
+	proof, _ := worker.DoProofForString("A message with a timestamp")
+
+	data, _ := proof.MarshalBinary()
+	decoded := new(PoWork)
+	if err := decoded.UnmarshalBinary(data); err != nil || !decoded.GetTimestamp().Equal(proof.GetTimestamp()) {
+		t.Fatalf("Binary round trip lost the timestamp: %v\n", err)
+	}
+
+	j, _ := json.Marshal(proof)
+	fromJSON := new(PoWork)
+	if err := json.Unmarshal(j, fromJSON); err != nil || !fromJSON.GetTimestamp().Equal(proof.GetTimestamp()) {
+		t.Fatalf("JSON round trip lost the timestamp: %s\n", j)
+	}
+
+	ok, err := worker.ValidatePoWork(decoded)
+	if err != nil || !ok {
+		t.Fatalf("Decoded timestamped proof did not validate: %v\n", err)
+	}
+}
+
+func TestSpentTTLFollowsTimestamp(t *testing.T) {
+	worker := NewWorker()
+	worker.SetMaxProofAge(time.Minute)
+	worker.SetSpentStore(NewMemorySpentStore(10), time.Hour)
+
+	proof, _ := worker.DoProofForString("A message with a timestamp")
+	ttl := worker.spentTTLFor(proof, proof.GetTimestamp().Add(20*time.Second))
+	if ttl != 40*time.Second {
+		t.Fatalf("Spent proof would be recorded for %v, expected 40s\n", ttl)
+	}
+
+	plain, _ := NewWorker().DoProofForString("A message without a timestamp")
+	if ttl := worker.spentTTLFor(plain, time.Now()); ttl != time.Hour {
+		t.Fatalf("Untimestamped proof would be recorded for %v, expected 1h\n", ttl)
+	}
+}
@@ -0,0 +1,229 @@
+// Package vdf implements a verifiable delay function: Wesolowski's proof of repeated squaring
+// in an RSA group.
+//
+// Evaluating the function for t iterations takes t modular squarings one after the other, so
+// the work cannot be spread over more cores, and the iteration count maps to wall-clock time
+// rather than to rented hardware. The result carries a proof that is checked with two small
+// exponentiations.
+package vdf
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+// RSA2048 is the modulus of the RSA-2048 factoring challenge. Nobody is known to have its
+// factors, which would allow evaluating the function without doing the squarings.
+var RSA2048, _ = new(big.Int).SetString("25195908475657893494027183240048398571429282126204032027777137836043662020707595556264018525880784406918290641249515082189298559149176184502808489120072844992687392807287776735971418347270261896375014971824691165077613379859095700097330459748808428401797429100642458691817195118746121515172654632282216869987549182422433637259085141865462043576798423387184774447920739934236584823824281198163815010674810451660377306056201619676256133844143603833904414952634432190114657544454178424020924616515723350778707749817125772467962926386356373289912154831438167899885040445364023527381951378636564391212010397122822120720357", 10)
+
+// minModulusBits is the size below which a modulus is too easy to factor
+const minModulusBits = 1024
+
+// challengeBits is the size of the prime the proof is computed for
+const challengeBits = 128
+
+// benchmarkIterations is the number of squarings IterationsFor measures
+const benchmarkIterations = 2000
+
+// VDF evaluates and verifies the delay function modulo an RSA modulus
+type VDF struct {
+	modulus *big.Int
+}
+
+// New creates a VDF for a modulus whose factors nobody knows. It must be odd and at least
+// 1024 bits long.
+func New(modulus *big.Int) (*VDF, error) {
+	if modulus.Bit(0) == 0 || modulus.BitLen() < minModulusBits {
+		return nil, fmt.Errorf("%w: modulus must be odd and at least %d bits", powork.ErrInvalidSetting, minModulusBits)
+	}
+
+	return &VDF{modulus: new(big.Int).Set(modulus)}, nil
+}
+
+// Default creates a VDF for the RSA-2048 modulus
+func Default() *VDF {
+	v, _ := New(RSA2048)
+	return v
+}
+
+// A Proof is the result of evaluating the delay function: y = x^(2^t) and Wesolowski's proof
+// pi that y is correct
+type Proof struct {
+	Iterations uint64
+	Y          *big.Int
+	Pi         *big.Int
+}
+
+// Evaluate computes the delay function of input for t iterations, which takes t sequential
+// squarings to compute y and as many again for the proof
+func (v *VDF) Evaluate(ctx context.Context, input []byte, t uint64) (*Proof, error) {
+	if t == 0 {
+		return nil, fmt.Errorf("%w: iterations must be at least 1", powork.ErrInvalidSetting)
+	}
+
+	x := v.element(input)
+
+	y := new(big.Int).Set(x)
+	for i := uint64(0); i < t; i++ {
+		if i%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		y.Mul(y, y).Mod(y, v.modulus)
+	}
+
+	l := v.challenge(x, y, t)
+
+	// pi = x^floor(2^t / l), by long division of 2^t by l one bit at a time
+	pi := big.NewInt(1)
+	r := big.NewInt(1)
+	two := big.NewInt(2)
+	for i := uint64(0); i < t; i++ {
+		if i%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		r.Mul(r, two)
+		pi.Mul(pi, pi)
+		if r.Cmp(l) >= 0 {
+			r.Sub(r, l)
+			pi.Mul(pi, x)
+		}
+		pi.Mod(pi, v.modulus)
+	}
+
+	return &Proof{Iterations: t, Y: y, Pi: pi}, nil
+}
+
+// Verify checks a proof for input by testing pi^l * x^(2^t mod l) = y. It does not check that
+// the proof has as many iterations as the caller wants.
+func (v *VDF) Verify(input []byte, proof *Proof) (bool, error) {
+	if proof.Iterations == 0 || proof.Y == nil || proof.Pi == nil {
+		return false, fmt.Errorf("%w: incomplete proof", powork.ErrInvalidEncoding)
+	}
+
+	for _, e := range []*big.Int{proof.Y, proof.Pi} {
+		if e.Sign() <= 0 || e.Cmp(v.modulus) >= 0 {
+			return false, nil
+		}
+	}
+
+	x := v.element(input)
+	l := v.challenge(x, proof.Y, proof.Iterations)
+	r := new(big.Int).Exp(big.NewInt(2), new(big.Int).SetUint64(proof.Iterations), l)
+
+	lhs := new(big.Int).Exp(proof.Pi, l, v.modulus)
+	lhs.Mul(lhs, new(big.Int).Exp(x, r, v.modulus)).Mod(lhs, v.modulus)
+	return lhs.Cmp(proof.Y) == 0, nil
+}
+
+// IterationsFor measures how fast this machine squares and returns the number of iterations
+// expected to take d. Faster machines finish sooner, so use it as a lower bound on the delay.
+func (v *VDF) IterationsFor(d time.Duration) uint64 {
+	y := v.element([]byte("powork vdf calibration"))
+
+	start := time.Now()
+	for i := 0; i < benchmarkIterations; i++ {
+		y.Mul(y, y).Mod(y, v.modulus)
+	}
+	elapsed := time.Since(start)
+
+	return uint64(float64(benchmarkIterations) * d.Seconds() / elapsed.Seconds())
+}
+
+// element maps input to a square modulo the modulus, by squaring SHA-256 of the input
+// stretched to the size of the modulus
+func (v *VDF) element(input []byte) *big.Int {
+	size := (v.modulus.BitLen() + 7) / 8
+	buf := make([]byte, 0, size+sha256.Size)
+
+	digest := sha256.Sum256(input)
+	var counter [4]byte
+	for i := uint32(0); len(buf) < size; i++ {
+		binary.BigEndian.PutUint32(counter[:], i)
+		h := sha256.New()
+		h.Write(digest[:])
+		h.Write(counter[:])
+		buf = h.Sum(buf)
+	}
+
+	x := new(big.Int).SetBytes(buf[:size])
+	x.Mod(x, v.modulus)
+	return x.Mul(x, x).Mod(x, v.modulus)
+}
+
+// challenge derives the prime l from everything the proof is about, so that a prover cannot
+// choose it
+func (v *VDF) challenge(x, y *big.Int, t uint64) *big.Int {
+	h := sha256.New()
+	for _, e := range []*big.Int{v.modulus, x, y} {
+		b := e.Bytes()
+		var n [4]byte
+		binary.BigEndian.PutUint32(n[:], uint32(len(b)))
+		h.Write(n[:])
+		h.Write(b)
+	}
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], t)
+	h.Write(ts[:])
+
+	l := new(big.Int).SetBytes(h.Sum(nil)[:challengeBits/8])
+	l.SetBit(l, challengeBits-1, 1)
+	l.SetBit(l, 0, 1)
+	for !l.ProbablyPrime(20) {
+		l.Add(l, big.NewInt(2))
+	}
+	return l
+}
+
+// MarshalBinary encodes the proof as the iteration count, a big-endian uint64, followed by y
+// and pi, each a big-endian uint16 length and the big-endian value
+func (p *Proof) MarshalBinary() ([]byte, error) {
+	y, pi := p.Y.Bytes(), p.Pi.Bytes()
+	if len(y) > math.MaxUint16 || len(pi) > math.MaxUint16 {
+		return nil, fmt.Errorf("%w: proof is too large", powork.ErrInvalidEncoding)
+	}
+
+	data := make([]byte, 8, 12+len(y)+len(pi))
+	binary.BigEndian.PutUint64(data, p.Iterations)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(y)))
+	data = append(data, y...)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(pi)))
+	return append(data, pi...), nil
+}
+
+// UnmarshalBinary decodes a proof encoded by MarshalBinary
+func (p *Proof) UnmarshalBinary(data []byte) error {
+	if len(data) < 8 {
+		return fmt.Errorf("%w: encoded proof is too short", powork.ErrInvalidEncoding)
+	}
+	iterations := binary.BigEndian.Uint64(data)
+	data = data[8:]
+
+	values := make([]*big.Int, 2)
+	for i := range values {
+		if len(data) < 2 || len(data) < 2+int(binary.BigEndian.Uint16(data)) {
+			return fmt.Errorf("%w: encoded proof is truncated", powork.ErrInvalidEncoding)
+		}
+		n := int(binary.BigEndian.Uint16(data))
+		values[i] = new(big.Int).SetBytes(data[2 : 2+n])
+		data = data[2+n:]
+	}
+
+	if len(data) != 0 {
+		return fmt.Errorf("%w: trailing bytes after proof", powork.ErrInvalidEncoding)
+	}
+
+	p.Iterations, p.Y, p.Pi = iterations, values[0], values[1]
+	return nil
+}
@@ -0,0 +1,94 @@
+package vdf
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Zumium/powork"
+)
+
+func TestEvaluateVerify(t *testing.T) {
+	v := Default()
+	input := []byte("A message that took a while")
+
+	proof, err := v.Evaluate(context.Background(), input, 1000)
+	if err != nil {
+		t.Fatalf("Could not evaluate: %v\n", err)
+	}
+
+	// y really is x^(2^t)
+	x := v.element(input)
+	expected := new(big.Int).Exp(x, new(big.Int).Lsh(big.NewInt(1), 1000), RSA2048)
+	if proof.Y.Cmp(expected) != 0 {
+		t.Fatalf("Output is not x^(2^t)\n")
+	}
+
+	ok, err := v.Verify(input, proof)
+	if err != nil || !ok {
+		t.Fatalf("Could not verify proof: %v, %v\n", ok, err)
+	}
+
+	if ok, _ := v.Verify([]byte("Another message"), proof); ok {
+		t.Fatalf("Proof verified for another input\n")
+	}
+
+	fewer := *proof
+	fewer.Iterations = 999
+	if ok, _ := v.Verify(input, &fewer); ok {
+		t.Fatalf("Proof verified for another iteration count\n")
+	}
+
+	forged := *proof
+	forged.Pi = new(big.Int).Add(proof.Pi, big.NewInt(1))
+	if ok, _ := v.Verify(input, &forged); ok {
+		t.Fatalf("Forged proof verified\n")
+	}
+}
+
+func TestProofEncoding(t *testing.T) {
+	v := Default()
+	proof, _ := v.Evaluate(context.Background(), []byte("encode me"), 100)
+
+	data, err := proof.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Could not encode proof: %v\n", err)
+	}
+
+	decoded := new(Proof)
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Could not decode proof: %v\n", err)
+	}
+	if ok, err := v.Verify([]byte("encode me"), decoded); err != nil || !ok {
+		t.Fatalf("Decoded proof did not verify: %v, %v\n", ok, err)
+	}
+
+	for _, bad := range [][]byte{data[:7], data[:len(data)-1], append(data, 0)} {
+		if err := new(Proof).UnmarshalBinary(bad); !errors.Is(err, powork.ErrInvalidEncoding) {
+			t.Fatalf("Expected ErrInvalidEncoding, got %v\n", err)
+		}
+	}
+}
+
+func TestSettings(t *testing.T) {
+	if _, err := New(big.NewInt(1<<40 + 1)); !errors.Is(err, powork.ErrInvalidSetting) {
+		t.Fatalf("Expected ErrInvalidSetting for a small modulus, got %v\n", err)
+	}
+
+	v := Default()
+	if _, err := v.Evaluate(context.Background(), nil, 0); !errors.Is(err, powork.ErrInvalidSetting) {
+		t.Fatalf("Expected ErrInvalidSetting for 0 iterations, got %v\n", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := v.Evaluate(ctx, nil, 10); err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v\n", err)
+	}
+
+	if n := v.IterationsFor(100 * time.Millisecond); n == 0 {
+		t.Fatalf("Expected some iterations in 100ms\n")
+	}
+}
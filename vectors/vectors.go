@@ -0,0 +1,184 @@
+// Package vectors generates and verifies test vectors for PoWork proofs, so that
+// implementations in other languages can be checked against this one.
+//
+// A vector is a proof bound to nothing but its message, whose hash is H(msg || nonce). It is
+// exchanged as JSON with hex encoded bytes:
+//
+//	{"msg":"68656c6c6f","alg":"sha256","difficulty":8,"nonce":"...","digest":"...","proof":"..."}
+//
+// The nonce is given as it is hashed. The proof member, which is optional when verifying, is
+// the proof as encoded by PoWork.MarshalBinary.
+package vectors
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Zumium/powork"
+)
+
+// ErrMismatch is returned when a vector's digest or proof does not match its other members
+var ErrMismatch = errors.New("Test vector does not match")
+
+// bigEndianSuffix marks algorithm identifiers of proofs with big-endian nonces
+const bigEndianSuffix = "/be"
+
+// A Vector is a test vector for one proof
+type Vector struct {
+	Message    string `json:"msg"`
+	Algorithm  string `json:"alg"`
+	Difficulty int    `json:"difficulty"`
+	Nonce      string `json:"nonce"`
+	Digest     string `json:"digest"`
+	Proof      string `json:"proof,omitempty"`
+}
+
+// Algorithms are the identifiers of the built-in algorithms in both nonce byte orders, which
+// Default generates vectors for
+var Algorithms = []string{
+	"sha3-512", "sha256", "sha512", "blake2b-256", "blake2b-512", "blake3-256", "sha256d",
+	"sha3-512/be", "sha256/be", "sha512/be", "blake2b-256/be", "blake2b-512/be", "blake3-256/be", "sha256d/be",
+}
+
+// Messages are the messages Default generates vectors for: an empty one, a short one, and one
+// longer than a hash block
+var Messages = [][]byte{
+	{},
+	[]byte("hello"),
+	bytes.Repeat([]byte("PoWork test vector "), 10),
+}
+
+// Default generates vectors for every message of Messages with every algorithm of Algorithms
+// at difficulty 8. The search starts at nonce 0, so the vectors are the same every time.
+func Default() ([]Vector, error) {
+	var vs []Vector
+	for _, alg := range Algorithms {
+		v, err := Generate(alg, 8, Messages...)
+		if err != nil {
+			return nil, err
+		}
+		vs = append(vs, v...)
+	}
+	return vs, nil
+}
+
+// Generate computes a proof for each message with a registered algorithm, given by an
+// identifier as proofs declare it, and returns the vectors for the proofs
+func Generate(alg string, difficulty int, msgs ...[]byte) ([]Vector, error) {
+	w, err := newWorker(alg, difficulty)
+	if err != nil {
+		return nil, err
+	}
+
+	vs := make([]Vector, 0, len(msgs))
+	for _, msg := range msgs {
+		pow, err := w.DoProofFor(msg)
+		if err != nil {
+			return nil, err
+		}
+
+		proof, err := pow.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+
+		a, _ := powork.LookupAlgorithm(pow.GetAlgorithm())
+		vs = append(vs, Vector{
+			Message:    hex.EncodeToString(msg),
+			Algorithm:  alg,
+			Difficulty: difficulty,
+			Nonce:      hex.EncodeToString(pow.GetNonce()),
+			Digest:     hex.EncodeToString(pow.Sum(a.New)),
+			Proof:      hex.EncodeToString(proof),
+		})
+	}
+
+	return vs, nil
+}
+
+// Verify checks that a vector's digest is the hash of its message and nonce, that the digest
+// meets its difficulty, and, if it has one, that its proof decodes to the same message, nonce,
+// difficulty and algorithm
+func Verify(v Vector) error {
+	msg, err := hex.DecodeString(v.Message)
+	if err != nil {
+		return fmt.Errorf("%w: vector message is not hex", powork.ErrInvalidEncoding)
+	}
+	nonce, err := hex.DecodeString(v.Nonce)
+	if err != nil || len(nonce) == 0 || len(nonce) > powork.MaxNonceLength {
+		return fmt.Errorf("%w: vector nonce is not hex of 1 to %d bytes", powork.ErrInvalidEncoding, powork.MaxNonceLength)
+	}
+	digest, err := hex.DecodeString(v.Digest)
+	if err != nil {
+		return fmt.Errorf("%w: vector digest is not hex", powork.ErrInvalidEncoding)
+	}
+
+	w, err := newWorker(v.Algorithm, v.Difficulty)
+	if err != nil {
+		return err
+	}
+
+	// built the way a decoder would, so that the vector checks decoding as well as hashing
+	data, _ := json.Marshal(map[string]any{
+		"msg":        msg,
+		"nonceBytes": nonce,
+		"difficulty": v.Difficulty,
+		"alg":        v.Algorithm,
+	})
+	pow := new(powork.PoWork)
+	if err := pow.UnmarshalJSON(data); err != nil {
+		return err
+	}
+
+	a, _ := powork.LookupAlgorithm(pow.GetAlgorithm())
+	if !bytes.Equal(pow.Sum(a.New), digest) {
+		return fmt.Errorf("%w: digest is not the %s hash of the message and nonce", ErrMismatch, pow.GetAlgorithm())
+	}
+
+	if ok, err := w.ValidatePoWork(pow); err != nil || !ok {
+		return fmt.Errorf("%w: digest does not meet difficulty %d", ErrMismatch, v.Difficulty)
+	}
+
+	if v.Proof == "" {
+		return nil
+	}
+
+	encoded, err := hex.DecodeString(v.Proof)
+	if err != nil {
+		return fmt.Errorf("%w: vector proof is not hex", powork.ErrInvalidEncoding)
+	}
+	decoded := new(powork.PoWork)
+	if err := decoded.UnmarshalBinary(encoded); err != nil {
+		return err
+	}
+
+	if !bytes.Equal(decoded.GetMessage(), msg) || !bytes.Equal(decoded.GetNonce(), nonce) ||
+		decoded.GetDifficulty() != v.Difficulty || decoded.GetAlgorithm() != pow.GetAlgorithm() ||
+		decoded.GetNonceByteOrder() != pow.GetNonceByteOrder() {
+		return fmt.Errorf("%w: proof does not decode to the vector's message, nonce, difficulty and algorithm", ErrMismatch)
+	}
+
+	return nil
+}
+
+// newWorker creates a Worker for an algorithm identifier
+func newWorker(alg string, difficulty int) (*powork.Worker, error) {
+	name, bigEndian := strings.CutSuffix(alg, bigEndianSuffix)
+	w, err := powork.NewWorkerForAlgorithm(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if bigEndian {
+		w.SetNonceByteOrder(binary.BigEndian)
+	}
+	if err := w.SetDifficulty(difficulty); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
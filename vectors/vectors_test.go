@@ -0,0 +1,80 @@
+package vectors
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+)
+
+// set UPDATE_VECTORS to regenerate testdata/vectors.json
+var update = os.Getenv("UPDATE_VECTORS") != ""
+
+func TestDefault(t *testing.T) {
+	vs, err := Default()
+	if err != nil {
+		t.Fatalf("Error generating vectors: %v\n", err)
+	}
+
+	if update {
+		data, _ := json.MarshalIndent(vs, "", "\t")
+		if err := os.WriteFile("testdata/vectors.json", append(data, '\n'), 0o644); err != nil {
+			t.Fatalf("Error writing vectors: %v\n", err)
+		}
+	}
+
+	data, err := os.ReadFile("testdata/vectors.json")
+	if err != nil {
+		t.Fatalf("Error reading vectors: %v\n", err)
+	}
+	var saved []Vector
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("Error decoding vectors: %v\n", err)
+	}
+
+	// proofs must hash and encode as they always have
+	if len(saved) != len(vs) {
+		t.Fatalf("Generated %d vectors, %d saved\n", len(vs), len(saved))
+	}
+	for i := range vs {
+		if vs[i] != saved[i] {
+			t.Fatalf("Generated vector %+v, saved %+v\n", vs[i], saved[i])
+		}
+		if err := Verify(saved[i]); err != nil {
+			t.Fatalf("Saved vector %+v did not verify: %v\n", saved[i], err)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	vs, _ := Generate("sha256/be", 12, []byte("hello"))
+	v := vs[0]
+
+	// a vector without a proof is checked by its digest alone
+	v.Proof = ""
+	if err := Verify(v); err != nil {
+		t.Fatalf("Vector without a proof did not verify: %v\n", err)
+	}
+
+	wrong := vs[0]
+	wrong.Digest = wrong.Digest[:len(wrong.Digest)-2] + "00"
+	if wrong.Digest == vs[0].Digest {
+		wrong.Digest = wrong.Digest[:len(wrong.Digest)-2] + "01"
+	}
+	if err := Verify(wrong); !errors.Is(err, ErrMismatch) {
+		t.Fatalf("Vector with the wrong digest produced %v\n", err)
+	}
+
+	wrong = vs[0]
+	wrong.Algorithm = "sha256"
+	if err := Verify(wrong); !errors.Is(err, ErrMismatch) {
+		t.Fatalf("Vector with the wrong byte order produced %v\n", err)
+	}
+
+	wrong = vs[0]
+	other, _ := Generate("sha256/be", 12, []byte("world"))
+	wrong.Proof = other[0].Proof
+	if err := Verify(wrong); !errors.Is(err, ErrMismatch) {
+		t.Fatalf("Vector with another vector's proof produced %v\n", err)
+	}
+}
@@ -0,0 +1,36 @@
+package powork
+
+import (
+	"crypto/hmac"
+	"hash"
+)
+
+// DoubleHash wraps a hash constructor so that its hashes are H(H(x)), as Bitcoin's double
+// SHA-256 is. The result can be passed to SetHashFunc or NewWorkerWithHashFunc.
+func DoubleHash(f func() hash.Hash) func() hash.Hash {
+	return func() hash.Hash {
+		return &doubleHash{Hash: f(), outer: f()}
+	}
+}
+
+// doubleHash hashes everything written with the embedded hash, and the result with outer
+type doubleHash struct {
+	hash.Hash
+	outer hash.Hash
+}
+
+func (d *doubleHash) Sum(b []byte) []byte {
+	d.outer.Reset()
+	d.outer.Write(d.Hash.Sum(nil))
+	return d.outer.Sum(b)
+}
+
+// KeyedHash wraps a hash constructor so that its hashes are HMACs under key. Only holders of
+// the key can then compute or check a proof, so a server can keep third parties from
+// validating its proofs or precomputing them. The key is copied.
+func KeyedHash(f func() hash.Hash, key []byte) func() hash.Hash {
+	k := append([]byte(nil), key...)
+	return func() hash.Hash {
+		return hmac.New(f, k)
+	}
+}
@@ -0,0 +1,72 @@
+package powork
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"testing"
+)
+
+func TestDoubleHash(t *testing.T) {
+	h := DoubleHash(sha256.New)()
+	h.Write([]byte("hello"))
+
+	first := sha256.Sum256([]byte("hello"))
+	expected := sha256.Sum256(first[:])
+	if sum := h.Sum(nil); !bytes.Equal(sum, expected[:]) {
+		t.Fatalf("Double hash is %x, expected %x\n", sum, expected)
+	}
+
+	// Sum does not change the state, and Reset starts over
+	if sum := h.Sum([]byte{1}); !bytes.Equal(sum[1:], expected[:]) || sum[0] != 1 {
+		t.Fatalf("Second Sum differs: %x\n", sum)
+	}
+	h.Reset()
+	h.Write([]byte("hello"))
+	if sum := h.Sum(nil); !bytes.Equal(sum, expected[:]) {
+		t.Fatalf("Double hash after Reset is %x, expected %x\n", sum, expected)
+	}
+
+	worker, _ := NewWorkerForAlgorithm("sha256d")
+	pow, err := worker.DoProofForString("A message hashed twice")
+	if err != nil {
+		t.Fatalf("Could not compute proof: %v\n", err)
+	}
+	if ok, err := worker.ValidatePoWork(pow); err != nil || !ok {
+		t.Fatalf("Could not validate proof: %v, %v\n", ok, err)
+	}
+}
+
+func TestKeyedHash(t *testing.T) {
+	key := []byte("a server secret")
+	h := KeyedHash(sha256.New, key)()
+	h.Write([]byte("hello"))
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("hello"))
+	if !bytes.Equal(h.Sum(nil), mac.Sum(nil)) {
+		t.Fatalf("Keyed hash is not HMAC-SHA256\n")
+	}
+
+	server := NewWorkerWithHashFunc(KeyedHash(sha256.New, key))
+	server.SetDifficulty(12)
+	pow, err := server.DoProofForString("A message only the server can check")
+	if err != nil {
+		t.Fatalf("Could not compute proof: %v\n", err)
+	}
+	if ok, _ := server.ValidatePoWork(pow); !ok {
+		t.Fatalf("Server could not validate its keyed proof\n")
+	}
+
+	// the key is copied, so changing it later has no effect
+	key[0] ^= 0xff
+	if ok, _ := server.ValidatePoWork(pow); !ok {
+		t.Fatalf("Changing the caller's key changed the Worker's\n")
+	}
+
+	other := NewWorkerWithHashFunc(KeyedHash(sha256.New, []byte("another secret")))
+	other.SetDifficulty(12)
+	if ok, _ := other.ValidatePoWork(pow); ok {
+		t.Fatalf("Proof validated under another key\n")
+	}
+}